@@ -2,19 +2,25 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
+	"time"
 
-	"github.com/gorilla/sessions"
 
 	"tuis-oj-prototype/core"
 )
 
 func main() {
+	startedAt := time.Now()
 	cfg := core.Load()
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	logCloser, err := core.SetupLogging(cfg, "api.log")
 	if err != nil {
@@ -22,6 +28,28 @@ func main() {
 	}
 	defer logCloser.Close()
 
+	if err := core.RegisterExtraVerdicts(cfg.ExtraVerdicts); err != nil {
+		log.Fatalf("invalid EXTRA_VERDICTS: %v", err)
+	}
+	if _, err := core.ParseSubmissionRateLimits(cfg.SubmissionRateLimits); err != nil {
+		log.Fatalf("invalid SUBMISSION_RATE_LIMITS: %v", err)
+	}
+	if _, err := core.ParseOIDCRoleRules(cfg.OIDCRoleRules); err != nil {
+		log.Fatalf("invalid OIDC_ROLE_RULES: %v", err)
+	}
+	if _, err := core.ParseTimeLimitPolicy(cfg.TimeLimitPolicy); err != nil {
+		log.Fatalf("invalid TIME_LIMIT_POLICY: %v", err)
+	}
+	if _, err := core.ParseSandboxPolicies(cfg.JudgeSandboxPolicy); err != nil {
+		log.Fatalf("invalid JUDGE_SANDBOX_POLICY: %v", err)
+	}
+	if cfg.PasswordLoginDisabled && len(core.OAuthProviders(cfg)) == 0 {
+		log.Fatalf("PASSWORD_LOGIN_DISABLED requires at least one OAuth provider (OAUTH_PUBLIC_URL and client ID / secret)")
+	}
+	core.SetProblemArchiveLimit(cfg.ProblemArchiveMaxMB)
+	core.SetProblemTestcaseLimits(cfg.ProblemMaxTestcases, cfg.ProblemTestcasesMaxMB, cfg.ProblemJudgeCostWarnSec)
+	core.SetCheckerEpsRange(cfg.CheckerEpsMin, cfg.CheckerEpsMax)
+
 	db, err := core.Connect(ctx, cfg.DatabaseURL)
 	if err != nil {
 		log.Fatalf("failed to connect database: %v", err)
@@ -44,9 +72,16 @@ func main() {
 	if err := os.MkdirAll(cfg.SubmissionDir, 0o755); err != nil {
 		log.Fatalf("failed to ensure submission dir %s: %v", cfg.SubmissionDir, err)
 	}
+	// Resumable uploads in progress
+	if abs, err := filepath.Abs(cfg.UploadDir); err == nil {
+		cfg.UploadDir = abs
+	}
+	if err := os.MkdirAll(cfg.UploadDir, 0o755); err != nil {
+		log.Fatalf("failed to ensure upload dir %s: %v", cfg.UploadDir, err)
+	}
 
-	// Gorilla cookie store for session management.
-	store := sessions.NewCookieStore([]byte(cfg.SessionKey))
+	// セッションは Redis に保存し、cookie には署名付きのセッション ID だけを載せる（一覧・失効のため）
+	store := core.NewRedisSessionStore(redisClient, []byte(cfg.SessionKey))
 
 	userRepo := core.NewPgUserRepository(db)
 	authService := core.NewRepositoryAuthService(userRepo)
@@ -57,9 +92,52 @@ func main() {
 
 	router := core.NewRouter(cfg, store, authService, db, redisClient)
 
+	// コンテスト終了後の解説自動公開
+	go core.RunEditorialReleaser(ctx, core.NewPgContestRepository(db), core.NewPgNoticeRepository(db), time.Minute)
+	// 判定異常（SE 急増・編集後の AC 消失・特定ワーカーの失敗率）の検知
+	anomalyMonitor := core.NewAnomalyMonitor(core.NewPgIncidentRepository(db), cfg.AlertWebhookURL)
+	go anomalyMonitor.Run(ctx, time.Minute)
+	// ハートビートが途絶えたワーカーの実行中ジョブを即座に再投入し、インシデントとして通知する
+	go core.NewWorkerReaper(core.NewPgWorkerRegistry(db), redisClient, core.NewPgSubmissionRepository(db), anomalyMonitor).Run(ctx, 15*time.Second)
+	// 提出時の IP / User-Agent の保持期間切れを削除
+	if cfg.ClientInfoRetentionDays > 0 {
+		retention := time.Duration(cfg.ClientInfoRetentionDays) * 24 * time.Hour
+		go core.RunClientInfoPurger(ctx, core.NewPgSubmissionRepository(db), retention, time.Hour)
+	}
+	// DB / Redis / go-judge の稼働履歴（/admin/system/uptime）
+	go core.NewHealthMonitor(core.NewPgHealthRepository(db), db, redisClient, cfg.GoJudgeURL).Run(ctx, core.HealthSnapshotInterval)
+	// ゴミ箱の保持期間切れを完全削除
+	if cfg.TrashRetentionDays > 0 {
+		retention := time.Duration(cfg.TrashRetentionDays) * 24 * time.Hour
+		go core.RunTrashPurger(ctx, core.NewPgTrashRepository(db, retention), retention, time.Hour)
+	}
+	// DB に対応する行のない提出ディレクトリ（作成途中の失敗などの残骸）を削除
+	if cfg.OrphanFileGCMinutes > 0 {
+		go core.RunOrphanFileCollector(ctx, cfg.SubmissionDir, core.NewPgSubmissionRepository(db), redisClient, time.Duration(cfg.OrphanFileGCMinutes)*time.Minute)
+	}
+	// 採点系 Webhook の送信と失敗時の再送
+	go core.NewGraderWebhookDispatcher(db).Run(ctx, 10*time.Second)
+
 	addr := fmt.Sprintf(":%s", cfg.Port)
-	log.Printf("starting api server on %s", addr)
-	if err := router.Run(addr); err != nil {
-		log.Fatalf("server failed: %v", err)
+	srv := &http.Server{Addr: addr, Handler: router}
+	go func() {
+		log.Printf("starting api server on %s", addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("server failed: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), core.ShutdownDrainTimeout)
+	defer cancel()
+	report := core.ShutdownReport{Process: "api", StartedAt: startedAt}
+	report.Hostname, _ = os.Hostname()
+	report.InstanceID = fmt.Sprintf("%s:%d", report.Hostname, os.Getpid())
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("graceful shutdown failed: %v", err)
+		report.Error = err.Error()
+	}
+	if err := core.SaveShutdownReport(shutdownCtx, redisClient, report); err != nil {
+		log.Printf("failed to save shutdown report: %v", err)
 	}
 }
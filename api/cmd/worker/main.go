@@ -18,6 +18,7 @@ import (
 )
 
 func main() {
+	startedAt := time.Now()
 	cfg := core.Load()
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
@@ -28,6 +29,10 @@ func main() {
 	}
 	defer logCloser.Close()
 
+	if err := core.RegisterExtraVerdicts(cfg.ExtraVerdicts); err != nil {
+		log.Fatalf("invalid EXTRA_VERDICTS: %v", err)
+	}
+
 	db, err := core.Connect(ctx, cfg.DatabaseURL)
 	if err != nil {
 		log.Fatalf("failed to connect database: %v", err)
@@ -44,13 +49,30 @@ func main() {
 	repo := core.NewPgSubmissionRepository(db)
 	problemRepo := core.NewPgProblemRepository(db)
 	judge := core.NewHTTPJudgeClient(cfg.GoJudgeURL)
-	processor := core.NewWorkerProcessor(repo, problemRepo, judge, cfg.CompileTimeLimitMs)
+	sandboxPolicies, err := core.ParseSandboxPolicies(cfg.JudgeSandboxPolicy)
+	if err != nil {
+		log.Fatalf("invalid JUDGE_SANDBOX_POLICY: %v", err)
+	}
+	judge.ConfigureSandbox(sandboxPolicies, cfg.GoJudgeNetworkURL)
 	concurrency := cfg.WorkerConcurrency
 	if concurrency <= 0 {
 		concurrency = 1
 	}
 	workerID := core.NewWorkerID()
 	hostname, _ := os.Hostname()
+	processor := core.NewWorkerProcessor(repo, problemRepo, judge, cfg.CompileTimeLimitMs, workerID, []byte(cfg.ResultSigningKey))
+	judgedBy := workerID // goroutine 内の workerID（スロット番号）と区別する
+	events := core.NewEventBus(redisClient)
+	// 確定した判定を提出者へ、コンテスト提出なら順位表・風船イベントも配信する（失敗しても判定自体には影響しない）
+	publishVerdict := func(job string) {
+		id, err := strconv.ParseInt(job, 10, 64)
+		if err != nil {
+			return
+		}
+		if err := core.PublishSubmissionVerdict(context.WithoutCancel(ctx), events, repo, id); err != nil {
+			log.Printf("publish verdict for job %s failed: %v", job, err)
+		}
+	}
 	currentUser, _ := user.Current()
 	username := "unknown"
 	if currentUser != nil && currentUser.Username != "" {
@@ -58,6 +80,16 @@ func main() {
 	}
 	log.Printf("worker started. id=%s concurrency=%d queue=%s judge=%s user=%s", workerID, concurrency, core.PendingQueueKey, cfg.GoJudgeURL, username)
 
+	// 前回のワーカーが異常終了していればクラッシュ出力と実行中だったジョブをインシデントに記録し、
+	// このワーカーのクラッシュ出力も同じ場所に残るようにする
+	crashes := core.NewWorkerCrashRecorder(core.NewAnomalyMonitor(core.NewPgIncidentRepository(db), cfg.AlertWebhookURL), redisClient, workerID, hostname, cfg.LogDir)
+	crashes.ReportPreviousCrashes(ctx)
+	stopCrashCapture, err := crashes.CaptureCrashOutput()
+	if err != nil {
+		log.Printf("capture crash output failed: %v", err)
+		stopCrashCapture = func() {}
+	}
+
 	const pendingKey = core.PendingQueueKey
 	const processingKey = core.ProcessingQueueKey
 	visibility := core.DefaultVisibilityTimeout
@@ -66,6 +98,40 @@ func main() {
 
 	state := core.NewHeartbeatState(workerID, hostname, concurrency)
 	go state.Start(ctx, redisClient)
+	// 登録簿に載せておくと、ハートビートが途絶えたときに API 側が実行中のジョブを即座に再投入する
+	registry := core.NewPgWorkerRegistry(db)
+	if err := registry.Register(ctx, core.WorkerRegistration{WorkerID: workerID, Hostname: hostname, PID: os.Getpid(), Version: core.WorkerVersion(), Concurrency: concurrency}); err != nil {
+		log.Printf("register worker failed: %v", err)
+	}
+	// 最終確認時刻と処理件数を登録簿にも残す（ハートビートが消えた後も経歴として参照できる）
+	touchRegistry := func(ctx context.Context) {
+		processed, failed := state.Counters()
+		if err := registry.Touch(ctx, workerID, processed, failed); err != nil {
+			log.Printf("touch worker registry failed: %v", err)
+		}
+	}
+	go func() {
+		ticker := time.NewTicker(core.WorkerRegistryTouchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				touchRegistry(ctx)
+			}
+		}
+	}()
+	// ack と同時に実行中の印も外す
+	ack := func(job string) error {
+		ackCtx := context.WithoutCancel(ctx)
+		if err := queue.Ack(ackCtx, processingKey, job); err != nil {
+			return err
+		}
+		return core.UntrackWorkerJob(ackCtx, redisClient, workerID, job)
+	}
+	// ディスク使用量をハートビートに載せ、上限を超えたら古い成果物を掃除する
+	go core.NewDiskJanitor(state, judge, cfg).Run(ctx, time.Minute)
 
 	// requeue expired in-flight jobs periodically
 	go func() {
@@ -91,12 +157,34 @@ func main() {
 		}
 	}()
 
+	// 停止で中断したジョブ（終了時に pending へ戻す）
+	var interruptedMu sync.Mutex
+	var interrupted []string
+
 	var wg sync.WaitGroup
 	for i := 0; i < concurrency; i++ {
 		wg.Add(1)
 		go func(workerID int) {
 			defer wg.Done()
+			paused := false
 			for {
+				// 管理画面から一時停止されている間は新しいジョブを取り出さない
+				if p, err := core.GetQueuePause(ctx, redisClient); err == nil && p != nil {
+					if !paused {
+						log.Printf("[worker %d] queue paused by %s: %s", workerID, p.PausedBy, p.Reason)
+						paused = true
+					}
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(core.QueuePauseCheckInterval):
+						continue
+					}
+				} else if paused {
+					log.Printf("[worker %d] queue resumed", workerID)
+					paused = false
+				}
+
 				job, err := queue.Reserve(ctx, pendingKey, processingKey, visibility)
 				if err != nil {
 					if errors.Is(err, redis.Nil) {
@@ -118,20 +206,37 @@ func main() {
 				}
 
 				log.Printf("[worker %d] received job %s", workerID, job)
+				if err := core.TrackWorkerJob(ctx, redisClient, judgedBy, job); err != nil {
+					log.Printf("[worker %d] track job %s failed: %v", workerID, job, err)
+				}
 				state.JobStarted(job)
 
-				verdict, procErr := processor.Process(ctx, job)
+				verdict, procErr := core.RunJobRecovered(job, func() (string, error) { return processor.Process(ctx, job) })
+				// panic したジョブも通常の失敗と同じくリトライし、上限を超えたら SE にする
+				var panicErr *core.JobPanicError
+				if errors.As(procErr, &panicErr) {
+					log.Printf("[worker %d] %v\n%s", workerID, panicErr, panicErr.Stack)
+					crashes.RecordPanic(context.WithoutCancel(ctx), workerID, panicErr)
+				}
+				if procErr != nil && ctx.Err() != nil {
+					// 停止による中断はリトライとして数えない
+					interruptedMu.Lock()
+					interrupted = append(interrupted, job)
+					interruptedMu.Unlock()
+					state.JobFinished(job, nil)
+					return
+				}
 				if procErr != nil {
 					id, parseErr := strconv.ParseInt(job, 10, 64)
 					if parseErr != nil {
 						log.Printf("[worker %d] parse job id error for %s: %v", workerID, job, parseErr)
-						_ = queue.Ack(ctx, processingKey, job)
+						_ = ack(job)
 						continue
 					}
 
 					if errors.Is(procErr, core.ErrSubmissionNotPending) {
 						log.Printf("[worker %d] skip job %s: already processed", workerID, job)
-						_ = queue.Ack(ctx, processingKey, job)
+						_ = ack(job)
 						continue
 					}
 
@@ -151,19 +256,27 @@ func main() {
 						errMsg := procErr.Error()
 						res := core.SubmissionResult{
 							SubmissionID: id,
-							Verdict:      "SE",
+							Verdict:      core.VerdictSE,
+							JudgedBy:     judgedBy,
 							ErrorMessage: &errMsg,
+							SEReason:     core.ClassifySystemError(procErr),
 						}
+						res.Signature = core.SignResult([]byte(cfg.ResultSigningKey), res)
 						if saveErr := repo.SaveResult(ctx, res, "failed"); saveErr != nil {
 							log.Printf("[worker %d] final fail save result job %s: %v", workerID, job, saveErr)
+						} else {
+							publishVerdict(job)
 						}
 						log.Printf("[worker %d] job %s failed after retries (retry_count=%d)", workerID, job, newRetry)
 					}
-				} else if verdict != "AC" {
-					log.Printf("[worker %d] job %s finished with verdict=%s", workerID, job, verdict)
+				} else {
+					if verdict != core.VerdictAC {
+						log.Printf("[worker %d] job %s finished with verdict=%s", workerID, job, verdict)
+					}
+					publishVerdict(job)
 				}
 
-				if err := queue.Ack(ctx, processingKey, job); err != nil {
+				if err := ack(job); err != nil {
 					log.Printf("[worker %d] ack failed for job %s: %v", workerID, job, err)
 				}
 				state.JobFinished(job, procErr)
@@ -172,4 +285,18 @@ func main() {
 	}
 
 	wg.Wait()
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), core.ShutdownDrainTimeout)
+	defer cancel()
+	report := core.ShutdownReport{Process: "worker", InstanceID: workerID, Hostname: hostname, StartedAt: startedAt}
+	report.Requeued, report.Abandoned = core.DrainInterruptedJobs(drainCtx, queue, repo, interrupted)
+	_ = redisClient.Del(drainCtx, core.WorkerInFlightKey(workerID)).Err()
+	touchRegistry(drainCtx)
+	if err := registry.MarkStopped(drainCtx, workerID); err != nil {
+		log.Printf("failed to mark worker stopped: %v", err)
+	}
+	if err := core.SaveShutdownReport(drainCtx, redisClient, report); err != nil {
+		log.Printf("failed to save shutdown report: %v", err)
+	}
+	stopCrashCapture()
 }
@@ -0,0 +1,217 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// dashboardCacheTTL は集約結果を使い回す期間。複数の管理画面が同時にポーリングしても集計は1回で済む
+	dashboardCacheTTL         = 5 * time.Second
+	dashboardRecentSELimit    = 10
+	dashboardSubmissionWindow = time.Hour
+)
+
+// DashboardWorkers summarizes worker heartbeats.
+type DashboardWorkers struct {
+	Total    int               `json:"total"`
+	Busy     int               `json:"busy"`
+	Idle     int               `json:"idle"`
+	Starting int               `json:"starting"`
+	Items    []WorkerHeartbeat `json:"items"`
+}
+
+// DashboardSubmission is a submission listed on the dashboard.
+type DashboardSubmission struct {
+	ID           int64     `json:"id"`
+	UserID       int64     `json:"user_id"`
+	Username     string    `json:"userid"`
+	ProblemID    int64     `json:"problem_id"`
+	ProblemTitle string    `json:"problem_title"`
+	Language     string    `json:"language"`
+	JudgedBy     *string   `json:"judged_by"`
+	ErrorMessage *string   `json:"error_message"`
+	SEReason     *string   `json:"se_reason"`
+	CreatedAt    time.Time `json:"created_at"`
+	JudgedAt     time.Time `json:"judged_at"`
+}
+
+// DashboardSubmissionRate counts submissions created in the last window.
+type DashboardSubmissionRate struct {
+	WindowMinutes int `json:"window_minutes"`
+	Total         int `json:"total"`
+	Judged        int `json:"judged"`
+	SystemErrors  int `json:"system_errors"`
+	// SystemErrorReasons は SE の原因コードごとの件数（コード未記録の古い結果は unknown）
+	SystemErrorReasons []SystemErrorReasonCount `json:"system_error_reasons"`
+}
+
+// SystemErrorReasonCount is the number of System Errors with one reason code.
+type SystemErrorReasonCount struct {
+	Reason string `json:"reason"`
+	Count  int    `json:"count"`
+}
+
+// AdminDashboard is the aggregated view shown on the admin top page.
+type AdminDashboard struct {
+	Queue              QueueMetrics            `json:"queue"`
+	Workers            DashboardWorkers        `json:"workers"`
+	MetricsStale       bool                    `json:"metrics_stale"` // Redis 障害中で、キュー・ワーカーは最後に取得できた値
+	MetricsAt          *time.Time              `json:"metrics_collected_at"`
+	RecentSystemErrors []DashboardSubmission   `json:"recent_system_errors"`
+	SubmissionRate     DashboardSubmissionRate `json:"submission_rate"`
+	ActiveContests     []contestView           `json:"active_contests"`
+	GeneratedAt        time.Time               `json:"generated_at"`
+}
+
+// DashboardService assembles AdminDashboard and caches it for dashboardCacheTTL.
+// Concurrent requests during a rebuild wait for it and share the result.
+type DashboardService struct {
+	db      *pgxpool.Pool
+	metrics *MetricsService
+
+	mu       sync.Mutex
+	cached   *AdminDashboard
+	cachedAt time.Time
+}
+
+func NewDashboardService(db *pgxpool.Pool, metrics *MetricsService) *DashboardService {
+	return &DashboardService{db: db, metrics: metrics}
+}
+
+// Get returns the cached dashboard, rebuilding it when stale or when refresh is set.
+func (s *DashboardService) Get(ctx context.Context, refresh bool) (*AdminDashboard, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !refresh && s.cached != nil && time.Since(s.cachedAt) < dashboardCacheTTL {
+		return s.cached, nil
+	}
+	d, err := s.build(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	s.cached, s.cachedAt = d, time.Now()
+	return d, nil
+}
+
+func (s *DashboardService) build(ctx context.Context, now time.Time) (*AdminDashboard, error) {
+	d := &AdminDashboard{GeneratedAt: now}
+	m := s.metrics.Overview(ctx)
+	d.Queue = m.Queue
+	d.Workers = summarizeWorkers(m.Workers)
+	d.MetricsStale, d.MetricsAt = m.Stale, m.CollectedAt
+	var err error
+	if d.RecentSystemErrors, err = s.recentSystemErrors(ctx); err != nil {
+		return nil, err
+	}
+	if d.SubmissionRate, err = s.submissionRate(ctx, now); err != nil {
+		return nil, err
+	}
+	if d.ActiveContests, err = s.activeContests(ctx, now); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func summarizeWorkers(workers []WorkerHeartbeat) DashboardWorkers {
+	out := DashboardWorkers{Total: len(workers), Items: workers}
+	if out.Items == nil {
+		out.Items = []WorkerHeartbeat{}
+	}
+	for _, w := range workers {
+		switch w.Status {
+		case "busy":
+			out.Busy++
+		case "starting":
+			out.Starting++
+		default:
+			out.Idle++
+		}
+	}
+	return out
+}
+
+func (s *DashboardService) recentSystemErrors(ctx context.Context) ([]DashboardSubmission, error) {
+	rows, err := s.db.Query(ctx, `
+SELECT s.id, s.user_id, u.username, s.problem_id, p.title, s.language, sr.judged_by, sr.error_message, sr.se_reason, s.created_at, sr.updated_at
+FROM submission_results sr
+JOIN submissions s ON s.id = sr.submission_id
+JOIN users u ON u.id = s.user_id
+JOIN problems p ON p.id = s.problem_id
+WHERE sr.verdict = $1
+ORDER BY sr.updated_at DESC
+LIMIT $2`, VerdictSE, dashboardRecentSELimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []DashboardSubmission{}
+	for rows.Next() {
+		var sub DashboardSubmission
+		if err := rows.Scan(&sub.ID, &sub.UserID, &sub.Username, &sub.ProblemID, &sub.ProblemTitle, &sub.Language,
+			&sub.JudgedBy, &sub.ErrorMessage, &sub.SEReason, &sub.CreatedAt, &sub.JudgedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, sub)
+	}
+	return out, rows.Err()
+}
+
+func (s *DashboardService) submissionRate(ctx context.Context, now time.Time) (DashboardSubmissionRate, error) {
+	rate := DashboardSubmissionRate{WindowMinutes: int(dashboardSubmissionWindow / time.Minute)}
+	err := s.db.QueryRow(ctx, `
+SELECT COUNT(*),
+       COUNT(sr.verdict),
+       COUNT(*) FILTER (WHERE sr.verdict = $2)
+FROM submissions s
+LEFT JOIN submission_results sr ON sr.submission_id = s.id
+WHERE s.created_at >= $1`, now.Add(-dashboardSubmissionWindow), VerdictSE).Scan(&rate.Total, &rate.Judged, &rate.SystemErrors)
+	if err != nil {
+		return rate, err
+	}
+	rate.SystemErrorReasons, err = s.SystemErrorReasons(ctx, now.Add(-dashboardSubmissionWindow))
+	return rate, err
+}
+
+// SystemErrorReasons counts System Errors judged since the given time by reason code, most frequent first.
+func (s *DashboardService) SystemErrorReasons(ctx context.Context, since time.Time) ([]SystemErrorReasonCount, error) {
+	rows, err := s.db.Query(ctx, `
+SELECT COALESCE(se_reason, $3), COUNT(*)
+FROM submission_results
+WHERE verdict = $1 AND updated_at >= $2
+GROUP BY 1
+ORDER BY 2 DESC, 1`, VerdictSE, since, SEReasonUnknown)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []SystemErrorReasonCount{}
+	for rows.Next() {
+		var rc SystemErrorReasonCount
+		if err := rows.Scan(&rc.Reason, &rc.Count); err != nil {
+			return nil, err
+		}
+		out = append(out, rc)
+	}
+	return out, rows.Err()
+}
+
+func (s *DashboardService) activeContests(ctx context.Context, now time.Time) ([]contestView, error) {
+	rows, err := s.db.Query(ctx, `SELECT `+contestColumns+` FROM contests WHERE start_at <= $1 AND end_at > $1 ORDER BY end_at, id`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []contestView{}
+	for rows.Next() {
+		c, err := scanContest(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, newContestView(*c, now))
+	}
+	return out, rows.Err()
+}
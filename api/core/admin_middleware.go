@@ -4,16 +4,13 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/gorilla/sessions"
 )
 
-// AdminOnly ensures the session role is admin.
-func AdminOnly() gin.HandlerFunc {
+// RequireStaff admits sessions whose role holds at least one permission.
+// Route groups below it narrow access further with RequirePermission.
+func RequireStaff() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		sessionAny, _ := c.Get("session")
-		sess, _ := sessionAny.(*sessions.Session)
-		role, _ := sess.Values["role"].(string)
-		if role != "admin" {
+		if !isStaffRole(sessionRole(c)) {
 			respondError(c, http.StatusForbidden, "FORBIDDEN", "管理者権限が必要です")
 			c.Abort()
 			return
@@ -21,3 +18,15 @@ func AdminOnly() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RequirePermission ensures the session role grants perm (e.g. "problems.write").
+func RequirePermission(perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasPermission(sessionRole(c), perm) {
+			respondError(c, http.StatusForbidden, "FORBIDDEN", "この操作の権限がありません ("+perm+")")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,215 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 検知のしきい値。少数の提出で誤検知しないよう最小件数を設ける。
+const (
+	seSpikeWindow       = 10 * time.Minute
+	seSpikeBaseline     = 24 * time.Hour
+	seSpikeMinFailed    = 5
+	seSpikeMinRate      = 0.2
+	seSpikeFactor       = 3.0
+	zeroACEditLookback  = 7 * 24 * time.Hour
+	zeroACMinACBefore   = 3
+	zeroACMinJudged     = 5
+	workerFailureWindow = time.Hour
+	workerMinJudged     = 10
+	workerMinRate       = 0.2
+	workerFactor        = 3.0
+)
+
+// AnomalyMonitor periodically inspects judge results, records incidents and
+// posts newly opened ones to an optional alert webhook.
+type AnomalyMonitor struct {
+	repo       IncidentRepository
+	webhookURL string
+	client     *http.Client
+}
+
+func NewAnomalyMonitor(repo IncidentRepository, webhookURL string) *AnomalyMonitor {
+	return &AnomalyMonitor{
+		repo:       repo,
+		webhookURL: webhookURL,
+		client:     &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Run checks for anomalies every interval until ctx is cancelled.
+func (m *AnomalyMonitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := m.Check(ctx, time.Now()); err != nil {
+			log.Printf("[anomaly] check failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check runs every detector once and returns incidents opened by this run.
+func (m *AnomalyMonitor) Check(ctx context.Context, now time.Time) ([]Incident, error) {
+	found, err := m.detect(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	var opened []Incident
+	for _, in := range found {
+		inc, err := m.Raise(ctx, in)
+		if err != nil {
+			return opened, err
+		}
+		if inc != nil {
+			opened = append(opened, *inc)
+		}
+	}
+	return opened, nil
+}
+
+// Raise records an incident and alerts the webhook when it is newly opened. It returns the incident
+// only when it was opened by this call.
+func (m *AnomalyMonitor) Raise(ctx context.Context, in Incident) (*Incident, error) {
+	inc, created, err := m.repo.Open(ctx, in)
+	if err != nil || !created {
+		return nil, err
+	}
+	log.Printf("[anomaly] incident %d opened: %s", inc.ID, inc.Summary)
+	if err := m.notify(ctx, *inc); err != nil {
+		log.Printf("[anomaly] webhook for incident %d failed: %v", inc.ID, err)
+	}
+	return inc, nil
+}
+
+func (m *AnomalyMonitor) detect(ctx context.Context, now time.Time) ([]Incident, error) {
+	var found []Incident
+
+	recent, baseline, err := m.repo.SystemErrorCounts(ctx, now.Add(-seSpikeWindow), now.Add(-seSpikeBaseline))
+	if err != nil {
+		return nil, err
+	}
+	if isSESpike(recent, baseline) {
+		found = append(found, Incident{
+			Kind:    IncidentKindSESpike,
+			Subject: "global",
+			Summary: fmt.Sprintf("SE が急増しています（直近 %d 分で %d/%d 件）", int(seSpikeWindow.Minutes()), recent.Failed, recent.Total),
+			Details: map[string]any{
+				"window_minutes": int(seSpikeWindow.Minutes()),
+				"recent_total":   recent.Total,
+				"recent_se":      recent.Failed,
+				"baseline_total": baseline.Total,
+				"baseline_se":    baseline.Failed,
+			},
+		})
+	}
+
+	problems, err := m.repo.ProblemEditStats(ctx, now.Add(-zeroACEditLookback))
+	if err != nil {
+		return nil, err
+	}
+	for _, st := range problems {
+		if !isZeroACAfterEdit(st) {
+			continue
+		}
+		found = append(found, Incident{
+			Kind:    IncidentKindProblemZeroAC,
+			Subject: "problem:" + strconv.FormatInt(st.ProblemID, 10),
+			Summary: fmt.Sprintf("問題「%s」が編集後 %d 件連続で AC なし", st.Title, st.JudgedAfter),
+			Details: map[string]any{
+				"problem_id":   st.ProblemID,
+				"edited_at":    st.EditedAt,
+				"ac_before":    st.ACBefore,
+				"judged_after": st.JudgedAfter,
+			},
+		})
+	}
+
+	workers, err := m.repo.WorkerFailureStats(ctx, now.Add(-workerFailureWindow))
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range divergentWorkers(workers) {
+		found = append(found, Incident{
+			Kind:    IncidentKindWorkerFailure,
+			Subject: "worker:" + w.WorkerID,
+			Summary: fmt.Sprintf("ワーカー %s の失敗率が他と比べて高い（%d/%d 件）", w.WorkerID, w.Failed, w.Total),
+			Details: map[string]any{
+				"worker_id":      w.WorkerID,
+				"window_minutes": int(workerFailureWindow.Minutes()),
+				"total":          w.Total,
+				"failed":         w.Failed,
+			},
+		})
+	}
+	return found, nil
+}
+
+// isSESpike reports whether the recent SE rate is both high and well above the baseline.
+func isSESpike(recent, baseline VerdictCounts) bool {
+	if recent.Failed < seSpikeMinFailed || recent.Rate() < seSpikeMinRate {
+		return false
+	}
+	return recent.Rate() >= seSpikeFactor*baseline.Rate()
+}
+
+// isZeroACAfterEdit reports a problem that was solvable before its last edit but has
+// had enough judged submissions since without a single AC.
+func isZeroACAfterEdit(st ProblemEditStat) bool {
+	return st.ACBefore >= zeroACMinACBefore && st.JudgedAfter >= zeroACMinJudged && st.ACAfter == 0
+}
+
+// divergentWorkers returns workers whose failure rate is far above the pooled rate of their peers.
+func divergentWorkers(stats []WorkerFailureStat) []WorkerFailureStat {
+	var all VerdictCounts
+	for _, w := range stats {
+		all.Total += w.Total
+		all.Failed += w.Failed
+	}
+	var res []WorkerFailureStat
+	for _, w := range stats {
+		peers := VerdictCounts{Total: all.Total - w.Total, Failed: all.Failed - w.Failed}
+		if w.Total < workerMinJudged || peers.Total < workerMinJudged {
+			continue
+		}
+		if w.Rate() >= workerMinRate && w.Rate() >= workerFactor*peers.Rate() {
+			res = append(res, w)
+		}
+	}
+	return res
+}
+
+// notify posts the incident to the alert webhook as JSON. No-op when unconfigured.
+func (m *AnomalyMonitor) notify(ctx context.Context, inc Incident) error {
+	if m.webhookURL == "" {
+		return nil
+	}
+	body, err := json.Marshal(map[string]any{"event": "incident.opened", "incident": inc})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
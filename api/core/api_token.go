@@ -0,0 +1,190 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/sessions"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	apiTokenPrefix       = "oj_"
+	apiTokenDisplayLen   = 10 // 一覧で見分けるために保存する先頭部分（oj_ + 7 文字）
+	apiTokenMaxPerUser   = 20
+	apiTokenMaxNameLen   = 100
+	apiTokenMaxLifetime  = 365 * 24 * time.Hour
+	apiTokenTouchLatency = time.Minute // last_used_at の更新間隔
+	// tokenAuthContextKey は Bearer トークンで認証したリクエストに立てる（値はトークン ID）
+	tokenAuthContextKey = "api_token_id"
+)
+
+var (
+	ErrAPITokenLimit   = errors.New("too many api tokens")
+	ErrAPITokenInvalid = errors.New("invalid api token")
+)
+
+// APIToken is a personal access token. The secret itself is only returned by Create.
+type APIToken struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// APITokenOwner is the user a presented token authenticates as.
+type APITokenOwner struct {
+	TokenID  int64
+	Username string
+	Role     string
+}
+
+// APITokenRepository issues, lists, revokes and resolves personal API tokens.
+type APITokenRepository interface {
+	Create(ctx context.Context, userID int64, name string, expiresAt *time.Time) (*APIToken, string, error)
+	List(ctx context.Context, userID int64) ([]APIToken, error)
+	Delete(ctx context.Context, userID, id int64) error
+	// Authenticate resolves a raw token; it returns ErrAPITokenInvalid for unknown or expired tokens
+	// and for tokens of disabled or suspended accounts.
+	Authenticate(ctx context.Context, raw string) (*APITokenOwner, error)
+}
+
+type PgAPITokenRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPgAPITokenRepository(db *pgxpool.Pool) *PgAPITokenRepository {
+	return &PgAPITokenRepository{db: db}
+}
+
+func hashAPIToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+const apiTokenColumns = `id, name, token_prefix, expires_at, last_used_at, created_at`
+
+func scanAPIToken(row pgx.Row) (*APIToken, error) {
+	var t APIToken
+	if err := row.Scan(&t.ID, &t.Name, &t.Prefix, &t.ExpiresAt, &t.LastUsedAt, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Create issues a token and returns it with the raw secret, which is not stored.
+func (r *PgAPITokenRepository) Create(ctx context.Context, userID int64, name string, expiresAt *time.Time) (*APIToken, string, error) {
+	raw := apiTokenPrefix + randomHex(24)
+	// 上限チェックと挿入を 1 文で行う
+	t, err := scanAPIToken(r.db.QueryRow(ctx, `INSERT INTO api_tokens (user_id, name, token_hash, token_prefix, expires_at)
+SELECT $1, $2, $3, $4, $5
+WHERE (SELECT COUNT(*) FROM api_tokens WHERE user_id=$1) < $6
+RETURNING `+apiTokenColumns, userID, name, hashAPIToken(raw), raw[:apiTokenDisplayLen], expiresAt, apiTokenMaxPerUser))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, "", ErrAPITokenLimit
+		}
+		return nil, "", err
+	}
+	return t, raw, nil
+}
+
+func (r *PgAPITokenRepository) List(ctx context.Context, userID int64) ([]APIToken, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+apiTokenColumns+` FROM api_tokens WHERE user_id=$1 ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []APIToken{}
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *t)
+	}
+	return out, rows.Err()
+}
+
+func (r *PgAPITokenRepository) Delete(ctx context.Context, userID, id int64) error {
+	ct, err := r.db.Exec(ctx, `DELETE FROM api_tokens WHERE user_id=$1 AND id=$2`, userID, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PgAPITokenRepository) Authenticate(ctx context.Context, raw string) (*APITokenOwner, error) {
+	if !strings.HasPrefix(raw, apiTokenPrefix) {
+		return nil, ErrAPITokenInvalid
+	}
+	var o APITokenOwner
+	err := r.db.QueryRow(ctx, `SELECT t.id, u.username, u.role
+FROM api_tokens t
+JOIN users u ON u.id = t.user_id
+WHERE t.token_hash=$1 AND u.deleted_at IS NULL AND u.disabled_at IS NULL AND u.is_active AND (t.expires_at IS NULL OR t.expires_at > NOW())`, hashAPIToken(raw)).Scan(&o.TokenID, &o.Username, &o.Role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAPITokenInvalid
+		}
+		return nil, err
+	}
+	// 使用のたびに書き込まないよう間隔をあける
+	_, _ = r.db.Exec(ctx, `UPDATE api_tokens SET last_used_at=NOW()
+WHERE id=$1 AND (last_used_at IS NULL OR last_used_at < NOW() - make_interval(secs => $2))`, o.TokenID, apiTokenTouchLatency.Seconds())
+	return &o, nil
+}
+
+// TokenAuthMiddleware authenticates "Authorization: Bearer <token>" requests. It installs an
+// unsaved session carrying the token owner's userid / role, so handlers and permission checks work
+// unchanged, and SessionMiddleware / CSRFMiddleware skip cookie handling for such requests.
+// Requests without the header fall through to cookie sessions.
+func TokenAuthMiddleware(store *RedisSessionStore, tokens APITokenRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			c.Next()
+			return
+		}
+		scheme, raw, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(raw) == "" {
+			respondError(c, http.StatusUnauthorized, "UNAUTHORIZED", "Authorization ヘッダーの形式が正しくありません")
+			c.Abort()
+			return
+		}
+		owner, err := tokens.Authenticate(c.Request.Context(), strings.TrimSpace(raw))
+		if err != nil {
+			if errors.Is(err, ErrAPITokenInvalid) {
+				respondError(c, http.StatusUnauthorized, "UNAUTHORIZED", "API トークンが無効です")
+			} else {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to verify api token")
+			}
+			c.Abort()
+			return
+		}
+		sess := sessions.NewSession(store, sessionName)
+		sess.Values["userid"] = owner.Username
+		sess.Values["role"] = owner.Role
+		c.Set("session", sess)
+		c.Set(tokenAuthContextKey, owner.TokenID)
+		c.Next()
+	}
+}
+
+// isTokenAuth reports whether the request was authenticated with an API token.
+func isTokenAuth(c *gin.Context) bool {
+	_, ok := c.Get(tokenAuthContextKey)
+	return ok
+}
@@ -0,0 +1,67 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+type stubAPITokenRepo struct {
+	APITokenRepository
+	tokens map[string]APITokenOwner
+}
+
+func (s stubAPITokenRepo) Authenticate(_ context.Context, raw string) (*APITokenOwner, error) {
+	o, ok := s.tokens[raw]
+	if !ok {
+		return nil, ErrAPITokenInvalid
+	}
+	return &o, nil
+}
+
+func TestTokenAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	store := NewRedisSessionStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}), []byte("test-secret-test-secret-test-sec"))
+	repo := stubAPITokenRepo{tokens: map[string]APITokenOwner{"oj_good": {TokenID: 5, Username: "alice", Role: "user"}}}
+	cfg := Config{}
+	r := gin.New()
+	r.Use(TokenAuthMiddleware(store, repo), SessionMiddleware(cfg, store), CSRFMiddleware(cfg, store))
+	r.POST("/whoami", func(c *gin.Context) {
+		userid, ok := requireLogin(c)
+		if !ok {
+			return
+		}
+		c.String(http.StatusOK, userid)
+	})
+
+	cases := []struct {
+		header string
+		code   int
+		body   string
+	}{
+		{"Bearer oj_good", http.StatusOK, "alice"},
+		{"bearer  oj_good ", http.StatusOK, "alice"},
+		{"Bearer oj_bad", http.StatusUnauthorized, ""},
+		{"Basic oj_good", http.StatusUnauthorized, ""},
+		{"Bearer", http.StatusUnauthorized, ""},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/whoami", nil)
+		req.Header.Set("Authorization", tc.header)
+		r.ServeHTTP(w, req)
+		if w.Code != tc.code || (tc.body != "" && w.Body.String() != tc.body) {
+			t.Fatalf("%q: got %d %q", tc.header, w.Code, w.Body.String())
+		}
+		// トークン認証ではセッション Cookie を発行しない
+		if len(w.Result().Cookies()) != 0 {
+			t.Fatalf("%q: unexpected cookies %v", tc.header, w.Result().Cookies())
+		}
+	}
+}
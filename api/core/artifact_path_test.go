@@ -0,0 +1,30 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPathInsideDir(t *testing.T) {
+	abs, err := filepath.Abs(filepath.Join("submissions", "42", "out_01.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		dir, path string
+		want      bool
+	}{
+		{"submissions/42", "submissions/42/out_01.txt", true},
+		{"submissions/42", abs, true},
+		{"./submissions/42", "submissions/42/run/err_01.txt", true},
+		{"submissions/42", "submissions/42", false},
+		{"submissions/42", "submissions/420/out_01.txt", false},
+		{"submissions/42", "submissions/42/../43/out_01.txt", false},
+		{"submissions/42", "/etc/passwd", false},
+	}
+	for _, tc := range cases {
+		if got := pathInsideDir(tc.dir, tc.path); got != tc.want {
+			t.Errorf("pathInsideDir(%q, %q) = %t, want %t", tc.dir, tc.path, got, tc.want)
+		}
+	}
+}
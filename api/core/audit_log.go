@@ -0,0 +1,275 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// auditBodyPeekLimit bounds the JSON request bodies summarized into audit_logs.summary.
+	auditBodyPeekLimit = 64 << 10
+	maxAuditSummaryLen = 1000
+	maxAuditValueLen   = 40
+	auditTargetKey     = "audit_target"
+	auditSummaryKey    = "audit_summary"
+	auditRecordTimeout = 5 * time.Second
+	maxAuditTargetLen  = 128 // audit_logs.target_id
+)
+
+// AuditLogEntry is one privileged (admin) mutation.
+type AuditLogEntry struct {
+	ID         int64     `json:"id"`
+	ActorID    *int64    `json:"actor_id"`
+	Actor      string    `json:"actor"`
+	Action     string    `json:"action"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	TargetType string    `json:"target_type"`
+	TargetID   string    `json:"target_id"`
+	Summary    string    `json:"summary"`
+	IP         string    `json:"ip"`
+	Status     int       `json:"status"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AuditLogFilter narrows GET /admin/audit_logs; zero values do not filter.
+type AuditLogFilter struct {
+	Actor      string
+	Action     string // prefix of the action, e.g. "POST /api/v1/admin/users"
+	TargetType string
+	TargetID   string
+	From       *time.Time
+	To         *time.Time
+}
+
+type AuditLogRepository interface {
+	Record(ctx context.Context, e AuditLogEntry) error
+	// List returns the entries matching f, newest first, with the total count.
+	List(ctx context.Context, f AuditLogFilter, page, perPage int) ([]AuditLogEntry, int, error)
+}
+
+type PgAuditLogRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPgAuditLogRepository(db *pgxpool.Pool) *PgAuditLogRepository {
+	return &PgAuditLogRepository{db: db}
+}
+
+// 実施者の ID は名前から解決する（削除済みでも名前は残る）
+func (r *PgAuditLogRepository) Record(ctx context.Context, e AuditLogEntry) error {
+	_, err := r.db.Exec(ctx, `INSERT INTO audit_logs (actor_id, actor, action, method, path, target_type, target_id, summary, ip, status)
+VALUES ((SELECT id FROM users WHERE username=$1), $1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		e.Actor, e.Action, e.Method, e.Path, e.TargetType, e.TargetID, e.Summary, e.IP, e.Status)
+	return err
+}
+
+func (r *PgAuditLogRepository) List(ctx context.Context, f AuditLogFilter, page, perPage int) ([]AuditLogEntry, int, error) {
+	var conds []string
+	var args []any
+	add := func(cond string, v any) {
+		args = append(args, v)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if f.Actor != "" {
+		add("actor = $%d", f.Actor)
+	}
+	if f.Action != "" {
+		add("starts_with(action, $%d)", f.Action)
+	}
+	if f.TargetType != "" {
+		add("target_type = $%d", f.TargetType)
+	}
+	if f.TargetID != "" {
+		add("target_id = $%d", f.TargetID)
+	}
+	if f.From != nil {
+		add("created_at >= $%d", *f.From)
+	}
+	if f.To != nil {
+		add("created_at < $%d", *f.To)
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM audit_logs`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	args = append(args, perPage, (page-1)*perPage)
+	rows, err := r.db.Query(ctx, `SELECT id, actor_id, actor, action, method, path, target_type, target_id, summary, ip, status, created_at
+FROM audit_logs`+where+fmt.Sprintf(` ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d`, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	items := []AuditLogEntry{}
+	for rows.Next() {
+		var e AuditLogEntry
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.Actor, &e.Action, &e.Method, &e.Path, &e.TargetType, &e.TargetID, &e.Summary, &e.IP, &e.Status, &e.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		items = append(items, e)
+	}
+	return items, total, rows.Err()
+}
+
+// setAuditTarget names the object a handler created or changed, for routes whose path does not
+// carry it (e.g. the id of a newly created record).
+func setAuditTarget(c *gin.Context, targetType string, id any) {
+	c.Set(auditTargetKey, [2]string{targetType, fmt.Sprint(id)})
+}
+
+// setAuditSummary replaces the summary derived from the request body.
+func setAuditSummary(c *gin.Context, format string, args ...any) {
+	c.Set(auditSummaryKey, fmt.Sprintf(format, args...))
+}
+
+// AuditLogMiddleware records every successful mutation (POST / PUT / PATCH / DELETE) under the
+// group into audit_logs: the actor, the route, the target (the first path parameter unless the
+// handler sets one), a summary of the JSON body and the client IP. Values of secret-looking fields
+// are masked. Recording failures are logged and never fail the request.
+func AuditLogMiddleware(repo AuditLogRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			c.Next()
+			return
+		}
+		body := peekAuditBody(c)
+		c.Next()
+		if c.Writer.Status() >= http.StatusBadRequest {
+			return
+		}
+
+		route := c.FullPath()
+		e := AuditLogEntry{
+			Actor:   sessionUsername(c),
+			Action:  c.Request.Method + " " + route,
+			Method:  c.Request.Method,
+			Path:    c.Request.URL.Path,
+			Summary: summarizeAuditBody(body),
+			IP:      c.ClientIP(),
+			Status:  c.Writer.Status(),
+		}
+		e.TargetType, e.TargetID = auditTargetFromRoute(route, c.Params)
+		if v, ok := c.Get(auditTargetKey); ok {
+			t := v.([2]string)
+			e.TargetType, e.TargetID = t[0], t[1]
+		}
+		if v, ok := c.Get(auditSummaryKey); ok {
+			e.Summary = v.(string)
+		}
+		e.TargetID = truncateRunes(e.TargetID, maxAuditTargetLen)
+		e.Summary = truncateRunes(e.Summary, maxAuditSummaryLen)
+
+		// クライアントが切断しても記録は残す
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), auditRecordTimeout)
+		defer cancel()
+		if err := repo.Record(ctx, e); err != nil {
+			log.Printf("[audit] record %s by %q: %v", e.Action, e.Actor, err)
+		}
+	}
+}
+
+// peekAuditBody reads a small JSON request body and puts it back for the handler.
+func peekAuditBody(c *gin.Context) []byte {
+	r := c.Request
+	if r.Body == nil || r.ContentLength <= 0 || r.ContentLength > auditBodyPeekLimit ||
+		!strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		return nil
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, auditBodyPeekLimit))
+	r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(data), r.Body))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// auditTargetFromRoute takes the target from the first path parameter: the static segment before
+// it is the type ("/admin/problems/:id/rejudge" → problems, 12). Without parameters the last
+// static segment is the type and the id stays empty.
+func auditTargetFromRoute(route string, params gin.Params) (string, string) {
+	segments := strings.Split(strings.Trim(route, "/"), "/")
+	last := ""
+	for _, s := range segments {
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+			v, _ := params.Get(s[1:])
+			return last, v
+		}
+		if s != "" {
+			last = s
+		}
+	}
+	return last, ""
+}
+
+// summarizeAuditBody lists the fields of a JSON object as key=value (sorted). Long strings are
+// reduced to their length, nested values to their shape, and secrets are masked.
+func summarizeAuditBody(body []byte) string {
+	var fields map[string]json.RawMessage
+	if len(body) == 0 || json.Unmarshal(body, &fields) != nil {
+		return ""
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+summarizeAuditValue(k, fields[k]))
+	}
+	return strings.Join(parts, " ")
+}
+
+func summarizeAuditValue(key string, raw json.RawMessage) string {
+	lower := strings.ToLower(key)
+	for _, s := range []string{"password", "secret", "token", "code"} {
+		if strings.Contains(lower, s) {
+			return "***"
+		}
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return "?"
+	}
+	switch t := v.(type) {
+	case string:
+		if n := len([]rune(t)); n > maxAuditValueLen {
+			return "(" + strconv.Itoa(n) + " 文字)"
+		}
+		return strconv.Quote(t)
+	case []any:
+		return "[" + strconv.Itoa(len(t)) + " 件]"
+	case map[string]any:
+		return "{…}"
+	default:
+		return string(raw)
+	}
+}
+
+// truncateRunes shortens s to at most n characters, marking the cut with "…".
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type stubAuditLogRepo struct {
+	AuditLogRepository
+	entries []AuditLogEntry
+}
+
+func (s *stubAuditLogRepo) Record(_ context.Context, e AuditLogEntry) error {
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+func TestAuditLogMiddlewareRecordsSuccessfulMutations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &stubAuditLogRepo{}
+	r := gin.New()
+	admin := r.Group("/api/v1/admin", AuditLogMiddleware(repo))
+	admin.PATCH("/problems/:id", func(c *gin.Context) {
+		var req map[string]any
+		_ = c.ShouldBindJSON(&req)
+		c.JSON(http.StatusOK, req)
+	})
+	admin.POST("/notices", func(c *gin.Context) {
+		setAuditTarget(c, "notices", 42)
+		c.Status(http.StatusCreated)
+	})
+	admin.GET("/problems/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	admin.DELETE("/problems/:id", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// ハンドラーは読み戻された本文をそのまま受け取る
+	if w := do(http.MethodPatch, "/api/v1/admin/problems/12", `{"title":"A + B","statement":"`+strings.Repeat("x", 100)+`","api_token":"t"}`); !strings.Contains(w.Body.String(), "A + B") {
+		t.Fatalf("body was not passed to the handler: %s", w.Body.String())
+	}
+	do(http.MethodPost, "/api/v1/admin/notices", `{}`)
+	do(http.MethodGet, "/api/v1/admin/problems/12", "")
+	do(http.MethodDelete, "/api/v1/admin/problems/13", "")
+
+	if len(repo.entries) != 2 {
+		t.Fatalf("entries = %+v", repo.entries)
+	}
+	e := repo.entries[0]
+	if e.Action != "PATCH /api/v1/admin/problems/:id" || e.TargetType != "problems" || e.TargetID != "12" ||
+		e.Summary != `api_token=*** statement=(100 文字) title="A + B"` {
+		t.Fatalf("patch entry = %+v", e)
+	}
+	if e := repo.entries[1]; e.TargetType != "notices" || e.TargetID != "42" {
+		t.Fatalf("create entry = %+v", e)
+	}
+}
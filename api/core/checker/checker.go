@@ -0,0 +1,94 @@
+// Package checker compares a submission's output with the expected output using the built-in
+// checkers. exact compares the whole text ignoring trailing newlines and spaces; eps compares
+// whitespace-separated numbers within an absolute error. Custom checkers run in go-judge and are
+// not handled here.
+package checker
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Built-in checker types.
+const (
+	TypeExact = "exact"
+	TypeEps   = "eps"
+)
+
+// Equal compares with the checker of typ. Unknown types fall back to exact.
+func Equal(actual, expected, typ string, eps float64) bool {
+	if strings.ToLower(strings.TrimSpace(typ)) == TypeEps {
+		return EpsEqual(actual, expected, eps)
+	}
+	return ExactEqual(actual, expected)
+}
+
+// ExactEqual compares the outputs ignoring trailing CR / LF / spaces at the end of the whole text.
+func ExactEqual(actual, expected string) bool {
+	return trimTrailing(actual) == trimTrailing(expected)
+}
+
+// EpsEqual compares the whitespace-separated tokens of both outputs as numbers: the token counts
+// must match and every pair must satisfy NumberEqual. Whitespace is any Unicode space, as with
+// strings.Fields.
+func EpsEqual(actual, expected string, eps float64) bool {
+	for {
+		var a, e string
+		a, actual = nextField(actual)
+		e, expected = nextField(expected)
+		if a == "" || e == "" {
+			return a == e
+		}
+		if !NumberEqual(a, e, eps) {
+			return false
+		}
+	}
+}
+
+// NumberEqual compares two tokens as float64: a token that does not parse (including one out of
+// float64 range) never matches, and otherwise the pair matches unless it differs by more than eps.
+// A NaN difference is never greater than eps, so NaN matches any number.
+func NumberEqual(a, b string, eps float64) bool {
+	x, err1 := strconv.ParseFloat(a, 64)
+	y, err2 := strconv.ParseFloat(b, 64)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return !(math.Abs(x-y) > eps)
+}
+
+// nextField returns the next whitespace-separated field of s and the remainder, like one step of
+// strings.Fields without allocating the whole slice (outputs can be tens of megabytes).
+func nextField(s string) (field, rest string) {
+	i := 0
+	for i < len(s) {
+		r, size := rune(s[i]), 1
+		if r >= utf8.RuneSelf {
+			r, size = utf8.DecodeRuneInString(s[i:])
+		}
+		if !unicode.IsSpace(r) {
+			break
+		}
+		i += size
+	}
+	s = s[i:]
+	j := 0
+	for j < len(s) {
+		r, size := rune(s[j]), 1
+		if r >= utf8.RuneSelf {
+			r, size = utf8.DecodeRuneInString(s[j:])
+		}
+		if unicode.IsSpace(r) {
+			break
+		}
+		j += size
+	}
+	return s[:j], s[j:]
+}
+
+func trimTrailing(s string) string {
+	return strings.TrimRight(s, "\r\n ")
+}
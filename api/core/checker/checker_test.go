@@ -0,0 +1,158 @@
+package checker
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		name     string
+		typ      string
+		eps      float64
+		actual   string
+		expected string
+		want     bool
+	}{
+		{"exact same", TypeExact, 0, "1 2\n3\n", "1 2\n3\n", true},
+		{"exact trailing newline", TypeExact, 0, "42", "42\n", true},
+		{"exact trailing crlf and spaces", TypeExact, 0, "42 \r\n\r\n", "42", true},
+		{"exact inner crlf differs", TypeExact, 0, "1\r\n2", "1\n2", false},
+		{"exact leading space differs", TypeExact, 0, " 42", "42", false},
+		{"exact trailing tab differs", TypeExact, 0, "42\t", "42", false},
+		{"exact both empty", TypeExact, 0, "", "\n", true},
+		{"exact unicode", TypeExact, 0, "こんにちは 世界\n", "こんにちは 世界", true},
+		{"exact unicode differs", TypeExact, 0, "こんにちは", "こんばんは", false},
+		{"exact nfc vs nfd", TypeExact, 0, "é", "é", false},
+		{"unknown type falls back to exact", "custom", 0, "1.0", "1", false},
+		{"type is case insensitive", " EPS ", 1e-6, "1.0", "1", true},
+
+		{"eps within", TypeEps, 1e-6, "0.3333333", "0.33333333", true},
+		{"eps boundary", TypeEps, 0.5, "1.5", "1", true},
+		{"eps outside", TypeEps, 1e-9, "0.333", "0.334", false},
+		{"eps token count", TypeEps, 1e-6, "1 2", "1 2 3", false},
+		{"eps whitespace layout ignored", TypeEps, 1e-6, "1\n2\t3", "1 2 3\n", true},
+		{"eps unicode space separates", TypeEps, 1e-6, "1 2　3", "1 2 3", true},
+		{"eps exponent", TypeEps, 1e-9, "1e-3", "0.001", true},
+		{"eps non number", TypeEps, 1e-6, "abc", "1", false},
+		{"eps identical words rejected", TypeEps, 1e-6, "Yes 1.0", "Yes 1", false},
+		{"eps differing words", TypeEps, 1e-6, "Yes", "No", false},
+		{"eps huge identical integers", TypeEps, 0, "123456789012345678901234567890", "123456789012345678901234567890", true},
+		{"eps out of range tokens", TypeEps, 1e-6, "1e400", "2e400", false},
+		{"eps identical out of range", TypeEps, 1e-6, "1e400", "1e400", false},
+		{"eps nan matches number", TypeEps, 1e-6, "nan", "0", true},
+		{"eps nan vs nan spelled differently", TypeEps, 1e-6, "NaN", "nan", true},
+		{"eps inf vs inf", TypeEps, 1e-6, "inf", "+Inf", true},
+		{"eps inf vs -inf", TypeEps, 1e-6, "inf", "-inf", false},
+		{"eps inf vs large", TypeEps, math.MaxFloat64, "inf", "1e308", false},
+		{"eps negative zero", TypeEps, 0, "-0", "0", true},
+		{"eps negative eps rejects equal", TypeEps, -1, "1", "1.0", false},
+		{"eps empty", TypeEps, 1e-6, " \n", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Equal(tt.actual, tt.expected, tt.typ, tt.eps); got != tt.want {
+				t.Fatalf("Equal(%q, %q) = %v, want %v", tt.actual, tt.expected, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHugeOutputs(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 1_000_000; i++ {
+		b.WriteString(strconv.Itoa(i))
+		b.WriteByte('\n')
+	}
+	expected := b.String()
+	actual := strings.Replace(expected, "\n", " ", -1)
+	if !EpsEqual(actual, expected, 0) {
+		t.Fatal("eps: whitespace layout should not matter")
+	}
+	if ExactEqual(actual, expected) {
+		t.Fatal("exact: layout should matter")
+	}
+	wrong := expected[:len(expected)-len("999999\n")] + "999998\n"
+	if EpsEqual(wrong, expected, 0) {
+		t.Fatal("eps: last token differs")
+	}
+}
+
+// referenceEpsEqual is the strings.Fields implementation the judge used before the extraction.
+func referenceEpsEqual(actual, expected string, eps float64) bool {
+	aa, bb := strings.Fields(actual), strings.Fields(expected)
+	if len(aa) != len(bb) {
+		return false
+	}
+	for i := range aa {
+		x, err1 := strconv.ParseFloat(aa[i], 64)
+		y, err2 := strconv.ParseFloat(bb[i], 64)
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		if math.Abs(x-y) > eps {
+			return false
+		}
+	}
+	return true
+}
+
+func FuzzEpsEqual(f *testing.F) {
+	f.Add("1 2 3", "1 2 3", 1e-6)
+	f.Add("0.1\n0.2", "0.10000001 0.2", 1e-6)
+	f.Add("nan", "nan", 0.0)
+	f.Add("inf -inf", "+inf -Inf", 1.0)
+	f.Add("1e400", "1e401", 1e300)
+	f.Add(" 1 ", "1", 0.0)
+	f.Add("\xff 1", "\xff 1", 0.0)
+	f.Fuzz(func(t *testing.T, actual, expected string, eps float64) {
+		got := EpsEqual(actual, expected, eps)
+		if want := referenceEpsEqual(actual, expected, eps); got != want {
+			t.Fatalf("EpsEqual(%q, %q, %v) = %v, reference %v", actual, expected, eps, got, want)
+		}
+		if EpsEqual(expected, actual, eps) != got {
+			t.Fatalf("EpsEqual is not symmetric for %q, %q", actual, expected)
+		}
+	})
+}
+
+func FuzzExactEqual(f *testing.F) {
+	f.Add("a\nb\n", "a\nb")
+	f.Add("a\r\n", "a")
+	f.Add("こんにちは\n", "こんにちは \r\n")
+	f.Add("", "\n\n")
+	f.Fuzz(func(t *testing.T, actual, expected string) {
+		got := ExactEqual(actual, expected)
+		if want := strings.TrimRight(actual, "\r\n ") == strings.TrimRight(expected, "\r\n "); got != want {
+			t.Fatalf("ExactEqual(%q, %q) = %v, want %v", actual, expected, got, want)
+		}
+	})
+}
+
+func FuzzNextField(f *testing.F) {
+	f.Add("  a b\tc\n")
+	f.Add("　全角　スペース")
+	f.Add("\xffbroken\x85utf8")
+	f.Fuzz(func(t *testing.T, s string) {
+		var got []string
+		for rest := s; ; {
+			var field string
+			field, rest = nextField(rest)
+			if field == "" {
+				break
+			}
+			got = append(got, field)
+		}
+		want := strings.Fields(s)
+		if len(got) != len(want) {
+			t.Fatalf("nextField(%q) = %q, strings.Fields %q", s, got, want)
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("nextField(%q) = %q, strings.Fields %q", s, got, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,194 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Clarification is a participant question within a contest and its answer.
+type Clarification struct {
+	ID           int64      `json:"id"`
+	ContestID    int64      `json:"contest_id"`
+	ProblemID    *int64     `json:"problem_id"`
+	ProblemLabel *string    `json:"problem_label"`
+	UserID       int64      `json:"user_id"`
+	Username     string     `json:"userid"`
+	Question     string     `json:"question"`
+	Answer       *string    `json:"answer"`
+	AnsweredAt   *time.Time `json:"answered_at"`
+	IsPublic     bool       `json:"is_public"`
+	Unread       bool       `json:"unread"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// ClarificationFilter narrows a clarification listing.
+type ClarificationFilter struct {
+	// ViewerID limits results to the viewer's own questions plus public answers.
+	// nil means every clarification (admin view).
+	ViewerID       *int64
+	UnansweredOnly bool
+}
+
+// ClarificationRepository defines persistence operations for contest clarifications.
+type ClarificationRepository interface {
+	List(ctx context.Context, contestID int64, filter ClarificationFilter) ([]Clarification, error)
+	Get(ctx context.Context, contestID, id int64) (*Clarification, error)
+	Create(ctx context.Context, contestID, userID int64, problemID *int64, question string) (*Clarification, error)
+	Answer(ctx context.Context, contestID, id, answeredBy int64, answer string, isPublic bool) (*Clarification, error)
+	Delete(ctx context.Context, contestID, id int64) error
+	UnreadCount(ctx context.Context, contestID, userID int64) (int, error)
+	MarkRead(ctx context.Context, contestID, userID int64) error
+}
+
+// PgClarificationRepository implements ClarificationRepository using pgxpool.
+type PgClarificationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPgClarificationRepository(db *pgxpool.Pool) *PgClarificationRepository {
+	return &PgClarificationRepository{db: db}
+}
+
+// unread: 回答済みかつ既読位置より後に回答されたもの
+const clarificationSelect = `
+SELECT cl.id, cl.contest_id, cl.problem_id, cp.label, cl.user_id, u.username, cl.question, cl.answer, cl.answered_at, cl.is_public,
+       (cl.answered_at IS NOT NULL AND cl.answered_at > COALESCE(rd.last_read_at, 'epoch'::timestamptz)) AS unread,
+       cl.created_at, cl.updated_at
+FROM contest_clarifications cl
+JOIN users u ON u.id = cl.user_id
+LEFT JOIN contest_problems cp ON cp.contest_id = cl.contest_id AND cp.problem_id = cl.problem_id
+LEFT JOIN contest_clarification_reads rd ON rd.contest_id = cl.contest_id AND rd.user_id = $2
+`
+
+func scanClarification(row pgx.Row) (*Clarification, error) {
+	var cl Clarification
+	if err := row.Scan(&cl.ID, &cl.ContestID, &cl.ProblemID, &cl.ProblemLabel, &cl.UserID, &cl.Username, &cl.Question, &cl.Answer, &cl.AnsweredAt, &cl.IsPublic, &cl.Unread, &cl.CreatedAt, &cl.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &cl, nil
+}
+
+func (r *PgClarificationRepository) List(ctx context.Context, contestID int64, filter ClarificationFilter) ([]Clarification, error) {
+	var viewer int64
+	if filter.ViewerID != nil {
+		viewer = *filter.ViewerID
+	}
+	q := clarificationSelect + `WHERE cl.contest_id=$1`
+	args := []any{contestID, viewer}
+	if filter.ViewerID != nil {
+		q += ` AND (cl.user_id=$2 OR (cl.is_public AND cl.answered_at IS NOT NULL))`
+	}
+	if filter.UnansweredOnly {
+		q += ` AND cl.answered_at IS NULL`
+	}
+	q += ` ORDER BY cl.id DESC`
+	rows, err := r.db.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Clarification{}
+	for rows.Next() {
+		cl, err := scanClarification(rows)
+		if err != nil {
+			return nil, err
+		}
+		if filter.ViewerID == nil {
+			cl.Unread = false
+		}
+		items = append(items, *cl)
+	}
+	return items, rows.Err()
+}
+
+func (r *PgClarificationRepository) Get(ctx context.Context, contestID, id int64) (*Clarification, error) {
+	cl, err := scanClarification(r.db.QueryRow(ctx, clarificationSelect+`WHERE cl.contest_id=$1 AND cl.id=$3`, contestID, int64(0), id))
+	if err != nil {
+		return nil, err
+	}
+	cl.Unread = false
+	return cl, nil
+}
+
+func (r *PgClarificationRepository) Create(ctx context.Context, contestID, userID int64, problemID *int64, question string) (*Clarification, error) {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return nil, errors.New("question is required")
+	}
+	if problemID != nil {
+		var one int
+		err := r.db.QueryRow(ctx, `SELECT 1 FROM contest_problems WHERE contest_id=$1 AND problem_id=$2`, contestID, *problemID).Scan(&one)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New("problem " + strconv.FormatInt(*problemID, 10) + " is not part of the contest")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	var id int64
+	if err := r.db.QueryRow(ctx, `INSERT INTO contest_clarifications (contest_id, problem_id, user_id, question)
+VALUES ($1,$2,$3,$4) RETURNING id`, contestID, problemID, userID, question).Scan(&id); err != nil {
+		return nil, err
+	}
+	return r.Get(ctx, contestID, id)
+}
+
+// Answer sets (or overwrites) the answer. isPublic broadcasts it to every participant.
+func (r *PgClarificationRepository) Answer(ctx context.Context, contestID, id, answeredBy int64, answer string, isPublic bool) (*Clarification, error) {
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return nil, errors.New("answer is required")
+	}
+	ct, err := r.db.Exec(ctx, `UPDATE contest_clarifications
+SET answer=$1, answered_by=$2, answered_at=NOW(), is_public=$3
+WHERE contest_id=$4 AND id=$5`, answer, answeredBy, isPublic, contestID, id)
+	if err != nil {
+		return nil, err
+	}
+	if ct.RowsAffected() == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return r.Get(ctx, contestID, id)
+}
+
+func (r *PgClarificationRepository) Delete(ctx context.Context, contestID, id int64) error {
+	ct, err := r.db.Exec(ctx, `DELETE FROM contest_clarifications WHERE contest_id=$1 AND id=$2`, contestID, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// UnreadCount counts answers visible to the user that were answered after their last read.
+func (r *PgClarificationRepository) UnreadCount(ctx context.Context, contestID, userID int64) (int, error) {
+	const q = `
+SELECT COUNT(*)
+FROM contest_clarifications cl
+LEFT JOIN contest_clarification_reads rd ON rd.contest_id = cl.contest_id AND rd.user_id = $2
+WHERE cl.contest_id=$1
+  AND cl.answered_at IS NOT NULL
+  AND (cl.user_id=$2 OR cl.is_public)
+  AND cl.answered_at > COALESCE(rd.last_read_at, 'epoch'::timestamptz)`
+	var n int
+	if err := r.db.QueryRow(ctx, q, contestID, userID).Scan(&n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (r *PgClarificationRepository) MarkRead(ctx context.Context, contestID, userID int64) error {
+	_, err := r.db.Exec(ctx, `INSERT INTO contest_clarification_reads (contest_id, user_id, last_read_at)
+VALUES ($1,$2,NOW())
+ON CONFLICT (contest_id, user_id) DO UPDATE SET last_read_at=NOW()`, contestID, userID)
+	return err
+}
@@ -8,21 +8,75 @@ import (
 
 // Config holds runtime settings for the API process.
 type Config struct {
-	Port                     string   // HTTP listen port (e.g., "3000")
-	SessionKey               string   // Cookie signing/encryption key
-	CookieSecure             bool     // Whether to set Secure flag on session cookie
-	CookieSameSite           string   // SameSite policy: Strict/Lax/None
-	LogDir                   string   // Directory to write application logs
-	DatabaseURL              string   // PostgreSQL DSN
-	RedisURL                 string   // Redis URL (redis://host:port/db)
-	GoJudgeURL               string   // go-judge HTTP endpoint base
-	CSRFSecret               string   // secret for CSRF token generation/validation
-	SubmissionDir            string   // base directory to store submission files
-	WorkerConcurrency        int      // number of worker goroutines (= go-judge parallelism)
-	InitialAdminPasswordPath string   // where to write generated admin password (if empty -> log output)
-	BootstrapAdminEnabled    bool     // whether to run bootstrap admin creation at startup
-	AllowedOrigins           []string // allowed origins for CORS/CSRF origin check
-	CompileTimeLimitMs       int      // per-language compile time limit passed to go-judge
+	Port                      string   // HTTP listen port (e.g., "3000")
+	SessionKey                string   // Cookie signing/encryption key
+	CookieSecure              bool     // Whether to set Secure flag on session cookie
+	CookieSameSite            string   // SameSite policy: Strict/Lax/None
+	LogDir                    string   // Directory to write application logs
+	DatabaseURL               string   // PostgreSQL DSN
+	RedisURL                  string   // Redis URL (redis://host:port/db)
+	GoJudgeURL                string   // go-judge HTTP endpoint base
+	CSRFSecret                string   // secret for CSRF token generation/validation
+	SubmissionDir             string   // base directory to store submission files
+	WorkerConcurrency         int      // number of worker goroutines (= go-judge parallelism)
+	InitialAdminPasswordPath  string   // where to write generated admin password (if empty -> log output)
+	BootstrapAdminEnabled     bool     // whether to run bootstrap admin creation at startup
+	AllowedOrigins            []string // allowed origins for CORS/CSRF origin check
+	CompileTimeLimitMs        int      // per-language compile time limit passed to go-judge
+	AlertWebhookURL           string   // optional URL receiving JSON alerts for detected incidents
+	ClientInfoRetentionDays   int      // days to keep submission IP / user agent (<= 0 keeps forever)
+	ResultSigningKey          string   // shared HMAC key for judge result signatures (empty disables signing)
+	TrashRetentionDays        int      // days deleted notices/problems/users stay restorable (<= 0 keeps forever)
+	ExtraVerdicts             string   // deployment-specific verdicts, CODE[:Label[:kind]] comma-separated (see RegisterExtraVerdicts)
+	ProblemImportMaxMB        int      // size cap of an uploaded problem package zip (direct and resumable uploads)
+	ProblemArchiveMaxMB       int      // cap of the total uncompressed size of a problem package
+	ProblemMaxTestcases       int      // testcases per problem (0 = unlimited)
+	ProblemTestcasesMaxMB     int      // total size of the testcases of a problem (0 = unlimited)
+	ProblemJudgeCostWarnSec   int      // warn when time limit × testcases exceeds this (0 = never)
+	CheckerEpsMin             float64  // smallest accepted checker_eps of the eps checker
+	CheckerEpsMax             float64  // largest accepted checker_eps of the eps checker
+	UploadDir                 string   // directory holding in-progress resumable uploads
+	QueueBackpressureDepth    int      // pending depth above which non-contest submissions are throttled (<= 0 disables)
+	QueueBackpressurePolicy   string   // "delay" (accept with 202 and a longer ETA) or "reject" (429)
+	ListResponseMaxKB         int      // soft cap of list responses; trailing items beyond it are dropped (see ListResponseMiddleware)
+	TranscriptSigningKey      string   // HMAC key for contest result transcripts (empty disables issuing / verifying)
+	JudgeFileStoreDir         string   // go-judge file store (-dir) as mounted in the worker; empty skips measuring it
+	WorkerDiskLimitMB         int      // disk usage (submission dir + go-judge store) above which workers clean up (<= 0 disables)
+	RunOutputRetentionDays    int      // age after which compile / run outputs may be removed by cleanup (<= 0 keeps them)
+	SubmissionRateLimits      string   // per-user submission limits, COUNT/DURATION comma-separated (e.g. "1/10s,60/1h"; empty disables)
+	SubmissionDailyQuota      int      // default cap of submissions per user and day in the user's time zone (<= 0 unlimited)
+	SubmissionContestQuota    int      // default cap of submissions per user and contest (<= 0 unlimited)
+	OAuthPublicURL            string   // external base URL of the site, used for OAuth callbacks, redirects and password reset links (empty disables both)
+	OAuthGitHubClientID       string   // GitHub OAuth app; the provider is enabled when both ID and secret are set
+	OAuthGitHubClientSecret   string   // GitHub OAuth app client secret
+	OAuthGoogleClientID       string   // Google OAuth client; the provider is enabled when both ID and secret are set
+	OAuthGoogleClientSecret   string   // Google OAuth client secret
+	OAuthSignupEnabled        bool     // create an account on the first OAuth login of an unlinked identity
+	OIDCIssuer                string   // OpenID Connect issuer URL (endpoints are discovered); enabled with client ID and secret
+	OIDCClientID              string   // OpenID Connect client ID
+	OIDCClientSecret          string   // OpenID Connect client secret
+	OIDCProviderName          string   // provider key in URLs and linked identities ([a-z0-9_-], up to 16 chars; default "oidc")
+	OIDCDisplayName           string   // label of the provider on the login page (default "SSO")
+	OIDCScopes                string   // requested scopes (default "openid email profile")
+	OIDCUsernameClaim         string   // claim that suggests the username of provisioned accounts (default preferred_username)
+	OIDCAutoProvision         bool     // create an account on the first OIDC login regardless of OAUTH_SIGNUP
+	OIDCRoleClaim             string   // claim (string or list) matched by OIDC_ROLE_RULES (default "groups")
+	OIDCRoleRules             string   // claim-value=role, comma-separated, first match wins (see ParseOIDCRoleRules)
+	OIDCRoleSync              bool     // set the role from the rules on every OIDC login (unmatched users become "user")
+	PasswordLoginDisabled     bool     // reject userid/password login (link an OAuth identity to the admin account first)
+	MailFrom                  string   // sender address of outgoing mail (password reset, email verification)
+	SMTPAddr                  string   // SMTP relay host:port for outgoing mail
+	SMTPUsername              string   // SMTP AUTH PLAIN user (empty sends without authentication)
+	SMTPPassword              string   // SMTP AUTH PLAIN password
+	SendGridAPIKey            string   // send mail through the SendGrid API instead of SMTP
+	EmailVerificationRequired bool     // users (not staff) must verify their email address before submitting
+	TOTPIssuer                string   // issuer shown in authenticator apps for two-factor authentication (default "TUIS OJ")
+	LoginLockoutThreshold     int      // failed password logins that lock an account (<= 0 disables)
+	LoginLockoutMinutes       int      // how long a locked account stays locked, and the window failures are counted in
+	TimeLimitPolicy           string   // multipliers suggested time limits apply to reference runtimes (see ParseTimeLimitPolicy)
+	JudgeSandboxPolicy        string   // per-language go-judge sandbox options (see ParseSandboxPolicies)
+	GoJudgeNetworkURL         string   // go-judge started with -net-share, for policies that enable the network
+	OrphanFileGCMinutes       int      // interval of the orphaned submission directory collection (<= 0 disables)
 }
 
 // Load populates Config from environment variables with sane defaults.
@@ -40,10 +94,64 @@ func Load() Config {
 		SubmissionDir:  firstNonEmpty(os.Getenv("SUBMISSION_DIR"), "./submission-files"),
 		WorkerConcurrency: intFromEnv("WORKER_CONCURRENCY",
 			intFromEnv("GOJUDGE_PARALLELISM", 4)),
-		InitialAdminPasswordPath: firstNonEmpty(os.Getenv("INITIAL_ADMIN_PASSWORD_PATH"), "/run/oj-secrets/initial_admin_password.secret"),
-		BootstrapAdminEnabled:    boolFromEnv("BOOTSTRAP_ADMIN", true),
-		AllowedOrigins:           parseCSV(os.Getenv("ALLOWED_ORIGINS")),
-		CompileTimeLimitMs:       intFromEnv("COMPILE_TIME_LIMIT_MS", 5000),
+		InitialAdminPasswordPath:  firstNonEmpty(os.Getenv("INITIAL_ADMIN_PASSWORD_PATH"), "/run/oj-secrets/initial_admin_password.secret"),
+		BootstrapAdminEnabled:     boolFromEnv("BOOTSTRAP_ADMIN", true),
+		AllowedOrigins:            parseCSV(os.Getenv("ALLOWED_ORIGINS")),
+		CompileTimeLimitMs:        intFromEnv("COMPILE_TIME_LIMIT_MS", 5000),
+		AlertWebhookURL:           os.Getenv("ALERT_WEBHOOK_URL"),
+		ClientInfoRetentionDays:   intFromEnv("CLIENT_INFO_RETENTION_DAYS", 90),
+		ResultSigningKey:          os.Getenv("RESULT_SIGNING_KEY"),
+		TrashRetentionDays:        intFromEnv("TRASH_RETENTION_DAYS", 30),
+		ExtraVerdicts:             os.Getenv("EXTRA_VERDICTS"),
+		ProblemImportMaxMB:        intFromEnv("PROBLEM_IMPORT_MAX_MB", 8),
+		ProblemArchiveMaxMB:       intFromEnv("PROBLEM_ARCHIVE_MAX_MB", 32),
+		ProblemMaxTestcases:       intFromEnv("PROBLEM_MAX_TESTCASES", 100),
+		ProblemTestcasesMaxMB:     intFromEnv("PROBLEM_TESTCASES_MAX_MB", 0),
+		ProblemJudgeCostWarnSec:   intFromEnv("PROBLEM_JUDGE_COST_WARN_SEC", 120),
+		CheckerEpsMin:             floatFromEnv("CHECKER_EPS_MIN", 1e-12),
+		CheckerEpsMax:             floatFromEnv("CHECKER_EPS_MAX", 1),
+		UploadDir:                 firstNonEmpty(os.Getenv("UPLOAD_DIR"), "./upload-files"),
+		QueueBackpressureDepth:    intFromEnv("QUEUE_BACKPRESSURE_DEPTH", 0),
+		QueueBackpressurePolicy:   firstNonEmpty(os.Getenv("QUEUE_BACKPRESSURE_POLICY"), BackpressureDelay),
+		ListResponseMaxKB:         intFromEnv("LIST_RESPONSE_MAX_KB", 1024),
+		TranscriptSigningKey:      os.Getenv("TRANSCRIPT_SIGNING_KEY"),
+		JudgeFileStoreDir:         os.Getenv("GOJUDGE_FILE_STORE_DIR"),
+		WorkerDiskLimitMB:         intFromEnv("WORKER_DISK_LIMIT_MB", 0),
+		RunOutputRetentionDays:    intFromEnv("RUN_OUTPUT_RETENTION_DAYS", 14),
+		SubmissionRateLimits:      os.Getenv("SUBMISSION_RATE_LIMITS"),
+		SubmissionDailyQuota:      intFromEnv("SUBMISSION_DAILY_QUOTA", 0),
+		SubmissionContestQuota:    intFromEnv("SUBMISSION_CONTEST_QUOTA", 0),
+		OAuthPublicURL:            strings.TrimRight(os.Getenv("OAUTH_PUBLIC_URL"), "/"),
+		OAuthGitHubClientID:       os.Getenv("OAUTH_GITHUB_CLIENT_ID"),
+		OAuthGitHubClientSecret:   os.Getenv("OAUTH_GITHUB_CLIENT_SECRET"),
+		OAuthGoogleClientID:       os.Getenv("OAUTH_GOOGLE_CLIENT_ID"),
+		OAuthGoogleClientSecret:   os.Getenv("OAUTH_GOOGLE_CLIENT_SECRET"),
+		OAuthSignupEnabled:        boolFromEnv("OAUTH_SIGNUP", false),
+		OIDCIssuer:                os.Getenv("OIDC_ISSUER"),
+		OIDCClientID:              os.Getenv("OIDC_CLIENT_ID"),
+		OIDCClientSecret:          os.Getenv("OIDC_CLIENT_SECRET"),
+		OIDCProviderName:          os.Getenv("OIDC_PROVIDER_NAME"),
+		OIDCDisplayName:           os.Getenv("OIDC_DISPLAY_NAME"),
+		OIDCScopes:                os.Getenv("OIDC_SCOPES"),
+		OIDCUsernameClaim:         os.Getenv("OIDC_USERNAME_CLAIM"),
+		OIDCAutoProvision:         boolFromEnv("OIDC_AUTO_PROVISION", true),
+		OIDCRoleClaim:             os.Getenv("OIDC_ROLE_CLAIM"),
+		OIDCRoleRules:             os.Getenv("OIDC_ROLE_RULES"),
+		OIDCRoleSync:              boolFromEnv("OIDC_ROLE_SYNC", false),
+		PasswordLoginDisabled:     boolFromEnv("PASSWORD_LOGIN_DISABLED", false),
+		MailFrom:                  firstNonEmpty(os.Getenv("MAIL_FROM"), os.Getenv("SMTP_FROM")),
+		SMTPAddr:                  os.Getenv("SMTP_ADDR"),
+		SMTPUsername:              os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:              os.Getenv("SMTP_PASSWORD"),
+		SendGridAPIKey:            os.Getenv("SENDGRID_API_KEY"),
+		EmailVerificationRequired: boolFromEnv("EMAIL_VERIFICATION_REQUIRED", false),
+		TOTPIssuer:                firstNonEmpty(os.Getenv("TOTP_ISSUER"), "TUIS OJ"),
+		LoginLockoutThreshold:     intFromEnv("LOGIN_LOCKOUT_THRESHOLD", 10),
+		LoginLockoutMinutes:       intFromEnv("LOGIN_LOCKOUT_MINUTES", 15),
+		TimeLimitPolicy:           os.Getenv("TIME_LIMIT_POLICY"),
+		JudgeSandboxPolicy:        os.Getenv("JUDGE_SANDBOX_POLICY"),
+		GoJudgeNetworkURL:         os.Getenv("GOJUDGE_NETWORK_URL"),
+		OrphanFileGCMinutes:       intFromEnv("ORPHAN_FILE_GC_MINUTES", 360),
 	}
 }
 
@@ -76,6 +184,16 @@ func intFromEnv(name string, defaultVal int) int {
 	return defaultVal
 }
 
+// floatFromEnv reads a float64 from env var name, falling back to defaultVal when empty or invalid.
+func floatFromEnv(name string, defaultVal float64) float64 {
+	if v := os.Getenv(name); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return defaultVal
+}
+
 // parseCSV splits comma-separated list and trims spaces; empty entries are skipped.
 func parseCSV(s string) []string {
 	var out []string
@@ -0,0 +1,294 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrAccessCodeInvalid covers unknown, disabled, expired and used-up codes alike so guesses learn nothing.
+	ErrAccessCodeInvalid = errors.New("access code is invalid")
+	ErrAccessCodeExists  = errors.New("access code already exists")
+	// ErrAccessCodeInput wraps validation errors of Create / Update.
+	ErrAccessCodeInput = errors.New("invalid access code")
+)
+
+// 参加コードの総当たり対策: ユーザー / IP ごとに accessCodeFailWindow 内で accessCodeMaxFailures 回失敗したらロックする
+const (
+	accessCodeMaxFailures = 10
+	accessCodeFailWindow  = 15 * time.Minute
+	accessCodeFailKey     = "contest_access_code:fail:"
+)
+
+// 自動生成コードは読み間違えやすい 0/O/1/I を除いた 10 文字
+const (
+	accessCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	accessCodeLength   = 10
+)
+
+var accessCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{6,64}$`)
+
+// ContestAccessCode lets users join a (private) contest without admin approval.
+type ContestAccessCode struct {
+	ID        int64      `json:"id"`
+	ContestID int64      `json:"contest_id"`
+	Code      string     `json:"code"`
+	MaxUses   *int       `json:"max_uses"`
+	UseCount  int        `json:"use_count"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	IsActive  bool       `json:"is_active"`
+	CreatedBy *int64     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ContestAccessCodeInput creates a code; an empty Code is generated.
+type ContestAccessCodeInput struct {
+	ContestID int64
+	Code      string
+	MaxUses   *int
+	ExpiresAt *time.Time
+	CreatedBy int64
+}
+
+// ContestAccessCodeUpdate holds mutable fields of a code.
+type ContestAccessCodeUpdate struct {
+	MaxUses   *int
+	ExpiresAt *time.Time
+	IsActive  *bool
+}
+
+// ContestAccessCodeRepository manages access codes and redeems them into registrations.
+type ContestAccessCodeRepository interface {
+	List(ctx context.Context, contestID int64) ([]ContestAccessCode, error)
+	Create(ctx context.Context, input ContestAccessCodeInput) (*ContestAccessCode, error)
+	Update(ctx context.Context, contestID, id int64, input ContestAccessCodeUpdate) (*ContestAccessCode, error)
+	Delete(ctx context.Context, contestID, id int64) error
+	// Redeem registers the user for the code's contest and returns it. Redeeming again is a no-op
+	// that does not count as another use.
+	Redeem(ctx context.Context, code string, userID int64) (*Contest, error)
+}
+
+type PgContestAccessCodeRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPgContestAccessCodeRepository(db *pgxpool.Pool) *PgContestAccessCodeRepository {
+	return &PgContestAccessCodeRepository{db: db}
+}
+
+// normalizeAccessCode makes codes case-insensitive and tolerant of surrounding spaces.
+func normalizeAccessCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func generateAccessCode() string {
+	b := make([]byte, accessCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return strings.ToUpper(randomHex(accessCodeLength / 2))
+	}
+	for i := range b {
+		b[i] = accessCodeAlphabet[int(b[i])%len(accessCodeAlphabet)]
+	}
+	return string(b)
+}
+
+const accessCodeColumns = `id, contest_id, code, max_uses, use_count, expires_at, is_active, created_by, created_at`
+
+func scanAccessCode(row pgx.Row) (*ContestAccessCode, error) {
+	var a ContestAccessCode
+	if err := row.Scan(&a.ID, &a.ContestID, &a.Code, &a.MaxUses, &a.UseCount, &a.ExpiresAt, &a.IsActive, &a.CreatedBy, &a.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *PgContestAccessCodeRepository) List(ctx context.Context, contestID int64) ([]ContestAccessCode, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+accessCodeColumns+` FROM contest_access_codes WHERE contest_id=$1 ORDER BY id`, contestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []ContestAccessCode{}
+	for rows.Next() {
+		a, err := scanAccessCode(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *a)
+	}
+	return out, rows.Err()
+}
+
+func (r *PgContestAccessCodeRepository) Create(ctx context.Context, input ContestAccessCodeInput) (*ContestAccessCode, error) {
+	if input.MaxUses != nil && *input.MaxUses <= 0 {
+		return nil, fmt.Errorf("%w: max_uses must be positive", ErrAccessCodeInput)
+	}
+	code := normalizeAccessCode(input.Code)
+	generated := code == ""
+	if !generated && !accessCodePattern.MatchString(code) {
+		return nil, fmt.Errorf("%w: code must be 6-64 characters of A-Z, 0-9, '-' or '_'", ErrAccessCodeInput)
+	}
+	const q = `INSERT INTO contest_access_codes (contest_id, code, max_uses, expires_at, created_by)
+VALUES ($1,$2,$3,$4,$5) ON CONFLICT (code) DO NOTHING RETURNING ` + accessCodeColumns
+	// 生成したコードが衝突した場合だけ作り直す
+	for attempt := 0; attempt < 5; attempt++ {
+		if generated {
+			code = generateAccessCode()
+		}
+		a, err := scanAccessCode(r.db.QueryRow(ctx, q, input.ContestID, code, input.MaxUses, input.ExpiresAt, input.CreatedBy))
+		if err == nil {
+			return a, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		if !generated {
+			return nil, ErrAccessCodeExists
+		}
+	}
+	return nil, ErrAccessCodeExists
+}
+
+func (r *PgContestAccessCodeRepository) Update(ctx context.Context, contestID, id int64, input ContestAccessCodeUpdate) (*ContestAccessCode, error) {
+	if input.MaxUses != nil && *input.MaxUses <= 0 {
+		return nil, fmt.Errorf("%w: max_uses must be positive", ErrAccessCodeInput)
+	}
+	return scanAccessCode(r.db.QueryRow(ctx, `UPDATE contest_access_codes
+SET max_uses=COALESCE($3, max_uses), expires_at=COALESCE($4, expires_at), is_active=COALESCE($5, is_active)
+WHERE contest_id=$1 AND id=$2 RETURNING `+accessCodeColumns, contestID, id, input.MaxUses, input.ExpiresAt, input.IsActive))
+}
+
+func (r *PgContestAccessCodeRepository) Delete(ctx context.Context, contestID, id int64) error {
+	ct, err := r.db.Exec(ctx, `DELETE FROM contest_access_codes WHERE contest_id=$1 AND id=$2`, contestID, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PgContestAccessCodeRepository) Redeem(ctx context.Context, code string, userID int64) (*Contest, error) {
+	code = normalizeAccessCode(code)
+	if code == "" {
+		return nil, ErrAccessCodeInvalid
+	}
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// use_count の上限判定を並行する参加と競合させないよう行ロックを取る
+	a, err := scanAccessCode(tx.QueryRow(ctx, `SELECT `+accessCodeColumns+` FROM contest_access_codes WHERE code=$1 FOR UPDATE`, code))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAccessCodeInvalid
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if !a.IsActive || (a.ExpiresAt != nil && !now.Before(*a.ExpiresAt)) {
+		return nil, ErrAccessCodeInvalid
+	}
+	contest, err := scanContest(tx.QueryRow(ctx, `SELECT `+contestColumns+` FROM contests WHERE id=$1`, a.ContestID))
+	if err != nil {
+		return nil, err
+	}
+	if contest.Phase(now) == ContestPhaseEnded {
+		return nil, ErrContestNotOpen
+	}
+
+	var registered bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM contest_registrations WHERE contest_id=$1 AND user_id=$2)`, a.ContestID, userID).Scan(&registered); err != nil {
+		return nil, err
+	}
+	if registered {
+		return contest, nil
+	}
+	if a.MaxUses != nil && a.UseCount >= *a.MaxUses {
+		return nil, ErrAccessCodeInvalid
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO contest_registrations (contest_id, user_id, access_code_id) VALUES ($1,$2,$3) ON CONFLICT DO NOTHING`, a.ContestID, userID, a.ID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE contest_access_codes SET use_count = use_count + 1 WHERE id=$1`, a.ID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return contest, nil
+}
+
+// AccessCodeLimiter counts failed redemptions in Redis and locks out a user / IP that keeps guessing.
+type AccessCodeLimiter struct {
+	client *redis.Client
+}
+
+func NewAccessCodeLimiter(client *redis.Client) *AccessCodeLimiter {
+	return &AccessCodeLimiter{client: client}
+}
+
+// accessCodeFailKeys returns the counters of the caller. userID 0 (not logged in, e.g. invitation codes
+// on registration) counts by IP only.
+func accessCodeFailKeys(userID int64, ip string) []string {
+	if userID == 0 {
+		return []string{accessCodeFailKey + "ip:" + ip}
+	}
+	return []string{fmt.Sprintf("%suser:%d", accessCodeFailKey, userID), accessCodeFailKey + "ip:" + ip}
+}
+
+// Blocked returns how long the caller must wait, or 0 when redemption is allowed.
+func (l *AccessCodeLimiter) Blocked(ctx context.Context, userID int64, ip string) (time.Duration, error) {
+	var wait time.Duration
+	for _, key := range accessCodeFailKeys(userID, ip) {
+		n, err := l.client.Get(ctx, key).Int()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if n < accessCodeMaxFailures {
+			continue
+		}
+		ttl, err := l.client.TTL(ctx, key).Result()
+		if err != nil {
+			return 0, err
+		}
+		if ttl <= 0 {
+			ttl = accessCodeFailWindow
+		}
+		if ttl > wait {
+			wait = ttl
+		}
+	}
+	return wait, nil
+}
+
+// Fail records a failed attempt. The window starts at the first failure.
+func (l *AccessCodeLimiter) Fail(ctx context.Context, userID int64, ip string) error {
+	for _, key := range accessCodeFailKeys(userID, ip) {
+		n, err := l.client.Incr(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+		if n == 1 {
+			if err := l.client.Expire(ctx, key, accessCodeFailWindow).Err(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ContestAnnouncement is an admin announcement scoped to one contest.
+type ContestAnnouncement struct {
+	ID        int64     `json:"id"`
+	ContestID int64     `json:"contest_id"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	Critical  bool      `json:"critical"`
+	CreatedAt time.Time `json:"created_at"`
+	// Acked is the viewer's acknowledgement state (participant view).
+	Acked bool `json:"acked"`
+	// AckCount is filled for the admin view only.
+	AckCount *int `json:"ack_count,omitempty"`
+}
+
+// ContestAnnouncementAck is the acknowledgement state of one participant.
+type ContestAnnouncementAck struct {
+	UserID   int64      `json:"user_id"`
+	Username string     `json:"userid"`
+	AckedAt  *time.Time `json:"acked_at"`
+}
+
+// ContestAnnouncementRepository defines persistence operations for contest announcements.
+type ContestAnnouncementRepository interface {
+	// List returns announcements with id > afterID in ascending order.
+	// viewerID 0 means the admin view (ack counts instead of the viewer's state).
+	List(ctx context.Context, contestID, viewerID, afterID int64) ([]ContestAnnouncement, error)
+	Create(ctx context.Context, contestID, createdBy int64, title, body string, critical bool) (*ContestAnnouncement, error)
+	Delete(ctx context.Context, contestID, id int64) error
+	Ack(ctx context.Context, contestID, id, userID int64) error
+	// AckStatus lists every registered participant with their acknowledgement time.
+	AckStatus(ctx context.Context, contestID, id int64) ([]ContestAnnouncementAck, error)
+}
+
+// PgContestAnnouncementRepository implements ContestAnnouncementRepository using pgxpool.
+type PgContestAnnouncementRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPgContestAnnouncementRepository(db *pgxpool.Pool) *PgContestAnnouncementRepository {
+	return &PgContestAnnouncementRepository{db: db}
+}
+
+func (r *PgContestAnnouncementRepository) List(ctx context.Context, contestID, viewerID, afterID int64) ([]ContestAnnouncement, error) {
+	const q = `
+SELECT a.id, a.contest_id, a.title, a.body, a.is_critical, a.created_at,
+       EXISTS (SELECT 1 FROM contest_announcement_acks k WHERE k.announcement_id = a.id AND k.user_id = $2),
+       (SELECT COUNT(*) FROM contest_announcement_acks k WHERE k.announcement_id = a.id)
+FROM contest_announcements a
+WHERE a.contest_id=$1 AND a.id > $3
+ORDER BY a.id`
+	rows, err := r.db.Query(ctx, q, contestID, viewerID, afterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ContestAnnouncement{}
+	for rows.Next() {
+		var a ContestAnnouncement
+		var ackCount int
+		if err := rows.Scan(&a.ID, &a.ContestID, &a.Title, &a.Body, &a.Critical, &a.CreatedAt, &a.Acked, &ackCount); err != nil {
+			return nil, err
+		}
+		if viewerID == 0 {
+			a.AckCount = &ackCount
+		}
+		items = append(items, a)
+	}
+	return items, rows.Err()
+}
+
+func (r *PgContestAnnouncementRepository) Create(ctx context.Context, contestID, createdBy int64, title, body string, critical bool) (*ContestAnnouncement, error) {
+	title = strings.TrimSpace(title)
+	body = strings.TrimSpace(body)
+	if title == "" || body == "" {
+		return nil, errors.New("title and body are required")
+	}
+	a := ContestAnnouncement{ContestID: contestID, Title: title, Body: body, Critical: critical}
+	const q = `INSERT INTO contest_announcements (contest_id, title, body, is_critical, created_by)
+VALUES ($1,$2,$3,$4,$5) RETURNING id, created_at`
+	if err := r.db.QueryRow(ctx, q, contestID, title, body, critical, createdBy).Scan(&a.ID, &a.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *PgContestAnnouncementRepository) Delete(ctx context.Context, contestID, id int64) error {
+	ct, err := r.db.Exec(ctx, `DELETE FROM contest_announcements WHERE contest_id=$1 AND id=$2`, contestID, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// Ack records the acknowledgement; repeating it keeps the first time.
+func (r *PgContestAnnouncementRepository) Ack(ctx context.Context, contestID, id, userID int64) error {
+	ct, err := r.db.Exec(ctx, `INSERT INTO contest_announcement_acks (announcement_id, user_id)
+SELECT id, $3 FROM contest_announcements WHERE contest_id=$1 AND id=$2
+ON CONFLICT (announcement_id, user_id) DO NOTHING`, contestID, id, userID)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		// 既に確認済みか、アナウンスが存在しない
+		var one int
+		if err := r.db.QueryRow(ctx, `SELECT 1 FROM contest_announcements WHERE contest_id=$1 AND id=$2`, contestID, id).Scan(&one); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *PgContestAnnouncementRepository) AckStatus(ctx context.Context, contestID, id int64) ([]ContestAnnouncementAck, error) {
+	var one int
+	if err := r.db.QueryRow(ctx, `SELECT 1 FROM contest_announcements WHERE contest_id=$1 AND id=$2`, contestID, id).Scan(&one); err != nil {
+		return nil, err
+	}
+	const q = `
+SELECT u.id, u.username, k.acked_at
+FROM contest_registrations cr
+JOIN users u ON u.id = cr.user_id
+LEFT JOIN contest_announcement_acks k ON k.announcement_id = $2 AND k.user_id = cr.user_id
+WHERE cr.contest_id=$1
+ORDER BY k.acked_at NULLS FIRST, u.username`
+	rows, err := r.db.Query(ctx, q, contestID, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ContestAnnouncementAck{}
+	for rows.Next() {
+		var a ContestAnnouncementAck
+		if err := rows.Scan(&a.UserID, &a.Username, &a.AckedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, a)
+	}
+	return items, rows.Err()
+}
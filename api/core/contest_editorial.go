@@ -0,0 +1,192 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ContestEditorial is the editorial of a contest problem. It is the editorial of the problem
+// (see problem_editorial.go) seen from the contest: Public reports whether it is released now and
+// AutoRelease whether it follows the contest end (visibility auto).
+type ContestEditorial struct {
+	ContestID   int64  `json:"contest_id"`
+	ProblemID   int64  `json:"problem_id"`
+	Label       string `json:"label"`
+	EditorialMD string `json:"editorial"`
+	Visibility  string `json:"visibility"`
+	Public      bool   `json:"public"`
+	AutoRelease bool   `json:"auto_release"`
+}
+
+// ContestEditorialInput is a partial update of a contest problem editorial.
+type ContestEditorialInput struct {
+	EditorialMD *string
+	Public      *bool
+	AutoRelease *bool
+}
+
+// ContestEditorialRelease describes editorials published when a contest ended.
+type ContestEditorialRelease struct {
+	Contest Contest
+	Labels  []string
+}
+
+// contestEditorialVisibility maps the public / auto_release flags of the contest routes onto the
+// problem editorial visibility: public wins, then auto_release selects auto over hidden.
+// An omitted flag keeps its current meaning.
+func contestEditorialVisibility(current string, public, autoRelease *bool) string {
+	pub := current == EditorialVisibilityPublic
+	auto := current != EditorialVisibilityHidden
+	if public != nil {
+		pub = *public
+	}
+	if autoRelease != nil {
+		auto = *autoRelease
+	}
+	switch {
+	case pub:
+		return EditorialVisibilityPublic
+	case auto:
+		return EditorialVisibilityAuto
+	default:
+		return EditorialVisibilityHidden
+	}
+}
+
+func (r *PgContestRepository) GetEditorial(ctx context.Context, contestID, problemID int64) (*ContestEditorial, error) {
+	const q = `SELECT cp.contest_id, cp.problem_id, cp.label, p.editorial_md, p.editorial_visibility, ` + editorialReleasedSQL + `
+FROM contest_problems cp
+JOIN problems p ON p.id = cp.problem_id
+WHERE cp.contest_id=$1 AND cp.problem_id=$2 AND p.deleted_at IS NULL`
+	var e ContestEditorial
+	if err := r.db.QueryRow(ctx, q, contestID, problemID).Scan(&e.ContestID, &e.ProblemID, &e.Label, &e.EditorialMD, &e.Visibility, &e.Public); err != nil {
+		return nil, err
+	}
+	e.AutoRelease = e.Visibility == EditorialVisibilityAuto
+	return &e, nil
+}
+
+// SetEditorial updates the editorial of the problem, which every contest listing it shares.
+func (r *PgContestRepository) SetEditorial(ctx context.Context, contestID, problemID int64, input ContestEditorialInput) (*ContestEditorial, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var current string
+	if err := tx.QueryRow(ctx, `SELECT p.editorial_visibility FROM contest_problems cp
+JOIN problems p ON p.id = cp.problem_id
+WHERE cp.contest_id=$1 AND cp.problem_id=$2 AND p.deleted_at IS NULL
+FOR UPDATE OF p`, contestID, problemID).Scan(&current); err != nil {
+		return nil, err
+	}
+	visibility := contestEditorialVisibility(current, input.Public, input.AutoRelease)
+	if _, err := tx.Exec(ctx, `UPDATE problems SET editorial_md=COALESCE($1, editorial_md), editorial_visibility=$2 WHERE id=$3`,
+		input.EditorialMD, visibility, problemID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return r.GetEditorial(ctx, contestID, problemID)
+}
+
+// ReleaseEndedEditorials marks contests that have ended as released and returns the labels whose
+// auto editorials became visible with the end. Rows are claimed with SKIP LOCKED so several
+// API instances can run the releaser concurrently.
+func (r *PgContestRepository) ReleaseEndedEditorials(ctx context.Context, now time.Time) ([]ContestEditorialRelease, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	rows, err := tx.Query(ctx, `SELECT `+contestColumns+` FROM contests
+WHERE editorials_released_at IS NULL AND end_at <= $1
+ORDER BY end_at
+LIMIT 20
+FOR UPDATE SKIP LOCKED`, now)
+	if err != nil {
+		return nil, err
+	}
+	var contests []Contest
+	for rows.Next() {
+		c, err := scanContest(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		contests = append(contests, *c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var released []ContestEditorialRelease
+	for _, c := range contests {
+		lrows, err := tx.Query(ctx, `SELECT cp.label FROM contest_problems cp
+JOIN problems p ON p.id = cp.problem_id
+WHERE cp.contest_id=$1 AND p.deleted_at IS NULL AND p.editorial_visibility='auto' AND p.editorial_md <> ''
+  AND `+editorialReleasedSQL+`
+ORDER BY cp.position, cp.label`, c.ID)
+		if err != nil {
+			return nil, err
+		}
+		var labels []string
+		for lrows.Next() {
+			var label string
+			if err := lrows.Scan(&label); err != nil {
+				lrows.Close()
+				return nil, err
+			}
+			labels = append(labels, label)
+		}
+		lrows.Close()
+		if err := lrows.Err(); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(ctx, `UPDATE contests SET editorials_released_at=$1 WHERE id=$2`, now, c.ID); err != nil {
+			return nil, err
+		}
+		if len(labels) > 0 {
+			released = append(released, ContestEditorialRelease{Contest: c, Labels: labels})
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return released, nil
+}
+
+// RunEditorialReleaser periodically publishes editorials of ended contests and posts a notice
+// for each release. It blocks until ctx is cancelled.
+func RunEditorialReleaser(ctx context.Context, contestRepo ContestRepository, noticeRepo NoticeRepository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		released, err := contestRepo.ReleaseEndedEditorials(ctx, time.Now())
+		if err != nil {
+			log.Printf("[editorial] release failed: %v", err)
+		}
+		for _, rel := range released {
+			title := fmt.Sprintf("%s の解説を公開しました", rel.Contest.Title)
+			body := fmt.Sprintf("コンテスト「%s」の問題 %s の解説を公開しました。", rel.Contest.Title, strings.Join(rel.Labels, ", "))
+			if _, err := noticeRepo.Create(ctx, title, body); err != nil {
+				log.Printf("[editorial] notice for contest %d failed: %v", rel.Contest.ID, err)
+			}
+			log.Printf("[editorial] released contest=%d problems=%v", rel.Contest.ID, rel.Labels)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
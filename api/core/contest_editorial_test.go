@@ -0,0 +1,47 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestContestEditorialVisibility(t *testing.T) {
+	yes, no := true, false
+	cases := []struct {
+		current             string
+		public, autoRelease *bool
+		want                string
+	}{
+		{EditorialVisibilityAuto, nil, nil, EditorialVisibilityAuto},
+		{EditorialVisibilityAuto, &yes, nil, EditorialVisibilityPublic},
+		{EditorialVisibilityAuto, nil, &no, EditorialVisibilityHidden},
+		{EditorialVisibilityHidden, nil, &yes, EditorialVisibilityAuto},
+		{EditorialVisibilityHidden, &no, nil, EditorialVisibilityHidden},
+		{EditorialVisibilityPublic, &no, nil, EditorialVisibilityAuto},
+		{EditorialVisibilityPublic, &no, &no, EditorialVisibilityHidden},
+	}
+	for _, tc := range cases {
+		if got := contestEditorialVisibility(tc.current, tc.public, tc.autoRelease); got != tc.want {
+			t.Errorf("contestEditorialVisibility(%q, %v, %v) = %q, want %q", tc.current, tc.public, tc.autoRelease, got, tc.want)
+		}
+	}
+}
+
+// 練習問題でも、その問題を使うコンテストが終わるまで auto の解説は公開しない
+func TestEditorialReleasedWaitsForListingContests(t *testing.T) {
+	now := time.Now()
+	practice := ProblemVisibility{ProblemID: 1, IsPublic: true}
+	if !practice.EditorialReleased(EditorialVisibilityAuto, now) {
+		t.Fatal("practice editorial should be released")
+	}
+	practice.ContestsEnd = now.Add(time.Hour)
+	if practice.EditorialReleased(EditorialVisibilityAuto, now) {
+		t.Fatal("editorial released while a contest listing the problem is running")
+	}
+	if !practice.EditorialReleased(EditorialVisibilityPublic, now) {
+		t.Fatal("public editorial should be released")
+	}
+	if !practice.EditorialReleased(EditorialVisibilityAuto, now.Add(2*time.Hour)) {
+		t.Fatal("editorial should be released after the contest")
+	}
+}
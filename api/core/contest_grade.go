@@ -0,0 +1,211 @@
+package core
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ErrLatePolicyInput is returned for an invalid late policy.
+var ErrLatePolicyInput = errors.New("invalid late policy")
+
+// LatePolicy is how a contest used as an assignment grades submissions made after its end.
+// Without CutoffAt no late submission is accepted.
+type LatePolicy struct {
+	PenaltyPercent int        `json:"penalty_percent_per_day"`
+	CutoffAt       *time.Time `json:"cutoff_at"`
+}
+
+// ContestGradeCell is a participant's grade on one problem: the best penalized score.
+type ContestGradeCell struct {
+	ProblemID   int64      `json:"problem_id"`
+	Label       string     `json:"label"`
+	RawScore    int        `json:"raw_score"`
+	Score       int        `json:"score"`
+	DaysLate    int        `json:"days_late"`
+	SubmittedAt *time.Time `json:"submitted_at,omitempty"`
+}
+
+// ContestGradeRow is one participant line of the gradebook.
+type ContestGradeRow struct {
+	UserID   int64              `json:"user_id"`
+	Username string             `json:"userid"`
+	Total    int                `json:"total"`
+	MaxTotal int                `json:"max_total"`
+	Cells    []ContestGradeCell `json:"cells"`
+}
+
+// gradeAttempt is one judged contest submission considered for grading.
+type gradeAttempt struct {
+	UserID    int64
+	ProblemID int64
+	Verdict   string
+	Score     *int // 部分点採点のときだけ
+	CreatedAt time.Time
+}
+
+// lateScore applies the late policy to a raw score. Submissions before the end are not
+// penalized; each started day after it costs PenaltyPercent of the score. Submissions at or
+// after the cutoff (or any late one without a cutoff) are not graded.
+func lateScore(raw int, endAt time.Time, policy LatePolicy, at time.Time) (score, daysLate int, ok bool) {
+	if at.Before(endAt) {
+		return raw, 0, true
+	}
+	daysLate = int(at.Sub(endAt)/(24*time.Hour)) + 1
+	if policy.CutoffAt == nil || !at.Before(*policy.CutoffAt) {
+		return 0, daysLate, false
+	}
+	factor := 100 - policy.PenaltyPercent*daysLate
+	if factor < 0 {
+		factor = 0
+	}
+	return raw * factor / 100, daysLate, true
+}
+
+// rawGradeScore is the unpenalized score of an attempt: the partial score when judged with
+// scoring, otherwise full marks for AC.
+func rawGradeScore(a gradeAttempt) int {
+	if a.Score != nil {
+		return *a.Score
+	}
+	if a.Verdict == VerdictAC {
+		return int(problemMaxScore)
+	}
+	return 0
+}
+
+func (c Contest) latePolicy() LatePolicy {
+	return LatePolicy{PenaltyPercent: c.LatePenaltyPercent, CutoffAt: c.LateCutoffAt}
+}
+
+// buildContestGrades grades every participant on the problems assigned to them. Attempts must
+// be ordered by submission time; the earliest of equally scored attempts is kept.
+func buildContestGrades(contest Contest, problems []ContestProblem, participants []ContestRegistration, attempts []gradeAttempt) []ContestGradeRow {
+	policy := contest.latePolicy()
+	type cellKey struct{ userID, problemID int64 }
+	cellIndex := map[cellKey]*ContestGradeCell{}
+	rows := make([]ContestGradeRow, 0, len(participants))
+	for _, p := range participants {
+		assigned := assignContestProblems(contest.ID, p.UserID, problems)
+		row := ContestGradeRow{UserID: p.UserID, Username: p.Username, MaxTotal: len(assigned) * int(problemMaxScore), Cells: make([]ContestGradeCell, len(assigned))}
+		for i, cp := range assigned {
+			row.Cells[i] = ContestGradeCell{ProblemID: cp.ProblemID, Label: cp.Label}
+		}
+		rows = append(rows, row)
+	}
+	for i := range rows {
+		for j := range rows[i].Cells {
+			cellIndex[cellKey{rows[i].UserID, rows[i].Cells[j].ProblemID}] = &rows[i].Cells[j]
+		}
+	}
+	for _, a := range attempts {
+		cell := cellIndex[cellKey{a.UserID, a.ProblemID}]
+		if cell == nil || a.Verdict == "" || a.CreatedAt.Before(contest.StartAt) {
+			continue
+		}
+		raw := rawGradeScore(a)
+		score, days, ok := lateScore(raw, contest.EndAt, policy, a.CreatedAt)
+		if !ok || (cell.SubmittedAt != nil && score <= cell.Score) {
+			continue
+		}
+		at := a.CreatedAt
+		cell.RawScore, cell.Score, cell.DaysLate, cell.SubmittedAt = raw, score, days, &at
+	}
+	for i := range rows {
+		for _, cell := range rows[i].Cells {
+			rows[i].Total += cell.Score
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Username < rows[j].Username })
+	return rows
+}
+
+// SetLatePolicy replaces a contest's late policy. The cutoff must be after the contest end.
+func (r *PgContestRepository) SetLatePolicy(ctx context.Context, id int64, policy LatePolicy) (*Contest, error) {
+	if policy.PenaltyPercent < 0 || policy.PenaltyPercent > 100 {
+		return nil, fmt.Errorf("%w: penalty_percent_per_day must be between 0 and 100", ErrLatePolicyInput)
+	}
+	current, err := r.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if policy.CutoffAt != nil && !policy.CutoffAt.After(current.EndAt) {
+		return nil, fmt.Errorf("%w: cutoff_at must be after end_at", ErrLatePolicyInput)
+	}
+	return scanContest(r.db.QueryRow(ctx, `UPDATE contests SET late_penalty_percent=$2, late_cutoff_at=$3, updated_at=NOW()
+WHERE id=$1 RETURNING `+contestColumns, id, policy.PenaltyPercent, policy.CutoffAt))
+}
+
+// Grades computes the gradebook of a contest from its judged submissions.
+func (r *PgContestRepository) Grades(ctx context.Context, contest Contest, problems []ContestProblem) ([]ContestGradeRow, error) {
+	participants, err := r.listParticipants(ctx, contest.ID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.Query(ctx, `SELECT s.user_id, s.problem_id, COALESCE(sr.verdict, ''), sr.score, s.created_at
+FROM submissions s
+LEFT JOIN submission_results sr ON sr.submission_id = s.id
+WHERE s.contest_id=$1
+ORDER BY s.created_at, s.id`, contest.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var attempts []gradeAttempt
+	for rows.Next() {
+		var a gradeAttempt
+		var score *int32
+		if err := rows.Scan(&a.UserID, &a.ProblemID, &a.Verdict, &score, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		a.Score = intPtrFromInt32(score)
+		attempts = append(attempts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return buildContestGrades(contest, problems, participants, attempts), nil
+}
+
+// writeContestGradebookCSV writes one line per participant with a column per contest problem
+// (blank when the problem was not assigned to them) and the penalized total.
+func writeContestGradebookCSV(w io.Writer, problems []ContestProblem, rows []ContestGradeRow) error {
+	cw := csv.NewWriter(w)
+	header := []string{"userid"}
+	for _, p := range problems {
+		header = append(header, p.Label)
+	}
+	header = append(header, "late_days", "total", "max_total")
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		byProblem := make(map[int64]ContestGradeCell, len(row.Cells))
+		lateDays := 0
+		for _, cell := range row.Cells {
+			byProblem[cell.ProblemID] = cell
+			if cell.DaysLate > lateDays {
+				lateDays = cell.DaysLate
+			}
+		}
+		record := []string{row.Username}
+		for _, p := range problems {
+			if cell, ok := byProblem[p.ProblemID]; ok {
+				record = append(record, strconv.Itoa(cell.Score))
+			} else {
+				record = append(record, "")
+			}
+		}
+		record = append(record, strconv.Itoa(lateDays), strconv.Itoa(row.Total), strconv.Itoa(row.MaxTotal))
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildContestGradesAppliesLatePenalty(t *testing.T) {
+	start := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	end := start.Add(7 * 24 * time.Hour)
+	cutoff := end.Add(3 * 24 * time.Hour)
+	contest := Contest{ID: 1, ScoringMode: ScoringModeICPC, StartAt: start, EndAt: end, LatePenaltyPercent: 10, LateCutoffAt: &cutoff}
+	problems := []ContestProblem{{ProblemID: 1, Label: "A"}, {ProblemID: 2, Label: "B"}, {ProblemID: 3, Label: "C"}}
+	participants := []ContestRegistration{{UserID: 7, Username: "bob"}, {UserID: 8, Username: "alice"}}
+	score := func(n int) *int { return &n }
+	attempts := []gradeAttempt{
+		{UserID: 7, ProblemID: 1, Verdict: VerdictWA, CreatedAt: end.Add(-time.Hour)},
+		// 1 時間遅れでも 1 日分の減点
+		{UserID: 7, ProblemID: 1, Verdict: VerdictAC, CreatedAt: end.Add(time.Hour)},
+		{UserID: 7, ProblemID: 2, Verdict: VerdictAC, CreatedAt: end.Add(-time.Minute)},
+		// 遅れて取った AC より期限内の部分点が高ければそちらを残す
+		{UserID: 7, ProblemID: 3, Verdict: VerdictWA, Score: score(95), CreatedAt: end.Add(-2 * time.Hour)},
+		{UserID: 7, ProblemID: 3, Verdict: VerdictAC, CreatedAt: end.Add(25 * time.Hour)},
+		// 締切後は採点しない
+		{UserID: 8, ProblemID: 1, Verdict: VerdictAC, CreatedAt: cutoff},
+		{UserID: 8, ProblemID: 2, Verdict: "", CreatedAt: end.Add(-time.Hour)},
+	}
+
+	rows := buildContestGrades(contest, problems, participants, attempts)
+	if len(rows) != 2 || rows[0].Username != "alice" || rows[1].Username != "bob" {
+		t.Fatalf("unexpected rows %+v", rows)
+	}
+	if rows[0].Total != 0 || rows[0].MaxTotal != 300 {
+		t.Fatalf("alice: %+v", rows[0])
+	}
+	bob := rows[1]
+	want := []struct{ score, days int }{{90, 1}, {100, 0}, {95, 0}}
+	for i, w := range want {
+		if cell := bob.Cells[i]; cell.Score != w.score || cell.DaysLate != w.days {
+			t.Fatalf("bob %s: got %+v, want score %d days %d", cell.Label, cell, w.score, w.days)
+		}
+	}
+	if bob.Total != 285 {
+		t.Fatalf("bob total %d", bob.Total)
+	}
+
+	// 締切がなければ遅延提出は採点しない
+	contest.LateCutoffAt = nil
+	if rows := buildContestGrades(contest, problems, participants, attempts); rows[1].Cells[0].Score != 0 {
+		t.Fatalf("late submission graded without a cutoff: %+v", rows[1].Cells[0])
+	}
+}
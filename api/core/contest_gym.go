@@ -0,0 +1,359 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	// ErrContestNotEnded is returned when a gym is cloned from a contest that has not ended yet.
+	ErrContestNotEnded = errors.New("contest has not ended")
+	// ErrGymSessionRunning is returned when a user starts a session while another one is still running.
+	ErrGymSessionRunning = errors.New("gym session already running")
+)
+
+// Gym is a practice mirror of a finished contest. Anyone can start a session and solve the problem
+// set within the original duration; there is no registration and it does not affect ratings.
+type Gym struct {
+	ID              int64     `json:"id"`
+	SourceContestID *int64    `json:"source_contest_id"`
+	Title           string    `json:"title"`
+	DescriptionMD   string    `json:"description"`
+	ScoringMode     string    `json:"scoring_mode"`
+	DurationMinutes int       `json:"duration_minutes"`
+	IsPublic        bool      `json:"is_public"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// GymSession is one timed attempt of a gym by a user.
+type GymSession struct {
+	ID        int64     `json:"id"`
+	GymID     int64     `json:"gym_id"`
+	UserID    int64     `json:"user_id"`
+	Username  string    `json:"userid"`
+	StartedAt time.Time `json:"started_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	Running   bool      `json:"running"`
+}
+
+// GymStandingRow is one session line of a gym scoreboard. Times are relative to the session start.
+type GymStandingRow struct {
+	SessionID int64     `json:"session_id"`
+	StartedAt time.Time `json:"started_at"`
+	Running   bool      `json:"running"`
+	ContestStandingRow
+}
+
+// GymCreateInput overrides the fields copied from the source contest.
+type GymCreateInput struct {
+	Title    *string
+	IsPublic *bool
+}
+
+// GymRepository stores gyms and their sessions.
+type GymRepository interface {
+	// CreateFromContest clones the problem set, scoring mode and duration of an ended contest.
+	CreateFromContest(ctx context.Context, contestID, createdBy int64, input GymCreateInput) (*Gym, error)
+	List(ctx context.Context, includeHidden bool, page, perPage int) ([]Gym, int, error)
+	Get(ctx context.Context, id int64) (*Gym, error)
+	Delete(ctx context.Context, id int64) error
+	ListProblems(ctx context.Context, id int64) ([]ContestProblem, error)
+	// StartSession begins a new attempt; only one session per user may run at a time.
+	StartSession(ctx context.Context, gymID, userID int64) (*GymSession, error)
+	GetSession(ctx context.Context, id int64) (*GymSession, error)
+	ListUserSessions(ctx context.Context, gymID, userID int64) ([]GymSession, error)
+	Standings(ctx context.Context, gym Gym, problems []ContestProblem) ([]GymStandingRow, error)
+}
+
+type PgGymRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPgGymRepository(db *pgxpool.Pool) *PgGymRepository {
+	return &PgGymRepository{db: db}
+}
+
+const gymColumns = `id, source_contest_id, title, description_md, scoring_mode, duration_minutes, is_public, created_at`
+
+func scanGym(row pgx.Row) (*Gym, error) {
+	var g Gym
+	if err := row.Scan(&g.ID, &g.SourceContestID, &g.Title, &g.DescriptionMD, &g.ScoringMode, &g.DurationMinutes, &g.IsPublic, &g.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+func (r *PgGymRepository) CreateFromContest(ctx context.Context, contestID, createdBy int64, input GymCreateInput) (*Gym, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	contest, err := scanContest(tx.QueryRow(ctx, `SELECT `+contestColumns+` FROM contests WHERE id=$1`, contestID))
+	if err != nil {
+		return nil, err
+	}
+	if contest.Phase(time.Now()) != ContestPhaseEnded {
+		return nil, ErrContestNotEnded
+	}
+	title := contest.Title
+	if input.Title != nil {
+		title = strings.TrimSpace(*input.Title)
+		if title == "" {
+			return nil, errors.New("title must not be empty")
+		}
+	}
+	isPublic := contest.IsPublic
+	if input.IsPublic != nil {
+		isPublic = *input.IsPublic
+	}
+	duration := int(contest.EndAt.Sub(contest.StartAt) / time.Minute)
+	if duration <= 0 {
+		duration = 1
+	}
+
+	gym, err := scanGym(tx.QueryRow(ctx, `INSERT INTO contest_gyms (source_contest_id, title, description_md, scoring_mode, duration_minutes, is_public, created_by)
+VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING `+gymColumns,
+		contest.ID, title, contest.DescriptionMD, contest.ScoringMode, duration, isPublic, createdBy))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO contest_gym_problems (gym_id, problem_id, label, position)
+SELECT $1, cp.problem_id, cp.label, cp.position
+FROM contest_problems cp
+JOIN problems p ON p.id = cp.problem_id
+WHERE cp.contest_id=$2 AND p.deleted_at IS NULL`, gym.ID, contest.ID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return gym, nil
+}
+
+// List returns gyms ordered by creation (newest first).
+func (r *PgGymRepository) List(ctx context.Context, includeHidden bool, page, perPage int) ([]Gym, int, error) {
+	if page <= 0 || perPage <= 0 {
+		return nil, 0, errors.New("invalid pagination")
+	}
+	where := "WHERE is_public = TRUE"
+	if includeHidden {
+		where = ""
+	}
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM contest_gyms `+where).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := r.db.Query(ctx, `SELECT `+gymColumns+` FROM contest_gyms `+where+` ORDER BY id DESC LIMIT $1 OFFSET $2`, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	items := make([]Gym, 0, perPage)
+	for rows.Next() {
+		g, err := scanGym(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		items = append(items, *g)
+	}
+	return items, total, rows.Err()
+}
+
+func (r *PgGymRepository) Get(ctx context.Context, id int64) (*Gym, error) {
+	return scanGym(r.db.QueryRow(ctx, `SELECT `+gymColumns+` FROM contest_gyms WHERE id=$1`, id))
+}
+
+func (r *PgGymRepository) Delete(ctx context.Context, id int64) error {
+	ct, err := r.db.Exec(ctx, `DELETE FROM contest_gyms WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// ListProblems returns gym problems ordered by position then label.
+func (r *PgGymRepository) ListProblems(ctx context.Context, id int64) ([]ContestProblem, error) {
+	const q = `
+SELECT gp.problem_id, gp.label, gp.position, p.slug, p.title, p.title_ja, p.title_en, p.time_limit_ms, p.memory_limit_kb
+FROM contest_gym_problems gp
+JOIN problems p ON p.id = gp.problem_id
+WHERE gp.gym_id=$1 AND p.deleted_at IS NULL
+ORDER BY gp.position, gp.label`
+	rows, err := r.db.Query(ctx, q, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []ContestProblem{}
+	for rows.Next() {
+		var p ContestProblem
+		if err := rows.Scan(&p.ProblemID, &p.Label, &p.Position, &p.Slug, &p.Title, &p.TitleJA, &p.TitleEN, &p.TimeLimitMS, &p.MemoryLimitKB); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+const gymSessionSelect = `
+SELECT gs.id, gs.gym_id, gs.user_id, u.username, gs.started_at, gs.ends_at
+FROM contest_gym_sessions gs
+JOIN users u ON u.id = gs.user_id
+`
+
+func scanGymSession(row pgx.Row, now time.Time) (*GymSession, error) {
+	var s GymSession
+	if err := row.Scan(&s.ID, &s.GymID, &s.UserID, &s.Username, &s.StartedAt, &s.EndsAt); err != nil {
+		return nil, err
+	}
+	s.Running = now.Before(s.EndsAt)
+	return &s, nil
+}
+
+func (r *PgGymRepository) StartSession(ctx context.Context, gymID, userID int64) (*GymSession, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var duration int
+	if err := tx.QueryRow(ctx, `SELECT duration_minutes FROM contest_gyms WHERE id=$1 FOR UPDATE`, gymID).Scan(&duration); err != nil {
+		return nil, err
+	}
+	var running int
+	if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM contest_gym_sessions WHERE gym_id=$1 AND user_id=$2 AND ends_at > NOW()`, gymID, userID).Scan(&running); err != nil {
+		return nil, err
+	}
+	if running > 0 {
+		return nil, ErrGymSessionRunning
+	}
+	var id int64
+	if err := tx.QueryRow(ctx, `INSERT INTO contest_gym_sessions (gym_id, user_id, started_at, ends_at)
+VALUES ($1, $2, NOW(), NOW() + make_interval(mins => $3)) RETURNING id`, gymID, userID, duration).Scan(&id); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return r.GetSession(ctx, id)
+}
+
+func (r *PgGymRepository) GetSession(ctx context.Context, id int64) (*GymSession, error) {
+	return scanGymSession(r.db.QueryRow(ctx, gymSessionSelect+`WHERE gs.id=$1`, id), time.Now())
+}
+
+// ListUserSessions returns the sessions of a user, newest first.
+func (r *PgGymRepository) ListUserSessions(ctx context.Context, gymID, userID int64) ([]GymSession, error) {
+	rows, err := r.db.Query(ctx, gymSessionSelect+`WHERE gs.gym_id=$1 AND gs.user_id=$2 ORDER BY gs.id DESC`, gymID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	now := time.Now()
+	out := []GymSession{}
+	for rows.Next() {
+		s, err := scanGymSession(rows, now)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *s)
+	}
+	return out, rows.Err()
+}
+
+// Standings computes the scoreboard with one row per session. Each session is scored against its
+// own start time, and only submissions made before the session ended are counted.
+func (r *PgGymRepository) Standings(ctx context.Context, gym Gym, problems []ContestProblem) ([]GymStandingRow, error) {
+	rows, err := r.db.Query(ctx, gymSessionSelect+`WHERE gs.gym_id=$1`, gym.ID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	sessions := map[int64]*GymSession{}
+	// buildStandings は UserID で行をまとめるため、セッション ID を UserID として渡す
+	var participants []ContestRegistration
+	for rows.Next() {
+		s, err := scanGymSession(rows, now)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		sessions[s.ID] = s
+		participants = append(participants, ContestRegistration{UserID: s.ID, Username: s.Username, RegisteredAt: s.StartedAt})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	subRows, err := r.db.Query(ctx, `SELECT s.gym_session_id, s.problem_id, s.created_at, COALESCE(sr.verdict, ''), sr.score
+FROM submissions s
+JOIN contest_gym_sessions gs ON gs.id = s.gym_session_id
+LEFT JOIN submission_results sr ON sr.submission_id = s.id
+WHERE gs.gym_id=$1 AND s.created_at < gs.ends_at
+ORDER BY s.gym_session_id, s.problem_id, s.created_at, s.id`, gym.ID)
+	if err != nil {
+		return nil, err
+	}
+	type cellKey struct{ sessionID, problemID int64 }
+	var keys []cellKey
+	attempts := map[cellKey][]standingAttempt{}
+	for subRows.Next() {
+		var key cellKey
+		var a standingAttempt
+		var score *int32
+		if err := subRows.Scan(&key.sessionID, &key.problemID, &a.CreatedAt, &a.Verdict, &score); err != nil {
+			subRows.Close()
+			return nil, err
+		}
+		if score != nil {
+			v := int(*score)
+			a.Score = &v
+		}
+		if _, ok := attempts[key]; !ok {
+			keys = append(keys, key)
+		}
+		attempts[key] = append(attempts[key], a)
+	}
+	subRows.Close()
+	if err := subRows.Err(); err != nil {
+		return nil, err
+	}
+
+	cells := make([]ContestStandingCell, 0, len(keys))
+	for _, key := range keys {
+		s, ok := sessions[key.sessionID]
+		if !ok {
+			continue
+		}
+		cell := computeStandingCell(gym.ScoringMode, defaultPenaltyPolicy(), s.StartedAt, attempts[key])
+		cell.UserID = s.ID
+		cell.Username = s.Username
+		cell.ProblemID = key.problemID
+		cells = append(cells, cell)
+	}
+
+	built := buildStandings(gym.ScoringMode, defaultPenaltyPolicy(), problems, participants, cells)
+	out := make([]GymStandingRow, 0, len(built))
+	for _, row := range built {
+		s := sessions[row.UserID]
+		row.UserID = s.UserID
+		// セッションごとに開始時刻が違うため、最速正解の印は付けない
+		for i := range row.Cells {
+			row.Cells[i].FirstSolve = false
+		}
+		out = append(out, GymStandingRow{SessionID: s.ID, StartedAt: s.StartedAt, Running: s.Running, ContestStandingRow: row})
+	}
+	return out, nil
+}
@@ -0,0 +1,281 @@
+package core
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	maxContestImportSize   = 64 * 1024 * 1024 // upload payload limit
+	maxContestPackageFiles = 64
+)
+
+// ContestPackage is a parsed contest archive: contest.yaml plus problem archives.
+// Expected layout (トップフォルダ = contest slug):
+//
+//	contest.yaml (required)
+//	problems/<problem-slug>.zip (problem package, same format as problem import)
+type ContestPackage struct {
+	Slug          string
+	Title         string
+	DescriptionMD string
+	StartAt       time.Time
+	EndAt         time.Time
+	IsPublic      bool
+	ScoringMode   string
+	Penalty       PenaltyPolicy
+	Late          LatePolicy
+	Problems      []ContestPackageProblem
+}
+
+// ContestPackageProblem is one problem entry of a contest package.
+type ContestPackageProblem struct {
+	Label   string
+	Slug    string
+	Pool    string
+	Problem ProblemCreateInput
+}
+
+type contestDoc struct {
+	Slug        string    `yaml:"slug"`
+	Title       string    `yaml:"title"`
+	Description string    `yaml:"description"`
+	StartAt     time.Time `yaml:"start_at"`
+	EndAt       time.Time `yaml:"end_at"`
+	ScoringMode string    `yaml:"scoring_mode"`
+	// Penalty（任意）: ICPC のペナルティ規則。省略した項目は既定値（20 分・CE は数えない・last_solve）
+	Penalty struct {
+		Minutes            *int   `yaml:"minutes_per_wrong_attempt"`
+		CountCompileErrors bool   `yaml:"count_compile_errors"`
+		TieBreak           string `yaml:"tie_break"`
+	} `yaml:"penalty"`
+	// Late（任意）: 遅延提出。cutoff_at まで終了後の提出を受け付け、1 日ごとに penalty_percent_per_day を減点する
+	Late struct {
+		PenaltyPercent int        `yaml:"penalty_percent_per_day"`
+		CutoffAt       *time.Time `yaml:"cutoff_at"`
+	} `yaml:"late"`
+	Visibility struct {
+		Public *bool `yaml:"public"`
+	} `yaml:"visibility"`
+	Problems []struct {
+		Label string `yaml:"label"`
+		Slug  string `yaml:"slug"`
+		Pool  string `yaml:"pool"`
+	} `yaml:"problems"`
+}
+
+// ParseContestArchive converts a contest zip into ContestPackage.
+func ParseContestArchive(ctx context.Context, data []byte, gen *TestcaseGenerator) (ContestPackage, error) {
+	if len(data) < 4 || !bytes.Equal(data[:4], []byte{'P', 'K', 0x03, 0x04}) {
+		return ContestPackage{}, errors.New("zip 形式のみ対応しています")
+	}
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return ContestPackage{}, fmt.Errorf("zip を展開できません: %w", err)
+	}
+
+	files := map[string][]byte{}
+	roots := map[string]struct{}{}
+	var total int64
+	for _, f := range reader.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if len(files) >= maxContestPackageFiles {
+			return ContestPackage{}, fmt.Errorf("エントリ数が多すぎます (%d 上限)", maxContestPackageFiles)
+		}
+		norm := normalizeArchivePath(f.Name)
+		if strings.HasPrefix(norm, "/") || strings.Contains(norm, "../") {
+			return ContestPackage{}, errors.New("不正なパスが含まれています")
+		}
+		parts := strings.SplitN(norm, "/", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return ContestPackage{}, errors.New("トップフォルダが必要です (contest slug と一致させてください)")
+		}
+		roots[parts[0]] = struct{}{}
+		rc, err := f.Open()
+		if err != nil {
+			return ContestPackage{}, fmt.Errorf("%s を開けません: %w", f.Name, err)
+		}
+		content, err := io.ReadAll(io.LimitReader(rc, maxContestImportSize+1))
+		rc.Close()
+		if err != nil {
+			return ContestPackage{}, fmt.Errorf("%s の読み込みに失敗しました: %w", f.Name, err)
+		}
+		total += int64(len(content))
+		if total > maxContestImportSize {
+			return ContestPackage{}, errors.New("展開後サイズが大きすぎます")
+		}
+		files[parts[1]] = content
+	}
+	if len(roots) != 1 {
+		return ContestPackage{}, errors.New("トップフォルダは1つにまとめてください")
+	}
+	var root string
+	for k := range roots {
+		root = k
+	}
+
+	raw, ok := files["contest.yaml"]
+	if !ok {
+		return ContestPackage{}, errors.New("contest.yaml が見つかりません")
+	}
+	var doc contestDoc
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return ContestPackage{}, fmt.Errorf("contest.yaml の形式が不正です: %w", err)
+	}
+	slug := normalizeSlug(doc.Slug)
+	if slug == "" {
+		return ContestPackage{}, errors.New("slug は必須です (英小文字・数字・ハイフンのみ)")
+	}
+	if slug != normalizeSlug(root) {
+		return ContestPackage{}, errors.New("zip のトップフォルダ名と slug が一致していません")
+	}
+	if strings.TrimSpace(doc.Title) == "" {
+		return ContestPackage{}, errors.New("title は必須です")
+	}
+	if doc.StartAt.IsZero() || doc.EndAt.IsZero() || !doc.EndAt.After(doc.StartAt) {
+		return ContestPackage{}, errors.New("start_at / end_at が不正です (RFC3339, end_at > start_at)")
+	}
+	mode, err := normalizeScoringMode(doc.ScoringMode)
+	if err != nil {
+		return ContestPackage{}, errors.New("scoring_mode は icpc または ioi を指定してください")
+	}
+	penalty := defaultPenaltyPolicy()
+	if doc.Penalty.Minutes != nil {
+		penalty.Minutes = *doc.Penalty.Minutes
+	}
+	penalty.CountCompileErrors = doc.Penalty.CountCompileErrors
+	if tb := strings.TrimSpace(doc.Penalty.TieBreak); tb != "" {
+		penalty.TieBreak = tb
+	}
+	if err := penalty.validate(); err != nil {
+		return ContestPackage{}, fmt.Errorf("penalty が不正です: %w", err)
+	}
+	if doc.Late.PenaltyPercent < 0 || doc.Late.PenaltyPercent > 100 {
+		return ContestPackage{}, errors.New("late.penalty_percent_per_day は 0〜100 で指定してください")
+	}
+	if doc.Late.CutoffAt != nil && !doc.Late.CutoffAt.After(doc.EndAt) {
+		return ContestPackage{}, errors.New("late.cutoff_at は end_at より後にしてください")
+	}
+	if len(doc.Problems) == 0 {
+		return ContestPackage{}, errors.New("problems が空です")
+	}
+
+	pkg := ContestPackage{
+		Slug:          slug,
+		Title:         strings.TrimSpace(doc.Title),
+		DescriptionMD: doc.Description,
+		StartAt:       doc.StartAt,
+		EndAt:         doc.EndAt,
+		IsPublic:      true,
+		ScoringMode:   mode,
+		Penalty:       penalty,
+		Late:          LatePolicy{PenaltyPercent: doc.Late.PenaltyPercent, CutoffAt: doc.Late.CutoffAt},
+	}
+	if doc.Visibility.Public != nil {
+		pkg.IsPublic = *doc.Visibility.Public
+	}
+	seen := map[string]struct{}{}
+	for i, p := range doc.Problems {
+		pslug := normalizeSlug(p.Slug)
+		if pslug == "" {
+			return ContestPackage{}, fmt.Errorf("problems[%d].slug は必須です", i)
+		}
+		if _, dup := seen[pslug]; dup {
+			return ContestPackage{}, fmt.Errorf("problems[%d].slug %s が重複しています", i, pslug)
+		}
+		seen[pslug] = struct{}{}
+		archive, ok := files[path.Join("problems", pslug+".zip")]
+		if !ok {
+			return ContestPackage{}, fmt.Errorf("problems/%s.zip が見つかりません", pslug)
+		}
+		problem, err := ParseProblemArchive(ctx, archive, gen)
+		if err != nil {
+			return ContestPackage{}, fmt.Errorf("problems/%s.zip: %w", pslug, err)
+		}
+		if problem.Slug != pslug {
+			return ContestPackage{}, fmt.Errorf("problems/%s.zip の slug (%s) が一致していません", pslug, problem.Slug)
+		}
+		label := strings.ToUpper(strings.TrimSpace(p.Label))
+		if label == "" {
+			label = contestLabelFor(i)
+		}
+		pkg.Problems = append(pkg.Problems, ContestPackageProblem{Label: label, Slug: pslug, Pool: strings.TrimSpace(p.Pool), Problem: problem})
+	}
+	return pkg, nil
+}
+
+// buildContestArchive exports a contest and its problems as a contest package zip.
+func buildContestArchive(contest Contest, problems []ContestProblem, archives map[int64][]byte) ([]byte, error) {
+	doc := map[string]any{
+		"slug":         contest.Slug,
+		"title":        contest.Title,
+		"description":  contest.DescriptionMD,
+		"start_at":     contest.StartAt.UTC().Format(time.RFC3339),
+		"end_at":       contest.EndAt.UTC().Format(time.RFC3339),
+		"scoring_mode": contest.ScoringMode,
+		"penalty": map[string]any{
+			"minutes_per_wrong_attempt": contest.Penalty.Minutes,
+			"count_compile_errors":      contest.Penalty.CountCompileErrors,
+			"tie_break":                 contest.Penalty.TieBreak,
+		},
+		"visibility": map[string]any{"public": contest.IsPublic},
+	}
+	if contest.LateCutoffAt != nil || contest.LatePenaltyPercent != 0 {
+		late := map[string]any{"penalty_percent_per_day": contest.LatePenaltyPercent}
+		if contest.LateCutoffAt != nil {
+			late["cutoff_at"] = contest.LateCutoffAt.UTC().Format(time.RFC3339)
+		}
+		doc["late"] = late
+	}
+	entries := make([]map[string]string, 0, len(problems))
+	for _, p := range problems {
+		entry := map[string]string{"label": p.Label, "slug": p.Slug}
+		if p.Pool != "" {
+			entry["pool"] = p.Pool
+		}
+		entries = append(entries, entry)
+	}
+	doc["problems"] = entries
+	contestYAML, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	write := func(name string, content []byte) error {
+		w, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(content)
+		return err
+	}
+	if err := write(contest.Slug+"/contest.yaml", contestYAML); err != nil {
+		return nil, err
+	}
+	for _, p := range problems {
+		archive, ok := archives[p.ProblemID]
+		if !ok {
+			return nil, fmt.Errorf("archive for problem %d missing", p.ProblemID)
+		}
+		if err := write(path.Join(contest.Slug, "problems", p.Slug+".zip"), archive); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
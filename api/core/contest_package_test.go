@@ -0,0 +1,66 @@
+package core
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func testProblemArchive(t *testing.T, slug string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for name, content := range map[string]string{
+		"problem.yaml":       "slug: " + slug + "\ntitle: A+B\n",
+		"statement.md":       "# A+B\n",
+		"data/secret/01.in":  "1 2\n",
+		"data/secret/01.out": "3\n",
+	} {
+		w, err := zw.Create(slug + "/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// エクスポートしたパッケージを読み込むと、コンテストの設定がそのまま戻る
+func TestContestArchiveRoundTrip(t *testing.T) {
+	start := time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC)
+	cutoff := start.Add(72 * time.Hour)
+	contest := Contest{
+		Slug:               "spring",
+		Title:              "Spring",
+		StartAt:            start,
+		EndAt:              start.Add(3 * time.Hour),
+		IsPublic:           true,
+		ScoringMode:        ScoringModeICPC,
+		Penalty:            PenaltyPolicy{Minutes: 5, CountCompileErrors: true, TieBreak: TieBreakNone},
+		LatePenaltyPercent: 10,
+		LateCutoffAt:       &cutoff,
+	}
+	problems := []ContestProblem{{ProblemID: 1, Label: "A", Slug: "aplusb"}}
+	data, err := buildContestArchive(contest, problems, map[int64][]byte{1: testProblemArchive(t, "aplusb")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := ParseContestArchive(context.Background(), data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Penalty != contest.Penalty {
+		t.Errorf("penalty = %+v, want %+v", pkg.Penalty, contest.Penalty)
+	}
+	if pkg.Late.PenaltyPercent != 10 || pkg.Late.CutoffAt == nil || !pkg.Late.CutoffAt.Equal(cutoff) {
+		t.Errorf("late = %+v", pkg.Late)
+	}
+	if len(pkg.Problems) != 1 || pkg.Problems[0].Label != "A" {
+		t.Errorf("problems = %+v", pkg.Problems)
+	}
+}
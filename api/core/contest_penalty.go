@@ -0,0 +1,68 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrPenaltyPolicyInput is returned for an invalid penalty policy.
+var ErrPenaltyPolicyInput = errors.New("invalid penalty policy")
+
+// Tie-break rules between participants with the same solved count and penalty (or IOI score).
+const (
+	TieBreakLastSolve = "last_solve" // 最後に正解（得点を更新）した時刻が早い方を上位にする
+	TieBreakNone      = "none"       // 同順位にする
+)
+
+const maxPenaltyMinutes = 1000
+
+// PenaltyPolicy is the ICPC penalty rule of a contest. Changing it rebuilds the standings.
+type PenaltyPolicy struct {
+	Minutes            int    `json:"minutes_per_wrong_attempt"`
+	CountCompileErrors bool   `json:"count_compile_errors"`
+	TieBreak           string `json:"tie_break"`
+}
+
+// defaultPenaltyPolicy is the conventional ICPC rule, also used by gyms.
+func defaultPenaltyPolicy() PenaltyPolicy {
+	return PenaltyPolicy{Minutes: icpcPenaltyMinutes, TieBreak: TieBreakLastSolve}
+}
+
+// penalized reports whether the verdict counts as a wrong attempt under the policy.
+func (p PenaltyPolicy) penalized(verdict string) bool {
+	if verdict == VerdictCE && p.CountCompileErrors {
+		return true
+	}
+	return verdictPenalized(verdict)
+}
+
+func (p PenaltyPolicy) validate() error {
+	if p.Minutes < 0 || p.Minutes > maxPenaltyMinutes {
+		return fmt.Errorf("%w: minutes_per_wrong_attempt は 0〜%d で指定してください", ErrPenaltyPolicyInput, maxPenaltyMinutes)
+	}
+	if p.TieBreak != TieBreakLastSolve && p.TieBreak != TieBreakNone {
+		return fmt.Errorf("%w: tie_break は last_solve または none を指定してください", ErrPenaltyPolicyInput)
+	}
+	return nil
+}
+
+// SetPenaltyPolicy replaces a contest's penalty policy and recomputes its standings.
+func (r *PgContestRepository) SetPenaltyPolicy(ctx context.Context, id int64, policy PenaltyPolicy) (*Contest, error) {
+	if policy.TieBreak == "" {
+		policy.TieBreak = TieBreakLastSolve
+	}
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+	contest, err := scanContest(r.db.QueryRow(ctx, `UPDATE contests SET penalty_minutes=$2, penalty_count_compile_errors=$3, tie_break=$4, updated_at=NOW()
+WHERE id=$1 RETURNING `+contestColumns, id, policy.Minutes, policy.CountCompileErrors, policy.TieBreak))
+	if err != nil {
+		return nil, err
+	}
+	// コンパイルエラーの扱いは集計セルの不正解数に効くので作り直す
+	if err := r.RebuildStandings(ctx, id); err != nil {
+		return nil, err
+	}
+	return contest, nil
+}
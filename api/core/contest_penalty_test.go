@@ -0,0 +1,41 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStandingsFollowContestPenaltyPolicy(t *testing.T) {
+	start := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	at := func(min int) time.Time { return start.Add(time.Duration(min) * time.Minute) }
+	policy := PenaltyPolicy{Minutes: 5, CountCompileErrors: true, TieBreak: TieBreakNone}
+
+	cell := computeStandingCell(ScoringModeICPC, policy, start, []standingAttempt{
+		{Verdict: "CE", CreatedAt: at(1)},
+		{Verdict: "WA", CreatedAt: at(2)},
+		{Verdict: "AC", CreatedAt: at(20)},
+	})
+	cell.UserID, cell.ProblemID = 10, 1
+	if cell.WrongAttempts != 2 {
+		t.Fatalf("compile error must count: %+v", cell)
+	}
+
+	// bob は 30 分に一発正解で alice（20 分 + 2 回 × 5 分）と同じペナルティ
+	later := computeStandingCell(ScoringModeICPC, policy, start, []standingAttempt{{Verdict: "AC", CreatedAt: at(30)}})
+	later.UserID, later.ProblemID = 20, 1
+	participants := []ContestRegistration{{UserID: 10, Username: "alice"}, {UserID: 20, Username: "bob"}}
+	problems := []ContestProblem{{ProblemID: 1, Label: "A"}}
+	rows := buildStandings(ScoringModeICPC, policy, problems, participants, []ContestStandingCell{cell, later})
+	if rows[0].Penalty != 30 || rows[1].Penalty != 30 || rows[0].Rank != 1 || rows[1].Rank != 1 {
+		t.Fatalf("tie_break=none must share the rank: %+v", rows)
+	}
+
+	policy.TieBreak = TieBreakLastSolve
+	rows = buildStandings(ScoringModeICPC, policy, problems, participants, []ContestStandingCell{cell, later})
+	if rows[0].Username != "alice" || rows[1].Rank != 2 {
+		t.Fatalf("last_solve must rank the earlier solve first: %+v", rows)
+	}
+	if err := (PenaltyPolicy{Minutes: 20, TieBreak: "coin"}).validate(); err == nil {
+		t.Fatal("unknown tie_break must be rejected")
+	}
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+	"strconv"
+)
+
+// assignContestProblems returns the problems userID sees in a contest: every problem without a pool
+// plus one problem per pool. The pick depends only on the contest, the pool name, the user and the
+// pool's members, so it survives reloads and reordering but changes if the pool is edited.
+func assignContestProblems(contestID, userID int64, problems []ContestProblem) []ContestProblem {
+	pools := map[string][]int64{}
+	for _, p := range problems {
+		if p.Pool != "" {
+			pools[p.Pool] = append(pools[p.Pool], p.ProblemID)
+		}
+	}
+	if len(pools) == 0 {
+		return problems
+	}
+	chosen := make(map[int64]bool, len(pools))
+	for name, ids := range pools {
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		chosen[ids[poolIndex(contestID, name, userID, len(ids))]] = true
+	}
+	out := make([]ContestProblem, 0, len(problems))
+	for _, p := range problems {
+		if p.Pool == "" || chosen[p.ProblemID] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func poolIndex(contestID int64, pool string, userID int64, n int) int {
+	sum := sha256.Sum256([]byte(strconv.FormatInt(contestID, 10) + ":" + pool + ":" + strconv.FormatInt(userID, 10)))
+	return int(binary.BigEndian.Uint64(sum[:8]) % uint64(n))
+}
+
+// containsContestProblem reports whether problemID is in problems.
+func containsContestProblem(problems []ContestProblem, problemID int64) bool {
+	for _, p := range problems {
+		if p.ProblemID == problemID {
+			return true
+		}
+	}
+	return false
+}
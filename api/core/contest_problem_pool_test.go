@@ -0,0 +1,44 @@
+package core
+
+import "testing"
+
+func TestAssignContestProblems(t *testing.T) {
+	problems := []ContestProblem{
+		{ProblemID: 1, Label: "A"},
+		{ProblemID: 2, Label: "B1", Pool: "B"},
+		{ProblemID: 3, Label: "B2", Pool: "B"},
+		{ProblemID: 4, Label: "B3", Pool: "B"},
+		{ProblemID: 5, Label: "C1", Pool: "C"},
+		{ProblemID: 6, Label: "C2", Pool: "C"},
+	}
+	seen := map[int64]bool{}
+	for userID := int64(1); userID <= 50; userID++ {
+		got := assignContestProblems(10, userID, problems)
+		if len(got) != 3 || got[0].ProblemID != 1 {
+			t.Fatalf("user %d: got %+v", userID, got)
+		}
+		if got[1].Pool != "B" || got[2].Pool != "C" {
+			t.Fatalf("user %d: expected one problem per pool in order, got %+v", userID, got)
+		}
+		// 並び順が変わっても同じ問題が割り当てられる
+		reversed := make([]ContestProblem, len(problems))
+		for i, p := range problems {
+			reversed[len(problems)-1-i] = p
+		}
+		again := assignContestProblems(10, userID, reversed)
+		if !containsContestProblem(again, got[1].ProblemID) || !containsContestProblem(again, got[2].ProblemID) {
+			t.Fatalf("user %d: assignment depends on order", userID)
+		}
+		for _, p := range got {
+			seen[p.ProblemID] = true
+		}
+	}
+	if len(seen) != len(problems) {
+		t.Fatalf("expected every pool member to be assigned to someone, got %v", seen)
+	}
+
+	plain := problems[:1]
+	if got := assignContestProblems(10, 1, plain); len(got) != 1 {
+		t.Fatalf("contest without pools should be unchanged, got %+v", got)
+	}
+}
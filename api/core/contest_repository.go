@@ -0,0 +1,552 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// コンテストのフェーズ。
+const (
+	ContestPhaseUpcoming = "upcoming"
+	ContestPhaseRunning  = "running"
+	ContestPhaseEnded    = "ended"
+)
+
+// 採点モード。icpc は AC/非 AC + ペナルティ、ioi は部分点の最高得点で順位付けする。
+const (
+	ScoringModeICPC = "icpc"
+	ScoringModeIOI  = "ioi"
+)
+
+// normalizeScoringMode returns a valid scoring mode ("" defaults to icpc).
+func normalizeScoringMode(mode string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", ScoringModeICPC:
+		return ScoringModeICPC, nil
+	case ScoringModeIOI:
+		return ScoringModeIOI, nil
+	default:
+		return "", errors.New("scoring_mode must be icpc or ioi")
+	}
+}
+
+var (
+	// ErrContestNotOpen is returned when registration is attempted after the contest has ended.
+	ErrContestNotOpen = errors.New("contest is not open for registration")
+	// ErrContestAlreadyStarted is returned when unregistration is attempted after start.
+	ErrContestAlreadyStarted = errors.New("contest already started")
+)
+
+// Contest represents a timed problem set.
+type Contest struct {
+	ID            int64     `json:"id"`
+	Slug          string    `json:"slug"`
+	Title         string    `json:"title"`
+	DescriptionMD string    `json:"description"`
+	StartAt       time.Time `json:"start_at"`
+	EndAt         time.Time `json:"end_at"`
+	IsPublic      bool      `json:"is_public"`
+	ScoringMode   string    `json:"scoring_mode"`
+	// 遅延提出: LateCutoffAt まで終了後の提出を受け付け、1 日ごとに LatePenaltyPercent を減点する
+	LatePenaltyPercent int        `json:"late_penalty_percent"`
+	LateCutoffAt       *time.Time `json:"late_cutoff_at"`
+	// ICPC のペナルティ規則（SetPenaltyPolicy で変更）
+	Penalty   PenaltyPolicy `json:"penalty"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// AcceptsLateSubmissions reports whether now falls in the late window after the end.
+func (c Contest) AcceptsLateSubmissions(now time.Time) bool {
+	return c.LateCutoffAt != nil && !now.Before(c.EndAt) && now.Before(*c.LateCutoffAt)
+}
+
+// Phase returns upcoming/running/ended relative to now.
+func (c Contest) Phase(now time.Time) string {
+	switch {
+	case now.Before(c.StartAt):
+		return ContestPhaseUpcoming
+	case now.Before(c.EndAt):
+		return ContestPhaseRunning
+	default:
+		return ContestPhaseEnded
+	}
+}
+
+// ContestProblem is a problem attached to a contest with its label.
+type ContestProblem struct {
+	ProblemID int64  `json:"problem_id"`
+	Label     string `json:"label"`
+	Position  int    `json:"position"`
+	Slug      string `json:"slug"`
+	Title     string `json:"title"`
+	LocalizedTitles
+	TimeLimitMS   int32 `json:"time_limit_ms"`
+	MemoryLimitKB int32 `json:"memory_limit_kb"`
+	// 解説: 本文は専用エンドポイントで返す
+	HasEditorial         bool `json:"has_editorial"`
+	EditorialPublic      bool `json:"editorial_public"`
+	EditorialAutoRelease bool `json:"editorial_auto_release"`
+	// Pool groups interchangeable problems; each participant is assigned one per pool.
+	Pool string `json:"pool,omitempty"`
+}
+
+// ContestProblemInput attaches a problem to a contest.
+type ContestProblemInput struct {
+	ProblemID int64  `json:"problem_id"`
+	Label     string `json:"label"`
+	Pool      string `json:"pool"`
+}
+
+// ContestRegistration is a registered participant.
+type ContestRegistration struct {
+	UserID       int64     `json:"user_id"`
+	Username     string    `json:"userid"`
+	RegisteredAt time.Time `json:"registered_at"`
+}
+
+// ContestCreateInput holds fields for a new contest.
+type ContestCreateInput struct {
+	Slug          string
+	Title         string
+	DescriptionMD string
+	StartAt       time.Time
+	EndAt         time.Time
+	IsPublic      bool
+	ScoringMode   string
+}
+
+// ContestUpdateInput holds mutable fields of a contest.
+type ContestUpdateInput struct {
+	Title         *string
+	DescriptionMD *string
+	StartAt       *time.Time
+	EndAt         *time.Time
+	IsPublic      *bool
+	ScoringMode   *string
+}
+
+// ContestRepository defines persistence operations for contests.
+type ContestRepository interface {
+	// List returns public contests and the private ones viewerID is registered for (all with includeHidden).
+	List(ctx context.Context, includeHidden bool, viewerID int64, page, perPage int) ([]Contest, int, error)
+	Get(ctx context.Context, id int64) (*Contest, error)
+	Create(ctx context.Context, input ContestCreateInput) (*Contest, error)
+	Update(ctx context.Context, id int64, input ContestUpdateInput) (*Contest, error)
+	Delete(ctx context.Context, id int64) error
+	ListProblems(ctx context.Context, id int64) ([]ContestProblem, error)
+	SetProblems(ctx context.Context, id int64, problems []ContestProblemInput) error
+	Register(ctx context.Context, contestID, userID int64) error
+	Unregister(ctx context.Context, contestID, userID int64) error
+	IsRegistered(ctx context.Context, contestID, userID int64) (bool, error)
+	CountRegistrations(ctx context.Context, contestID int64) (int, error)
+	ListRegistrations(ctx context.Context, contestID int64, page, perPage int) ([]ContestRegistration, int, error)
+	ImportPackage(ctx context.Context, pkg ContestPackage, reuseExisting bool) (*ContestImportResult, error)
+	Standings(ctx context.Context, contest Contest, problems []ContestProblem) ([]ContestStandingRow, error)
+	RebuildStandings(ctx context.Context, contestID int64) error
+	GetEditorial(ctx context.Context, contestID, problemID int64) (*ContestEditorial, error)
+	SetEditorial(ctx context.Context, contestID, problemID int64, input ContestEditorialInput) (*ContestEditorial, error)
+	ReleaseEndedEditorials(ctx context.Context, now time.Time) ([]ContestEditorialRelease, error)
+	SetLatePolicy(ctx context.Context, id int64, policy LatePolicy) (*Contest, error)
+	SetPenaltyPolicy(ctx context.Context, id int64, policy PenaltyPolicy) (*Contest, error)
+	Grades(ctx context.Context, contest Contest, problems []ContestProblem) ([]ContestGradeRow, error)
+}
+
+// PgContestRepository implements ContestRepository using pgxpool.
+type PgContestRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPgContestRepository(db *pgxpool.Pool) *PgContestRepository {
+	return &PgContestRepository{db: db}
+}
+
+const contestColumns = `id, slug, title, description_md, start_at, end_at, is_public, scoring_mode, late_penalty_percent, late_cutoff_at,
+    penalty_minutes, penalty_count_compile_errors, tie_break, created_at, updated_at`
+
+func scanContest(row pgx.Row) (*Contest, error) {
+	var c Contest
+	if err := row.Scan(&c.ID, &c.Slug, &c.Title, &c.DescriptionMD, &c.StartAt, &c.EndAt, &c.IsPublic, &c.ScoringMode, &c.LatePenaltyPercent, &c.LateCutoffAt,
+		&c.Penalty.Minutes, &c.Penalty.CountCompileErrors, &c.Penalty.TieBreak, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// List returns contests ordered by start time (newest first).
+func (r *PgContestRepository) List(ctx context.Context, includeHidden bool, viewerID int64, page, perPage int) ([]Contest, int, error) {
+	if page <= 0 || perPage <= 0 {
+		return nil, 0, errors.New("invalid pagination")
+	}
+	// 非公開コンテストも参加コードで登録済みなら一覧に出す
+	const where = `WHERE $1 OR is_public = TRUE OR id IN (SELECT contest_id FROM contest_registrations WHERE user_id=$2)`
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM contests `+where, includeHidden, viewerID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := r.db.Query(ctx, `SELECT `+contestColumns+` FROM contests `+where+` ORDER BY start_at DESC, id DESC LIMIT $3 OFFSET $4`, includeHidden, viewerID, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	items := make([]Contest, 0, perPage)
+	for rows.Next() {
+		c, err := scanContest(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		items = append(items, *c)
+	}
+	return items, total, rows.Err()
+}
+
+func (r *PgContestRepository) Get(ctx context.Context, id int64) (*Contest, error) {
+	return scanContest(r.db.QueryRow(ctx, `SELECT `+contestColumns+` FROM contests WHERE id=$1`, id))
+}
+
+func (r *PgContestRepository) Create(ctx context.Context, input ContestCreateInput) (*Contest, error) {
+	input.Slug = normalizeSlug(input.Slug)
+	input.Title = strings.TrimSpace(input.Title)
+	if input.Slug == "" || input.Title == "" {
+		return nil, errors.New("slug and title are required")
+	}
+	if !input.EndAt.After(input.StartAt) {
+		return nil, errors.New("end_at must be after start_at")
+	}
+	mode, err := normalizeScoringMode(input.ScoringMode)
+	if err != nil {
+		return nil, err
+	}
+	const q = `INSERT INTO contests (slug, title, description_md, start_at, end_at, is_public, scoring_mode)
+VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING ` + contestColumns
+	return scanContest(r.db.QueryRow(ctx, q, input.Slug, input.Title, input.DescriptionMD, input.StartAt, input.EndAt, input.IsPublic, mode))
+}
+
+// Update applies a partial update and returns the latest row.
+func (r *PgContestRepository) Update(ctx context.Context, id int64, input ContestUpdateInput) (*Contest, error) {
+	current, err := r.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	start, end := current.StartAt, current.EndAt
+	if input.StartAt != nil {
+		start = *input.StartAt
+	}
+	if input.EndAt != nil {
+		end = *input.EndAt
+	}
+	if !end.After(start) {
+		return nil, errors.New("end_at must be after start_at")
+	}
+
+	var sets []string
+	var args []any
+	if input.Title != nil {
+		title := strings.TrimSpace(*input.Title)
+		if title == "" {
+			return nil, errors.New("title must not be empty")
+		}
+		sets = append(sets, "title=$"+strconv.Itoa(len(args)+1))
+		args = append(args, title)
+	}
+	if input.DescriptionMD != nil {
+		sets = append(sets, "description_md=$"+strconv.Itoa(len(args)+1))
+		args = append(args, *input.DescriptionMD)
+	}
+	if input.StartAt != nil {
+		sets = append(sets, "start_at=$"+strconv.Itoa(len(args)+1))
+		args = append(args, *input.StartAt)
+	}
+	if input.EndAt != nil {
+		sets = append(sets, "end_at=$"+strconv.Itoa(len(args)+1))
+		args = append(args, *input.EndAt)
+		if input.EndAt.After(time.Now()) {
+			// 延長されたら終了時に改めて解説を公開する
+			sets = append(sets, "editorials_released_at=NULL")
+		}
+	}
+	if input.IsPublic != nil {
+		sets = append(sets, "is_public=$"+strconv.Itoa(len(args)+1))
+		args = append(args, *input.IsPublic)
+	}
+	if input.ScoringMode != nil {
+		mode, err := normalizeScoringMode(*input.ScoringMode)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, "scoring_mode=$"+strconv.Itoa(len(args)+1))
+		args = append(args, mode)
+	}
+	if len(sets) == 0 {
+		return current, nil
+	}
+	args = append(args, id)
+	q := "UPDATE contests SET " + strings.Join(sets, ", ") + " WHERE id=$" + strconv.Itoa(len(args)) + " RETURNING " + contestColumns
+	return scanContest(r.db.QueryRow(ctx, q, args...))
+}
+
+func (r *PgContestRepository) Delete(ctx context.Context, id int64) error {
+	ct, err := r.db.Exec(ctx, `DELETE FROM contests WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// ListProblems returns contest problems ordered by position then label.
+func (r *PgContestRepository) ListProblems(ctx context.Context, id int64) ([]ContestProblem, error) {
+	const q = `
+SELECT cp.problem_id, cp.label, cp.position, p.slug, p.title, p.title_ja, p.title_en, p.time_limit_ms, p.memory_limit_kb,
+       p.editorial_md <> '', ` + editorialReleasedSQL + `, p.editorial_visibility = 'auto', COALESCE(cp.pool, '')
+FROM contest_problems cp
+JOIN problems p ON p.id = cp.problem_id
+WHERE cp.contest_id=$1 AND p.deleted_at IS NULL
+ORDER BY cp.position, cp.label`
+	rows, err := r.db.Query(ctx, q, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []ContestProblem{}
+	for rows.Next() {
+		var p ContestProblem
+		if err := rows.Scan(&p.ProblemID, &p.Label, &p.Position, &p.Slug, &p.Title, &p.TitleJA, &p.TitleEN, &p.TimeLimitMS, &p.MemoryLimitKB,
+			&p.HasEditorial, &p.EditorialPublic, &p.EditorialAutoRelease, &p.Pool); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// SetProblems replaces the problem set of a contest atomically.
+// Labels default to A, B, C ... by position when empty.
+func (r *PgContestRepository) SetProblems(ctx context.Context, id int64, problems []ContestProblemInput) error {
+	seenLabel := map[string]struct{}{}
+	seenProblem := map[int64]struct{}{}
+	for i := range problems {
+		if problems[i].ProblemID <= 0 {
+			return errors.New("problem_id must be positive")
+		}
+		label := strings.ToUpper(strings.TrimSpace(problems[i].Label))
+		if label == "" {
+			label = contestLabelFor(i)
+		}
+		if len(label) > 16 {
+			return errors.New("label is too long")
+		}
+		if _, dup := seenLabel[label]; dup {
+			return errors.New("duplicate label " + label)
+		}
+		if _, dup := seenProblem[problems[i].ProblemID]; dup {
+			return errors.New("duplicate problem_id " + strconv.FormatInt(problems[i].ProblemID, 10))
+		}
+		pool := strings.TrimSpace(problems[i].Pool)
+		if len(pool) > 32 {
+			return errors.New("pool is too long")
+		}
+		seenLabel[label] = struct{}{}
+		seenProblem[problems[i].ProblemID] = struct{}{}
+		problems[i].Label = label
+		problems[i].Pool = pool
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// 残す問題の解説を保持するため、外れた問題だけ削除して残りは upsert する
+	ids := make([]int64, 0, len(problems))
+	for _, p := range problems {
+		ids = append(ids, p.ProblemID)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM contest_problems WHERE contest_id=$1 AND NOT (problem_id = ANY($2))`, id, ids); err != nil {
+		return err
+	}
+	// ラベルの入れ替えで UNIQUE(contest_id, label) に当たらないよう一旦退避
+	if _, err := tx.Exec(ctx, `UPDATE contest_problems SET label='#'||problem_id WHERE contest_id=$1`, id); err != nil {
+		return err
+	}
+	for i, p := range problems {
+		if _, err := tx.Exec(ctx, `INSERT INTO contest_problems (contest_id, problem_id, label, position, pool) VALUES ($1,$2,$3,$4,$5)
+ON CONFLICT (contest_id, problem_id) DO UPDATE SET label=EXCLUDED.label, position=EXCLUDED.position, pool=EXCLUDED.pool`,
+			id, p.ProblemID, p.Label, i, stringPtrIfNotEmpty(p.Pool)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// contestLabelFor returns A..Z, then AA, AB ... for the given zero-based index.
+func contestLabelFor(i int) string {
+	label := ""
+	for n := i + 1; n > 0; n = (n - 1) / 26 {
+		label = string(rune('A'+(n-1)%26)) + label
+	}
+	return label
+}
+
+// Register adds a participant. Registration is accepted until the contest ends.
+func (r *PgContestRepository) Register(ctx context.Context, contestID, userID int64) error {
+	c, err := r.Get(ctx, contestID)
+	if err != nil {
+		return err
+	}
+	if c.Phase(time.Now()) == ContestPhaseEnded {
+		return ErrContestNotOpen
+	}
+	_, err = r.db.Exec(ctx, `INSERT INTO contest_registrations (contest_id, user_id) VALUES ($1,$2) ON CONFLICT DO NOTHING`, contestID, userID)
+	return err
+}
+
+// Unregister removes a participant; only allowed before the contest starts.
+func (r *PgContestRepository) Unregister(ctx context.Context, contestID, userID int64) error {
+	c, err := r.Get(ctx, contestID)
+	if err != nil {
+		return err
+	}
+	if c.Phase(time.Now()) != ContestPhaseUpcoming {
+		return ErrContestAlreadyStarted
+	}
+	_, err = r.db.Exec(ctx, `DELETE FROM contest_registrations WHERE contest_id=$1 AND user_id=$2`, contestID, userID)
+	return err
+}
+
+func (r *PgContestRepository) IsRegistered(ctx context.Context, contestID, userID int64) (bool, error) {
+	var one int
+	if err := r.db.QueryRow(ctx, `SELECT 1 FROM contest_registrations WHERE contest_id=$1 AND user_id=$2`, contestID, userID).Scan(&one); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *PgContestRepository) CountRegistrations(ctx context.Context, contestID int64) (int, error) {
+	var c int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM contest_registrations WHERE contest_id=$1`, contestID).Scan(&c); err != nil {
+		return 0, err
+	}
+	return c, nil
+}
+
+func (r *PgContestRepository) ListRegistrations(ctx context.Context, contestID int64, page, perPage int) ([]ContestRegistration, int, error) {
+	if page <= 0 || perPage <= 0 {
+		return nil, 0, errors.New("invalid pagination")
+	}
+	total, err := r.CountRegistrations(ctx, contestID)
+	if err != nil {
+		return nil, 0, err
+	}
+	rows, err := r.db.Query(ctx, `
+SELECT cr.user_id, u.username, cr.registered_at
+FROM contest_registrations cr
+JOIN users u ON u.id = cr.user_id
+WHERE cr.contest_id=$1
+ORDER BY cr.registered_at, cr.user_id
+LIMIT $2 OFFSET $3`, contestID, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	items := make([]ContestRegistration, 0, perPage)
+	for rows.Next() {
+		var reg ContestRegistration
+		if err := rows.Scan(&reg.UserID, &reg.Username, &reg.RegisteredAt); err != nil {
+			return nil, 0, err
+		}
+		items = append(items, reg)
+	}
+	return items, total, rows.Err()
+}
+
+// ContestImportResult summarizes a contest package import.
+type ContestImportResult struct {
+	Contest         Contest  `json:"contest"`
+	CreatedProblems []string `json:"created_problems"`
+	ReusedProblems  []string `json:"reused_problems"`
+}
+
+var (
+	// ErrProblemSlugExists is returned by ImportPackage when a problem slug already exists and reuse is disabled.
+	ErrProblemSlugExists = errors.New("problem slug already exists")
+	// ErrProblemSlugTrashed is returned by ImportPackage when the slug belongs to a problem in the
+	// recycle bin; it can neither be reused nor created until restored or purged.
+	ErrProblemSlugTrashed = errors.New("problem slug is in the recycle bin")
+)
+
+// ImportPackage creates the contest and its problems in a single transaction.
+// When reuseExisting is true, problems whose slug already exists are attached as-is
+// instead of failing the import.
+func (r *PgContestRepository) ImportPackage(ctx context.Context, pkg ContestPackage, reuseExisting bool) (*ContestImportResult, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	result := &ContestImportResult{CreatedProblems: []string{}, ReusedProblems: []string{}}
+	problemIDs := make([]int64, 0, len(pkg.Problems))
+	for _, p := range pkg.Problems {
+		var existingID int64
+		err := tx.QueryRow(ctx, `SELECT id FROM problems WHERE slug=$1 AND deleted_at IS NULL`, p.Slug).Scan(&existingID)
+		switch {
+		case err == nil:
+			if !reuseExisting {
+				return nil, fmt.Errorf("%w: %s", ErrProblemSlugExists, p.Slug)
+			}
+			problemIDs = append(problemIDs, existingID)
+			result.ReusedProblems = append(result.ReusedProblems, p.Slug)
+			continue
+		case !errors.Is(err, pgx.ErrNoRows):
+			return nil, err
+		}
+		// slug は削除済みの問題とも重複できない
+		var trashed bool
+		if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM problems WHERE slug=$1 AND deleted_at IS NOT NULL)`, p.Slug).Scan(&trashed); err != nil {
+			return nil, err
+		}
+		if trashed {
+			return nil, fmt.Errorf("%w: %s", ErrProblemSlugTrashed, p.Slug)
+		}
+		id, err := insertProblemTx(ctx, tx, p.Problem)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p.Slug, err)
+		}
+		problemIDs = append(problemIDs, id)
+		result.CreatedProblems = append(result.CreatedProblems, p.Slug)
+	}
+
+	contest, err := scanContest(tx.QueryRow(ctx, `INSERT INTO contests (slug, title, description_md, start_at, end_at, is_public, scoring_mode,
+    penalty_minutes, penalty_count_compile_errors, tie_break, late_penalty_percent, late_cutoff_at)
+VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12) RETURNING `+contestColumns,
+		pkg.Slug, pkg.Title, pkg.DescriptionMD, pkg.StartAt, pkg.EndAt, pkg.IsPublic, pkg.ScoringMode,
+		pkg.Penalty.Minutes, pkg.Penalty.CountCompileErrors, pkg.Penalty.TieBreak, pkg.Late.PenaltyPercent, pkg.Late.CutoffAt))
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range pkg.Problems {
+		if _, err := tx.Exec(ctx, `INSERT INTO contest_problems (contest_id, problem_id, label, position, pool) VALUES ($1,$2,$3,$4,$5)`,
+			contest.ID, problemIDs[i], p.Label, i, stringPtrIfNotEmpty(p.Pool)); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	result.Contest = *contest
+	return result, nil
+}
@@ -0,0 +1,371 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// icpcPenaltyMinutes is the default penalty added per rejected attempt before the first AC
+// (contests may set their own, see PenaltyPolicy).
+const icpcPenaltyMinutes = 20
+
+// pgQuerier is the subset shared by *pgxpool.Pool and pgx.Tx.
+type pgQuerier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// ContestStandingCell is the aggregated state of one (user, problem) pair.
+type ContestStandingCell struct {
+	UserID          int64      `json:"-"`
+	Username        string     `json:"-"`
+	ProblemID       int64      `json:"problem_id"`
+	Label           string     `json:"label"`
+	WrongAttempts   int        `json:"wrong_attempts"`
+	PendingAttempts int        `json:"pending_attempts"`
+	SolvedAt        *time.Time `json:"solved_at,omitempty"`
+	SolvedMinutes   *int       `json:"solved_minutes,omitempty"`
+	FirstSolve      bool       `json:"first_solve,omitempty"`
+	// IOI: 最高得点と、それを最初に得た時刻（分）
+	BestScore        *int `json:"best_score,omitempty"`
+	BestScoreMinutes *int `json:"best_score_minutes,omitempty"`
+}
+
+// ContestStandingRow is one participant line of the scoreboard.
+type ContestStandingRow struct {
+	Rank     int                   `json:"rank"`
+	UserID   int64                 `json:"user_id"`
+	Username string                `json:"userid"`
+	Solved   int                   `json:"solved"`
+	Penalty  int                   `json:"penalty"`
+	Score    int                   `json:"score"`
+	Cells    []ContestStandingCell `json:"cells"`
+
+	lastSolvedMinutes int
+}
+
+// standingAttempt is one submission considered for a standing cell.
+type standingAttempt struct {
+	Verdict   string // empty while pending/running
+	Score     *int   // set for scored (IOI) judging
+	CreatedAt time.Time
+}
+
+func minutesSince(start, at time.Time) int {
+	minutes := int(at.Sub(start) / time.Minute)
+	if minutes < 0 {
+		return 0
+	}
+	return minutes
+}
+
+// computeStandingCell aggregates the attempts of one (user, problem) pair.
+// Attempts must be ordered by submission time. Excluded verdicts (CE/SE) are not penalized
+// unless the policy counts compile errors.
+// ICPC: attempts after the first AC are ignored.
+// IOI: every judged attempt counts and the best score is kept.
+func computeStandingCell(mode string, policy PenaltyPolicy, start time.Time, attempts []standingAttempt) ContestStandingCell {
+	var cell ContestStandingCell
+	for _, a := range attempts {
+		if a.Verdict == "" {
+			cell.PendingAttempts++
+			continue
+		}
+		if mode == ScoringModeIOI && a.Score != nil && (cell.BestScore == nil || *a.Score > *cell.BestScore) {
+			score, minutes := *a.Score, minutesSince(start, a.CreatedAt)
+			cell.BestScore = &score
+			cell.BestScoreMinutes = &minutes
+		}
+		switch a.Verdict {
+		case VerdictAC:
+			if cell.SolvedAt == nil {
+				at := a.CreatedAt
+				minutes := minutesSince(start, at)
+				cell.SolvedAt = &at
+				cell.SolvedMinutes = &minutes
+			}
+			if mode != ScoringModeIOI {
+				return cell
+			}
+		default:
+			if cell.SolvedAt == nil && policy.penalized(a.Verdict) {
+				cell.WrongAttempts++
+			}
+		}
+	}
+	return cell
+}
+
+// buildStandings ranks participants.
+// ICPC: solved desc, penalty asc (policy.Minutes per wrong attempt), last AC time asc.
+// IOI: total of best scores desc, then time of the last score improvement asc.
+// With TieBreakNone the last AC / improvement time is not compared.
+// Participants with equal keys share the same rank.
+func buildStandings(mode string, policy PenaltyPolicy, problems []ContestProblem, participants []ContestRegistration, cells []ContestStandingCell) []ContestStandingRow {
+	rows := make([]*ContestStandingRow, 0, len(participants))
+	byUser := make(map[int64]*ContestStandingRow, len(participants))
+	addRow := func(userID int64, username string) *ContestStandingRow {
+		if row, ok := byUser[userID]; ok {
+			return row
+		}
+		row := &ContestStandingRow{UserID: userID, Username: username, Cells: make([]ContestStandingCell, len(problems))}
+		for i, p := range problems {
+			row.Cells[i] = ContestStandingCell{ProblemID: p.ProblemID, Label: p.Label}
+		}
+		byUser[userID] = row
+		rows = append(rows, row)
+		return row
+	}
+	for _, p := range participants {
+		addRow(p.UserID, p.Username)
+	}
+
+	index := make(map[int64]int, len(problems))
+	for i, p := range problems {
+		index[p.ProblemID] = i
+	}
+	firstSolve := make(map[int64]*ContestStandingCell, len(problems))
+	for _, cell := range cells {
+		i, ok := index[cell.ProblemID]
+		if !ok {
+			continue
+		}
+		row := addRow(cell.UserID, cell.Username)
+		cell.Label = problems[i].Label
+		row.Cells[i] = cell
+		if mode == ScoringModeIOI {
+			if cell.BestScore != nil {
+				row.Score += *cell.BestScore
+				if *cell.BestScore > 0 && *cell.BestScoreMinutes > row.lastSolvedMinutes {
+					row.lastSolvedMinutes = *cell.BestScoreMinutes
+				}
+			}
+			if cell.SolvedMinutes != nil {
+				row.Solved++
+			}
+			continue
+		}
+		if cell.SolvedMinutes != nil {
+			row.Solved++
+			row.Penalty += *cell.SolvedMinutes + policy.Minutes*cell.WrongAttempts
+			if *cell.SolvedMinutes > row.lastSolvedMinutes {
+				row.lastSolvedMinutes = *cell.SolvedMinutes
+			}
+			if cur := firstSolve[cell.ProblemID]; cur == nil || cell.SolvedAt.Before(*cur.SolvedAt) {
+				firstSolve[cell.ProblemID] = &row.Cells[i]
+			}
+		}
+	}
+	for _, cell := range firstSolve {
+		cell.FirstSolve = true
+	}
+
+	byLastSolve := policy.TieBreak != TieBreakNone
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Solved != b.Solved {
+			return a.Solved > b.Solved
+		}
+		if a.Penalty != b.Penalty {
+			return a.Penalty < b.Penalty
+		}
+		if byLastSolve && a.lastSolvedMinutes != b.lastSolvedMinutes {
+			return a.lastSolvedMinutes < b.lastSolvedMinutes
+		}
+		return a.Username < b.Username
+	})
+	out := make([]ContestStandingRow, len(rows))
+	for i, row := range rows {
+		row.Rank = i + 1
+		if i > 0 {
+			prev := rows[i-1]
+			if prev.Score == row.Score && prev.Solved == row.Solved && prev.Penalty == row.Penalty && (!byLastSolve || prev.lastSolvedMinutes == row.lastSolvedMinutes) {
+				row.Rank = prev.Rank
+			}
+		}
+		out[i] = *row
+	}
+	return out
+}
+
+// refreshContestStandingCell recomputes the standing cell touched by a submission.
+// Only the submissions of that (contest, user, problem) are scanned; it is a no-op
+// for submissions outside contests.
+func refreshContestStandingCell(ctx context.Context, q pgQuerier, submissionID int64) error {
+	var contestID *int64
+	var userID, problemID int64
+	err := q.QueryRow(ctx, `SELECT contest_id, user_id, problem_id FROM submissions WHERE id=$1`, submissionID).Scan(&contestID, &userID, &problemID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && contestID == nil) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return recomputeStandingCell(ctx, q, *contestID, userID, problemID)
+}
+
+func recomputeStandingCell(ctx context.Context, q pgQuerier, contestID, userID, problemID int64) error {
+	var startAt, endAt time.Time
+	var mode string
+	policy := defaultPenaltyPolicy()
+	if err := q.QueryRow(ctx, `SELECT start_at, end_at, scoring_mode, penalty_count_compile_errors FROM contests WHERE id=$1`, contestID).
+		Scan(&startAt, &endAt, &mode, &policy.CountCompileErrors); err != nil {
+		return err
+	}
+	rows, err := q.Query(ctx, `SELECT s.created_at, COALESCE(sr.verdict, ''), sr.score
+FROM submissions s
+LEFT JOIN submission_results sr ON sr.submission_id = s.id
+WHERE s.contest_id=$1 AND s.user_id=$2 AND s.problem_id=$3 AND s.created_at >= $4 AND s.created_at < $5
+ORDER BY s.created_at, s.id`, contestID, userID, problemID, startAt, endAt)
+	if err != nil {
+		return err
+	}
+	var attempts []standingAttempt
+	for rows.Next() {
+		var a standingAttempt
+		var score *int32
+		if err := rows.Scan(&a.CreatedAt, &a.Verdict, &score); err != nil {
+			rows.Close()
+			return err
+		}
+		if score != nil {
+			v := int(*score)
+			a.Score = &v
+		}
+		attempts = append(attempts, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	cell := computeStandingCell(mode, policy, startAt, attempts)
+	_, err = q.Exec(ctx, `INSERT INTO contest_standing_cells (contest_id, user_id, problem_id, wrong_attempts, pending_attempts, solved_at, solved_minutes, best_score, best_score_minutes)
+VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+ON CONFLICT (contest_id, user_id, problem_id) DO UPDATE SET
+  wrong_attempts=EXCLUDED.wrong_attempts,
+  pending_attempts=EXCLUDED.pending_attempts,
+  solved_at=EXCLUDED.solved_at,
+  solved_minutes=EXCLUDED.solved_minutes,
+  best_score=EXCLUDED.best_score,
+  best_score_minutes=EXCLUDED.best_score_minutes`,
+		contestID, userID, problemID, cell.WrongAttempts, cell.PendingAttempts, cell.SolvedAt, cell.SolvedMinutes, cell.BestScore, cell.BestScoreMinutes)
+	return err
+}
+
+// Standings builds the scoreboard from the aggregated cells and all registered participants.
+func (r *PgContestRepository) Standings(ctx context.Context, contest Contest, problems []ContestProblem) ([]ContestStandingRow, error) {
+	participants, err := r.listParticipants(ctx, contest.ID)
+	if err != nil {
+		return nil, err
+	}
+	cells, err := r.listStandingCells(ctx, contest.ID)
+	if err != nil {
+		return nil, err
+	}
+	return buildStandings(contest.ScoringMode, contest.Penalty, problems, participants, cells), nil
+}
+
+func (r *PgContestRepository) listParticipants(ctx context.Context, contestID int64) ([]ContestRegistration, error) {
+	rows, err := r.db.Query(ctx, `
+SELECT cr.user_id, u.username, cr.registered_at
+FROM contest_registrations cr
+JOIN users u ON u.id = cr.user_id
+WHERE cr.contest_id=$1`, contestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var participants []ContestRegistration
+	for rows.Next() {
+		var reg ContestRegistration
+		if err := rows.Scan(&reg.UserID, &reg.Username, &reg.RegisteredAt); err != nil {
+			return nil, err
+		}
+		participants = append(participants, reg)
+	}
+	return participants, rows.Err()
+}
+
+func (r *PgContestRepository) listStandingCells(ctx context.Context, contestID int64) ([]ContestStandingCell, error) {
+	rows, err := r.db.Query(ctx, `SELECT sc.user_id, u.username, sc.problem_id, sc.wrong_attempts, sc.pending_attempts, sc.solved_at, sc.solved_minutes,
+       sc.best_score, sc.best_score_minutes
+FROM contest_standing_cells sc
+JOIN users u ON u.id = sc.user_id
+WHERE sc.contest_id=$1`, contestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var cells []ContestStandingCell
+	for rows.Next() {
+		var cell ContestStandingCell
+		var minutes, bestScore, bestMinutes *int32
+		if err := rows.Scan(&cell.UserID, &cell.Username, &cell.ProblemID, &cell.WrongAttempts, &cell.PendingAttempts, &cell.SolvedAt, &minutes, &bestScore, &bestMinutes); err != nil {
+			return nil, err
+		}
+		cell.SolvedMinutes = intPtrFromInt32(minutes)
+		cell.BestScore = intPtrFromInt32(bestScore)
+		cell.BestScoreMinutes = intPtrFromInt32(bestMinutes)
+		cells = append(cells, cell)
+	}
+	return cells, rows.Err()
+}
+
+// RebuildStandings recomputes every cell of a contest from submissions (e.g. after a rejudge).
+func (r *PgContestRepository) RebuildStandings(ctx context.Context, contestID int64) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var one int
+	if err := tx.QueryRow(ctx, `SELECT 1 FROM contests WHERE id=$1 FOR UPDATE`, contestID).Scan(&one); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM contest_standing_cells WHERE contest_id=$1`, contestID); err != nil {
+		return err
+	}
+	rows, err := tx.Query(ctx, `SELECT DISTINCT user_id, problem_id FROM submissions WHERE contest_id=$1`, contestID)
+	if err != nil {
+		return err
+	}
+	type pair struct{ userID, problemID int64 }
+	var pairs []pair
+	for rows.Next() {
+		var p pair
+		if err := rows.Scan(&p.userID, &p.problemID); err != nil {
+			rows.Close()
+			return err
+		}
+		pairs = append(pairs, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, p := range pairs {
+		if err := recomputeStandingCell(ctx, tx, contestID, p.userID, p.problemID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func intPtrFromInt32(v *int32) *int {
+	if v == nil {
+		return nil
+	}
+	n := int(*v)
+	return &n
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildStandingsICPC(t *testing.T) {
+	start := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	at := func(min int) time.Time { return start.Add(time.Duration(min) * time.Minute) }
+
+	// WA, CE (not penalized), AC at 30min, then a WA after AC (ignored)
+	cell := computeStandingCell(ScoringModeICPC, defaultPenaltyPolicy(), start, []standingAttempt{
+		{Verdict: "WA", CreatedAt: at(5)},
+		{Verdict: "CE", CreatedAt: at(10)},
+		{Verdict: "AC", CreatedAt: at(30)},
+		{Verdict: "WA", CreatedAt: at(40)},
+	})
+	if cell.WrongAttempts != 1 || cell.SolvedMinutes == nil || *cell.SolvedMinutes != 30 {
+		t.Fatalf("unexpected cell: %+v", cell)
+	}
+
+	problems := []ContestProblem{{ProblemID: 1, Label: "A"}, {ProblemID: 2, Label: "B"}}
+	participants := []ContestRegistration{{UserID: 10, Username: "alice"}, {UserID: 20, Username: "bob"}, {UserID: 30, Username: "carol"}}
+	solved := func(userID, problemID int64, min, wrong int) ContestStandingCell {
+		c := computeStandingCell(ScoringModeICPC, defaultPenaltyPolicy(), start, []standingAttempt{{Verdict: "AC", CreatedAt: at(min)}})
+		c.UserID, c.ProblemID, c.WrongAttempts = userID, problemID, wrong
+		return c
+	}
+	rows := buildStandings(ScoringModeICPC, defaultPenaltyPolicy(), problems, participants, []ContestStandingCell{
+		solved(10, 1, 30, 1), // alice: 30 + 20 = 50
+		solved(20, 1, 20, 0), // bob: 20 + 40 = 60
+		solved(20, 2, 40, 0),
+		{UserID: 10, ProblemID: 2, WrongAttempts: 2},
+	})
+
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(rows))
+	}
+	if rows[0].Username != "bob" || rows[0].Solved != 2 || rows[0].Penalty != 60 || rows[0].Rank != 1 {
+		t.Fatalf("unexpected first row: %+v", rows[0])
+	}
+	if rows[1].Username != "alice" || rows[1].Penalty != 50 || rows[1].Rank != 2 {
+		t.Fatalf("unexpected second row: %+v", rows[1])
+	}
+	if rows[2].Username != "carol" || rows[2].Solved != 0 || rows[2].Rank != 3 {
+		t.Fatalf("unexpected third row: %+v", rows[2])
+	}
+	if !rows[0].Cells[0].FirstSolve || rows[1].Cells[0].FirstSolve {
+		t.Fatalf("first solve of A should belong to bob")
+	}
+}
+
+func TestBuildStandingsIOI(t *testing.T) {
+	start := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	at := func(min int) time.Time { return start.Add(time.Duration(min) * time.Minute) }
+	score := func(v int) *int { return &v }
+
+	// best score is kept even if a later attempt scores lower
+	cell := computeStandingCell(ScoringModeIOI, defaultPenaltyPolicy(), start, []standingAttempt{
+		{Verdict: "WA", Score: score(40), CreatedAt: at(5)},
+		{Verdict: "WA", Score: score(70), CreatedAt: at(20)},
+		{Verdict: "WA", Score: score(30), CreatedAt: at(25)},
+	})
+	if cell.BestScore == nil || *cell.BestScore != 70 || *cell.BestScoreMinutes != 20 || cell.SolvedAt != nil {
+		t.Fatalf("unexpected cell: %+v", cell)
+	}
+
+	problems := []ContestProblem{{ProblemID: 1, Label: "A"}, {ProblemID: 2, Label: "B"}}
+	cell.UserID, cell.Username, cell.ProblemID = 10, "alice", 1
+	full := computeStandingCell(ScoringModeIOI, defaultPenaltyPolicy(), start, []standingAttempt{{Verdict: "AC", Score: score(100), CreatedAt: at(50)}})
+	full.UserID, full.Username, full.ProblemID = 20, "bob", 2
+	rows := buildStandings(ScoringModeIOI, defaultPenaltyPolicy(), problems, nil, []ContestStandingCell{cell, full})
+	if len(rows) != 2 || rows[0].Username != "bob" || rows[0].Score != 100 || rows[0].Solved != 1 || rows[1].Score != 70 {
+		t.Fatalf("unexpected rows: %+v", rows)
+	}
+}
@@ -0,0 +1,114 @@
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// transcriptVersion is embedded in every transcript so the signed format can evolve.
+const transcriptVersion = "v1"
+
+// ContestTranscript is the certified record of one participant's contest results.
+// Every field is covered by the signature; times are UTC so the JSON round-trips unchanged.
+type ContestTranscript struct {
+	Version      string              `json:"v"`
+	ContestID    int64               `json:"contest_id"`
+	ContestSlug  string              `json:"contest_slug"`
+	ContestTitle string              `json:"contest_title"`
+	ScoringMode  string              `json:"scoring_mode"`
+	StartAt      time.Time           `json:"start_at"`
+	EndAt        time.Time           `json:"end_at"`
+	UserID       int64               `json:"user_id"`
+	Username     string              `json:"userid"`
+	Rank         int                 `json:"rank"`
+	Participants int                 `json:"participants"`
+	Solved       int                 `json:"solved"`
+	Penalty      int                 `json:"penalty"`
+	Score        int                 `json:"score"`
+	Problems     []TranscriptProblem `json:"problems"`
+	IssuedAt     time.Time           `json:"issued_at"`
+}
+
+// TranscriptProblem is the participant's outcome on one (assigned) contest problem.
+type TranscriptProblem struct {
+	Label         string     `json:"label"`
+	Title         string     `json:"title"`
+	Solved        bool       `json:"solved"`
+	SolvedAt      *time.Time `json:"solved_at,omitempty"`
+	SolvedMinutes *int       `json:"solved_minutes,omitempty"`
+	WrongAttempts int        `json:"wrong_attempts"`
+	BestScore     *int       `json:"best_score,omitempty"`
+}
+
+// SignedTranscript is what users download and what the verification endpoint accepts.
+type SignedTranscript struct {
+	Transcript ContestTranscript `json:"transcript"`
+	Signature  string            `json:"signature"`
+}
+
+// buildContestTranscript extracts userID's row from the standings. Problems from pools the user
+// was not assigned are left out. It returns false when the user is not on the scoreboard.
+func buildContestTranscript(contest Contest, problems []ContestProblem, rows []ContestStandingRow, userID int64, now time.Time) (*ContestTranscript, bool) {
+	for _, row := range rows {
+		if row.UserID != userID {
+			continue
+		}
+		t := &ContestTranscript{
+			Version:      transcriptVersion,
+			ContestID:    contest.ID,
+			ContestSlug:  contest.Slug,
+			ContestTitle: contest.Title,
+			ScoringMode:  contest.ScoringMode,
+			StartAt:      contest.StartAt.UTC(),
+			EndAt:        contest.EndAt.UTC(),
+			UserID:       row.UserID,
+			Username:     row.Username,
+			Rank:         row.Rank,
+			Participants: len(rows),
+			Solved:       row.Solved,
+			Penalty:      row.Penalty,
+			Score:        row.Score,
+			Problems:     []TranscriptProblem{},
+			IssuedAt:     now.UTC().Truncate(time.Second),
+		}
+		assigned := assignContestProblems(contest.ID, userID, problems)
+		for i, p := range problems {
+			if !containsContestProblem(assigned, p.ProblemID) || i >= len(row.Cells) {
+				continue
+			}
+			cell := row.Cells[i]
+			tp := TranscriptProblem{
+				Label:         p.Label,
+				Title:         p.Title,
+				Solved:        cell.SolvedAt != nil,
+				SolvedMinutes: cell.SolvedMinutes,
+				WrongAttempts: cell.WrongAttempts,
+				BestScore:     cell.BestScore,
+			}
+			if cell.SolvedAt != nil {
+				at := cell.SolvedAt.UTC()
+				tp.SolvedAt = &at
+			}
+			t.Problems = append(t.Problems, tp)
+		}
+		return t, true
+	}
+	return nil, false
+}
+
+// SignTranscript returns the hex HMAC-SHA256 of the transcript's JSON encoding.
+func SignTranscript(key []byte, t ContestTranscript) string {
+	data, _ := json.Marshal(t) // 固定フィールドの構造体なので失敗しない
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyTranscript reports whether s carries a valid signature for its transcript.
+func VerifyTranscript(key []byte, s SignedTranscript) bool {
+	return len(key) > 0 && s.Transcript.Version == transcriptVersion &&
+		hmac.Equal([]byte(s.Signature), []byte(SignTranscript(key, s.Transcript)))
+}
@@ -0,0 +1,44 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestContestTranscriptSignatureRoundTrip(t *testing.T) {
+	start := time.Date(2026, 4, 1, 21, 0, 0, 0, time.FixedZone("JST", 9*3600))
+	solvedAt := start.Add(12 * time.Minute)
+	minutes := 12
+	contest := Contest{ID: 3, Slug: "abc", Title: "ABC", ScoringMode: ScoringModeICPC, StartAt: start, EndAt: start.Add(time.Hour)}
+	problems := []ContestProblem{{ProblemID: 1, Label: "A", Title: "Sum"}, {ProblemID: 2, Label: "B", Title: "Sort"}}
+	rows := []ContestStandingRow{{Rank: 1, UserID: 7, Username: "alice", Solved: 1, Penalty: 32, Cells: []ContestStandingCell{
+		{ProblemID: 1, Label: "A", SolvedAt: &solvedAt, SolvedMinutes: &minutes, WrongAttempts: 1},
+		{ProblemID: 2, Label: "B", WrongAttempts: 2},
+	}}}
+
+	if _, ok := buildContestTranscript(contest, problems, rows, 8, time.Now()); ok {
+		t.Fatal("transcript issued for a non-participant")
+	}
+	tr, ok := buildContestTranscript(contest, problems, rows, 7, time.Now())
+	if !ok || len(tr.Problems) != 2 || !tr.Problems[0].Solved || tr.Problems[1].Solved {
+		t.Fatalf("unexpected transcript %+v", tr)
+	}
+	key := []byte("transcript-key")
+	data, err := json.Marshal(SignedTranscript{Transcript: *tr, Signature: SignTranscript(key, *tr)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ダウンロードした JSON をそのまま検証に回しても署名が一致する
+	var got SignedTranscript
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !VerifyTranscript(key, got) {
+		t.Fatal("round-tripped transcript did not verify")
+	}
+	got.Transcript.Rank = 2
+	if VerifyTranscript(key, got) {
+		t.Fatal("tampered transcript verified")
+	}
+}
@@ -0,0 +1,171 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	maxEmailLen             = 254
+	emailVerificationTTL    = 24 * time.Hour
+	emailVerifyMailInterval = time.Minute // 同じユーザーへの再送間隔
+)
+
+var (
+	ErrEmailInput             = errors.New("invalid email")
+	ErrEmailTaken             = errors.New("email is used by another user")
+	ErrEmailVerificationToken = errors.New("invalid or expired verification token")
+)
+
+// normalizeEmail checks a bare address (no display name) and returns it trimmed.
+func normalizeEmail(raw string) (string, error) {
+	email := strings.TrimSpace(raw)
+	if email == "" || len(email) > maxEmailLen {
+		return "", fmt.Errorf("%w: メールアドレスを %d 文字以内で指定してください", ErrEmailInput, maxEmailLen)
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", fmt.Errorf("%w: メールアドレスの形式が正しくありません", ErrEmailInput)
+	}
+	return email, nil
+}
+
+// emailVerificationEnabled reports whether verification mails can be sent.
+func emailVerificationEnabled(cfg Config) bool {
+	return mailConfigured(cfg) && cfg.OAuthPublicURL != ""
+}
+
+func emailVerificationMail(publicURL string, user UserRecord, token string, expiresAt time.Time) (string, string) {
+	link := publicURL + "/verify_email?token=" + token
+	locale := user.PreferredLocale()
+	expires := formatLocalTime(expiresAt, user.Location(), locale)
+	hours := int(emailVerificationTTL / time.Hour)
+	if locale == "en" {
+		body := fmt.Sprintf("Hello %s,\n\n"+
+			"To verify your email address, open the link below within %d hours (until %s).\n\n"+
+			"%s\n\n"+
+			"If you did not request this, please ignore this email.\n",
+			user.Username, hours, expires, link)
+		return "Verify your email address", body
+	}
+	body := fmt.Sprintf("%s さん\n\n"+
+		"メールアドレスの確認のため、次のリンクを %d 時間以内（%s まで）に開いてください。\n\n"+
+		"%s\n\n"+
+		"心当たりがない場合はこのメールを破棄してください。\n",
+		user.Username, hours, expires, link)
+	return "メールアドレスの確認", body
+}
+
+// UserEmail is the address of a user and whether it has been verified.
+type UserEmail struct {
+	Email      *string    `json:"email"`
+	VerifiedAt *time.Time `json:"verified_at"`
+}
+
+// UserEmailRepository stores users' addresses and their verification.
+type UserEmailRepository interface {
+	Get(ctx context.Context, userID int64) (*UserEmail, error)
+	// Set sets (nil clears) the address. Changing it drops the verification; it returns ErrEmailTaken
+	// when another user has the address.
+	Set(ctx context.Context, userID int64, email *string) (*UserEmail, error)
+	// CreateToken stores a verification token for the user's current address unless one was issued
+	// within emailVerifyMailInterval; it reports whether the token was stored.
+	CreateToken(ctx context.Context, userID int64, email, tokenHash string, expiresAt time.Time) (bool, error)
+	// Verify marks the address the token was issued for as verified. It returns
+	// ErrEmailVerificationToken for unknown, used or expired tokens and for addresses changed since.
+	Verify(ctx context.Context, tokenHash string) (int64, error)
+	IsVerified(ctx context.Context, userID int64) (bool, error)
+}
+
+type PgUserEmailRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPgUserEmailRepository(db *pgxpool.Pool) *PgUserEmailRepository {
+	return &PgUserEmailRepository{db: db}
+}
+
+func (r *PgUserEmailRepository) Get(ctx context.Context, userID int64) (*UserEmail, error) {
+	var e UserEmail
+	if err := r.db.QueryRow(ctx, `SELECT email, email_verified_at FROM users WHERE id=$1 AND deleted_at IS NULL`, userID).
+		Scan(&e.Email, &e.VerifiedAt); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *PgUserEmailRepository) Set(ctx context.Context, userID int64, email *string) (*UserEmail, error) {
+	var e UserEmail
+	err := r.db.QueryRow(ctx, `
+UPDATE users SET
+    email_verified_at = CASE WHEN lower(email) IS NOT DISTINCT FROM lower($2) THEN email_verified_at END,
+    email = $2
+WHERE id=$1 AND deleted_at IS NULL
+RETURNING email, email_verified_at`, userID, email).Scan(&e.Email, &e.VerifiedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrEmailTaken
+		}
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *PgUserEmailRepository) CreateToken(ctx context.Context, userID int64, email, tokenHash string, expiresAt time.Time) (bool, error) {
+	if _, err := r.db.Exec(ctx, `DELETE FROM email_verification_tokens WHERE user_id=$1 AND expires_at < NOW()`, userID); err != nil {
+		return false, err
+	}
+	ct, err := r.db.Exec(ctx, `
+INSERT INTO email_verification_tokens (token_hash, user_id, email, expires_at)
+SELECT $2, $1, $3, $4
+WHERE NOT EXISTS (
+    SELECT 1 FROM email_verification_tokens WHERE user_id=$1 AND created_at > NOW() - $5::interval
+)`, userID, tokenHash, email, expiresAt, fmt.Sprintf("%d seconds", int(emailVerifyMailInterval/time.Second)))
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() == 1, nil
+}
+
+func (r *PgUserEmailRepository) Verify(ctx context.Context, tokenHash string) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var userID int64
+	err = tx.QueryRow(ctx, `
+SELECT t.user_id FROM email_verification_tokens t
+JOIN users u ON u.id = t.user_id AND u.deleted_at IS NULL AND lower(u.email) = lower(t.email)
+WHERE t.token_hash=$1 AND t.used_at IS NULL AND t.expires_at > NOW()
+FOR UPDATE OF t, u`, tokenHash).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrEmailVerificationToken
+	}
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE users SET email_verified_at=COALESCE(email_verified_at, NOW()) WHERE id=$1`, userID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE email_verification_tokens SET used_at=NOW() WHERE user_id=$1 AND used_at IS NULL`, userID); err != nil {
+		return 0, err
+	}
+	return userID, tx.Commit(ctx)
+}
+
+func (r *PgUserEmailRepository) IsVerified(ctx context.Context, userID int64) (bool, error) {
+	var verified bool
+	err := r.db.QueryRow(ctx, `SELECT email IS NOT NULL AND email_verified_at IS NOT NULL FROM users WHERE id=$1`, userID).Scan(&verified)
+	return verified, err
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmailVerificationMail(t *testing.T) {
+	expiresAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	subject, body := emailVerificationMail("https://oj.example.com", UserRecord{Username: "alice"}, "abc123", expiresAt)
+	if subject == "" || !strings.Contains(body, "https://oj.example.com/verify_email?token=abc123") || !strings.Contains(body, "24 時間") {
+		t.Fatalf("unexpected mail: %q / %q", subject, body)
+	}
+	if subject, body := emailVerificationMail("https://oj.example.com", UserRecord{Username: "alice", Locale: "EN", Timezone: "UTC"}, "abc123", expiresAt); subject != "Verify your email address" || !strings.Contains(body, "24 hours (until Jan 2, 2025 03:04:05 UTC)") {
+		t.Fatalf("unexpected english mail: %q / %q", subject, body)
+	}
+}
+
+func TestSendGridMailer(t *testing.T) {
+	var got struct {
+		Personalizations []struct {
+			To []struct {
+				Email string `json:"email"`
+			} `json:"to"`
+		} `json:"personalizations"`
+		From struct {
+			Email string `json:"email"`
+		} `json:"from"`
+		Subject string `json:"subject"`
+	}
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	cfg := Config{SendGridAPIKey: "SG.key", SMTPAddr: "smtp.example.com:587", MailFrom: "oj@example.com"}
+	m, ok := NewMailer(cfg).(*SendGridMailer)
+	if !ok {
+		t.Fatal("expected SendGrid to take precedence over SMTP")
+	}
+	m.Endpoint = srv.URL
+	if err := m.Send(context.Background(), "alice@example.com", "件名", "本文"); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Bearer SG.key" || got.From.Email != "oj@example.com" || got.Subject != "件名" ||
+		len(got.Personalizations) != 1 || got.Personalizations[0].To[0].Email != "alice@example.com" {
+		t.Fatalf("unexpected request: %q %+v", auth, got)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad key", http.StatusUnauthorized)
+	}))
+	defer failing.Close()
+	m.Endpoint = failing.URL
+	if err := m.Send(context.Background(), "alice@example.com", "s", "b"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected status error, got %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Event is a message delivered to live subscribers (SSE / WebSocket).
+type Event struct {
+	Type string `json:"type"`
+	ID   int64  `json:"id,omitempty"`
+	// UserID addresses events on UserEventChannel to a single user.
+	UserID int64           `json:"user_id,omitempty"`
+	Data   json.RawMessage `json:"data"`
+	// Channel is the pub/sub channel the event arrived on (set by Subscribe).
+	Channel string `json:"-"`
+}
+
+// EventBus fans out events across API instances via Redis pub/sub.
+type EventBus struct {
+	client *redis.Client
+}
+
+func NewEventBus(client *redis.Client) *EventBus {
+	return &EventBus{client: client}
+}
+
+// ContestEventChannel is the pub/sub channel for live events of a contest.
+func ContestEventChannel(contestID int64) string {
+	return "contest:" + strconv.FormatInt(contestID, 10) + ":events"
+}
+
+// UserEventChannel carries per-user events (verdicts) of all users; subscribers filter by Event.UserID.
+const UserEventChannel = "users:events"
+
+// NoticeEventChannel carries newly published notices.
+const NoticeEventChannel = "notices:events"
+
+// AdminEventChannel carries events for staff (system errors).
+const AdminEventChannel = "admin:events"
+
+// Publish sends an event with data marshalled as JSON.
+func (b *EventBus) Publish(ctx context.Context, channel, eventType string, id int64, data any) error {
+	return b.publish(ctx, channel, Event{Type: eventType, ID: id}, data)
+}
+
+// PublishToUser sends an event addressed to one user on UserEventChannel.
+func (b *EventBus) PublishToUser(ctx context.Context, userID int64, eventType string, id int64, data any) error {
+	return b.publish(ctx, UserEventChannel, Event{Type: eventType, ID: id, UserID: userID}, data)
+}
+
+func (b *EventBus) publish(ctx context.Context, channel string, ev Event, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	ev.Data = raw
+	msg, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, channel, msg).Err()
+}
+
+// Subscribe returns a channel of events published to any of channels until ctx is done.
+// Malformed messages are skipped.
+func (b *EventBus) Subscribe(ctx context.Context, channels ...string) (<-chan Event, error) {
+	sub := b.client.Subscribe(ctx, channels...)
+	// 購読が確立してから返す（直後の Publish を取りこぼさない）
+	if _, err := sub.Receive(ctx); err != nil {
+		_ = sub.Close()
+		return nil, err
+	}
+	out := make(chan Event, 16)
+	go func() {
+		defer close(out)
+		defer sub.Close()
+		msgs := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case m, ok := <-msgs:
+				if !ok {
+					return
+				}
+				var ev Event
+				if err := json.Unmarshal([]byte(m.Payload), &ev); err != nil {
+					log.Printf("[events] malformed message on %s: %v", m.Channel, err)
+					continue
+				}
+				ev.Channel = m.Channel
+				select {
+				case out <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,476 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Delivery states.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed" // 再送上限に達した
+)
+
+const (
+	graderWebhookEvent       = "submission.judged"
+	graderWebhookMaxAttempts = 8
+	graderWebhookBaseBackoff = 30 * time.Second
+	graderWebhookMaxBackoff  = time.Hour
+	graderWebhookBatchSize   = 20
+	// graderWebhookLease は送信中の配信を他プロセスが重ねて取らないよう先送りする時間
+	graderWebhookLease = 2 * time.Minute
+)
+
+var (
+	ErrGraderWebhookTarget = errors.New("contest_id and problem_id cannot both be set")
+	ErrGraderWebhookURL    = errors.New("url must be an absolute http(s) URL")
+)
+
+// Webhook scopes.
+const (
+	GraderWebhookScopeGlobal  = "global"
+	GraderWebhookScopeContest = "contest"
+	GraderWebhookScopeProblem = "problem"
+)
+
+// GraderWebhook sends judged results of a contest (assignment), a single problem, or every
+// submission (global) to an external grader.
+type GraderWebhook struct {
+	ID        int64     `json:"id"`
+	Scope     string    `json:"scope"`
+	ContestID *int64    `json:"contest_id"`
+	ProblemID *int64    `json:"problem_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"` // 作成時のみ返す
+	IsActive  bool      `json:"is_active"`
+	CreatedBy *int64    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GraderWebhookDelivery is one queued / attempted delivery of a result.
+type GraderWebhookDelivery struct {
+	ID             int64           `json:"id"`
+	WebhookID      int64           `json:"webhook_id"`
+	SubmissionID   int64           `json:"submission_id"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at"`
+	LastStatusCode *int            `json:"last_status_code"`
+	LastError      *string         `json:"last_error"`
+	Payload        json.RawMessage `json:"payload"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// GraderWebhookPayload is the JSON body POSTed to the grader.
+type GraderWebhookPayload struct {
+	Event        string    `json:"event"`
+	SubmissionID int64     `json:"submission_id"`
+	UserID       int64     `json:"user_id"`
+	Username     string    `json:"userid"`
+	ProblemID    int64     `json:"problem_id"`
+	ProblemSlug  string    `json:"problem_slug"`
+	ContestID    *int64    `json:"contest_id"`
+	Language     string    `json:"language"`
+	Verdict      string    `json:"verdict"`
+	Score        *int32    `json:"score"`
+	MaxScore     *int32    `json:"max_score"`
+	TimeMS       *int32    `json:"time_ms"`
+	MemoryKB     *int32    `json:"memory_kb"`
+	PassedCount  int32     `json:"passed_count"`
+	TotalCount   int32     `json:"total_count"`
+	SubmittedAt  time.Time `json:"submitted_at"`
+	JudgedAt     time.Time `json:"judged_at"`
+}
+
+// GraderWebhookInput creates a webhook for ContestID, ProblemID, or neither (global).
+// An empty Secret is generated.
+type GraderWebhookInput struct {
+	ContestID *int64
+	ProblemID *int64
+	URL       string
+	Secret    string
+	CreatedBy int64
+}
+
+// GraderWebhookFilter narrows List; the zero value lists every webhook.
+type GraderWebhookFilter struct {
+	ContestID *int64
+	ProblemID *int64
+	Scope     string
+}
+
+// GraderWebhookRepository stores grader webhooks and their delivery log.
+type GraderWebhookRepository interface {
+	List(ctx context.Context, filter GraderWebhookFilter) ([]GraderWebhook, error)
+	Create(ctx context.Context, input GraderWebhookInput) (*GraderWebhook, error)
+	Update(ctx context.Context, id int64, url *string, isActive *bool) (*GraderWebhook, error)
+	Delete(ctx context.Context, id int64) error
+	ListDeliveries(ctx context.Context, webhookID int64, page, perPage int) ([]GraderWebhookDelivery, int, error)
+	// RetryDelivery puts a delivery back to pending for an immediate attempt.
+	RetryDelivery(ctx context.Context, id int64) (*GraderWebhookDelivery, error)
+}
+
+type PgGraderWebhookRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPgGraderWebhookRepository(db *pgxpool.Pool) *PgGraderWebhookRepository {
+	return &PgGraderWebhookRepository{db: db}
+}
+
+// validateWebhookURL accepts absolute http / https URLs only.
+func validateWebhookURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", ErrGraderWebhookURL
+	}
+	return raw, nil
+}
+
+const graderWebhookColumns = `id, contest_id, problem_id, url, is_active, created_by, created_at`
+
+func scanGraderWebhook(row pgx.Row) (*GraderWebhook, error) {
+	var w GraderWebhook
+	if err := row.Scan(&w.ID, &w.ContestID, &w.ProblemID, &w.URL, &w.IsActive, &w.CreatedBy, &w.CreatedAt); err != nil {
+		return nil, err
+	}
+	switch {
+	case w.ContestID != nil:
+		w.Scope = GraderWebhookScopeContest
+	case w.ProblemID != nil:
+		w.Scope = GraderWebhookScopeProblem
+	default:
+		w.Scope = GraderWebhookScopeGlobal
+	}
+	return &w, nil
+}
+
+func (r *PgGraderWebhookRepository) List(ctx context.Context, filter GraderWebhookFilter) ([]GraderWebhook, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+graderWebhookColumns+` FROM grader_webhooks
+WHERE ($1::BIGINT IS NULL OR contest_id=$1) AND ($2::BIGINT IS NULL OR problem_id=$2)
+  AND ($3 = '' OR ($3 = 'global' AND contest_id IS NULL AND problem_id IS NULL)
+       OR ($3 = 'contest' AND contest_id IS NOT NULL) OR ($3 = 'problem' AND problem_id IS NOT NULL))
+ORDER BY id`, filter.ContestID, filter.ProblemID, filter.Scope)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []GraderWebhook{}
+	for rows.Next() {
+		w, err := scanGraderWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *w)
+	}
+	return out, rows.Err()
+}
+
+func (r *PgGraderWebhookRepository) Create(ctx context.Context, input GraderWebhookInput) (*GraderWebhook, error) {
+	if input.ContestID != nil && input.ProblemID != nil {
+		return nil, ErrGraderWebhookTarget
+	}
+	u, err := validateWebhookURL(input.URL)
+	if err != nil {
+		return nil, err
+	}
+	secret := strings.TrimSpace(input.Secret)
+	if secret == "" {
+		secret = randomHex(32)
+	}
+	// 対象のコンテスト / 問題が無ければ行が返らず pgx.ErrNoRows になる
+	w, err := scanGraderWebhook(r.db.QueryRow(ctx, `INSERT INTO grader_webhooks (contest_id, problem_id, url, secret, created_by)
+SELECT $1::BIGINT, $2::BIGINT, $3, $4, $5
+WHERE ($1::BIGINT IS NULL OR EXISTS (SELECT 1 FROM contests WHERE id=$1))
+  AND ($2::BIGINT IS NULL OR EXISTS (SELECT 1 FROM problems WHERE id=$2))
+RETURNING `+graderWebhookColumns, input.ContestID, input.ProblemID, u, secret, input.CreatedBy))
+	if err != nil {
+		return nil, err
+	}
+	w.Secret = secret
+	return w, nil
+}
+
+func (r *PgGraderWebhookRepository) Update(ctx context.Context, id int64, rawURL *string, isActive *bool) (*GraderWebhook, error) {
+	var u *string
+	if rawURL != nil {
+		v, err := validateWebhookURL(*rawURL)
+		if err != nil {
+			return nil, err
+		}
+		u = &v
+	}
+	return scanGraderWebhook(r.db.QueryRow(ctx, `UPDATE grader_webhooks SET url=COALESCE($2, url), is_active=COALESCE($3, is_active)
+WHERE id=$1 RETURNING `+graderWebhookColumns, id, u, isActive))
+}
+
+func (r *PgGraderWebhookRepository) Delete(ctx context.Context, id int64) error {
+	ct, err := r.db.Exec(ctx, `DELETE FROM grader_webhooks WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+const graderDeliveryColumns = `id, webhook_id, submission_id, status, attempts, next_attempt_at, last_status_code, last_error, payload, created_at, updated_at`
+
+func scanGraderDelivery(row pgx.Row) (*GraderWebhookDelivery, error) {
+	var d GraderWebhookDelivery
+	var next time.Time
+	var payload []byte
+	if err := row.Scan(&d.ID, &d.WebhookID, &d.SubmissionID, &d.Status, &d.Attempts, &next, &d.LastStatusCode, &d.LastError, &payload, &d.CreatedAt, &d.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if d.Status == WebhookDeliveryPending {
+		d.NextAttemptAt = &next
+	}
+	d.Payload = payload
+	return &d, nil
+}
+
+// ListDeliveries returns the delivery log of a webhook, newest first.
+func (r *PgGraderWebhookRepository) ListDeliveries(ctx context.Context, webhookID int64, page, perPage int) ([]GraderWebhookDelivery, int, error) {
+	if page <= 0 || perPage <= 0 {
+		return nil, 0, errors.New("invalid pagination")
+	}
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM grader_webhook_deliveries WHERE webhook_id=$1`, webhookID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := r.db.Query(ctx, `SELECT `+graderDeliveryColumns+` FROM grader_webhook_deliveries WHERE webhook_id=$1 ORDER BY id DESC LIMIT $2 OFFSET $3`,
+		webhookID, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	items := make([]GraderWebhookDelivery, 0, perPage)
+	for rows.Next() {
+		d, err := scanGraderDelivery(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		items = append(items, *d)
+	}
+	return items, total, rows.Err()
+}
+
+func (r *PgGraderWebhookRepository) RetryDelivery(ctx context.Context, id int64) (*GraderWebhookDelivery, error) {
+	return scanGraderDelivery(r.db.QueryRow(ctx, `UPDATE grader_webhook_deliveries
+SET status='pending', attempts=0, next_attempt_at=NOW(), updated_at=NOW()
+WHERE id=$1 RETURNING `+graderDeliveryColumns, id))
+}
+
+// enqueueGraderWebhooks queues the result for every active global webhook and every active webhook
+// of the submission's contest or problem.
+// It runs inside SaveResult so a result is never saved without its deliveries.
+func enqueueGraderWebhooks(ctx context.Context, q pgQuerier, result SubmissionResult) error {
+	rows, err := q.Query(ctx, `SELECT w.id FROM grader_webhooks w
+JOIN submissions s ON s.id=$1
+WHERE w.is_active AND ((w.contest_id IS NULL AND w.problem_id IS NULL)
+    OR w.contest_id = s.contest_id OR w.problem_id = s.problem_id)`, result.SubmissionID)
+	if err != nil {
+		return err
+	}
+	var hookIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		hookIDs = append(hookIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(hookIDs) == 0 {
+		return err
+	}
+
+	p := GraderWebhookPayload{
+		Event:        graderWebhookEvent,
+		SubmissionID: result.SubmissionID,
+		Verdict:      result.Verdict,
+		Score:        result.Score,
+		MaxScore:     result.MaxScore,
+		TimeMS:       result.TimeMS,
+		MemoryKB:     result.MemoryKB,
+		PassedCount:  result.PassedCount,
+		TotalCount:   result.TotalCount,
+		JudgedAt:     time.Now(),
+	}
+	if err := q.QueryRow(ctx, `SELECT s.user_id, u.username, s.problem_id, p.slug, s.contest_id, s.language, s.created_at
+FROM submissions s
+JOIN users u ON u.id = s.user_id
+JOIN problems p ON p.id = s.problem_id
+WHERE s.id=$1`, result.SubmissionID).Scan(&p.UserID, &p.Username, &p.ProblemID, &p.ProblemSlug, &p.ContestID, &p.Language, &p.SubmittedAt); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(ctx, `INSERT INTO grader_webhook_deliveries (webhook_id, submission_id, payload)
+SELECT unnest($1::BIGINT[]), $2, $3`, hookIDs, result.SubmissionID, payload)
+	return err
+}
+
+// signGraderWebhook returns the X-OJ-Signature header: t=<unix>,v1=<hex HMAC-SHA256 of "<unix>.<body>">.
+// The timestamp lets receivers reject replays.
+func signGraderWebhook(secret string, ts int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts, 10)))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return fmt.Sprintf("t=%d,v1=%s", ts, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// graderWebhookBackoff is the wait before attempt n+1 after n failed attempts.
+func graderWebhookBackoff(attempts int) time.Duration {
+	d := graderWebhookBaseBackoff
+	for i := 1; i < attempts && d < graderWebhookMaxBackoff; i++ {
+		d *= 2
+	}
+	if d > graderWebhookMaxBackoff {
+		d = graderWebhookMaxBackoff
+	}
+	return d
+}
+
+// GraderWebhookDispatcher sends queued deliveries and reschedules failures with exponential backoff.
+// Deliveries are claimed with SKIP LOCKED, so several API processes can run it.
+type GraderWebhookDispatcher struct {
+	db     *pgxpool.Pool
+	client *http.Client
+}
+
+func NewGraderWebhookDispatcher(db *pgxpool.Pool) *GraderWebhookDispatcher {
+	return &GraderWebhookDispatcher{db: db, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Run delivers due webhooks every interval until ctx is cancelled.
+func (d *GraderWebhookDispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for {
+			n, err := d.DeliverDue(ctx, time.Now())
+			if err != nil {
+				log.Printf("[webhook] delivery failed: %v", err)
+			}
+			if err != nil || n < graderWebhookBatchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type graderDeliveryJob struct {
+	id       int64
+	payload  []byte
+	attempts int
+	url      string
+	secret   string
+}
+
+// DeliverDue sends one batch of due deliveries and returns how many were attempted.
+func (d *GraderWebhookDispatcher) DeliverDue(ctx context.Context, now time.Time) (int, error) {
+	rows, err := d.db.Query(ctx, `
+UPDATE grader_webhook_deliveries d
+SET attempts = d.attempts + 1, next_attempt_at = $2, updated_at = NOW()
+FROM grader_webhooks w
+WHERE w.id = d.webhook_id AND d.id IN (
+    SELECT gd.id FROM grader_webhook_deliveries gd
+    JOIN grader_webhooks gw ON gw.id = gd.webhook_id
+    WHERE gd.status = 'pending' AND gd.next_attempt_at <= $1 AND gw.is_active
+    ORDER BY gd.next_attempt_at
+    LIMIT $3
+    FOR UPDATE OF gd SKIP LOCKED)
+RETURNING d.id, d.payload, d.attempts, w.url, w.secret`, now, now.Add(graderWebhookLease), graderWebhookBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	var jobs []graderDeliveryJob
+	for rows.Next() {
+		var j graderDeliveryJob
+		if err := rows.Scan(&j.id, &j.payload, &j.attempts, &j.url, &j.secret); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		jobs = append(jobs, j)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, j := range jobs {
+		code, sendErr := d.send(ctx, j)
+		var codePtr *int
+		if code > 0 {
+			codePtr = &code
+		}
+		if sendErr == nil {
+			_, err = d.db.Exec(ctx, `UPDATE grader_webhook_deliveries SET status='delivered', last_status_code=$2, last_error=NULL, updated_at=NOW() WHERE id=$1`, j.id, codePtr)
+		} else {
+			msg := sendErr.Error()
+			status := WebhookDeliveryPending
+			if j.attempts >= graderWebhookMaxAttempts {
+				status = WebhookDeliveryFailed
+				log.Printf("[webhook] delivery %d gave up after %d attempts: %v", j.id, j.attempts, sendErr)
+			}
+			_, err = d.db.Exec(ctx, `UPDATE grader_webhook_deliveries SET status=$2, last_status_code=$3, last_error=$4, next_attempt_at=$5, updated_at=NOW() WHERE id=$1`,
+				j.id, status, codePtr, msg, time.Now().Add(graderWebhookBackoff(j.attempts)))
+		}
+		if err != nil {
+			return len(jobs), err
+		}
+	}
+	return len(jobs), nil
+}
+
+func (d *GraderWebhookDispatcher) send(ctx context.Context, j graderDeliveryJob) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, j.url, bytes.NewReader(j.payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-OJ-Event", graderWebhookEvent)
+	req.Header.Set("X-OJ-Delivery", strconv.FormatInt(j.id, 10))
+	req.Header.Set("X-OJ-Signature", signGraderWebhook(j.secret, time.Now().Unix(), j.payload))
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
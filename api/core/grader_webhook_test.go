@@ -0,0 +1,32 @@
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+func TestSignGraderWebhook(t *testing.T) {
+	body := []byte(`{"event":"submission.judged"}`)
+	got := signGraderWebhook("secret", 1700000000, body)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000." + string(body)))
+	if want := "t=1700000000,v1=" + hex.EncodeToString(mac.Sum(nil)); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+	if signGraderWebhook("other", 1700000000, body) == got {
+		t.Fatal("signature must depend on the secret")
+	}
+}
+
+func TestGraderWebhookBackoff(t *testing.T) {
+	tests := map[int]time.Duration{1: 30 * time.Second, 2: time.Minute, 4: 4 * time.Minute, 8: time.Hour, 50: time.Hour}
+	for attempts, want := range tests {
+		if got := graderWebhookBackoff(attempts); got != want {
+			t.Fatalf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
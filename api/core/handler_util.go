@@ -1,8 +1,47 @@
-package core
-
-import "github.com/gin-gonic/gin"
-
-// respondError sends unified error payload {"error": {"code", "message"}}.
-func respondError(c *gin.Context, status int, code, message string) {
-	c.JSON(status, gin.H{"error": gin.H{"code": code, "message": message}})
-}
+package core
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/sessions"
+)
+
+// respondError sends unified error payload {"error": {"code", "message"}}.
+func respondError(c *gin.Context, status int, code, message string) {
+	c.JSON(status, gin.H{"error": gin.H{"code": code, "message": message}})
+}
+
+// parseIDParam parses a positive integer path parameter; responds 400 on failure.
+func parseIDParam(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil || id <= 0 {
+		respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid "+name)
+		return 0, false
+	}
+	return id, true
+}
+
+// sessionRole returns the role stored in the session ("" for anonymous).
+func sessionRole(c *gin.Context) string {
+	sessionAny, _ := c.Get("session")
+	sess, _ := sessionAny.(*sessions.Session)
+	if sess == nil {
+		return ""
+	}
+	role, _ := sess.Values["role"].(string)
+	return role
+}
+
+// sessionUsername returns the userid stored in the session ("" for anonymous).
+func sessionUsername(c *gin.Context) string {
+	sessionAny, _ := c.Get("session")
+	sess, _ := sessionAny.(*sessions.Session)
+	if sess == nil {
+		return ""
+	}
+	userid, _ := sess.Values["userid"].(string)
+	return strings.TrimSpace(userid)
+}
@@ -0,0 +1,278 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// HealthSnapshotInterval is how often the API records a health snapshot.
+	HealthSnapshotInterval = time.Minute
+	healthProbeTimeout     = 5 * time.Second
+	healthRetention        = 400 * 24 * time.Hour
+	// maxPendingHealthSnapshots bounds snapshots kept in memory while the DB is unreachable (1 day).
+	maxPendingHealthSnapshots = 1440
+)
+
+// HealthSnapshot is one probe of the services the API depends on.
+type HealthSnapshot struct {
+	TakenAt        time.Time
+	DBOK           bool
+	DBLatencyMS    *int32
+	RedisOK        bool
+	RedisLatencyMS *int32
+	JudgeOK        bool
+	JudgeLatencyMS *int32
+}
+
+// HealthDayCounts aggregates the snapshots of one calendar day.
+type HealthDayCounts struct {
+	Date                   time.Time
+	Samples                int
+	DBOK, RedisOK          int
+	JudgeOK, AllOK         int
+	DBMS, RedisMS, JudgeMS *float64
+}
+
+// HealthDay is the availability of one day in percent. API availability counts
+// missing snapshots as downtime; the other components are measured over the snapshots taken.
+type HealthDay struct {
+	Date           string   `json:"date"`
+	Samples        int      `json:"samples"`
+	Expected       int      `json:"expected_samples"`
+	Overall        float64  `json:"overall"`
+	API            float64  `json:"api"`
+	DB             float64  `json:"db"`
+	Redis          float64  `json:"redis"`
+	Judge          float64  `json:"judge"`
+	DBLatencyMS    *float64 `json:"db_latency_ms"`
+	RedisLatencyMS *float64 `json:"redis_latency_ms"`
+	JudgeLatencyMS *float64 `json:"judge_latency_ms"`
+}
+
+type HealthRepository interface {
+	Save(ctx context.Context, s HealthSnapshot) error
+	DailyCounts(ctx context.Context, since time.Time, loc *time.Location) ([]HealthDayCounts, error)
+	FirstSnapshotAt(ctx context.Context) (*time.Time, error)
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+type PgHealthRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPgHealthRepository(db *pgxpool.Pool) *PgHealthRepository {
+	return &PgHealthRepository{db: db}
+}
+
+func (r *PgHealthRepository) Save(ctx context.Context, s HealthSnapshot) error {
+	_, err := r.db.Exec(ctx, `INSERT INTO health_snapshots (taken_at, db_ok, db_latency_ms, redis_ok, redis_latency_ms, judge_ok, judge_latency_ms)
+VALUES ($1,$2,$3,$4,$5,$6,$7)`, s.TakenAt, s.DBOK, s.DBLatencyMS, s.RedisOK, s.RedisLatencyMS, s.JudgeOK, s.JudgeLatencyMS)
+	return err
+}
+
+// DailyCounts groups snapshots taken since the given time by calendar day in loc.
+func (r *PgHealthRepository) DailyCounts(ctx context.Context, since time.Time, loc *time.Location) ([]HealthDayCounts, error) {
+	const q = `
+SELECT to_char(taken_at AT TIME ZONE $2, 'YYYY-MM-DD') AS day,
+       COUNT(*),
+       COUNT(*) FILTER (WHERE db_ok),
+       COUNT(*) FILTER (WHERE redis_ok),
+       COUNT(*) FILTER (WHERE judge_ok),
+       COUNT(*) FILTER (WHERE db_ok AND redis_ok AND judge_ok),
+       AVG(db_latency_ms) FILTER (WHERE db_ok),
+       AVG(redis_latency_ms) FILTER (WHERE redis_ok),
+       AVG(judge_latency_ms) FILTER (WHERE judge_ok)
+FROM health_snapshots
+WHERE taken_at >= $1
+GROUP BY day
+ORDER BY day`
+	rows, err := r.db.Query(ctx, q, since, loc.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []HealthDayCounts
+	for rows.Next() {
+		var d HealthDayCounts
+		var day string
+		if err := rows.Scan(&day, &d.Samples, &d.DBOK, &d.RedisOK, &d.JudgeOK, &d.AllOK, &d.DBMS, &d.RedisMS, &d.JudgeMS); err != nil {
+			return nil, err
+		}
+		if d.Date, err = time.ParseInLocation("2006-01-02", day, loc); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// FirstSnapshotAt returns when tracking started (nil when nothing is recorded yet).
+func (r *PgHealthRepository) FirstSnapshotAt(ctx context.Context) (*time.Time, error) {
+	var first *time.Time
+	if err := r.db.QueryRow(ctx, `SELECT MIN(taken_at) FROM health_snapshots`).Scan(&first); err != nil {
+		return nil, err
+	}
+	return first, nil
+}
+
+func (r *PgHealthRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	ct, err := r.db.Exec(ctx, `DELETE FROM health_snapshots WHERE taken_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
+
+// buildUptimeReport turns per-day counts into availability percentages for the last `days` days up to now.
+// Days before tracking started are omitted; the first and current day only expect snapshots for the tracked part.
+func buildUptimeReport(counts []HealthDayCounts, first time.Time, now time.Time, loc *time.Location, days int, interval time.Duration) []HealthDay {
+	byDate := make(map[string]HealthDayCounts, len(counts))
+	for _, c := range counts {
+		byDate[c.Date.Format("2006-01-02")] = c
+	}
+	y, m, d := now.In(loc).Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	out := []HealthDay{}
+	for i := days - 1; i >= 0; i-- {
+		start := today.AddDate(0, 0, -i)
+		end := start.AddDate(0, 0, 1)
+		if !end.After(first) {
+			continue
+		}
+		from, to := start, end
+		if first.After(from) {
+			from = first
+		}
+		if now.Before(to) {
+			to = now
+		}
+		c := byDate[start.Format("2006-01-02")]
+		expected := max(int(to.Sub(from)/interval), c.Samples, 1)
+		day := HealthDay{
+			Date:           start.Format("2006-01-02"),
+			Samples:        c.Samples,
+			Expected:       expected,
+			Overall:        percent(c.AllOK, expected),
+			API:            percent(c.Samples, expected),
+			DB:             percent(c.DBOK, c.Samples),
+			Redis:          percent(c.RedisOK, c.Samples),
+			Judge:          percent(c.JudgeOK, c.Samples),
+			DBLatencyMS:    c.DBMS,
+			RedisLatencyMS: c.RedisMS,
+			JudgeLatencyMS: c.JudgeMS,
+		}
+		out = append(out, day)
+	}
+	return out
+}
+
+func percent(n, total int) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(int(float64(n)*10000/float64(total))) / 100
+}
+
+// HealthMonitor probes DB, Redis and go-judge periodically and records snapshots.
+// Snapshots taken while the DB is down are kept in memory and written once it recovers.
+type HealthMonitor struct {
+	repo     HealthRepository
+	db       *pgxpool.Pool
+	redis    *redis.Client
+	judgeURL string
+	client   *http.Client
+	pending  []HealthSnapshot
+}
+
+func NewHealthMonitor(repo HealthRepository, db *pgxpool.Pool, redisClient *redis.Client, judgeURL string) *HealthMonitor {
+	return &HealthMonitor{
+		repo:     repo,
+		db:       db,
+		redis:    redisClient,
+		judgeURL: strings.TrimRight(judgeURL, "/"),
+		client:   &http.Client{Timeout: healthProbeTimeout},
+	}
+}
+
+// Run records a snapshot every interval until ctx is cancelled.
+func (m *HealthMonitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var lastPurge time.Time
+	for {
+		m.record(ctx, m.Probe(ctx))
+		if time.Since(lastPurge) > 24*time.Hour {
+			if _, err := m.repo.Purge(ctx, time.Now().Add(-healthRetention)); err != nil {
+				log.Printf("[health] purge failed: %v", err)
+			} else {
+				lastPurge = time.Now()
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Probe measures every dependency once.
+func (m *HealthMonitor) Probe(ctx context.Context) HealthSnapshot {
+	s := HealthSnapshot{TakenAt: time.Now()}
+	s.DBOK, s.DBLatencyMS = timeProbe(ctx, func(ctx context.Context) error { return m.db.Ping(ctx) })
+	s.RedisOK, s.RedisLatencyMS = timeProbe(ctx, func(ctx context.Context) error { return m.redis.Ping(ctx).Err() })
+	if m.judgeURL != "" {
+		s.JudgeOK, s.JudgeLatencyMS = timeProbe(ctx, m.pingJudge)
+	}
+	return s
+}
+
+func (m *HealthMonitor) pingJudge(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.judgeURL+"/version", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("go-judge returned %s", resp.Status)
+	}
+	return nil
+}
+
+// timeProbe runs fn with the probe timeout and returns success and latency (latency only on success).
+func timeProbe(ctx context.Context, fn func(context.Context) error) (bool, *int32) {
+	ctx, cancel := context.WithTimeout(ctx, healthProbeTimeout)
+	defer cancel()
+	start := time.Now()
+	if err := fn(ctx); err != nil {
+		return false, nil
+	}
+	ms := int32(time.Since(start).Milliseconds())
+	return true, &ms
+}
+
+func (m *HealthMonitor) record(ctx context.Context, s HealthSnapshot) {
+	m.pending = append(m.pending, s)
+	if over := len(m.pending) - maxPendingHealthSnapshots; over > 0 {
+		m.pending = m.pending[over:]
+	}
+	for len(m.pending) > 0 {
+		if err := m.repo.Save(ctx, m.pending[0]); err != nil {
+			log.Printf("[health] save failed (%d pending): %v", len(m.pending), err)
+			return
+		}
+		m.pending = m.pending[1:]
+	}
+}
@@ -22,6 +22,7 @@ func NewHeartbeatState(workerID, hostname string, concurrency int) *HeartbeatSta
 			WorkerID:     workerID,
 			Hostname:     hostname,
 			PID:          os.Getpid(),
+			Version:      WorkerVersion(),
 			Concurrency:  concurrency,
 			Status:       "starting",
 			RunningCount: 0,
@@ -76,6 +77,20 @@ func (s *HeartbeatState) JobFinished(job string, err error) {
 	s.updateRunningFieldsLocked()
 }
 
+// Counters returns the lifetime processed and failed job counts.
+func (s *HeartbeatState) Counters() (processed, failed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hb.ProcessedTotal, s.hb.FailedTotal
+}
+
+// SetDiskUsage は次回の送信に含めるディスク使用量を更新する。
+func (s *HeartbeatState) SetDiskUsage(u WorkerDiskUsage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hb.Disk = &u
+}
+
 func (s *HeartbeatState) updateRunningFieldsLocked() {
 	s.hb.RunningCount = len(s.running)
 	s.hb.RunningJobs = s.hb.RunningJobs[:0]
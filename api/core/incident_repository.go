@@ -0,0 +1,226 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Incident kinds recorded by the anomaly monitor.
+const (
+	IncidentKindSESpike       = "se_spike"
+	IncidentKindProblemZeroAC = "problem_zero_ac"
+	IncidentKindWorkerFailure = "worker_failure"
+	IncidentKindWorkerLost    = "worker_lost"
+	// IncidentKindWorkerPanic and IncidentKindWorkerCrash are raised by workers (see worker_crash.go).
+	IncidentKindWorkerPanic = "worker_panic"
+	IncidentKindWorkerCrash = "worker_crash"
+)
+
+// Incident is an anomaly detected in judge results.
+type Incident struct {
+	ID         int64          `json:"id"`
+	Kind       string         `json:"kind"`
+	Subject    string         `json:"subject"`
+	Summary    string         `json:"summary"`
+	Details    map[string]any `json:"details"`
+	DetectedAt time.Time      `json:"detected_at"`
+	LastSeenAt time.Time      `json:"last_seen_at"`
+	ResolvedAt *time.Time     `json:"resolved_at"`
+}
+
+// VerdictCounts is the number of judged results and how many of them failed (SE).
+type VerdictCounts struct {
+	Total  int
+	Failed int
+}
+
+// Rate returns Failed/Total, or 0 when nothing was judged.
+func (v VerdictCounts) Rate() float64 {
+	if v.Total == 0 {
+		return 0
+	}
+	return float64(v.Failed) / float64(v.Total)
+}
+
+// ProblemEditStat compares a problem's results before and after its last edit.
+type ProblemEditStat struct {
+	ProblemID   int64
+	Title       string
+	EditedAt    time.Time
+	ACBefore    int
+	JudgedAfter int
+	ACAfter     int
+}
+
+// WorkerFailureStat is the result count of one worker.
+type WorkerFailureStat struct {
+	WorkerID string
+	VerdictCounts
+}
+
+// IncidentRepository persists incidents and provides the aggregates the monitor inspects.
+type IncidentRepository interface {
+	// Open records an incident, or refreshes the open incident with the same kind and subject.
+	// created reports whether a new incident was opened.
+	Open(ctx context.Context, in Incident) (inc *Incident, created bool, err error)
+	List(ctx context.Context, status, kind string, page, perPage int) ([]Incident, int, error)
+	Resolve(ctx context.Context, id int64) (*Incident, error)
+
+	SystemErrorCounts(ctx context.Context, recentSince, baselineSince time.Time) (recent, baseline VerdictCounts, err error)
+	ProblemEditStats(ctx context.Context, editedSince time.Time) ([]ProblemEditStat, error)
+	WorkerFailureStats(ctx context.Context, since time.Time) ([]WorkerFailureStat, error)
+}
+
+// PgIncidentRepository implements IncidentRepository using pgxpool.
+type PgIncidentRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPgIncidentRepository(db *pgxpool.Pool) *PgIncidentRepository {
+	return &PgIncidentRepository{db: db}
+}
+
+const incidentColumns = `id, kind, subject, summary, details, detected_at, last_seen_at, resolved_at`
+
+func scanIncident(row pgx.Row, extra ...any) (*Incident, error) {
+	var inc Incident
+	dest := append([]any{&inc.ID, &inc.Kind, &inc.Subject, &inc.Summary, &inc.Details, &inc.DetectedAt, &inc.LastSeenAt, &inc.ResolvedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return &inc, nil
+}
+
+func (r *PgIncidentRepository) Open(ctx context.Context, in Incident) (*Incident, bool, error) {
+	if in.Details == nil {
+		in.Details = map[string]any{}
+	}
+	// xmax=0 のときは INSERT された行（既存行の UPDATE ではない）
+	const q = `INSERT INTO incidents (kind, subject, summary, details)
+VALUES ($1,$2,$3,$4)
+ON CONFLICT (kind, subject) WHERE resolved_at IS NULL DO UPDATE
+SET summary=EXCLUDED.summary, details=EXCLUDED.details, last_seen_at=NOW()
+RETURNING ` + incidentColumns + `, (xmax = 0) AS created`
+	var created bool
+	inc, err := scanIncident(r.db.QueryRow(ctx, q, in.Kind, in.Subject, in.Summary, in.Details), &created)
+	if err != nil {
+		return nil, false, err
+	}
+	return inc, created, nil
+}
+
+// List returns incidents newest first. status is "open", "resolved" or "" (all); a non-empty kind
+// keeps only the incidents of that kind.
+func (r *PgIncidentRepository) List(ctx context.Context, status, kind string, page, perPage int) ([]Incident, int, error) {
+	if page <= 0 || perPage <= 0 {
+		return nil, 0, errors.New("invalid pagination")
+	}
+	conds := []string{"($1 = '' OR kind = $1)"}
+	switch status {
+	case "open":
+		conds = append(conds, "resolved_at IS NULL")
+	case "resolved":
+		conds = append(conds, "resolved_at IS NOT NULL")
+	}
+	where := " WHERE " + strings.Join(conds, " AND ")
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM incidents`+where, kind).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := r.db.Query(ctx, `SELECT `+incidentColumns+` FROM incidents`+where+`
+ORDER BY detected_at DESC, id DESC
+LIMIT $2 OFFSET $3`, kind, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	items := make([]Incident, 0, perPage)
+	for rows.Next() {
+		inc, err := scanIncident(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		items = append(items, *inc)
+	}
+	return items, total, rows.Err()
+}
+
+func (r *PgIncidentRepository) Resolve(ctx context.Context, id int64) (*Incident, error) {
+	return scanIncident(r.db.QueryRow(ctx, `UPDATE incidents SET resolved_at=COALESCE(resolved_at, NOW())
+WHERE id=$1 RETURNING `+incidentColumns, id))
+}
+
+// SystemErrorCounts counts results judged since recentSince, and those judged between
+// baselineSince and recentSince for comparison.
+func (r *PgIncidentRepository) SystemErrorCounts(ctx context.Context, recentSince, baselineSince time.Time) (VerdictCounts, VerdictCounts, error) {
+	const q = `
+SELECT COUNT(*) FILTER (WHERE updated_at >= $1),
+       COUNT(*) FILTER (WHERE updated_at >= $1 AND verdict = '` + VerdictSE + `'),
+       COUNT(*) FILTER (WHERE updated_at < $1),
+       COUNT(*) FILTER (WHERE updated_at < $1 AND verdict = '` + VerdictSE + `')
+FROM submission_results
+WHERE updated_at >= $2`
+	var recent, baseline VerdictCounts
+	if err := r.db.QueryRow(ctx, q, recentSince, baselineSince).Scan(&recent.Total, &recent.Failed, &baseline.Total, &baseline.Failed); err != nil {
+		return VerdictCounts{}, VerdictCounts{}, err
+	}
+	return recent, baseline, nil
+}
+
+// ProblemEditStats returns AC counts around the last edit of problems edited since editedSince.
+// CE / SE などの excluded な判定は提出者・ジャッジ側の問題なので判定数に含めない。
+func (r *PgIncidentRepository) ProblemEditStats(ctx context.Context, editedSince time.Time) ([]ProblemEditStat, error) {
+	const q = `
+SELECT p.id, p.title, p.updated_at,
+       COUNT(*) FILTER (WHERE s.created_at < p.updated_at AND sr.verdict = '` + VerdictAC + `'),
+       COUNT(*) FILTER (WHERE s.created_at >= p.updated_at AND sr.verdict <> ALL($2)),
+       COUNT(*) FILTER (WHERE s.created_at >= p.updated_at AND sr.verdict = '` + VerdictAC + `')
+FROM problems p
+JOIN submissions s ON s.problem_id = p.id
+JOIN submission_results sr ON sr.submission_id = s.id
+WHERE p.updated_at >= $1
+GROUP BY p.id, p.title, p.updated_at`
+	rows, err := r.db.Query(ctx, q, editedSince, verdictCodes(VerdictKindExcluded))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var stats []ProblemEditStat
+	for rows.Next() {
+		var st ProblemEditStat
+		if err := rows.Scan(&st.ProblemID, &st.Title, &st.EditedAt, &st.ACBefore, &st.JudgedAfter, &st.ACAfter); err != nil {
+			return nil, err
+		}
+		stats = append(stats, st)
+	}
+	return stats, rows.Err()
+}
+
+// WorkerFailureStats counts results per worker judged since the given time.
+func (r *PgIncidentRepository) WorkerFailureStats(ctx context.Context, since time.Time) ([]WorkerFailureStat, error) {
+	const q = `
+SELECT judged_by, COUNT(*), COUNT(*) FILTER (WHERE verdict = '` + VerdictSE + `')
+FROM submission_results
+WHERE judged_by IS NOT NULL AND updated_at >= $1
+GROUP BY judged_by
+ORDER BY judged_by`
+	rows, err := r.db.Query(ctx, q, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var stats []WorkerFailureStat
+	for rows.Next() {
+		var st WorkerFailureStat
+		if err := rows.Scan(&st.WorkerID, &st.Total, &st.Failed); err != nil {
+			return nil, err
+		}
+		stats = append(stats, st)
+	}
+	return stats, rows.Err()
+}
@@ -0,0 +1,217 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	// ErrInvitationCodeInvalid covers unknown, disabled, expired and used-up codes alike.
+	ErrInvitationCodeInvalid = errors.New("invitation code is invalid")
+	ErrInvitationCodeExists  = errors.New("invitation code already exists")
+	ErrInvitationCodeInput   = errors.New("invalid invitation code")
+	ErrRegistrationInput     = errors.New("invalid registration")
+	ErrUsernameTaken         = errors.New("username already exists")
+)
+
+const maxInvitationNoteLen = 200
+
+// 自己登録で使えるユーザー名（OAuth での自動作成と同じ文字種）
+var registerUsernamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{3,32}$`)
+
+// InvitationCode lets people create their own account (role user).
+type InvitationCode struct {
+	ID        int64      `json:"id"`
+	Code      string     `json:"code"`
+	Note      string     `json:"note"`
+	MaxUses   *int       `json:"max_uses"`
+	UseCount  int        `json:"use_count"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	IsActive  bool       `json:"is_active"`
+	CreatedBy *int64     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// InvitationCodeInput creates a code; an empty Code is generated.
+type InvitationCodeInput struct {
+	Code      string
+	Note      string
+	MaxUses   *int
+	ExpiresAt *time.Time
+	CreatedBy int64
+}
+
+// InvitationCodeUpdate holds mutable fields of a code.
+type InvitationCodeUpdate struct {
+	Note      *string
+	MaxUses   *int
+	ExpiresAt *time.Time
+	IsActive  *bool
+}
+
+// validateRegistration checks the username and password chosen on self-registration.
+func validateRegistration(username, password string) error {
+	if !registerUsernamePattern.MatchString(username) {
+		return fmt.Errorf("%w: ユーザー名は 3〜32 文字の英数字と _ . - で指定してください", ErrRegistrationInput)
+	}
+	if err := validateNewPassword("", password); err != nil {
+		return fmt.Errorf("%w: %s", ErrRegistrationInput, err.Error())
+	}
+	return nil
+}
+
+// InvitationCodeRepository manages invitation codes and registers users with them.
+type InvitationCodeRepository interface {
+	List(ctx context.Context) ([]InvitationCode, error)
+	Create(ctx context.Context, input InvitationCodeInput) (*InvitationCode, error)
+	Update(ctx context.Context, id int64, input InvitationCodeUpdate) (*InvitationCode, error)
+	Delete(ctx context.Context, id int64) error
+	// Register creates a user with role user and counts one use of the code. It returns
+	// ErrInvitationCodeInvalid or ErrUsernameTaken without creating anything.
+	Register(ctx context.Context, code, username, passwordHash string) (*UserRecord, error)
+}
+
+type PgInvitationCodeRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPgInvitationCodeRepository(db *pgxpool.Pool) *PgInvitationCodeRepository {
+	return &PgInvitationCodeRepository{db: db}
+}
+
+const invitationCodeColumns = `id, code, note, max_uses, use_count, expires_at, is_active, created_by, created_at`
+
+func scanInvitationCode(row pgx.Row) (*InvitationCode, error) {
+	var a InvitationCode
+	if err := row.Scan(&a.ID, &a.Code, &a.Note, &a.MaxUses, &a.UseCount, &a.ExpiresAt, &a.IsActive, &a.CreatedBy, &a.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func validateInvitationLimits(note *string, maxUses *int) error {
+	if maxUses != nil && *maxUses <= 0 {
+		return fmt.Errorf("%w: max_uses must be positive", ErrInvitationCodeInput)
+	}
+	if note != nil && len([]rune(*note)) > maxInvitationNoteLen {
+		return fmt.Errorf("%w: note must be at most %d characters", ErrInvitationCodeInput, maxInvitationNoteLen)
+	}
+	return nil
+}
+
+func (r *PgInvitationCodeRepository) List(ctx context.Context) ([]InvitationCode, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+invitationCodeColumns+` FROM invitation_codes ORDER BY id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []InvitationCode{}
+	for rows.Next() {
+		a, err := scanInvitationCode(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *a)
+	}
+	return out, rows.Err()
+}
+
+func (r *PgInvitationCodeRepository) Create(ctx context.Context, input InvitationCodeInput) (*InvitationCode, error) {
+	if err := validateInvitationLimits(&input.Note, input.MaxUses); err != nil {
+		return nil, err
+	}
+	// コードの形式と生成は参加コードと共通
+	code := normalizeAccessCode(input.Code)
+	generated := code == ""
+	if !generated && !accessCodePattern.MatchString(code) {
+		return nil, fmt.Errorf("%w: code must be 6-64 characters of A-Z, 0-9, '-' or '_'", ErrInvitationCodeInput)
+	}
+	const q = `INSERT INTO invitation_codes (code, note, max_uses, expires_at, created_by)
+VALUES ($1,$2,$3,$4,$5) ON CONFLICT (code) DO NOTHING RETURNING ` + invitationCodeColumns
+	for attempt := 0; attempt < 5; attempt++ {
+		if generated {
+			code = generateAccessCode()
+		}
+		a, err := scanInvitationCode(r.db.QueryRow(ctx, q, code, input.Note, input.MaxUses, input.ExpiresAt, input.CreatedBy))
+		if err == nil {
+			return a, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		if !generated {
+			return nil, ErrInvitationCodeExists
+		}
+	}
+	return nil, ErrInvitationCodeExists
+}
+
+func (r *PgInvitationCodeRepository) Update(ctx context.Context, id int64, input InvitationCodeUpdate) (*InvitationCode, error) {
+	if err := validateInvitationLimits(input.Note, input.MaxUses); err != nil {
+		return nil, err
+	}
+	return scanInvitationCode(r.db.QueryRow(ctx, `UPDATE invitation_codes
+SET note=COALESCE($2, note), max_uses=COALESCE($3, max_uses), expires_at=COALESCE($4, expires_at), is_active=COALESCE($5, is_active)
+WHERE id=$1 RETURNING `+invitationCodeColumns, id, input.Note, input.MaxUses, input.ExpiresAt, input.IsActive))
+}
+
+func (r *PgInvitationCodeRepository) Delete(ctx context.Context, id int64) error {
+	ct, err := r.db.Exec(ctx, `DELETE FROM invitation_codes WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PgInvitationCodeRepository) Register(ctx context.Context, code, username, passwordHash string) (*UserRecord, error) {
+	code = normalizeAccessCode(code)
+	if code == "" {
+		return nil, ErrInvitationCodeInvalid
+	}
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// use_count の上限判定を並行する登録と競合させないよう行ロックを取る
+	a, err := scanInvitationCode(tx.QueryRow(ctx, `SELECT `+invitationCodeColumns+` FROM invitation_codes WHERE code=$1 FOR UPDATE`, code))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvitationCodeInvalid
+		}
+		return nil, err
+	}
+	if !a.IsActive || (a.ExpiresAt != nil && !time.Now().Before(*a.ExpiresAt)) || (a.MaxUses != nil && a.UseCount >= *a.MaxUses) {
+		return nil, ErrInvitationCodeInvalid
+	}
+
+	var u UserRecord
+	err = tx.QueryRow(ctx, `INSERT INTO users (username, password_hash, role, invitation_code_id) VALUES ($1,$2,$3,$4)
+RETURNING id, username, password_hash, role, timezone, locale, created_at`, username, passwordHash, RoleUser, a.ID).
+		Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Role, &u.Timezone, &u.Locale, &u.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrUsernameTaken
+		}
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE invitation_codes SET use_count = use_count + 1 WHERE id=$1`, a.ID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
@@ -0,0 +1,34 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateRegistration(t *testing.T) {
+	if err := validateRegistration("student_01", "correct horse"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cases := []struct{ username, password string }{
+		{"ab", "correct horse"},
+		{"has space", "correct horse"},
+		{"日本語ユーザー", "correct horse"},
+		{strings.Repeat("a", 33), "correct horse"},
+		{"student_01", "short"},
+	}
+	for _, tc := range cases {
+		if err := validateRegistration(tc.username, tc.password); !errors.Is(err, ErrRegistrationInput) {
+			t.Errorf("%q / %q: expected ErrRegistrationInput, got %v", tc.username, tc.password, err)
+		}
+	}
+}
+
+func TestAccessCodeFailKeysAnonymous(t *testing.T) {
+	if keys := accessCodeFailKeys(0, "192.0.2.1"); len(keys) != 1 || !strings.HasSuffix(keys[0], "ip:192.0.2.1") {
+		t.Fatalf("anonymous keys = %v", keys)
+	}
+	if keys := accessCodeFailKeys(7, "192.0.2.1"); len(keys) != 2 {
+		t.Fatalf("user keys = %v", keys)
+	}
+}
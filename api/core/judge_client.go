@@ -19,13 +19,17 @@ import (
 type JudgeClient interface {
 	Compile(ctx context.Context, lang, source string, timeLimitMs, memoryLimitMb int) (*judgeResponse, string, string, error)
 	RunWithArtifact(ctx context.Context, lang, artifactID, stdin string, timeLimitMs, memoryLimitMb int) (*judgeResponse, error)
+	RunWithArgs(ctx context.Context, lang, artifactID, stdin string, args []string, timeLimitMs, memoryLimitMb int) (*judgeResponse, error)
+	RunChecker(ctx context.Context, checkerID, input, expected, actual string, timeLimitMs, memoryLimitMb int) (*judgeResponse, error)
 	RemoveFiles(ctx context.Context, ids ...string) error
 }
 
 // HTTPJudgeClient calls go-judge HTTP endpoints.
 type HTTPJudgeClient struct {
-	client *http.Client
-	base   string
+	client      *http.Client
+	base        string
+	networkBase string                   // go-judge started with -net-share, used when a policy enables the network
+	policies    map[string]SandboxPolicy // per-language sandbox options (see ParseSandboxPolicies)
 }
 
 func NewHTTPJudgeClient(baseURL string) *HTTPJudgeClient {
@@ -35,6 +39,34 @@ func NewHTTPJudgeClient(baseURL string) *HTTPJudgeClient {
 	}
 }
 
+// ConfigureSandbox sets the per-language sandbox policies and the go-judge instance that runs
+// programs with network access (empty disables it).
+func (c *HTTPJudgeClient) ConfigureSandbox(policies map[string]SandboxPolicy, networkURL string) {
+	c.policies = policies
+	c.networkBase = strings.TrimRight(networkURL, "/")
+}
+
+// sandboxBase returns the go-judge instance a program in lang compiles and runs on, and the
+// prefix its artifact ids get.
+func (c *HTTPJudgeClient) sandboxBase(ctx context.Context, lang string) (string, string, error) {
+	p := effectiveSandboxPolicy(ctx, c.policies, lang)
+	if p.Network == nil || !*p.Network {
+		return c.base, "", nil
+	}
+	if c.networkBase == "" {
+		return "", "", errors.New("sandbox policy enables network but GOJUDGE_NETWORK_URL is not configured")
+	}
+	return c.networkBase, networkArtifactPrefix, nil
+}
+
+// artifactBase resolves an artifact id returned by Compile to its go-judge instance and file id.
+func (c *HTTPJudgeClient) artifactBase(id string) (string, string) {
+	if rest, ok := strings.CutPrefix(id, networkArtifactPrefix); ok && c.networkBase != "" {
+		return c.networkBase, rest
+	}
+	return c.base, id
+}
+
 // go-judge request payload structures
 
 type judgeFile struct {
@@ -54,6 +86,9 @@ type judgeCommand struct {
 	CopyIn        map[string]judgeFile `json:"copyIn,omitempty"`
 	CopyOut       []string             `json:"copyOut,omitempty"`
 	CopyOutCached []string             `json:"copyOutCached,omitempty"`
+	// StackLimit (bytes) と AddressSpaceLimit は SandboxPolicy で指定されたときだけ送る
+	StackLimit        int64 `json:"stackLimit,omitempty"`
+	AddressSpaceLimit bool  `json:"addressSpaceLimit,omitempty"`
 }
 
 type judgeResponse struct {
@@ -122,12 +157,16 @@ func (c *HTTPJudgeClient) Compile(ctx context.Context, lang, source string, time
 		return nil, "", "", errors.New("go-judge url not configured")
 	}
 	cfg := langConfigFor(lang)
+	base, prefix, err := c.sandboxBase(ctx, lang)
+	if err != nil {
+		return nil, "", "", err
+	}
 
 	if timeLimitMs <= 0 {
-		timeLimitMs = 2000
+		timeLimitMs = defaultTimeLimitMS
 	}
 	if memoryLimitMb <= 0 {
-		memoryLimitMb = 256
+		memoryLimitMb = defaultMemoryLimitMB
 	}
 	cpuLimit := int64(timeLimitMs) * 1_000_000 // ms -> ns
 	memLimit := int64(memoryLimitMb) * 1024 * 1024
@@ -147,7 +186,7 @@ func (c *HTTPJudgeClient) Compile(ctx context.Context, lang, source string, time
 	b, _ := json.Marshal(payload)
 	log.Printf("judge compile lang=%s time_ms=%d mem_mb=%d size=%dB", lang, timeLimitMs, memoryLimitMb, len(source))
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.base+"/run", bytes.NewReader(b))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/run", bytes.NewReader(b))
 	if err != nil {
 		return nil, "", "", err
 	}
@@ -174,8 +213,8 @@ func (c *HTTPJudgeClient) Compile(ctx context.Context, lang, source string, time
 
 	r := body[0]
 	artifactID := ""
-	if r.FileIDs != nil {
-		artifactID = r.FileIDs[cfg.ArtifactKey]
+	if r.FileIDs != nil && r.FileIDs[cfg.ArtifactKey] != "" {
+		artifactID = prefix + r.FileIDs[cfg.ArtifactKey]
 	}
 
 	return &r, cfg.ArtifactKey, artifactID, nil
@@ -183,6 +222,11 @@ func (c *HTTPJudgeClient) Compile(ctx context.Context, lang, source string, time
 
 // RunWithArtifact executes the compiled artifact with provided stdin.
 func (c *HTTPJudgeClient) RunWithArtifact(ctx context.Context, lang, artifactID, stdin string, timeLimitMs, memoryLimitMb int) (*judgeResponse, error) {
+	return c.RunWithArgs(ctx, lang, artifactID, stdin, nil, timeLimitMs, memoryLimitMb)
+}
+
+// RunWithArgs is RunWithArtifact with extra command-line arguments (used by testcase generators).
+func (c *HTTPJudgeClient) RunWithArgs(ctx context.Context, lang, artifactID, stdin string, args []string, timeLimitMs, memoryLimitMb int) (*judgeResponse, error) {
 	if c.base == "" {
 		return nil, errors.New("go-judge url not configured")
 	}
@@ -190,12 +234,13 @@ func (c *HTTPJudgeClient) RunWithArtifact(ctx context.Context, lang, artifactID,
 		return nil, errors.New("empty artifact id")
 	}
 	cfg := langConfigFor(lang)
+	base, fileID := c.artifactBase(artifactID)
 
 	if timeLimitMs <= 0 {
-		timeLimitMs = 2000
+		timeLimitMs = defaultTimeLimitMS
 	}
 	if memoryLimitMb <= 0 {
-		memoryLimitMb = 256
+		memoryLimitMb = defaultMemoryLimitMB
 	}
 	cpuLimit := int64(timeLimitMs) * 1_000_000
 	memLimit := int64(memoryLimitMb) * 1024 * 1024
@@ -209,22 +254,84 @@ func (c *HTTPJudgeClient) RunWithArtifact(ctx context.Context, lang, artifactID,
 	}
 
 	cmd := judgeCommand{
-		Args:        cfg.RunArgs,
+		Args:        append(cfg.RunArgs[:len(cfg.RunArgs):len(cfg.RunArgs)], args...),
 		Env:         []string{"PATH=/usr/bin:/bin"},
 		Files:       files,
 		CPULimit:    cpuLimit,
 		MemoryLimit: memLimit,
 		ProcLimit:   50,
 		CopyIn: map[string]judgeFile{
-			cfg.ArtifactKey: {FileID: artifactID},
+			cfg.ArtifactKey: {FileID: fileID},
 		},
 	}
+	effectiveSandboxPolicy(ctx, c.policies, lang).apply(&cmd)
 
 	payload := map[string]any{"cmd": []judgeCommand{cmd}}
 	b, _ := json.Marshal(payload)
 	log.Printf("judge run lang=%s time_ms=%d mem_mb=%d stdin_bytes=%d", lang, timeLimitMs, memoryLimitMb, len(stdin))
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.base+"/run", bytes.NewReader(b))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/run", bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body []judgeResponse
+	if resp.StatusCode >= 300 {
+		var textErr string
+		_ = json.NewDecoder(resp.Body).Decode(&textErr)
+		return nil, fmt.Errorf("judge returned status %d: %s", resp.StatusCode, textErr)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	if len(body) == 0 {
+		return nil, fmt.Errorf("empty judge response")
+	}
+	return &body[0], nil
+}
+
+// RunChecker executes a compiled checker as `checker input.txt expected.txt actual.txt`.
+// The checker's exit status decides the verdict (see checkerVerdict).
+func (c *HTTPJudgeClient) RunChecker(ctx context.Context, checkerID, input, expected, actual string, timeLimitMs, memoryLimitMb int) (*judgeResponse, error) {
+	if c.base == "" {
+		return nil, errors.New("go-judge url not configured")
+	}
+	if checkerID == "" {
+		return nil, errors.New("empty checker id")
+	}
+	base, checkerID := c.artifactBase(checkerID)
+	if timeLimitMs <= 0 {
+		timeLimitMs = defaultTimeLimitMS
+	}
+	if memoryLimitMb <= 0 {
+		memoryLimitMb = defaultMemoryLimitMB
+	}
+	empty := ""
+	cmd := judgeCommand{
+		Args:        []string{"./checker", "input.txt", "expected.txt", "actual.txt"},
+		Env:         []string{"PATH=/usr/bin:/bin"},
+		Files:       []judgeFile{{Content: &empty}, {Name: "stdout", Max: 10240}, {Name: "stderr", Max: 10240}},
+		CPULimit:    int64(timeLimitMs) * 1_000_000,
+		MemoryLimit: int64(memoryLimitMb) * 1024 * 1024,
+		ProcLimit:   50,
+		CopyIn: map[string]judgeFile{
+			"checker":      {FileID: checkerID},
+			"input.txt":    {Content: &input},
+			"expected.txt": {Content: &expected},
+			"actual.txt":   {Content: &actual},
+		},
+	}
+
+	payload := map[string]any{"cmd": []judgeCommand{cmd}}
+	b, _ := json.Marshal(payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/run", bytes.NewReader(b))
 	if err != nil {
 		return nil, err
 	}
@@ -264,7 +371,8 @@ func (c *HTTPJudgeClient) RemoveFiles(ctx context.Context, ids ...string) error
 		if strings.TrimSpace(id) == "" {
 			continue
 		}
-		endpoint := fmt.Sprintf("%s/file/%s", c.base, url.PathEscape(id))
+		base, fileID := c.artifactBase(id)
+		endpoint := fmt.Sprintf("%s/file/%s", base, url.PathEscape(fileID))
 		req, err := http.NewRequestWithContext(ctx, http.MethodDelete, endpoint, nil)
 		if err != nil {
 			return err
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limits applied when a problem (or a judge request) does not set its own.
+const (
+	defaultTimeLimitMS   = 2000
+	defaultMemoryLimitMB = 256
+)
+
+// verdictDescriptions explains the built-in verdicts to users, per locale. Verdicts added with
+// EXTRA_VERDICTS have no description and fall back to their label.
+var verdictDescriptions = map[string]map[string]string{
+	VerdictAC: {
+		"ja": "すべてのテストケースで正しい出力が得られました。",
+		"en": "The program produced the correct output for every test case.",
+	},
+	VerdictWA: {
+		"ja": "いずれかのテストケースで出力が正解と一致しませんでした。",
+		"en": "The output did not match the expected answer on some test case.",
+	},
+	VerdictTLE: {
+		"ja": "実行時間が制限時間を超えました。",
+		"en": "The program ran longer than the time limit.",
+	},
+	VerdictMLE: {
+		"ja": "使用メモリがメモリ制限を超えました。",
+		"en": "The program used more memory than the memory limit.",
+	},
+	VerdictOLE: {
+		"ja": "出力の量が上限を超えました。",
+		"en": "The program wrote more output than allowed.",
+	},
+	VerdictRE: {
+		"ja": "実行中にエラーで終了しました（例外、不正なメモリアクセス、0 以外の終了コードなど）。",
+		"en": "The program terminated with an error (an exception, invalid memory access, a non-zero exit code, ...).",
+	},
+	VerdictCE: {
+		"ja": "コンパイルに失敗しました。ペナルティには数えません。",
+		"en": "The source code failed to compile. It does not count as a penalty.",
+	},
+	VerdictSE: {
+		"ja": "ジャッジシステム側のエラーです。ペナルティには数えず、再判定の対象になります。",
+		"en": "An error occurred in the judge system. It does not count as a penalty and will be rejudged.",
+	},
+}
+
+// languageVersions names the compiler / runtime behind each language of supportedLanguages.
+var languageVersions = map[string]string{
+	"c":      "GCC (gnu17)",
+	"cpp":    "G++ (gnu++17)",
+	"python": "CPython 3",
+	"java":   "OpenJDK 21",
+}
+
+// JudgeMetaVerdict is a verdict with its explanation in the requested locale.
+type JudgeMetaVerdict struct {
+	VerdictInfo
+	Description string `json:"description"`
+}
+
+// JudgeMetaLanguage is a submittable language with the commands the judge runs.
+type JudgeMetaLanguage struct {
+	Key     string `json:"key"`
+	Label   string `json:"label"`
+	Syntax  string `json:"syntax"`
+	Version string `json:"version"`
+	Compile string `json:"compile_command"`
+	Run     string `json:"run_command"`
+}
+
+// JudgeMeta is the response of GET /judge/meta.
+type JudgeMeta struct {
+	Locale        string              `json:"locale"`
+	Verdicts      []JudgeMetaVerdict  `json:"verdicts"`
+	Languages     []JudgeMetaLanguage `json:"languages"`
+	DefaultLimits struct {
+		TimeMS   int `json:"time_limit_ms"`
+		MemoryKB int `json:"memory_limit_kb"`
+	} `json:"default_limits"`
+}
+
+// buildJudgeMeta collects the verdicts (descriptions in locale), languages and default limits.
+func buildJudgeMeta(locale string) JudgeMeta {
+	meta := JudgeMeta{Locale: locale}
+	for _, v := range Verdicts() {
+		desc := verdictDescriptions[v.Code][locale]
+		if desc == "" {
+			desc = v.Label
+		}
+		meta.Verdicts = append(meta.Verdicts, JudgeMetaVerdict{VerdictInfo: v, Description: desc})
+	}
+	for _, l := range supportedLanguages {
+		cfg := langConfigFor(l["key"])
+		meta.Languages = append(meta.Languages, JudgeMetaLanguage{
+			Key:     l["key"],
+			Label:   l["label"],
+			Syntax:  l["syntax"],
+			Version: languageVersions[l["key"]],
+			Compile: strings.Join(cfg.CompileArgs, " "),
+			Run:     strings.Join(cfg.RunArgs, " "),
+		})
+	}
+	meta.DefaultLimits.TimeMS = defaultTimeLimitMS
+	meta.DefaultLimits.MemoryKB = defaultMemoryLimitMB * 1024
+	return meta
+}
+
+// registerJudgeMetaRoutes wires the judge metadata for frontends and CLI tools. The locale follows
+// ?lang=, the user's preference and Accept-Language.
+func registerJudgeMetaRoutes(api *gin.RouterGroup, userRepo UserRepository) {
+	api.GET("/judge/meta", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		locale := requestLocale(c, user)
+		if locale == "" {
+			locale = defaultLocale
+		}
+		c.JSON(http.StatusOK, buildJudgeMeta(locale))
+	})
+}
@@ -0,0 +1,18 @@
+package core
+
+import "testing"
+
+func TestJudgeMetaDescribesVerdictsAndLanguages(t *testing.T) {
+	meta := buildJudgeMeta("en")
+	if len(meta.Verdicts) < len(builtinVerdicts) || meta.Verdicts[0].Code != VerdictAC || meta.Verdicts[0].Description != verdictDescriptions[VerdictAC]["en"] {
+		t.Fatalf("verdicts = %+v", meta.Verdicts)
+	}
+	for _, l := range meta.Languages {
+		if l.Version == "" || l.Run == "" {
+			t.Fatalf("language %s lacks version or run command: %+v", l.Key, l)
+		}
+	}
+	if len(meta.Languages) != len(supportedLanguages) || meta.DefaultLimits.MemoryKB != 262144 {
+		t.Fatalf("meta = %+v", meta)
+	}
+}
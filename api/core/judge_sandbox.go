@@ -0,0 +1,185 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// networkArtifactPrefix marks artifacts cached on the network-enabled go-judge (GOJUDGE_NETWORK_URL),
+// so later runs and deletes reach the same instance.
+const networkArtifactPrefix = "net:"
+
+const (
+	maxSandboxStackLimitMB = 1024
+	maxSandboxProcLimit    = 256
+)
+
+// SandboxPolicy adjusts the go-judge sandbox a submission runs in. Nil fields keep the default
+// (network isolated, no address space limit, go-judge's stack size, 50 processes).
+// go-judge isolates the network per instance, so Network selects the instance started with
+// -net-share (GOJUDGE_NETWORK_URL) for compiling and running; the other options are sent with
+// each command.
+type SandboxPolicy struct {
+	Network           *bool `json:"network,omitempty"`
+	AddressSpaceLimit *bool `json:"address_space_limit,omitempty"`
+	StackLimitMB      *int  `json:"stack_limit_mb,omitempty"`
+	ProcLimit         *int  `json:"proc_limit,omitempty"`
+}
+
+// IsZero reports whether the policy changes nothing.
+func (p SandboxPolicy) IsZero() bool {
+	return p.Network == nil && p.AddressSpaceLimit == nil && p.StackLimitMB == nil && p.ProcLimit == nil
+}
+
+// Validate checks the numeric options.
+func (p SandboxPolicy) Validate() error {
+	if p.StackLimitMB != nil && (*p.StackLimitMB <= 0 || *p.StackLimitMB > maxSandboxStackLimitMB) {
+		return fmt.Errorf("stack_limit_mb は 1〜%d で指定してください", maxSandboxStackLimitMB)
+	}
+	if p.ProcLimit != nil && (*p.ProcLimit <= 0 || *p.ProcLimit > maxSandboxProcLimit) {
+		return fmt.Errorf("proc_limit は 1〜%d で指定してください", maxSandboxProcLimit)
+	}
+	return nil
+}
+
+// merge returns p with the fields set in over replacing its own.
+func (p SandboxPolicy) merge(over SandboxPolicy) SandboxPolicy {
+	if over.Network != nil {
+		p.Network = over.Network
+	}
+	if over.AddressSpaceLimit != nil {
+		p.AddressSpaceLimit = over.AddressSpaceLimit
+	}
+	if over.StackLimitMB != nil {
+		p.StackLimitMB = over.StackLimitMB
+	}
+	if over.ProcLimit != nil {
+		p.ProcLimit = over.ProcLimit
+	}
+	return p
+}
+
+// apply sets the command options of the policy.
+func (p SandboxPolicy) apply(cmd *judgeCommand) {
+	if p.AddressSpaceLimit != nil {
+		cmd.AddressSpaceLimit = *p.AddressSpaceLimit
+	}
+	if p.StackLimitMB != nil {
+		cmd.StackLimit = int64(*p.StackLimitMB) * 1024 * 1024
+	}
+	if p.ProcLimit != nil {
+		cmd.ProcLimit = int32(*p.ProcLimit)
+	}
+}
+
+// ParseSandboxPolicies parses JUDGE_SANDBOX_POLICY: "language.option=value" pairs, comma-separated,
+// where language is a language key or "*" for all and option is network, address_space_limit
+// (true/false), stack_limit_mb or proc_limit. Example: "*.stack_limit_mb=256,java.proc_limit=128".
+func ParseSandboxPolicies(raw string) (map[string]SandboxPolicy, error) {
+	policies := map[string]SandboxPolicy{}
+	for _, part := range parseCSV(raw) {
+		key, value, ok := strings.Cut(part, "=")
+		lang, option, ok2 := strings.Cut(strings.TrimSpace(key), ".")
+		lang, option, value = strings.ToLower(lang), strings.ToLower(option), strings.TrimSpace(value)
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("invalid JUDGE_SANDBOX_POLICY entry %q (want language.option=value)", part)
+		}
+		if lang != "*" && !isSupportedLanguage(lang) {
+			return nil, fmt.Errorf("JUDGE_SANDBOX_POLICY entry %q: unknown language %q", part, lang)
+		}
+		p := policies[lang]
+		switch option {
+		case "network", "address_space_limit":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("JUDGE_SANDBOX_POLICY entry %q: %s must be true or false", part, option)
+			}
+			if option == "network" {
+				p.Network = &b
+			} else {
+				p.AddressSpaceLimit = &b
+			}
+		case "stack_limit_mb", "proc_limit":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("JUDGE_SANDBOX_POLICY entry %q: %s must be an integer", part, option)
+			}
+			if option == "stack_limit_mb" {
+				p.StackLimitMB = &n
+			} else {
+				p.ProcLimit = &n
+			}
+		default:
+			return nil, fmt.Errorf("JUDGE_SANDBOX_POLICY entry %q: unknown option %q", part, option)
+		}
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("JUDGE_SANDBOX_POLICY entry %q: %v", part, err)
+		}
+		policies[lang] = p
+	}
+	return policies, nil
+}
+
+type sandboxPolicyKey struct{}
+
+// WithSandboxPolicy attaches a problem's sandbox policy to the judge calls made with ctx. It takes
+// precedence over the per-language configuration.
+func WithSandboxPolicy(ctx context.Context, p *SandboxPolicy) context.Context {
+	if p == nil || p.IsZero() {
+		return ctx
+	}
+	return context.WithValue(ctx, sandboxPolicyKey{}, *p)
+}
+
+// effectiveSandboxPolicy combines the "*" and language entries of the configuration with the
+// problem's policy carried by ctx (nil ctx means none).
+func effectiveSandboxPolicy(ctx context.Context, policies map[string]SandboxPolicy, lang string) SandboxPolicy {
+	p := policies["*"].merge(policies[strings.ToLower(strings.TrimSpace(lang))])
+	if ctx != nil {
+		if over, ok := ctx.Value(sandboxPolicyKey{}).(SandboxPolicy); ok {
+			p = p.merge(over)
+		}
+	}
+	return p
+}
+
+// SandboxPolicy returns the problem's own sandbox policy, nil when it has none.
+func (r *PgProblemRepository) SandboxPolicy(ctx context.Context, id int64) (*SandboxPolicy, error) {
+	var raw []byte
+	if err := r.db.QueryRow(ctx, `SELECT sandbox_policy FROM problems WHERE id=$1`, id).Scan(&raw); err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+	var p SandboxPolicy
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// SetSandboxPolicy replaces the problem's sandbox policy; nil or an empty policy clears it.
+func (r *PgProblemRepository) SetSandboxPolicy(ctx context.Context, id int64, p *SandboxPolicy) error {
+	var raw []byte
+	if p != nil && !p.IsZero() {
+		b, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		raw = b
+	}
+	ct, err := r.db.Exec(ctx, `UPDATE problems SET sandbox_policy=$2 WHERE id=$1`, id, raw)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
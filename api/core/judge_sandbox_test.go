@@ -0,0 +1,54 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSandboxPolicyAppliedToRun(t *testing.T) {
+	if _, err := ParseSandboxPolicies("ruby.network=true"); err == nil {
+		t.Fatal("expected unknown language to be rejected")
+	}
+	policies, err := ParseSandboxPolicies("*.stack_limit_mb=256, java.proc_limit=128")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []judgeCommand
+	judge := func(network bool) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				Cmd []judgeCommand `json:"cmd"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			got = append(got, body.Cmd...)
+			id := "local"
+			if network {
+				id = "remote"
+			}
+			_ = json.NewEncoder(w).Encode([]judgeResponse{{Status: "Accepted", FileIDs: map[string]string{"Main.jar": id}}})
+		}))
+	}
+	local, remote := judge(false), judge(true)
+	defer local.Close()
+	defer remote.Close()
+	client := NewHTTPJudgeClient(local.URL)
+	client.ConfigureSandbox(policies, remote.URL)
+
+	enabled := true
+	ctx := WithSandboxPolicy(context.Background(), &SandboxPolicy{Network: &enabled})
+	_, _, artifactID, err := client.Compile(ctx, "java", "class Main {}", 1000, 256)
+	if err != nil || artifactID != networkArtifactPrefix+"remote" {
+		t.Fatalf("expected artifact on the network judge, got %q (%v)", artifactID, err)
+	}
+	if _, err := client.RunWithArtifact(ctx, "java", artifactID, "", 1000, 256); err != nil {
+		t.Fatal(err)
+	}
+	run := got[len(got)-1]
+	if run.CopyIn["Main.jar"].FileID != "remote" || run.ProcLimit != 128 || run.StackLimit != 256<<20 || run.AddressSpaceLimit {
+		t.Fatalf("unexpected run command: %+v", run)
+	}
+}
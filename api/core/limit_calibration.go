@@ -0,0 +1,261 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"tuis-oj-prototype/core/checker"
+)
+
+const (
+	maxCalibrationSolutions = 4
+	maxCalibrationRuns      = 10
+	defaultCalibrationRuns  = 3
+	// 参照解は現在の制限の calibrationHeadroom 倍まで（最大 maxCalibrationRunMs）待つ
+	calibrationHeadroom  = 4
+	maxCalibrationRunMs  = 20000
+	calibrationRoundMS   = 100
+	calibrationMinMS     = 1000
+	defaultTimeLimitRule = "*=2"
+)
+
+// Calibration outcomes of a reference solution. Only "ok" ones count towards the recommendation.
+const (
+	CalibrationOK           = "ok"
+	CalibrationCompileError = "compile_error"
+	CalibrationWrongAnswer  = "wrong_answer"
+	CalibrationRuntimeError = "runtime_error"
+	CalibrationTooSlow      = "too_slow"
+)
+
+var ErrCalibrationInput = errors.New("invalid calibration")
+
+// TimeLimitPolicy maps a language to the multiplier applied to its slowest reference run; "*" is
+// the default for languages without an entry.
+type TimeLimitPolicy map[string]float64
+
+// ParseTimeLimitPolicy parses TIME_LIMIT_POLICY: "language=multiplier" pairs, comma-separated,
+// e.g. "*=2,python=3". Empty means "*=2".
+func ParseTimeLimitPolicy(raw string) (TimeLimitPolicy, error) {
+	if strings.TrimSpace(raw) == "" {
+		raw = defaultTimeLimitRule
+	}
+	policy := TimeLimitPolicy{}
+	for _, part := range parseCSV(raw) {
+		lang, factor, ok := strings.Cut(part, "=")
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		f, err := strconv.ParseFloat(strings.TrimSpace(factor), 64)
+		if !ok || lang == "" || err != nil || f < 1 || f > 100 {
+			return nil, fmt.Errorf("invalid TIME_LIMIT_POLICY entry %q (want language=multiplier, multiplier 1〜100)", part)
+		}
+		if lang != "*" && !isSupportedLanguage(lang) {
+			return nil, fmt.Errorf("TIME_LIMIT_POLICY entry %q: unknown language %q", part, lang)
+		}
+		policy[lang] = f
+	}
+	if _, ok := policy["*"]; !ok {
+		policy["*"] = 2
+	}
+	return policy, nil
+}
+
+func (p TimeLimitPolicy) multiplier(lang string) float64 {
+	if f, ok := p[lang]; ok {
+		return f
+	}
+	return p["*"]
+}
+
+// suggest returns the time limit for a slowest run of ms: the multiplier applied, rounded up to
+// calibrationRoundMS and at least calibrationMinMS.
+func (p TimeLimitPolicy) suggest(lang string, ms int64) int32 {
+	limit := int64(math.Ceil(float64(ms)*p.multiplier(lang)/calibrationRoundMS)) * calibrationRoundMS
+	if limit < calibrationMinMS {
+		limit = calibrationMinMS
+	}
+	return int32(limit)
+}
+
+// LimitCalibrationSolution is a reference solution to measure.
+type LimitCalibrationSolution struct {
+	Language   string `json:"language"`
+	SourceCode string `json:"source_code"`
+}
+
+// RuntimeDistribution summarises the slowest-testcase runtime of each run.
+type RuntimeDistribution struct {
+	Samples  int   `json:"samples"`
+	MinMS    int64 `json:"min_ms"`
+	MedianMS int64 `json:"median_ms"`
+	P90MS    int64 `json:"p90_ms"`
+	MaxMS    int64 `json:"max_ms"`
+}
+
+// LimitCalibrationResult is the measurement of one reference solution.
+type LimitCalibrationResult struct {
+	Language string `json:"language"`
+	Status   string `json:"status"`
+	Message  string `json:"message,omitempty"`
+	// OutputChecked is false for custom checkers: the outputs are not verified then.
+	OutputChecked        bool                 `json:"output_checked"`
+	SlowestTestcase      string               `json:"slowest_testcase,omitempty"`
+	Runtime              *RuntimeDistribution `json:"runtime"`
+	MaxMemoryKB          int64                `json:"max_memory_kb"`
+	Multiplier           float64              `json:"multiplier"`
+	SuggestedTimeLimitMS int32                `json:"suggested_time_limit_ms"`
+}
+
+// LimitCalibrationReport is the outcome of calibrateTimeLimit. RecommendedTimeLimitMS is the
+// largest suggestion among the passing solutions, 0 when none passed.
+type LimitCalibrationReport struct {
+	ProblemID              int64                    `json:"problem_id"`
+	Runs                   int                      `json:"runs"`
+	CurrentTimeLimitMS     int32                    `json:"current_time_limit_ms"`
+	RecommendedTimeLimitMS int32                    `json:"recommended_time_limit_ms"`
+	Solutions              []LimitCalibrationResult `json:"solutions"`
+	Applied                bool                     `json:"applied"`
+}
+
+// validateCalibration normalises the request and returns the run count to use.
+func validateCalibration(solutions []LimitCalibrationSolution, runs int) (int, error) {
+	if len(solutions) == 0 || len(solutions) > maxCalibrationSolutions {
+		return 0, fmt.Errorf("%w: solutions は 1〜%d 件で指定してください", ErrCalibrationInput, maxCalibrationSolutions)
+	}
+	for i := range solutions {
+		solutions[i].Language = strings.ToLower(strings.TrimSpace(solutions[i].Language))
+		if !isSupportedLanguage(solutions[i].Language) {
+			return 0, fmt.Errorf("%w: solutions[%d] はサポートされていない言語です", ErrCalibrationInput, i)
+		}
+		if strings.TrimSpace(solutions[i].SourceCode) == "" {
+			return 0, fmt.Errorf("%w: solutions[%d] の source_code が空です", ErrCalibrationInput, i)
+		}
+	}
+	if runs == 0 {
+		runs = defaultCalibrationRuns
+	}
+	if runs < 1 || runs > maxCalibrationRuns {
+		return 0, fmt.Errorf("%w: runs は 1〜%d で指定してください", ErrCalibrationInput, maxCalibrationRuns)
+	}
+	return runs, nil
+}
+
+func runtimeDistribution(ms []int64) *RuntimeDistribution {
+	if len(ms) == 0 {
+		return nil
+	}
+	sorted := append([]int64(nil), ms...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := func(p int) int64 {
+		idx := (p*len(sorted)+99)/100 - 1
+		if idx < 0 {
+			idx = 0
+		}
+		return sorted[idx]
+	}
+	return &RuntimeDistribution{Samples: len(sorted), MinMS: sorted[0], MedianMS: rank(50), P90MS: rank(90), MaxMS: sorted[len(sorted)-1]}
+}
+
+// calibrateTimeLimit runs every solution runs times over all testcases, one execution at a time so
+// they do not disturb each other's timing. Testcases of groups with their own time limit are run
+// (a wrong answer there still fails the solution) but do not count towards the problem's limit.
+func calibrateTimeLimit(ctx context.Context, judge JudgeClient, detail *ProblemDetail, cases []ProblemTestcase, solutions []LimitCalibrationSolution, runs int, policy TimeLimitPolicy) (*LimitCalibrationReport, error) {
+	report := &LimitCalibrationReport{ProblemID: detail.ID, Runs: runs, CurrentTimeLimitMS: detail.TimeLimitMS}
+	memoryLimitMb := int((detail.MemoryLimitKB + 1023) / 1024)
+	runLimitMs := int(detail.TimeLimitMS) * calibrationHeadroom
+	if runLimitMs > maxCalibrationRunMs || runLimitMs <= 0 {
+		runLimitMs = maxCalibrationRunMs
+	}
+	checked := detail.CheckerType != CheckerTypeCustom
+
+	for _, sol := range solutions {
+		res := LimitCalibrationResult{Language: sol.Language, OutputChecked: checked, Multiplier: policy.multiplier(sol.Language)}
+		if err := measureSolution(ctx, judge, detail, cases, sol, runs, runLimitMs, memoryLimitMb, &res); err != nil {
+			return nil, err
+		}
+		if res.Status == CalibrationOK && res.Runtime != nil {
+			res.SuggestedTimeLimitMS = policy.suggest(sol.Language, res.Runtime.MaxMS)
+			if res.SuggestedTimeLimitMS > report.RecommendedTimeLimitMS {
+				report.RecommendedTimeLimitMS = res.SuggestedTimeLimitMS
+			}
+		}
+		report.Solutions = append(report.Solutions, res)
+	}
+	return report, nil
+}
+
+// measureSolution fills res for one solution. Only judge connection errors are returned; a
+// failing solution is reported through res.Status.
+func measureSolution(ctx context.Context, judge JudgeClient, detail *ProblemDetail, cases []ProblemTestcase, sol LimitCalibrationSolution, runs, runLimitMs, memoryLimitMb int, res *LimitCalibrationResult) error {
+	compileRes, _, artifactID, err := judge.Compile(ctx, sol.Language, sol.SourceCode, generatorCompileLimitMs, memoryLimitMb)
+	if err != nil {
+		return err
+	}
+	if artifactID != "" {
+		defer func() { _ = judge.RemoveFiles(context.WithoutCancel(ctx), artifactID) }()
+	}
+	if compileRes.Status != "Accepted" || compileRes.ExitStatus != 0 || artifactID == "" {
+		res.Status = CalibrationCompileError
+		res.Message = strings.TrimSpace(firstNonEmpty(compileRes.Files["stderr"], compileRes.Error, compileRes.Status))
+		return nil
+	}
+
+	var slowest []int64
+	slowestCase := map[int]int{} // テストケースの位置 -> 最も遅かった回数
+	for run := 0; run < runs; run++ {
+		slowestMS, slowestAt := int64(-1), -1
+		for i, tc := range cases {
+			r, err := judge.RunWithArtifact(ctx, sol.Language, artifactID, tc.InputText, runLimitMs, memoryLimitMb)
+			if err != nil {
+				return err
+			}
+			name := strconv.Itoa(i + 1)
+			switch r.Status {
+			case "Accepted":
+			case "Time Limit Exceeded":
+				res.Status, res.Message = CalibrationTooSlow, fmt.Sprintf("testcase %s が %dms を超えました", name, runLimitMs)
+				return nil
+			default:
+				res.Status, res.Message = CalibrationRuntimeError, fmt.Sprintf("testcase %s: %s", name, firstNonEmpty(r.Error, r.Status))
+				return nil
+			}
+			if r.ExitStatus != 0 {
+				res.Status, res.Message = CalibrationRuntimeError, fmt.Sprintf("testcase %s: exit status %d", name, r.ExitStatus)
+				return nil
+			}
+			if res.OutputChecked && !checker.Equal(r.Files["stdout"], tc.OutputText, detail.CheckerType, detail.CheckerEps) {
+				res.Status, res.Message = CalibrationWrongAnswer, fmt.Sprintf("testcase %s の出力が期待と異なります", name)
+				return nil
+			}
+			if kb := r.Memory / 1024; kb > res.MaxMemoryKB {
+				res.MaxMemoryKB = kb
+			}
+			if tc.TimeLimitMS != nil {
+				continue
+			}
+			if ms := r.Time / 1_000_000; ms > slowestMS {
+				slowestMS, slowestAt = ms, i
+			}
+		}
+		if slowestAt >= 0 {
+			slowest = append(slowest, slowestMS)
+			slowestCase[slowestAt]++
+		}
+	}
+	res.Status = CalibrationOK
+	res.Runtime = runtimeDistribution(slowest)
+	best := -1
+	for at, n := range slowestCase {
+		if best < 0 || n > slowestCase[best] || (n == slowestCase[best] && at < best) {
+			best = at
+		}
+	}
+	if best >= 0 {
+		res.SlowestTestcase = strconv.Itoa(best + 1)
+	}
+	return nil
+}
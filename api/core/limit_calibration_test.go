@@ -0,0 +1,51 @@
+package core
+
+import (
+	"context"
+	"testing"
+)
+
+// calibrationJudge answers each run with the next time in ms and echoes the input.
+type calibrationJudge struct {
+	JudgeClient
+	times []int64
+}
+
+func (j *calibrationJudge) Compile(context.Context, string, string, int, int) (*judgeResponse, string, string, error) {
+	return &judgeResponse{Status: "Accepted"}, "", "artifact", nil
+}
+
+func (j *calibrationJudge) RunWithArtifact(_ context.Context, _, _, stdin string, _, _ int) (*judgeResponse, error) {
+	ms := j.times[0]
+	j.times = j.times[1:]
+	return &judgeResponse{Status: "Accepted", Time: ms * 1_000_000, Memory: 2048 * 1024, Files: map[string]string{"stdout": stdin}}, nil
+}
+
+func (j *calibrationJudge) RemoveFiles(context.Context, ...string) error { return nil }
+
+func TestCalibrateTimeLimit(t *testing.T) {
+	policy, err := ParseTimeLimitPolicy("*=2,python=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseTimeLimitPolicy("ruby=2"); err == nil {
+		t.Fatal("expected unknown language to be rejected")
+	}
+	detail := &ProblemDetail{ProblemMeta: ProblemMeta{ID: 7, TimeLimitMS: 2000, MemoryLimitKB: 262144}, CheckerType: "exact"}
+	cases := []ProblemTestcase{{InputText: "1\n", OutputText: "1\n"}, {InputText: "2\n", OutputText: "2\n"}}
+	solutions := []LimitCalibrationSolution{{Language: "cpp", SourceCode: "x"}, {Language: "python", SourceCode: "x"}}
+	// cpp: 2 回 × 2 ケース、python: 同じく
+	judge := &calibrationJudge{times: []int64{100, 420, 90, 380, 900, 1210, 950, 1180}}
+
+	report, err := calibrateTimeLimit(context.Background(), judge, detail, cases, solutions, 2, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cpp, py := report.Solutions[0], report.Solutions[1]
+	if cpp.Status != CalibrationOK || cpp.Runtime.MaxMS != 420 || cpp.SlowestTestcase != "2" || cpp.SuggestedTimeLimitMS != 1000 {
+		t.Fatalf("unexpected cpp result: %+v %+v", cpp, cpp.Runtime)
+	}
+	if py.Runtime.MinMS != 1180 || py.SuggestedTimeLimitMS != 3700 || report.RecommendedTimeLimitMS != 3700 {
+		t.Fatalf("unexpected python result: %+v %+v (recommended %d)", py, py.Runtime, report.RecommendedTimeLimitMS)
+	}
+}
@@ -0,0 +1,222 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log"
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListResponseMiddleware shapes GET responses that carry a top-level "items" array:
+//   - fields=id,title,verdict keeps only those keys of each item (sparse fieldsets)
+//   - responses above LIST_RESPONSE_MAX_KB keep as many leading items as fit and report
+//     "truncated": true with "returned_items", so a page with huge items stays bounded
+//
+// Other responses (non-JSON, streams, downloads) pass through untouched.
+func ListResponseMiddleware(cfg Config) gin.HandlerFunc {
+	maxBytes := cfg.ListResponseMaxKB * 1024
+	if maxBytes <= 0 {
+		maxBytes = 1024 * 1024
+	}
+	return func(c *gin.Context) {
+		if c.Request.Method != "GET" {
+			c.Next()
+			return
+		}
+		w := &listResponseWriter{ResponseWriter: c.Writer, status: c.Writer.Status()}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if w.mode != listWriterBuffer {
+			if w.mode == listWriterUndecided {
+				w.ResponseWriter.WriteHeader(w.status)
+			}
+			return
+		}
+		body := w.buf.Bytes()
+		if shaped, changed := shapeListResponse(body, parseFieldsParam(c.Query("fields")), maxBytes); changed {
+			body = shaped
+		}
+		w.ResponseWriter.WriteHeader(w.status)
+		if _, err := w.ResponseWriter.Write(body); err != nil {
+			log.Printf("list response write failed: %v", err)
+		}
+	}
+}
+
+func parseFieldsParam(v string) []string {
+	var fields []string
+	for _, f := range strings.Split(v, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// shapeListResponse applies the field selection and the size cap to a JSON body.
+// It reports false when the body is left as is (no items array or nothing to do).
+func shapeListResponse(body []byte, fields []string, maxBytes int) ([]byte, bool) {
+	if len(fields) == 0 && len(body) <= maxBytes {
+		return body, false
+	}
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(body, &top); err != nil {
+		return body, false
+	}
+	var items []json.RawMessage
+	if raw, ok := top["items"]; !ok || json.Unmarshal(raw, &items) != nil {
+		return body, false
+	}
+
+	if len(fields) > 0 {
+		keep := make(map[string]bool, len(fields))
+		for _, f := range fields {
+			keep[f] = true
+		}
+		for i, item := range items {
+			var obj map[string]json.RawMessage
+			if json.Unmarshal(item, &obj) != nil {
+				continue
+			}
+			for k := range obj {
+				if !keep[k] {
+					delete(obj, k)
+				}
+			}
+			if b, err := json.Marshal(obj); err == nil {
+				items[i] = b
+			}
+		}
+	}
+
+	top["items"] = json.RawMessage("[]")
+	base, err := json.Marshal(top)
+	if err != nil {
+		return body, false
+	}
+	size := len(base)
+	n := 0
+	for ; n < len(items); n++ {
+		size += len(items[n]) + 1
+		if size > maxBytes && n > 0 {
+			break
+		}
+	}
+	if n < len(items) {
+		top["truncated"] = json.RawMessage("true")
+		top["returned_items"], _ = json.Marshal(n)
+		items = items[:n]
+	}
+	top["items"], err = json.Marshal(items)
+	if err != nil {
+		return body, false
+	}
+	out, err := json.Marshal(top)
+	if err != nil {
+		return body, false
+	}
+	return out, true
+}
+
+const (
+	listWriterUndecided = iota
+	listWriterBuffer
+	listWriterPassthrough
+)
+
+// listResponseWriter buffers JSON bodies so they can be reshaped after the handler ran;
+// anything else is written through as soon as the first byte arrives.
+type listResponseWriter struct {
+	gin.ResponseWriter
+	status int
+	mode   int
+	buf    bytes.Buffer
+}
+
+func (w *listResponseWriter) decide() {
+	if w.mode != listWriterUndecided {
+		return
+	}
+	if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		w.mode = listWriterBuffer
+		return
+	}
+	w.mode = listWriterPassthrough
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+func (w *listResponseWriter) WriteHeader(code int) {
+	if w.mode == listWriterPassthrough {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if code > 0 {
+		w.status = code
+	}
+}
+
+func (w *listResponseWriter) WriteHeaderNow() {
+	w.decide()
+	if w.mode == listWriterPassthrough {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *listResponseWriter) Write(b []byte) (int, error) {
+	w.decide()
+	if w.mode == listWriterBuffer {
+		return w.buf.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *listResponseWriter) WriteString(s string) (int, error) {
+	w.decide()
+	if w.mode == listWriterBuffer {
+		return w.buf.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *listResponseWriter) Flush() {
+	w.decide()
+	if w.mode == listWriterPassthrough {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// Hijack hands the connection over (WebSocket upgrade); nothing is buffered from then on.
+func (w *listResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.mode = listWriterPassthrough
+	return w.ResponseWriter.Hijack()
+}
+
+func (w *listResponseWriter) Status() int {
+	if w.mode == listWriterPassthrough {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+func (w *listResponseWriter) Size() int {
+	if w.mode == listWriterBuffer {
+		return w.buf.Len()
+	}
+	return w.ResponseWriter.Size()
+}
+
+func (w *listResponseWriter) Written() bool {
+	switch w.mode {
+	case listWriterBuffer:
+		return true
+	case listWriterPassthrough:
+		return w.ResponseWriter.Written()
+	}
+	return false
+}
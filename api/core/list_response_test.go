@@ -0,0 +1,50 @@
+package core
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestShapeListResponse(t *testing.T) {
+	body := []byte(`{"items":[{"id":1,"title":"A","statement":"long"},{"id":2,"title":"B","statement":"long"}],"page":1}`)
+
+	out, changed := shapeListResponse(body, []string{"id", "title"}, 1<<20)
+	if !changed {
+		t.Fatal("expected field selection to apply")
+	}
+	if want := `{"items":[{"id":1,"title":"A"},{"id":2,"title":"B"}],"page":1}`; string(out) != want {
+		t.Fatalf("got %s", out)
+	}
+
+	if _, changed := shapeListResponse(body, nil, 1<<20); changed {
+		t.Fatal("small response without fields should be left as is")
+	}
+	if _, changed := shapeListResponse([]byte(`{"id":1}`), []string{"id"}, 1<<20); changed {
+		t.Fatal("response without items should be left as is")
+	}
+
+	big := `{"items":[` + strings.Repeat(`{"s":"`+strings.Repeat("x", 100)+`"},`, 20) + `{"s":"y"}],"page":1}`
+	out, changed = shapeListResponse([]byte(big), nil, 500)
+	if !changed || len(out) > 500 {
+		t.Fatalf("expected truncation to 500 bytes, got %d (changed=%v)", len(out), changed)
+	}
+	var resp struct {
+		Items         []json.RawMessage `json:"items"`
+		Truncated     bool              `json:"truncated"`
+		ReturnedItems int               `json:"returned_items"`
+		Page          int               `json:"page"`
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Truncated || resp.ReturnedItems != len(resp.Items) || resp.ReturnedItems == 0 || resp.Page != 1 {
+		t.Fatalf("unexpected truncated response: %+v", resp)
+	}
+
+	// 1件だけでも上限を超える場合はその1件を返す
+	out, _ = shapeListResponse([]byte(big), nil, 10)
+	if err := json.Unmarshal(out, &resp); err != nil || len(resp.Items) != 1 {
+		t.Fatalf("expected a single item, got %s", out)
+	}
+}
@@ -0,0 +1,299 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Load test states. They describe the generator only; judging of the generated submissions may
+// still be in progress after a test is completed.
+const (
+	LoadTestRunning   = "running"
+	LoadTestCompleted = "completed"
+	LoadTestCancelled = "cancelled"
+	LoadTestFailed    = "failed"
+)
+
+const (
+	maxLoadTestRatePerMinute = 600
+	maxLoadTestDuration      = time.Hour
+	maxLoadTestSubmissions   = 10000
+	maxLoadTestsListed       = 50
+)
+
+var (
+	ErrLoadTestInput   = errors.New("invalid load test")
+	ErrLoadTestRunning = errors.New("load test is running")
+)
+
+// LoadTest is a run of synthetic submissions against the judge queue and its measured outcome.
+type LoadTest struct {
+	ID            int64      `json:"id"`
+	ProblemID     int64      `json:"problem_id"`
+	Language      string     `json:"language"`
+	RatePerMinute int        `json:"rate_per_minute"`
+	DurationSec   int        `json:"duration_sec"`
+	RequestedBy   *int64     `json:"requested_by"`
+	Status        string     `json:"status"`
+	ErrorMessage  *string    `json:"error_message"`
+	CreatedAt     time.Time  `json:"created_at"`
+	FinishedAt    *time.Time `json:"finished_at"`
+	Submitted     int        `json:"submitted"`
+	Judged        int        `json:"judged"`
+	SystemErrors  int        `json:"system_errors"`
+	// Latency is filled by Get only.
+	Latency *LoadTestLatency `json:"latency,omitempty"`
+}
+
+// LoadTestLatency summarises end-to-end latency (submission created → result stored) of judged submissions.
+type LoadTestLatency struct {
+	Samples int   `json:"samples"`
+	P50MS   int64 `json:"p50_ms"`
+	P90MS   int64 `json:"p90_ms"`
+	P95MS   int64 `json:"p95_ms"`
+	P99MS   int64 `json:"p99_ms"`
+	MaxMS   int64 `json:"max_ms"`
+}
+
+// ValidateLoadTest checks the generator parameters and returns the number of submissions to create.
+func ValidateLoadTest(ratePerMinute, durationSec int) (int, error) {
+	if ratePerMinute <= 0 || ratePerMinute > maxLoadTestRatePerMinute {
+		return 0, fmt.Errorf("%w: rate_per_minute は 1〜%d で指定してください", ErrLoadTestInput, maxLoadTestRatePerMinute)
+	}
+	if durationSec <= 0 || time.Duration(durationSec)*time.Second > maxLoadTestDuration {
+		return 0, fmt.Errorf("%w: duration_sec は 1〜%d で指定してください", ErrLoadTestInput, int(maxLoadTestDuration/time.Second))
+	}
+	total := ratePerMinute * durationSec / 60
+	if total < 1 {
+		total = 1
+	}
+	if total > maxLoadTestSubmissions {
+		return 0, fmt.Errorf("%w: 提出数 (rate_per_minute × duration_sec / 60) は %d 件までです", ErrLoadTestInput, maxLoadTestSubmissions)
+	}
+	return total, nil
+}
+
+// latencyPercentiles computes nearest-rank percentiles of the given latencies; nil when there are none.
+func latencyPercentiles(ms []int64) *LoadTestLatency {
+	if len(ms) == 0 {
+		return nil
+	}
+	sorted := append([]int64(nil), ms...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := func(p int) int64 {
+		idx := (p*len(sorted)+99)/100 - 1
+		if idx < 0 {
+			idx = 0
+		}
+		return sorted[idx]
+	}
+	return &LoadTestLatency{
+		Samples: len(sorted),
+		P50MS:   rank(50),
+		P90MS:   rank(90),
+		P95MS:   rank(95),
+		P99MS:   rank(99),
+		MaxMS:   sorted[len(sorted)-1],
+	}
+}
+
+// LoadTestRepository stores load tests and the tag linking generated submissions to them.
+type LoadTestRepository interface {
+	Create(ctx context.Context, t LoadTest) (*LoadTest, error)
+	// Tag marks a generated submission as belonging to the test.
+	Tag(ctx context.Context, id, submissionID int64) error
+	// Finish moves a running test to status; it returns pgx.ErrNoRows when the test is not running.
+	Finish(ctx context.Context, id int64, status string, errMsg *string) error
+	Status(ctx context.Context, id int64) (string, error)
+	Get(ctx context.Context, id int64) (*LoadTest, error)
+	List(ctx context.Context, limit int) ([]LoadTest, error)
+	// Delete removes the test and its tagged submissions, returning the submission ids so their files
+	// can be removed. It returns ErrLoadTestRunning while the generator is still running.
+	Delete(ctx context.Context, id int64) ([]int64, error)
+}
+
+type PgLoadTestRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPgLoadTestRepository(db *pgxpool.Pool) *PgLoadTestRepository {
+	return &PgLoadTestRepository{db: db}
+}
+
+const loadTestSelect = `
+SELECT t.id, t.problem_id, t.language, t.rate_per_minute, t.duration_sec, t.requested_by, t.status,
+       t.error_message, t.created_at, t.finished_at,
+       COUNT(s.id),
+       COUNT(sr.verdict),
+       COUNT(*) FILTER (WHERE sr.verdict = '` + VerdictSE + `')
+FROM load_tests t
+LEFT JOIN submissions s ON s.load_test_id = t.id
+LEFT JOIN submission_results sr ON sr.submission_id = s.id
+`
+
+func scanLoadTest(row pgx.Row) (*LoadTest, error) {
+	var t LoadTest
+	if err := row.Scan(&t.ID, &t.ProblemID, &t.Language, &t.RatePerMinute, &t.DurationSec, &t.RequestedBy, &t.Status,
+		&t.ErrorMessage, &t.CreatedAt, &t.FinishedAt, &t.Submitted, &t.Judged, &t.SystemErrors); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *PgLoadTestRepository) Create(ctx context.Context, t LoadTest) (*LoadTest, error) {
+	var id int64
+	if err := r.db.QueryRow(ctx, `INSERT INTO load_tests (problem_id, language, rate_per_minute, duration_sec, requested_by)
+VALUES ($1,$2,$3,$4,$5) RETURNING id`, t.ProblemID, t.Language, t.RatePerMinute, t.DurationSec, t.RequestedBy).Scan(&id); err != nil {
+		return nil, err
+	}
+	return r.Get(ctx, id)
+}
+
+func (r *PgLoadTestRepository) Tag(ctx context.Context, id, submissionID int64) error {
+	_, err := r.db.Exec(ctx, `UPDATE submissions SET load_test_id=$1 WHERE id=$2`, id, submissionID)
+	return err
+}
+
+func (r *PgLoadTestRepository) Finish(ctx context.Context, id int64, status string, errMsg *string) error {
+	ct, err := r.db.Exec(ctx, `UPDATE load_tests SET status=$2, error_message=$3, finished_at=NOW() WHERE id=$1 AND status=$4`,
+		id, status, errMsg, LoadTestRunning)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PgLoadTestRepository) Status(ctx context.Context, id int64) (string, error) {
+	var status string
+	err := r.db.QueryRow(ctx, `SELECT status FROM load_tests WHERE id=$1`, id).Scan(&status)
+	return status, err
+}
+
+func (r *PgLoadTestRepository) Get(ctx context.Context, id int64) (*LoadTest, error) {
+	t, err := scanLoadTest(r.db.QueryRow(ctx, loadTestSelect+`WHERE t.id=$1 GROUP BY t.id`, id))
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.Query(ctx, `
+SELECT (EXTRACT(EPOCH FROM (sr.updated_at - s.created_at)) * 1000)::BIGINT
+FROM submissions s
+JOIN submission_results sr ON sr.submission_id = s.id
+WHERE s.load_test_id=$1 AND sr.verdict IS NOT NULL`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var latencies []int64
+	for rows.Next() {
+		var ms int64
+		if err := rows.Scan(&ms); err != nil {
+			return nil, err
+		}
+		latencies = append(latencies, ms)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	t.Latency = latencyPercentiles(latencies)
+	return t, nil
+}
+
+func (r *PgLoadTestRepository) List(ctx context.Context, limit int) ([]LoadTest, error) {
+	rows, err := r.db.Query(ctx, loadTestSelect+`GROUP BY t.id ORDER BY t.id DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []LoadTest{}
+	for rows.Next() {
+		t, err := scanLoadTest(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *t)
+	}
+	return items, rows.Err()
+}
+
+func (r *PgLoadTestRepository) Delete(ctx context.Context, id int64) ([]int64, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var status string
+	if err := tx.QueryRow(ctx, `SELECT status FROM load_tests WHERE id=$1 FOR UPDATE`, id).Scan(&status); err != nil {
+		return nil, err
+	}
+	if status == LoadTestRunning {
+		return nil, ErrLoadTestRunning
+	}
+	rows, err := tx.Query(ctx, `DELETE FROM submissions WHERE load_test_id=$1 RETURNING id`, id)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var sid int64
+		if err := rows.Scan(&sid); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, sid)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM load_tests WHERE id=$1`, id); err != nil {
+		return nil, err
+	}
+	return ids, tx.Commit(ctx)
+}
+
+// runLoadTest creates total submissions spread evenly at the test's rate, tagging each one, until
+// done or the test is cancelled (possibly from another API instance). submit creates and enqueues
+// one submission.
+func runLoadTest(ctx context.Context, repo LoadTestRepository, t LoadTest, total int, submit func(ctx context.Context) (int64, error)) {
+	interval := time.Minute / time.Duration(t.RatePerMinute)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for i := 0; i < total; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			// 取り消しは DB の状態で伝わる
+			if status, err := repo.Status(ctx, t.ID); err == nil && status != LoadTestRunning {
+				log.Printf("[loadtest] %d stopped at %d/%d (%s)", t.ID, i, total, status)
+				return
+			}
+		}
+		subID, err := submit(ctx)
+		if err == nil {
+			err = repo.Tag(ctx, t.ID, subID)
+		}
+		if err != nil {
+			msg := fmt.Sprintf("failed at %d/%d: %v", i+1, total, err)
+			log.Printf("[loadtest] %d %s", t.ID, msg)
+			_ = repo.Finish(ctx, t.ID, LoadTestFailed, &msg)
+			return
+		}
+	}
+	if err := repo.Finish(ctx, t.ID, LoadTestCompleted, nil); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("[loadtest] %d finish failed: %v", t.ID, err)
+	}
+}
@@ -0,0 +1,40 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLatencyPercentiles(t *testing.T) {
+	if latencyPercentiles(nil) != nil {
+		t.Fatal("expected nil without samples")
+	}
+	ms := make([]int64, 0, 100)
+	for i := int64(100); i >= 1; i-- {
+		ms = append(ms, i*10)
+	}
+	got := latencyPercentiles(ms)
+	if got.Samples != 100 || got.P50MS != 500 || got.P90MS != 900 || got.P95MS != 950 || got.P99MS != 990 || got.MaxMS != 1000 {
+		t.Fatalf("got %+v", got)
+	}
+	if ms[0] != 1000 {
+		t.Fatal("input must not be reordered")
+	}
+	if one := latencyPercentiles([]int64{42}); one.P50MS != 42 || one.P99MS != 42 {
+		t.Fatalf("got %+v", one)
+	}
+}
+
+func TestValidateLoadTest(t *testing.T) {
+	if n, err := ValidateLoadTest(120, 300); err != nil || n != 600 {
+		t.Fatalf("got %d %v", n, err)
+	}
+	if n, err := ValidateLoadTest(1, 10); err != nil || n != 1 {
+		t.Fatalf("short runs should create at least one submission, got %d %v", n, err)
+	}
+	for _, tc := range [][2]int{{0, 60}, {601, 60}, {60, 0}, {60, 3601}} {
+		if _, err := ValidateLoadTest(tc[0], tc[1]); !errors.Is(err, ErrLoadTestInput) {
+			t.Fatalf("%v: expected ErrLoadTestInput, got %v", tc, err)
+		}
+	}
+}
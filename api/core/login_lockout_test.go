@@ -0,0 +1,39 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestLoginLockout(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+	lockout := NewLoginLockout(client, Config{LoginLockoutThreshold: 3, LoginLockoutMinutes: 15})
+
+	for i := 1; i <= 3; i++ {
+		locked, err := lockout.Fail(ctx, "alice", "10.0.0.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if locked != (i == 3) {
+			t.Fatalf("failure %d: locked=%v", i, locked)
+		}
+	}
+	if wait, _ := lockout.Locked(ctx, "alice", "10.0.0.2"); wait <= 0 {
+		t.Fatal("expected the account to be locked from any address")
+	}
+	if wait, _ := lockout.Locked(ctx, "bob", "10.0.0.1"); wait != 0 {
+		t.Fatalf("other accounts must not be locked by a few failures, got %v", wait)
+	}
+	if unlocked, err := lockout.Unlock(ctx, "alice"); err != nil || !unlocked {
+		t.Fatalf("unlock: %v %v", unlocked, err)
+	}
+	if wait, _ := lockout.Locked(ctx, "alice", "10.0.0.1"); wait != 0 {
+		t.Fatalf("expected unlocked account, got %v", wait)
+	}
+}
@@ -0,0 +1,165 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Mailer sends plain-text mail.
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// SMTPMailer delivers mail through an SMTP relay, upgrading to TLS when the server offers STARTTLS.
+type SMTPMailer struct {
+	Addr string // host:port
+	From string
+	Auth smtp.Auth // nil sends without authentication
+}
+
+// mailConfigured reports whether NewMailer returns a mailer.
+func mailConfigured(cfg Config) bool {
+	return cfg.MailFrom != "" && (cfg.SendGridAPIKey != "" || cfg.SMTPAddr != "")
+}
+
+// NewMailer returns the mailer configured by cfg: SendGrid when SENDGRID_API_KEY is set, otherwise SMTP.
+// It returns nil when MAIL_FROM or both transports are unset.
+func NewMailer(cfg Config) Mailer {
+	if !mailConfigured(cfg) {
+		return nil
+	}
+	if cfg.SendGridAPIKey != "" {
+		return &SendGridMailer{APIKey: cfg.SendGridAPIKey, From: cfg.MailFrom, Client: &http.Client{Timeout: 10 * time.Second}}
+	}
+	m := &SMTPMailer{Addr: cfg.SMTPAddr, From: cfg.MailFrom}
+	if cfg.SMTPUsername != "" {
+		host, _, _ := net.SplitHostPort(cfg.SMTPAddr)
+		// PlainAuth は TLS でない接続（localhost を除く）では認証情報を送らない
+		m.Auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, host)
+	}
+	return m
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, to, subject, body string) error {
+	if strings.ContainsAny(to, "\r\n") || strings.ContainsAny(m.From, "\r\n") {
+		return errors.New("mail address contains a line break")
+	}
+	host, _, err := net.SplitHostPort(m.Addr)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP_ADDR: %w", err)
+	}
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", m.Addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if m.Auth != nil {
+		if err := c.Auth(m.Auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(m.From); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(buildMailMessage(m.From, to, subject, body, time.Now())); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+const sendGridEndpoint = "https://api.sendgrid.com/v3/mail/send"
+
+// SendGridMailer delivers mail through the SendGrid v3 API.
+type SendGridMailer struct {
+	APIKey   string
+	From     string
+	Client   *http.Client
+	Endpoint string // empty uses the public API
+}
+
+func (m *SendGridMailer) Send(ctx context.Context, to, subject, body string) error {
+	payload, err := json.Marshal(map[string]any{
+		"personalizations": []any{map[string]any{"to": []any{map[string]string{"email": to}}}},
+		"from":             map[string]string{"email": m.From},
+		"subject":          subject,
+		"content":          []any{map[string]string{"type": "text/plain", "value": body}},
+	})
+	if err != nil {
+		return err
+	}
+	endpoint := m.Endpoint
+	if endpoint == "" {
+		endpoint = sendGridEndpoint
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+m.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := m.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("sendgrid responded with status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// buildMailMessage builds a UTF-8 text/plain message. The subject is MIME-encoded and the body is
+// base64 so that Japanese text passes any relay.
+func buildMailMessage(from, to, subject, body string, now time.Time) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+	encoded := base64.StdEncoding.EncodeToString([]byte(strings.ReplaceAll(body, "\n", "\r\n")))
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded + "\r\n")
+	return b.Bytes()
+}
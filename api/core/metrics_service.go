@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 )
 
@@ -12,31 +13,69 @@ type QueueMetrics struct {
 	Pending          int64 `json:"pending"`
 	Processing       int64 `json:"processing"`
 	ExpiredCandidate int64 `json:"expired_candidate"`
+	// Paused は取り出しが一時停止中かどうか（QueuePausedKey）。
+	Paused bool `json:"paused"`
+}
+
+// MetricsOverview はキューと全ワーカーの簡易情報。Redis に届かないときは最後に取得できた値を
+// Stale=true で返す（一度も取得できていなければゼロ値）。
+type MetricsOverview struct {
+	Queue       QueueMetrics      `json:"queues"`
+	Workers     []WorkerHeartbeat `json:"workers"`
+	Stale       bool              `json:"stale"`
+	CollectedAt *time.Time        `json:"collected_at"` // 値を Redis から取得した時刻
+	RedisError  string            `json:"redis_error,omitempty"`
+	// OrphanFiles は孤立した提出ディレクトリの最後の回収結果（未実行なら nil）。
+	OrphanFiles *OrphanFileReport `json:"orphan_files"`
 }
 
 // MetricsService は Redis からキュー長とワーカーハートビートを取得する。
 type MetricsService struct {
 	redis RedisClientRaw
+
+	mu   sync.Mutex
+	last *MetricsOverview // 最後に Redis から取得できた値
 }
 
 func NewMetricsService(redis RedisClientRaw) *MetricsService {
 	return &MetricsService{redis: redis}
 }
 
-// Overview はキューと全ワーカーの簡易情報を返す。
-func (s *MetricsService) Overview(ctx context.Context) (QueueMetrics, []WorkerHeartbeat, error) {
+// Overview はキューと全ワーカーの簡易情報を返す。Redis の障害でエラーにはせず、最後の値で代替する。
+func (s *MetricsService) Overview(ctx context.Context) MetricsOverview {
 	queue, err := s.Queue(ctx)
-	if err != nil {
-		return QueueMetrics{}, nil, err
+	var workers []WorkerHeartbeat
+	if err == nil {
+		workers, err = s.Workers(ctx)
+	}
+	var orphans *OrphanFileReport
+	if err == nil {
+		orphans, err = LoadOrphanFileReport(ctx, s.redis)
 	}
-	workers, err := s.Workers(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if err != nil {
-		return queue, nil, err
+		out := MetricsOverview{Workers: []WorkerHeartbeat{}}
+		if s.last != nil {
+			out = *s.last
+		}
+		out.Stale, out.RedisError = true, err.Error()
+		return out
 	}
-	return queue, workers, nil
+	if workers == nil {
+		workers = []WorkerHeartbeat{}
+	}
+	now := time.Now()
+	s.last = &MetricsOverview{Queue: queue, Workers: workers, CollectedAt: &now, OrphanFiles: orphans}
+	return *s.last
+}
+
+// Ping は Redis への疎通を確認する。
+func (s *MetricsService) Ping(ctx context.Context) error {
+	return s.redis.Ping(ctx).Err()
 }
 
-// Queue は pending / processing の件数と期限切れ候補数を返す。
+// Queue は pending / processing の件数と期限切れ候補数、一時停止の有無を返す。
 func (s *MetricsService) Queue(ctx context.Context) (QueueMetrics, error) {
 	now := time.Now().UnixMilli()
 	pending, err := s.redis.LLen(ctx, PendingQueueKey).Result()
@@ -51,7 +90,11 @@ func (s *MetricsService) Queue(ctx context.Context) (QueueMetrics, error) {
 	if err != nil {
 		return QueueMetrics{}, err
 	}
-	return QueueMetrics{Pending: pending, Processing: processing, ExpiredCandidate: expired}, nil
+	pause, err := GetQueuePause(ctx, s.redis)
+	if err != nil {
+		return QueueMetrics{}, err
+	}
+	return QueueMetrics{Pending: pending, Processing: processing, ExpiredCandidate: expired, Paused: pause != nil}, nil
 }
 
 // Workers は Redis に残っているハートビートをすべて返す。
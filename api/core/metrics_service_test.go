@@ -0,0 +1,35 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMetricsOverviewFallsBackWhenRedisIsDown(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	defer client.Close()
+	ctx := context.Background()
+	metrics := NewMetricsService(client)
+
+	if _, err := mr.Lpush(PendingQueueKey, "1"); err != nil {
+		t.Fatal(err)
+	}
+	live := metrics.Overview(ctx)
+	if live.Stale || live.Queue.Pending != 1 || live.CollectedAt == nil {
+		t.Fatalf("unexpected live overview: %+v", live)
+	}
+
+	mr.Close()
+	stale := metrics.Overview(ctx)
+	if !stale.Stale || stale.RedisError == "" || stale.Queue.Pending != 1 || !stale.CollectedAt.Equal(*live.CollectedAt) {
+		t.Fatalf("expected last-known values flagged stale, got %+v", stale)
+	}
+	st, err := CollectSystemStatus(ctx, metrics, live.CollectedAt.Add(-1))
+	if err != nil || st.Redis.Status != "down" || !st.MetricsStale || st.Queue.Pending != 1 {
+		t.Fatalf("unexpected status: %+v %v", st, err)
+	}
+}
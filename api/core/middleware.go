@@ -15,8 +15,20 @@ const sessionName = "oj_session"
 const sessionMaxAge = 18000 // 5h
 
 // SessionMiddleware ensures a session exists and applies consistent cookie options.
-func SessionMiddleware(cfg Config, store *sessions.CookieStore) gin.HandlerFunc {
+func SessionMiddleware(cfg Config, store *RedisSessionStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// API トークンで認証済み（TokenAuthMiddleware がセッションを用意している）
+		if isTokenAuth(c) {
+			sess, _ := c.MustGet("session").(*sessions.Session)
+			userid, _ := sess.Values["userid"].(string)
+			if rejectSuspendedSession(c, store, userid) {
+				return
+			}
+			c.Next()
+			return
+		}
+		// セッション一覧に表示する接続元（プロキシ越しでも gin が解決したアドレス）
+		c.Request = withSessionClientIP(c.Request, c.ClientIP())
 		session, err := store.Get(c.Request, sessionName)
 		if err != nil {
 			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "session error")
@@ -33,6 +45,11 @@ func SessionMiddleware(cfg Config, store *sessions.CookieStore) gin.HandlerFunc
 		}
 
 		c.Set("session", session)
+		// 利用停止中のアカウントは ACCOUNT_SUSPENDED で拒否する（ログアウトのみ可）
+		userid, _ := session.Values["userid"].(string)
+		if rejectSuspendedSession(c, store, userid) {
+			return
+		}
 		c.Next()
 	}
 }
@@ -79,6 +96,11 @@ func OriginRefererMiddleware(cfg Config) gin.HandlerFunc {
 			return
 		}
 
+		// OAuth のコールバックはプロバイダからの遷移なので Referer が外部になる（state パラメータで検証する）
+		if c.Request.Method == http.MethodGet && isOAuthCallbackPath(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
 		if !isAllowed(origin) {
 			respondError(c, http.StatusForbidden, "FORBIDDEN", "origin not allowed")
 			c.Abort()
@@ -95,13 +117,18 @@ func setCORSHeaders(c *gin.Context, origin string) {
 	c.Header("Access-Control-Allow-Origin", origin)
 	c.Header("Vary", "Origin")
 	c.Header("Access-Control-Allow-Credentials", "true")
-	c.Header("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token")
+	c.Header("Access-Control-Allow-Headers", "Content-Type, X-CSRF-Token, Authorization, Idempotency-Key")
 	c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 }
 
 // CSRFMiddleware issues and validates a per-session CSRF token.
-func CSRFMiddleware(cfg Config, store *sessions.CookieStore) gin.HandlerFunc {
+func CSRFMiddleware(cfg Config, store *RedisSessionStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// Bearer トークンはブラウザが自動送信しないので CSRF の対象外
+		if isTokenAuth(c) {
+			c.Next()
+			return
+		}
 		sessionAny, ok := c.Get("session")
 		var session *sessions.Session
 		var err error
@@ -158,10 +185,21 @@ func isSafeMethod(method string) bool {
 	}
 }
 
+// isOAuthCallbackPath reports whether path is the callback of an OAuth provider
+// (/api/v1/auth/oauth/<provider>/callback).
+func isOAuthCallbackPath(path string) bool {
+	rest, ok := strings.CutPrefix(path, "/api/v1/auth/oauth/")
+	if !ok {
+		return false
+	}
+	provider, tail, ok := strings.Cut(rest, "/")
+	return ok && provider != "" && tail == "callback"
+}
+
 // Paths that intentionally skip CSRF validation (e.g., login).
 func csrfExemptPath(path string) bool {
 	switch path {
-	case "/api/v1/auth/login":
+	case "/api/v1/auth/login", "/api/v1/auth/login/totp", "/api/v1/auth/register", "/api/v1/auth/password_reset", "/api/v1/auth/password_reset/confirm", "/api/v1/auth/verify_email", "/api/v1/transcripts/verify":
 		return true
 	default:
 		return false
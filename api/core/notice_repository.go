@@ -22,7 +22,6 @@ type NoticeRepository interface {
 	Get(ctx context.Context, id int64) (*Notice, error)
 	Create(ctx context.Context, title, body string) (*Notice, error)
 	Update(ctx context.Context, id int64, title, body string) (*Notice, error)
-	Delete(ctx context.Context, id int64) error
 }
 
 type PgNoticeRepository struct {
@@ -37,7 +36,7 @@ func (r *PgNoticeRepository) List(ctx context.Context, page, perPage int) ([]Not
 	if page <= 0 || perPage <= 0 {
 		return nil, 0, errors.New("invalid pagination")
 	}
-	const countQ = `SELECT COUNT(*) FROM notices`
+	const countQ = `SELECT COUNT(*) FROM notices WHERE deleted_at IS NULL`
 	var total int
 	if err := r.db.QueryRow(ctx, countQ).Scan(&total); err != nil {
 		return nil, 0, err
@@ -45,6 +44,7 @@ func (r *PgNoticeRepository) List(ctx context.Context, page, perPage int) ([]Not
 	rows, err := r.db.Query(ctx, `
 SELECT id, title, body, created_at, updated_at
 FROM notices
+WHERE deleted_at IS NULL
 ORDER BY updated_at DESC, id DESC
 LIMIT $1 OFFSET $2
 `, perPage, (page-1)*perPage)
@@ -64,7 +64,7 @@ LIMIT $1 OFFSET $2
 }
 
 func (r *PgNoticeRepository) Get(ctx context.Context, id int64) (*Notice, error) {
-	const q = `SELECT id, title, body, created_at, updated_at FROM notices WHERE id=$1`
+	const q = `SELECT id, title, body, created_at, updated_at FROM notices WHERE id=$1 AND deleted_at IS NULL`
 	var n Notice
 	if err := r.db.QueryRow(ctx, q, id).Scan(&n.ID, &n.Title, &n.Body, &n.CreatedAt, &n.UpdatedAt); err != nil {
 		return nil, err
@@ -88,7 +88,7 @@ func (r *PgNoticeRepository) Create(ctx context.Context, title, body string) (*N
 func (r *PgNoticeRepository) Update(ctx context.Context, id int64, title, body string) (*Notice, error) {
 	title = strings.TrimSpace(title)
 	body = strings.TrimSpace(body)
-	const q = `UPDATE notices SET title=$1, body=$2 WHERE id=$3 RETURNING id, created_at, updated_at`
+	const q = `UPDATE notices SET title=$1, body=$2 WHERE id=$3 AND deleted_at IS NULL RETURNING id, created_at, updated_at`
 	var n Notice
 	if err := r.db.QueryRow(ctx, q, title, body, id).Scan(&n.ID, &n.CreatedAt, &n.UpdatedAt); err != nil {
 		return nil, err
@@ -97,9 +97,3 @@ func (r *PgNoticeRepository) Update(ctx context.Context, id int64, title, body s
 	n.Body = body
 	return &n, nil
 }
-
-func (r *PgNoticeRepository) Delete(ctx context.Context, id int64) error {
-	const q = `DELETE FROM notices WHERE id=$1`
-	_, err := r.db.Exec(ctx, q, id)
-	return err
-}
@@ -0,0 +1,191 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrNoticeTemplateExists = errors.New("notice template already exists")
+	ErrNoticeTemplateInput  = errors.New("invalid notice template")
+
+	noticeTemplateName = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+	// {{ 変数名 }}。変数名は英小文字・数字・_ のみ
+	noticeTemplateVar = regexp.MustCompile(`\{\{\s*([a-z_][a-z0-9_]*)\s*\}\}`)
+)
+
+// NoticeTemplate is a reusable notice whose title and body contain {{variable}} placeholders.
+type NoticeTemplate struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	Variables []string  `json:"variables"`
+	CreatedBy *int64    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NoticeTemplateInput is a partial update of a template; every field is required on create.
+type NoticeTemplateInput struct {
+	Name  *string `json:"name"`
+	Title *string `json:"title"`
+	Body  *string `json:"body"`
+}
+
+// noticeTemplateVariables returns the distinct placeholder names of the texts, sorted.
+func noticeTemplateVariables(texts ...string) []string {
+	seen := map[string]bool{}
+	vars := []string{}
+	for _, t := range texts {
+		for _, m := range noticeTemplateVar.FindAllStringSubmatch(t, -1) {
+			if !seen[m[1]] {
+				seen[m[1]] = true
+				vars = append(vars, m[1])
+			}
+		}
+	}
+	sort.Strings(vars)
+	return vars
+}
+
+// Render substitutes vars into the template. Every placeholder must have a non-empty value;
+// values are inserted as is (the result is rendered as markdown like any notice).
+func (t *NoticeTemplate) Render(vars map[string]string) (title, body string, err error) {
+	var missing []string
+	for _, name := range noticeTemplateVariables(t.Title, t.Body) {
+		if strings.TrimSpace(vars[name]) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", "", fmt.Errorf("%w: 変数 %s の値がありません", ErrNoticeTemplateInput, strings.Join(missing, ", "))
+	}
+	replace := func(s string) string {
+		return noticeTemplateVar.ReplaceAllStringFunc(s, func(m string) string {
+			return strings.TrimSpace(vars[noticeTemplateVar.FindStringSubmatch(m)[1]])
+		})
+	}
+	return strings.TrimSpace(replace(t.Title)), strings.TrimSpace(replace(t.Body)), nil
+}
+
+func validateNoticeTemplate(in NoticeTemplateInput) error {
+	if in.Name != nil && !noticeTemplateName.MatchString(*in.Name) {
+		return fmt.Errorf("%w: name は英小文字・数字・_・- の 64 文字以内で指定してください", ErrNoticeTemplateInput)
+	}
+	if in.Title != nil && strings.TrimSpace(*in.Title) == "" {
+		return fmt.Errorf("%w: title は必須です", ErrNoticeTemplateInput)
+	}
+	if in.Body != nil && strings.TrimSpace(*in.Body) == "" {
+		return fmt.Errorf("%w: body は必須です", ErrNoticeTemplateInput)
+	}
+	return nil
+}
+
+type NoticeTemplateRepository interface {
+	List(ctx context.Context) ([]NoticeTemplate, error)
+	Get(ctx context.Context, id int64) (*NoticeTemplate, error)
+	Create(ctx context.Context, in NoticeTemplateInput, createdBy int64) (*NoticeTemplate, error)
+	Update(ctx context.Context, id int64, in NoticeTemplateInput) (*NoticeTemplate, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+type PgNoticeTemplateRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPgNoticeTemplateRepository(db *pgxpool.Pool) *PgNoticeTemplateRepository {
+	return &PgNoticeTemplateRepository{db: db}
+}
+
+const noticeTemplateColumns = `id, name, title, body, created_by, created_at, updated_at`
+
+func scanNoticeTemplate(row pgx.Row) (*NoticeTemplate, error) {
+	var t NoticeTemplate
+	if err := row.Scan(&t.ID, &t.Name, &t.Title, &t.Body, &t.CreatedBy, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	t.Variables = noticeTemplateVariables(t.Title, t.Body)
+	return &t, nil
+}
+
+func (r *PgNoticeTemplateRepository) List(ctx context.Context) ([]NoticeTemplate, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+noticeTemplateColumns+` FROM notice_templates ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []NoticeTemplate{}
+	for rows.Next() {
+		t, err := scanNoticeTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *t)
+	}
+	return items, rows.Err()
+}
+
+func (r *PgNoticeTemplateRepository) Get(ctx context.Context, id int64) (*NoticeTemplate, error) {
+	return scanNoticeTemplate(r.db.QueryRow(ctx, `SELECT `+noticeTemplateColumns+` FROM notice_templates WHERE id=$1`, id))
+}
+
+func (r *PgNoticeTemplateRepository) Create(ctx context.Context, in NoticeTemplateInput, createdBy int64) (*NoticeTemplate, error) {
+	if in.Name == nil || in.Title == nil || in.Body == nil {
+		return nil, fmt.Errorf("%w: name・title・body は必須です", ErrNoticeTemplateInput)
+	}
+	if err := validateNoticeTemplate(in); err != nil {
+		return nil, err
+	}
+	t, err := scanNoticeTemplate(r.db.QueryRow(ctx, `
+INSERT INTO notice_templates (name, title, body, created_by) VALUES ($1,$2,$3,$4)
+RETURNING `+noticeTemplateColumns, *in.Name, strings.TrimSpace(*in.Title), strings.TrimSpace(*in.Body), createdBy))
+	return t, noticeTemplateError(err)
+}
+
+// Update changes the given fields. Returns pgx.ErrNoRows when the template does not exist.
+func (r *PgNoticeTemplateRepository) Update(ctx context.Context, id int64, in NoticeTemplateInput) (*NoticeTemplate, error) {
+	if err := validateNoticeTemplate(in); err != nil {
+		return nil, err
+	}
+	trim := func(s *string) *string {
+		if s == nil {
+			return nil
+		}
+		v := strings.TrimSpace(*s)
+		return &v
+	}
+	t, err := scanNoticeTemplate(r.db.QueryRow(ctx, `
+UPDATE notice_templates SET name=COALESCE($2, name), title=COALESCE($3, title), body=COALESCE($4, body)
+WHERE id=$1
+RETURNING `+noticeTemplateColumns, id, in.Name, trim(in.Title), trim(in.Body)))
+	return t, noticeTemplateError(err)
+}
+
+func (r *PgNoticeTemplateRepository) Delete(ctx context.Context, id int64) error {
+	ct, err := r.db.Exec(ctx, `DELETE FROM notice_templates WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func noticeTemplateError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrNoticeTemplateExists
+	}
+	return err
+}
@@ -0,0 +1,28 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNoticeTemplateRender(t *testing.T) {
+	tmpl := &NoticeTemplate{
+		Title: "{{contest}} を開始しました",
+		Body:  "{{ contest }} は {{end_at}} に終了します。{{notes}}",
+	}
+	if got := noticeTemplateVariables(tmpl.Title, tmpl.Body); !reflect.DeepEqual(got, []string{"contest", "end_at", "notes"}) {
+		t.Fatalf("variables: %v", got)
+	}
+	if _, _, err := tmpl.Render(map[string]string{"contest": "ABC 1"}); !errors.Is(err, ErrNoticeTemplateInput) {
+		t.Fatalf("expected missing variables to be rejected, got %v", err)
+	}
+	title, body, err := tmpl.Render(map[string]string{"contest": "ABC 1", "end_at": "18:00", "notes": "{{contest}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 値の中の {{...}} は置き換えない
+	if title != "ABC 1 を開始しました" || body != "ABC 1 は 18:00 に終了します。{{contest}}" {
+		t.Fatalf("got %q / %q", title, body)
+	}
+}
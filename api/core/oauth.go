@@ -0,0 +1,458 @@
+package core
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	OAuthGitHub = "github"
+	OAuthGoogle = "google"
+
+	oauthStateTTL       = 10 * time.Minute
+	oauthStateKeyPrefix = "oauth:state:"
+	oauthHTTPTimeout    = 10 * time.Second
+	oauthMaxResponse    = 1 << 20
+	oauthUsernameMaxLen = 32
+
+	// oauthNonceCookie ties a state to the browser that started the flow (see oauthState).
+	oauthNonceCookie     = "oj_oauth_nonce"
+	oauthNonceCookiePath = "/api/v1/auth/oauth/"
+)
+
+var (
+	ErrOAuthState         = errors.New("invalid or expired oauth state")
+	ErrOAuthExchange      = errors.New("oauth provider rejected the request")
+	ErrOAuthIdentityTaken = errors.New("identity is linked to another user")
+)
+
+// OAuthIdentity is the external account returned by a provider.
+type OAuthIdentity struct {
+	Subject string // provider-side stable user id
+	Login   string // suggested username (GitHub login, Google email local part)
+	Email   string
+	Role    string // role granted by the OIDC role rules ("" when none applies)
+}
+
+// OAuthProvider is an OAuth2 authorization-code provider with a userinfo endpoint.
+type OAuthProvider struct {
+	Name         string
+	Label        string // shown on the login page
+	Issuer       string // OIDC issuer; the endpoints are discovered from it when set
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	Scope        string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	SignUp       bool // create accounts for unlinked identities even without OAUTH_SIGNUP
+	RoleSync     bool // set the user's role from the identity on every login
+	// parseIdentity maps the userinfo response to an identity.
+	parseIdentity func(body []byte) (*OAuthIdentity, error)
+	mu            sync.Mutex // guards discovery
+}
+
+// OAuthProviders returns the providers enabled by cfg, keyed by name.
+func OAuthProviders(cfg Config) map[string]*OAuthProvider {
+	out := map[string]*OAuthProvider{}
+	if cfg.OAuthPublicURL == "" {
+		return out
+	}
+	callback := func(name string) string {
+		return cfg.OAuthPublicURL + "/api/v1/auth/oauth/" + name + "/callback"
+	}
+	if cfg.OAuthGitHubClientID != "" && cfg.OAuthGitHubClientSecret != "" {
+		out[OAuthGitHub] = &OAuthProvider{
+			Name:          OAuthGitHub,
+			Label:         "GitHub",
+			AuthURL:       "https://github.com/login/oauth/authorize",
+			TokenURL:      "https://github.com/login/oauth/access_token",
+			UserInfoURL:   "https://api.github.com/user",
+			Scope:         "read:user user:email",
+			ClientID:      cfg.OAuthGitHubClientID,
+			ClientSecret:  cfg.OAuthGitHubClientSecret,
+			RedirectURL:   callback(OAuthGitHub),
+			parseIdentity: parseGitHubIdentity,
+		}
+	}
+	if cfg.OAuthGoogleClientID != "" && cfg.OAuthGoogleClientSecret != "" {
+		out[OAuthGoogle] = &OAuthProvider{
+			Name:          OAuthGoogle,
+			Label:         "Google",
+			AuthURL:       "https://accounts.google.com/o/oauth2/v2/auth",
+			TokenURL:      "https://oauth2.googleapis.com/token",
+			UserInfoURL:   "https://openidconnect.googleapis.com/v1/userinfo",
+			Scope:         "openid email profile",
+			ClientID:      cfg.OAuthGoogleClientID,
+			ClientSecret:  cfg.OAuthGoogleClientSecret,
+			RedirectURL:   callback(OAuthGoogle),
+			parseIdentity: parseGoogleIdentity,
+		}
+	}
+	if cfg.OIDCIssuer != "" && cfg.OIDCClientID != "" && cfg.OIDCClientSecret != "" {
+		// 不正なルールは起動時に検出する（cmd/api）
+		rules, _ := ParseOIDCRoleRules(cfg.OIDCRoleRules)
+		name := oidcProviderName(cfg)
+		out[name] = newOIDCProvider(cfg, callback(name), rules)
+	}
+	return out
+}
+
+func parseGitHubIdentity(body []byte) (*OAuthIdentity, error) {
+	var v struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return nil, err
+	}
+	if v.ID == 0 {
+		return nil, fmt.Errorf("%w: missing user id", ErrOAuthExchange)
+	}
+	return &OAuthIdentity{Subject: strconv.FormatInt(v.ID, 10), Login: v.Login, Email: v.Email}, nil
+}
+
+func parseGoogleIdentity(body []byte) (*OAuthIdentity, error) {
+	var v struct {
+		Sub           string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+	}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return nil, err
+	}
+	if v.Sub == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrOAuthExchange)
+	}
+	id := &OAuthIdentity{Subject: v.Sub}
+	// 未確認のメールアドレスは記録しない
+	if v.EmailVerified {
+		id.Email = v.Email
+		id.Login, _, _ = strings.Cut(v.Email, "@")
+	}
+	return id, nil
+}
+
+// AuthCodeURL is where the browser is sent to start the login.
+func (p *OAuthProvider) AuthCodeURL(state string) string {
+	v := url.Values{
+		"client_id":     {p.ClientID},
+		"redirect_uri":  {p.RedirectURL},
+		"response_type": {"code"},
+		"scope":         {p.Scope},
+		"state":         {state},
+	}
+	return p.AuthURL + "?" + v.Encode()
+}
+
+// Exchange trades an authorization code for an access token and fetches the identity with it.
+func (p *OAuthProvider) Exchange(ctx context.Context, client *http.Client, code string) (*OAuthIdentity, error) {
+	if err := p.discover(ctx, client); err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.RedirectURL},
+		"client_id":     {p.ClientID},
+		"client_secret": {p.ClientSecret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	body, err := doOAuthRequest(client, req)
+	if err != nil {
+		return nil, err
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &tok); err != nil {
+		return nil, err
+	}
+	// GitHub は失敗も 200 で返す
+	if tok.AccessToken == "" {
+		return nil, fmt.Errorf("%w: token endpoint returned %q", ErrOAuthExchange, tok.Error)
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, p.UserInfoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+tok.AccessToken)
+	req.Header.Set("Accept", "application/json")
+	body, err = doOAuthRequest(client, req)
+	if err != nil {
+		return nil, err
+	}
+	return p.parseIdentity(body)
+}
+
+func doOAuthRequest(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, oauthMaxResponse))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%w: %s returned %d", ErrOAuthExchange, req.URL.Host, resp.StatusCode)
+	}
+	return body, nil
+}
+
+// oauthState is what the state parameter stands for. It lives in Redis rather than the session
+// because SameSite=Strict cookies are not sent on the provider's redirect back to us. Its nonce is
+// also kept in a SameSite=Lax cookie of the browser that started the flow, so that nobody else can
+// complete an authorize URL someone started (and, for a link, bind their identity to that account).
+type oauthState struct {
+	Provider string `json:"provider"`
+	// LinkUserID is set when a logged-in user links an identity instead of logging in.
+	LinkUserID int64  `json:"link_user_id,omitempty"`
+	Nonce      string `json:"nonce"`
+}
+
+// saveOAuthState stores st under a new state with a new nonce, returning both.
+func saveOAuthState(ctx context.Context, rdb *redis.Client, st oauthState) (string, string, error) {
+	st.Nonce = randomHex(16)
+	raw, err := json.Marshal(st)
+	if err != nil {
+		return "", "", err
+	}
+	state := randomHex(24)
+	if err := rdb.Set(ctx, oauthStateKeyPrefix+state, raw, oauthStateTTL).Err(); err != nil {
+		return "", "", err
+	}
+	return state, st.Nonce, nil
+}
+
+// takeOAuthState consumes a state so that a callback URL cannot be replayed. nonce is the value of
+// the nonce cookie of the callback request and must match the one of the state.
+func takeOAuthState(ctx context.Context, rdb *redis.Client, state, nonce string) (*oauthState, error) {
+	if state == "" {
+		return nil, ErrOAuthState
+	}
+	raw, err := rdb.GetDel(ctx, oauthStateKeyPrefix+state).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrOAuthState
+		}
+		return nil, err
+	}
+	var st oauthState
+	if err := json.Unmarshal(raw, &st); err != nil {
+		return nil, ErrOAuthState
+	}
+	if st.Nonce == "" || subtle.ConstantTimeCompare([]byte(st.Nonce), []byte(nonce)) != 1 {
+		return nil, ErrOAuthState
+	}
+	return &st, nil
+}
+
+// setOAuthNonceCookie keeps nonce in the browser for the callback; an empty nonce clears it. The
+// cookie is Lax (not the session's Strict) so that it is sent on the provider's redirect back.
+func setOAuthNonceCookie(w http.ResponseWriter, cfg Config, nonce string) {
+	maxAge := int(oauthStateTTL / time.Second)
+	if nonce == "" {
+		maxAge = -1
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthNonceCookie,
+		Value:    nonce,
+		Path:     oauthNonceCookiePath,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   cfg.CookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+var oauthUsernameInvalid = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// oauthUsernameBase derives a username for a new account from the identity.
+func oauthUsernameBase(provider string, id OAuthIdentity) string {
+	name := oauthUsernameInvalid.ReplaceAllString(id.Login, "")
+	name = strings.Trim(name, ".-")
+	if len(name) > oauthUsernameMaxLen {
+		name = name[:oauthUsernameMaxLen]
+	}
+	if name == "" {
+		name = provider + "-user"
+	}
+	return name
+}
+
+// UserIdentity is an external account linked to a local user.
+type UserIdentity struct {
+	Provider  string    `json:"provider"`
+	Email     *string   `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserIdentityRepository links OAuth identities to local users.
+type UserIdentityRepository interface {
+	// FindUser returns the user linked to the identity, pgx.ErrNoRows, ErrAccountDisabled or
+	// ErrAccountSuspended.
+	FindUser(ctx context.Context, provider, subject string) (*UserRecord, error)
+	// Link attaches the identity to userID, replacing the user's previous identity of that provider.
+	// It returns ErrOAuthIdentityTaken when the identity belongs to someone else.
+	Link(ctx context.Context, userID int64, provider string, id OAuthIdentity) error
+	Unlink(ctx context.Context, userID int64, provider string) error
+	List(ctx context.Context, userID int64) ([]UserIdentity, error)
+	// SignUp creates a user named after the identity (suffixed when taken) and links the identity.
+	// The user gets id.Role, or the user role when it is empty.
+	SignUp(ctx context.Context, provider string, id OAuthIdentity, passwordHash string) (*UserRecord, error)
+	SetRole(ctx context.Context, userID int64, role string) error
+}
+
+type PgUserIdentityRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPgUserIdentityRepository(db *pgxpool.Pool) *PgUserIdentityRepository {
+	return &PgUserIdentityRepository{db: db}
+}
+
+func (r *PgUserIdentityRepository) FindUser(ctx context.Context, provider, subject string) (*UserRecord, error) {
+	var u UserRecord
+	var disabled bool
+	err := r.db.QueryRow(ctx, `SELECT u.id, u.username, u.password_hash, u.role, u.timezone, u.locale, u.created_at, u.disabled_at IS NOT NULL, NOT u.is_active
+FROM user_identities i
+JOIN users u ON u.id = i.user_id
+WHERE i.provider=$1 AND i.subject=$2 AND u.deleted_at IS NULL`, provider, subject).
+		Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Role, &u.Timezone, &u.Locale, &u.CreatedAt, &disabled, &u.Suspended)
+	if err != nil {
+		return nil, err
+	}
+	if disabled {
+		return nil, ErrAccountDisabled
+	}
+	if u.Suspended {
+		return nil, ErrAccountSuspended
+	}
+	return &u, nil
+}
+
+func (r *PgUserIdentityRepository) Link(ctx context.Context, userID int64, provider string, id OAuthIdentity) error {
+	return linkIdentity(ctx, r.db, userID, provider, id)
+}
+
+func linkIdentity(ctx context.Context, q pgQuerier, userID int64, provider string, id OAuthIdentity) error {
+	var email *string
+	if id.Email != "" {
+		email = &id.Email
+	}
+	_, err := q.Exec(ctx, `INSERT INTO user_identities (user_id, provider, subject, email) VALUES ($1,$2,$3,$4)
+ON CONFLICT (user_id, provider) DO UPDATE SET subject=EXCLUDED.subject, email=EXCLUDED.email, created_at=NOW()`,
+		userID, provider, id.Subject, email)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		// (provider, subject) が別ユーザーに紐付いている
+		return ErrOAuthIdentityTaken
+	}
+	return err
+}
+
+func (r *PgUserIdentityRepository) Unlink(ctx context.Context, userID int64, provider string) error {
+	ct, err := r.db.Exec(ctx, `DELETE FROM user_identities WHERE user_id=$1 AND provider=$2`, userID, provider)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PgUserIdentityRepository) List(ctx context.Context, userID int64) ([]UserIdentity, error) {
+	rows, err := r.db.Query(ctx, `SELECT provider, email, created_at FROM user_identities WHERE user_id=$1 ORDER BY provider`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []UserIdentity{}
+	for rows.Next() {
+		var v UserIdentity
+		if err := rows.Scan(&v.Provider, &v.Email, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+	}
+	return items, rows.Err()
+}
+
+func (r *PgUserIdentityRepository) SignUp(ctx context.Context, provider string, id OAuthIdentity, passwordHash string) (*UserRecord, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// ユーザー名の一意制約は削除済みユーザーも含むので、空きを探してから作る
+	base := oauthUsernameBase(provider, id)
+	username := ""
+	for i := 1; i <= 20 && username == ""; i++ {
+		candidate := base
+		if i > 1 {
+			candidate = base + "-" + strconv.Itoa(i)
+		}
+		var taken bool
+		if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE username=$1)`, candidate).Scan(&taken); err != nil {
+			return nil, err
+		}
+		if !taken {
+			username = candidate
+		}
+	}
+	if username == "" {
+		username = base + "-" + randomHex(3)
+	}
+
+	role := RoleUser
+	if ValidRole(id.Role) {
+		role = id.Role
+	}
+	var u UserRecord
+	if err := tx.QueryRow(ctx, `INSERT INTO users (username, password_hash, role) VALUES ($1,$2,$3)
+RETURNING id, username, password_hash, role, timezone, locale, created_at`, username, passwordHash, role).
+		Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Role, &u.Timezone, &u.Locale, &u.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := linkIdentity(ctx, tx, u.ID, provider, id); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *PgUserIdentityRepository) SetRole(ctx context.Context, userID int64, role string) error {
+	_, err := r.db.Exec(ctx, `UPDATE users SET role=$2 WHERE id=$1 AND deleted_at IS NULL`, userID, role)
+	return err
+}
@@ -0,0 +1,161 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestOAuthProviderExchange(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/token":
+			if err := r.ParseForm(); err != nil {
+				t.Fatal(err)
+			}
+			if r.PostForm.Get("code") != "good" || r.PostForm.Get("client_secret") != "secret" {
+				// GitHub と同じく失敗も 200 で返す
+				_, _ = w.Write([]byte(`{"error":"bad_verification_code"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"access_token":"at-1","token_type":"bearer"}`))
+		case "/user":
+			if r.Header.Get("Authorization") != "Bearer at-1" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":4242,"login":"octo cat!","email":"octo@example.com"}`))
+		}
+	}))
+	defer srv.Close()
+
+	p := &OAuthProvider{
+		Name:          OAuthGitHub,
+		TokenURL:      srv.URL + "/token",
+		UserInfoURL:   srv.URL + "/user",
+		ClientID:      "id",
+		ClientSecret:  "secret",
+		RedirectURL:   "https://oj.example.com/api/v1/auth/oauth/github/callback",
+		parseIdentity: parseGitHubIdentity,
+	}
+	id, err := p.Exchange(context.Background(), srv.Client(), "good")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Subject != "4242" || id.Email != "octo@example.com" {
+		t.Fatalf("got %+v", id)
+	}
+	if got := oauthUsernameBase(OAuthGitHub, *id); got != "octocat" {
+		t.Fatalf("username base = %q", got)
+	}
+	if _, err := p.Exchange(context.Background(), srv.Client(), "bad"); !errors.Is(err, ErrOAuthExchange) {
+		t.Fatalf("expected ErrOAuthExchange, got %v", err)
+	}
+}
+
+func TestOAuthProvidersAndCallbackPath(t *testing.T) {
+	cfg := Config{OAuthGitHubClientID: "id", OAuthGitHubClientSecret: "secret", OAuthGoogleClientID: "only-id"}
+	if len(OAuthProviders(cfg)) != 0 {
+		t.Fatal("providers need OAUTH_PUBLIC_URL")
+	}
+	cfg.OAuthPublicURL = "https://oj.example.com"
+	providers := OAuthProviders(cfg)
+	if len(providers) != 1 || providers[OAuthGitHub] == nil {
+		t.Fatalf("got %v", providers)
+	}
+	if providers[OAuthGitHub].RedirectURL != "https://oj.example.com/api/v1/auth/oauth/github/callback" {
+		t.Fatalf("redirect = %s", providers[OAuthGitHub].RedirectURL)
+	}
+	if !isOAuthCallbackPath("/api/v1/auth/oauth/github/callback") || isOAuthCallbackPath("/api/v1/auth/oauth/github/start") || isOAuthCallbackPath("/api/v1/auth/oauth//callback") {
+		t.Fatal("callback path detection is wrong")
+	}
+}
+
+func TestOIDCProviderDiscoveryAndRoles(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			_, _ = w.Write([]byte(`{"issuer":"` + srv.URL + `","authorization_endpoint":"` + srv.URL + `/authorize","token_endpoint":"` + srv.URL + `/token","userinfo_endpoint":"` + srv.URL + `/userinfo"}`))
+		case "/token":
+			_, _ = w.Write([]byte(`{"access_token":"at-1","token_type":"Bearer"}`))
+		case "/userinfo":
+			_, _ = w.Write([]byte(`{"sub":"s-1","preferred_username":"taro@univ.example.ac.jp","email":"taro@univ.example.ac.jp","email_verified":true,"groups":["students","oj-ta"]}`))
+		}
+	}))
+	defer srv.Close()
+
+	rules, err := ParseOIDCRoleRules("oj-admins=admin, oj-ta=ta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{
+		OAuthPublicURL: "https://oj.example.com", OIDCIssuer: srv.URL + "/", OIDCClientID: "id", OIDCClientSecret: "secret",
+		OIDCProviderName: "Univ", OIDCRoleRules: "oj-admins=admin, oj-ta=ta", OIDCAutoProvision: true,
+	}
+	p := OAuthProviders(cfg)["univ"]
+	if p == nil || p.RedirectURL != "https://oj.example.com/api/v1/auth/oauth/univ/callback" || !p.SignUp {
+		t.Fatalf("got %+v", p)
+	}
+	if err := p.discover(context.Background(), srv.Client()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(p.AuthCodeURL("st"), srv.URL+"/authorize?") {
+		t.Fatalf("auth url = %s", p.AuthCodeURL("st"))
+	}
+	id, err := p.Exchange(context.Background(), srv.Client(), "code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Subject != "s-1" || id.Login != "taro" || id.Email != "taro@univ.example.ac.jp" || id.Role != RoleTA {
+		t.Fatalf("got %+v", id)
+	}
+	if got := oidcRole("oj-admins", rules); got != RoleAdmin {
+		t.Fatalf("string claim role = %q", got)
+	}
+	if got := oidcRole([]any{"students"}, rules); got != "" {
+		t.Fatalf("unmatched role = %q", got)
+	}
+
+	for _, bad := range []string{"oj-admins", "=admin", "oj-admins=root"} {
+		if _, err := ParseOIDCRoleRules(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+	if oidcProviderName(Config{OIDCProviderName: "github"}) != defaultOIDCProviderName {
+		t.Fatal("built-in provider name must not be reused")
+	}
+}
+
+func TestOAuthStateRequiresNonce(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	ctx := context.Background()
+
+	state, nonce, err := saveOAuthState(ctx, rdb, oauthState{Provider: OAuthGitHub, LinkUserID: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 別のブラウザ（nonce の Cookie がない）からの完了は拒否し、その state は使えなくなる
+	if _, err := takeOAuthState(ctx, rdb, state, ""); !errors.Is(err, ErrOAuthState) {
+		t.Fatalf("without nonce: %v", err)
+	}
+	if _, err := takeOAuthState(ctx, rdb, state, nonce); !errors.Is(err, ErrOAuthState) {
+		t.Fatalf("state must be consumed: %v", err)
+	}
+
+	state, nonce, _ = saveOAuthState(ctx, rdb, oauthState{Provider: OAuthGitHub, LinkUserID: 7})
+	st, err := takeOAuthState(ctx, rdb, state, nonce)
+	if err != nil || st.LinkUserID != 7 {
+		t.Fatalf("matching nonce: %+v %v", st, err)
+	}
+}
@@ -0,0 +1,153 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const defaultOIDCProviderName = "oidc"
+
+// oidcProviderNamePattern keeps the name usable in URLs and in user_identities.provider (VARCHAR(16)).
+var oidcProviderNamePattern = regexp.MustCompile(`^[a-z0-9_-]{1,16}$`)
+
+// OIDCRoleRule assigns Role to identities whose role claim contains Value.
+type OIDCRoleRule struct {
+	Value string
+	Role  string
+}
+
+// ParseOIDCRoleRules parses OIDC_ROLE_RULES: "claim-value=role" pairs, comma-separated, in priority order.
+func ParseOIDCRoleRules(raw string) ([]OIDCRoleRule, error) {
+	var rules []OIDCRoleRule
+	for _, part := range parseCSV(raw) {
+		value, role, ok := strings.Cut(part, "=")
+		value, role = strings.TrimSpace(value), strings.TrimSpace(role)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid OIDC_ROLE_RULES entry %q (want value=role)", part)
+		}
+		if !ValidRole(role) {
+			return nil, fmt.Errorf("OIDC_ROLE_RULES entry %q: unknown role %q", part, role)
+		}
+		rules = append(rules, OIDCRoleRule{Value: value, Role: role})
+	}
+	return rules, nil
+}
+
+// oidcProviderName returns the configured provider name, or the default when it is unusable.
+func oidcProviderName(cfg Config) string {
+	name := strings.ToLower(strings.TrimSpace(cfg.OIDCProviderName))
+	if !oidcProviderNamePattern.MatchString(name) || name == OAuthGitHub || name == OAuthGoogle {
+		return defaultOIDCProviderName
+	}
+	return name
+}
+
+// newOIDCProvider builds the provider for OIDC_ISSUER. Its endpoints are read from the issuer's
+// discovery document on first use (see OAuthProvider.discover).
+func newOIDCProvider(cfg Config, redirectURL string, rules []OIDCRoleRule) *OAuthProvider {
+	usernameClaim := firstNonEmpty(cfg.OIDCUsernameClaim, "preferred_username")
+	roleClaim := firstNonEmpty(cfg.OIDCRoleClaim, "groups")
+	return &OAuthProvider{
+		Name:         oidcProviderName(cfg),
+		Label:        firstNonEmpty(cfg.OIDCDisplayName, "SSO"),
+		Issuer:       strings.TrimRight(cfg.OIDCIssuer, "/"),
+		Scope:        firstNonEmpty(cfg.OIDCScopes, "openid email profile"),
+		ClientID:     cfg.OIDCClientID,
+		ClientSecret: cfg.OIDCClientSecret,
+		RedirectURL:  redirectURL,
+		SignUp:       cfg.OIDCAutoProvision,
+		RoleSync:     cfg.OIDCRoleSync,
+		parseIdentity: func(body []byte) (*OAuthIdentity, error) {
+			return parseOIDCIdentity(body, usernameClaim, roleClaim, rules)
+		},
+	}
+}
+
+func parseOIDCIdentity(body []byte, usernameClaim, roleClaim string, rules []OIDCRoleRule) (*OAuthIdentity, error) {
+	var claims map[string]any
+	if err := json.Unmarshal(body, &claims); err != nil {
+		return nil, err
+	}
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrOAuthExchange)
+	}
+	id := &OAuthIdentity{Subject: sub}
+	// Google と同じく、確認済みと明示されたメールアドレスだけを記録する
+	if verified, _ := claims["email_verified"].(bool); verified {
+		id.Email, _ = claims["email"].(string)
+	}
+	id.Login, _ = claims[usernameClaim].(string)
+	if id.Login == "" && id.Email != "" {
+		id.Login, _, _ = strings.Cut(id.Email, "@")
+	}
+	// Azure AD の UPN のようにアドレス形式のものはローカル部だけを使う
+	id.Login, _, _ = strings.Cut(id.Login, "@")
+	id.Role = oidcRole(claims[roleClaim], rules)
+	return id, nil
+}
+
+// oidcRole returns the role of the first rule whose value appears in the claim (a string or a list
+// of strings), or "" when none matches.
+func oidcRole(claim any, rules []OIDCRoleRule) string {
+	values := map[string]bool{}
+	switch v := claim.(type) {
+	case string:
+		values[v] = true
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values[s] = true
+			}
+		}
+	}
+	for _, r := range rules {
+		if values[r.Value] {
+			return r.Role
+		}
+	}
+	return ""
+}
+
+// discover fills the endpoints from the issuer's discovery document. It is a no-op for providers
+// with fixed endpoints and after the first success.
+func (p *OAuthProvider) discover(ctx context.Context, client *http.Client) error {
+	if p.Issuer == "" {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.TokenURL != "" {
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	body, err := doOAuthRequest(client, req)
+	if err != nil {
+		return err
+	}
+	var doc struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return err
+	}
+	if strings.TrimRight(doc.Issuer, "/") != p.Issuer {
+		return fmt.Errorf("%w: discovery document is for issuer %q", ErrOAuthExchange, doc.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.UserInfoEndpoint == "" {
+		return fmt.Errorf("%w: discovery document lacks endpoints", ErrOAuthExchange)
+	}
+	p.AuthURL, p.TokenURL, p.UserInfoURL = doc.AuthorizationEndpoint, doc.TokenEndpoint, doc.UserInfoEndpoint
+	return nil
+}
@@ -0,0 +1,141 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// OrphanFilesReportKey holds the last OrphanFileReport as JSON (shown in /admin/metrics/overview).
+	OrphanFilesReportKey = "metrics:orphan_files"
+	// orphanDirMinAge keeps directories of submissions that are still being created: the row is
+	// inserted before the directory, but a young directory is never worth the risk.
+	orphanDirMinAge   = time.Hour
+	orphanLookupBatch = 1000
+)
+
+// OrphanFileReport is the outcome of the last orphan file collection. Total* accumulate over runs.
+type OrphanFileReport struct {
+	RunAt               time.Time `json:"run_at"`
+	ScannedDirs         int       `json:"scanned_dirs"`
+	RemovedDirs         int       `json:"removed_dirs"`
+	ReclaimedBytes      int64     `json:"reclaimed_bytes"`
+	TotalRemovedDirs    int64     `json:"total_removed_dirs"`
+	TotalReclaimedBytes int64     `json:"total_reclaimed_bytes"`
+	Error               string    `json:"error,omitempty"`
+}
+
+// CollectOrphanSubmissionDirs removes directories under root named after a submission ID that has
+// no row any more (left by failed submission creation or by deletions that could not remove the
+// files). Directories modified within minAge and names that are not IDs are left alone.
+func CollectOrphanSubmissionDirs(ctx context.Context, root string, subRepo SubmissionRepository, minAge time.Duration) (*OrphanFileReport, error) {
+	report := &OrphanFileReport{RunAt: time.Now().UTC()}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return report, nil
+		}
+		return nil, err
+	}
+	cutoff := time.Now().Add(-minAge)
+	var ids []int64
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		id, err := strconv.ParseInt(e.Name(), 10, 64)
+		if err != nil || id <= 0 || strconv.FormatInt(id, 10) != e.Name() {
+			continue
+		}
+		report.ScannedDirs++
+		if info, err := e.Info(); err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	for start := 0; start < len(ids); start += orphanLookupBatch {
+		batch := ids[start:min(start+orphanLookupBatch, len(ids))]
+		existing, err := subRepo.Statuses(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range batch {
+			if _, ok := existing[id]; ok {
+				continue
+			}
+			dir := filepath.Join(root, strconv.FormatInt(id, 10))
+			size, _ := dirUsage(dir)
+			if err := os.RemoveAll(dir); err != nil {
+				log.Printf("[orphan] remove %s failed: %v", dir, err)
+				continue
+			}
+			report.RemovedDirs++
+			report.ReclaimedBytes += size
+		}
+	}
+	return report, nil
+}
+
+// saveOrphanFileReport stores report in Redis, adding the totals of the previous one.
+func saveOrphanFileReport(ctx context.Context, client *redis.Client, report *OrphanFileReport) error {
+	if prev, err := LoadOrphanFileReport(ctx, client); err == nil && prev != nil {
+		report.TotalRemovedDirs = prev.TotalRemovedDirs
+		report.TotalReclaimedBytes = prev.TotalReclaimedBytes
+	}
+	report.TotalRemovedDirs += int64(report.RemovedDirs)
+	report.TotalReclaimedBytes += report.ReclaimedBytes
+	b, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	return client.Set(ctx, OrphanFilesReportKey, b, 0).Err()
+}
+
+// LoadOrphanFileReport returns the last stored report, nil when the collector has not run yet.
+func LoadOrphanFileReport(ctx context.Context, client RedisClientRaw) (*OrphanFileReport, error) {
+	val, err := client.Get(ctx, OrphanFilesReportKey).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var report OrphanFileReport
+	if err := json.Unmarshal([]byte(val), &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// RunOrphanFileCollector collects orphaned submission directories every interval until ctx is cancelled.
+func RunOrphanFileCollector(ctx context.Context, root string, subRepo SubmissionRepository, client *redis.Client, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		report, err := CollectOrphanSubmissionDirs(ctx, root, subRepo, orphanDirMinAge)
+		if err != nil {
+			log.Printf("[orphan] collection failed: %v", err)
+			report = &OrphanFileReport{RunAt: time.Now().UTC(), Error: err.Error()}
+		} else if report.RemovedDirs > 0 {
+			log.Printf("[orphan] removed %d directories, reclaimed %d bytes", report.RemovedDirs, report.ReclaimedBytes)
+		}
+		if err := saveOrphanFileReport(ctx, client, report); err != nil {
+			log.Printf("[orphan] save report failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestCollectOrphanSubmissionDirs(t *testing.T) {
+	root := t.TempDir()
+	old := time.Now().Add(-2 * time.Hour)
+	for _, name := range []string{"1", "2", "3", "uploads"} {
+		dir := filepath.Join(root, name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "source"), []byte("int main(){}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if name != "3" {
+			if err := os.Chtimes(dir, old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// 1 は行があり、2 は孤立、3 は孤立だが新しすぎる、uploads は提出 ID ではない
+	repo := stubStatusRepo{statuses: map[int64]string{1: "succeeded"}}
+	ctx := context.Background()
+	report, err := CollectOrphanSubmissionDirs(ctx, root, repo, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.ScannedDirs != 3 || report.RemovedDirs != 1 || report.ReclaimedBytes != 12 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	for name, want := range map[string]bool{"1": true, "2": false, "3": true, "uploads": true} {
+		if _, err := os.Stat(filepath.Join(root, name)); (err == nil) != want {
+			t.Fatalf("%s: exists=%v, want %v", name, err == nil, want)
+		}
+	}
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	for i := 0; i < 2; i++ {
+		if err := saveOrphanFileReport(ctx, client, &OrphanFileReport{RemovedDirs: 1, ReclaimedBytes: 12}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := NewMetricsService(client).Overview(ctx).OrphanFiles; got == nil || got.TotalRemovedDirs != 2 || got.TotalReclaimedBytes != 24 {
+		t.Fatalf("unexpected metrics report: %+v", got)
+	}
+}
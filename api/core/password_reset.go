@@ -0,0 +1,132 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	passwordResetTTL      = time.Hour
+	passwordResetInterval = time.Minute // 同じユーザーへの再送間隔
+)
+
+var (
+	ErrPasswordResetToken = errors.New("invalid or expired reset token")
+)
+
+// passwordResetEnabled reports whether reset links can be sent: password login is on, mail is
+// configured and the public URL for the link is known.
+func passwordResetEnabled(cfg Config) bool {
+	return !cfg.PasswordLoginDisabled && mailConfigured(cfg) && cfg.OAuthPublicURL != ""
+}
+
+// hashResetToken is what is stored for a reset token; the token itself only appears in the mail.
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func passwordResetMail(publicURL string, user UserRecord, token string, expiresAt time.Time) (string, string) {
+	link := publicURL + "/password_reset?token=" + token
+	locale := user.PreferredLocale()
+	expires := formatLocalTime(expiresAt, user.Location(), locale)
+	minutes := int(passwordResetTTL / time.Minute)
+	if locale == "en" {
+		body := fmt.Sprintf("Hello %s,\n\n"+
+			"We received a request to reset your password. Set a new password within %d minutes (until %s) using the link below.\n\n"+
+			"%s\n\n"+
+			"If you did not request this, please ignore this email. Your password will not be changed.\n",
+			user.Username, minutes, expires, link)
+		return "Reset your password", body
+	}
+	body := fmt.Sprintf("%s さん\n\n"+
+		"パスワード再設定の申請を受け付けました。次のリンクから %d 分以内（%s まで）に新しいパスワードを設定してください。\n\n"+
+		"%s\n\n"+
+		"心当たりがない場合はこのメールを破棄してください。パスワードは変更されません。\n",
+		user.Username, minutes, expires, link)
+	return "パスワード再設定のご案内", body
+}
+
+// PasswordResetRepository stores single-use reset tokens.
+type PasswordResetRepository interface {
+	// UserByEmail returns the active user with the address (case-insensitive), or pgx.ErrNoRows.
+	UserByEmail(ctx context.Context, email string) (*UserRecord, error)
+	// Create stores a token for userID unless one was issued within passwordResetInterval; it reports
+	// whether the token was stored.
+	Create(ctx context.Context, userID int64, tokenHash string, expiresAt time.Time) (bool, error)
+	// Consume sets the password of the token's user and invalidates all their unused tokens, returning
+	// the username. It returns ErrPasswordResetToken for unknown, used or expired tokens.
+	Consume(ctx context.Context, tokenHash, passwordHash string) (string, error)
+}
+
+type PgPasswordResetRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPgPasswordResetRepository(db *pgxpool.Pool) *PgPasswordResetRepository {
+	return &PgPasswordResetRepository{db: db}
+}
+
+func (r *PgPasswordResetRepository) UserByEmail(ctx context.Context, email string) (*UserRecord, error) {
+	var u UserRecord
+	err := r.db.QueryRow(ctx, `
+SELECT id, username, password_hash, role, timezone, locale, created_at
+FROM users WHERE lower(email)=lower($1) AND deleted_at IS NULL`, email).
+		Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Role, &u.Timezone, &u.Locale, &u.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *PgPasswordResetRepository) Create(ctx context.Context, userID int64, tokenHash string, expiresAt time.Time) (bool, error) {
+	if _, err := r.db.Exec(ctx, `DELETE FROM password_reset_tokens WHERE user_id=$1 AND expires_at < NOW()`, userID); err != nil {
+		return false, err
+	}
+	ct, err := r.db.Exec(ctx, `
+INSERT INTO password_reset_tokens (token_hash, user_id, expires_at)
+SELECT $2, $1, $3
+WHERE NOT EXISTS (
+    SELECT 1 FROM password_reset_tokens WHERE user_id=$1 AND created_at > NOW() - $4::interval
+)`, userID, tokenHash, expiresAt, fmt.Sprintf("%d seconds", int(passwordResetInterval/time.Second)))
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() == 1, nil
+}
+
+func (r *PgPasswordResetRepository) Consume(ctx context.Context, tokenHash, passwordHash string) (string, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var userID int64
+	var username string
+	err = tx.QueryRow(ctx, `
+SELECT t.user_id, u.username FROM password_reset_tokens t
+JOIN users u ON u.id = t.user_id AND u.deleted_at IS NULL
+WHERE t.token_hash=$1 AND t.used_at IS NULL AND t.expires_at > NOW()
+FOR UPDATE OF t`, tokenHash).Scan(&userID, &username)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrPasswordResetToken
+	}
+	if err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE users SET password_hash=$1 WHERE id=$2`, passwordHash, userID); err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE password_reset_tokens SET used_at=NOW() WHERE user_id=$1 AND used_at IS NULL`, userID); err != nil {
+		return "", err
+	}
+	return username, tx.Commit(ctx)
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	if got, err := normalizeEmail("  alice@example.com "); err != nil || got != "alice@example.com" {
+		t.Fatalf("got %q, %v", got, err)
+	}
+	for _, bad := range []string{"", "alice", "Alice <alice@example.com>", "a@example.com\r\nBcc: x@example.com", strings.Repeat("a", 250) + "@example.com"} {
+		if _, err := normalizeEmail(bad); !errors.Is(err, ErrEmailInput) {
+			t.Errorf("%q: expected ErrEmailInput, got %v", bad, err)
+		}
+	}
+}
+
+func TestPasswordResetMail(t *testing.T) {
+	expiresAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	subject, body := passwordResetMail("https://oj.example.com", UserRecord{Username: "alice", Locale: "ja", Timezone: "Asia/Tokyo"}, "abc123", expiresAt)
+	if subject == "" || !strings.Contains(body, "https://oj.example.com/password_reset?token=abc123") || !strings.Contains(body, "60 分") || !strings.Contains(body, "2025/01/02 12:04:05 (JST)") {
+		t.Fatalf("unexpected mail: %q / %q", subject, body)
+	}
+	// 利用者の言語とタイムゾーンで送る
+	if subject, enBody := passwordResetMail("https://oj.example.com", UserRecord{Username: "alice", Locale: "en", Timezone: "America/New_York"}, "abc123", expiresAt); subject != "Reset your password" || !strings.Contains(enBody, "60 minutes (until Jan 1, 2025 22:04:05 EST)") {
+		t.Fatalf("unexpected english mail: %q / %q", subject, enBody)
+	}
+	if h := hashResetToken("abc123"); len(h) != 64 || h == hashResetToken("abc124") {
+		t.Fatalf("unexpected hash %q", h)
+	}
+
+	msg := string(buildMailMessage("oj@example.com", "alice@example.com", subject, body, time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)))
+	head, encoded, ok := strings.Cut(msg, "\r\n\r\n")
+	if !ok {
+		t.Fatalf("no header separator: %q", msg)
+	}
+	for _, want := range []string{"From: oj@example.com\r\n", "To: alice@example.com\r\n", "Subject: =?UTF-8?b?", "Content-Type: text/plain; charset=UTF-8"} {
+		if !strings.Contains(head+"\r\n", want) {
+			t.Errorf("header %q missing in %q", want, head)
+		}
+	}
+	for _, line := range strings.Split(strings.TrimSpace(encoded), "\r\n") {
+		if len(line) > 76 {
+			t.Fatalf("body line too long: %d", len(line))
+		}
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(encoded, "\r\n", ""))
+	if err != nil || string(decoded) != strings.ReplaceAll(body, "\n", "\r\n") {
+		t.Fatalf("body round trip failed: %v", err)
+	}
+}
+
+func TestPasswordResetEnabled(t *testing.T) {
+	cfg := Config{SMTPAddr: "smtp.example.com:587", MailFrom: "oj@example.com", OAuthPublicURL: "https://oj.example.com"}
+	if !passwordResetEnabled(cfg) {
+		t.Fatal("expected enabled")
+	}
+	cfg.PasswordLoginDisabled = true
+	if passwordResetEnabled(cfg) {
+		t.Fatal("expected disabled without password login")
+	}
+	if NewMailer(Config{SMTPAddr: "smtp.example.com:587"}) != nil {
+		t.Fatal("mailer without sender should be nil")
+	}
+}
@@ -0,0 +1,75 @@
+package core
+
+// Permissions attached to admin route groups (see RequirePermission).
+const (
+	PermProblemsWrite       = "problems.write"
+	PermProblemsPublish     = "problems.publish" // 作問者の変更を審査して公開する
+	PermContestsManage      = "contests.manage"
+	PermSubmissionsRead     = "submissions.read"
+	PermSubmissionsGrade    = "submissions.grade"
+	PermNoticesWrite        = "notices.write"
+	PermUsersManage         = "users.manage"
+	PermMetricsRead         = "metrics.read"
+	PermTrashManage         = "trash.manage"
+	PermDiscussionsModerate = "discussions.moderate"
+	PermAuditRead           = "audit.read" // 管理操作の監査ログの閲覧
+)
+
+// Roles stored in users.role.
+const (
+	RoleUser   = "user"
+	RoleAdmin  = "admin"
+	RoleSetter = "setter" // 作問者: 問題・コンテストの管理
+	RoleTA     = "ta"     // TA: 提出の閲覧とコンテスト運営（質問回答・アナウンス）
+)
+
+// rolePermissions is the permission set of each role. admin holds every permission.
+var rolePermissions = map[string][]string{
+	RoleAdmin: {
+		PermProblemsWrite, PermProblemsPublish, PermContestsManage, PermSubmissionsRead, PermSubmissionsGrade,
+		PermNoticesWrite, PermUsersManage, PermMetricsRead, PermTrashManage, PermDiscussionsModerate, PermAuditRead,
+	},
+	RoleSetter: {PermProblemsWrite, PermContestsManage, PermSubmissionsRead, PermSubmissionsGrade, PermDiscussionsModerate},
+	RoleTA:     {PermContestsManage, PermSubmissionsRead, PermDiscussionsModerate},
+	RoleUser:   {},
+}
+
+// ValidRole reports whether role is a known role.
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// RolePermissions returns the permissions granted to role (empty for unknown roles).
+func RolePermissions(role string) []string {
+	perms := rolePermissions[role]
+	out := make([]string, len(perms))
+	copy(out, perms)
+	return out
+}
+
+// HasPermission reports whether role grants perm.
+func HasPermission(role, perm string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// isStaffRole reports whether role grants any permission (i.e. may enter /admin).
+func isStaffRole(role string) bool {
+	return len(rolePermissions[role]) > 0
+}
+
+// roleCovers reports whether every permission of target is also held by actor,
+// so staff cannot create accounts more privileged than themselves.
+func roleCovers(actor, target string) bool {
+	for _, p := range rolePermissions[target] {
+		if !HasPermission(actor, p) {
+			return false
+		}
+	}
+	return true
+}
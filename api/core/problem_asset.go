@@ -0,0 +1,88 @@
+package core
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// assetDir is the package folder holding statement images, referenced as assets/<name> from statement.md.
+const assetDir = "assets/"
+
+// assetContentTypes lists the accepted asset extensions. SVG is excluded because it can carry scripts.
+var assetContentTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// ProblemAsset is a packaged statement image.
+type ProblemAsset struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// collectProblemAssets returns the assets/ entries of a package; other file types are rejected.
+func collectProblemAssets(files map[string][]byte) ([]ProblemAsset, []string) {
+	var assets []ProblemAsset
+	var problems []string
+	for name, data := range files {
+		if !strings.HasPrefix(name, assetDir) {
+			continue
+		}
+		rel := strings.TrimPrefix(name, assetDir)
+		ct, ok := assetContentTypes[strings.ToLower(path.Ext(rel))]
+		if !ok {
+			problems = append(problems, name+": 対応していない形式です (png / jpg / gif / webp)")
+			continue
+		}
+		assets = append(assets, ProblemAsset{Name: rel, ContentType: ct, Data: data})
+	}
+	sort.Slice(assets, func(i, j int) bool { return assets[i].Name < assets[j].Name })
+	sort.Strings(problems)
+	return assets, problems
+}
+
+func insertProblemAssetsTx(ctx context.Context, tx pgx.Tx, problemID int64, assets []ProblemAsset) error {
+	for _, a := range assets {
+		if _, err := tx.Exec(ctx, `INSERT INTO problem_assets (problem_id, name, content_type, data) VALUES ($1,$2,$3,$4)`,
+			problemID, a.Name, a.ContentType, a.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListAssets returns the packaged assets of a problem.
+func (r *PgProblemRepository) ListAssets(ctx context.Context, id int64) ([]ProblemAsset, error) {
+	rows, err := r.db.Query(ctx, `SELECT name, content_type, data FROM problem_assets WHERE problem_id=$1 ORDER BY name`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ProblemAsset{}
+	for rows.Next() {
+		var a ProblemAsset
+		if err := rows.Scan(&a.Name, &a.ContentType, &a.Data); err != nil {
+			return nil, err
+		}
+		items = append(items, a)
+	}
+	return items, rows.Err()
+}
+
+// GetAsset returns one asset; pgx.ErrNoRows when missing.
+func (r *PgProblemRepository) GetAsset(ctx context.Context, id int64, name string) (*ProblemAsset, error) {
+	var a ProblemAsset
+	if err := r.db.QueryRow(ctx, `SELECT name, content_type, data FROM problem_assets WHERE problem_id=$1 AND name=$2`, id, name).
+		Scan(&a.Name, &a.ContentType, &a.Data); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"tuis-oj-prototype/core/checker"
+)
+
+// Checker types. exact / eps are compared in-process; custom runs the problem's checker.cpp.
+const (
+	CheckerTypeExact  = checker.TypeExact
+	CheckerTypeEps    = checker.TypeEps
+	CheckerTypeCustom = "custom"
+)
+
+const (
+	// checkerSourceName is the checker file at the problem package root.
+	checkerSourceName = "checker.cpp"
+	maxCheckerSize    = 256 * 1024
+	// checkerTimeLimitMs / checkerMemoryLimitMb bound both compiling and running the checker.
+	checkerTimeLimitMs   = 10000
+	checkerMemoryLimitMb = 512
+)
+
+// validCheckerType normalizes t and reports whether it is a supported checker type.
+func validCheckerType(t string) (string, bool) {
+	t = strings.ToLower(strings.TrimSpace(t))
+	switch t {
+	case CheckerTypeExact, CheckerTypeEps, CheckerTypeCustom:
+		return t, true
+	}
+	return t, false
+}
+
+// checkerCache keeps compiled checkers in go-judge per problem, keyed by the source hash
+// so an edited checker is recompiled on the next submission.
+type checkerCache struct {
+	mu      sync.Mutex
+	entries map[int64]cachedChecker
+}
+
+type cachedChecker struct {
+	hash   string
+	fileID string
+}
+
+// checkerFor returns the go-judge file ID of the problem's compiled checker, compiling it when needed.
+func (p *WorkerProcessor) checkerFor(ctx context.Context, problemID int64, source string) (string, error) {
+	if strings.TrimSpace(source) == "" {
+		return "", errors.New("custom checker has no source")
+	}
+	sum := sha256.Sum256([]byte(source))
+	hash := hex.EncodeToString(sum[:])
+
+	p.checkers.mu.Lock()
+	defer p.checkers.mu.Unlock()
+	if p.checkers.entries == nil {
+		p.checkers.entries = map[int64]cachedChecker{}
+	}
+	cached, ok := p.checkers.entries[problemID]
+	if ok && cached.hash == hash {
+		return cached.fileID, nil
+	}
+
+	res, _, fileID, err := p.judge.Compile(ctx, "cpp", source, checkerTimeLimitMs, checkerMemoryLimitMb)
+	if err != nil {
+		return "", err
+	}
+	if res.Status != "Accepted" || res.ExitStatus != 0 || fileID == "" {
+		return "", fmt.Errorf("checker compile failed for problem %d: %s %s", problemID, res.Status, strings.TrimSpace(res.Files["stderr"]))
+	}
+	if ok {
+		_ = p.judge.RemoveFiles(ctx, cached.fileID)
+	}
+	p.checkers.entries[problemID] = cachedChecker{hash: hash, fileID: fileID}
+	log.Printf("compiled checker for problem %d", problemID)
+	return fileID, nil
+}
+
+// dropChecker forgets a cached checker, e.g. after go-judge lost the file.
+func (p *WorkerProcessor) dropChecker(problemID int64) {
+	p.checkers.mu.Lock()
+	defer p.checkers.mu.Unlock()
+	delete(p.checkers.entries, problemID)
+}
+
+// runChecker judges one output with the custom checker. Exit status 0 is AC, 1 or 2 (testlib WA/PE) is WA
+// or the custom verdict named by the checker (see checkerVerdict); anything else means the checker itself
+// failed and is returned as an error so the job is retried.
+func (p *WorkerProcessor) runChecker(ctx context.Context, problemID int64, checkerID string, tc testCase, actual string) (string, error) {
+	res, err := p.judge.RunChecker(ctx, checkerID, tc.stdin, tc.expected, actual, checkerTimeLimitMs, checkerMemoryLimitMb)
+	if err != nil {
+		p.dropChecker(problemID)
+		return "", err
+	}
+	if res.Status == "Accepted" || res.Status == "Nonzero Exit Status" {
+		switch res.ExitStatus {
+		case 0:
+			return VerdictAC, nil
+		case 1, 2:
+			return checkerVerdict(res.Files["stdout"]), nil
+		}
+	}
+	if res.Status == "File Error" {
+		p.dropChecker(problemID)
+	}
+	return "", fmt.Errorf("checker failed on testcase %s: status=%s exit=%d %s", tc.name, res.Status, res.ExitStatus, strings.TrimSpace(res.Files["stderr"]))
+}
+
+// checkerVerdict lets a rejecting checker report a registered custom verdict (e.g. QLE) as the first
+// word of its stdout. Anything else is WA.
+func checkerVerdict(stdout string) string {
+	fields := strings.Fields(stdout)
+	if len(fields) == 0 {
+		return VerdictWA
+	}
+	if v, ok := LookupVerdict(strings.ToUpper(fields[0])); ok && v.Kind == VerdictKindRejected {
+		return v.Code
+	}
+	return VerdictWA
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Accepted range of checker_eps for the eps checker (an absolute error). Set it once at startup
+// with SetCheckerEpsRange.
+var (
+	minCheckerEps = 1e-12
+	maxCheckerEps = 1.0
+)
+
+// ErrCheckerEpsInput is returned when checker_eps is outside the accepted range.
+var ErrCheckerEpsInput = errors.New("invalid checker_eps")
+
+// SetCheckerEpsRange sets the accepted range of checker_eps; non-positive values keep the default.
+func SetCheckerEpsRange(min, max float64) {
+	if min > 0 {
+		minCheckerEps = min
+	}
+	if max > 0 {
+		maxCheckerEps = max
+	}
+}
+
+// validateCheckerEps checks the tolerance of an eps checker.
+func validateCheckerEps(eps float64) error {
+	if math.IsNaN(eps) || math.IsInf(eps, 0) || eps <= 0 {
+		return fmt.Errorf("%w: checker_eps must be > 0 when checker_type=eps", ErrCheckerEpsInput)
+	}
+	if eps < minCheckerEps || eps > maxCheckerEps {
+		return fmt.Errorf("%w: checker_eps must be between %g and %g", ErrCheckerEpsInput, minCheckerEps, maxCheckerEps)
+	}
+	return nil
+}
+
+// ProblemCheckerInfo tells contestants how outputs are compared. The source of a custom checker is
+// not shown.
+type ProblemCheckerInfo struct {
+	Type        string   `json:"type"`
+	Eps         *float64 `json:"eps,omitempty"`
+	Description string   `json:"description"`
+}
+
+func publicCheckerInfo(checkerType string, eps float64) ProblemCheckerInfo {
+	switch defaultChecker(checkerType) {
+	case CheckerTypeEps:
+		return ProblemCheckerInfo{
+			Type:        CheckerTypeEps,
+			Eps:         &eps,
+			Description: "空白区切りの値をすべて数値として比べ、絶対誤差 " + strconv.FormatFloat(eps, 'g', -1, 64) + " 以下であれば正解とします（数値でない値は不正解）",
+		}
+	case CheckerTypeCustom:
+		return ProblemCheckerInfo{Type: CheckerTypeCustom, Description: "専用のチェッカーで判定します（問題文の出力の説明を参照してください）"}
+	default:
+		return ProblemCheckerInfo{Type: CheckerTypeExact, Description: "出力が完全に一致すれば正解とします（出力全体の末尾にある空白と改行だけは無視）"}
+	}
+}
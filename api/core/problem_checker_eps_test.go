@@ -0,0 +1,28 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestValidateCheckerEps(t *testing.T) {
+	for _, eps := range []float64{1e-12, 1e-6, 0.5, 1} {
+		if err := validateCheckerEps(eps); err != nil {
+			t.Errorf("eps=%g: %v", eps, err)
+		}
+	}
+	for _, eps := range []float64{0, -1e-6, 1e-13, 1e10, math.NaN(), math.Inf(1)} {
+		if err := validateCheckerEps(eps); !errors.Is(err, ErrCheckerEpsInput) {
+			t.Errorf("eps=%g: err = %v, want ErrCheckerEpsInput", eps, err)
+		}
+	}
+
+	info := publicCheckerInfo(" EPS ", 1e-6)
+	if info.Type != CheckerTypeEps || info.Eps == nil || *info.Eps != 1e-6 {
+		t.Fatalf("unexpected checker info: %+v", info)
+	}
+	if info := publicCheckerInfo(CheckerTypeCustom, 0); info.Type != CheckerTypeCustom || info.Eps != nil {
+		t.Fatalf("unexpected checker info: %+v", info)
+	}
+}
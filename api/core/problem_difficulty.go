@@ -0,0 +1,49 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Problem difficulty range (1 = easiest). Problems without a difficulty have none.
+const (
+	minProblemDifficulty = 1
+	maxProblemDifficulty = 10
+)
+
+// ErrProblemDifficultyInput is returned for a difficulty out of range.
+var ErrProblemDifficultyInput = errors.New("invalid problem difficulty")
+
+// Sort orders of the public problem list (?sort=).
+const (
+	ProblemSortID             = "id"
+	ProblemSortDifficulty     = "difficulty"  // 易しい順
+	ProblemSortDifficultyDesc = "-difficulty" // 難しい順
+)
+
+// ProblemListFilter narrows and orders the public problem list; zero values keep every problem in id order.
+type ProblemListFilter struct {
+	Tag  string
+	Sort string
+}
+
+// validateProblemDifficulty checks a difficulty; nil (unset) is valid.
+func validateProblemDifficulty(d *int) error {
+	if d != nil && (*d < minProblemDifficulty || *d > maxProblemDifficulty) {
+		return fmt.Errorf("%w: difficulty は %d〜%d で指定してください", ErrProblemDifficultyInput, minProblemDifficulty, maxProblemDifficulty)
+	}
+	return nil
+}
+
+// problemListOrder returns the ORDER BY of the public problem list; unset difficulties come last.
+func problemListOrder(sort string) (string, bool) {
+	switch sort {
+	case "", ProblemSortID:
+		return "p.id", true
+	case ProblemSortDifficulty:
+		return "p.difficulty ASC NULLS LAST, p.id", true
+	case ProblemSortDifficultyDesc:
+		return "p.difficulty DESC NULLS LAST, p.id", true
+	}
+	return "", false
+}
@@ -0,0 +1,24 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestProblemDifficultyAndListOrder(t *testing.T) {
+	for _, d := range []int{0, 11} {
+		if err := validateProblemDifficulty(&d); !errors.Is(err, ErrProblemDifficultyInput) {
+			t.Errorf("difficulty %d: %v", d, err)
+		}
+	}
+	three := 3
+	if validateProblemDifficulty(&three) != nil || validateProblemDifficulty(nil) != nil {
+		t.Error("3 and unset must be valid")
+	}
+	if order, ok := problemListOrder("-difficulty"); !ok || order != "p.difficulty DESC NULLS LAST, p.id" {
+		t.Errorf("-difficulty: %q %v", order, ok)
+	}
+	if _, ok := problemListOrder("title"); ok {
+		t.Error("unknown sort must be rejected")
+	}
+}
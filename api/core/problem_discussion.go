@@ -0,0 +1,317 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	maxThreadTitleLength = 200
+	maxDiscussionBody    = 10000
+)
+
+// ErrThreadLocked is returned when replying to a locked thread.
+var ErrThreadLocked = errors.New("thread is locked")
+
+// ProblemThread is a discussion thread on a problem. Body is the opening post.
+type ProblemThread struct {
+	ID         int64   `json:"id"`
+	ProblemID  int64   `json:"problem_id"`
+	AuthorID   *int64  `json:"author_id"`
+	AuthorName *string `json:"author_userid"`
+	Title      string  `json:"title"`
+	Body       string  `json:"body"`
+	Spoiler    bool    `json:"spoiler"`
+	// SpoilerHidden is set when Body was withheld because the viewer has not solved the problem.
+	SpoilerHidden bool      `json:"spoiler_hidden"`
+	Pinned        bool      `json:"pinned"`
+	Locked        bool      `json:"locked"`
+	Hidden        bool      `json:"hidden"`
+	ReplyCount    int       `json:"reply_count"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	LastPostedAt  time.Time `json:"last_posted_at"`
+}
+
+// ProblemThreadPost is a reply in a thread.
+type ProblemThreadPost struct {
+	ID            int64     `json:"id"`
+	ThreadID      int64     `json:"thread_id"`
+	AuthorID      *int64    `json:"author_id"`
+	AuthorName    *string   `json:"author_userid"`
+	Body          string    `json:"body"`
+	Spoiler       bool      `json:"spoiler"`
+	SpoilerHidden bool      `json:"spoiler_hidden"`
+	Hidden        bool      `json:"hidden"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// ThreadModeration is a partial moderation update of a thread.
+type ThreadModeration struct {
+	Pinned *bool
+	Locked *bool
+	Hidden *bool
+}
+
+// maskSpoiler withholds the body of a spoiler post from viewers who may not see it yet.
+// Authors always see their own posts.
+func maskSpoiler(spoiler bool, authorID *int64, viewerID int64, revealed bool, body *string, hidden *bool) {
+	if !spoiler || revealed || (authorID != nil && *authorID == viewerID) {
+		return
+	}
+	*body = ""
+	*hidden = true
+}
+
+func (t *ProblemThread) maskFor(viewerID int64, revealed bool) {
+	maskSpoiler(t.Spoiler, t.AuthorID, viewerID, revealed, &t.Body, &t.SpoilerHidden)
+}
+
+func (p *ProblemThreadPost) maskFor(viewerID int64, revealed bool) {
+	maskSpoiler(p.Spoiler, p.AuthorID, viewerID, revealed, &p.Body, &p.SpoilerHidden)
+}
+
+type DiscussionRepository interface {
+	ListThreads(ctx context.Context, problemID int64, includeHidden bool, page, perPage int) ([]ProblemThread, int, error)
+	GetThread(ctx context.Context, id int64) (*ProblemThread, error)
+	ListPosts(ctx context.Context, threadID int64, includeHidden bool) ([]ProblemThreadPost, error)
+	CreateThread(ctx context.Context, problemID, authorID int64, title, body string, spoiler bool) (*ProblemThread, error)
+	CreatePost(ctx context.Context, threadID, authorID int64, body string, spoiler bool) (*ProblemThreadPost, error)
+	ModerateThread(ctx context.Context, id, moderatorID int64, input ThreadModeration) (*ProblemThread, error)
+	SetPostHidden(ctx context.Context, id, moderatorID int64, hidden bool) (*ProblemThreadPost, error)
+	DeleteThread(ctx context.Context, id int64) error
+	DeletePost(ctx context.Context, id int64) error
+	// HasSolved reports whether the user has an AC submission for the problem (unlocks spoilers).
+	HasSolved(ctx context.Context, userID, problemID int64) (bool, error)
+}
+
+type PgDiscussionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPgDiscussionRepository(db *pgxpool.Pool) *PgDiscussionRepository {
+	return &PgDiscussionRepository{db: db}
+}
+
+const threadSelect = `
+SELECT t.id, t.problem_id, t.author_id, u.username, t.title, t.body, t.spoiler, t.pinned, t.locked,
+       t.hidden_at IS NOT NULL,
+       (SELECT COUNT(*) FROM problem_thread_posts p WHERE p.thread_id = t.id AND p.hidden_at IS NULL),
+       t.created_at, t.updated_at, t.last_posted_at
+FROM problem_threads t
+LEFT JOIN users u ON u.id = t.author_id
+`
+
+func scanThread(row pgx.Row) (*ProblemThread, error) {
+	var t ProblemThread
+	if err := row.Scan(&t.ID, &t.ProblemID, &t.AuthorID, &t.AuthorName, &t.Title, &t.Body, &t.Spoiler, &t.Pinned, &t.Locked,
+		&t.Hidden, &t.ReplyCount, &t.CreatedAt, &t.UpdatedAt, &t.LastPostedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+const postSelect = `
+SELECT p.id, p.thread_id, p.author_id, u.username, p.body, p.spoiler, p.hidden_at IS NOT NULL, p.created_at, p.updated_at
+FROM problem_thread_posts p
+LEFT JOIN users u ON u.id = p.author_id
+`
+
+func scanPost(row pgx.Row) (*ProblemThreadPost, error) {
+	var p ProblemThreadPost
+	if err := row.Scan(&p.ID, &p.ThreadID, &p.AuthorID, &p.AuthorName, &p.Body, &p.Spoiler, &p.Hidden, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ListThreads returns pinned threads first, then by latest activity.
+func (r *PgDiscussionRepository) ListThreads(ctx context.Context, problemID int64, includeHidden bool, page, perPage int) ([]ProblemThread, int, error) {
+	where := "WHERE t.problem_id=$1"
+	if !includeHidden {
+		where += " AND t.hidden_at IS NULL"
+	}
+	var total int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM problem_threads t "+where, problemID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	q := threadSelect + where + " ORDER BY t.pinned DESC, t.last_posted_at DESC, t.id DESC LIMIT $2 OFFSET $3"
+	rows, err := r.db.Query(ctx, q, problemID, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	items := []ProblemThread{}
+	for rows.Next() {
+		t, err := scanThread(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		items = append(items, *t)
+	}
+	return items, total, rows.Err()
+}
+
+func (r *PgDiscussionRepository) GetThread(ctx context.Context, id int64) (*ProblemThread, error) {
+	return scanThread(r.db.QueryRow(ctx, threadSelect+"WHERE t.id=$1", id))
+}
+
+func (r *PgDiscussionRepository) ListPosts(ctx context.Context, threadID int64, includeHidden bool) ([]ProblemThreadPost, error) {
+	q := postSelect + "WHERE p.thread_id=$1"
+	if !includeHidden {
+		q += " AND p.hidden_at IS NULL"
+	}
+	rows, err := r.db.Query(ctx, q+" ORDER BY p.id", threadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ProblemThreadPost{}
+	for rows.Next() {
+		p, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *p)
+	}
+	return items, rows.Err()
+}
+
+func (r *PgDiscussionRepository) CreateThread(ctx context.Context, problemID, authorID int64, title, body string, spoiler bool) (*ProblemThread, error) {
+	title = strings.TrimSpace(title)
+	body = strings.TrimSpace(body)
+	if title == "" || body == "" {
+		return nil, errors.New("title and body are required")
+	}
+	var id int64
+	if err := r.db.QueryRow(ctx, `INSERT INTO problem_threads (problem_id, author_id, title, body, spoiler) VALUES ($1,$2,$3,$4,$5) RETURNING id`,
+		problemID, authorID, title, body, spoiler).Scan(&id); err != nil {
+		return nil, err
+	}
+	return r.GetThread(ctx, id)
+}
+
+// CreatePost appends a reply and bumps the thread's activity; locked threads reject replies.
+func (r *PgDiscussionRepository) CreatePost(ctx context.Context, threadID, authorID int64, body string, spoiler bool) (*ProblemThreadPost, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, errors.New("body is required")
+	}
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var locked bool
+	if err := tx.QueryRow(ctx, `SELECT locked FROM problem_threads WHERE id=$1 AND hidden_at IS NULL FOR UPDATE`, threadID).Scan(&locked); err != nil {
+		return nil, err
+	}
+	if locked {
+		return nil, ErrThreadLocked
+	}
+	var id int64
+	if err := tx.QueryRow(ctx, `INSERT INTO problem_thread_posts (thread_id, author_id, body, spoiler) VALUES ($1,$2,$3,$4) RETURNING id`,
+		threadID, authorID, body, spoiler).Scan(&id); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE problem_threads SET last_posted_at=NOW() WHERE id=$1`, threadID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return scanPost(r.db.QueryRow(ctx, postSelect+"WHERE p.id=$1", id))
+}
+
+func (r *PgDiscussionRepository) ModerateThread(ctx context.Context, id, moderatorID int64, input ThreadModeration) (*ProblemThread, error) {
+	var sets []string
+	var args []any
+	if input.Pinned != nil {
+		args = append(args, *input.Pinned)
+		sets = append(sets, "pinned=$"+strconv.Itoa(len(args)))
+	}
+	if input.Locked != nil {
+		args = append(args, *input.Locked)
+		sets = append(sets, "locked=$"+strconv.Itoa(len(args)))
+	}
+	if input.Hidden != nil {
+		if *input.Hidden {
+			args = append(args, moderatorID)
+			sets = append(sets, "hidden_at=COALESCE(hidden_at, NOW()), hidden_by=$"+strconv.Itoa(len(args)))
+		} else {
+			sets = append(sets, "hidden_at=NULL, hidden_by=NULL")
+		}
+	}
+	if len(sets) == 0 {
+		return r.GetThread(ctx, id)
+	}
+	args = append(args, id)
+	q := "UPDATE problem_threads SET " + strings.Join(sets, ", ") + ", updated_at=NOW() WHERE id=$" + strconv.Itoa(len(args))
+	ct, err := r.db.Exec(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	if ct.RowsAffected() == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return r.GetThread(ctx, id)
+}
+
+func (r *PgDiscussionRepository) SetPostHidden(ctx context.Context, id, moderatorID int64, hidden bool) (*ProblemThreadPost, error) {
+	q := `UPDATE problem_thread_posts SET hidden_at=NULL, hidden_by=NULL, updated_at=NOW() WHERE id=$1`
+	args := []any{id}
+	if hidden {
+		q = `UPDATE problem_thread_posts SET hidden_at=COALESCE(hidden_at, NOW()), hidden_by=$2, updated_at=NOW() WHERE id=$1`
+		args = append(args, moderatorID)
+	}
+	ct, err := r.db.Exec(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	if ct.RowsAffected() == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return scanPost(r.db.QueryRow(ctx, postSelect+"WHERE p.id=$1", id))
+}
+
+func (r *PgDiscussionRepository) DeleteThread(ctx context.Context, id int64) error {
+	ct, err := r.db.Exec(ctx, `DELETE FROM problem_threads WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PgDiscussionRepository) DeletePost(ctx context.Context, id int64) error {
+	ct, err := r.db.Exec(ctx, `DELETE FROM problem_thread_posts WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PgDiscussionRepository) HasSolved(ctx context.Context, userID, problemID int64) (bool, error) {
+	const q = `SELECT EXISTS (
+SELECT 1 FROM submissions s
+JOIN submission_results r ON r.submission_id = s.id
+WHERE s.user_id=$1 AND s.problem_id=$2 AND r.verdict='` + VerdictAC + `')`
+	var ok bool
+	if err := r.db.QueryRow(ctx, q, userID, problemID).Scan(&ok); err != nil {
+		return false, err
+	}
+	return ok, nil
+}
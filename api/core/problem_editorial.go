@@ -0,0 +1,109 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Editorial visibility overrides. auto follows the contests of the problem (see EditorialReleased).
+// The editorial is stored once per problem; the contest editorial routes edit the same one.
+const (
+	EditorialVisibilityAuto   = "auto"
+	EditorialVisibilityPublic = "public"
+	EditorialVisibilityHidden = "hidden"
+)
+
+// editorialSourceName is the optional editorial of a problem package.
+const editorialSourceName = "editorial.md"
+
+// editorialVisibilityFromPackage maps editorial_public of problem.yaml to a visibility: true
+// publishes the editorial and false hides it. Unset returns "", which creates the problem with
+// auto and keeps the stored visibility when the package overwrites a problem.
+func editorialVisibilityFromPackage(public *bool) string {
+	switch {
+	case public == nil:
+		return ""
+	case *public:
+		return EditorialVisibilityPublic
+	default:
+		return EditorialVisibilityHidden
+	}
+}
+
+// ProblemEditorial is the editorial stored on a problem.
+type ProblemEditorial struct {
+	ProblemID   int64  `json:"problem_id"`
+	EditorialMD string `json:"editorial"`
+	Visibility  string `json:"visibility"`
+	Released    bool   `json:"released"`
+}
+
+// ProblemEditorialInput is a partial update of a problem editorial.
+type ProblemEditorialInput struct {
+	EditorialMD *string
+	Visibility  *string
+}
+
+// EditorialReleased applies the release policy: hidden/public override everything,
+// auto releases practice problems immediately and contest problems once the owning contest and
+// every contest that lists the problem have ended.
+func (v ProblemVisibility) EditorialReleased(visibility string, now time.Time) bool {
+	switch visibility {
+	case EditorialVisibilityHidden:
+		return false
+	case EditorialVisibilityPublic:
+		return true
+	}
+	phase := v.ContestPhase(now)
+	return (phase == "" || phase == ContestPhaseEnded) && !now.Before(v.ContestsEnd)
+}
+
+// editorialReleasedSQL is EditorialReleased for a query over problems p.
+const editorialReleasedSQL = `(p.editorial_visibility = 'public' OR (p.editorial_visibility = 'auto' AND NOT EXISTS (
+    SELECT 1 FROM contests ec
+    WHERE ec.end_at > NOW()
+      AND (ec.id = p.contest_id OR ec.id IN (SELECT contest_id FROM contest_problems WHERE problem_id = p.id)))))`
+
+func (r *PgProblemRepository) GetEditorial(ctx context.Context, id int64) (*ProblemEditorial, error) {
+	const q = `SELECT id, editorial_md, editorial_visibility FROM problems WHERE id=$1 AND deleted_at IS NULL`
+	var e ProblemEditorial
+	if err := r.db.QueryRow(ctx, q, id).Scan(&e.ProblemID, &e.EditorialMD, &e.Visibility); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *PgProblemRepository) SetEditorial(ctx context.Context, id int64, input ProblemEditorialInput) (*ProblemEditorial, error) {
+	var sets []string
+	var args []any
+	if input.EditorialMD != nil {
+		sets = append(sets, "editorial_md=$"+strconv.Itoa(len(args)+1))
+		args = append(args, *input.EditorialMD)
+	}
+	if input.Visibility != nil {
+		vis := strings.ToLower(strings.TrimSpace(*input.Visibility))
+		if vis != EditorialVisibilityAuto && vis != EditorialVisibilityPublic && vis != EditorialVisibilityHidden {
+			return nil, errors.New("visibility must be auto, public or hidden")
+		}
+		sets = append(sets, "editorial_visibility=$"+strconv.Itoa(len(args)+1))
+		args = append(args, vis)
+	}
+	if len(sets) == 0 {
+		return r.GetEditorial(ctx, id)
+	}
+	args = append(args, id)
+	q := "UPDATE problems SET " + strings.Join(sets, ", ") + " WHERE id=$" + strconv.Itoa(len(args)) + " AND deleted_at IS NULL"
+	ct, err := r.db.Exec(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	if ct.RowsAffected() == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return r.GetEditorial(ctx, id)
+}
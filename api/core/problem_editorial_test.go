@@ -0,0 +1,75 @@
+package core
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestParseProblemArchiveEditorial(t *testing.T) {
+	build := func(files map[string]string) []byte {
+		buf := &bytes.Buffer{}
+		zw := zip.NewWriter(buf)
+		for name, content := range files {
+			w, _ := zw.Create("aplusb/" + name)
+			_, _ = w.Write([]byte(content))
+		}
+		_ = zw.Close()
+		return buf.Bytes()
+	}
+	files := map[string]string{
+		"problem.yaml":       "slug: aplusb\ntitle: A+B\neditorial_public: true\n",
+		"statement.md":       "# A+B\n",
+		"editorial.md":       "足すだけ<script>alert(1)</script>\n",
+		"data/secret/01.in":  "1 2\n",
+		"data/secret/01.out": "3\n",
+	}
+	pkg, err := ParseProblemArchive(context.Background(), build(files), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.EditorialMD == nil || *pkg.EditorialMD != "足すだけ\n" || pkg.EditorialVisibility != EditorialVisibilityPublic {
+		t.Fatalf("editorial = %v %q", pkg.EditorialMD, pkg.EditorialVisibility)
+	}
+	if len(pkg.StatementSanitized) != 1 {
+		t.Errorf("sanitized = %v", pkg.StatementSanitized)
+	}
+
+	delete(files, "editorial.md")
+	files["problem.yaml"] = "slug: aplusb\ntitle: A+B\n"
+	if pkg, err = ParseProblemArchive(context.Background(), build(files), nil); err != nil || pkg.EditorialMD != nil || pkg.EditorialVisibility != "" {
+		t.Fatalf("without editorial: %v %v %q", err, pkg.EditorialMD, pkg.EditorialVisibility)
+	}
+}
+
+// 非公開にした解説は、editorial_public を書かないパッケージで再インポートしても非公開のまま
+func TestReimportKeepsHiddenEditorial(t *testing.T) {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for name, content := range map[string]string{
+		"problem.yaml":       "slug: aplusb\ntitle: A+B\n",
+		"statement.md":       "# A+B\n",
+		"editorial.md":       "書き直した解説\n",
+		"data/secret/01.in":  "1 2\n",
+		"data/secret/01.out": "3\n",
+	} {
+		w, _ := zw.Create("aplusb/" + name)
+		_, _ = w.Write([]byte(content))
+	}
+	_ = zw.Close()
+	pkg, err := ParseProblemArchive(context.Background(), buf.Bytes(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// replaceWithPackageTx は空の visibility を現在の値（hidden）のまま残す
+	if pkg.EditorialMD == nil || *pkg.EditorialMD != "書き直した解説\n" {
+		t.Fatalf("editorial = %v", pkg.EditorialMD)
+	}
+	if pkg.EditorialVisibility != "" {
+		t.Fatalf("visibility = %q, want empty so the stored hidden is kept", pkg.EditorialVisibility)
+	}
+	if got := editorialVisibilityFromPackage(nil); got != "" {
+		t.Fatalf("unset editorial_public = %q", got)
+	}
+}
@@ -0,0 +1,176 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	generatorDir            = "generators/"
+	maxGeneratedCases       = 100
+	generatorCompileLimitMs = 30000
+	generatorTimeLimitMs    = 10000
+	generatorMemoryLimitMb  = 1024
+)
+
+// generated testcase names: sample/<name> or secret/<name>
+var generatedCaseNamePattern = regexp.MustCompile(`^(sample|secret)/[A-Za-z0-9_.-]+$`)
+
+// generatorLanguages maps source extensions to judge languages.
+var generatorLanguages = map[string]string{
+	".c":   "c",
+	".cpp": "cpp",
+	".cc":  "cpp",
+	".py":  "python",
+}
+
+// problemGeneratorsDoc is the generators manifest of problem.yaml:
+//
+//	generators:
+//	  solution: solution.cpp       # 任意: .out が同梱されていないケースの出力を作る
+//	  cases:
+//	    - name: secret/random_01
+//	      generator: gen_random.cpp  # generators/ 以下のファイル
+//	      args: ["100000", "1"]
+type problemGeneratorsDoc struct {
+	Solution string             `yaml:"solution"`
+	Cases    []generatorCaseDoc `yaml:"cases"`
+}
+
+type generatorCaseDoc struct {
+	Name      string   `yaml:"name"`
+	Generator string   `yaml:"generator"`
+	Args      []string `yaml:"args"`
+}
+
+// TestcaseGenerator materializes generated testcases by running generators inside go-judge.
+type TestcaseGenerator struct {
+	judge JudgeClient
+}
+
+func NewTestcaseGenerator(judge JudgeClient) *TestcaseGenerator {
+	return &TestcaseGenerator{judge: judge}
+}
+
+// Generate runs the manifest and returns data/<name>.in (and .out produced by the solution) entries.
+// Outputs already present in files are kept.
+func (g *TestcaseGenerator) Generate(ctx context.Context, doc problemGeneratorsDoc, files map[string][]byte) (map[string][]byte, error) {
+	if len(doc.Cases) == 0 {
+		return nil, nil
+	}
+	if g == nil || g.judge == nil {
+		return nil, errors.New("generators を使うには go-judge の設定が必要です")
+	}
+	if len(doc.Cases) > maxGeneratedCases {
+		return nil, fmt.Errorf("generators.cases が多すぎます (%d 件上限)", maxGeneratedCases)
+	}
+
+	compiled := map[string]string{} // source path -> artifact id
+	defer func() {
+		var ids []string
+		for _, id := range compiled {
+			ids = append(ids, id)
+		}
+		if len(ids) > 0 {
+			_ = g.judge.RemoveFiles(context.WithoutCancel(ctx), ids...)
+		}
+	}()
+	compile := func(srcPath string) (string, string, error) {
+		lang, ok := generatorLanguages[path.Ext(srcPath)]
+		if !ok {
+			return "", "", fmt.Errorf("%s: 対応していない言語です (.c / .cpp / .py)", srcPath)
+		}
+		if id, ok := compiled[srcPath]; ok {
+			return lang, id, nil
+		}
+		src, ok := files[srcPath]
+		if !ok {
+			return "", "", fmt.Errorf("%s が見つかりません", srcPath)
+		}
+		res, _, id, err := g.judge.Compile(ctx, lang, string(src), generatorCompileLimitMs, generatorMemoryLimitMb)
+		if err != nil {
+			return "", "", fmt.Errorf("%s のコンパイルに失敗しました: %w", srcPath, err)
+		}
+		if res.Status != "Accepted" || res.ExitStatus != 0 || id == "" {
+			return "", "", fmt.Errorf("%s のコンパイルに失敗しました: %s", srcPath, strings.TrimSpace(firstNonEmpty(res.Files["stderr"], res.Error, res.Status)))
+		}
+		compiled[srcPath] = id
+		return lang, id, nil
+	}
+
+	out := map[string][]byte{}
+	var total int64
+	var names []string
+	seen := map[string]bool{}
+	for i, tc := range doc.Cases {
+		name := strings.TrimSpace(tc.Name)
+		if !generatedCaseNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("generators.cases[%d].name は sample/<名前> または secret/<名前> で指定してください", i)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("generators.cases の name %q が重複しています", name)
+		}
+		seen[name] = true
+		names = append(names, name)
+		inName := "data/" + name + ".in"
+		if _, exists := files[inName]; exists {
+			return nil, fmt.Errorf("%s は generators で生成されるため同梱できません", inName)
+		}
+		genPath := path.Join(generatorDir, strings.TrimSpace(tc.Generator))
+		if strings.TrimSpace(tc.Generator) == "" || !strings.HasPrefix(genPath, generatorDir) {
+			return nil, fmt.Errorf("generators.cases[%d].generator は generators/ 以下のファイル名で指定してください", i)
+		}
+		lang, id, err := compile(genPath)
+		if err != nil {
+			return nil, err
+		}
+		res, err := g.judge.RunWithArgs(ctx, lang, id, "", tc.Args, generatorTimeLimitMs, generatorMemoryLimitMb)
+		if err != nil {
+			return nil, fmt.Errorf("%s の生成に失敗しました: %w", name, err)
+		}
+		if res.Status != "Accepted" || res.ExitStatus != 0 {
+			return nil, fmt.Errorf("%s の生成に失敗しました (%s)", name, res.Status)
+		}
+		input := res.Files["stdout"]
+		if strings.TrimSpace(input) == "" {
+			return nil, fmt.Errorf("%s: generator の出力が空です", name)
+		}
+		if len(input) > maxArchiveFileSize {
+			return nil, fmt.Errorf("%s: 生成された入力が大きすぎます (上限 %d bytes)", name, maxArchiveFileSize)
+		}
+		total += int64(len(input))
+		if total > maxArchiveTotalSize {
+			return nil, fmt.Errorf("生成された入力の合計サイズが大きすぎます (%dMB 上限)", maxArchiveTotalSize/1024/1024)
+		}
+		out[inName] = []byte(input)
+	}
+
+	solution := strings.TrimSpace(doc.Solution)
+	for _, name := range names {
+		outName := "data/" + name + ".out"
+		if _, ok := files[outName]; ok {
+			continue
+		}
+		if solution == "" {
+			return nil, fmt.Errorf("%s がありません (同梱するか generators.solution を指定してください)", outName)
+		}
+		lang, id, err := compile(normalizeArchivePath(solution))
+		if err != nil {
+			return nil, err
+		}
+		inName := "data/" + name + ".in"
+		res, err := g.judge.RunWithArtifact(ctx, lang, id, string(out[inName]), generatorTimeLimitMs, generatorMemoryLimitMb)
+		if err != nil {
+			return nil, fmt.Errorf("%s の出力の生成に失敗しました: %w", name, err)
+		}
+		if res.Status != "Accepted" || res.ExitStatus != 0 {
+			return nil, fmt.Errorf("%s の出力の生成に失敗しました (%s)", name, res.Status)
+		}
+		out[outName] = []byte(res.Files["stdout"])
+	}
+	return out, nil
+}
@@ -3,6 +3,7 @@ package core
 import (
 	"archive/zip"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -14,32 +15,62 @@ import (
 )
 
 const (
-	maxArchiveEntries   = 200
-	maxArchiveTotalSize = 32 * 1024 * 1024
-	maxArchiveFileSize  = 4 * 1024 * 1024
+	maxArchiveEntries  = 200
+	maxArchiveFileSize = 4 * 1024 * 1024
 )
 
+// maxArchiveTotalSize caps the total uncompressed size of a package (PROBLEM_ARCHIVE_MAX_MB).
+var maxArchiveTotalSize int64 = 32 * 1024 * 1024
+
+// SetProblemArchiveLimit sets the uncompressed size cap of problem packages in MB; values <= 0 keep the default.
+// Call it once at startup.
+func SetProblemArchiveLimit(mb int) {
+	if mb > 0 {
+		maxArchiveTotalSize = int64(mb) * 1024 * 1024
+	}
+}
+
 // ParseProblemArchive converts a zip problem package into inline DTO.
 // Expected layout (トップフォルダは任意):
 //
 //	problem.yaml (required)
 //	statement.md (required)
+//	checker.cpp (checker.type: custom のとき required)
+//	editorial.md (optional, 解説。problem.yaml の editorial_public で公開を制御)
+//	assets/* (optional, 問題文から assets/<name> で参照する画像: png / jpg / gif / webp)
 //	data/sample/*.in, *.out (optional, is_sample=true)
 //	data/secret/*.in, *.out (optional, is_sample=false)
 //
+// problem.yaml の generators（任意）は generators/ 以下のジェネレータを go-judge で実行して
+// data/<name>.in を生成する（gen が nil のときは使用できない。problemGeneratorsDoc を参照）。
+//
+// problem.yaml の subtasks（任意）はテストケース名のパターン（例: secret/01, secret/large_*）で
+// 小課題ごとの配点を定義する。groups（任意）も同じパターンでテストケースをまとめ、
+// グループごとに limits（time_ms / memory_mb）を上書きする。
+//
 // Files may be placed directly under the archive root or under a single
 // top-level folder whose name equals slug.
-func ParseProblemArchive(data []byte) (ProblemCreateInput, error) {
-	if len(data) == 0 {
+func ParseProblemArchive(ctx context.Context, data []byte, gen *TestcaseGenerator) (ProblemCreateInput, error) {
+	return ParseProblemArchiveAt(ctx, bytes.NewReader(data), int64(len(data)), gen)
+}
+
+// ParseProblemArchiveAt is ParseProblemArchive for an archive of size bytes read from archive (e.g.
+// an uploaded file), without loading the whole zip into memory.
+func ParseProblemArchiveAt(ctx context.Context, archive io.ReaderAt, size int64, gen *TestcaseGenerator) (ProblemCreateInput, error) {
+	if size == 0 {
 		return ProblemCreateInput{}, errors.New("アーカイブが空です")
 	}
 
 	files := map[string][]byte{}
 	// Accept zip only
-	if len(data) < 4 || !bytes.Equal(data[:4], []byte{'P', 'K', 0x03, 0x04}) {
+	magic := make([]byte, 4)
+	if size < 4 {
+		return ProblemCreateInput{}, errors.New("zip 形式のみ対応しています")
+	}
+	if _, err := archive.ReadAt(magic, 0); err != nil || !bytes.Equal(magic, []byte{'P', 'K', 0x03, 0x04}) {
 		return ProblemCreateInput{}, errors.New("zip 形式のみ対応しています")
 	}
-	rootName, err := collectFromZip(data, files)
+	rootName, err := collectFromZip(archive, size, files)
 	if err != nil {
 		return ProblemCreateInput{}, err
 	}
@@ -87,11 +118,56 @@ func ParseProblemArchive(data []byte) (ProblemCreateInput, error) {
 		return ProblemCreateInput{}, errors.New("title は必須です")
 	}
 
+	// 問題文のスクリプト類を除去し、画像・リンクの参照先が同梱 assets にあるか確認する
+	assets, report := collectProblemAssets(files)
+	statementMD, sanitized := sanitizeStatement(string(statement))
+	assetNames := make(map[string]bool, len(assets))
+	for _, a := range assets {
+		assetNames[a.Name] = true
+	}
+	report = append(report, checkStatementRefs(statementMD, assetNames)...)
+	if len(report) > 0 {
+		return ProblemCreateInput{}, errors.New("statement.md の検証に失敗しました:\n" + strings.Join(report, "\n"))
+	}
+
+	// editorial.md（任意）も問題文と同じ検証をかけて解説として保存する
+	var editorialMD *string
+	if src, ok := files[editorialSourceName]; ok {
+		md, removed := sanitizeStatement(string(src))
+		if broken := checkStatementRefs(md, assetNames); len(broken) > 0 {
+			return ProblemCreateInput{}, errors.New("editorial.md の検証に失敗しました:\n" + strings.Join(broken, "\n"))
+		}
+		for _, r := range removed {
+			sanitized = append(sanitized, editorialSourceName+" "+r)
+		}
+		editorialMD = &md
+	}
+
+	checkerSource := ""
+	if doc.Checker.Type == CheckerTypeCustom {
+		src, ok := files[checkerSourceName]
+		if !ok || strings.TrimSpace(string(src)) == "" {
+			return ProblemCreateInput{}, errors.New("checker.type が custom の場合は checker.cpp が必要です")
+		}
+		if len(src) > maxCheckerSize {
+			return ProblemCreateInput{}, errors.New("checker.cpp が大きすぎます")
+		}
+		checkerSource = string(src)
+	}
+
+	generated, err := gen.Generate(ctx, doc.Generators, files)
+	if err != nil {
+		return ProblemCreateInput{}, err
+	}
+	for name, content := range generated {
+		files[name] = content
+	}
+
 	if doc.Limits.TimeMS <= 0 {
-		doc.Limits.TimeMS = 2000
+		doc.Limits.TimeMS = defaultTimeLimitMS
 	}
 	if doc.Limits.MemoryMB <= 0 {
-		doc.Limits.MemoryMB = 256
+		doc.Limits.MemoryMB = defaultMemoryLimitMB
 	}
 
 	// Collect testcases from data/sample and data/secret
@@ -141,11 +217,20 @@ func ParseProblemArchive(data []byte) (ProblemCreateInput, error) {
 	sort.Strings(keys)
 
 	var tcs []ProblemTestcaseInput
+	normalized := []OutputNormalization{}
 	for _, key := range keys {
 		b := caseBuckets[key]
 		if strings.TrimSpace(b.in) == "" || strings.TrimSpace(b.out) == "" {
 			return ProblemCreateInput{}, fmt.Errorf("%s の .in/.out が揃っていません", key)
 		}
+		if doc.NormalizeOutputs {
+			var n OutputNormalization
+			b.out, n = normalizeExpectedOutput(b.out)
+			if n.changed() {
+				n.Testcase = key
+				normalized = append(normalized, n)
+			}
+		}
 		var inPath, outPath string
 		if strings.HasPrefix(key, "sample/") {
 			base := strings.TrimPrefix(key, "sample/")
@@ -165,28 +250,71 @@ func ParseProblemArchive(data []byte) (ProblemCreateInput, error) {
 		})
 	}
 
+	if err := checkTestcaseLimits(tcs); err != nil {
+		return ProblemCreateInput{}, err
+	}
+
+	subtasks, err := resolveSubtaskPatterns(doc.Subtasks, keys)
+	if err != nil {
+		return ProblemCreateInput{}, err
+	}
+	groups, err := resolveTestcaseGroups(doc.Groups, keys)
+	if err != nil {
+		return ProblemCreateInput{}, err
+	}
+
+	if err := validateProblemDifficulty(doc.Difficulty); err != nil {
+		return ProblemCreateInput{}, fmt.Errorf("problem.yaml の difficulty が不正です: %w", err)
+	}
+	tags, err := normalizeProblemTags(doc.Tags)
+	if err != nil {
+		return ProblemCreateInput{}, fmt.Errorf("problem.yaml の tags が不正です: %w", err)
+	}
+
 	isPublic := true
 	if doc.Visibility.Public != nil {
 		isPublic = *doc.Visibility.Public
 	}
 	return ProblemCreateInput{
-		Title:         strings.TrimSpace(doc.Title),
-		Slug:          slug,
-		StatementMD:   string(statement),
-		StatementPath: nil,
-		TimeLimitMS:   int32(doc.Limits.TimeMS),
-		MemoryLimitKB: int32(doc.Limits.MemoryMB * 1024),
-		IsPublic:      isPublic,
-		CheckerType:   doc.Checker.Type,
-		CheckerEps:    doc.Checker.Eps,
-		Testcases:     tcs,
+		Title:               strings.TrimSpace(doc.Title),
+		TitleJA:             strings.TrimSpace(doc.TitleJA),
+		TitleEN:             strings.TrimSpace(doc.TitleEN),
+		Slug:                slug,
+		StatementMD:         statementMD,
+		StatementPath:       nil,
+		TimeLimitMS:         int32(doc.Limits.TimeMS),
+		MemoryLimitKB:       int32(doc.Limits.MemoryMB * 1024),
+		IsPublic:            isPublic,
+		CheckerType:         doc.Checker.Type,
+		CheckerEps:          doc.Checker.Eps,
+		CheckerSource:       checkerSource,
+		RunAllTestcases:     doc.RunAllTestcases,
+		Difficulty:          doc.Difficulty,
+		EditorialMD:         editorialMD,
+		EditorialVisibility: editorialVisibilityFromPackage(doc.EditorialPublic),
+		Tags:                tags,
+		Testcases:           tcs,
+		Subtasks:            subtasks,
+		Groups:              groups,
+		Assets:              assets,
+		StatementSanitized:  sanitized,
+		NormalizedOutputs:   normalized,
 	}, nil
 }
 
 type problemDoc struct {
-	Slug   string `yaml:"slug"`
-	Title  string `yaml:"title"`
-	Limits struct {
+	Slug  string `yaml:"slug"`
+	Title string `yaml:"title"`
+	// TitleJA / TitleEN（任意）は一覧や順位表で利用者の言語に合わせて表示する問題名
+	TitleJA string `yaml:"title_ja"`
+	TitleEN string `yaml:"title_en"`
+	// Tags（任意）は問題一覧の絞り込みに使うタグ（例: [dp, graph]）
+	Tags []string `yaml:"tags"`
+	// Difficulty（任意）は 1（易）〜10（難）の難易度
+	Difficulty *int `yaml:"difficulty"`
+	// EditorialPublic（任意）: true で解説を常に公開、false で非公開。省略時は新規作成なら auto、上書きなら現在の設定のまま
+	EditorialPublic *bool `yaml:"editorial_public"`
+	Limits          struct {
 		TimeMS   int `yaml:"time_ms"`
 		MemoryMB int `yaml:"memory_mb"`
 	} `yaml:"limits"`
@@ -197,6 +325,19 @@ type problemDoc struct {
 	Visibility struct {
 		Public *bool `yaml:"public"`
 	} `yaml:"visibility"`
+	// RunAllTestcases: true で最初の不正解後も全テストケースを実行する
+	RunAllTestcases bool `yaml:"run_all_testcases"`
+	// NormalizeOutputs: true で .out の改行を LF に揃え、行末空白を除き、末尾改行を補う
+	NormalizeOutputs bool                 `yaml:"normalize_outputs"`
+	Subtasks         []problemSubtaskDoc  `yaml:"subtasks"`
+	Groups           []problemGroupDoc    `yaml:"groups"`
+	Generators       problemGeneratorsDoc `yaml:"generators"`
+}
+
+type problemSubtaskDoc struct {
+	Name      string   `yaml:"name"`
+	Score     int      `yaml:"score"`
+	Testcases []string `yaml:"testcases"`
 }
 
 func parseProblemYAML(b []byte) (problemDoc, error) {
@@ -206,15 +347,16 @@ func parseProblemYAML(b []byte) (problemDoc, error) {
 	}
 	doc.Title = strings.TrimSpace(doc.Title)
 	if doc.Checker.Type == "" {
-		doc.Checker.Type = "exact"
+		doc.Checker.Type = CheckerTypeExact
 	}
-	doc.Checker.Type = strings.ToLower(strings.TrimSpace(doc.Checker.Type))
-	if doc.Checker.Type != "exact" && doc.Checker.Type != "eps" {
-		return doc, fmt.Errorf("checker.type は exact または eps で指定してください")
+	checkerType, ok := validCheckerType(doc.Checker.Type)
+	if !ok {
+		return doc, fmt.Errorf("checker.type は exact・eps・custom のいずれかで指定してください")
 	}
-	if doc.Checker.Type == "eps" {
-		if doc.Checker.Eps <= 0 {
-			return doc, fmt.Errorf("checker.eps は 0 より大きい値を指定してください")
+	doc.Checker.Type = checkerType
+	if doc.Checker.Type == CheckerTypeEps {
+		if err := validateCheckerEps(doc.Checker.Eps); err != nil {
+			return doc, fmt.Errorf("checker.eps は %g 以上 %g 以下で指定してください", minCheckerEps, maxCheckerEps)
 		}
 	} else {
 		doc.Checker.Eps = 0
@@ -223,8 +365,8 @@ func parseProblemYAML(b []byte) (problemDoc, error) {
 }
 
 // collectFromZip reads zip entries into files map with size/entry/path validation.
-func collectFromZip(data []byte, files map[string][]byte) (string, error) {
-	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+func collectFromZip(archive io.ReaderAt, size int64, files map[string][]byte) (string, error) {
+	reader, err := zip.NewReader(archive, size)
 	if err != nil {
 		return "", fmt.Errorf("zip を展開できません: %w", err)
 	}
@@ -265,7 +407,7 @@ func collectFromZip(data []byte, files map[string][]byte) (string, error) {
 		}
 		total += int64(len(content))
 		if total > maxArchiveTotalSize {
-			return "", errors.New("展開後サイズが大きすぎます (32MB 上限)")
+			return "", fmt.Errorf("展開後サイズが大きすぎます (%dMB 上限)", maxArchiveTotalSize/1024/1024)
 		}
 		entries = append(entries, entry{name: norm, content: content})
 		parts := strings.Split(norm, "/")
@@ -0,0 +1,207 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ProblemImportDiff compares a package with the existing problem that has the same slug. The import
+// only overwrites the problem when it is explicitly confirmed (overwrite=true).
+type ProblemImportDiff struct {
+	ProblemID        int64                     `json:"problem_id"`
+	Slug             string                    `json:"slug"`
+	Changed          bool                      `json:"changed"`
+	TitleChanged     bool                      `json:"title_changed"`
+	StatementChanged bool                      `json:"statement_changed"`
+	Limits           *ProblemImportLimitChange `json:"limits"` // 変更がなければ null
+	CheckerChanged   bool                      `json:"checker_changed"`
+	RunAllChanged    bool                      `json:"run_all_testcases_changed"`
+	SubtasksChanged  bool                      `json:"subtasks_changed"`
+	GroupsChanged    bool                      `json:"groups_changed"`
+	AddedTestcases   []string                  `json:"added_testcases"`
+	RemovedTestcases []string                  `json:"removed_testcases"`
+	ChangedTestcases []string                  `json:"changed_testcases"`
+	AddedAssets      []string                  `json:"added_assets"`
+	RemovedAssets    []string                  `json:"removed_assets"`
+	ChangedAssets    []string                  `json:"changed_assets"`
+}
+
+type ProblemImportLimits struct {
+	TimeLimitMS   int32 `json:"time_limit_ms"`
+	MemoryLimitKB int32 `json:"memory_limit_kb"`
+}
+
+type ProblemImportLimitChange struct {
+	From ProblemImportLimits `json:"from"`
+	To   ProblemImportLimits `json:"to"`
+}
+
+// importTestcaseKey names a testcase the way packages do (sample/01, secret/large_1) from its input
+// path; testcases without one are named by position.
+func importTestcaseKey(inputPath string, index int) string {
+	p := strings.TrimPrefix(normalizeArchivePath(inputPath), "data/")
+	if inputPath == "" || p == "." {
+		return fmt.Sprintf("#%d", index+1)
+	}
+	return strings.TrimSuffix(p, path.Ext(p))
+}
+
+// loadProblemImportDiff loads the problem id and compares it with pkg.
+func loadProblemImportDiff(ctx context.Context, problemRepo ProblemRepository, id int64, pkg ProblemCreateInput) (*ProblemImportDiff, error) {
+	current, err := problemRepo.FindDetailAdmin(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	testcases, err := problemRepo.ListTestcases(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	subtasks, err := problemRepo.ListSubtasks(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	assets, err := problemRepo.ListAssets(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return diffProblemImport(current, testcases, subtasks, assets, pkg), nil
+}
+
+func diffProblemImport(current *ProblemDetail, testcases []ProblemTestcase, subtasks []ProblemSubtask, assets []ProblemAsset, pkg ProblemCreateInput) *ProblemImportDiff {
+	d := &ProblemImportDiff{
+		ProblemID:        current.ID,
+		Slug:             current.Slug,
+		TitleChanged:     current.Title != pkg.Title || current.LocalizedTitles.For("ja", "") != pkg.TitleJA || current.LocalizedTitles.For("en", "") != pkg.TitleEN,
+		StatementChanged: current.StatementMD != pkg.StatementMD,
+		CheckerChanged:   current.CheckerType != pkg.CheckerType || current.CheckerEps != pkg.CheckerEps || current.CheckerSource != pkg.CheckerSource,
+		RunAllChanged:    current.RunAllTestcases != pkg.RunAllTestcases,
+		AddedTestcases:   []string{},
+		RemovedTestcases: []string{},
+		ChangedTestcases: []string{},
+		AddedAssets:      []string{},
+		RemovedAssets:    []string{},
+		ChangedAssets:    []string{},
+	}
+	if current.TimeLimitMS != pkg.TimeLimitMS || current.MemoryLimitKB != pkg.MemoryLimitKB {
+		d.Limits = &ProblemImportLimitChange{
+			From: ProblemImportLimits{TimeLimitMS: current.TimeLimitMS, MemoryLimitKB: current.MemoryLimitKB},
+			To:   ProblemImportLimits{TimeLimitMS: pkg.TimeLimitMS, MemoryLimitKB: pkg.MemoryLimitKB},
+		}
+	}
+
+	// テストケースは名前で突き合わせ、グループはテストケースごとの所属と制限で比べる
+	curCases := make(map[string]ProblemTestcase, len(testcases))
+	curKeys := make(map[int64]string, len(testcases))
+	curGroups := map[string]string{}
+	for i, tc := range testcases {
+		key := importTestcaseKey(tc.InputPath, i)
+		curCases[key] = tc
+		curKeys[tc.ID] = key
+		if tc.GroupName != "" {
+			curGroups[key] = groupSignature(tc.GroupName, tc.TimeLimitMS, tc.MemoryLimitKB)
+		}
+	}
+	pkgKeys := make([]string, len(pkg.Testcases))
+	seen := make(map[string]bool, len(pkg.Testcases))
+	for i, tc := range pkg.Testcases {
+		key := importTestcaseKey(tc.InputPath, i)
+		pkgKeys[i] = key
+		seen[key] = true
+		cur, ok := curCases[key]
+		switch {
+		case !ok:
+			d.AddedTestcases = append(d.AddedTestcases, key)
+		case cur.InputText != tc.InputText || cur.OutputText != tc.OutputText || cur.IsSample != tc.IsSample:
+			d.ChangedTestcases = append(d.ChangedTestcases, key)
+		}
+	}
+	for key := range curCases {
+		if !seen[key] {
+			d.RemovedTestcases = append(d.RemovedTestcases, key)
+		}
+	}
+	sort.Strings(d.RemovedTestcases)
+
+	pkgGroups := map[string]string{}
+	for _, g := range pkg.Groups {
+		for _, idx := range g.TestcaseIndexes {
+			pkgGroups[pkgKeys[idx]] = groupSignature(g.Name, g.TimeLimitMS, g.MemoryLimitKB)
+		}
+	}
+	d.GroupsChanged = !equalStringMaps(curGroups, pkgGroups)
+
+	curSubtasks := make([]string, len(subtasks))
+	for i, st := range subtasks {
+		keys := make([]string, len(st.TestcaseIDs))
+		for j, id := range st.TestcaseIDs {
+			keys[j] = curKeys[id]
+		}
+		curSubtasks[i] = subtaskSignature(st.Name, st.Score, keys)
+	}
+	pkgSubtasks := make([]string, len(pkg.Subtasks))
+	for i, st := range pkg.Subtasks {
+		keys := make([]string, len(st.TestcaseIndexes))
+		for j, idx := range st.TestcaseIndexes {
+			keys[j] = pkgKeys[idx]
+		}
+		pkgSubtasks[i] = subtaskSignature(st.Name, st.Score, keys)
+	}
+	d.SubtasksChanged = strings.Join(curSubtasks, "\n") != strings.Join(pkgSubtasks, "\n")
+
+	curAssets := make(map[string][]byte, len(assets))
+	for _, a := range assets {
+		curAssets[a.Name] = a.Data
+	}
+	for _, a := range pkg.Assets {
+		data, ok := curAssets[a.Name]
+		switch {
+		case !ok:
+			d.AddedAssets = append(d.AddedAssets, a.Name)
+		case !bytes.Equal(data, a.Data):
+			d.ChangedAssets = append(d.ChangedAssets, a.Name)
+		}
+		delete(curAssets, a.Name)
+	}
+	for name := range curAssets {
+		d.RemovedAssets = append(d.RemovedAssets, name)
+	}
+	sort.Strings(d.RemovedAssets)
+
+	d.Changed = d.TitleChanged || d.StatementChanged || d.Limits != nil || d.CheckerChanged || d.RunAllChanged ||
+		d.SubtasksChanged || d.GroupsChanged ||
+		len(d.AddedTestcases)+len(d.RemovedTestcases)+len(d.ChangedTestcases) > 0 ||
+		len(d.AddedAssets)+len(d.RemovedAssets)+len(d.ChangedAssets) > 0
+	return d
+}
+
+func groupSignature(name string, timeLimitMS, memoryLimitKB *int32) string {
+	limit := func(v *int32) string {
+		if v == nil {
+			return "-"
+		}
+		return fmt.Sprint(*v)
+	}
+	return name + "|" + limit(timeLimitMS) + "|" + limit(memoryLimitKB)
+}
+
+func subtaskSignature(name string, score int32, testcaseKeys []string) string {
+	keys := append([]string(nil), testcaseKeys...)
+	sort.Strings(keys)
+	return fmt.Sprintf("%s|%d|%s", name, score, strings.Join(keys, ","))
+}
+
+func equalStringMaps(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || w != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffProblemImport(t *testing.T) {
+	current := &ProblemDetail{
+		ProblemMeta: ProblemMeta{ID: 7, Slug: "two-string", Title: "Two String", TimeLimitMS: 2000, MemoryLimitKB: 262144},
+		StatementMD: "# 問題",
+		CheckerType: CheckerTypeExact,
+	}
+	testcases := []ProblemTestcase{
+		{ID: 1, InputPath: "data/sample/01.in", InputText: "1\n", OutputText: "1\n", IsSample: true},
+		{ID: 2, InputPath: "data/secret/01.in", InputText: "2\n", OutputText: "4\n"},
+		{ID: 3, InputPath: "data/secret/02.in", InputText: "3\n", OutputText: "9\n"},
+	}
+	subtasks := []ProblemSubtask{{Name: "all", Score: 100, TestcaseIDs: []int64{2, 3}}}
+	pkg := ProblemCreateInput{
+		Title:         "Two String",
+		StatementMD:   "# 問題",
+		TimeLimitMS:   3000,
+		MemoryLimitKB: 262144,
+		CheckerType:   CheckerTypeExact,
+		Testcases: []ProblemTestcaseInput{
+			{InputPath: "data/sample/01.in", InputText: "1\n", OutputText: "1\n", IsSample: true},
+			{InputPath: "data/secret/01.in", InputText: "2\n", OutputText: "5\n"},
+			{InputPath: "data/secret/03.in", InputText: "4\n", OutputText: "16\n"},
+		},
+		Subtasks: []ProblemSubtaskInput{{Name: "all", Score: 100, TestcaseIndexes: []int{1, 2}}},
+	}
+	d := diffProblemImport(current, testcases, subtasks, nil, pkg)
+	if !d.Changed || d.TitleChanged || d.StatementChanged || d.CheckerChanged || d.GroupsChanged {
+		t.Fatalf("unexpected flags: %+v", d)
+	}
+	if d.Limits == nil || d.Limits.From.TimeLimitMS != 2000 || d.Limits.To.TimeLimitMS != 3000 {
+		t.Fatalf("limits = %+v", d.Limits)
+	}
+	if !reflect.DeepEqual(d.AddedTestcases, []string{"secret/03"}) || !reflect.DeepEqual(d.RemovedTestcases, []string{"secret/02"}) ||
+		!reflect.DeepEqual(d.ChangedTestcases, []string{"secret/01"}) || !d.SubtasksChanged {
+		t.Fatalf("unexpected testcase diff: %+v", d)
+	}
+
+	pkg.TimeLimitMS = 2000
+	pkg.Testcases[1].OutputText, pkg.Testcases[2] = "4\n", ProblemTestcaseInput{InputPath: "data/secret/02.in", InputText: "3\n", OutputText: "9\n"}
+	if d := diffProblemImport(current, testcases, subtasks, nil, pkg); d.Changed {
+		t.Fatalf("identical package reported as changed: %+v", d)
+	}
+}
@@ -0,0 +1,40 @@
+package core
+
+import "strings"
+
+// OutputNormalization reports what normalize_outputs changed in one expected output.
+type OutputNormalization struct {
+	Testcase                string `json:"testcase"`
+	CRLFLines               int    `json:"crlf_lines"`                // CRLF / CR を LF にした行数
+	TrailingWhitespaceLines int    `json:"trailing_whitespace_lines"` // 行末空白を削除した行数
+	FinalNewlineAdded       bool   `json:"final_newline_added"`
+}
+
+func (n OutputNormalization) changed() bool {
+	return n.CRLFLines > 0 || n.TrailingWhitespaceLines > 0 || n.FinalNewlineAdded
+}
+
+// normalizeExpectedOutput converts CRLF/CR to LF, strips trailing spaces and tabs from every line
+// and ensures the output ends with a newline.
+func normalizeExpectedOutput(s string) (string, OutputNormalization) {
+	var n OutputNormalization
+	if strings.Contains(s, "\r") {
+		n.CRLFLines = strings.Count(s, "\r")
+		s = strings.ReplaceAll(s, "\r\n", "\n")
+		s = strings.ReplaceAll(s, "\r", "\n")
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimRight(line, " \t")
+		if trimmed != line {
+			n.TrailingWhitespaceLines++
+			lines[i] = trimmed
+		}
+	}
+	s = strings.Join(lines, "\n")
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		s += "\n"
+		n.FinalNewlineAdded = true
+	}
+	return s, n
+}
@@ -0,0 +1,249 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Publish states of a problem (problems.publish_state). Changes by a role without
+// PermProblemsPublish to a published problem are kept as a pending update / package in draft, the
+// setter submits them for review and an admin approves (applies) or rejects them. A problem that has
+// never been approved (published_at NULL) is only visible to staff.
+const (
+	ProblemStateDraft     = "draft"
+	ProblemStateReview    = "review"
+	ProblemStatePublished = "published"
+)
+
+var (
+	// ErrProblemInReview is returned for changes to a problem waiting for review.
+	ErrProblemInReview = errors.New("problem is in review")
+	// ErrProblemReviewState is returned when a review transition does not apply to the current state.
+	ErrProblemReviewState = errors.New("invalid review state")
+)
+
+// ProblemPublishStatus is the review state of a problem.
+type ProblemPublishStatus struct {
+	ProblemID   int64      `json:"problem_id"`
+	State       string     `json:"state"`
+	PublishedAt *time.Time `json:"published_at"`
+	// PendingUpdate holds the staged field changes, nil when there are none.
+	PendingUpdate *ProblemUpdateInput `json:"pending_update"`
+	// PendingPackage is the staged package import (see ProblemImportDiff for a summary).
+	PendingPackage *ProblemCreateInput `json:"-"`
+	HasPackage     bool                `json:"pending_package"`
+	SubmittedBy    *int64              `json:"submitted_by"`
+	SubmittedAt    *time.Time          `json:"submitted_at"`
+	ReviewedBy     *int64              `json:"reviewed_by"`
+	ReviewedAt     *time.Time          `json:"reviewed_at"`
+	ReviewNote     string              `json:"review_note"`
+}
+
+// hasPendingChanges reports whether anything is staged for a published problem.
+func (s ProblemPublishStatus) hasPendingChanges() bool {
+	return s.PendingUpdate != nil || s.PendingPackage != nil
+}
+
+// lockProblemForChange locks the problem row and reports whether a change should be staged rather
+// than applied: a problem in review accepts no changes, and with reviewRequired the changes to a
+// problem that has been published are staged. Never published drafts are edited in place.
+func lockProblemForChange(ctx context.Context, tx pgx.Tx, id int64, reviewRequired bool) (bool, error) {
+	var state string
+	var live bool
+	if err := tx.QueryRow(ctx, `SELECT publish_state, published_at IS NOT NULL FROM problems WHERE id=$1 FOR UPDATE`, id).Scan(&state, &live); err != nil {
+		return false, err
+	}
+	if state == ProblemStateReview {
+		return false, ErrProblemInReview
+	}
+	return reviewRequired && live, nil
+}
+
+// mergeProblemUpdate returns base with the fields set in over replacing its own.
+func mergeProblemUpdate(base, over ProblemUpdateInput) ProblemUpdateInput {
+	b := reflect.ValueOf(&base).Elem()
+	o := reflect.ValueOf(over)
+	for i := 0; i < o.NumField(); i++ {
+		if f := o.Field(i); f.Kind() == reflect.Pointer && !f.IsNil() {
+			b.Field(i).Set(f)
+		}
+	}
+	base.ReviewRequired = false
+	return base
+}
+
+// stageProblemUpdate merges input into the pending update of the problem and returns it to draft.
+func stageProblemUpdate(ctx context.Context, tx pgx.Tx, id int64, input ProblemUpdateInput) error {
+	var raw []byte
+	if err := tx.QueryRow(ctx, `SELECT pending_update FROM problems WHERE id=$1`, id).Scan(&raw); err != nil {
+		return err
+	}
+	var pending ProblemUpdateInput
+	if raw != nil {
+		if err := json.Unmarshal(raw, &pending); err != nil {
+			return err
+		}
+	}
+	b, err := json.Marshal(mergeProblemUpdate(pending, input))
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `UPDATE problems SET pending_update=$2, publish_state='draft' WHERE id=$1`, id, b)
+	return err
+}
+
+// stageProblemPackage keeps a validated package as the pending package of the problem, replacing
+// any earlier one, and returns it to draft.
+func stageProblemPackage(ctx context.Context, tx pgx.Tx, id int64, input ProblemCreateInput) error {
+	input.NormalizedOutputs, input.StatementSanitized = nil, nil
+	b, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `UPDATE problems SET pending_package=$2, publish_state='draft' WHERE id=$1`, id, b)
+	return err
+}
+
+// PublishStatus returns the review state of the problem with its pending changes.
+func (r *PgProblemRepository) PublishStatus(ctx context.Context, id int64) (*ProblemPublishStatus, error) {
+	return loadPublishStatus(ctx, r.db, id, false)
+}
+
+func loadPublishStatus(ctx context.Context, q pgQuerier, id int64, lock bool) (*ProblemPublishStatus, error) {
+	query := `
+SELECT id, publish_state, published_at, pending_update, pending_package,
+       review_submitted_by, review_submitted_at, reviewed_by, reviewed_at, review_note
+FROM problems WHERE id=$1 AND deleted_at IS NULL`
+	if lock {
+		query += ` FOR UPDATE`
+	}
+	var s ProblemPublishStatus
+	var update, pkg []byte
+	if err := q.QueryRow(ctx, query, id).Scan(&s.ProblemID, &s.State, &s.PublishedAt, &update, &pkg,
+		&s.SubmittedBy, &s.SubmittedAt, &s.ReviewedBy, &s.ReviewedAt, &s.ReviewNote); err != nil {
+		return nil, err
+	}
+	if update != nil {
+		s.PendingUpdate = &ProblemUpdateInput{}
+		if err := json.Unmarshal(update, s.PendingUpdate); err != nil {
+			return nil, err
+		}
+	}
+	if pkg != nil {
+		s.PendingPackage = &ProblemCreateInput{}
+		if err := json.Unmarshal(pkg, s.PendingPackage); err != nil {
+			return nil, err
+		}
+		s.HasPackage = true
+	}
+	return &s, nil
+}
+
+// SubmitForReview moves a draft with something to review (pending changes, or a problem never
+// published) to review.
+func (r *PgProblemRepository) SubmitForReview(ctx context.Context, id, by int64) error {
+	return r.reviewTransition(ctx, id, func(tx pgx.Tx, s *ProblemPublishStatus) error {
+		if s.State != ProblemStateDraft {
+			return fmt.Errorf("%w: 下書きの問題のみ審査に出せます", ErrProblemReviewState)
+		}
+		if s.PublishedAt != nil && !s.hasPendingChanges() {
+			return fmt.Errorf("%w: 審査待ちの変更がありません", ErrProblemReviewState)
+		}
+		_, err := tx.Exec(ctx, `UPDATE problems SET publish_state='review', review_submitted_by=$2, review_submitted_at=NOW(), review_note='' WHERE id=$1`, id, by)
+		return err
+	})
+}
+
+// WithdrawReview returns a problem in review to draft so its changes can be edited again.
+func (r *PgProblemRepository) WithdrawReview(ctx context.Context, id int64) error {
+	return r.reviewTransition(ctx, id, func(tx pgx.Tx, s *ProblemPublishStatus) error {
+		if s.State != ProblemStateReview {
+			return fmt.Errorf("%w: 審査中ではありません", ErrProblemReviewState)
+		}
+		_, err := tx.Exec(ctx, `UPDATE problems SET publish_state='draft' WHERE id=$1`, id)
+		return err
+	})
+}
+
+// ApproveReview applies the pending package and then the pending update, and publishes the problem.
+func (r *PgProblemRepository) ApproveReview(ctx context.Context, id, by int64) error {
+	return r.reviewTransition(ctx, id, func(tx pgx.Tx, s *ProblemPublishStatus) error {
+		if s.State != ProblemStateReview {
+			return fmt.Errorf("%w: 審査中ではありません", ErrProblemReviewState)
+		}
+		if s.PendingPackage != nil {
+			if err := validateProblemCreateInput(s.PendingPackage); err != nil {
+				return err
+			}
+			if err := replaceWithPackageTx(ctx, tx, id, *s.PendingPackage, s.SubmittedBy); err != nil {
+				return err
+			}
+		}
+		if s.PendingUpdate != nil {
+			sets, args, err := problemUpdateSets(ctx, tx, id, *s.PendingUpdate)
+			if err != nil {
+				return err
+			}
+			if len(sets) > 0 {
+				if err := applyProblemUpdateTx(ctx, tx, id, *s.PendingUpdate, sets, args); err != nil {
+					return err
+				}
+			}
+		}
+		_, err := tx.Exec(ctx, `UPDATE problems SET publish_state='published', published_at=COALESCE(published_at, NOW()),
+    pending_update=NULL, pending_package=NULL, reviewed_by=$2, reviewed_at=NOW(), review_note=''
+WHERE id=$1`, id, by)
+		return err
+	})
+}
+
+// RejectReview returns a problem in review to draft with the reviewer's note. The pending changes
+// are kept so the setter can revise and resubmit them.
+func (r *PgProblemRepository) RejectReview(ctx context.Context, id, by int64, note string) error {
+	return r.reviewTransition(ctx, id, func(tx pgx.Tx, s *ProblemPublishStatus) error {
+		if s.State != ProblemStateReview {
+			return fmt.Errorf("%w: 審査中ではありません", ErrProblemReviewState)
+		}
+		_, err := tx.Exec(ctx, `UPDATE problems SET publish_state='draft', reviewed_by=$2, reviewed_at=NOW(), review_note=$3 WHERE id=$1`, id, by, note)
+		return err
+	})
+}
+
+// DiscardPendingChanges drops the staged changes of a draft, leaving the published problem as is.
+func (r *PgProblemRepository) DiscardPendingChanges(ctx context.Context, id int64) error {
+	return r.reviewTransition(ctx, id, func(tx pgx.Tx, s *ProblemPublishStatus) error {
+		if s.State == ProblemStateReview {
+			return ErrProblemInReview
+		}
+		state := ProblemStatePublished
+		if s.PublishedAt == nil {
+			state = ProblemStateDraft
+		}
+		_, err := tx.Exec(ctx, `UPDATE problems SET publish_state=$2, pending_update=NULL, pending_package=NULL WHERE id=$1`, id, state)
+		return err
+	})
+}
+
+// reviewTransition runs fn on the locked review state of the problem in one transaction.
+func (r *PgProblemRepository) reviewTransition(ctx context.Context, id int64, fn func(pgx.Tx, *ProblemPublishStatus) error) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	s, err := loadPublishStatus(ctx, tx, id, true)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx, s); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
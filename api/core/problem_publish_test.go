@@ -0,0 +1,30 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestStagedProblemUpdateMergesAndHidesDrafts(t *testing.T) {
+	title, oldTitle := "新しい題名", "古い題名"
+	limit := int32(3000)
+	editor := int64(7)
+	pending := mergeProblemUpdate(ProblemUpdateInput{Title: &oldTitle, TimeLimitMS: &limit}, ProblemUpdateInput{Title: &title, EditedBy: &editor, ReviewRequired: true})
+
+	raw, err := json.Marshal(pending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(raw); got != `{"title":"新しい題名","time_limit_ms":3000,"edited_by":7}` {
+		t.Fatalf("pending update = %s", got)
+	}
+
+	draft := ProblemVisibility{ProblemID: 1, IsPublic: true, Unpublished: true}
+	if draft.AccessFor(false, true, time.Now()).Visible {
+		t.Fatal("unpublished problem must be hidden from users")
+	}
+	if !draft.AccessFor(true, false, time.Now()).Visible {
+		t.Fatal("staff must see unpublished problems")
+	}
+}
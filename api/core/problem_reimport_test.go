@@ -0,0 +1,36 @@
+package core
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReadProblemImportFile(t *testing.T) {
+	upload := func(field string, size int) *gin.Context {
+		body := &bytes.Buffer{}
+		w := multipart.NewWriter(body)
+		part, _ := w.CreateFormFile(field, "a.zip")
+		_, _ = part.Write([]byte(strings.Repeat("x", size)))
+		_ = w.Close()
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("POST", "/api/v1/admin/problems/1/import", body)
+		c.Request.Header.Set("Content-Type", w.FormDataContentType())
+		return c
+	}
+	cfg := Config{ProblemImportMaxMB: 1}
+
+	if data, ok := readProblemImportFile(upload("file", 1024), cfg); !ok || len(data) != 1024 {
+		t.Fatalf("read = %d bytes, ok=%t", len(data), ok)
+	}
+	if _, ok := readProblemImportFile(upload("file", 1<<20+1), cfg); ok {
+		t.Fatal("oversized upload was accepted")
+	}
+	if _, ok := readProblemImportFile(upload("zip", 10), cfg); ok {
+		t.Fatal("upload without the file field was accepted")
+	}
+}
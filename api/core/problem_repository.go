@@ -14,16 +14,41 @@ import (
 )
 
 type ProblemRepository interface {
-	ExistsAndPublic(ctx context.Context, id int64) (bool, error)
+	Visibility(ctx context.Context, id int64) (*ProblemVisibility, error)
 	Exists(ctx context.Context, id int64) (bool, error)
-	ListPublic(ctx context.Context) ([]ProblemMeta, error)
+	IDBySlug(ctx context.Context, slug string) (int64, error)
+	ListPublic(ctx context.Context, f ProblemListFilter) ([]ProblemMeta, error)
 	FindDetail(ctx context.Context, id int64) (*ProblemDetail, error)
 	FindDetailAdmin(ctx context.Context, id int64) (*ProblemDetail, error)
 	ListTestcases(ctx context.Context, id int64) ([]ProblemTestcase, error)
+	TestcaseCount(ctx context.Context, id int64) (int, error)
+	EachTestcase(ctx context.Context, id int64, fn func(ProblemTestcase) error) error
+	TestcasesAddedSince(ctx context.Context, id int64, since time.Time) (bool, error)
+	ListAssets(ctx context.Context, id int64) ([]ProblemAsset, error)
+	GetAsset(ctx context.Context, id int64, name string) (*ProblemAsset, error)
+	ListSubtasks(ctx context.Context, id int64) ([]ProblemSubtask, error)
 	CreateWithTestcases(ctx context.Context, input ProblemCreateInput) (int64, error)
+	ReplaceWithPackage(ctx context.Context, id int64, input ProblemCreateInput, editedBy *int64) error
 	UpdateProblem(ctx context.Context, id int64, input ProblemUpdateInput) error
 	AdminList(ctx context.Context, page, perPage int) ([]ProblemAdminListItem, int, error)
 	ProblemStats(ctx context.Context, id int64) (*ProblemStats, error)
+	StatementVersions(ctx context.Context, id int64) ([]ProblemStatementVersion, error)
+	StatementVersion(ctx context.Context, id, versionID int64) (*ProblemStatementVersion, error)
+	InRunningContest(ctx context.Context, id int64, now time.Time) (bool, error)
+	ProblemRevisions(ctx context.Context, id int64) ([]ProblemRevision, error)
+	ProblemRevision(ctx context.Context, id int64, revision int) (*ProblemRevision, error)
+	TestcasePackage(ctx context.Context, id int64, pending bool) (*ProblemCreateInput, bool, error)
+	EditTestcases(ctx context.Context, id int64, edit TestcaseEdit, editedBy *int64, reviewRequired bool) (bool, error)
+	GetEditorial(ctx context.Context, id int64) (*ProblemEditorial, error)
+	SetEditorial(ctx context.Context, id int64, input ProblemEditorialInput) (*ProblemEditorial, error)
+	SandboxPolicy(ctx context.Context, id int64) (*SandboxPolicy, error)
+	SetSandboxPolicy(ctx context.Context, id int64, p *SandboxPolicy) error
+	PublishStatus(ctx context.Context, id int64) (*ProblemPublishStatus, error)
+	SubmitForReview(ctx context.Context, id, by int64) error
+	WithdrawReview(ctx context.Context, id int64) error
+	ApproveReview(ctx context.Context, id, by int64) error
+	RejectReview(ctx context.Context, id, by int64, note string) error
+	DiscardPendingChanges(ctx context.Context, id int64) error
 }
 
 type PgProblemRepository struct {
@@ -34,17 +59,24 @@ func NewPgProblemRepository(db *pgxpool.Pool) *PgProblemRepository {
 	return &PgProblemRepository{db: db}
 }
 
-func (r *PgProblemRepository) ExistsAndPublic(ctx context.Context, id int64) (bool, error) {
-	const q = `SELECT is_public FROM problems WHERE id=$1`
-	var isPublic bool
-	if err := r.db.QueryRow(ctx, q, id).Scan(&isPublic); err != nil {
-		return false, err
+// Visibility returns the inputs of the visibility policy (see ProblemVisibility.AccessFor).
+func (r *PgProblemRepository) Visibility(ctx context.Context, id int64) (*ProblemVisibility, error) {
+	const q = `
+SELECT p.id, p.is_public, p.contest_id, COALESCE(c.is_public, FALSE),
+       COALESCE(c.start_at, 'epoch'::timestamptz), COALESCE(c.end_at, 'epoch'::timestamptz), p.published_at IS NULL,
+       COALESCE((SELECT MAX(lc.end_at) FROM contest_problems cp JOIN contests lc ON lc.id = cp.contest_id WHERE cp.problem_id = p.id), 'epoch'::timestamptz)
+FROM problems p
+LEFT JOIN contests c ON c.id = p.contest_id
+WHERE p.id=$1 AND p.deleted_at IS NULL`
+	var v ProblemVisibility
+	if err := r.db.QueryRow(ctx, q, id).Scan(&v.ProblemID, &v.IsPublic, &v.ContestID, &v.ContestPublic, &v.ContestStart, &v.ContestEnd, &v.Unpublished, &v.ContestsEnd); err != nil {
+		return nil, err
 	}
-	return isPublic, nil
+	return &v, nil
 }
 
 func (r *PgProblemRepository) Exists(ctx context.Context, id int64) (bool, error) {
-	const q = `SELECT 1 FROM problems WHERE id=$1`
+	const q = `SELECT 1 FROM problems WHERE id=$1 AND deleted_at IS NULL`
 	var one int
 	if err := r.db.QueryRow(ctx, q, id).Scan(&one); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -55,12 +87,22 @@ func (r *PgProblemRepository) Exists(ctx context.Context, id int64) (bool, error
 	return true, nil
 }
 
+// IDBySlug resolves a (non-deleted) problem by slug. Slugs survive export / import while IDs do not.
+func (r *PgProblemRepository) IDBySlug(ctx context.Context, slug string) (int64, error) {
+	var id int64
+	err := r.db.QueryRow(ctx, `SELECT id FROM problems WHERE slug=$1 AND deleted_at IS NULL`, strings.ToLower(strings.TrimSpace(slug))).Scan(&id)
+	return id, err
+}
+
 type ProblemMeta struct {
-	ID            int64  `json:"id"`
-	Slug          string `json:"slug"`
-	Title         string `json:"title"`
-	TimeLimitMS   int32  `json:"time_limit_ms"`
-	MemoryLimitKB int32  `json:"memory_limit_kb"`
+	ID    int64  `json:"id"`
+	Slug  string `json:"slug"`
+	Title string `json:"title"`
+	LocalizedTitles
+	TimeLimitMS   int32    `json:"time_limit_ms"`
+	MemoryLimitKB int32    `json:"memory_limit_kb"`
+	Difficulty    *int     `json:"difficulty"`
+	Tags          []string `json:"tags"`
 }
 
 type ProblemDetail struct {
@@ -69,6 +111,13 @@ type ProblemDetail struct {
 	Samples     []SampleCase
 	CheckerType string
 	CheckerEps  float64
+	// CheckerSource is the checker.cpp of a custom checker (empty otherwise).
+	CheckerSource string
+	// RunAllTestcases makes the worker run every testcase instead of stopping at the first failure.
+	RunAllTestcases bool
+	// EditorialMD and EditorialVisibility are exported with the package (see problem_editorial.go).
+	EditorialMD         string
+	EditorialVisibility string
 }
 
 type SampleCase struct {
@@ -78,12 +127,16 @@ type SampleCase struct {
 
 // ProblemAdminListItem represents admin-visible problem summary with counts.
 type ProblemAdminListItem struct {
-	ID              int64  `json:"id"`
-	Slug            string `json:"slug"`
-	Title           string `json:"title"`
-	Visibility      string `json:"visibility"`
-	SolvedCount     int    `json:"solved_count"`
-	SubmissionCount int    `json:"submission_count"`
+	ID    int64  `json:"id"`
+	Slug  string `json:"slug"`
+	Title string `json:"title"`
+	LocalizedTitles
+	Visibility      string   `json:"visibility"`
+	ContestID       *int64   `json:"contest_id"`
+	SolvedCount     int      `json:"solved_count"`
+	SubmissionCount int      `json:"submission_count"`
+	Difficulty      *int     `json:"difficulty"`
+	Tags            []string `json:"tags"`
 }
 
 // ProblemStats aggregates submission statistics for a problem.
@@ -97,29 +150,64 @@ type ProblemStats struct {
 	AcceptanceRate      float64        `json:"acceptance_rate"`
 	LastSubmissionAt    *time.Time     `json:"last_submission_at"`
 	StatusBreakdown     map[string]int `json:"status_breakdown"`
+	CloseCalls          CloseCallStats `json:"close_calls"`
+}
+
+// CloseCallStats counts AC submissions that used at least 95% of the time or memory limit.
+type CloseCallStats struct {
+	Total      int            `json:"total"`
+	Time       int            `json:"time"`
+	Memory     int            `json:"memory"`
+	ByLanguage map[string]int `json:"by_language"`
 }
 
 // ProblemTestcase represents a single testcase path pair.
 type ProblemTestcase struct {
+	ID         int64
 	InputPath  string
 	OutputPath string
 	InputText  string
 	OutputText string
 	IsSample   bool
+	// GroupName and the limits are set when the testcase belongs to a group; nil limits inherit the problem's.
+	GroupName     string
+	TimeLimitMS   *int32
+	MemoryLimitKB *int32
 }
 
 // ProblemCreateInput represents a new problem and all testcases to be inserted atomically.
 type ProblemCreateInput struct {
-	Title         string
-	Slug          string
-	StatementMD   string
-	StatementPath *string
-	TimeLimitMS   int32
-	MemoryLimitKB int32
-	IsPublic      bool
-	CheckerType   string
-	CheckerEps    float64
-	Testcases     []ProblemTestcaseInput
+	Title           string
+	TitleJA         string // 空なら未設定
+	TitleEN         string
+	Slug            string
+	StatementMD     string
+	StatementPath   *string
+	TimeLimitMS     int32
+	MemoryLimitKB   int32
+	IsPublic        bool
+	CheckerType     string
+	CheckerEps      float64
+	CheckerSource   string
+	RunAllTestcases bool
+	Difficulty      *int // nil なら未設定
+	// EditorialMD is the editorial.md of the package; nil keeps the current editorial on overwrite.
+	EditorialMD         *string
+	EditorialVisibility string // auto / public / hidden (editorial_public of problem.yaml); "" keeps the current one
+	Tags                []string
+	Testcases           []ProblemTestcaseInput
+	Subtasks            []ProblemSubtaskInput
+	Groups              []ProblemTestcaseGroupInput
+	Assets              []ProblemAsset
+	// NormalizedOutputs reports the expected outputs changed by normalize_outputs at import (not stored).
+	NormalizedOutputs []OutputNormalization
+	// StatementSanitized lists what was stripped from statement.md (and editorial.md) at import (not stored).
+	StatementSanitized []string
+	// ReviewRequired creates the problem as an unpublished draft, or stages the package of a published
+	// problem until an admin approves it (see problem_publish.go).
+	ReviewRequired bool `json:"-"`
+	// RollbackTo is the revision the package was rebuilt from when rolling a problem back (see problem_revision.go).
+	RollbackTo int
 }
 
 // ProblemTestcaseInput holds inline testcase content for creation.
@@ -131,20 +219,50 @@ type ProblemTestcaseInput struct {
 	IsSample   bool
 }
 
-// ProblemUpdateInput holds mutable fields for a problem.
+// ProblemUpdateInput holds mutable fields for a problem. The JSON form is the pending update kept
+// for review (problems.pending_update).
 type ProblemUpdateInput struct {
-	Title         *string
-	StatementMD   *string
-	TimeLimitMS   *int32
-	MemoryLimitKB *int32
-	IsPublic      *bool
-	CheckerType   *string
-	CheckerEps    *float64
+	Title           *string  `json:"title,omitempty"`
+	TitleJA         *string  `json:"title_ja,omitempty"` // 空文字で解除
+	TitleEN         *string  `json:"title_en,omitempty"`
+	StatementMD     *string  `json:"statement_md,omitempty"`
+	TimeLimitMS     *int32   `json:"time_limit_ms,omitempty"`
+	MemoryLimitKB   *int32   `json:"memory_limit_kb,omitempty"`
+	IsPublic        *bool    `json:"is_public,omitempty"`
+	CheckerType     *string  `json:"checker_type,omitempty"`
+	CheckerEps      *float64 `json:"checker_eps,omitempty"`
+	CheckerSource   *string  `json:"checker_source,omitempty"`
+	RunAllTestcases *bool    `json:"run_all_testcases,omitempty"`
+	// Difficulty sets the difficulty (1-10); 0 clears it.
+	Difficulty *int `json:"difficulty,omitempty"`
+	// Tags replaces the tags of the problem; an empty list removes them.
+	Tags *[]string `json:"tags,omitempty"`
+	// ContestID ties the problem's visibility window to a contest; 0 clears it.
+	ContestID *int64 `json:"contest_id,omitempty"`
+	// EditedBy is recorded on the statement version saved when statement_md changes.
+	EditedBy *int64 `json:"edited_by,omitempty"`
+	// ReviewRequired stages the change of a published problem until an admin approves it instead of
+	// applying it (see problem_publish.go).
+	ReviewRequired bool `json:"-"`
 }
 
-func (r *PgProblemRepository) ListPublic(ctx context.Context) ([]ProblemMeta, error) {
-	const q = `SELECT id, slug, title, time_limit_ms, memory_limit_kb FROM problems WHERE is_public = TRUE ORDER BY id`
-	rows, err := r.db.Query(ctx, q)
+// ListPublic returns the listed problems; a non-empty f.Tag keeps only the problems with that tag.
+func (r *PgProblemRepository) ListPublic(ctx context.Context, f ProblemListFilter) ([]ProblemMeta, error) {
+	order, ok := problemListOrder(f.Sort)
+	if !ok {
+		order = "p.id"
+	}
+	// コンテストに紐付く問題は終了後にのみ練習問題として一覧に出す
+	q := `
+SELECT p.id, p.slug, p.title, p.title_ja, p.title_en, p.time_limit_ms, p.memory_limit_kb, p.difficulty, ` + problemTagsColumn + `
+FROM problems p
+LEFT JOIN contests c ON c.id = p.contest_id
+WHERE p.deleted_at IS NULL AND p.published_at IS NOT NULL
+  AND ((p.contest_id IS NULL AND p.is_public = TRUE)
+       OR (c.is_public = TRUE AND c.end_at <= NOW()))
+  AND ($1 = '' OR EXISTS (SELECT 1 FROM problem_tags pt JOIN tags t ON t.id = pt.tag_id WHERE pt.problem_id = p.id AND t.name = $1))
+ORDER BY ` + order
+	rows, err := r.db.Query(ctx, q, strings.ToLower(strings.TrimSpace(f.Tag)))
 	if err != nil {
 		return nil, err
 	}
@@ -153,7 +271,7 @@ func (r *PgProblemRepository) ListPublic(ctx context.Context) ([]ProblemMeta, er
 	var out []ProblemMeta
 	for rows.Next() {
 		var p ProblemMeta
-		if err := rows.Scan(&p.ID, &p.Slug, &p.Title, &p.TimeLimitMS, &p.MemoryLimitKB); err != nil {
+		if err := rows.Scan(&p.ID, &p.Slug, &p.Title, &p.TitleJA, &p.TitleEN, &p.TimeLimitMS, &p.MemoryLimitKB, &p.Difficulty, &p.Tags); err != nil {
 			return nil, err
 		}
 		out = append(out, p)
@@ -167,19 +285,21 @@ func (r *PgProblemRepository) AdminList(ctx context.Context, page, perPage int)
 		return nil, 0, errors.New("invalid pagination")
 	}
 
-	const countQ = `SELECT COUNT(*) FROM problems`
+	const countQ = `SELECT COUNT(*) FROM problems WHERE deleted_at IS NULL`
 	var total int
 	if err := r.db.QueryRow(ctx, countQ).Scan(&total); err != nil {
 		return nil, 0, err
 	}
 
 	const q = `
-SELECT p.id, p.slug, p.title, p.is_public,
-       COALESCE(SUM(CASE WHEN sr.verdict='AC' THEN 1 ELSE 0 END),0) AS solved_count,
-       COALESCE(COUNT(s.id),0) AS submission_count
+SELECT p.id, p.slug, p.title, p.title_ja, p.title_en, p.is_public, p.contest_id, p.difficulty,
+       COALESCE(SUM(CASE WHEN sr.verdict='` + VerdictAC + `' THEN 1 ELSE 0 END),0) AS solved_count,
+       COALESCE(COUNT(s.id),0) AS submission_count,
+       ` + problemTagsColumn + `
 FROM problems p
 LEFT JOIN submissions s ON s.problem_id = p.id
 LEFT JOIN submission_results sr ON sr.submission_id = s.id
+WHERE p.deleted_at IS NULL
 GROUP BY p.id
 ORDER BY p.id
 LIMIT $1 OFFSET $2`
@@ -193,12 +313,15 @@ LIMIT $1 OFFSET $2`
 	for rows.Next() {
 		var item ProblemAdminListItem
 		var isPublic bool
-		if err := rows.Scan(&item.ID, &item.Slug, &item.Title, &isPublic, &item.SolvedCount, &item.SubmissionCount); err != nil {
+		if err := rows.Scan(&item.ID, &item.Slug, &item.Title, &item.TitleJA, &item.TitleEN, &isPublic, &item.ContestID, &item.Difficulty, &item.SolvedCount, &item.SubmissionCount, &item.Tags); err != nil {
 			return nil, 0, err
 		}
-		if isPublic {
+		switch {
+		case item.ContestID != nil:
+			item.Visibility = "contest"
+		case isPublic:
 			item.Visibility = "public"
-		} else {
+		default:
 			item.Visibility = "hidden"
 		}
 		out = append(out, item)
@@ -207,13 +330,13 @@ LIMIT $1 OFFSET $2`
 }
 
 func (r *PgProblemRepository) findDetail(ctx context.Context, id int64, allowHidden bool) (*ProblemDetail, bool, error) {
-	const q = `SELECT id, slug, title, statement_md, time_limit_ms, memory_limit_kb, is_public, checker_type, checker_eps FROM problems WHERE id=$1`
+	const q = `SELECT p.id, p.slug, p.title, p.title_ja, p.title_en, p.statement_md, p.time_limit_ms, p.memory_limit_kb, p.is_public, p.checker_type, p.checker_eps, COALESCE(p.checker_source, ''), p.run_all_testcases, p.difficulty, p.editorial_md, p.editorial_visibility, ` + problemTagsColumn + ` FROM problems p WHERE p.id=$1`
 	var d ProblemDetail
 	var isPublic bool
 	var statementMD *string
 	var checkerType string
 	var checkerEps float64
-	if err := r.db.QueryRow(ctx, q, id).Scan(&d.ID, &d.Slug, &d.Title, &statementMD, &d.TimeLimitMS, &d.MemoryLimitKB, &isPublic, &checkerType, &checkerEps); err != nil {
+	if err := r.db.QueryRow(ctx, q, id).Scan(&d.ID, &d.Slug, &d.Title, &d.TitleJA, &d.TitleEN, &statementMD, &d.TimeLimitMS, &d.MemoryLimitKB, &isPublic, &checkerType, &checkerEps, &d.CheckerSource, &d.RunAllTestcases, &d.Difficulty, &d.EditorialMD, &d.EditorialVisibility, &d.Tags); err != nil {
 		log.Printf("findDetail problem query err id=%d: %v", id, err)
 		return nil, false, err
 	}
@@ -266,33 +389,68 @@ func (r *PgProblemRepository) FindDetailAdmin(ctx context.Context, id int64) (*P
 
 // ListTestcases returns all testcases (including hidden) for the problem in deterministic order.
 func (r *PgProblemRepository) ListTestcases(ctx context.Context, id int64) ([]ProblemTestcase, error) {
-	const q = `SELECT input_path, output_path, input_text, output_text, is_sample FROM testcases WHERE problem_id=$1 ORDER BY id`
+	var out []ProblemTestcase
+	if err := r.EachTestcase(ctx, id, func(tc ProblemTestcase) error {
+		out = append(out, tc)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TestcasesAddedSince reports whether any testcase of the problem was created after since. Testcases
+// are only ever replaced, never edited, so this tells whether a stored judgement used the current set.
+func (r *PgProblemRepository) TestcasesAddedSince(ctx context.Context, id int64, since time.Time) (bool, error) {
+	var added bool
+	err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM testcases WHERE problem_id=$1 AND created_at > $2)`, id, since).Scan(&added)
+	return added, err
+}
+
+// EachTestcase streams the problem's testcases in ListTestcases order, holding one row at a time.
+// An error returned by fn stops the iteration and is returned as is.
+func (r *PgProblemRepository) EachTestcase(ctx context.Context, id int64, fn func(ProblemTestcase) error) error {
+	const q = `
+SELECT t.id, t.input_path, t.output_path, t.input_text, t.output_text, t.is_sample,
+       COALESCE(g.name, ''), g.time_limit_ms, g.memory_limit_kb
+FROM testcases t
+LEFT JOIN problem_testcase_groups g ON g.id = t.group_id
+WHERE t.problem_id=$1
+ORDER BY t.id`
 	rows, err := r.db.Query(ctx, q, id)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
-	var out []ProblemTestcase
 	for rows.Next() {
+		var id int64
 		var inPath, outPath, inText, outText sql.NullString
 		var isSample bool
-		if err := rows.Scan(&inPath, &outPath, &inText, &outText, &isSample); err != nil {
-			return nil, err
+		var groupName string
+		var timeLimit, memoryLimit *int32
+		if err := rows.Scan(&id, &inPath, &outPath, &inText, &outText, &isSample, &groupName, &timeLimit, &memoryLimit); err != nil {
+			return err
 		}
 		tc := ProblemTestcase{
-			InputPath:  inPath.String,
-			OutputPath: outPath.String,
-			InputText:  inText.String,
-			OutputText: outText.String,
-			IsSample:   isSample,
+			ID:            id,
+			InputPath:     inPath.String,
+			OutputPath:    outPath.String,
+			InputText:     inText.String,
+			OutputText:    outText.String,
+			IsSample:      isSample,
+			GroupName:     groupName,
+			TimeLimitMS:   timeLimit,
+			MemoryLimitKB: memoryLimit,
 		}
 		if strings.TrimSpace(tc.OutputText) == "" {
-			return nil, errors.New("testcase output missing; file path fallback disabled")
+			return errors.New("testcase output missing; file path fallback disabled")
+		}
+		if err := fn(tc); err != nil {
+			return err
 		}
-		out = append(out, tc)
 	}
-	return out, rows.Err()
+	return rows.Err()
 }
 
 // ProblemStats aggregates submission statistics for a problem.
@@ -300,9 +458,9 @@ func (r *PgProblemRepository) ProblemStats(ctx context.Context, id int64) (*Prob
 	const summaryQ = `
 SELECT p.title,
        COALESCE(COUNT(s.id),0) AS submission_count,
-       COALESCE(SUM(CASE WHEN sr.verdict='AC' THEN 1 ELSE 0 END),0) AS accepted_count,
+       COALESCE(SUM(CASE WHEN sr.verdict='` + VerdictAC + `' THEN 1 ELSE 0 END),0) AS accepted_count,
        COALESCE(COUNT(DISTINCT s.user_id),0) AS unique_users,
-       COALESCE(COUNT(DISTINCT CASE WHEN sr.verdict='AC' THEN s.user_id END),0) AS unique_accepted_users,
+       COALESCE(COUNT(DISTINCT CASE WHEN sr.verdict='` + VerdictAC + `' THEN s.user_id END),0) AS unique_accepted_users,
        MAX(s.created_at) AS last_submission_at
 FROM problems p
 LEFT JOIN submissions s ON s.problem_id = p.id
@@ -331,7 +489,11 @@ GROUP BY p.id`
 		return nil, err
 	}
 	defer rows.Close()
+	// 登録済みの判定は 0 件でも含める
 	stats.StatusBreakdown = map[string]int{}
+	for _, v := range Verdicts() {
+		stats.StatusBreakdown[v.Code] = 0
+	}
 	for rows.Next() {
 		var verdict string
 		var count int
@@ -343,55 +505,216 @@ GROUP BY p.id`
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+
+	// close calls (言語ごと: 遅い言語で制限が厳しすぎないかの目安)
+	const closeCallQ = `
+SELECT s.language,
+       COUNT(*),
+       COUNT(*) FILTER (WHERE sr.close_call_time),
+       COUNT(*) FILTER (WHERE sr.close_call_memory)
+FROM submissions s
+JOIN submission_results sr ON sr.submission_id = s.id
+WHERE s.problem_id=$1 AND sr.verdict='` + VerdictAC + `' AND (sr.close_call_time OR sr.close_call_memory)
+GROUP BY s.language`
+	ccRows, err := r.db.Query(ctx, closeCallQ, id)
+	if err != nil {
+		return nil, err
+	}
+	defer ccRows.Close()
+	stats.CloseCalls.ByLanguage = map[string]int{}
+	for ccRows.Next() {
+		var lang string
+		var total, timeCount, memCount int
+		if err := ccRows.Scan(&lang, &total, &timeCount, &memCount); err != nil {
+			return nil, err
+		}
+		stats.CloseCalls.Total += total
+		stats.CloseCalls.Time += timeCount
+		stats.CloseCalls.Memory += memCount
+		stats.CloseCalls.ByLanguage[lang] = total
+	}
+	if err := ccRows.Err(); err != nil {
+		return nil, err
+	}
 	return &stats, nil
 }
 
 // CreateWithTestcases inserts a problem and all its testcases in a single transaction.
 func (r *PgProblemRepository) CreateWithTestcases(ctx context.Context, input ProblemCreateInput) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	problemID, err := insertProblemTx(ctx, tx, input)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return problemID, nil
+}
+
+// insertProblemTx validates input and inserts the problem with its testcases inside tx.
+func insertProblemTx(ctx context.Context, tx pgx.Tx, input ProblemCreateInput) (int64, error) {
+	if err := validateProblemCreateInput(&input); err != nil {
+		return 0, err
+	}
+
+	// 審査が必要な作成は未公開の下書きとして登録し、承認されるまで利用者には見せない
+	state := ProblemStatePublished
+	if input.ReviewRequired {
+		state = ProblemStateDraft
+	}
+	var problemID int64
+	if err := tx.QueryRow(ctx, `INSERT INTO problems (slug, title, title_ja, title_en, statement_path, statement_md, time_limit_ms, memory_limit_kb, is_public, checker_type, checker_eps, checker_source, run_all_testcases, publish_state, published_at, difficulty, editorial_md, editorial_visibility)
+VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14, CASE WHEN $14='published' THEN NOW() END, $15, COALESCE($16, ''), COALESCE(NULLIF($17, ''), 'auto')) RETURNING id`,
+		input.Slug, input.Title, stringPtrIfNotEmpty(input.TitleJA), stringPtrIfNotEmpty(input.TitleEN), input.StatementPath, input.StatementMD, input.TimeLimitMS, input.MemoryLimitKB, input.IsPublic, input.CheckerType, input.CheckerEps, stringPtrIfNotEmpty(input.CheckerSource), input.RunAllTestcases, state, input.Difficulty, input.EditorialMD, input.EditorialVisibility).Scan(&problemID); err != nil {
+		return 0, err
+	}
+	if err := insertProblemContentTx(ctx, tx, problemID, input); err != nil {
+		return 0, err
+	}
+	if err := setProblemTagsTx(ctx, tx, problemID, input.Tags); err != nil {
+		return 0, err
+	}
+	if err := recordProblemRevisionTx(ctx, tx, problemID, ProblemRevisionCreate, true, nil, nil); err != nil {
+		return 0, err
+	}
+	return problemID, nil
+}
+
+// validateProblemCreateInput checks input and normalizes its checker type.
+func validateProblemCreateInput(input *ProblemCreateInput) error {
 	if strings.TrimSpace(input.Title) == "" || strings.TrimSpace(input.Slug) == "" {
-		return 0, errors.New("title and slug are required")
+		return errors.New("title and slug are required")
 	}
 	if len(input.Testcases) == 0 {
-		return 0, errors.New("at least one testcase is required")
+		return errors.New("at least one testcase is required")
 	}
 	if strings.TrimSpace(input.CheckerType) == "" {
 		input.CheckerType = "exact"
 	}
-	input.CheckerType = strings.ToLower(strings.TrimSpace(input.CheckerType))
-	if input.CheckerType != "exact" && input.CheckerType != "eps" {
-		return 0, errors.New("checker_type must be exact or eps")
+	checkerType, ok := validCheckerType(input.CheckerType)
+	if !ok {
+		return errors.New("checker_type must be exact, eps or custom")
 	}
-	if input.CheckerType == "eps" && input.CheckerEps <= 0 {
-		return 0, errors.New("checker_eps must be > 0 when checker_type=eps")
+	input.CheckerType = checkerType
+	if input.CheckerType == CheckerTypeEps {
+		if err := validateCheckerEps(input.CheckerEps); err != nil {
+			return err
+		}
+	}
+	if input.CheckerType == CheckerTypeCustom && strings.TrimSpace(input.CheckerSource) == "" {
+		return errors.New("checker_source is required when checker_type=custom")
 	}
+	for _, tc := range input.Testcases {
+		if strings.TrimSpace(tc.InputText) == "" || strings.TrimSpace(tc.OutputText) == "" {
+			return errors.New("testcase input/output is required")
+		}
+	}
+	if err := checkTestcaseLimits(input.Testcases); err != nil {
+		return err
+	}
+	if err := validateProblemDifficulty(input.Difficulty); err != nil {
+		return err
+	}
+	tags, err := normalizeProblemTags(input.Tags)
+	if err != nil {
+		return err
+	}
+	input.Tags = tags
+	return nil
+}
+
+// insertProblemContentTx inserts the testcases, subtasks, groups and assets of input for problemID.
+func insertProblemContentTx(ctx context.Context, tx pgx.Tx, problemID int64, input ProblemCreateInput) error {
+	testcaseIDs := make([]int64, 0, len(input.Testcases))
+	for _, tc := range input.Testcases {
+		var tcID int64
+		if err := tx.QueryRow(ctx, `INSERT INTO testcases (problem_id, input_path, output_path, input_text, output_text, is_sample)
+VALUES ($1,$2,$3,$4,$5,$6) RETURNING id`, problemID, nonNilString(tc.InputPath), nonNilString(tc.OutputPath), tc.InputText, tc.OutputText, tc.IsSample).Scan(&tcID); err != nil {
+			return err
+		}
+		testcaseIDs = append(testcaseIDs, tcID)
+	}
+	if err := insertSubtasksTx(ctx, tx, problemID, input.Subtasks, testcaseIDs); err != nil {
+		return err
+	}
+	if err := insertTestcaseGroupsTx(ctx, tx, problemID, input.Groups, testcaseIDs); err != nil {
+		return err
+	}
+	return insertProblemAssetsTx(ctx, tx, problemID, input.Assets)
+}
 
+// ReplaceWithPackage overwrites an existing problem with an imported package: its settings,
+// statement (the previous one is kept in the history), testcases, subtasks, groups and assets.
+// Visibility and the contest link are left as they are. With input.ReviewRequired the package of a
+// published problem is only staged for review.
+func (r *PgProblemRepository) ReplaceWithPackage(ctx context.Context, id int64, input ProblemCreateInput, editedBy *int64) error {
+	if err := validateProblemCreateInput(&input); err != nil {
+		return err
+	}
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
-		return 0, err
+		return err
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
-	var problemID int64
-	if err := tx.QueryRow(ctx, `INSERT INTO problems (slug, title, statement_path, statement_md, time_limit_ms, memory_limit_kb, is_public, checker_type, checker_eps)
-VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9) RETURNING id`,
-		input.Slug, input.Title, input.StatementPath, input.StatementMD, input.TimeLimitMS, input.MemoryLimitKB, input.IsPublic, input.CheckerType, input.CheckerEps).Scan(&problemID); err != nil {
-		return 0, err
+	stage, err := lockProblemForChange(ctx, tx, id, input.ReviewRequired)
+	if err != nil {
+		return err
 	}
-
-	for _, tc := range input.Testcases {
-		if strings.TrimSpace(tc.InputText) == "" || strings.TrimSpace(tc.OutputText) == "" {
-			return 0, errors.New("testcase input/output is required")
-		}
-		if _, err := tx.Exec(ctx, `INSERT INTO testcases (problem_id, input_path, output_path, input_text, output_text, is_sample)
-VALUES ($1,$2,$3,$4,$5,$6)`, problemID, nonNilString(tc.InputPath), nonNilString(tc.OutputPath), tc.InputText, tc.OutputText, tc.IsSample); err != nil {
-			return 0, err
+	if stage {
+		if err := stageProblemPackage(ctx, tx, id, input); err != nil {
+			return err
 		}
+	} else if err := replaceWithPackageTx(ctx, tx, id, input, editedBy); err != nil {
+		return err
 	}
+	return tx.Commit(ctx)
+}
 
-	if err := tx.Commit(ctx); err != nil {
-		return 0, err
+// replaceWithPackageTx applies a validated package to the problem inside tx.
+func replaceWithPackageTx(ctx context.Context, tx pgx.Tx, id int64, input ProblemCreateInput, editedBy *int64) error {
+	if err := ensureProblemBaselineTx(ctx, tx, id); err != nil {
+		return err
+	}
+	if err := saveStatementVersion(ctx, tx, id, input.StatementMD, editedBy); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE problems SET title=$2, title_ja=$3, title_en=$4, statement_md=$5, time_limit_ms=$6, memory_limit_kb=$7,
+    checker_type=$8, checker_eps=$9, checker_source=$10, run_all_testcases=$11, difficulty=$12,
+    editorial_md=COALESCE($13, editorial_md), editorial_visibility=COALESCE(NULLIF($14, ''), editorial_visibility)
+WHERE id=$1`, id, input.Title, stringPtrIfNotEmpty(input.TitleJA), stringPtrIfNotEmpty(input.TitleEN), input.StatementMD, input.TimeLimitMS, input.MemoryLimitKB,
+		input.CheckerType, input.CheckerEps, stringPtrIfNotEmpty(input.CheckerSource), input.RunAllTestcases, input.Difficulty,
+		input.EditorialMD, input.EditorialVisibility); err != nil {
+		return err
+	}
+	// 小課題とテストケースの対応はテストケースの削除に伴って消える
+	for _, q := range []string{
+		`DELETE FROM testcases WHERE problem_id=$1`,
+		`DELETE FROM problem_subtasks WHERE problem_id=$1`,
+		`DELETE FROM problem_testcase_groups WHERE problem_id=$1`,
+		`DELETE FROM problem_assets WHERE problem_id=$1`,
+	} {
+		if _, err := tx.Exec(ctx, q, id); err != nil {
+			return err
+		}
 	}
-	return problemID, nil
+	if err := setProblemTagsTx(ctx, tx, id, input.Tags); err != nil {
+		return err
+	}
+	if err := insertProblemContentTx(ctx, tx, id, input); err != nil {
+		return err
+	}
+	reason, rolledBackTo := ProblemRevisionPackage, (*int)(nil)
+	if input.RollbackTo > 0 {
+		reason, rolledBackTo = ProblemRevisionRollback, &input.RollbackTo
+	}
+	return recordProblemRevisionTx(ctx, tx, id, reason, true, editedBy, rolledBackTo)
 }
 
 func nonNilString(v string) string {
@@ -401,8 +724,38 @@ func nonNilString(v string) string {
 	return v
 }
 
-// UpdateProblem updates mutable fields of a problem.
+// UpdateProblem updates mutable fields of a problem. With input.ReviewRequired the change of a
+// published problem is only staged for review.
 func (r *PgProblemRepository) UpdateProblem(ctx context.Context, id int64, input ProblemUpdateInput) error {
+	sets, args, err := problemUpdateSets(ctx, r.db, id, input)
+	if err != nil {
+		return err
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	stage, err := lockProblemForChange(ctx, tx, id, input.ReviewRequired)
+	if err != nil {
+		return err
+	}
+	if stage {
+		if err := stageProblemUpdate(ctx, tx, id, input); err != nil {
+			return err
+		}
+	} else if err := applyProblemUpdateTx(ctx, tx, id, input, sets, args); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// problemUpdateSets validates input and returns the SET clauses and their arguments.
+func problemUpdateSets(ctx context.Context, q pgQuerier, id int64, input ProblemUpdateInput) ([]string, []any, error) {
 	var sets []string
 	var args []any
 
@@ -410,20 +763,28 @@ func (r *PgProblemRepository) UpdateProblem(ctx context.Context, id int64, input
 		sets = append(sets, "title=$"+strconv.Itoa(len(args)+1))
 		args = append(args, strings.TrimSpace(*input.Title))
 	}
+	if input.TitleJA != nil {
+		sets = append(sets, "title_ja=$"+strconv.Itoa(len(args)+1))
+		args = append(args, stringPtrIfNotEmpty(strings.TrimSpace(*input.TitleJA)))
+	}
+	if input.TitleEN != nil {
+		sets = append(sets, "title_en=$"+strconv.Itoa(len(args)+1))
+		args = append(args, stringPtrIfNotEmpty(strings.TrimSpace(*input.TitleEN)))
+	}
 	if input.StatementMD != nil {
 		sets = append(sets, "statement_md=$"+strconv.Itoa(len(args)+1))
 		args = append(args, *input.StatementMD)
 	}
 	if input.TimeLimitMS != nil {
 		if *input.TimeLimitMS <= 0 {
-			return errors.New("time_limit_ms must be > 0")
+			return nil, nil, errors.New("time_limit_ms must be > 0")
 		}
 		sets = append(sets, "time_limit_ms=$"+strconv.Itoa(len(args)+1))
 		args = append(args, *input.TimeLimitMS)
 	}
 	if input.MemoryLimitKB != nil {
 		if *input.MemoryLimitKB <= 0 {
-			return errors.New("memory_limit_kb must be > 0")
+			return nil, nil, errors.New("memory_limit_kb must be > 0")
 		}
 		sets = append(sets, "memory_limit_kb=$"+strconv.Itoa(len(args)+1))
 		args = append(args, *input.MemoryLimitKB)
@@ -433,26 +794,111 @@ func (r *PgProblemRepository) UpdateProblem(ctx context.Context, id int64, input
 		args = append(args, *input.IsPublic)
 	}
 	if input.CheckerType != nil {
-		ct := strings.ToLower(strings.TrimSpace(*input.CheckerType))
-		if ct != "exact" && ct != "eps" {
-			return errors.New("checker_type must be exact or eps")
+		ct, ok := validCheckerType(*input.CheckerType)
+		if !ok {
+			return nil, nil, errors.New("checker_type must be exact, eps or custom")
+		}
+		if ct == CheckerTypeCustom && input.CheckerSource == nil {
+			var hasSource bool
+			if err := q.QueryRow(ctx, `SELECT COALESCE(checker_source, '') <> '' FROM problems WHERE id=$1`, id).Scan(&hasSource); err != nil {
+				return nil, nil, err
+			}
+			if !hasSource {
+				return nil, nil, errors.New("checker_source is required when checker_type=custom")
+			}
 		}
 		sets = append(sets, "checker_type=$"+strconv.Itoa(len(args)+1))
 		args = append(args, ct)
 	}
-	if input.CheckerEps != nil {
-		if input.CheckerType != nil && strings.ToLower(strings.TrimSpace(*input.CheckerType)) == "eps" && *input.CheckerEps <= 0 {
-			return errors.New("checker_eps must be > 0 when checker_type=eps")
+	if input.CheckerSource != nil {
+		if len(*input.CheckerSource) > maxCheckerSize {
+			return nil, nil, errors.New("checker_source is too large")
+		}
+		if input.CheckerType != nil && strings.ToLower(strings.TrimSpace(*input.CheckerType)) == CheckerTypeCustom && strings.TrimSpace(*input.CheckerSource) == "" {
+			return nil, nil, errors.New("checker_source is required when checker_type=custom")
+		}
+		sets = append(sets, "checker_source=$"+strconv.Itoa(len(args)+1))
+		args = append(args, stringPtrIfNotEmpty(*input.CheckerSource))
+	}
+	if input.CheckerType != nil || input.CheckerEps != nil {
+		// 許容誤差は変更後のチェッカーが eps のときに検証する（それ以外でも桁外れの値は受け付けない）
+		var currentType string
+		var currentEps float64
+		if err := q.QueryRow(ctx, `SELECT checker_type, checker_eps FROM problems WHERE id=$1`, id).Scan(&currentType, &currentEps); err != nil {
+			return nil, nil, err
+		}
+		checkerType, eps := currentType, currentEps
+		if input.CheckerType != nil {
+			checkerType = *input.CheckerType
 		}
+		if input.CheckerEps != nil {
+			eps = *input.CheckerEps
+		}
+		if defaultChecker(checkerType) == CheckerTypeEps || (input.CheckerEps != nil && eps != 0) {
+			if err := validateCheckerEps(eps); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if input.CheckerEps != nil {
 		sets = append(sets, "checker_eps=$"+strconv.Itoa(len(args)+1))
 		args = append(args, *input.CheckerEps)
 	}
+	if input.RunAllTestcases != nil {
+		sets = append(sets, "run_all_testcases=$"+strconv.Itoa(len(args)+1))
+		args = append(args, *input.RunAllTestcases)
+	}
 
-	if len(sets) == 0 {
-		return nil
+	if input.ContestID != nil {
+		sets = append(sets, "contest_id=$"+strconv.Itoa(len(args)+1))
+		if *input.ContestID == 0 {
+			args = append(args, nil)
+		} else {
+			args = append(args, *input.ContestID)
+		}
+	}
+	if input.Difficulty != nil {
+		var difficulty *int
+		if *input.Difficulty != 0 {
+			if err := validateProblemDifficulty(input.Difficulty); err != nil {
+				return nil, nil, err
+			}
+			difficulty = input.Difficulty
+		}
+		sets = append(sets, "difficulty=$"+strconv.Itoa(len(args)+1))
+		args = append(args, difficulty)
+	}
+	// タグは別テーブルなので applyProblemUpdateTx で書き換える（ここでは検証と更新日時のみ）
+	if input.Tags != nil {
+		if _, err := normalizeProblemTags(*input.Tags); err != nil {
+			return nil, nil, err
+		}
+		sets = append(sets, "updated_at=NOW()")
+	}
+
+	return sets, args, nil
+}
+
+// applyProblemUpdateTx writes the clauses built by problemUpdateSets inside tx.
+func applyProblemUpdateTx(ctx context.Context, tx pgx.Tx, id int64, input ProblemUpdateInput, sets []string, args []any) error {
+	if err := ensureProblemBaselineTx(ctx, tx, id); err != nil {
+		return err
+	}
+	// 問題文が変わる場合は更新前の版を履歴に残す
+	if input.StatementMD != nil {
+		if err := saveStatementVersion(ctx, tx, id, *input.StatementMD, input.EditedBy); err != nil {
+			return err
+		}
+	}
+	if input.Tags != nil {
+		if err := setProblemTagsTx(ctx, tx, id, *input.Tags); err != nil {
+			return err
+		}
 	}
 	args = append(args, id)
 	q := "UPDATE problems SET " + strings.Join(sets, ", ") + " WHERE id=$" + strconv.Itoa(len(args))
-	_, err := r.db.Exec(ctx, q, args...)
-	return err
+	if _, err := tx.Exec(ctx, q, args...); err != nil {
+		return err
+	}
+	return recordProblemRevisionTx(ctx, tx, id, ProblemRevisionUpdate, false, input.EditedBy, nil)
 }
@@ -0,0 +1,446 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Reasons a problem revision was recorded.
+const (
+	ProblemRevisionBaseline = "baseline" // 履歴の導入前からある問題の、最初の変更の直前の内容
+	ProblemRevisionCreate   = "create"
+	ProblemRevisionUpdate   = "update"  // PATCH /admin/problems/:id
+	ProblemRevisionPackage  = "package" // パッケージの上書き取り込み
+	ProblemRevisionRollback = "rollback"
+)
+
+// ProblemRevisionSettings are the single-valued fields of a problem kept in each revision. The
+// editorial, visibility, contest link and sandbox policy are not versioned.
+type ProblemRevisionSettings struct {
+	Title           string   `json:"title"`
+	TitleJA         string   `json:"title_ja"`
+	TitleEN         string   `json:"title_en"`
+	StatementMD     string   `json:"statement_md"`
+	TimeLimitMS     int32    `json:"time_limit_ms"`
+	MemoryLimitKB   int32    `json:"memory_limit_kb"`
+	CheckerType     string   `json:"checker_type"`
+	CheckerEps      float64  `json:"checker_eps"`
+	CheckerSource   string   `json:"checker_source"`
+	RunAllTestcases bool     `json:"run_all_testcases"`
+	Difficulty      *int     `json:"difficulty"`
+	Tags            []string `json:"tags"`
+}
+
+// ProblemRevisionContent is the judge data of a revision. It is stored once per distinct content
+// (problem_revision_contents) and shared by the revisions that did not change it.
+type ProblemRevisionContent struct {
+	Testcases []ProblemTestcaseInput
+	Subtasks  []ProblemSubtaskInput
+	Groups    []ProblemTestcaseGroupInput
+	Assets    []ProblemAsset
+}
+
+// ProblemRevision is one entry of the revision history of a problem: its content after an edit.
+type ProblemRevision struct {
+	Revision     int       `json:"revision"`
+	Reason       string    `json:"reason"`
+	RolledBackTo *int      `json:"rolled_back_to,omitempty"`
+	Changes      []string  `json:"changes"` // 直前の版から変わった項目
+	Testcases    int       `json:"testcases"`
+	EditedBy     *int64    `json:"edited_by"`
+	EditorName   *string   `json:"edited_by_userid"`
+	CreatedAt    time.Time `json:"created_at"`
+	// Settings and Content are only loaded for a single revision.
+	Settings *ProblemRevisionSettings `json:"settings,omitempty"`
+	Content  *ProblemRevisionContent  `json:"-"`
+}
+
+// ProblemRevisionDiff compares two revisions of a problem. Changes uses the comparison of package
+// imports, matching testcases by their names.
+type ProblemRevisionDiff struct {
+	From              int                `json:"from"`
+	To                int                `json:"to"`
+	Changes           *ProblemImportDiff `json:"changes"`
+	StatementDiff     string             `json:"statement_diff"` // unified diff（変更がなければ空）
+	AddedLines        int                `json:"added_lines"`
+	RemovedLines      int                `json:"removed_lines"`
+	DifficultyChanged bool               `json:"difficulty_changed"`
+	TagsChanged       bool               `json:"tags_changed"`
+}
+
+// diffProblemRevisions compares from with to; both need their settings and content.
+func diffProblemRevisions(from, to *ProblemRevision) ProblemRevisionDiff {
+	current, testcases, subtasks, assets := revisionAsProblem(from)
+	d := ProblemRevisionDiff{
+		From:              from.Revision,
+		To:                to.Revision,
+		Changes:           diffProblemImport(current, testcases, subtasks, assets, revisionPackageInput("", to)),
+		DifficultyChanged: !reflect.DeepEqual(from.Settings.Difficulty, to.Settings.Difficulty),
+		TagsChanged:       strings.Join(from.Settings.Tags, ",") != strings.Join(to.Settings.Tags, ","),
+	}
+	d.StatementDiff, d.AddedLines, d.RemovedLines = unifiedDiff(from.Settings.StatementMD, to.Settings.StatementMD, 3)
+	return d
+}
+
+// changeNames lists the changed items of d, as stored in problem_revisions.changes.
+func (d ProblemRevisionDiff) changeNames() []string {
+	c := d.Changes
+	names := []string{}
+	for _, item := range []struct {
+		name    string
+		changed bool
+	}{
+		{"title", c.TitleChanged},
+		{"statement", c.StatementChanged},
+		{"limits", c.Limits != nil},
+		{"checker", c.CheckerChanged},
+		{"run_all_testcases", c.RunAllChanged},
+		{"difficulty", d.DifficultyChanged},
+		{"tags", d.TagsChanged},
+		{"testcases", len(c.AddedTestcases)+len(c.RemovedTestcases)+len(c.ChangedTestcases) > 0},
+		{"subtasks", c.SubtasksChanged},
+		{"groups", c.GroupsChanged},
+		{"assets", len(c.AddedAssets)+len(c.RemovedAssets)+len(c.ChangedAssets) > 0},
+	} {
+		if item.changed {
+			names = append(names, item.name)
+		}
+	}
+	return names
+}
+
+// revisionAsProblem presents a revision as the stored problem diffProblemImport compares packages
+// with. Testcase IDs are the 1-based positions.
+func revisionAsProblem(rev *ProblemRevision) (*ProblemDetail, []ProblemTestcase, []ProblemSubtask, []ProblemAsset) {
+	s, c := rev.Settings, rev.Content
+	detail := &ProblemDetail{
+		StatementMD:     s.StatementMD,
+		CheckerType:     s.CheckerType,
+		CheckerEps:      s.CheckerEps,
+		CheckerSource:   s.CheckerSource,
+		RunAllTestcases: s.RunAllTestcases,
+	}
+	detail.Title = s.Title
+	detail.LocalizedTitles = LocalizedTitles{TitleJA: stringPtrIfNotEmpty(s.TitleJA), TitleEN: stringPtrIfNotEmpty(s.TitleEN)}
+	detail.TimeLimitMS, detail.MemoryLimitKB = s.TimeLimitMS, s.MemoryLimitKB
+
+	testcases := make([]ProblemTestcase, len(c.Testcases))
+	for i, tc := range c.Testcases {
+		testcases[i] = ProblemTestcase{ID: int64(i + 1), InputPath: tc.InputPath, OutputPath: tc.OutputPath, InputText: tc.InputText, OutputText: tc.OutputText, IsSample: tc.IsSample}
+	}
+	for _, g := range c.Groups {
+		for _, idx := range g.TestcaseIndexes {
+			if idx >= 0 && idx < len(testcases) {
+				testcases[idx].GroupName, testcases[idx].TimeLimitMS, testcases[idx].MemoryLimitKB = g.Name, g.TimeLimitMS, g.MemoryLimitKB
+			}
+		}
+	}
+	subtasks := make([]ProblemSubtask, len(c.Subtasks))
+	for i, st := range c.Subtasks {
+		subtasks[i] = ProblemSubtask{Name: st.Name, Score: st.Score}
+		for _, idx := range st.TestcaseIndexes {
+			subtasks[i].TestcaseIDs = append(subtasks[i].TestcaseIDs, int64(idx+1))
+		}
+	}
+	return detail, testcases, subtasks, c.Assets
+}
+
+// revisionPackageInput turns a revision back into a package, for rolling the problem back with
+// ReplaceWithPackage. The editorial is left as it is.
+func revisionPackageInput(slug string, rev *ProblemRevision) ProblemCreateInput {
+	s, c := rev.Settings, rev.Content
+	return ProblemCreateInput{
+		Title:           s.Title,
+		TitleJA:         s.TitleJA,
+		TitleEN:         s.TitleEN,
+		Slug:            slug,
+		StatementMD:     s.StatementMD,
+		TimeLimitMS:     s.TimeLimitMS,
+		MemoryLimitKB:   s.MemoryLimitKB,
+		CheckerType:     s.CheckerType,
+		CheckerEps:      s.CheckerEps,
+		CheckerSource:   s.CheckerSource,
+		RunAllTestcases: s.RunAllTestcases,
+		Difficulty:      s.Difficulty,
+		Tags:            s.Tags,
+		Testcases:       c.Testcases,
+		Subtasks:        c.Subtasks,
+		Groups:          c.Groups,
+		Assets:          c.Assets,
+		RollbackTo:      rev.Revision,
+	}
+}
+
+// loadProblemRevisionSettings reads the versioned settings of the problem as they are now.
+func loadProblemRevisionSettings(ctx context.Context, q pgQuerier, id int64) (ProblemRevisionSettings, error) {
+	query := `SELECT p.title, COALESCE(p.title_ja, ''), COALESCE(p.title_en, ''), COALESCE(p.statement_md, ''), p.time_limit_ms, p.memory_limit_kb,
+    p.checker_type, p.checker_eps, COALESCE(p.checker_source, ''), p.run_all_testcases, p.difficulty, ` + problemTagsColumn + `
+FROM problems p WHERE p.id=$1`
+	var s ProblemRevisionSettings
+	if err := q.QueryRow(ctx, query, id).Scan(&s.Title, &s.TitleJA, &s.TitleEN, &s.StatementMD, &s.TimeLimitMS, &s.MemoryLimitKB,
+		&s.CheckerType, &s.CheckerEps, &s.CheckerSource, &s.RunAllTestcases, &s.Difficulty, &s.Tags); err != nil {
+		return s, err
+	}
+	s.CheckerType = strings.TrimSpace(s.CheckerType)
+	if s.Tags == nil {
+		s.Tags = []string{}
+	}
+	return s, nil
+}
+
+// loadProblemRevisionContent reads the testcases, subtasks, groups and assets of the problem as
+// they are now, referring to testcases by their position.
+func loadProblemRevisionContent(ctx context.Context, q pgQuerier, id int64) (ProblemRevisionContent, error) {
+	c := ProblemRevisionContent{
+		Testcases: []ProblemTestcaseInput{},
+		Subtasks:  []ProblemSubtaskInput{},
+		Groups:    []ProblemTestcaseGroupInput{},
+		Assets:    []ProblemAsset{},
+	}
+	index := map[int64]int{}
+	members := map[int64][]int{}
+	err := eachRow(ctx, q, `SELECT id, COALESCE(input_path, ''), COALESCE(output_path, ''), COALESCE(input_text, ''), COALESCE(output_text, ''), is_sample, group_id
+FROM testcases WHERE problem_id=$1 ORDER BY id`, id, func(rows pgx.Rows) error {
+		var tcID int64
+		var groupID *int64
+		var tc ProblemTestcaseInput
+		if err := rows.Scan(&tcID, &tc.InputPath, &tc.OutputPath, &tc.InputText, &tc.OutputText, &tc.IsSample, &groupID); err != nil {
+			return err
+		}
+		index[tcID] = len(c.Testcases)
+		if groupID != nil {
+			members[*groupID] = append(members[*groupID], len(c.Testcases))
+		}
+		c.Testcases = append(c.Testcases, tc)
+		return nil
+	})
+	if err != nil {
+		return c, err
+	}
+	err = eachRow(ctx, q, `SELECT id, name, time_limit_ms, memory_limit_kb FROM problem_testcase_groups WHERE problem_id=$1 ORDER BY id`, id, func(rows pgx.Rows) error {
+		var groupID int64
+		var g ProblemTestcaseGroupInput
+		if err := rows.Scan(&groupID, &g.Name, &g.TimeLimitMS, &g.MemoryLimitKB); err != nil {
+			return err
+		}
+		g.TestcaseIndexes = members[groupID]
+		c.Groups = append(c.Groups, g)
+		return nil
+	})
+	if err != nil {
+		return c, err
+	}
+	err = eachRow(ctx, q, `
+SELECT st.name, st.score, COALESCE(array_agg(stc.testcase_id ORDER BY stc.testcase_id) FILTER (WHERE stc.testcase_id IS NOT NULL), '{}')
+FROM problem_subtasks st
+LEFT JOIN problem_subtask_testcases stc ON stc.subtask_id = st.id
+WHERE st.problem_id=$1
+GROUP BY st.id, st.name, st.score, st.position
+ORDER BY st.position`, id, func(rows pgx.Rows) error {
+		var st ProblemSubtaskInput
+		var testcaseIDs []int64
+		if err := rows.Scan(&st.Name, &st.Score, &testcaseIDs); err != nil {
+			return err
+		}
+		for _, tcID := range testcaseIDs {
+			st.TestcaseIndexes = append(st.TestcaseIndexes, index[tcID])
+		}
+		c.Subtasks = append(c.Subtasks, st)
+		return nil
+	})
+	if err != nil {
+		return c, err
+	}
+	err = eachRow(ctx, q, `SELECT name, content_type, data FROM problem_assets WHERE problem_id=$1 ORDER BY name`, id, func(rows pgx.Rows) error {
+		var a ProblemAsset
+		if err := rows.Scan(&a.Name, &a.ContentType, &a.Data); err != nil {
+			return err
+		}
+		c.Assets = append(c.Assets, a)
+		return nil
+	})
+	return c, err
+}
+
+// eachRow runs a query with a single id argument and calls fn for every row.
+func eachRow(ctx context.Context, q pgQuerier, query string, id int64, fn func(pgx.Rows) error) error {
+	rows, err := q.Query(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ensureProblemBaselineTx records the current content of a problem that has no revision yet, so the
+// first edit of a problem created before the history existed can be rolled back.
+func ensureProblemBaselineTx(ctx context.Context, q pgQuerier, id int64) error {
+	var exists bool
+	if err := q.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM problem_revisions WHERE problem_id=$1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	return recordProblemRevisionTx(ctx, q, id, ProblemRevisionBaseline, true, nil, nil)
+}
+
+// recordProblemRevisionTx stores the current content of the problem as a new revision. Nothing is
+// stored when nothing versioned changed since the latest revision. Without contentChanged the
+// testcases are known to be those of the latest revision and are not read again.
+func recordProblemRevisionTx(ctx context.Context, q pgQuerier, id int64, reason string, contentChanged bool, editedBy *int64, rolledBackTo *int) error {
+	settings, err := loadProblemRevisionSettings(ctx, q, id)
+	if err != nil {
+		return err
+	}
+	latest, err := latestProblemRevision(ctx, q, id)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	hasLatest := err == nil
+
+	var latestSHA string
+	if hasLatest {
+		latestSHA = latest.contentSHA
+	}
+	contentSHA := latestSHA
+	var content []byte
+	current := &ProblemRevision{Settings: &settings, Content: &ProblemRevisionContent{}}
+	if !hasLatest || contentChanged {
+		c, err := loadProblemRevisionContent(ctx, q, id)
+		if err != nil {
+			return err
+		}
+		if content, err = json.Marshal(c); err != nil {
+			return err
+		}
+		sum := sha256.Sum256(content)
+		contentSHA = hex.EncodeToString(sum[:])
+		current.Content = &c
+	}
+
+	changes := []string{}
+	if hasLatest {
+		prev := &ProblemRevision{Settings: &latest.settings, Content: &ProblemRevisionContent{}}
+		if contentSHA != latestSHA {
+			if prev.Content, err = loadStoredRevisionContent(ctx, q, id, latestSHA); err != nil {
+				return err
+			}
+		} else {
+			// 内容が同じなら比べる必要はない
+			current.Content = &ProblemRevisionContent{}
+		}
+		changes = diffProblemRevisions(prev, current).changeNames()
+		if len(changes) == 0 {
+			return nil
+		}
+	}
+
+	if content != nil {
+		if _, err := q.Exec(ctx, `INSERT INTO problem_revision_contents (problem_id, sha256, content) VALUES ($1,$2,$3) ON CONFLICT DO NOTHING`,
+			id, contentSHA, content); err != nil {
+			return err
+		}
+	}
+	rawSettings, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(ctx, `INSERT INTO problem_revisions (problem_id, revision, reason, rolled_back_to, settings, content_sha256, changes, edited_by)
+VALUES ($1, COALESCE((SELECT MAX(revision) FROM problem_revisions WHERE problem_id=$1), 0) + 1, $2, $3, $4, $5, $6, $7)`,
+		id, reason, rolledBackTo, rawSettings, contentSHA, changes, editedBy)
+	return err
+}
+
+type storedProblemRevision struct {
+	settings   ProblemRevisionSettings
+	contentSHA string
+}
+
+func latestProblemRevision(ctx context.Context, q pgQuerier, id int64) (*storedProblemRevision, error) {
+	var raw []byte
+	var rev storedProblemRevision
+	if err := q.QueryRow(ctx, `SELECT settings, content_sha256 FROM problem_revisions WHERE problem_id=$1 ORDER BY revision DESC LIMIT 1`, id).
+		Scan(&raw, &rev.contentSHA); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &rev.settings); err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+func loadStoredRevisionContent(ctx context.Context, q pgQuerier, id int64, sha string) (*ProblemRevisionContent, error) {
+	var raw []byte
+	if err := q.QueryRow(ctx, `SELECT content FROM problem_revision_contents WHERE problem_id=$1 AND sha256=$2`, id, sha).Scan(&raw); err != nil {
+		return nil, err
+	}
+	var c ProblemRevisionContent
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+const problemRevisionColumns = `r.revision, r.reason, r.rolled_back_to, r.changes, jsonb_array_length(c.content->'Testcases'),
+       r.edited_by, u.username, r.created_at`
+
+// ProblemRevisions lists the revisions of a problem newest first, without their content.
+func (r *PgProblemRepository) ProblemRevisions(ctx context.Context, id int64) ([]ProblemRevision, error) {
+	q := `SELECT ` + problemRevisionColumns + `
+FROM problem_revisions r
+JOIN problem_revision_contents c ON c.problem_id = r.problem_id AND c.sha256 = r.content_sha256
+LEFT JOIN users u ON u.id = r.edited_by
+WHERE r.problem_id=$1
+ORDER BY r.revision DESC`
+	rows, err := r.db.Query(ctx, q, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ProblemRevision{}
+	for rows.Next() {
+		var rev ProblemRevision
+		if err := rows.Scan(&rev.Revision, &rev.Reason, &rev.RolledBackTo, &rev.Changes, &rev.Testcases, &rev.EditedBy, &rev.EditorName, &rev.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, rev)
+	}
+	return items, rows.Err()
+}
+
+// ProblemRevision returns one revision with its settings and content; pgx.ErrNoRows when missing.
+func (r *PgProblemRepository) ProblemRevision(ctx context.Context, id int64, revision int) (*ProblemRevision, error) {
+	q := `SELECT ` + problemRevisionColumns + `, r.settings, c.content
+FROM problem_revisions r
+JOIN problem_revision_contents c ON c.problem_id = r.problem_id AND c.sha256 = r.content_sha256
+LEFT JOIN users u ON u.id = r.edited_by
+WHERE r.problem_id=$1 AND r.revision=$2`
+	var rev ProblemRevision
+	var settings, content []byte
+	if err := r.db.QueryRow(ctx, q, id, revision).Scan(&rev.Revision, &rev.Reason, &rev.RolledBackTo, &rev.Changes, &rev.Testcases,
+		&rev.EditedBy, &rev.EditorName, &rev.CreatedAt, &settings, &content); err != nil {
+		return nil, err
+	}
+	rev.Settings, rev.Content = &ProblemRevisionSettings{}, &ProblemRevisionContent{}
+	if err := json.Unmarshal(settings, rev.Settings); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, rev.Content); err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffProblemRevisions(t *testing.T) {
+	base := &ProblemRevision{
+		Revision: 1,
+		Settings: &ProblemRevisionSettings{Title: "A+B", StatementMD: "足し算\n", TimeLimitMS: 2000, MemoryLimitKB: 262144, CheckerType: "exact", Tags: []string{"math"}},
+		Content: &ProblemRevisionContent{Testcases: []ProblemTestcaseInput{
+			{InputPath: "data/sample/01.in", InputText: "1 2\n", OutputText: "3\n", IsSample: true},
+			{InputPath: "data/secret/01.in", InputText: "5 5\n", OutputText: "10\n"},
+		}},
+	}
+	settings := *base.Settings
+	settings.StatementMD = "足し算をする\n"
+	settings.TimeLimitMS = 3000
+	next := &ProblemRevision{
+		Revision: 2,
+		Settings: &settings,
+		Content: &ProblemRevisionContent{Testcases: []ProblemTestcaseInput{
+			{InputPath: "data/sample/01.in", InputText: "1 2\n", OutputText: "3\n", IsSample: true},
+			{InputPath: "data/secret/01.in", InputText: "5 5\n", OutputText: "11\n"},
+			{InputPath: "data/secret/02.in", InputText: "0 0\n", OutputText: "0\n"},
+		}},
+	}
+
+	d := diffProblemRevisions(base, next)
+	if got, want := d.changeNames(), []string{"statement", "limits", "testcases"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("changes = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(d.Changes.ChangedTestcases, []string{"secret/01"}) || !reflect.DeepEqual(d.Changes.AddedTestcases, []string{"secret/02"}) {
+		t.Fatalf("testcase diff = %+v", d.Changes)
+	}
+	if d.AddedLines != 1 || d.RemovedLines != 1 {
+		t.Fatalf("statement diff +%d -%d, want +1 -1", d.AddedLines, d.RemovedLines)
+	}
+
+	// 巻き戻し用のパッケージは版の内容をそのまま持ち、同じ版と比べると差分はない
+	input := revisionPackageInput("aplusb", base)
+	if input.RollbackTo != 1 || input.TimeLimitMS != 2000 || len(input.Testcases) != 2 {
+		t.Fatalf("unexpected rollback input: %+v", input)
+	}
+	if names := diffProblemRevisions(base, base).changeNames(); len(names) != 0 {
+		t.Fatalf("changes against itself = %v", names)
+	}
+}
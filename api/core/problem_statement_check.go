@@ -0,0 +1,149 @@
+package core
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// 問題文から取り除く要素（中身ごと削除する）
+	unsafeBlockPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?is)<script\b[^>]*>.*?</script\s*>`),
+		regexp.MustCompile(`(?is)<iframe\b[^>]*>.*?</iframe\s*>`),
+		regexp.MustCompile(`(?is)<object\b[^>]*>.*?</object\s*>`),
+		regexp.MustCompile(`(?is)<style\b[^>]*>.*?</style\s*>`),
+	}
+	// 閉じタグの無い・自己終了の要素
+	unsafeTagPattern = regexp.MustCompile(`(?i)</?(script|iframe|object|embed|style)\b[^>]*>`)
+	htmlTagPattern   = regexp.MustCompile(`<[a-zA-Z][^>]*>`)
+	eventAttrPattern = regexp.MustCompile(`(?i)\s+on[a-z]+\s*=\s*("[^"]*"|'[^']*'|[^\s>]+)`)
+
+	markdownLinkPattern = regexp.MustCompile(`(!?)\[[^\]]*\]\(\s*<?([^)\s>]*)>?(?:\s+["'(][^)]*)?\)`)
+	markdownRefPattern  = regexp.MustCompile(`^\s{0,3}\[[^\]]+\]:\s*<?(\S+?)>?(?:\s+.*)?$`)
+	htmlRefPattern      = regexp.MustCompile(`(?i)\b(src|href)\s*=\s*["']([^"']*)["']`)
+	inlineCodePattern   = regexp.MustCompile("`+[^`]*`+")
+	fencePattern        = regexp.MustCompile("^\\s{0,3}(```|~~~)")
+
+	statementAssetRefPattern = regexp.MustCompile(`(\]\(\s*<?|\]:\s*<?|\b(?:src|href)\s*=\s*["'])(?:\./)?assets/`)
+)
+
+// sanitizeStatement removes script-capable HTML (script / iframe / object / embed / style elements and
+// on* event attributes) from a statement and reports what was removed by line. Removed blocks keep their
+// line breaks so the reported line numbers stay valid for later checks.
+func sanitizeStatement(md string) (string, []string) {
+	removed := []string{}
+	lineOf := func(s string, idx int) int { return strings.Count(s[:idx], "\n") + 1 }
+	for _, re := range unsafeBlockPatterns {
+		for _, loc := range re.FindAllStringIndex(md, -1) {
+			removed = append(removed, fmt.Sprintf("%d行目: %s を削除しました", lineOf(md, loc[0]), tagName(md[loc[0]:loc[1]])))
+		}
+		md = re.ReplaceAllStringFunc(md, func(m string) string { return strings.Repeat("\n", strings.Count(m, "\n")) })
+	}
+	for _, loc := range unsafeTagPattern.FindAllStringIndex(md, -1) {
+		removed = append(removed, fmt.Sprintf("%d行目: %s を削除しました", lineOf(md, loc[0]), tagName(md[loc[0]:loc[1]])))
+	}
+	md = unsafeTagPattern.ReplaceAllStringFunc(md, func(m string) string { return strings.Repeat("\n", strings.Count(m, "\n")) })
+
+	var out strings.Builder
+	last := 0
+	for _, loc := range htmlTagPattern.FindAllStringIndex(md, -1) {
+		tag := md[loc[0]:loc[1]]
+		if !eventAttrPattern.MatchString(tag) {
+			continue
+		}
+		removed = append(removed, fmt.Sprintf("%d行目: %s のイベント属性を削除しました", lineOf(md, loc[0]), tagName(tag)))
+		out.WriteString(md[last:loc[0]])
+		out.WriteString(eventAttrPattern.ReplaceAllString(tag, ""))
+		last = loc[1]
+	}
+	out.WriteString(md[last:])
+	return out.String(), removed
+}
+
+func tagName(tag string) string {
+	name := strings.TrimLeft(tag, "</")
+	if i := strings.IndexAny(name, " \t\r\n/>"); i >= 0 {
+		name = name[:i]
+	}
+	return "<" + strings.ToLower(name) + ">"
+}
+
+// checkStatementRefs validates the images and links of a statement: relative references must point to
+// packaged assets (assets/<name>), and only http / https / mailto URLs are allowed. Code blocks and inline
+// code are skipped. It returns one line per broken reference.
+func checkStatementRefs(md string, assets map[string]bool) []string {
+	var broken []string
+	inFence := false
+	for i, line := range strings.Split(md, "\n") {
+		if fencePattern.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		line = inlineCodePattern.ReplaceAllString(line, "")
+		check := func(kind, target string) {
+			if msg := statementRefProblem(target, assets); msg != "" {
+				broken = append(broken, fmt.Sprintf("%d行目: %s %s %s", i+1, kind, strconv.Quote(target), msg))
+			}
+		}
+		for _, m := range markdownLinkPattern.FindAllStringSubmatch(line, -1) {
+			kind := "リンク"
+			if m[1] == "!" {
+				kind = "画像"
+			}
+			check(kind, m[2])
+		}
+		if m := markdownRefPattern.FindStringSubmatch(line); m != nil {
+			check("リンク", m[1])
+		}
+		for _, m := range htmlRefPattern.FindAllStringSubmatch(line, -1) {
+			kind := "リンク"
+			if strings.EqualFold(m[1], "src") {
+				kind = "画像"
+			}
+			check(kind, m[2])
+		}
+	}
+	return broken
+}
+
+func statementRefProblem(target string, assets map[string]bool) string {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return "の参照先が空です"
+	}
+	if strings.HasPrefix(target, "#") || strings.HasPrefix(target, "/") {
+		return ""
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return "を解釈できません"
+	}
+	if u.Scheme != "" {
+		switch strings.ToLower(u.Scheme) {
+		case "http", "https", "mailto":
+			return ""
+		default:
+			return "は使用できないスキームです (http / https / mailto のみ)"
+		}
+	}
+	p := path.Clean(u.Path)
+	if !strings.HasPrefix(p, assetDir) {
+		return "はパッケージ外を指しています (assets/ に同梱してください)"
+	}
+	if !assets[strings.TrimPrefix(p, assetDir)] {
+		return "が見つかりません (assets/ に同梱してください)"
+	}
+	return ""
+}
+
+// resolveStatementAssets rewrites assets/<name> references to the asset endpoint of the problem.
+func resolveStatementAssets(md string, problemID int64) string {
+	return statementAssetRefPattern.ReplaceAllString(md, "${1}/api/v1/problems/"+strconv.FormatInt(problemID, 10)+"/assets/")
+}
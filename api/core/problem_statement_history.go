@@ -0,0 +1,85 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// ProblemStatementVersion is a previous statement of a problem.
+type ProblemStatementVersion struct {
+	ID          int64     `json:"id"`
+	ProblemID   int64     `json:"problem_id"`
+	StatementMD string    `json:"statement_md,omitempty"`
+	EditedBy    *int64    `json:"edited_by"`
+	EditorName  *string   `json:"edited_by_userid"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// saveStatementVersion stores the current statement before it is replaced by newStatement.
+// Nothing is stored when the statement does not change.
+func saveStatementVersion(ctx context.Context, q pgQuerier, problemID int64, newStatement string, editedBy *int64) error {
+	var current string
+	if err := q.QueryRow(ctx, `SELECT statement_md FROM problems WHERE id=$1 FOR UPDATE`, problemID).Scan(&current); err != nil {
+		return err
+	}
+	if current == newStatement {
+		return nil
+	}
+	_, err := q.Exec(ctx, `INSERT INTO problem_statement_versions (problem_id, statement_md, edited_by) VALUES ($1,$2,$3)`,
+		problemID, current, editedBy)
+	return err
+}
+
+// StatementVersions lists previous statements newest first, without their bodies.
+func (r *PgProblemRepository) StatementVersions(ctx context.Context, id int64) ([]ProblemStatementVersion, error) {
+	const q = `
+SELECT v.id, v.problem_id, v.edited_by, u.username, v.created_at
+FROM problem_statement_versions v
+LEFT JOIN users u ON u.id = v.edited_by
+WHERE v.problem_id=$1
+ORDER BY v.id DESC`
+	rows, err := r.db.Query(ctx, q, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ProblemStatementVersion{}
+	for rows.Next() {
+		var v ProblemStatementVersion
+		if err := rows.Scan(&v.ID, &v.ProblemID, &v.EditedBy, &v.EditorName, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+	}
+	return items, rows.Err()
+}
+
+func (r *PgProblemRepository) StatementVersion(ctx context.Context, id, versionID int64) (*ProblemStatementVersion, error) {
+	const q = `
+SELECT v.id, v.problem_id, v.statement_md, v.edited_by, u.username, v.created_at
+FROM problem_statement_versions v
+LEFT JOIN users u ON u.id = v.edited_by
+WHERE v.problem_id=$1 AND v.id=$2`
+	var v ProblemStatementVersion
+	if err := r.db.QueryRow(ctx, q, id, versionID).Scan(&v.ID, &v.ProblemID, &v.StatementMD, &v.EditedBy, &v.EditorName, &v.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// InRunningContest reports whether the problem belongs to a contest in progress,
+// either as its owning contest or as one of the contest's problems.
+func (r *PgProblemRepository) InRunningContest(ctx context.Context, id int64, now time.Time) (bool, error) {
+	const q = `
+SELECT EXISTS (
+    SELECT 1 FROM contests c
+    WHERE c.start_at <= $2 AND $2 < c.end_at
+      AND (c.id = (SELECT contest_id FROM problems WHERE id=$1)
+           OR EXISTS (SELECT 1 FROM contest_problems cp WHERE cp.contest_id = c.id AND cp.problem_id = $1))
+)`
+	var running bool
+	if err := r.db.QueryRow(ctx, q, id, now).Scan(&running); err != nil {
+		return false, err
+	}
+	return running, nil
+}
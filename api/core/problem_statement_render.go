@@ -0,0 +1,211 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+)
+
+var (
+	statementMarkdown = goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		// 生の HTML はそのまま出し、後段の statementPolicy で許可リストに絞る
+		goldmark.WithRendererOptions(gmhtml.WithUnsafe()),
+	)
+	statementPolicy = newStatementPolicy()
+)
+
+func newStatementPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+	p.AllowAttrs("align").Matching(regexp.MustCompile(`^(left|center|right)$`)).OnElements("th", "td")
+	p.RequireNoFollowOnLinks(false)
+	return p
+}
+
+// statementMath is a TeX fragment taken out of a statement before markdown rendering.
+type statementMath struct {
+	tex     string
+	display bool
+}
+
+// renderStatementHTML renders a statement to sanitized HTML. TeX in $...$, $$...$$, \(...\) and \[...\]
+// bypasses markdown so that "_" or "*" in formulas survive, and is emitted HTML-escaped in
+// <span class="math math-inline"> / <div class="math math-display"> for KaTeX on the client.
+func renderStatementHTML(md string) (string, error) {
+	// 置換用の印は英数字だけにして、markdown の記法として解釈されないようにする
+	prefix := "ojmath" + randomHex(4) + "z"
+	text, maths := extractStatementMath(md, func(i int) string { return prefix + strconv.Itoa(i) + "z" })
+
+	var buf bytes.Buffer
+	if err := statementMarkdown.Convert([]byte(text), &buf); err != nil {
+		return "", err
+	}
+	out := statementPolicy.Sanitize(buf.String())
+	for i := len(maths) - 1; i >= 0; i-- {
+		m := maths[i]
+		token := prefix + strconv.Itoa(i) + "z"
+		tex := html.EscapeString(m.tex)
+		if m.display {
+			// 単独の段落になっている数式は段落ごと置き換える
+			out = strings.Replace(out, "<p>"+token+"</p>", `<div class="math math-display">`+tex+`</div>`, 1)
+			out = strings.Replace(out, token, `<span class="math math-display">`+tex+`</span>`, 1)
+			continue
+		}
+		out = strings.Replace(out, token, `<span class="math math-inline">`+tex+`</span>`, 1)
+	}
+	return out, nil
+}
+
+// statementETag is a strong ETag for a rendered statement.
+func statementETag(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// extractStatementMath replaces math spans outside code with token(i) and returns them in order.
+// Fenced code blocks and inline code are left untouched, as are "\$" escapes.
+func extractStatementMath(md string, token func(int) string) (string, []statementMath) {
+	var out strings.Builder
+	var maths []statementMath
+	var text strings.Builder
+	flush := func() {
+		maths = scanStatementMath(text.String(), token, maths, &out)
+		text.Reset()
+	}
+
+	fence := ""
+	for _, line := range strings.SplitAfter(md, "\n") {
+		if fence != "" {
+			out.WriteString(line)
+			if strings.HasPrefix(strings.TrimSpace(line), fence) {
+				fence = ""
+			}
+			continue
+		}
+		if m := fencePattern.FindStringSubmatch(line); m != nil {
+			flush()
+			fence = m[1]
+			out.WriteString(line)
+			continue
+		}
+		text.WriteString(line)
+	}
+	flush()
+	return out.String(), maths
+}
+
+func scanStatementMath(s string, token func(int) string, maths []statementMath, out *strings.Builder) []statementMath {
+	add := func(tex string, display bool) {
+		out.WriteString(token(len(maths)))
+		maths = append(maths, statementMath{tex: tex, display: display})
+	}
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && (s[i+1] == '(' || s[i+1] == '['):
+			closer, display := `\)`, false
+			if s[i+1] == '[' {
+				closer, display = `\]`, true
+			}
+			if end := strings.Index(s[i+2:], closer); end >= 0 {
+				add(strings.TrimSpace(s[i+2:i+2+end]), display)
+				i += 2 + end + len(closer)
+				continue
+			}
+			out.WriteString(s[i : i+2])
+			i += 2
+		case s[i] == '\\' && i+1 < len(s):
+			out.WriteString(s[i : i+2])
+			i += 2
+		case s[i] == '`':
+			n := 1
+			for i+n < len(s) && s[i+n] == '`' {
+				n++
+			}
+			end := closingBackticks(s, i+n, n)
+			if end < 0 {
+				out.WriteString(s[i : i+n])
+				i += n
+				continue
+			}
+			out.WriteString(s[i:end])
+			i = end
+		case strings.HasPrefix(s[i:], "$$"):
+			end := strings.Index(s[i+2:], "$$")
+			if end < 0 || strings.TrimSpace(s[i+2:i+2+end]) == "" {
+				out.WriteString("$$")
+				i += 2
+				continue
+			}
+			add(strings.TrimSpace(s[i+2:i+2+end]), true)
+			i += 2 + end + 2
+		case s[i] == '$':
+			if end := closingDollar(s, i+1); end > 0 {
+				add(s[i+1:end], false)
+				i = end + 1
+				continue
+			}
+			out.WriteByte('$')
+			i++
+		default:
+			out.WriteByte(s[i])
+			i++
+		}
+	}
+	return maths
+}
+
+// closingBackticks returns the index just past the run of exactly n backticks closing a code span
+// that starts at from, or -1.
+func closingBackticks(s string, from, n int) int {
+	for j := from; j < len(s); {
+		if s[j] != '`' {
+			j++
+			continue
+		}
+		k := j
+		for k < len(s) && s[k] == '`' {
+			k++
+		}
+		if k-j == n {
+			return k
+		}
+		j = k
+	}
+	return -1
+}
+
+// closingDollar finds the "$" closing inline math opened just before from, on the same line. Like
+// pandoc, the content must not start or end with a space and the closing "$" must not be followed by a
+// digit, so that prices such as "$5 and $10" stay text.
+func closingDollar(s string, from int) int {
+	if from >= len(s) || s[from] == ' ' || s[from] == '\t' || s[from] == '\n' {
+		return -1
+	}
+	for j := from; j < len(s); j++ {
+		switch s[j] {
+		case '\n':
+			return -1
+		case '\\':
+			j++
+		case '$':
+			if s[j-1] == ' ' || s[j-1] == '\t' {
+				continue
+			}
+			if j+1 < len(s) && s[j+1] >= '0' && s[j+1] <= '9' {
+				continue
+			}
+			return j
+		}
+	}
+	return -1
+}
@@ -0,0 +1,51 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderStatementHTMLKeepsMath(t *testing.T) {
+	md := "# 問題\n\n$a_i * b_i$ の総和を求めよ。価格は $5 と $10 です。\n\n$$\n\\sum_{i=1}^{N} a_i < 10^9\n$$\n\n" +
+		"```\n$x_1$ はそのまま\n```\n\n`$y$` もそのまま。\\$z\\$ は数式ではない。\n"
+	got, err := renderStatementHTML(md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<h1>問題</h1>",
+		`<span class="math math-inline">a_i * b_i</span>`,
+		"価格は $5 と $10 です。",
+		`<div class="math math-display">\sum_{i=1}^{N} a_i &lt; 10^9</div>`,
+		"<code>$x_1$ はそのまま\n</code>",
+		"<code>$y$</code>",
+		"$z$ は数式ではない",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "ojmath") {
+		t.Errorf("placeholder left in output:\n%s", got)
+	}
+}
+
+func TestRenderStatementHTMLSanitizes(t *testing.T) {
+	md := "<script>alert(1)</script>\n\n<img src=\"/api/v1/problems/1/assets/a.png\" onerror=\"alert(1)\">\n\n" +
+		"[x](javascript:alert(1)) $<b onclick=x>$\n"
+	got, err := renderStatementHTML(md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{"<script", "onerror", "javascript:", "onclick=x>"} {
+		if strings.Contains(got, bad) {
+			t.Errorf("unsafe %q survived:\n%s", bad, got)
+		}
+	}
+	if !strings.Contains(got, `<img src="/api/v1/problems/1/assets/a.png">`) {
+		t.Errorf("asset image dropped:\n%s", got)
+	}
+	if !strings.Contains(got, `<span class="math math-inline">&lt;b onclick=x&gt;</span>`) {
+		t.Errorf("math not escaped:\n%s", got)
+	}
+}
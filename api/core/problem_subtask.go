@@ -0,0 +1,167 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ProblemSubtask is a group of testcases worth Score points when all of them pass.
+type ProblemSubtask struct {
+	ID          int64   `json:"id"`
+	Name        string  `json:"name"`
+	Score       int32   `json:"score"`
+	TestcaseIDs []int64 `json:"-"`
+}
+
+// ProblemSubtaskInput defines a subtask at creation; TestcaseIndexes point into ProblemCreateInput.Testcases.
+type ProblemSubtaskInput struct {
+	Name            string
+	Score           int32
+	TestcaseIndexes []int
+}
+
+// SubtaskResult is the per-subtask outcome stored with a submission result.
+type SubtaskResult struct {
+	Name     string `json:"name"`
+	Score    int32  `json:"score"`
+	MaxScore int32  `json:"max_score"`
+	Passed   bool   `json:"passed"`
+}
+
+// subtaskResultsArg stores no breakdown as SQL NULL rather than JSON null.
+func subtaskResultsArg(results []SubtaskResult) any {
+	if len(results) == 0 {
+		return nil
+	}
+	return results
+}
+
+// scoreSubtasks awards each subtask's points when every testcase in it passed.
+func scoreSubtasks(subtasks []ProblemSubtask, passed map[int64]bool) ([]SubtaskResult, int32, int32) {
+	results := make([]SubtaskResult, 0, len(subtasks))
+	var score, maxScore int32
+	for _, st := range subtasks {
+		ok := len(st.TestcaseIDs) > 0
+		for _, id := range st.TestcaseIDs {
+			if !passed[id] {
+				ok = false
+				break
+			}
+		}
+		r := SubtaskResult{Name: st.Name, MaxScore: st.Score, Passed: ok}
+		if ok {
+			r.Score = st.Score
+		}
+		score += r.Score
+		maxScore += st.Score
+		results = append(results, r)
+	}
+	return results, score, maxScore
+}
+
+// resolveSubtaskPatterns maps problem.yaml subtask testcase patterns (e.g. "secret/01", "secret/large_*")
+// to indexes of the sorted testcase keys.
+func resolveSubtaskPatterns(docs []problemSubtaskDoc, keys []string) ([]ProblemSubtaskInput, error) {
+	var out []ProblemSubtaskInput
+	var total int
+	for i, d := range docs {
+		name := strings.TrimSpace(d.Name)
+		if name == "" {
+			name = fmt.Sprintf("subtask%d", i+1)
+		}
+		if d.Score < 0 {
+			return nil, fmt.Errorf("subtasks[%d].score は 0 以上で指定してください", i)
+		}
+		if len(d.Testcases) == 0 {
+			return nil, fmt.Errorf("subtasks[%d].testcases が空です", i)
+		}
+		idxs, err := matchTestcasePatterns(d.Testcases, keys)
+		if err != nil {
+			return nil, fmt.Errorf("subtasks[%d] %w", i, err)
+		}
+		total += d.Score
+		out = append(out, ProblemSubtaskInput{Name: name, Score: int32(d.Score), TestcaseIndexes: idxs})
+	}
+	if len(out) > 0 && total <= 0 {
+		return nil, errors.New("subtasks の配点の合計は 1 以上にしてください")
+	}
+	return out, nil
+}
+
+// matchTestcasePatterns returns the indexes of keys (e.g. "secret/01") matched by any of the
+// problem.yaml patterns, in key order of first match. Every pattern must match at least one key.
+func matchTestcasePatterns(patterns []string, keys []string) ([]int, error) {
+	seen := map[int]bool{}
+	var idxs []int
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(pattern), "data/"), ".in")
+		matched := false
+		for k, key := range keys {
+			ok, err := path.Match(pattern, key)
+			if err != nil {
+				return nil, fmt.Errorf("のパターン %q が不正です", pattern)
+			}
+			if ok {
+				matched = true
+				if !seen[k] {
+					seen[k] = true
+					idxs = append(idxs, k)
+				}
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("のパターン %q に一致するテストケースがありません", pattern)
+		}
+	}
+	return idxs, nil
+}
+
+// insertSubtasksTx stores subtasks; testcaseIDs are the IDs of the inserted testcases in input order.
+func insertSubtasksTx(ctx context.Context, tx pgx.Tx, problemID int64, subtasks []ProblemSubtaskInput, testcaseIDs []int64) error {
+	for pos, st := range subtasks {
+		var subtaskID int64
+		if err := tx.QueryRow(ctx, `INSERT INTO problem_subtasks (problem_id, position, name, score) VALUES ($1,$2,$3,$4) RETURNING id`,
+			problemID, pos+1, st.Name, st.Score).Scan(&subtaskID); err != nil {
+			return err
+		}
+		for _, idx := range st.TestcaseIndexes {
+			if idx < 0 || idx >= len(testcaseIDs) {
+				return errors.New("subtask testcase index out of range")
+			}
+			if _, err := tx.Exec(ctx, `INSERT INTO problem_subtask_testcases (subtask_id, testcase_id) VALUES ($1,$2)`, subtaskID, testcaseIDs[idx]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ListSubtasks returns the problem's subtasks in order with their testcase IDs.
+func (r *PgProblemRepository) ListSubtasks(ctx context.Context, id int64) ([]ProblemSubtask, error) {
+	const q = `
+SELECT st.id, st.name, st.score, COALESCE(array_agg(stc.testcase_id ORDER BY stc.testcase_id) FILTER (WHERE stc.testcase_id IS NOT NULL), '{}')
+FROM problem_subtasks st
+LEFT JOIN problem_subtask_testcases stc ON stc.subtask_id = st.id
+WHERE st.problem_id=$1
+GROUP BY st.id, st.name, st.score, st.position
+ORDER BY st.position`
+	rows, err := r.db.Query(ctx, q, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ProblemSubtask{}
+	for rows.Next() {
+		var st ProblemSubtask
+		if err := rows.Scan(&st.ID, &st.Name, &st.Score, &st.TestcaseIDs); err != nil {
+			return nil, err
+		}
+		items = append(items, st)
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const maxProblemTags = 10
+
+// ErrProblemTagInput is returned for an invalid tag list.
+var ErrProblemTagInput = errors.New("invalid problem tags")
+
+// problemTagPattern allows lowercase letters (any script), digits, "_", "-" and "+" (up to 32 chars).
+var problemTagPattern = regexp.MustCompile(`^[\p{Ll}\p{Lo}\p{N}][\p{Ll}\p{Lo}\p{N}_+-]{0,31}$`)
+
+// problemTagsColumn selects the sorted tag names of problem p (empty array without tags).
+const problemTagsColumn = `COALESCE((SELECT array_agg(t.name ORDER BY t.name) FROM problem_tags pt JOIN tags t ON t.id = pt.tag_id WHERE pt.problem_id = p.id), '{}')`
+
+// normalizeProblemTag lowercases and trims a tag name; "" when it is not a valid tag.
+func normalizeProblemTag(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if !problemTagPattern.MatchString(tag) {
+		return ""
+	}
+	return tag
+}
+
+// normalizeProblemTags validates tags and returns them lowercased, deduplicated and sorted.
+func normalizeProblemTags(tags []string) ([]string, error) {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, raw := range tags {
+		tag := normalizeProblemTag(raw)
+		if tag == "" {
+			return nil, fmt.Errorf("%w: タグ %q は使用できません（英小文字・数字・_-+ の 32 文字以内）", ErrProblemTagInput, raw)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			out = append(out, tag)
+		}
+	}
+	if len(out) > maxProblemTags {
+		return nil, fmt.Errorf("%w: タグは %d 個までです", ErrProblemTagInput, maxProblemTags)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// setProblemTagsTx replaces the tags of the problem, creating unknown tags.
+func setProblemTagsTx(ctx context.Context, q pgQuerier, problemID int64, tags []string) error {
+	tags, err := normalizeProblemTags(tags)
+	if err != nil {
+		return err
+	}
+	if _, err := q.Exec(ctx, `DELETE FROM problem_tags WHERE problem_id=$1`, problemID); err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	if _, err := q.Exec(ctx, `INSERT INTO tags (name) SELECT unnest($1::TEXT[]) ON CONFLICT (name) DO NOTHING`, tags); err != nil {
+		return err
+	}
+	_, err = q.Exec(ctx, `INSERT INTO problem_tags (problem_id, tag_id) SELECT $1, id FROM tags WHERE name = ANY($2)`, problemID, tags)
+	return err
+}
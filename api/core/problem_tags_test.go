@@ -0,0 +1,19 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeProblemTags(t *testing.T) {
+	got, err := normalizeProblemTags([]string{" DP ", "graph", "dp", "c++"})
+	if err != nil || !reflect.DeepEqual(got, []string{"c++", "dp", "graph"}) {
+		t.Fatalf("got %v, %v", got, err)
+	}
+	for _, bad := range [][]string{{""}, {"two words"}, {"-dp"}, {"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"}} {
+		if _, err := normalizeProblemTags(bad); !errors.Is(err, ErrProblemTagInput) {
+			t.Errorf("%q: expected ErrProblemTagInput, got %v", bad, err)
+		}
+	}
+}
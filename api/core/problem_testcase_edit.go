@@ -0,0 +1,297 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Testcase edit operations of EditTestcases.
+const (
+	TestcaseEditAdd     = "add"
+	TestcaseEditReplace = "replace"
+	TestcaseEditDelete  = "delete"
+	TestcaseEditReorder = "reorder"
+)
+
+// ErrTestcaseEditInput is returned for an invalid testcase edit.
+var ErrTestcaseEditInput = errors.New("invalid testcase edit")
+
+// testcaseNamePattern is the name of a testcase as in packages (sample/01, secret/large_1).
+var testcaseNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+(/[A-Za-z0-9_.-]+)*$`)
+
+// TestcaseEdit is one change to the testcases of a problem. Testcases are addressed by their
+// 1-based position (the judging order).
+type TestcaseEdit struct {
+	Op       string
+	Position int // add: 挿入位置（0 は末尾）、replace / delete: 対象
+	Name     *string
+	Input    *string
+	Output   *string
+	IsSample *bool
+	// Order lists the current positions in their new order (reorder).
+	Order []int
+}
+
+// ProblemTestcaseSummary describes a testcase without its content.
+type ProblemTestcaseSummary struct {
+	Position    int    `json:"position"`
+	Name        string `json:"name"`
+	IsSample    bool   `json:"is_sample"`
+	Group       string `json:"group,omitempty"`
+	InputBytes  int    `json:"input_bytes"`
+	OutputBytes int    `json:"output_bytes"`
+}
+
+// summarizeTestcases lists the testcases of a package in order.
+func summarizeTestcases(pkg ProblemCreateInput) []ProblemTestcaseSummary {
+	groups := map[int]string{}
+	for _, g := range pkg.Groups {
+		for _, idx := range g.TestcaseIndexes {
+			groups[idx] = g.Name
+		}
+	}
+	items := make([]ProblemTestcaseSummary, len(pkg.Testcases))
+	for i, tc := range pkg.Testcases {
+		items[i] = ProblemTestcaseSummary{
+			Position:    i + 1,
+			Name:        importTestcaseKey(tc.InputPath, i),
+			IsSample:    tc.IsSample,
+			Group:       groups[i],
+			InputBytes:  len(tc.InputText),
+			OutputBytes: len(tc.OutputText),
+		}
+	}
+	return items
+}
+
+// apply changes the testcases of pkg, keeping the subtasks and groups pointing at the same
+// testcases. A new testcase belongs to no subtask or group.
+func (e TestcaseEdit) apply(pkg *ProblemCreateInput) error {
+	n := len(pkg.Testcases)
+	inRange := func(pos int) error {
+		if pos < 1 || pos > n {
+			return fmt.Errorf("%w: テストケース %d はありません（%d 件）", ErrTestcaseEditInput, pos, n)
+		}
+		return nil
+	}
+	// remap[i] は旧インデックス i の新しいインデックス（-1 は削除）
+	remap := make([]int, n)
+	var testcases []ProblemTestcaseInput
+
+	switch e.Op {
+	case TestcaseEditAdd:
+		pos := e.Position
+		if pos == 0 {
+			pos = n + 1
+		}
+		if pos < 1 || pos > n+1 {
+			return fmt.Errorf("%w: position は 1〜%d で指定してください", ErrTestcaseEditInput, n+1)
+		}
+		if e.Input == nil || e.Output == nil {
+			return fmt.Errorf("%w: input と output は必須です", ErrTestcaseEditInput)
+		}
+		tc := ProblemTestcaseInput{InputText: *e.Input, OutputText: *e.Output, IsSample: e.IsSample != nil && *e.IsSample}
+		name := ""
+		if e.Name != nil {
+			name = *e.Name
+		} else {
+			name = nextTestcaseName(pkg.Testcases, tc.IsSample)
+		}
+		if err := setTestcaseName(&tc, name, pkg.Testcases, -1); err != nil {
+			return err
+		}
+		testcases = append(testcases, pkg.Testcases[:pos-1]...)
+		testcases = append(testcases, tc)
+		testcases = append(testcases, pkg.Testcases[pos-1:]...)
+		for i := range remap {
+			remap[i] = i
+			if i >= pos-1 {
+				remap[i] = i + 1
+			}
+		}
+	case TestcaseEditReplace:
+		if err := inRange(e.Position); err != nil {
+			return err
+		}
+		testcases = append(testcases, pkg.Testcases...)
+		tc := &testcases[e.Position-1]
+		if e.Input != nil {
+			tc.InputText = *e.Input
+		}
+		if e.Output != nil {
+			tc.OutputText = *e.Output
+		}
+		if e.IsSample != nil {
+			tc.IsSample = *e.IsSample
+		}
+		if e.Name != nil {
+			if err := setTestcaseName(tc, *e.Name, testcases, e.Position-1); err != nil {
+				return err
+			}
+		}
+		for i := range remap {
+			remap[i] = i
+		}
+	case TestcaseEditDelete:
+		if err := inRange(e.Position); err != nil {
+			return err
+		}
+		if n == 1 {
+			return fmt.Errorf("%w: 最後のテストケースは削除できません", ErrTestcaseEditInput)
+		}
+		testcases = append(testcases, pkg.Testcases[:e.Position-1]...)
+		testcases = append(testcases, pkg.Testcases[e.Position:]...)
+		for i := range remap {
+			switch {
+			case i < e.Position-1:
+				remap[i] = i
+			case i == e.Position-1:
+				remap[i] = -1
+			default:
+				remap[i] = i - 1
+			}
+		}
+	case TestcaseEditReorder:
+		if len(e.Order) != n {
+			return fmt.Errorf("%w: order には全 %d 件の位置を並べてください", ErrTestcaseEditInput, n)
+		}
+		seen := make([]bool, n)
+		for newIdx, pos := range e.Order {
+			if pos < 1 || pos > n || seen[pos-1] {
+				return fmt.Errorf("%w: order は 1〜%d を 1 回ずつ並べてください", ErrTestcaseEditInput, n)
+			}
+			seen[pos-1] = true
+			remap[pos-1] = newIdx
+			testcases = append(testcases, pkg.Testcases[pos-1])
+		}
+	default:
+		return fmt.Errorf("%w: unknown operation %q", ErrTestcaseEditInput, e.Op)
+	}
+
+	for _, tc := range testcases {
+		if strings.TrimSpace(tc.InputText) == "" || strings.TrimSpace(tc.OutputText) == "" {
+			return fmt.Errorf("%w: input と output は空にできません", ErrTestcaseEditInput)
+		}
+	}
+	pkg.Testcases = testcases
+	for i := range pkg.Subtasks {
+		pkg.Subtasks[i].TestcaseIndexes = remapTestcaseIndexes(pkg.Subtasks[i].TestcaseIndexes, remap)
+	}
+	for i := range pkg.Groups {
+		pkg.Groups[i].TestcaseIndexes = remapTestcaseIndexes(pkg.Groups[i].TestcaseIndexes, remap)
+	}
+	return nil
+}
+
+func remapTestcaseIndexes(idxs, remap []int) []int {
+	out := make([]int, 0, len(idxs))
+	for _, idx := range idxs {
+		if idx >= 0 && idx < len(remap) && remap[idx] >= 0 {
+			out = append(out, remap[idx])
+		}
+	}
+	return out
+}
+
+// setTestcaseName stores name (sample/01 形式) as the paths of tc; it must not be used by another
+// testcase than the one at self.
+func setTestcaseName(tc *ProblemTestcaseInput, name string, testcases []ProblemTestcaseInput, self int) error {
+	name = strings.TrimSpace(name)
+	if !testcaseNamePattern.MatchString(name) || strings.Contains(name, "..") {
+		return fmt.Errorf("%w: name は sample/01 のような英数字の名前で指定してください", ErrTestcaseEditInput)
+	}
+	for i, other := range testcases {
+		if i != self && importTestcaseKey(other.InputPath, i) == name {
+			return fmt.Errorf("%w: name %q は既に使われています", ErrTestcaseEditInput, name)
+		}
+	}
+	tc.InputPath, tc.OutputPath = "data/"+name+".in", "data/"+name+".out"
+	return nil
+}
+
+// nextTestcaseName returns the first unused sample/NN or secret/NN name.
+func nextTestcaseName(testcases []ProblemTestcaseInput, sample bool) string {
+	prefix := "secret"
+	if sample {
+		prefix = "sample"
+	}
+	used := map[string]bool{}
+	for i, tc := range testcases {
+		used[importTestcaseKey(tc.InputPath, i)] = true
+	}
+	for k := 1; ; k++ {
+		if name := fmt.Sprintf("%s/%02d", prefix, k); !used[name] {
+			return name
+		}
+	}
+}
+
+// TestcasePackage returns the problem as a package, for listing and editing its testcases. With
+// pending the staged package is returned when there is one; staged reports that case.
+func (r *PgProblemRepository) TestcasePackage(ctx context.Context, id int64, pending bool) (*ProblemCreateInput, bool, error) {
+	return currentProblemPackage(ctx, r.db, id, pending)
+}
+
+func currentProblemPackage(ctx context.Context, q pgQuerier, id int64, pending bool) (*ProblemCreateInput, bool, error) {
+	status, err := loadPublishStatus(ctx, q, id, false)
+	if err != nil {
+		return nil, false, err
+	}
+	if pending && status.PendingPackage != nil {
+		return status.PendingPackage, true, nil
+	}
+	var slug string
+	if err := q.QueryRow(ctx, `SELECT slug FROM problems WHERE id=$1`, id).Scan(&slug); err != nil {
+		return nil, false, err
+	}
+	settings, err := loadProblemRevisionSettings(ctx, q, id)
+	if err != nil {
+		return nil, false, err
+	}
+	content, err := loadProblemRevisionContent(ctx, q, id)
+	if err != nil {
+		return nil, false, err
+	}
+	pkg := revisionPackageInput(slug, &ProblemRevision{Settings: &settings, Content: &content})
+	return &pkg, false, nil
+}
+
+// EditTestcases applies edit to the testcases and stores the result like a package overwrite, so
+// the change is versioned and checked against the testcase limits. With reviewRequired the change to
+// a published problem is staged (on top of an already staged package); staged reports that case.
+func (r *PgProblemRepository) EditTestcases(ctx context.Context, id int64, edit TestcaseEdit, editedBy *int64, reviewRequired bool) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	stage, err := lockProblemForChange(ctx, tx, id, reviewRequired)
+	if err != nil {
+		return false, err
+	}
+	pkg, _, err := currentProblemPackage(ctx, tx, id, stage)
+	if err != nil {
+		return false, err
+	}
+	if err := edit.apply(pkg); err != nil {
+		return false, err
+	}
+	if err := validateProblemCreateInput(pkg); err != nil {
+		return false, err
+	}
+	if stage {
+		err = stageProblemPackage(ctx, tx, id, *pkg)
+	} else {
+		err = replaceWithPackageTx(ctx, tx, id, *pkg, editedBy)
+	}
+	if err != nil {
+		return false, err
+	}
+	return stage, tx.Commit(ctx)
+}
@@ -0,0 +1,67 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTestcaseEditApply(t *testing.T) {
+	newPkg := func() *ProblemCreateInput {
+		return &ProblemCreateInput{
+			Testcases: []ProblemTestcaseInput{
+				{InputPath: "data/sample/01.in", InputText: "1\n", OutputText: "1\n", IsSample: true},
+				{InputPath: "data/secret/01.in", InputText: "2\n", OutputText: "2\n"},
+				{InputPath: "data/secret/02.in", InputText: "3\n", OutputText: "3\n"},
+			},
+			Subtasks: []ProblemSubtaskInput{{Name: "all", Score: 100, TestcaseIndexes: []int{1, 2}}},
+			Groups:   []ProblemTestcaseGroupInput{{Name: "large", TestcaseIndexes: []int{2}}},
+		}
+	}
+	names := func(pkg *ProblemCreateInput) []string {
+		out := []string{}
+		for _, tc := range summarizeTestcases(*pkg) {
+			out = append(out, tc.Name)
+		}
+		return out
+	}
+
+	pkg := newPkg()
+	input, output := "4\n", "4\n"
+	if err := (TestcaseEdit{Op: TestcaseEditAdd, Position: 2, Input: &input, Output: &output}).apply(pkg); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := names(pkg), []string{"sample/01", "secret/03", "secret/01", "secret/02"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("after add = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(pkg.Subtasks[0].TestcaseIndexes, []int{2, 3}) || !reflect.DeepEqual(pkg.Groups[0].TestcaseIndexes, []int{3}) {
+		t.Fatalf("indexes after add = %v %v", pkg.Subtasks[0].TestcaseIndexes, pkg.Groups[0].TestcaseIndexes)
+	}
+
+	pkg = newPkg()
+	if err := (TestcaseEdit{Op: TestcaseEditDelete, Position: 2}).apply(pkg); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pkg.Subtasks[0].TestcaseIndexes, []int{1}) || !reflect.DeepEqual(pkg.Groups[0].TestcaseIndexes, []int{1}) {
+		t.Fatalf("indexes after delete = %v %v", pkg.Subtasks[0].TestcaseIndexes, pkg.Groups[0].TestcaseIndexes)
+	}
+
+	pkg = newPkg()
+	if err := (TestcaseEdit{Op: TestcaseEditReorder, Order: []int{3, 1, 2}}).apply(pkg); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := names(pkg), []string{"secret/02", "sample/01", "secret/01"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("after reorder = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(pkg.Subtasks[0].TestcaseIndexes, []int{2, 0}) || !reflect.DeepEqual(pkg.Groups[0].TestcaseIndexes, []int{0}) {
+		t.Fatalf("indexes after reorder = %v %v", pkg.Subtasks[0].TestcaseIndexes, pkg.Groups[0].TestcaseIndexes)
+	}
+
+	name := "sample/01"
+	if err := (TestcaseEdit{Op: TestcaseEditReplace, Position: 3, Name: &name}).apply(newPkg()); !errors.Is(err, ErrTestcaseEditInput) {
+		t.Fatalf("duplicate name error = %v", err)
+	}
+	if err := (TestcaseEdit{Op: TestcaseEditReorder, Order: []int{1, 1, 2}}).apply(newPkg()); !errors.Is(err, ErrTestcaseEditInput) {
+		t.Fatalf("invalid order error = %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ProblemTestcaseGroupInput defines a testcase group at creation. Nil limits inherit the problem's limits;
+// TestcaseIndexes point into ProblemCreateInput.Testcases.
+type ProblemTestcaseGroupInput struct {
+	Name            string
+	TimeLimitMS     *int32
+	MemoryLimitKB   *int32
+	TestcaseIndexes []int
+}
+
+type problemGroupDoc struct {
+	Name      string   `yaml:"name"`
+	Testcases []string `yaml:"testcases"`
+	Limits    struct {
+		TimeMS   int `yaml:"time_ms"`
+		MemoryMB int `yaml:"memory_mb"`
+	} `yaml:"limits"`
+}
+
+// resolveTestcaseGroups maps problem.yaml groups to testcase indexes. A testcase may belong to one group only.
+func resolveTestcaseGroups(docs []problemGroupDoc, keys []string) ([]ProblemTestcaseGroupInput, error) {
+	var out []ProblemTestcaseGroupInput
+	names := map[string]bool{}
+	owner := map[int]string{}
+	for i, d := range docs {
+		name := strings.TrimSpace(d.Name)
+		if name == "" {
+			return nil, fmt.Errorf("groups[%d].name は必須です", i)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("groups の name %q が重複しています", name)
+		}
+		names[name] = true
+		if d.Limits.TimeMS < 0 || d.Limits.MemoryMB < 0 {
+			return nil, fmt.Errorf("groups[%d].limits は 0 より大きい値を指定してください", i)
+		}
+		if len(d.Testcases) == 0 {
+			return nil, fmt.Errorf("groups[%d].testcases が空です", i)
+		}
+		idxs, err := matchTestcasePatterns(d.Testcases, keys)
+		if err != nil {
+			return nil, fmt.Errorf("groups[%d] %w", i, err)
+		}
+		for _, k := range idxs {
+			if other, ok := owner[k]; ok {
+				return nil, fmt.Errorf("%s がグループ %q と %q の両方に含まれています", keys[k], other, name)
+			}
+			owner[k] = name
+		}
+		g := ProblemTestcaseGroupInput{Name: name, TestcaseIndexes: idxs}
+		if d.Limits.TimeMS > 0 {
+			g.TimeLimitMS = ptr(int32(d.Limits.TimeMS))
+		}
+		if d.Limits.MemoryMB > 0 {
+			g.MemoryLimitKB = ptr(int32(d.Limits.MemoryMB * 1024))
+		}
+		out = append(out, g)
+	}
+	return out, nil
+}
+
+// insertTestcaseGroupsTx stores groups and assigns their testcases; testcaseIDs are in input order.
+func insertTestcaseGroupsTx(ctx context.Context, tx pgx.Tx, problemID int64, groups []ProblemTestcaseGroupInput, testcaseIDs []int64) error {
+	for _, g := range groups {
+		var groupID int64
+		if err := tx.QueryRow(ctx, `INSERT INTO problem_testcase_groups (problem_id, name, time_limit_ms, memory_limit_kb) VALUES ($1,$2,$3,$4) RETURNING id`,
+			problemID, g.Name, g.TimeLimitMS, g.MemoryLimitKB).Scan(&groupID); err != nil {
+			return err
+		}
+		ids := make([]int64, 0, len(g.TestcaseIndexes))
+		for _, idx := range g.TestcaseIndexes {
+			if idx < 0 || idx >= len(testcaseIDs) {
+				return errors.New("group testcase index out of range")
+			}
+			ids = append(ids, testcaseIDs[idx])
+		}
+		if _, err := tx.Exec(ctx, `UPDATE testcases SET group_id=$1 WHERE id = ANY($2)`, groupID, ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Per-problem testcase guardrails protecting the shared judge capacity (0 = unlimited). Set them
+// once at startup with SetProblemTestcaseLimits.
+var (
+	maxProblemTestcases     = 100
+	maxProblemTestcaseBytes int64
+	// judgeCostWarnMS is the worst-case judge time (time limit × testcases) above which imports and
+	// limit changes report a warning.
+	judgeCostWarnMS int64 = 120 * 1000
+)
+
+// ErrProblemTestcaseLimit is returned when a problem has too many or too large testcases.
+var ErrProblemTestcaseLimit = errors.New("problem testcase limit exceeded")
+
+// SetProblemTestcaseLimits sets the testcase count cap, the total testcase size cap in MB and the
+// judge cost warning threshold in seconds; negative values keep the default, 0 disables the check.
+func SetProblemTestcaseLimits(count, totalMB, costWarnSec int) {
+	if count >= 0 {
+		maxProblemTestcases = count
+	}
+	if totalMB >= 0 {
+		maxProblemTestcaseBytes = int64(totalMB) * 1024 * 1024
+	}
+	if costWarnSec >= 0 {
+		judgeCostWarnMS = int64(costWarnSec) * 1000
+	}
+}
+
+// checkTestcaseLimits enforces the testcase count and total size (inputs and expected outputs).
+func checkTestcaseLimits(tcs []ProblemTestcaseInput) error {
+	if maxProblemTestcases > 0 && len(tcs) > maxProblemTestcases {
+		return fmt.Errorf("%w: テストケースが多すぎます（%d 件、上限 %d 件）", ErrProblemTestcaseLimit, len(tcs), maxProblemTestcases)
+	}
+	if maxProblemTestcaseBytes <= 0 {
+		return nil
+	}
+	var total int64
+	for _, tc := range tcs {
+		total += int64(len(tc.InputText) + len(tc.OutputText))
+	}
+	if total > maxProblemTestcaseBytes {
+		return fmt.Errorf("%w: テストケースの合計サイズが大きすぎます（%.1fMB、上限 %dMB）", ErrProblemTestcaseLimit,
+			float64(total)/1024/1024, maxProblemTestcaseBytes/1024/1024)
+	}
+	return nil
+}
+
+// judgeCostWarning describes the worst-case judge time of one submission (every testcase running
+// to the time limit) when it exceeds the threshold; "" otherwise.
+func judgeCostWarning(timeLimitMS int32, testcases int) string {
+	cost := int64(timeLimitMS) * int64(testcases)
+	if judgeCostWarnMS <= 0 || cost <= judgeCostWarnMS {
+		return ""
+	}
+	return fmt.Sprintf("1 提出あたりの最大ジャッジ時間が %.1f 秒（%d ms × %d ケース）で、目安の %d 秒を超えています。制限時間かテストケース数の見直しを検討してください",
+		float64(cost)/1000, timeLimitMS, testcases, judgeCostWarnMS/1000)
+}
+
+func (r *PgProblemRepository) TestcaseCount(ctx context.Context, id int64) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM testcases WHERE problem_id=$1`, id).Scan(&n)
+	return n, err
+}
@@ -0,0 +1,29 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckTestcaseLimits(t *testing.T) {
+	defer func(count int, size, cost int64) {
+		maxProblemTestcases, maxProblemTestcaseBytes, judgeCostWarnMS = count, size, cost
+	}(maxProblemTestcases, maxProblemTestcaseBytes, judgeCostWarnMS)
+	SetProblemTestcaseLimits(2, 1, 10)
+
+	tc := ProblemTestcaseInput{InputText: "1\n", OutputText: "1\n"}
+	if err := checkTestcaseLimits([]ProblemTestcaseInput{tc, tc}); err != nil {
+		t.Fatalf("within limits: %v", err)
+	}
+	if err := checkTestcaseLimits([]ProblemTestcaseInput{tc, tc, tc}); !errors.Is(err, ErrProblemTestcaseLimit) {
+		t.Fatalf("count limit not enforced: %v", err)
+	}
+	big := ProblemTestcaseInput{InputText: strings.Repeat("x", 1024*1024), OutputText: "1\n"}
+	if err := checkTestcaseLimits([]ProblemTestcaseInput{big}); !errors.Is(err, ErrProblemTestcaseLimit) {
+		t.Fatalf("size limit not enforced: %v", err)
+	}
+	if judgeCostWarning(2000, 5) != "" || judgeCostWarning(2000, 6) == "" {
+		t.Fatal("judge cost warning threshold is 10s")
+	}
+}
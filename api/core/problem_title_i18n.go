@@ -0,0 +1,75 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LocalizedTitles are the optional Japanese / English names of a problem. Lists show the one for
+// the request's locale and fall back to title.
+type LocalizedTitles struct {
+	TitleJA *string `json:"title_ja,omitempty"`
+	TitleEN *string `json:"title_en,omitempty"`
+}
+
+// For returns the title for locale, or fallback when there is none.
+func (t LocalizedTitles) For(locale, fallback string) string {
+	var v *string
+	switch locale {
+	case "ja":
+		v = t.TitleJA
+	case "en":
+		v = t.TitleEN
+	}
+	if v == nil || strings.TrimSpace(*v) == "" {
+		return fallback
+	}
+	return *v
+}
+
+// requestLocale picks the locale for localized names: ?lang=, then the user's preference, then
+// Accept-Language. It returns "" when none of them is supported.
+func requestLocale(c *gin.Context, user *UserRecord) string {
+	if locale, err := normalizeLocale(c.Query("lang")); err == nil {
+		return locale
+	}
+	if user != nil {
+		if locale, err := normalizeLocale(user.Locale); err == nil {
+			return locale
+		}
+	}
+	for _, part := range strings.Split(c.GetHeader("Accept-Language"), ",") {
+		tag, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+		primary, _, _ := strings.Cut(tag, "-")
+		if locale, err := normalizeLocale(primary); err == nil {
+			return locale
+		}
+	}
+	return ""
+}
+
+func localizeProblemMetas(list []ProblemMeta, locale string) {
+	for i := range list {
+		list[i].Title = list[i].LocalizedTitles.For(locale, list[i].Title)
+	}
+}
+
+func localizeContestProblems(list []ContestProblem, locale string) {
+	for i := range list {
+		list[i].Title = list[i].LocalizedTitles.For(locale, list[i].Title)
+	}
+}
+
+// localizedTitlesYAML returns the title_ja / title_en lines of problem.yaml for the set names.
+func localizedTitlesYAML(t LocalizedTitles) string {
+	var b strings.Builder
+	if t.TitleJA != nil {
+		fmt.Fprintf(&b, "title_ja: %q\n", *t.TitleJA)
+	}
+	if t.TitleEN != nil {
+		fmt.Fprintf(&b, "title_en: %q\n", *t.TitleEN)
+	}
+	return b.String()
+}
@@ -0,0 +1,35 @@
+package core
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestLocale(t *testing.T) {
+	cases := []struct {
+		query, acceptLanguage string
+		user                  *UserRecord
+		want                  string
+	}{
+		{"?lang=en", "ja", &UserRecord{Locale: "ja"}, "en"},
+		{"", "en-US,en;q=0.9", &UserRecord{Locale: "ja"}, "ja"},
+		{"?lang=fr", "fr-FR, en-GB;q=0.8", nil, "en"},
+		{"", "de", nil, ""},
+	}
+	for _, tc := range cases {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/api/v1/problems"+tc.query, nil)
+		c.Request.Header.Set("Accept-Language", tc.acceptLanguage)
+		if got := requestLocale(c, tc.user); got != tc.want {
+			t.Errorf("%q / %q: got %q, want %q", tc.query, tc.acceptLanguage, got, tc.want)
+		}
+	}
+
+	en := "Two Strings"
+	titles := LocalizedTitles{TitleEN: &en}
+	if titles.For("en", "二つの文字列") != en || titles.For("ja", "二つの文字列") != "二つの文字列" || titles.For("", "二つの文字列") != "二つの文字列" {
+		t.Fatal("unexpected localized title")
+	}
+}
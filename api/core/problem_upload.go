@@ -0,0 +1,272 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UploadTTL is how long an unfinished resumable upload is kept after it was created.
+const UploadTTL = 24 * time.Hour
+
+var (
+	ErrUploadNotFound       = errors.New("upload not found")
+	ErrUploadOffsetMismatch = errors.New("upload offset mismatch")
+	ErrUploadTooLarge       = errors.New("upload exceeds declared size")
+	ErrUploadIncomplete     = errors.New("upload is incomplete")
+	ErrUploadBusy           = errors.New("upload is receiving another chunk")
+)
+
+var uploadIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// Upload is a resumable upload of a problem package. Offset is the number of bytes received so far.
+type Upload struct {
+	ID        string    `json:"id"`
+	OwnerID   int64     `json:"owner_id"`
+	Filename  string    `json:"filename"`
+	Size      int64     `json:"size"`
+	Offset    int64     `json:"offset"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Complete reports whether all declared bytes have been received.
+func (u *Upload) Complete() bool {
+	return u.Offset == u.Size
+}
+
+// UploadStore keeps resumable uploads on disk: <id>.part holds the bytes received so far and
+// <id>.json the metadata. A chunk interrupted mid-transfer keeps what arrived, so the client
+// resumes from the offset reported by Get. Uploads are locked one by one and never while a chunk
+// is read from the network, so a stalled chunk holds up neither other uploads nor status requests.
+type UploadStore struct {
+	dir     string
+	mu      sync.Mutex // entries のみを守る
+	entries map[string]*uploadEntry
+}
+
+// uploadEntry serializes the operations on one upload. appending is set while a chunk is being
+// received; a second chunk for the same upload is refused meanwhile.
+type uploadEntry struct {
+	mu        sync.Mutex
+	refs      int
+	appending bool
+}
+
+func NewUploadStore(dir string) *UploadStore {
+	return &UploadStore{dir: dir, entries: map[string]*uploadEntry{}}
+}
+
+func (s *UploadStore) partPath(id string) string { return filepath.Join(s.dir, id+".part") }
+func (s *UploadStore) metaPath(id string) string { return filepath.Join(s.dir, id+".json") }
+
+// acquire returns the entry of id, to be locked by the caller and handed back with release.
+func (s *UploadStore) acquire(id string) *uploadEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.entries[id]
+	if e == nil {
+		e = &uploadEntry{}
+		s.entries[id] = e
+	}
+	e.refs++
+	return e
+}
+
+func (s *UploadStore) release(id string, e *uploadEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e.refs--; e.refs == 0 {
+		delete(s.entries, id)
+	}
+}
+
+// locked runs fn with the upload id locked.
+func (s *UploadStore) locked(id string, fn func(e *uploadEntry) error) error {
+	e := s.acquire(id)
+	defer s.release(id, e)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return fn(e)
+}
+
+// Create starts an upload of size bytes and removes expired ones.
+func (s *UploadStore) Create(ownerID int64, filename string, size int64) (*Upload, error) {
+	s.purgeExpired()
+
+	now := time.Now()
+	u := &Upload{
+		ID:        randomHex(16),
+		OwnerID:   ownerID,
+		Filename:  filepath.Base(strings.TrimSpace(filename)),
+		Size:      size,
+		CreatedAt: now,
+		ExpiresAt: now.Add(UploadTTL),
+	}
+	meta, err := json.Marshal(u)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(s.partPath(u.ID), nil, 0o644); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(s.metaPath(u.ID), meta, 0o644); err != nil {
+		_ = os.Remove(s.partPath(u.ID))
+		return nil, err
+	}
+	return u, nil
+}
+
+// Get returns the upload with its current offset.
+func (s *UploadStore) Get(id string) (*Upload, error) {
+	var u *Upload
+	err := s.locked(id, func(*uploadEntry) (err error) {
+		u, err = s.load(id)
+		return err
+	})
+	return u, err
+}
+
+func (s *UploadStore) load(id string) (*Upload, error) {
+	if !uploadIDPattern.MatchString(id) {
+		return nil, ErrUploadNotFound
+	}
+	meta, err := os.ReadFile(s.metaPath(id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrUploadNotFound
+		}
+		return nil, err
+	}
+	var u Upload
+	if err := json.Unmarshal(meta, &u); err != nil {
+		return nil, err
+	}
+	if time.Now().After(u.ExpiresAt) {
+		s.remove(id)
+		return nil, ErrUploadNotFound
+	}
+	info, err := os.Stat(s.partPath(id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrUploadNotFound
+		}
+		return nil, err
+	}
+	u.Offset = info.Size()
+	return &u, nil
+}
+
+// Append writes the chunk read from r at offset, which must equal the current offset.
+// Bytes beyond the declared size are rejected and discarded. While the chunk is received the
+// upload only refuses other chunks (ErrUploadBusy); its status stays readable.
+func (s *UploadStore) Append(id string, offset int64, r io.Reader) (*Upload, error) {
+	e := s.acquire(id)
+	defer s.release(id, e)
+
+	e.mu.Lock()
+	u, err := s.load(id)
+	if err == nil && e.appending {
+		err = ErrUploadBusy
+	} else if err == nil && offset != u.Offset {
+		err = ErrUploadOffsetMismatch
+	}
+	if err != nil {
+		e.mu.Unlock()
+		return u, err
+	}
+	e.appending = true
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		e.appending = false
+		e.mu.Unlock()
+	}()
+
+	f, err := os.OpenFile(s.partPath(id), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	n, copyErr := io.Copy(f, io.LimitReader(r, u.Size-u.Offset+1))
+	u.Offset += n
+	if u.Offset > u.Size {
+		if err := f.Truncate(u.Size); err != nil {
+			return nil, err
+		}
+		u.Offset = u.Size
+		return u, ErrUploadTooLarge
+	}
+	if copyErr != nil {
+		// 途中で切れたチャンクも受信済みの分は残し、次回はそのオフセットから再開する
+		log.Printf("[upload] %s interrupted at %d/%d: %v", id, u.Offset, u.Size, copyErr)
+		return u, copyErr
+	}
+	return u, nil
+}
+
+// OpenComplete opens the uploaded file with its size once every declared byte has been received.
+// The caller closes the file; it stays readable even if the upload is deleted meanwhile.
+func (s *UploadStore) OpenComplete(id string) (*os.File, int64, error) {
+	var f *os.File
+	var size int64
+	err := s.locked(id, func(e *uploadEntry) error {
+		u, err := s.load(id)
+		if err != nil {
+			return err
+		}
+		if !u.Complete() || e.appending {
+			return ErrUploadIncomplete
+		}
+		if f, err = os.Open(s.partPath(id)); err != nil {
+			return err
+		}
+		size = u.Size
+		return nil
+	})
+	return f, size, err
+}
+
+// Delete discards the upload.
+func (s *UploadStore) Delete(id string) error {
+	return s.locked(id, func(*uploadEntry) error {
+		if _, err := s.load(id); err != nil {
+			return err
+		}
+		s.remove(id)
+		return nil
+	})
+}
+
+func (s *UploadStore) remove(id string) {
+	_ = os.Remove(s.partPath(id))
+	_ = os.Remove(s.metaPath(id))
+}
+
+func (s *UploadStore) purgeExpired() {
+	matches, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return
+	}
+	for _, m := range matches {
+		id := strings.TrimSuffix(filepath.Base(m), ".json")
+		if !uploadIDPattern.MatchString(id) {
+			continue
+		}
+		// load は期限切れのアップロードを削除する
+		err := s.locked(id, func(*uploadEntry) error {
+			_, err := s.load(id)
+			return err
+		})
+		if errors.Is(err, ErrUploadNotFound) {
+			log.Printf("[upload] purged expired upload %s", id)
+		}
+	}
+}
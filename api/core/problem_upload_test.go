@@ -0,0 +1,54 @@
+package core
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestUploadStoreStalledChunk(t *testing.T) {
+	store := NewUploadStore(t.TempDir())
+	u, err := store.Create(1, "a.zip", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 受信が止まったチャンクの間も状態の取得や他のアップロードは待たされない
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := store.Append(u.ID, 0, pr)
+		done <- err
+	}()
+	if _, err := pw.Write([]byte("0123")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(u.ID); err != nil {
+		t.Fatalf("get during append: %v", err)
+	}
+	if _, err := store.Create(1, "b.zip", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Append(u.ID, 4, strings.NewReader("x")); !errors.Is(err, ErrUploadBusy) {
+		t.Fatalf("concurrent chunk: %v", err)
+	}
+	if _, _, err := store.OpenComplete(u.ID); !errors.Is(err, ErrUploadIncomplete) {
+		t.Fatalf("open while incomplete: %v", err)
+	}
+	_, _ = pw.Write([]byte("456789"))
+	_ = pw.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	f, size, err := store.OpenComplete(u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	data, _ := io.ReadAll(f)
+	if size != 10 || string(data) != "0123456789" {
+		t.Fatalf("complete upload: %d %q", size, data)
+	}
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// ProblemVisibility holds the inputs of the visibility policy for one problem.
+type ProblemVisibility struct {
+	ProblemID     int64
+	IsPublic      bool
+	ContestID     *int64
+	ContestPublic bool
+	ContestStart  time.Time
+	ContestEnd    time.Time
+	// ContestsEnd is the latest end of the contests that list the problem (contest_problems), or
+	// the zero time when there are none.
+	ContestsEnd time.Time
+	// Unpublished is set until the problem is first approved (see problem_publish.go).
+	Unpublished bool
+}
+
+// ProblemAccess is the outcome of the visibility policy for a viewer.
+type ProblemAccess struct {
+	Visible     bool
+	Submittable bool
+	// ContestID is set when submissions should be recorded for the owning (running) contest.
+	ContestID *int64
+	Reason    string
+}
+
+// ContestPhase returns the phase of the owning contest, or "" for practice problems.
+func (v ProblemVisibility) ContestPhase(now time.Time) string {
+	if v.ContestID == nil {
+		return ""
+	}
+	return Contest{StartAt: v.ContestStart, EndAt: v.ContestEnd}.Phase(now)
+}
+
+// AccessFor applies the policy:
+//   - 管理者は常に閲覧・提出可
+//   - 一度も承認されていない（未公開の）問題は管理者以外には見えない
+//   - コンテストに紐付かない問題は is_public に従う
+//   - 紐付く問題は開始前は非公開、開催中は参加登録者のみ、終了後は練習問題として公開
+//     （非公開コンテストは参加コードで登録した参加者のみ）
+func (v ProblemVisibility) AccessFor(isAdmin, registered bool, now time.Time) ProblemAccess {
+	phase := v.ContestPhase(now)
+	if isAdmin {
+		access := ProblemAccess{Visible: true, Submittable: true}
+		if phase == ContestPhaseRunning {
+			access.ContestID = v.ContestID
+		}
+		return access
+	}
+	if v.Unpublished {
+		return ProblemAccess{Reason: "非公開の問題です"}
+	}
+	switch phase {
+	case "":
+		if !v.IsPublic {
+			return ProblemAccess{Reason: "非公開の問題です"}
+		}
+		return ProblemAccess{Visible: true, Submittable: true}
+	case ContestPhaseUpcoming:
+		return ProblemAccess{Reason: "コンテスト開始前の問題です"}
+	case ContestPhaseRunning:
+		if !registered {
+			return ProblemAccess{Reason: "コンテスト参加者のみ閲覧できます"}
+		}
+		return ProblemAccess{Visible: true, Submittable: true, ContestID: v.ContestID}
+	default:
+		if !v.ContestPublic && !registered {
+			return ProblemAccess{Reason: "非公開の問題です"}
+		}
+		return ProblemAccess{Visible: true, Submittable: true}
+	}
+}
+
+// resolveProblemAccess loads visibility and registration state and evaluates the policy.
+func resolveProblemAccess(ctx context.Context, problemRepo ProblemRepository, contestRepo ContestRepository, user *UserRecord, problemID int64) (ProblemAccess, error) {
+	_, access, err := resolveProblemVisibility(ctx, problemRepo, contestRepo, user, problemID)
+	return access, err
+}
+
+// resolveProblemVisibility is resolveProblemAccess that also returns the policy inputs.
+func resolveProblemVisibility(ctx context.Context, problemRepo ProblemRepository, contestRepo ContestRepository, user *UserRecord, problemID int64) (*ProblemVisibility, ProblemAccess, error) {
+	v, err := problemRepo.Visibility(ctx, problemID)
+	if err != nil {
+		return nil, ProblemAccess{}, err
+	}
+	now := time.Now()
+	isStaff := isStaffRole(user.Role)
+	registered := false
+	phase := v.ContestPhase(now)
+	if !isStaff && (phase == ContestPhaseRunning || (phase == ContestPhaseEnded && !v.ContestPublic)) {
+		if registered, err = contestRepo.IsRegistered(ctx, *v.ContestID, user.ID); err != nil {
+			return nil, ProblemAccess{}, err
+		}
+	}
+	access := v.AccessFor(isStaff, registered, now)
+	// 開催中の試験で問題プールから別の問題が割り当てられている参加者には見せない
+	if access.Visible && !isStaff && phase == ContestPhaseRunning {
+		problems, err := contestRepo.ListProblems(ctx, *v.ContestID)
+		if err != nil {
+			return nil, ProblemAccess{}, err
+		}
+		if containsContestProblem(problems, problemID) && !containsContestProblem(assignContestProblems(*v.ContestID, user.ID, problems), problemID) {
+			access = ProblemAccess{Reason: "この問題は割り当てられていません"}
+		}
+	}
+	return v, access, nil
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"context"
+	"strings"
+)
+
+// Backpressure policies (QUEUE_BACKPRESSURE_POLICY).
+const (
+	BackpressureDelay  = "delay"  // 受け付けて 202 と長めの待ち時間の目安を返す
+	BackpressureReject = "reject" // 429 で再送を促す
+)
+
+// backpressureSecondsPerJob is the rough judge time of one submission used for the ETA.
+const backpressureSecondsPerJob = 2
+
+// Backpressure is the queue state reported when the pending depth crosses the threshold.
+type Backpressure struct {
+	Policy     string `json:"policy"`
+	Pending    int64  `json:"queue_pending"`
+	ETASeconds int    `json:"eta_seconds"`
+}
+
+// checkBackpressure returns nil while the pending queue is below QUEUE_BACKPRESSURE_DEPTH.
+// The ETA assumes every live worker judges at full concurrency.
+func checkBackpressure(ctx context.Context, cfg Config, metrics *MetricsService) (*Backpressure, error) {
+	if cfg.QueueBackpressureDepth <= 0 {
+		return nil, nil
+	}
+	queue, err := metrics.Queue(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if queue.Pending < int64(cfg.QueueBackpressureDepth) {
+		return nil, nil
+	}
+	workers, err := metrics.Workers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slots := 0
+	for _, w := range workers {
+		slots += w.Concurrency
+	}
+	if slots <= 0 {
+		slots = 1
+	}
+	policy := BackpressureDelay
+	if strings.EqualFold(strings.TrimSpace(cfg.QueueBackpressurePolicy), BackpressureReject) {
+		policy = BackpressureReject
+	}
+	jobs := int(queue.Pending) + 1
+	return &Backpressure{
+		Policy:     policy,
+		Pending:    queue.Pending,
+		ETASeconds: (jobs + slots - 1) / slots * backpressureSecondsPerJob,
+	}, nil
+}
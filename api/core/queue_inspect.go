@@ -0,0 +1,125 @@
+package core
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Queue states accepted by GET /admin/queues/items.
+const (
+	QueueStatePending    = "pending"
+	QueueStateProcessing = "processing"
+)
+
+const (
+	DefaultQueueItemsLimit = 100
+	MaxQueueItemsLimit     = 1000
+)
+
+// SubmissionQueueState is the database side of a queued submission.
+type SubmissionQueueState struct {
+	Status    string
+	ProblemID int64
+	CreatedAt time.Time
+	// UpdatedAt is when the status last changed: when it was queued (pending) or picked up (running).
+	UpdatedAt time.Time
+}
+
+// QueueItem is one submission in the pending or processing queue, cross-checked with the database.
+type QueueItem struct {
+	SubmissionID int64 `json:"submission_id"`
+	// Position is the order in which pending items are taken (1 = next).
+	Position  int        `json:"position,omitempty"`
+	ProblemID *int64     `json:"problem_id"`
+	DBStatus  string     `json:"db_status"` // "missing" when the submission no longer exists
+	CreatedAt *time.Time `json:"submitted_at"`
+	// EnqueuedAt is when a pending item was queued (the submission's last change to pending).
+	EnqueuedAt *time.Time `json:"enqueued_at,omitempty"`
+	// StartedAt is when a processing item was picked up by a worker.
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+	ReservedUntil *time.Time `json:"reserved_until,omitempty"`
+	// Expired marks a processing item past its reservation; it is requeued on the next sweep.
+	Expired bool `json:"expired,omitempty"`
+	// Mismatch is set when the database disagrees with the queue: missing (deleted) or finished
+	// (already judged, the queue entry is stale).
+	Mismatch string `json:"mismatch,omitempty"`
+}
+
+// QueueItems is the response of GET /admin/queues/items.
+type QueueItems struct {
+	State   string      `json:"state"`
+	Total   int64       `json:"total"`
+	Items   []QueueItem `json:"items"`
+	TakenAt time.Time   `json:"taken_at"`
+}
+
+// InspectQueue lists up to limit items of a queue: pending ones in the order they will be taken,
+// processing ones by reservation deadline.
+func InspectQueue(ctx context.Context, client *redis.Client, subRepo SubmissionRepository, state string, limit int) (*QueueItems, error) {
+	now := time.Now()
+	out := &QueueItems{State: state, Items: []QueueItem{}, TakenAt: now.UTC()}
+	pipe := client.TxPipeline()
+	var rangeCmd *redis.StringSliceCmd
+	var zrangeCmd *redis.ZSliceCmd
+	var countCmd *redis.IntCmd
+	if state == QueueStatePending {
+		countCmd = pipe.LLen(ctx, PendingQueueKey)
+		// LPUSH で積み RPOP で取り出すので、次に取り出されるのは末尾
+		rangeCmd = pipe.LRange(ctx, PendingQueueKey, int64(-limit), -1)
+	} else {
+		countCmd = pipe.ZCard(ctx, ProcessingQueueKey)
+		zrangeCmd = pipe.ZRangeWithScores(ctx, ProcessingQueueKey, 0, int64(limit-1))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	out.Total = countCmd.Val()
+
+	var ids []int64
+	if rangeCmd != nil {
+		values := rangeCmd.Val()
+		for i := len(values) - 1; i >= 0; i-- {
+			if id, err := strconv.ParseInt(values[i], 10, 64); err == nil {
+				out.Items = append(out.Items, QueueItem{SubmissionID: id, Position: len(values) - i})
+				ids = append(ids, id)
+			}
+		}
+	} else {
+		for _, z := range zrangeCmd.Val() {
+			member, _ := z.Member.(string)
+			if id, err := strconv.ParseInt(member, 10, 64); err == nil {
+				until := time.UnixMilli(int64(z.Score)).UTC()
+				out.Items = append(out.Items, QueueItem{SubmissionID: id, ReservedUntil: &until, Expired: until.Before(now)})
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	dbStates, err := subRepo.QueueStates(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range out.Items {
+		item := &out.Items[i]
+		st, ok := dbStates[item.SubmissionID]
+		if !ok {
+			item.DBStatus, item.Mismatch = "missing", "missing"
+			continue
+		}
+		item.DBStatus, item.ProblemID, item.CreatedAt = st.Status, &st.ProblemID, &st.CreatedAt
+		switch st.Status {
+		case "pending":
+			if state == QueueStatePending {
+				item.EnqueuedAt = &st.UpdatedAt
+			}
+		case "running":
+			item.StartedAt = &st.UpdatedAt
+		default:
+			item.Mismatch = "finished"
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+type stubQueueStateRepo struct {
+	SubmissionRepository
+	states map[int64]SubmissionQueueState
+}
+
+func (s stubQueueStateRepo) QueueStates(_ context.Context, ids []int64) (map[int64]SubmissionQueueState, error) {
+	out := map[int64]SubmissionQueueState{}
+	for _, id := range ids {
+		if st, ok := s.states[id]; ok {
+			out[id] = st
+		}
+	}
+	return out, nil
+}
+
+func TestInspectQueue(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+	queue := NewRedisQueue(client)
+
+	for _, id := range []string{"1", "2", "3", "4"} {
+		if err := queue.Enqueue(ctx, PendingQueueKey, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := queue.Reserve(ctx, PendingQueueKey, ProcessingQueueKey, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	repo := stubQueueStateRepo{states: map[int64]SubmissionQueueState{
+		1: {Status: "running", ProblemID: 10, CreatedAt: now, UpdatedAt: now},
+		2: {Status: "pending", ProblemID: 10, CreatedAt: now, UpdatedAt: now},
+		3: {Status: "accepted", ProblemID: 11, CreatedAt: now, UpdatedAt: now},
+	}}
+
+	pending, err := InspectQueue(ctx, client, repo, QueueStatePending, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending.Total != 3 || len(pending.Items) != 2 {
+		t.Fatalf("total=%d items=%d, want 3 and 2", pending.Total, len(pending.Items))
+	}
+	next, second := pending.Items[0], pending.Items[1]
+	if next.SubmissionID != 2 || next.Position != 1 || next.EnqueuedAt == nil || next.Mismatch != "" {
+		t.Fatalf("unexpected next item: %+v", next)
+	}
+	if second.SubmissionID != 3 || second.Mismatch != "finished" {
+		t.Fatalf("unexpected second item: %+v", second)
+	}
+
+	processing, err := InspectQueue(ctx, client, repo, QueueStateProcessing, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if processing.Total != 1 || len(processing.Items) != 1 {
+		t.Fatalf("total=%d items=%d, want 1 and 1", processing.Total, len(processing.Items))
+	}
+	item := processing.Items[0]
+	if item.SubmissionID != 1 || item.ReservedUntil == nil || item.Expired || item.StartedAt == nil {
+		t.Fatalf("unexpected processing item: %+v", item)
+	}
+
+	mr.Del(PendingQueueKey)
+	if err := queue.Enqueue(ctx, PendingQueueKey, "99"); err != nil {
+		t.Fatal(err)
+	}
+	missing, err := InspectQueue(ctx, client, repo, QueueStatePending, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if missing.Items[0].DBStatus != "missing" || missing.Items[0].Mismatch != "missing" {
+		t.Fatalf("unexpected missing item: %+v", missing.Items[0])
+	}
+}
@@ -0,0 +1,54 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// QueuePausedKey が存在する間、ワーカーは新しいジョブを取り出さない（実行中のジョブは最後まで判定する）。
+const QueuePausedKey = "queue:paused"
+
+// QueuePauseCheckInterval は一時停止中のワーカーがフラグを確認し直す間隔。
+const QueuePauseCheckInterval = time.Second
+
+// QueuePause は一時停止の理由と操作者。
+type QueuePause struct {
+	PausedBy string    `json:"paused_by"`
+	Reason   string    `json:"reason"`
+	PausedAt time.Time `json:"paused_at"`
+}
+
+// PauseQueue は一時停止フラグを立てる。既に停止中なら理由と操作者を上書きする。
+func PauseQueue(ctx context.Context, client RedisClientRaw, p QueuePause) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return client.Set(ctx, QueuePausedKey, data, 0).Err()
+}
+
+// ResumeQueue はフラグを消して取り出しを再開させる。
+func ResumeQueue(ctx context.Context, client *redis.Client) error {
+	return client.Del(ctx, QueuePausedKey).Err()
+}
+
+// GetQueuePause は一時停止中ならその内容を、停止していなければ nil を返す。
+func GetQueuePause(ctx context.Context, client RedisClientRaw) (*QueuePause, error) {
+	val, err := client.Get(ctx, QueuePausedKey).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var p QueuePause
+	if err := json.Unmarshal([]byte(val), &p); err != nil {
+		// 手で SET された値でも停止扱いにする
+		return &QueuePause{Reason: val}, nil
+	}
+	return &p, nil
+}
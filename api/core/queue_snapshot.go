@@ -0,0 +1,155 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// queueSnapshotVersion is the format of QueueSnapshot; restore rejects other versions.
+const queueSnapshotVersion = 1
+
+// QueueSnapshot is the content of the pending / processing queues at one moment, with the status
+// each submission had in the database. It is downloaded before a Redis migration or flush and
+// given back to RestoreQueueSnapshot afterwards.
+type QueueSnapshot struct {
+	Version    int                  `json:"version"`
+	TakenAt    time.Time            `json:"taken_at"`
+	TakenBy    string               `json:"taken_by"`
+	Pending    []QueueSnapshotEntry `json:"pending"`    // 取り出される順（古い順）
+	Processing []QueueSnapshotEntry `json:"processing"` // 可視タイムアウトの早い順
+}
+
+// QueueSnapshotEntry is one queued submission. Status is "missing" when the submission no longer
+// exists; ReservedUntil is set for processing entries.
+type QueueSnapshotEntry struct {
+	SubmissionID  int64      `json:"submission_id"`
+	Status        string     `json:"status"`
+	ReservedUntil *time.Time `json:"reserved_until,omitempty"`
+}
+
+// QueueRestoreSkip is an entry that RestoreQueueSnapshot did not queue again.
+type QueueRestoreSkip struct {
+	SubmissionID int64  `json:"submission_id"`
+	Reason       string `json:"reason"` // missing | finished | queued
+}
+
+type QueueRestoreResult struct {
+	Restored []int64            `json:"restored"`
+	Skipped  []QueueRestoreSkip `json:"skipped"`
+}
+
+// TakeQueueSnapshot reads both queues atomically and looks up the submissions in the database.
+func TakeQueueSnapshot(ctx context.Context, client *redis.Client, subRepo SubmissionRepository, takenBy string) (*QueueSnapshot, error) {
+	pipe := client.TxPipeline()
+	pendingCmd := pipe.LRange(ctx, PendingQueueKey, 0, -1)
+	processingCmd := pipe.ZRangeWithScores(ctx, ProcessingQueueKey, 0, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	snap := &QueueSnapshot{Version: queueSnapshotVersion, TakenAt: time.Now().UTC(), TakenBy: takenBy}
+	var ids []int64
+	// LPUSH で積み RPOP で取り出すので、末尾から並べると取り出される順になる
+	pending := pendingCmd.Val()
+	for i := len(pending) - 1; i >= 0; i-- {
+		if id, err := strconv.ParseInt(pending[i], 10, 64); err == nil {
+			snap.Pending = append(snap.Pending, QueueSnapshotEntry{SubmissionID: id})
+			ids = append(ids, id)
+		}
+	}
+	for _, z := range processingCmd.Val() {
+		member, _ := z.Member.(string)
+		if id, err := strconv.ParseInt(member, 10, 64); err == nil {
+			until := time.UnixMilli(int64(z.Score)).UTC()
+			snap.Processing = append(snap.Processing, QueueSnapshotEntry{SubmissionID: id, ReservedUntil: &until})
+			ids = append(ids, id)
+		}
+	}
+
+	statuses, err := subRepo.Statuses(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, entries := range [][]QueueSnapshotEntry{snap.Pending, snap.Processing} {
+		for i := range entries {
+			if st, ok := statuses[entries[i].SubmissionID]; ok {
+				entries[i].Status = st
+			} else {
+				entries[i].Status = "missing"
+			}
+		}
+	}
+	return snap, nil
+}
+
+// RestoreQueueSnapshot queues the snapshot's submissions again, ahead of anything queued since:
+// processing entries first (their judging was interrupted), then pending ones in their order.
+// Submissions are checked against the database at restore time, so ones that were judged after
+// the snapshot, deleted, or are already back in a queue are skipped. Interrupted ("running")
+// submissions are set back to pending.
+func RestoreQueueSnapshot(ctx context.Context, client *redis.Client, subRepo SubmissionRepository, snap *QueueSnapshot) (*QueueRestoreResult, error) {
+	if snap.Version != queueSnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+	order := make([]int64, 0, len(snap.Pending)+len(snap.Processing))
+	for _, e := range snap.Processing {
+		order = append(order, e.SubmissionID)
+	}
+	for _, e := range snap.Pending {
+		order = append(order, e.SubmissionID)
+	}
+
+	pipe := client.TxPipeline()
+	pendingCmd := pipe.LRange(ctx, PendingQueueKey, 0, -1)
+	processingCmd := pipe.ZRange(ctx, ProcessingQueueKey, 0, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	queued := map[int64]bool{}
+	for _, v := range append(pendingCmd.Val(), processingCmd.Val()...) {
+		if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+			queued[id] = true
+		}
+	}
+	statuses, err := subRepo.Statuses(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &QueueRestoreResult{Restored: []int64{}, Skipped: []QueueRestoreSkip{}}
+	for _, id := range order {
+		st, ok := statuses[id]
+		switch {
+		case !ok:
+			result.Skipped = append(result.Skipped, QueueRestoreSkip{SubmissionID: id, Reason: "missing"})
+		case st != "pending" && st != "running":
+			result.Skipped = append(result.Skipped, QueueRestoreSkip{SubmissionID: id, Reason: "finished"})
+		case queued[id]:
+			result.Skipped = append(result.Skipped, QueueRestoreSkip{SubmissionID: id, Reason: "queued"})
+		default:
+			if st == "running" {
+				if err := subRepo.MarkStatus(ctx, id, "pending"); err != nil {
+					return nil, err
+				}
+			}
+			queued[id] = true
+			result.Restored = append(result.Restored, id)
+		}
+	}
+	if len(result.Restored) == 0 {
+		return result, nil
+	}
+	// RPOP で取り出されるので、先に判定したいものほど右端（後ろ）に積む
+	values := make([]interface{}, 0, len(result.Restored))
+	for i := len(result.Restored) - 1; i >= 0; i-- {
+		values = append(values, strconv.FormatInt(result.Restored[i], 10))
+	}
+	if err := client.RPush(ctx, PendingQueueKey, values...).Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
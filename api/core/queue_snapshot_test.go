@@ -0,0 +1,83 @@
+package core
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+type stubStatusRepo struct {
+	SubmissionRepository
+	statuses map[int64]string
+}
+
+func (s stubStatusRepo) Statuses(_ context.Context, ids []int64) (map[int64]string, error) {
+	out := map[int64]string{}
+	for _, id := range ids {
+		if st, ok := s.statuses[id]; ok {
+			out[id] = st
+		}
+	}
+	return out, nil
+}
+
+func (s stubStatusRepo) MarkStatus(_ context.Context, id int64, status string) error {
+	s.statuses[id] = status
+	return nil
+}
+
+func TestQueueSnapshotRestore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+	queue := NewRedisQueue(client)
+
+	for _, id := range []string{"1", "2", "3", "4"} {
+		if err := queue.Enqueue(ctx, PendingQueueKey, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := queue.Reserve(ctx, PendingQueueKey, ProcessingQueueKey, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	repo := stubStatusRepo{statuses: map[int64]string{1: "running", 2: "pending", 3: "pending", 4: "pending"}}
+	snap, err := TakeQueueSnapshot(ctx, client, repo, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.Processing) != 1 || snap.Processing[0].SubmissionID != 1 || len(snap.Pending) != 3 || snap.Pending[0].SubmissionID != 2 {
+		t.Fatalf("unexpected snapshot: %+v", snap)
+	}
+
+	// FLUSH 後に 2 が判定済み、3 が削除済み、5 が新たに積まれた
+	mr.FlushAll()
+	repo.statuses[2] = "succeeded"
+	delete(repo.statuses, 3)
+	repo.statuses[5] = "pending"
+	if err := queue.Enqueue(ctx, PendingQueueKey, "5"); err != nil {
+		t.Fatal(err)
+	}
+	result, err := RestoreQueueSnapshot(ctx, client, repo, snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Restored, []int64{1, 4}) || len(result.Skipped) != 2 || repo.statuses[1] != "pending" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	var got []string
+	for {
+		v, err := queue.Reserve(ctx, PendingQueueKey, ProcessingQueueKey, time.Minute)
+		if err != nil || v == "" {
+			break
+		}
+		got = append(got, v)
+	}
+	if !reflect.DeepEqual(got, []string{"1", "4", "5"}) {
+		t.Fatalf("expected restored jobs ahead of new ones, got %v", got)
+	}
+}
@@ -25,6 +25,7 @@ type RedisClientRaw interface {
 	LLen(ctx context.Context, key string) *redis.IntCmd
 	ZCard(ctx context.Context, key string) *redis.IntCmd
 	ZCount(ctx context.Context, key, min, max string) *redis.IntCmd
+	Ping(ctx context.Context) *redis.StatusCmd
 }
 
 // RedisQueue implements RedisClient using go-redis.
@@ -0,0 +1,351 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Rejudge item states.
+const (
+	RejudgeQueued = "queued"
+	RejudgeDone   = "done"
+	RejudgeFailed = "failed" // 判定自体に失敗（SE）またはキュー投入に失敗
+)
+
+// Rejudge modes. A recheck re-evaluates the stored per-testcase outputs with the current checker and
+// falls back to a rerun for submissions whose outputs are gone.
+const (
+	RejudgeModeRerun   = "rerun"
+	RejudgeModeRecheck = "recheck"
+)
+
+// RejudgeJob is a bulk rejudge of a problem's submissions and its progress.
+type RejudgeJob struct {
+	ID              int64      `json:"id"`
+	ProblemID       int64      `json:"problem_id"`
+	RequestedBy     *int64     `json:"requested_by"`
+	RequesterName   *string    `json:"requested_by_userid"`
+	RunAllTestcases bool       `json:"run_all_testcases"`
+	Mode            string     `json:"mode"`
+	Total           int        `json:"total"`
+	Queued          int        `json:"queued"`
+	Done            int        `json:"done"`
+	Failed          int        `json:"failed"`
+	Rechecked       int        `json:"rechecked"` // done without running the program again
+	Status          string     `json:"status"`    // running|completed
+	CreatedAt       time.Time  `json:"created_at"`
+	FinishedAt      *time.Time `json:"finished_at"`
+	// StandingsRecomputedAt is set when the contest standings touched by the job were recomputed.
+	StandingsRecomputedAt *time.Time `json:"standings_recomputed_at"`
+	// FailedSubmissionIDs is filled by Get only.
+	FailedSubmissionIDs []int64 `json:"failed_submission_ids,omitempty"`
+}
+
+// RejudgeTarget is a submission put back to pending by a rejudge.
+type RejudgeTarget struct {
+	SubmissionID int64
+	PrevStatus   string
+}
+
+// RejudgeRepository stores rejudge jobs.
+type RejudgeRepository interface {
+	// CreateForProblem resets every judged submission of the problem to pending and records them in a new job.
+	// Submissions still pending or running are left alone. The caller enqueues the returned targets.
+	CreateForProblem(ctx context.Context, problemID, requestedBy int64, runAll bool, mode string) (*RejudgeJob, []RejudgeTarget, error)
+	// MarkEnqueueFailed restores a target that could not be enqueued and counts it as failed.
+	MarkEnqueueFailed(ctx context.Context, jobID int64, target RejudgeTarget) error
+	Get(ctx context.Context, id int64) (*RejudgeJob, error)
+	ListByProblem(ctx context.Context, problemID int64, limit int) ([]RejudgeJob, error)
+}
+
+type PgRejudgeRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPgRejudgeRepository(db *pgxpool.Pool) *PgRejudgeRepository {
+	return &PgRejudgeRepository{db: db}
+}
+
+func (r *PgRejudgeRepository) CreateForProblem(ctx context.Context, problemID, requestedBy int64, runAll bool, mode string) (*RejudgeJob, []RejudgeTarget, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	rows, err := tx.Query(ctx, `
+SELECT id, status FROM submissions
+WHERE problem_id=$1 AND status NOT IN ('pending', 'running') AND COALESCE(source_path, '') <> ''
+ORDER BY id
+FOR UPDATE`, problemID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var targets []RejudgeTarget
+	var ids []int64
+	for rows.Next() {
+		var t RejudgeTarget
+		if err := rows.Scan(&t.SubmissionID, &t.PrevStatus); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		targets = append(targets, t)
+		ids = append(ids, t.SubmissionID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var jobID int64
+	if err := tx.QueryRow(ctx, `INSERT INTO rejudge_jobs (problem_id, requested_by, run_all_testcases, mode) VALUES ($1,$2,$3,$4) RETURNING id`,
+		problemID, requestedBy, runAll, mode).Scan(&jobID); err != nil {
+		return nil, nil, err
+	}
+	if len(ids) > 0 {
+		if _, err := tx.Exec(ctx, `INSERT INTO rejudge_job_items (job_id, submission_id) SELECT $1, unnest($2::BIGINT[])`, jobID, ids); err != nil {
+			return nil, nil, err
+		}
+		if _, err := tx.Exec(ctx, `UPDATE submissions SET status='pending', retry_count=0, run_all_testcases=$2, updated_at=NOW() WHERE id = ANY($1)`, ids, runAll); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, err
+	}
+	job, err := r.Get(ctx, jobID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return job, targets, nil
+}
+
+func (r *PgRejudgeRepository) MarkEnqueueFailed(ctx context.Context, jobID int64, target RejudgeTarget) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if _, err := tx.Exec(ctx, `UPDATE rejudge_job_items SET status=$3, updated_at=NOW() WHERE job_id=$1 AND submission_id=$2`, jobID, target.SubmissionID, RejudgeFailed); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE submissions SET status=$2, updated_at=NOW() WHERE id=$1 AND status='pending'`, target.SubmissionID, target.PrevStatus); err != nil {
+		return err
+	}
+	if err := finishRejudgeJobs(ctx, tx, target.SubmissionID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+const rejudgeJobSelect = `
+SELECT j.id, j.problem_id, j.requested_by, u.username, j.run_all_testcases, j.mode, j.created_at, j.standings_recomputed_at,
+       COUNT(i.submission_id),
+       COUNT(*) FILTER (WHERE i.status='queued'),
+       COUNT(*) FILTER (WHERE i.status='done'),
+       COUNT(*) FILTER (WHERE i.status='failed'),
+       COUNT(*) FILTER (WHERE i.method='recheck'),
+       MAX(i.updated_at) FILTER (WHERE i.status<>'queued')
+FROM rejudge_jobs j
+LEFT JOIN users u ON u.id = j.requested_by
+LEFT JOIN rejudge_job_items i ON i.job_id = j.id
+`
+
+func scanRejudgeJob(row pgx.Row) (*RejudgeJob, error) {
+	var j RejudgeJob
+	var lastUpdate *time.Time
+	if err := row.Scan(&j.ID, &j.ProblemID, &j.RequestedBy, &j.RequesterName, &j.RunAllTestcases, &j.Mode, &j.CreatedAt, &j.StandingsRecomputedAt,
+		&j.Total, &j.Queued, &j.Done, &j.Failed, &j.Rechecked, &lastUpdate); err != nil {
+		return nil, err
+	}
+	j.Status = "running"
+	if j.Queued == 0 {
+		j.Status = "completed"
+		j.FinishedAt = lastUpdate
+		if j.FinishedAt == nil {
+			j.FinishedAt = &j.CreatedAt
+		}
+	}
+	return &j, nil
+}
+
+func (r *PgRejudgeRepository) Get(ctx context.Context, id int64) (*RejudgeJob, error) {
+	job, err := scanRejudgeJob(r.db.QueryRow(ctx, rejudgeJobSelect+`WHERE j.id=$1 GROUP BY j.id, u.username`, id))
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.Query(ctx, `SELECT submission_id FROM rejudge_job_items WHERE job_id=$1 AND status=$2 ORDER BY submission_id`, id, RejudgeFailed)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var sid int64
+		if err := rows.Scan(&sid); err != nil {
+			return nil, err
+		}
+		job.FailedSubmissionIDs = append(job.FailedSubmissionIDs, sid)
+	}
+	return job, rows.Err()
+}
+
+func (r *PgRejudgeRepository) ListByProblem(ctx context.Context, problemID int64, limit int) ([]RejudgeJob, error) {
+	rows, err := r.db.Query(ctx, rejudgeJobSelect+`WHERE j.problem_id=$1 GROUP BY j.id, u.username ORDER BY j.id DESC LIMIT $2`, problemID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []RejudgeJob{}
+	for rows.Next() {
+		j, err := scanRejudgeJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *j)
+	}
+	return items, rows.Err()
+}
+
+// advanceRejudgeItems records the outcome of a rejudged submission in its pending job items.
+// A system error counts as failed; any other verdict completes the item.
+func advanceRejudgeItems(ctx context.Context, q pgQuerier, submissionID int64, verdict string, rechecked bool) error {
+	status := RejudgeDone
+	if verdict == VerdictSE {
+		status = RejudgeFailed
+	}
+	method := RejudgeModeRerun
+	if rechecked {
+		method = RejudgeModeRecheck
+	}
+	_, err := q.Exec(ctx, `UPDATE rejudge_job_items SET status=$2, method=$3, updated_at=NOW() WHERE submission_id=$1 AND status='queued'`,
+		submissionID, status, method)
+	return err
+}
+
+// finishRejudgeJobs recomputes, once, the contest standings cells of every submission of the
+// rejudge jobs of submissionID that have no queued item left. The job rows are locked so that of
+// two submissions finishing concurrently, the later one sees the other's item as done.
+func finishRejudgeJobs(ctx context.Context, q pgQuerier, submissionID int64) error {
+	rows, err := q.Query(ctx, `
+SELECT id FROM rejudge_jobs
+WHERE standings_recomputed_at IS NULL
+  AND id IN (SELECT job_id FROM rejudge_job_items WHERE submission_id=$1)
+ORDER BY id
+FOR UPDATE`, submissionID)
+	if err != nil {
+		return err
+	}
+	var jobIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		jobIDs = append(jobIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, jobID := range jobIDs {
+		var running bool
+		if err := q.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM rejudge_job_items WHERE job_id=$1 AND status='queued')`, jobID).Scan(&running); err != nil {
+			return err
+		}
+		if running {
+			continue
+		}
+		if err := recomputeRejudgeStandings(ctx, q, jobID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recomputeRejudgeStandings recomputes the standings cells of the contest submissions of a job.
+func recomputeRejudgeStandings(ctx context.Context, q pgQuerier, jobID int64) error {
+	rows, err := q.Query(ctx, `
+SELECT DISTINCT s.contest_id, s.user_id, s.problem_id
+FROM rejudge_job_items i
+JOIN submissions s ON s.id = i.submission_id
+WHERE i.job_id=$1 AND s.contest_id IS NOT NULL
+ORDER BY 1, 2, 3`, jobID)
+	if err != nil {
+		return err
+	}
+	type cellKey struct{ contestID, userID, problemID int64 }
+	var cells []cellKey
+	for rows.Next() {
+		var k cellKey
+		if err := rows.Scan(&k.contestID, &k.userID, &k.problemID); err != nil {
+			rows.Close()
+			return err
+		}
+		cells = append(cells, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, k := range cells {
+		if err := recomputeStandingCell(ctx, q, k.contestID, k.userID, k.problemID); err != nil {
+			return err
+		}
+	}
+	_, err = q.Exec(ctx, `UPDATE rejudge_jobs SET standings_recomputed_at=NOW() WHERE id=$1`, jobID)
+	return err
+}
+
+// RejudgeStandingsUpdate is a contest whose standings a completed rejudge job recomputed.
+type RejudgeStandingsUpdate struct {
+	JobID     int64
+	ProblemID int64
+	ContestID int64
+}
+
+// ClaimRejudgeStandings returns the contests of the completed rejudge jobs of the submission whose
+// standings update has not been announced yet, marking them announced.
+func (r *PgSubmissionRepository) ClaimRejudgeStandings(ctx context.Context, submissionID int64) ([]RejudgeStandingsUpdate, error) {
+	rows, err := r.db.Query(ctx, `
+WITH claimed AS (
+    UPDATE rejudge_jobs SET standings_published_at=NOW()
+    WHERE standings_recomputed_at IS NOT NULL AND standings_published_at IS NULL
+      AND id IN (SELECT job_id FROM rejudge_job_items WHERE submission_id=$1)
+    RETURNING id, problem_id
+)
+SELECT DISTINCT c.id, c.problem_id, s.contest_id
+FROM claimed c
+JOIN rejudge_job_items i ON i.job_id = c.id
+JOIN submissions s ON s.id = i.submission_id
+WHERE s.contest_id IS NOT NULL
+ORDER BY 1, 3`, submissionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []RejudgeStandingsUpdate
+	for rows.Next() {
+		var u RejudgeStandingsUpdate
+		if err := rows.Scan(&u.JobID, &u.ProblemID, &u.ContestID); err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
+
+// RecheckRequested reports whether every rejudge job waiting for the submission is a recheck, so
+// that its stored outputs may be re-evaluated instead of running it again.
+func (r *PgSubmissionRepository) RecheckRequested(ctx context.Context, submissionID int64) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow(ctx, `
+SELECT COUNT(*) > 0 AND COUNT(*) FILTER (WHERE j.mode <> $2) = 0
+FROM rejudge_job_items i
+JOIN rejudge_jobs j ON j.id = i.job_id
+WHERE i.submission_id=$1 AND i.status='queued'`, submissionID, RejudgeModeRecheck).Scan(&ok)
+	return ok, err
+}
@@ -0,0 +1,187 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+
+	"github.com/jackc/pgx/v5"
+
+	"tuis-oj-prototype/core/checker"
+)
+
+// judgeSettings are the problem settings a submission is judged with (see WorkerProcessor.Process).
+type judgeSettings struct {
+	timeLimitMs   int
+	memoryLimitMb int
+	checkerType   string
+	checkerEps    float64
+	checkerSource string
+	runAll        bool
+}
+
+// isRunFailure reports whether a testcase status was decided by running the program (the checker
+// never saw its output), so a checker change cannot alter it.
+func isRunFailure(status string) bool {
+	switch status {
+	case VerdictTLE, VerdictMLE, VerdictOLE, VerdictRE:
+		return true
+	}
+	return false
+}
+
+// recheck recomputes the verdict of the previous judgement by passing its stored per-testcase
+// outputs to the current checker, without running the program again. It reports false when that
+// is not possible — no usable previous result, testcases replaced since, outputs already cleaned up,
+// or a testcase that was never run now matters — and the caller then judges the submission normally.
+// Limits are taken as they were: a recheck is meant for checker changes only.
+func (p *WorkerProcessor) recheck(ctx context.Context, sub *Submission, s judgeSettings) (string, bool, error) {
+	prev, err := p.subRepo.FindStoredResult(ctx, sub.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, withSEReason(SEReasonDatabase, err)
+	}
+	if prev.Verdict == VerdictCE || prev.Verdict == VerdictSE || len(prev.Details) == 0 {
+		return "", false, nil
+	}
+	testCases, err := p.loadTestCases(ctx, sub.ProblemID)
+	if err != nil {
+		return "", false, err
+	}
+	if int(prev.TotalCount) != len(testCases) || len(prev.Details) > len(testCases) {
+		return "", false, nil
+	}
+	added, err := p.problemRepo.TestcasesAddedSince(ctx, sub.ProblemID, prev.UpdatedAt)
+	if err != nil {
+		return "", false, withSEReason(SEReasonDatabase, err)
+	}
+	if added {
+		return "", false, nil
+	}
+	subtasks, err := p.problemRepo.ListSubtasks(ctx, sub.ProblemID)
+	if err != nil {
+		return "", false, withSEReason(SEReasonDatabase, err)
+	}
+	scored := sub.ScoringMode == ScoringModeIOI || len(subtasks) > 0
+	runAll := s.runAll || scored
+
+	checkerID := ""
+	if s.checkerType == CheckerTypeCustom {
+		if checkerID, err = p.checkerFor(ctx, sub.ProblemID, s.checkerSource); err != nil {
+			return "", false, withSEReason(SEReasonCompileInfra, err)
+		}
+	}
+
+	var passed int32
+	passedIDs := make(map[int64]bool, len(testCases))
+	finalVerdict := VerdictAC
+	finalStatus := "succeeded"
+	failing := -1 // details での最初の不正解の位置
+	var finalTimeMS, finalMemKB *int32
+	var details []SubmissionJudgeDetail
+	var closeCallTime, closeCallMemory bool
+
+	for i, tc := range testCases {
+		if i >= len(prev.Details) {
+			if finalVerdict != VerdictAC && !runAll {
+				break
+			}
+			// 前回は最初の不正解で打ち切っており、このケースの出力がない
+			return "", false, nil
+		}
+		d := prev.Details[i]
+		if d.Testcase != tc.name {
+			return "", false, nil
+		}
+		if !isRunFailure(d.Status) {
+			if d.StdoutPath == nil {
+				return "", false, nil
+			}
+			out, err := os.ReadFile(*d.StdoutPath)
+			if err != nil {
+				// 保存期間を過ぎて掃除された
+				return "", false, nil
+			}
+			if s.checkerType == CheckerTypeCustom {
+				if d.Status, err = p.runChecker(ctx, sub.ProblemID, checkerID, tc, string(out)); err != nil {
+					return "", false, withSEReason(SEReasonJudgeError, err)
+				}
+			} else if checker.Equal(string(out), tc.expected, s.checkerType, s.checkerEps) {
+				d.Status = VerdictAC
+			} else {
+				d.Status = VerdictWA
+			}
+		}
+		details = append(details, d)
+		if d.TimeMS != nil && (finalTimeMS == nil || *d.TimeMS > *finalTimeMS) {
+			finalTimeMS = d.TimeMS
+		}
+		if d.MemoryKB != nil && (finalMemKB == nil || *d.MemoryKB > *finalMemKB) {
+			finalMemKB = d.MemoryKB
+		}
+
+		if d.Status != VerdictAC {
+			if finalVerdict == VerdictAC {
+				finalVerdict = d.Status
+				finalStatus = "failed"
+				failing = len(details) - 1
+			}
+			if !runAll {
+				break
+			}
+			continue
+		}
+		caseTimeMs, caseMemMb := s.timeLimitMs, s.memoryLimitMb
+		if tc.timeLimitMs > 0 {
+			caseTimeMs = tc.timeLimitMs
+		}
+		if tc.memoryLimitMb > 0 {
+			caseMemMb = tc.memoryLimitMb
+		}
+		passed++
+		passedIDs[tc.id] = true
+		closeCallTime = closeCallTime || (d.TimeMS != nil && isCloseCall(int64(*d.TimeMS), int64(caseTimeMs)))
+		closeCallMemory = closeCallMemory || (d.MemoryKB != nil && isCloseCall(int64(*d.MemoryKB), int64(caseMemMb)*1024))
+	}
+
+	result := SubmissionResult{
+		SubmissionID: sub.ID,
+		Verdict:      finalVerdict,
+		JudgedBy:     p.workerID,
+		TimeMS:       finalTimeMS,
+		MemoryKB:     finalMemKB,
+		Details:      details,
+		PassedCount:  passed,
+		TotalCount:   int32(len(testCases)),
+		Rechecked:    true,
+	}
+	if failing >= 0 {
+		f := details[failing]
+		result.StdoutPath, result.StderrPath = f.StdoutPath, f.StderrPath
+		// 実行時の失敗は前回と同じケースなので、終了コードなどもそのまま引き継ぐ
+		if isRunFailure(f.Status) && f.Status == prev.Verdict {
+			result.ExitCode, result.ErrorMessage = prev.ExitCode, prev.ErrorMessage
+		}
+	}
+	if len(subtasks) > 0 {
+		breakdown, score, maxScore := scoreSubtasks(subtasks, passedIDs)
+		result.Subtasks = breakdown
+		result.Score, result.MaxScore = &score, &maxScore
+	} else if scored {
+		score, maxScore := partialScore(passed, int32(len(testCases)))
+		result.Score, result.MaxScore = &score, &maxScore
+	}
+	if finalVerdict == VerdictAC {
+		result.CloseCallTime = closeCallTime
+		result.CloseCallMemory = closeCallMemory
+	}
+
+	result.Signature = SignResult(p.signingKey, result)
+	if err := p.subRepo.SaveResult(ctx, result, finalStatus); err != nil {
+		log.Printf("failed to save recheck result for %d: %v", sub.ID, err)
+	}
+	return finalVerdict, true, nil
+}
@@ -0,0 +1,16 @@
+package core
+
+import "testing"
+
+func TestIsRunFailure(t *testing.T) {
+	for _, v := range []string{VerdictTLE, VerdictMLE, VerdictOLE, VerdictRE} {
+		if !isRunFailure(v) {
+			t.Errorf("%s should be kept as is on recheck", v)
+		}
+	}
+	for _, v := range []string{VerdictAC, VerdictWA} {
+		if isRunFailure(v) {
+			t.Errorf("%s should be rechecked", v)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+type stubRejudgeStandingsRepo struct {
+	SubmissionRepository
+	pending []RejudgeStandingsUpdate
+}
+
+func (s *stubRejudgeStandingsRepo) ClaimRejudgeStandings(context.Context, int64) ([]RejudgeStandingsUpdate, error) {
+	out := s.pending
+	s.pending = nil
+	return out, nil
+}
+
+func TestCompletedRejudgeAnnouncesStandingsOnce(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	bus := NewEventBus(client)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := bus.Subscribe(ctx, ContestEventChannel(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &stubRejudgeStandingsRepo{pending: []RejudgeStandingsUpdate{{JobID: 3, ProblemID: 9, ContestID: 5}}}
+	verdict := VerdictWA
+	// 練習提出の判定でも、完了したリジャッジが触れたコンテストには通知する
+	v := &SubmissionResultView{ID: 77, UserID: 1, ProblemID: 9, Verdict: &verdict}
+	for i := 0; i < 2; i++ {
+		if err := publishJudgedEvents(ctx, bus, repo, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case ev := <-events:
+		var data struct {
+			JobID int64 `json:"rejudge_job_id"`
+		}
+		_ = json.Unmarshal(ev.Data, &data)
+		if ev.Type != eventStandingsUpdated || data.JobID != 3 {
+			t.Fatalf("unexpected event %+v", ev)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("standings update was not announced")
+	}
+	select {
+	case ev := <-events:
+		t.Fatalf("standings update announced twice: %+v", ev)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -15,7 +15,12 @@ type UserRecord struct {
 	Username     string
 	PasswordHash string
 	Role         string
+	Timezone     string
+	Locale       string
 	CreatedAt    time.Time
+	// Suspended is set for accounts suspended by an admin (users.is_active = FALSE).
+	Suspended        bool
+	SuspensionReason string
 }
 
 // AdminUserListItem is a projection for admin user listing (no password hash).
@@ -23,6 +28,7 @@ type AdminUserListItem struct {
 	ID        int64     `json:"id"`
 	Username  string    `json:"userid"`
 	Role      string    `json:"role"`
+	Disabled  bool      `json:"disabled"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
@@ -32,6 +38,8 @@ type UserRepository interface {
 	Create(ctx context.Context, username, passwordHash, role string) (int64, error)
 	HasAdmin(ctx context.Context) (bool, error)
 	List(ctx context.Context, page, perPage int) ([]AdminUserListItem, int, error)
+	UpdatePreferences(ctx context.Context, id int64, timezone, locale *string) error
+	UpdatePassword(ctx context.Context, id int64, passwordHash string) error
 }
 
 // PgUserRepository implements UserRepository using pgxpool.
@@ -44,9 +52,9 @@ func NewPgUserRepository(db *pgxpool.Pool) *PgUserRepository {
 }
 
 func (r *PgUserRepository) FindByUsername(ctx context.Context, username string) (*UserRecord, error) {
-	const q = `SELECT id, username, password_hash, role, created_at FROM users WHERE username=$1`
+	const q = `SELECT id, username, password_hash, role, timezone, locale, created_at, NOT is_active, suspension_reason FROM users WHERE username=$1 AND deleted_at IS NULL AND disabled_at IS NULL`
 	var u UserRecord
-	if err := r.db.QueryRow(ctx, q, username).Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Role, &u.CreatedAt); err != nil {
+	if err := r.db.QueryRow(ctx, q, username).Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Role, &u.Timezone, &u.Locale, &u.CreatedAt, &u.Suspended, &u.SuspensionReason); err != nil {
 		return nil, err
 	}
 	return &u, nil
@@ -62,7 +70,7 @@ func (r *PgUserRepository) Create(ctx context.Context, username, passwordHash, r
 }
 
 func (r *PgUserRepository) HasAdmin(ctx context.Context) (bool, error) {
-	const q = `SELECT 1 FROM users WHERE role='admin' LIMIT 1`
+	const q = `SELECT 1 FROM users WHERE role='admin' AND deleted_at IS NULL LIMIT 1`
 	var one int
 	if err := r.db.QueryRow(ctx, q).Scan(&one); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -78,12 +86,12 @@ func (r *PgUserRepository) List(ctx context.Context, page, perPage int) ([]Admin
 	if page <= 0 || perPage <= 0 {
 		return nil, 0, errors.New("invalid pagination")
 	}
-	const countQ = `SELECT COUNT(*) FROM users`
+	const countQ = `SELECT COUNT(*) FROM users WHERE deleted_at IS NULL`
 	var total int
 	if err := r.db.QueryRow(ctx, countQ).Scan(&total); err != nil {
 		return nil, 0, err
 	}
-	rows, err := r.db.Query(ctx, `SELECT id, username, role, created_at FROM users ORDER BY id LIMIT $1 OFFSET $2`, perPage, (page-1)*perPage)
+	rows, err := r.db.Query(ctx, `SELECT id, username, role, disabled_at IS NOT NULL, created_at FROM users WHERE deleted_at IS NULL ORDER BY id LIMIT $1 OFFSET $2`, perPage, (page-1)*perPage)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -91,10 +99,34 @@ func (r *PgUserRepository) List(ctx context.Context, page, perPage int) ([]Admin
 	items := make([]AdminUserListItem, 0, perPage)
 	for rows.Next() {
 		var u AdminUserListItem
-		if err := rows.Scan(&u.ID, &u.Username, &u.Role, &u.CreatedAt); err != nil {
+		if err := rows.Scan(&u.ID, &u.Username, &u.Role, &u.Disabled, &u.CreatedAt); err != nil {
 			return nil, 0, err
 		}
 		items = append(items, u)
 	}
 	return items, total, rows.Err()
 }
+
+// UpdatePreferences updates timezone/locale; nil fields are left unchanged.
+func (r *PgUserRepository) UpdatePreferences(ctx context.Context, id int64, timezone, locale *string) error {
+	const q = `UPDATE users SET timezone=COALESCE($1, timezone), locale=COALESCE($2, locale) WHERE id=$3`
+	ct, err := r.db.Exec(ctx, q, timezone, locale, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PgUserRepository) UpdatePassword(ctx context.Context, id int64, passwordHash string) error {
+	ct, err := r.db.Exec(ctx, `UPDATE users SET password_hash=$1 WHERE id=$2 AND deleted_at IS NULL`, passwordHash, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package core
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// resultSignatureVersion prefixes the signed payload so the format can evolve.
+const resultSignatureVersion = "v1"
+
+// signedResultPayload is the canonical form of the judge outcome covered by the signature.
+// Only values that round-trip through submission_results / submission_result_details are included.
+type signedResultPayload struct {
+	Version      string               `json:"v"`
+	SubmissionID int64                `json:"submission_id"`
+	Verdict      string               `json:"verdict"`
+	TimeMS       *int32               `json:"time_ms"`
+	MemoryKB     *int32               `json:"memory_kb"`
+	ExitCode     *int32               `json:"exit_code"`
+	ErrorMessage *string              `json:"error_message"`
+	PassedCount  int32                `json:"passed_count"`
+	TotalCount   int32                `json:"total_count"`
+	Score        *int32               `json:"score"`
+	MaxScore     *int32               `json:"max_score"`
+	JudgedBy     string               `json:"judged_by"`
+	Details      []signedResultDetail `json:"details"`
+	Subtasks     []SubtaskResult      `json:"subtasks,omitempty"`
+}
+
+type signedResultDetail struct {
+	Testcase string `json:"testcase"`
+	Status   string `json:"status"`
+	TimeMS   *int32 `json:"time_ms"`
+	MemoryKB *int32 `json:"memory_kb"`
+	Group    string `json:"group,omitempty"`
+}
+
+// SignResult returns the hex HMAC-SHA256 of the result's canonical payload,
+// or "" when no key is configured.
+func SignResult(key []byte, r SubmissionResult) string {
+	if len(key) == 0 {
+		return ""
+	}
+	p := signedResultPayload{
+		Version:      resultSignatureVersion,
+		SubmissionID: r.SubmissionID,
+		Verdict:      r.Verdict,
+		TimeMS:       r.TimeMS,
+		MemoryKB:     r.MemoryKB,
+		ExitCode:     r.ExitCode,
+		ErrorMessage: r.ErrorMessage,
+		PassedCount:  r.PassedCount,
+		TotalCount:   r.TotalCount,
+		Score:        r.Score,
+		MaxScore:     r.MaxScore,
+		JudgedBy:     r.JudgedBy,
+		Details:      make([]signedResultDetail, 0, len(r.Details)),
+		Subtasks:     r.Subtasks,
+	}
+	for _, d := range r.Details {
+		p.Details = append(p.Details, signedResultDetail{Testcase: d.Testcase, Status: d.Status, TimeMS: d.TimeMS, MemoryKB: d.MemoryKB, Group: d.Group})
+	}
+	data, _ := json.Marshal(p) // 固定フィールドの構造体なので失敗しない
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ResultVerification is the outcome of checking a stored result against its signature.
+type ResultVerification struct {
+	SubmissionID int64  `json:"submission_id"`
+	Signed       bool   `json:"signed"`
+	Valid        bool   `json:"valid"`
+	Verdict      string `json:"verdict"`
+}
+
+// VerifyResult recomputes the signature of a stored result.
+func VerifyResult(key []byte, r SubmissionResult) ResultVerification {
+	v := ResultVerification{SubmissionID: r.SubmissionID, Verdict: r.Verdict, Signed: r.Signature != ""}
+	if v.Signed && len(key) > 0 {
+		v.Valid = hmac.Equal([]byte(r.Signature), []byte(SignResult(key, r)))
+	}
+	return v
+}
+
+// FindStoredResult loads a persisted result with its details and signature for verification (and
+// for rechecks, which also use the stored output paths).
+func (r *PgSubmissionRepository) FindStoredResult(ctx context.Context, submissionID int64) (*SubmissionResult, error) {
+	const q = `SELECT submission_id, verdict, time_ms, memory_kb, exit_code, error_message, passed_count, total_count, score, max_score,
+       COALESCE(judged_by, ''), COALESCE(signature, ''), subtask_results, updated_at
+FROM submission_results WHERE submission_id=$1`
+	var res SubmissionResult
+	if err := r.db.QueryRow(ctx, q, submissionID).Scan(&res.SubmissionID, &res.Verdict, &res.TimeMS, &res.MemoryKB, &res.ExitCode, &res.ErrorMessage,
+		&res.PassedCount, &res.TotalCount, &res.Score, &res.MaxScore, &res.JudgedBy, &res.Signature, &res.Subtasks, &res.UpdatedAt); err != nil {
+		return nil, err
+	}
+	rows, err := r.db.Query(ctx, `SELECT testcase, status, time_ms, memory_kb, COALESCE(group_name, ''), input_bytes, output_bytes, stdout_path, stderr_path
+FROM submission_result_details WHERE submission_id=$1 ORDER BY id`, submissionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d SubmissionJudgeDetail
+		if err := rows.Scan(&d.Testcase, &d.Status, &d.TimeMS, &d.MemoryKB, &d.Group, &d.InputBytes, &d.OutputBytes, &d.StdoutPath, &d.StderrPath); err != nil {
+			return nil, err
+		}
+		res.Details = append(res.Details, d)
+	}
+	return &res, rows.Err()
+}
@@ -4,11 +4,13 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
-	"encoding/csv"
 	"errors"
 	"fmt"
 	"io"
+	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -24,12 +26,18 @@ import (
 )
 
 // NewRouter constructs the Gin engine with routes wired.
-func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService, db *pgxpool.Pool, redisClient *redis.Client) *gin.Engine {
+func NewRouter(cfg Config, store *RedisSessionStore, authService AuthService, db *pgxpool.Pool, redisClient *redis.Client) *gin.Engine {
 	startedAt := time.Now()
 	r := gin.Default()
 
-	// Global middleware: origin/CORS -> session -> CSRF
+	apiTokenRepo := NewPgAPITokenRepository(db)
+	// Redis の停止マークがない（消えた）ときはデータベースで停止状態を確かめる
+	suspensionRepo := NewPgUserSuspensionRepository(db)
+	store.UseSuspensionSource(suspensionRepo.State)
+
+	// Global middleware: origin/CORS -> API token -> session -> CSRF
 	r.Use(OriginRefererMiddleware(cfg))
+	r.Use(TokenAuthMiddleware(store, apiTokenRepo))
 	r.Use(SessionMiddleware(cfg, store))
 	r.Use(CSRFMiddleware(cfg, store))
 
@@ -42,10 +50,45 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 	subRepo := NewPgSubmissionRepository(db)
 	queue := NewRedisQueue(redisClient)
 	metricsService := NewMetricsService(redisClient)
+	dashboardService := NewDashboardService(db, metricsService)
 	noticeRepo := NewPgNoticeRepository(db)
+	contestRepo := NewPgContestRepository(db)
+	clarRepo := NewPgClarificationRepository(db)
+	incidentRepo := NewPgIncidentRepository(db)
+	annRepo := NewPgContestAnnouncementRepository(db)
+	healthRepo := NewPgHealthRepository(db)
+	annotationRepo := NewPgSubmissionAnnotationRepository(db)
+	discussionRepo := NewPgDiscussionRepository(db)
+	rejudgeRepo := NewPgRejudgeRepository(db)
+	loadTestRepo := NewPgLoadTestRepository(db)
+	workerRegistry := NewPgWorkerRegistry(db)
+	emailRepo := NewPgUserEmailRepository(db)
+	totpRepo := NewPgTOTPRepository(db)
+	mailer := NewMailer(cfg)
+	gymRepo := NewPgGymRepository(db)
+	graderWebhookRepo := NewPgGraderWebhookRepository(db)
+	accessCodeRepo := NewPgContestAccessCodeRepository(db)
+	quotaRepo := NewPgSubmissionQuotaRepository(db)
+	auditRepo := NewPgAuditLogRepository(db)
+	trashRepo := NewPgTrashRepository(db, time.Duration(cfg.TrashRetentionDays)*24*time.Hour)
+	eventBus := NewEventBus(redisClient)
+	wsHub := NewWSHub(eventBus)
+	judgeClient := NewHTTPJudgeClient(cfg.GoJudgeURL)
+	testcaseGen := NewTestcaseGenerator(judgeClient)
+	// 書式は起動時に検証済み（cmd/api）
+	submissionRules, _ := ParseSubmissionRateLimits(cfg.SubmissionRateLimits)
+	sandboxPolicies, _ := ParseSandboxPolicies(cfg.JudgeSandboxPolicy)
+	judgeClient.ConfigureSandbox(sandboxPolicies, cfg.GoJudgeNetworkURL)
+	submissionLimiter := NewSubmissionRateLimiter(redisClient, submissionRules)
+	loginLockout := NewLoginLockout(redisClient, cfg)
 	api := r.Group("/api/v1")
+	api.Use(ListResponseMiddleware(cfg))
 	{
 		api.POST("/auth/login", func(c *gin.Context) {
+			if cfg.PasswordLoginDisabled {
+				respondError(c, http.StatusForbidden, "PASSWORD_LOGIN_DISABLED", "パスワードによるログインは無効です。外部アカウントでログインしてください")
+				return
+			}
 			var req struct {
 				UserID   string `json:"userid"`
 				Password string `json:"password"`
@@ -55,30 +98,66 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 				return
 			}
 
+			ctx := c.Request.Context()
+			ip := c.ClientIP()
+			wait, err := loginLockout.Locked(ctx, req.UserID, ip)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to check login attempts")
+				return
+			}
+			if wait > 0 {
+				respondLoginLocked(c, wait)
+				return
+			}
+
 			user, err := authService.Authenticate(req.UserID, req.Password)
 			if err != nil {
+				locked, err := loginLockout.Fail(ctx, req.UserID, ip)
+				if err != nil {
+					log.Printf("[login] record failure for %q: %v", req.UserID, err)
+				}
+				if locked {
+					log.Printf("[login] account %q locked after repeated failures (last from %s)", req.UserID, ip)
+					respondLoginLocked(c, loginLockout.duration)
+					return
+				}
 				respondError(c, http.StatusUnauthorized, "INVALID_CREDENTIALS", "ユーザーIDまたはパスワードが違います。")
 				return
 			}
+			if err := loginLockout.Reset(ctx, user.Username); err != nil {
+				log.Printf("[login] reset failures for %q: %v", user.Username, err)
+			}
+			// 利用停止中のアカウントは、パスワードが正しくてもセッションを発行しない
+			if record, err := userRepo.FindByUsername(ctx, user.Username); err == nil && record.Suspended {
+				if err := store.MarkSuspended(ctx, record.Username, record.SuspensionReason); err != nil {
+					log.Printf("[login] mark suspended %q: %v", record.Username, err)
+				}
+				respondAccountSuspended(c, record.SuspensionReason)
+				return
+			}
 
-			session, err := store.Get(c.Request, sessionName)
+			// 二要素認証を有効にしたアカウントは、認証コードを確認するまでセッションを発行しない（/auth/login/totp）
+			totpEnabled, err := totpRepo.IsEnabled(ctx, user.ID)
 			if err != nil {
-				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "session error")
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to check two-factor authentication")
+				return
+			}
+			if totpEnabled {
+				token, err := startTOTPLogin(ctx, redisClient, user.ID, user.Username)
+				if err != nil {
+					respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to start two-factor login")
+					return
+				}
+				c.JSON(http.StatusOK, gin.H{"totp_required": true, "login_token": token})
 				return
 			}
 
-			// reset session values (simple rotation)
-			session.Values = map[interface{}]interface{}{}
-			session.Values["userid"] = user.Username
-			session.Values["role"] = user.Role
-			applySessionOptions(cfg, session)
-
-			if err := session.Save(c.Request, c.Writer); err != nil {
+			if err := startUserSession(c, cfg, store, user.Username, user.Role); err != nil {
 				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to set session")
 				return
 			}
 
-			c.JSON(http.StatusOK, gin.H{"user": gin.H{"userid": user.Username, "role": user.Role}})
+			c.JSON(http.StatusOK, gin.H{"user": gin.H{"userid": user.Username, "role": user.Role, "permissions": RolePermissions(user.Role)}})
 		})
 
 		api.POST("/auth/logout", func(c *gin.Context) {
@@ -127,12 +206,114 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 			c.JSON(http.StatusOK, gin.H{
 				"userid":           u.Username,
 				"role":             u.Role,
+				"permissions":      RolePermissions(u.Role),
 				"solved_count":     solvedCount,
 				"submission_count": subCount,
 				"created_at":       u.CreatedAt,
+				"timezone":         u.Timezone,
+				"locale":           u.Locale,
 			})
 		})
 
+		api.GET("/users/me/preferences", func(c *gin.Context) {
+			u, ok := requireUser(c, userRepo)
+			if !ok {
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"timezone": u.Timezone, "locale": u.Locale})
+		})
+
+		api.PUT("/users/me/preferences", func(c *gin.Context) {
+			u, ok := requireUser(c, userRepo)
+			if !ok {
+				return
+			}
+			var req struct {
+				Timezone *string `json:"timezone"`
+				Locale   *string `json:"locale"`
+			}
+			if err := c.ShouldBindJSON(&req); err != nil {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+				return
+			}
+			if req.Timezone != nil {
+				tz, err := normalizeTimezone(*req.Timezone)
+				if err != nil {
+					respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+					return
+				}
+				req.Timezone = &tz
+				u.Timezone = tz
+			}
+			if req.Locale != nil {
+				locale, err := normalizeLocale(*req.Locale)
+				if err != nil {
+					respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+					return
+				}
+				req.Locale = &locale
+				u.Locale = locale
+			}
+			if err := userRepo.UpdatePreferences(c.Request.Context(), u.ID, req.Timezone, req.Locale); err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to update preferences")
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"timezone": u.Timezone, "locale": u.Locale})
+		})
+
+		// パスワード変更。現在のパスワードを確認し、成功したらセッションを張り直して他のセッションを失効させる
+		api.POST("/users/me/password", func(c *gin.Context) {
+			if isTokenAuth(c) {
+				respondError(c, http.StatusForbidden, "FORBIDDEN", "API トークンではパスワードを変更できません")
+				return
+			}
+			if cfg.PasswordLoginDisabled {
+				respondError(c, http.StatusForbidden, "PASSWORD_LOGIN_DISABLED", "パスワードによるログインは無効です")
+				return
+			}
+			u, ok := requireUser(c, userRepo)
+			if !ok {
+				return
+			}
+			var req struct {
+				CurrentPassword string `json:"current_password"`
+				NewPassword     string `json:"new_password"`
+			}
+			if err := c.ShouldBindJSON(&req); err != nil {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+				return
+			}
+			if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(req.CurrentPassword)) != nil {
+				respondError(c, http.StatusUnauthorized, "INVALID_CREDENTIALS", "現在のパスワードが違います")
+				return
+			}
+			if err := validateNewPassword(req.CurrentPassword, req.NewPassword); err != nil {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+				return
+			}
+			hash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to hash password")
+				return
+			}
+			if err := userRepo.UpdatePassword(c.Request.Context(), u.ID, string(hash)); err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to update password")
+				return
+			}
+			if err := startUserSession(c, cfg, store, u.Username, u.Role); err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to set session")
+				return
+			}
+			// 他の端末に残っているログインは古いパスワードで得たものなので失効させる
+			sessionAny, _ := c.Get("session")
+			if sess, ok := sessionAny.(*sessions.Session); ok {
+				if _, err := store.RevokeUserSessions(c.Request.Context(), u.Username, sess.ID); err != nil {
+					log.Printf("failed to revoke sessions of %s: %v", u.Username, err)
+				}
+			}
+			c.Status(http.StatusNoContent)
+		})
+
 		api.GET("/users/:userid", func(c *gin.Context) {
 			if _, ok := requireLogin(c); !ok {
 				return
@@ -164,7 +345,8 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 			})
 		})
 
-		api.POST("/submissions", func(c *gin.Context) {
+		// 再送は Idempotency-Key で前回の応答を返す（レート制限にも数えない）
+		api.POST("/submissions", SubmissionIdempotencyMiddleware(redisClient), SubmissionRateLimitMiddleware(submissionLimiter), func(c *gin.Context) {
 			// Simple session auth
 			sessionAny, _ := c.Get("session")
 			sess, _ := sessionAny.(*sessions.Session)
@@ -176,16 +358,21 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 			}
 
 			var req struct {
-				ProblemID int64  `json:"problem_id"`
-				Language  string `json:"language"`
-				Source    string `json:"source_code"`
+				ProblemID int64 `json:"problem_id"`
+				// ProblemSlug は problem_id の代わりに指定できる
+				ProblemSlug string `json:"problem_slug"`
+				ContestID   *int64 `json:"contest_id"`
+				// GymSessionID は練習用ミラーのセッション内の提出として記録する
+				GymSessionID *int64 `json:"gym_session_id"`
+				Language     string `json:"language"`
+				Source       string `json:"source_code"`
 			}
 			if err := c.ShouldBindJSON(&req); err != nil {
 				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
 				return
 			}
-			if req.ProblemID <= 0 || strings.TrimSpace(req.Language) == "" || strings.TrimSpace(req.Source) == "" {
-				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "problem_id, language, source_code は必須です")
+			if (req.ProblemID <= 0 && strings.TrimSpace(req.ProblemSlug) == "") || strings.TrimSpace(req.Language) == "" || strings.TrimSpace(req.Source) == "" {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "problem_id (または problem_slug), language, source_code は必須です")
 				return
 			}
 
@@ -195,25 +382,80 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 				respondError(c, http.StatusUnauthorized, "UNAUTHORIZED", "ユーザーが存在しません")
 				return
 			}
+			if user.Suspended {
+				respondAccountSuspended(c, user.SuspensionReason)
+				return
+			}
+			if cfg.EmailVerificationRequired && !isStaffRole(user.Role) {
+				verified, err := emailRepo.IsVerified(ctx, user.ID)
+				if err != nil {
+					respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to check email verification")
+					return
+				}
+				if !verified {
+					respondError(c, http.StatusForbidden, "EMAIL_NOT_VERIFIED", "提出するにはメールアドレスの確認が必要です")
+					return
+				}
+			}
+			if req.ProblemID <= 0 {
+				if req.ProblemID, err = problemRepo.IDBySlug(ctx, req.ProblemSlug); err != nil {
+					respondError(c, http.StatusNotFound, "NOT_FOUND", "問題が見つかりません")
+					return
+				}
+			}
 
 			// problem check
-			isPublic, err := problemRepo.ExistsAndPublic(ctx, req.ProblemID)
-			if err != nil {
-				respondError(c, http.StatusNotFound, "NOT_FOUND", "問題が見つかりません")
+			if req.ContestID != nil && req.GymSessionID != nil {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "contest_id と gym_session_id は同時に指定できません")
 				return
 			}
-			if !isPublic {
-				respondError(c, http.StatusForbidden, "FORBIDDEN", "非公開の問題です")
-				return
+			if req.GymSessionID != nil {
+				if !checkGymSubmission(c, gymRepo, problemRepo, user, *req.GymSessionID, req.ProblemID) {
+					return
+				}
+			} else if req.ContestID != nil {
+				if !checkContestSubmission(c, contestRepo, user, *req.ContestID, req.ProblemID) {
+					return
+				}
+			} else {
+				access, err := resolveProblemAccess(ctx, problemRepo, contestRepo, user, req.ProblemID)
+				if err != nil {
+					respondError(c, http.StatusNotFound, "NOT_FOUND", "問題が見つかりません")
+					return
+				}
+				if !access.Submittable {
+					respondError(c, http.StatusForbidden, "FORBIDDEN", access.Reason)
+					return
+				}
+				// 開催中コンテストの問題はそのコンテストの提出として記録する
+				req.ContestID = access.ContestID
 			}
 			if !isSupportedLanguage(req.Language) {
 				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "サポートされていない言語です")
 				return
 			}
 
+			// キューが詰まっているときは非コンテストの提出を抑える（コンテストの提出は常に受け付ける）
+			var backpressure *Backpressure
+			if req.ContestID == nil {
+				bp, err := checkBackpressure(ctx, cfg, metricsService)
+				if err != nil {
+					log.Printf("backpressure check failed: %v", err)
+				} else if bp != nil && bp.Policy == BackpressureReject {
+					c.Header("Retry-After", strconv.Itoa(bp.ETASeconds))
+					respondError(c, http.StatusTooManyRequests, "QUEUE_BUSY", "判定待ちの提出が多いため受け付けを制限しています。しばらくしてから再度提出してください")
+					return
+				} else {
+					backpressure = bp
+				}
+			}
+			if !checkSubmissionQuota(c, cfg, quotaRepo, user, req.ContestID) {
+				return
+			}
+
 			// Reserve ID by inserting with empty source_path first
 			sourcePath := ""
-			subID, createdAt, err := subRepo.Create(ctx, user.ID, req.ProblemID, req.Language, sourcePath)
+			subID, createdAt, err := subRepo.Create(ctx, user.ID, req.ProblemID, req.ContestID, req.Language, sourcePath)
 			if err != nil {
 				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to create submission")
 				return
@@ -233,13 +475,18 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 				return
 			}
 
-			if _, err := db.Exec(ctx, `UPDATE submissions SET source_path=$1 WHERE id=$2`, srcPath, subID); err != nil {
+			if _, err := db.Exec(ctx, `UPDATE submissions SET source_path=$1, gym_session_id=$2 WHERE id=$3`, srcPath, req.GymSessionID, subID); err != nil {
 				_ = subRepo.Delete(ctx, subID)
 				_ = os.RemoveAll(dir)
 				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to update source path")
 				return
 			}
 
+			// 不正調査用のクライアント情報（失敗しても提出は受け付ける）
+			if err := subRepo.SaveClientInfo(ctx, subID, c.ClientIP(), c.Request.UserAgent()); err != nil {
+				log.Printf("failed to save client info for submission %d: %v", subID, err)
+			}
+
 			// enqueue
 			if err := queue.Enqueue(ctx, "pending_submissions", strconv.FormatInt(subID, 10)); err != nil {
 				_ = subRepo.Delete(ctx, subID)
@@ -248,16 +495,26 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 				return
 			}
 
-			c.JSON(http.StatusCreated, gin.H{
-				"id":         subID,
-				"problem_id": req.ProblemID,
-				"language":   req.Language,
-				"status":     "pending",
-				"verdict":    nil,
-				"time_ms":    nil,
-				"memory_kb":  nil,
-				"created_at": createdAt,
-			})
+			resp := gin.H{
+				"id":             subID,
+				"problem_id":     req.ProblemID,
+				"contest_id":     req.ContestID,
+				"gym_session_id": req.GymSessionID,
+				"language":       req.Language,
+				"status":         "pending",
+				"verdict":        nil,
+				"time_ms":        nil,
+				"memory_kb":      nil,
+				"created_at":     createdAt,
+			}
+			if backpressure != nil {
+				// 受け付けはしたが判定まで時間がかかる
+				resp["queue_pending"] = backpressure.Pending
+				resp["eta_seconds"] = backpressure.ETASeconds
+				c.JSON(http.StatusAccepted, resp)
+				return
+			}
+			c.JSON(http.StatusCreated, resp)
 		})
 
 		api.GET("/languages", func(c *gin.Context) {
@@ -267,6 +524,14 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 			c.JSON(http.StatusOK, gin.H{"languages": supportedLanguages})
 		})
 
+		// 判定の一覧（EXTRA_VERDICTS で追加したものを含む）
+		api.GET("/verdicts", func(c *gin.Context) {
+			if _, ok := requireLogin(c); !ok {
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"verdicts": Verdicts()})
+		})
+
 		// お知らせ一覧
 		api.GET("/notices", func(c *gin.Context) {
 			if _, ok := requireLogin(c); !ok {
@@ -316,21 +581,22 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 			c.JSON(http.StatusOK, n)
 		})
 
+		// /admin は何らかの権限を持つロールのみ。各グループで必要な権限をさらに絞る
 		admin := api.Group("/admin")
-		admin.Use(AdminOnly())
-		metrics := admin.Group("/metrics")
+		admin.Use(RequireStaff())
+		// 以下の変更操作はすべて監査ログに残る
+		admin.Use(AuditLogMiddleware(auditRepo))
+		metrics := admin.Group("/metrics", RequirePermission(PermMetricsRead))
+		systemAdmin := admin.Group("", RequirePermission(PermMetricsRead))
+		submissionsAdmin := admin.Group("", RequirePermission(PermSubmissionsRead))
+		noticesAdmin := admin.Group("", RequirePermission(PermNoticesWrite))
+		usersAdmin := admin.Group("", RequirePermission(PermUsersManage))
+		problemsAdmin := admin.Group("", RequirePermission(PermProblemsWrite))
+		contestsAdmin := admin.Group("", RequirePermission(PermContestsManage))
 		{
 			metrics.GET("/overview", func(c *gin.Context) {
-				ctx := c.Request.Context()
-				queueMetrics, workers, err := metricsService.Overview(ctx)
-				if err != nil {
-					respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to load metrics")
-					return
-				}
-				c.JSON(http.StatusOK, gin.H{
-					"queues":  queueMetrics,
-					"workers": workers,
-				})
+				// Redis に届かないときも最後に取得できた値を stale=true で返す
+				c.JSON(http.StatusOK, metricsService.Overview(c.Request.Context()))
 			})
 
 			metrics.GET("/queues", func(c *gin.Context) {
@@ -367,8 +633,47 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 				}
 				c.JSON(http.StatusOK, hb)
 			})
+
+			// ワーカーの登録簿（停止済み・ハートビートが途絶えたものを含む、新しい順）
+			metrics.GET("/worker_registry", func(c *gin.Context) {
+				items, err := workerRegistry.List(c.Request.Context(), maxWorkerRegistryEntries)
+				if err != nil {
+					respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to load worker registry")
+					return
+				}
+				c.JSON(http.StatusOK, gin.H{"items": items})
+			})
+
+			// 登録簿をホスト別に集計したもの（初回・最終確認時刻、稼働したバージョン、累計処理件数）
+			metrics.GET("/worker_registry/hosts", func(c *gin.Context) {
+				items, err := workerRegistry.Hosts(c.Request.Context())
+				if err != nil {
+					respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to load worker hosts")
+					return
+				}
+				c.JSON(http.StatusOK, gin.H{"items": items})
+			})
+
+			// SE の原因コード別件数（直近 hours 時間、既定 24）
+			metrics.GET("/system_errors", func(c *gin.Context) {
+				hours := 24
+				if v := c.Query("hours"); v != "" {
+					n, err := strconv.Atoi(v)
+					if err != nil || n <= 0 || n > 24*30 {
+						respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "hours must be between 1 and 720")
+						return
+					}
+					hours = n
+				}
+				items, err := dashboardService.SystemErrorReasons(c.Request.Context(), time.Now().Add(-time.Duration(hours)*time.Hour))
+				if err != nil {
+					respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to load system errors")
+					return
+				}
+				c.JSON(http.StatusOK, gin.H{"hours": hours, "items": items})
+			})
 		}
-		admin.GET("/system/status", func(c *gin.Context) {
+		systemAdmin.GET("/system/status", func(c *gin.Context) {
 			ctx := c.Request.Context()
 			st, err := CollectSystemStatus(ctx, metricsService, startedAt)
 			if err != nil {
@@ -378,12 +683,240 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 			c.JSON(http.StatusOK, st)
 		})
 
-		admin.POST("/submissions/bulk_test", func(c *gin.Context) {
+		// 管理トップ用の集約（キュー・ワーカー・直近の SE・提出数・開催中コンテスト）。refresh=1 でキャッシュを無視する
+		systemAdmin.GET("/dashboard", func(c *gin.Context) {
+			d, err := dashboardService.Get(c.Request.Context(), c.Query("refresh") == "1")
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to load dashboard")
+				return
+			}
+			c.JSON(http.StatusOK, d)
+		})
+
+		// 判定キューの一時停止。ワーカーは実行中のジョブを終えたあと、再開まで取り出しを止める
+		systemAdmin.POST("/queue/pause", func(c *gin.Context) {
+			user, ok := requireUser(c, userRepo)
+			if !ok {
+				return
+			}
+			var req struct {
+				Reason string `json:"reason"`
+			}
+			if c.Request.ContentLength > 0 {
+				if err := c.ShouldBindJSON(&req); err != nil {
+					respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+					return
+				}
+			}
+			pause := QueuePause{PausedBy: user.Username, Reason: strings.TrimSpace(req.Reason), PausedAt: time.Now()}
+			if err := PauseQueue(c.Request.Context(), redisClient, pause); err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to pause queue")
+				return
+			}
+			log.Printf("[queue] paused by %s: %s", pause.PausedBy, pause.Reason)
+			c.JSON(http.StatusOK, gin.H{"paused": true, "pause": pause})
+		})
+
+		systemAdmin.POST("/queue/resume", func(c *gin.Context) {
+			user, ok := requireUser(c, userRepo)
+			if !ok {
+				return
+			}
+			if err := ResumeQueue(c.Request.Context(), redisClient); err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to resume queue")
+				return
+			}
+			log.Printf("[queue] resumed by %s", user.Username)
+			c.JSON(http.StatusOK, gin.H{"paused": false})
+		})
+
+		// キューの退避。Redis の移行や FLUSH の前に取得し、終わったら restore に渡す（取得前に pause しておくとよい）
+		systemAdmin.GET("/queue/snapshot", func(c *gin.Context) {
+			user, ok := requireUser(c, userRepo)
+			if !ok {
+				return
+			}
+			snap, err := TakeQueueSnapshot(c.Request.Context(), redisClient, subRepo, user.Username)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to take queue snapshot")
+				return
+			}
+			log.Printf("[queue] snapshot by %s: pending=%d processing=%d", user.Username, len(snap.Pending), len(snap.Processing))
+			c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=queue-snapshot-%s.json", snap.TakenAt.Format("20060102-150405")))
+			c.JSON(http.StatusOK, snap)
+		})
+
+		// snapshot で保存したファイルをそのまま本文に送る。判定済み・削除済み・キューに残っている提出は積み直さない
+		systemAdmin.POST("/queue/restore", func(c *gin.Context) {
+			user, ok := requireUser(c, userRepo)
+			if !ok {
+				return
+			}
+			var snap QueueSnapshot
+			if err := c.ShouldBindJSON(&snap); err != nil {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+				return
+			}
+			if snap.Version != queueSnapshotVersion {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "対応していないスナップショットの形式です")
+				return
+			}
+			result, err := RestoreQueueSnapshot(c.Request.Context(), redisClient, subRepo, &snap)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to restore queue")
+				return
+			}
+			log.Printf("[queue] restored by %s: snapshot of %s, restored=%d skipped=%d", user.Username, snap.TakenAt.Format(time.RFC3339), len(result.Restored), len(result.Skipped))
+			c.JSON(http.StatusOK, result)
+		})
+
+		// キューに実際に入っている提出の一覧（metrics は件数のみ）。DB の状態と食い違うものには mismatch が付く
+		systemAdmin.GET("/queues/items", func(c *gin.Context) {
+			state := firstNonEmpty(c.Query("state"), QueueStatePending)
+			if state != QueueStatePending && state != QueueStateProcessing {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "state は pending か processing を指定してください")
+				return
+			}
+			limit := DefaultQueueItemsLimit
+			if raw := c.Query("limit"); raw != "" {
+				n, err := strconv.Atoi(raw)
+				if err != nil || n < 1 || n > MaxQueueItemsLimit {
+					respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", fmt.Sprintf("limit は 1〜%d で指定してください", MaxQueueItemsLimit))
+					return
+				}
+				limit = n
+			}
+			items, err := InspectQueue(c.Request.Context(), redisClient, subRepo, state, limit)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to inspect queue")
+				return
+			}
+			c.JSON(http.StatusOK, items)
+		})
+
+		// ロールごとの 1 分あたりの提出上限（0 は無制限）。変更は次の提出から効く
+		systemAdmin.GET("/submission_quotas", func(c *gin.Context) {
+			quotas, err := GetSubmissionRoleQuotas(c.Request.Context(), redisClient)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to load submission quotas")
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"per_minute": quotas})
+		})
+
+		// 指定したロールだけを上書きする。reset=1 で既定値に戻す
+		systemAdmin.PUT("/submission_quotas", func(c *gin.Context) {
+			user, ok := requireUser(c, userRepo)
+			if !ok {
+				return
+			}
+			ctx := c.Request.Context()
+			if c.Query("reset") == "1" {
+				if err := ResetSubmissionRoleQuotas(ctx, redisClient); err != nil {
+					respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to reset submission quotas")
+					return
+				}
+			} else {
+				var req struct {
+					PerMinute map[string]int `json:"per_minute"`
+				}
+				if err := c.ShouldBindJSON(&req); err != nil {
+					respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+					return
+				}
+				if err := SetSubmissionRoleQuotas(ctx, redisClient, req.PerMinute); err != nil {
+					if errors.Is(err, ErrSubmissionQuotaInput) {
+						respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+						return
+					}
+					respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to update submission quotas")
+					return
+				}
+			}
+			quotas, err := GetSubmissionRoleQuotas(ctx, redisClient)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to load submission quotas")
+				return
+			}
+			log.Printf("[quota] submission quotas set by %s: %v", user.Username, quotas)
+			c.JSON(http.StatusOK, gin.H{"per_minute": quotas})
+		})
+
+		// 日ごとの稼働率（days は最大 365、tz は日付の区切りに使うタイムゾーン）
+		systemAdmin.GET("/system/uptime", func(c *gin.Context) {
+			days := 30
+			if v := c.Query("days"); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil || n <= 0 || n > 365 {
+					respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "days は 1〜365 で指定してください")
+					return
+				}
+				days = n
+			}
+			loc, err := time.LoadLocation(firstNonEmpty(c.Query("tz"), "UTC"))
+			if err != nil {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid tz")
+				return
+			}
+			ctx := c.Request.Context()
+			now := time.Now()
+			first, err := healthRepo.FirstSnapshotAt(ctx)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to load uptime")
+				return
+			}
+			items := []HealthDay{}
+			if first != nil {
+				since := now.In(loc).AddDate(0, 0, -days)
+				counts, err := healthRepo.DailyCounts(ctx, since, loc)
+				if err != nil {
+					respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to load uptime")
+					return
+				}
+				items = buildUptimeReport(counts, *first, now, loc, days, HealthSnapshotInterval)
+			}
+			c.JSON(http.StatusOK, gin.H{
+				"interval_seconds": int(HealthSnapshotInterval.Seconds()),
+				"timezone":         loc.String(),
+				"tracking_since":   first,
+				"days":             items,
+			})
+		})
+
+		// 直近の API / ワーカー停止時のキュー状態（limit は最大 50）
+		systemAdmin.GET("/system/shutdown_reports", func(c *gin.Context) {
+			limit := 10
+			if v := c.Query("limit"); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil || n <= 0 || n > maxShutdownReports {
+					respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "limit は 1〜50 で指定してください")
+					return
+				}
+				limit = n
+			}
+			items, err := ListShutdownReports(c.Request.Context(), redisClient, limit)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to load shutdown reports")
+				return
+			}
+			var last *ShutdownReport
+			if len(items) > 0 {
+				last = &items[0]
+			}
+			c.JSON(http.StatusOK, gin.H{"last": last, "items": items})
+		})
+
+		problemsAdmin.POST("/submissions/bulk_test", func(c *gin.Context) {
 			var req struct {
 				ProblemID  int64  `json:"problem_id"`
 				Language   string `json:"language"`
 				Count      int    `json:"count"`
 				SourceCode string `json:"source_code"`
+				// RunAll は最初の不正解で打ち切らず全テストケースを実行する（問題の設定より優先）
+				RunAll bool `json:"run_all_testcases"`
+				// RatePerMinute と DurationSec を指定すると負荷試験モード（count は無視し、一定レートで投入し続ける）
+				RatePerMinute int `json:"rate_per_minute"`
+				DurationSec   int `json:"duration_sec"`
 			}
 			if err := c.ShouldBindJSON(&req); err != nil {
 				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
@@ -430,9 +963,32 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 				return
 			}
 
+			if req.RatePerMinute != 0 || req.DurationSec != 0 {
+				total, err := ValidateLoadTest(req.RatePerMinute, req.DurationSec)
+				if err != nil {
+					respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+					return
+				}
+				test, err := startLoadTest(ctx, loadTestRepo, LoadTest{
+					ProblemID:     req.ProblemID,
+					Language:      req.Language,
+					RatePerMinute: req.RatePerMinute,
+					DurationSec:   req.DurationSec,
+					RequestedBy:   &user.ID,
+				}, total, func(ctx context.Context) (int64, error) {
+					return createSubmissionWithSource(ctx, cfg, subRepo, db, queue, user.ID, req.ProblemID, req.Language, req.SourceCode, req.RunAll)
+				})
+				if err != nil {
+					respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to start load test")
+					return
+				}
+				c.JSON(http.StatusAccepted, gin.H{"load_test": test, "planned": total})
+				return
+			}
+
 			ids := make([]int64, 0, req.Count)
 			for i := 0; i < req.Count; i++ {
-				subID, err := createSubmissionWithSource(ctx, cfg, subRepo, db, queue, user.ID, req.ProblemID, req.Language, req.SourceCode)
+				subID, err := createSubmissionWithSource(ctx, cfg, subRepo, db, queue, user.ID, req.ProblemID, req.Language, req.SourceCode, req.RunAll)
 				if err != nil {
 					respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", fmt.Sprintf("failed at %d/%d: %v", i+1, req.Count, err))
 					return
@@ -444,18 +1000,151 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 				"count":    len(ids),
 				"problem":  req.ProblemID,
 				"language": req.Language,
+				"run_all":  req.RunAll,
 			})
 		})
 
+		// 提出元 IP / User-Agent の検索（ip と userid のどちらかが必須）
+		submissionsAdmin.GET("/submissions/clients", func(c *gin.Context) {
+			filter := SubmissionClientFilter{IP: strings.TrimSpace(c.Query("ip"))}
+			if filter.IP != "" && net.ParseIP(filter.IP) == nil {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "ip の形式が不正です")
+				return
+			}
+			ctx := c.Request.Context()
+			if userid := strings.TrimSpace(c.Query("userid")); userid != "" {
+				u, err := userRepo.FindByUsername(ctx, userid)
+				if err != nil {
+					respondError(c, http.StatusNotFound, "NOT_FOUND", "user not found")
+					return
+				}
+				filter.UserID = &u.ID
+			}
+			if filter.IP == "" && filter.UserID == nil {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "ip または userid を指定してください")
+				return
+			}
+			items, err := subRepo.SearchClientInfo(ctx, filter, 500)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch client info")
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"items": items})
+		})
+
+		// テストケースごとの保存済み stdout/stderr をダウンロード
+		submissionsAdmin.GET("/submissions/:id/artifacts/:testcase/:kind", func(c *gin.Context) {
+			id, ok := parseIDParam(c, "id")
+			if !ok {
+				return
+			}
+			kind := c.Param("kind")
+			if kind != "stdout" && kind != "stderr" {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "kind は stdout または stderr を指定してください")
+				return
+			}
+			ctx := c.Request.Context()
+			res, err := subRepo.FindWithResult(ctx, id)
+			if err != nil {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "submission not found")
+				return
+			}
+			var path *string
+			for _, d := range res.Details {
+				if d.Testcase != c.Param("testcase") {
+					continue
+				}
+				if kind == "stdout" {
+					path = d.StdoutPath
+				} else {
+					path = d.StderrPath
+				}
+				break
+			}
+			if path == nil || strings.TrimSpace(*path) == "" {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "artifact not found")
+				return
+			}
+			// 提出ディレクトリ外のパスは返さない
+			if !pathInsideDir(filepath.Join(cfg.SubmissionDir, strconv.FormatInt(id, 10)), *path) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "artifact not found")
+				return
+			}
+			c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%d_%s.%s", id, c.Param("testcase"), kind))
+			c.File(*path)
+		})
+
+		// 判定結果の署名検証（RESULT_SIGNING_KEY はワーカーと共通）
+		submissionsAdmin.GET("/submissions/:id/verify", func(c *gin.Context) {
+			id, ok := parseIDParam(c, "id")
+			if !ok {
+				return
+			}
+			if cfg.ResultSigningKey == "" {
+				respondError(c, http.StatusServiceUnavailable, "SIGNING_DISABLED", "RESULT_SIGNING_KEY が設定されていません")
+				return
+			}
+			res, err := subRepo.FindStoredResult(c.Request.Context(), id)
+			if err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					respondError(c, http.StatusNotFound, "NOT_FOUND", "result not found")
+					return
+				}
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch result")
+				return
+			}
+			c.JSON(http.StatusOK, VerifyResult([]byte(cfg.ResultSigningKey), *res))
+		})
+
+		submissionsAdmin.POST("/submissions/verify", func(c *gin.Context) {
+			var req struct {
+				IDs []int64 `json:"ids"`
+			}
+			if err := c.ShouldBindJSON(&req); err != nil {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+				return
+			}
+			if len(req.IDs) == 0 || len(req.IDs) > 1000 {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "ids は 1〜1000 件で指定してください")
+				return
+			}
+			if cfg.ResultSigningKey == "" {
+				respondError(c, http.StatusServiceUnavailable, "SIGNING_DISABLED", "RESULT_SIGNING_KEY が設定されていません")
+				return
+			}
+			ctx := c.Request.Context()
+			key := []byte(cfg.ResultSigningKey)
+			items := make([]ResultVerification, 0, len(req.IDs))
+			var missing []int64
+			invalid := 0
+			for _, id := range req.IDs {
+				res, err := subRepo.FindStoredResult(ctx, id)
+				if err != nil {
+					if errors.Is(err, pgx.ErrNoRows) {
+						missing = append(missing, id)
+						continue
+					}
+					respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch result")
+					return
+				}
+				v := VerifyResult(key, *res)
+				if !v.Valid {
+					invalid++
+				}
+				items = append(items, v)
+			}
+			c.JSON(http.StatusOK, gin.H{"items": items, "invalid_count": invalid, "missing_ids": missing})
+		})
+
 		// alias: /submissions/test -> bulk_test
-		admin.POST("/submissions/test", func(c *gin.Context) {
+		problemsAdmin.POST("/submissions/test", func(c *gin.Context) {
 			// forward to bulk_test handler
 			c.Request.URL.Path = "/api/v1/admin/submissions/bulk_test"
 			r.HandleContext(c)
 		})
 
 		// お知らせ CRUD（管理者のみ）
-		admin.GET("/notices", func(c *gin.Context) {
+		noticesAdmin.GET("/notices", func(c *gin.Context) {
 			page, perPage, err := parsePagination(c.Query("page"), c.Query("per_page"))
 			if err != nil {
 				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
@@ -476,7 +1165,7 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 			})
 		})
 
-		admin.POST("/notices", func(c *gin.Context) {
+		noticesAdmin.POST("/notices", func(c *gin.Context) {
 			var req struct {
 				Title string `json:"title"`
 				Body  string `json:"body"`
@@ -497,10 +1186,14 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to create notice")
 				return
 			}
+			setAuditTarget(c, "notices", n.ID)
+			if err := eventBus.Publish(ctx, NoticeEventChannel, eventNotice, n.ID, n); err != nil {
+				log.Printf("[notice] publish %d failed: %v", n.ID, err)
+			}
 			c.JSON(http.StatusCreated, n)
 		})
 
-		admin.PATCH("/notices/:id", func(c *gin.Context) {
+		noticesAdmin.PATCH("/notices/:id", func(c *gin.Context) {
 			id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 			if err != nil || id <= 0 {
 				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid id")
@@ -545,21 +1238,30 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 			c.JSON(http.StatusOK, n)
 		})
 
-		admin.DELETE("/notices/:id", func(c *gin.Context) {
+		noticesAdmin.DELETE("/notices/:id", func(c *gin.Context) {
 			id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 			if err != nil || id <= 0 {
 				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid id")
 				return
 			}
+			user, ok := requireUser(c, userRepo)
+			if !ok {
+				return
+			}
 			ctx := c.Request.Context()
-			if err := noticeRepo.Delete(ctx, id); err != nil {
+			// ゴミ箱へ移すだけ（/admin/trash から復元できる）
+			if err := trashRepo.Trash(ctx, TrashKindNotice, id, &user.ID); err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					respondError(c, http.StatusNotFound, "NOT_FOUND", "notice not found")
+					return
+				}
 				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to delete notice")
 				return
 			}
 			c.Status(http.StatusNoContent)
 		})
 
-		admin.POST("/users", func(c *gin.Context) {
+		usersAdmin.POST("/users", func(c *gin.Context) {
 			var req struct {
 				UserID   string `json:"userid"`
 				Password string `json:"password"`
@@ -576,12 +1278,17 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 				return
 			}
 			if req.Role == "" {
-				req.Role = "user"
+				req.Role = RoleUser
 			}
-			if req.Role != "user" && req.Role != "admin" {
+			if !ValidRole(req.Role) {
 				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid role")
 				return
 			}
+			// 自分より強い権限のロールは作成できない
+			if !roleCovers(sessionRole(c), req.Role) {
+				respondError(c, http.StatusForbidden, "FORBIDDEN", "このロールのユーザーを作成する権限がありません")
+				return
+			}
 
 			hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 			if err != nil {
@@ -606,6 +1313,8 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 				return
 			}
 
+			setAuditTarget(c, "users", record.Username)
+			setAuditSummary(c, "role=%q", record.Role)
 			c.JSON(http.StatusCreated, gin.H{
 				"id":         record.ID,
 				"userid":     record.Username,
@@ -614,7 +1323,7 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 			})
 		})
 
-		admin.GET("/users", func(c *gin.Context) {
+		usersAdmin.GET("/users", func(c *gin.Context) {
 			page, perPage, err := parsePagination(c.Query("page"), c.Query("per_page"))
 			if err != nil {
 				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
@@ -635,7 +1344,7 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 			})
 		})
 
-		admin.GET("/problems/template", func(c *gin.Context) {
+		problemsAdmin.GET("/problems/template", func(c *gin.Context) {
 			data, err := buildProblemTemplateZip()
 			if err != nil {
 				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to build template")
@@ -646,61 +1355,28 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 			c.Data(http.StatusOK, "application/zip", data)
 		})
 
-		admin.POST("/problems/import", func(c *gin.Context) {
-			fileHeader, err := c.FormFile("file")
-			if err != nil {
-				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "file フィールドに zip を指定してください")
-				return
-			}
-			if fileHeader.Size > maxProblemImportSize {
-				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "ファイルが大きすぎます (8MB 以下にしてください)")
-				return
-			}
-			file, err := fileHeader.Open()
-			if err != nil {
-				respondError(c, http.StatusBadRequest, "INVALID_PROBLEM_PACKAGE", "ファイルを開けません")
-				return
-			}
-			defer file.Close()
-			limited := io.LimitReader(file, maxProblemImportSize+1024)
-			data, err := io.ReadAll(limited)
-			if err != nil {
-				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "アップロードの読み取りに失敗しました")
-				return
-			}
-			if int64(len(data)) > maxProblemImportSize {
-				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "ファイルが大きすぎます (8MB 以下にしてください)")
+		problemsAdmin.POST("/problems/import", func(c *gin.Context) {
+			data, ok := readProblemImportFile(c, cfg)
+			if !ok {
 				return
 			}
+			importProblemPackage(c, problemRepo, userRepo, testcaseGen, bytes.NewReader(data), int64(len(data)), importOverwriteRequested(c))
+		})
 
-			pkg, err := ParseProblemArchive(data)
-			if err != nil {
-				respondError(c, http.StatusBadRequest, "INVALID_PROBLEM_PACKAGE", err.Error())
+		// 既存の問題をパッケージで更新する（slug が一致する必要がある。overwrite の確認は不要）
+		problemsAdmin.POST("/problems/:id/import", func(c *gin.Context) {
+			id, ok := parseIDParam(c, "id")
+			if !ok {
 				return
 			}
-
-			ctx := c.Request.Context()
-			problemID, err := problemRepo.CreateWithTestcases(ctx, pkg)
-			if err != nil {
-				if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "unique") {
-					respondError(c, http.StatusConflict, "CONFLICT", "同じ slug の問題が既に存在します")
-					return
-				}
-				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "問題の保存に失敗しました")
+			data, ok := readProblemImportFile(c, cfg)
+			if !ok {
 				return
 			}
-
-			c.JSON(http.StatusCreated, gin.H{
-				"id":              problemID,
-				"title":           pkg.Title,
-				"slug":            pkg.Slug,
-				"time_limit_ms":   pkg.TimeLimitMS,
-				"memory_limit_kb": pkg.MemoryLimitKB,
-				"is_public":       pkg.IsPublic,
-			})
+			reimportProblemPackage(c, problemRepo, userRepo, testcaseGen, id, data)
 		})
 
-		admin.GET("/problems", func(c *gin.Context) {
+		problemsAdmin.GET("/problems", func(c *gin.Context) {
 			page, perPage, err := parsePagination(c.Query("page"), c.Query("per_page"))
 			if err != nil {
 				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
@@ -721,7 +1397,7 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 			})
 		})
 
-		admin.GET("/problems/:id/download", func(c *gin.Context) {
+		problemsAdmin.GET("/problems/:id/download", func(c *gin.Context) {
 			id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 			if err != nil || id <= 0 {
 				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid id")
@@ -733,41 +1409,74 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 				respondError(c, http.StatusNotFound, "NOT_FOUND", "problem not found")
 				return
 			}
-			cases, err := problemRepo.ListTestcases(ctx, id)
+			subtasks, err := problemRepo.ListSubtasks(ctx, id)
 			if err != nil {
-				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to load testcases")
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to load subtasks")
 				return
 			}
-			zipBytes, err := buildProblemZipFromDB(*detail, cases)
+			assets, err := problemRepo.ListAssets(ctx, id)
 			if err != nil {
-				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to build archive")
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to load assets")
 				return
 			}
+			// テストケースを1件ずつ読みながら zip をそのままレスポンスへ流す（全体をメモリに載せない）
 			c.Header("Content-Type", "application/zip")
 			c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.zip", detail.Slug))
-			c.Data(http.StatusOK, "application/zip", zipBytes)
+			c.Status(http.StatusOK)
+			each := func(fn func(ProblemTestcase) error) error {
+				return problemRepo.EachTestcase(ctx, id, fn)
+			}
+			if err := writeProblemZip(c.Writer, *detail, subtasks, assets, each); err != nil {
+				// ヘッダ送信済みのためエラー応答は返せない。central directory の無い壊れた zip として届く
+				log.Printf("problem download id=%d aborted: %v", id, err)
+				c.Abort()
+			}
 		})
 
-		admin.PATCH("/problems/:id", func(c *gin.Context) {
+		problemsAdmin.PATCH("/problems/:id", func(c *gin.Context) {
 			id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 			if err != nil || id <= 0 {
 				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid id")
 				return
 			}
+			editor, ok := requireUser(c, userRepo)
+			if !ok {
+				return
+			}
 			var req struct {
-				Title         *string  `json:"title"`
-				StatementMD   *string  `json:"statement_md"`
-				TimeLimitMS   *int32   `json:"time_limit_ms"`
-				MemoryLimitKB *int32   `json:"memory_limit_kb"`
-				IsPublic      *bool    `json:"is_public"`
-				CheckerType   *string  `json:"checker_type"`
-				CheckerEps    *float64 `json:"checker_eps"`
+				Title         *string   `json:"title"`
+				TitleJA       *string   `json:"title_ja"` // 空文字で解除
+				TitleEN       *string   `json:"title_en"`
+				StatementMD   *string   `json:"statement_md"`
+				TimeLimitMS   *int32    `json:"time_limit_ms"`
+				MemoryLimitKB *int32    `json:"memory_limit_kb"`
+				IsPublic      *bool     `json:"is_public"`
+				CheckerType   *string   `json:"checker_type"`
+				CheckerEps    *float64  `json:"checker_eps"`
+				CheckerSource *string   `json:"checker_source"` // checker_type=custom の checker.cpp
+				RunAll        *bool     `json:"run_all_testcases"`
+				ContestID     *int64    `json:"contest_id"` // 0 で紐付け解除
+				Difficulty    *int      `json:"difficulty"` // 1〜10、0 で解除
+				Tags          *[]string `json:"tags"`       // 空配列で全て外す
+				// Recheck はチェッカーを変えたとき、判定済みの提出を保存済みの出力で判定し直す（recheck モードのリジャッジ）
+				Recheck bool `json:"recheck"`
 			}
 			if err := c.ShouldBindJSON(&req); err != nil {
 				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
 				return
 			}
+			preview := c.Query("preview") == "true"
+			if preview && req.StatementMD == nil {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "preview には statement_md が必要です")
+				return
+			}
 			ctx := c.Request.Context()
+			if req.ContestID != nil && *req.ContestID != 0 {
+				if _, err := contestRepo.Get(ctx, *req.ContestID); err != nil {
+					respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "contest_id のコンテストが存在しません")
+					return
+				}
+			}
 			exists, err := problemRepo.Exists(ctx, id)
 			if err != nil {
 				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch problem")
@@ -777,26 +1486,199 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 				respondError(c, http.StatusNotFound, "NOT_FOUND", "problem not found")
 				return
 			}
+			if req.StatementMD != nil {
+				current, err := problemRepo.FindDetailAdmin(ctx, id)
+				if err != nil {
+					respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch problem")
+					return
+				}
+				running, err := problemRepo.InRunningContest(ctx, id, time.Now())
+				if err != nil {
+					respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to check contest state")
+					return
+				}
+				diff, added, removed := unifiedDiff(current.StatementMD, *req.StatementMD, 3)
+				// preview=true は保存せず差分だけ返す
+				if preview {
+					c.JSON(http.StatusOK, gin.H{
+						"preview":            true,
+						"changed":            diff != "",
+						"statement_md":       *req.StatementMD,
+						"diff":               diff,
+						"added_lines":        added,
+						"removed_lines":      removed,
+						"in_running_contest": running,
+					})
+					return
+				}
+				// 開催中コンテストの問題文変更は confirm=true を明示した場合のみ
+				if running && diff != "" && c.Query("confirm") != "true" {
+					respondError(c, http.StatusConflict, "CONFIRMATION_REQUIRED", "開催中のコンテストの問題文です。preview=true で差分を確認し confirm=true を付けて再送してください")
+					return
+				}
+			}
 			if err := problemRepo.UpdateProblem(ctx, id, ProblemUpdateInput{
-				Title:         req.Title,
-				StatementMD:   req.StatementMD,
-				TimeLimitMS:   req.TimeLimitMS,
-				MemoryLimitKB: req.MemoryLimitKB,
-				IsPublic:      req.IsPublic,
-				CheckerType:   req.CheckerType,
-				CheckerEps:    req.CheckerEps,
+				Title:           req.Title,
+				TitleJA:         req.TitleJA,
+				TitleEN:         req.TitleEN,
+				StatementMD:     req.StatementMD,
+				TimeLimitMS:     req.TimeLimitMS,
+				MemoryLimitKB:   req.MemoryLimitKB,
+				IsPublic:        req.IsPublic,
+				CheckerType:     req.CheckerType,
+				CheckerEps:      req.CheckerEps,
+				CheckerSource:   req.CheckerSource,
+				RunAllTestcases: req.RunAll,
+				Difficulty:      req.Difficulty,
+				Tags:            req.Tags,
+				ContestID:       req.ContestID,
+				EditedBy:        &editor.ID,
+				ReviewRequired:  reviewRequired(c),
 			}); err != nil {
-				if strings.Contains(err.Error(), "checker") || strings.Contains(err.Error(), "limit") {
+				if errors.Is(err, ErrProblemInReview) {
+					respondProblemReviewError(c, err, "")
+					return
+				}
+				if errors.Is(err, ErrProblemTagInput) || errors.Is(err, ErrProblemDifficultyInput) || errors.Is(err, ErrCheckerEpsInput) || strings.Contains(err.Error(), "checker") || strings.Contains(err.Error(), "limit") {
 					respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
 					return
 				}
 				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to update problem")
 				return
 			}
+			// 公開中の問題への作問者の変更は保留され、承認されるまで反映されない
+			if reviewRequired(c) {
+				status, err := problemRepo.PublishStatus(ctx, id)
+				if err != nil {
+					respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch review status")
+					return
+				}
+				if status.PublishedAt != nil && status.PendingUpdate != nil {
+					c.JSON(http.StatusAccepted, gin.H{"review": status})
+					return
+				}
+			}
+			checkerChanged := req.CheckerType != nil || req.CheckerEps != nil || req.CheckerSource != nil
+			if req.Recheck && checkerChanged {
+				job, err := startRejudge(ctx, rejudgeRepo, queue, id, editor.ID, false, RejudgeModeRecheck)
+				if err != nil {
+					respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "問題は更新しましたが、再判定の開始に失敗しました")
+					return
+				}
+				c.JSON(http.StatusAccepted, gin.H{"rejudge": job})
+				return
+			}
+			// 制限時間を延ばした結果ジャッジ負荷が目安を超える場合は警告を返す（更新自体は行う）
+			if req.TimeLimitMS != nil {
+				count, err := problemRepo.TestcaseCount(ctx, id)
+				if err != nil {
+					log.Printf("count testcases of problem %d: %v", id, err)
+				} else if warning := judgeCostWarning(*req.TimeLimitMS, count); warning != "" {
+					c.JSON(http.StatusOK, gin.H{"judge_cost_warning": warning})
+					return
+				}
+			}
 			c.Status(http.StatusNoContent)
 		})
 
-		admin.GET("/problems/:id/stats", func(c *gin.Context) {
+		problemsAdmin.GET("/problems/:id/editorial", func(c *gin.Context) {
+			id, ok := parseIDParam(c, "id")
+			if !ok {
+				return
+			}
+			ctx := c.Request.Context()
+			editorial, err := problemRepo.GetEditorial(ctx, id)
+			if err != nil {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "problem not found")
+				return
+			}
+			if v, err := problemRepo.Visibility(ctx, id); err == nil {
+				editorial.Released = v.EditorialReleased(editorial.Visibility, time.Now())
+			}
+			c.JSON(http.StatusOK, editorial)
+		})
+
+		problemsAdmin.PUT("/problems/:id/editorial", func(c *gin.Context) {
+			id, ok := parseIDParam(c, "id")
+			if !ok {
+				return
+			}
+			var req struct {
+				Editorial  *string `json:"editorial"`
+				Visibility *string `json:"visibility"` // auto|public|hidden
+			}
+			if err := c.ShouldBindJSON(&req); err != nil {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+				return
+			}
+			ctx := c.Request.Context()
+			editorial, err := problemRepo.SetEditorial(ctx, id, ProblemEditorialInput{
+				EditorialMD: req.Editorial,
+				Visibility:  req.Visibility,
+			})
+			if err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					respondError(c, http.StatusNotFound, "NOT_FOUND", "problem not found")
+					return
+				}
+				if strings.Contains(err.Error(), "visibility") {
+					respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+					return
+				}
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to update editorial")
+				return
+			}
+			if v, err := problemRepo.Visibility(ctx, id); err == nil {
+				editorial.Released = v.EditorialReleased(editorial.Visibility, time.Now())
+			}
+			c.JSON(http.StatusOK, editorial)
+		})
+
+		problemsAdmin.GET("/problems/:id/statement/versions", func(c *gin.Context) {
+			id, ok := parseIDParam(c, "id")
+			if !ok {
+				return
+			}
+			ctx := c.Request.Context()
+			exists, err := problemRepo.Exists(ctx, id)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch problem")
+				return
+			}
+			if !exists {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "problem not found")
+				return
+			}
+			items, err := problemRepo.StatementVersions(ctx, id)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch statement versions")
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"items": items})
+		})
+
+		problemsAdmin.GET("/problems/:id/statement/versions/:vid", func(c *gin.Context) {
+			id, ok := parseIDParam(c, "id")
+			if !ok {
+				return
+			}
+			vid, ok := parseIDParam(c, "vid")
+			if !ok {
+				return
+			}
+			v, err := problemRepo.StatementVersion(c.Request.Context(), id, vid)
+			if err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					respondError(c, http.StatusNotFound, "NOT_FOUND", "statement version not found")
+					return
+				}
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch statement version")
+				return
+			}
+			c.JSON(http.StatusOK, v)
+		})
+
+		problemsAdmin.GET("/problems/:id/stats", func(c *gin.Context) {
 			id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 			if err != nil || id <= 0 {
 				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid id")
@@ -815,19 +1697,29 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 			c.JSON(http.StatusOK, stats)
 		})
 
-		admin.GET("/users/:userid/submissions", func(c *gin.Context) {
+		submissionsAdmin.GET("/users/:userid/submissions", func(c *gin.Context) {
 			page, perPage, err := parsePagination(c.Query("page"), c.Query("per_page"))
 			if err != nil {
 				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
 				return
 			}
+			filter, err := parseSubmissionListFilter(c.Request.URL.Query())
+			if err != nil {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+				return
+			}
 			ctx := c.Request.Context()
 			user, err := userRepo.FindByUsername(ctx, c.Param("userid"))
 			if err != nil {
 				respondError(c, http.StatusNotFound, "NOT_FOUND", "user not found")
 				return
 			}
-			items, total, err := subRepo.ListByUser(ctx, user.ID, nil, page, perPage)
+			if serveSubmissionCursorPage(c, perPage, func(after *SubmissionCursor) ([]SubmissionListItem, *SubmissionCursor, error) {
+				return subRepo.ListByUserAfter(ctx, user.ID, filter, after, perPage)
+			}) {
+				return
+			}
+			items, total, err := subRepo.ListByUser(ctx, user.ID, filter, page, perPage)
 			if err != nil {
 				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch submissions")
 				return
@@ -841,7 +1733,7 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 			})
 		})
 
-		admin.GET("/problems/:id/submissions", func(c *gin.Context) {
+		submissionsAdmin.GET("/problems/:id/submissions", func(c *gin.Context) {
 			id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 			if err != nil || id <= 0 {
 				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid id")
@@ -852,6 +1744,11 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
 				return
 			}
+			filter, err := parseSubmissionListFilter(c.Request.URL.Query())
+			if err != nil {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+				return
+			}
 			ctx := c.Request.Context()
 			exists, err := problemRepo.Exists(ctx, id)
 			if err != nil {
@@ -862,7 +1759,12 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 				respondError(c, http.StatusNotFound, "NOT_FOUND", "problem not found")
 				return
 			}
-			items, total, err := subRepo.ListByProblem(ctx, id, page, perPage)
+			if serveSubmissionCursorPage(c, perPage, func(after *SubmissionCursor) ([]SubmissionListItem, *SubmissionCursor, error) {
+				return subRepo.ListByProblemAfter(ctx, id, filter, after, perPage)
+			}) {
+				return
+			}
+			items, total, err := subRepo.ListByProblem(ctx, id, filter, page, perPage)
 			if err != nil {
 				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch submissions")
 				return
@@ -876,115 +1778,162 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 			})
 		})
 
-		admin.POST("/users/bulk", func(c *gin.Context) {
-			fileHeader, err := c.FormFile("file")
-			if err != nil {
-				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "file フィールドに CSV を指定してください")
+		api.GET("/problems", func(c *gin.Context) {
+			user, ok := requireUser(c, userRepo)
+			if !ok {
+				return
+			}
+
+			ctx := c.Request.Context()
+			filter := ProblemListFilter{Tag: c.Query("tag"), Sort: c.Query("sort")}
+			if _, ok := problemListOrder(filter.Sort); !ok {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "sort は id / difficulty / -difficulty のいずれかを指定してください")
 				return
 			}
-			file, err := fileHeader.Open()
+			list, err := problemRepo.ListPublic(ctx, filter)
 			if err != nil {
-				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "ファイルを開けません")
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch problems")
 				return
 			}
-			defer file.Close()
+			localizeProblemMetas(list, requestLocale(c, user))
+			c.JSON(http.StatusOK, gin.H{"problems": list})
+		})
 
-			reader := csv.NewReader(file)
-			records, err := reader.ReadAll()
-			if err != nil || len(records) == 0 {
-				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "CSV を読み取れません")
+		// respondProblemDetail writes the public view of a problem (shared by the ID and slug routes).
+		respondProblemDetail := func(c *gin.Context, user *UserRecord, id int64) {
+			ctx := c.Request.Context()
+			access, err := resolveProblemAccess(ctx, problemRepo, contestRepo, user, id)
+			if err != nil {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "problem not found")
 				return
 			}
-			header := records[0]
-			if len(header) < 2 || strings.ToLower(strings.TrimSpace(header[0])) != "userid" || strings.ToLower(strings.TrimSpace(header[1])) != "password" {
-				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "ヘッダーは userid,password 形式にしてください")
+			if !access.Visible {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", access.Reason)
 				return
 			}
-
-			type failedRow struct {
-				RowNumber int    `json:"row_number"`
-				UserID    string `json:"userid"`
-				Reason    string `json:"reason"`
+			detail, err := problemRepo.FindDetailAdmin(ctx, id)
+			if err != nil {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", err.Error())
+				return
 			}
-			var failed []failedRow
-			created := 0
-
-			ctx := c.Request.Context()
-			for i, row := range records[1:] {
-				rowNumber := i + 2 // header is row 1
-				if len(row) < 2 {
-					failed = append(failed, failedRow{RowNumber: rowNumber, UserID: "", Reason: "INVALID_ROW"})
-					continue
-				}
-				userid := strings.TrimSpace(row[0])
-				password := row[1]
-				if userid == "" || password == "" {
-					failed = append(failed, failedRow{RowNumber: rowNumber, UserID: userid, Reason: "VALIDATION_ERROR"})
-					continue
-				}
-				hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-				if err != nil {
-					failed = append(failed, failedRow{RowNumber: rowNumber, UserID: userid, Reason: "INTERNAL_ERROR"})
-					continue
-				}
-				if _, err := userRepo.Create(ctx, userid, string(hash), "user"); err != nil {
-					reason := "UNKNOWN_ERROR"
-					if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "unique") {
-						reason = "USERID_ALREADY_EXISTS"
-					}
-					failed = append(failed, failedRow{RowNumber: rowNumber, UserID: userid, Reason: reason})
-					continue
-				}
-				created++
+			subtasks, err := problemRepo.ListSubtasks(ctx, id)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to load subtasks")
+				return
 			}
-
+			statement := resolveStatementAssets(detail.StatementMD, detail.ID)
 			c.JSON(http.StatusOK, gin.H{
-				"created_count": created,
-				"failed_count":  len(failed),
-				"failed_rows":   failed,
+				"id":              detail.ID,
+				"slug":            detail.Slug,
+				"title":           detail.LocalizedTitles.For(requestLocale(c, user), detail.Title),
+				"title_ja":        detail.TitleJA,
+				"title_en":        detail.TitleEN,
+				"statement":       statement,
+				"samples":         detail.Samples,
+				"time_limit_ms":   detail.TimeLimitMS,
+				"memory_limit_kb": detail.MemoryLimitKB,
+				"difficulty":      detail.Difficulty,
+				"tags":            detail.Tags,
+				"subtasks":        subtasks,
+				"checker":         publicCheckerInfo(detail.CheckerType, detail.CheckerEps),
 			})
-		})
+		}
 
-		api.GET("/problems", func(c *gin.Context) {
-			if _, ok := requireLogin(c); !ok {
+		api.GET("/problems/:id", func(c *gin.Context) {
+			user, ok := requireUser(c, userRepo)
+			if !ok {
 				return
 			}
 
-			ctx := c.Request.Context()
-			list, err := problemRepo.ListPublic(ctx)
+			id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 			if err != nil {
-				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch problems")
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid id")
 				return
 			}
-			c.JSON(http.StatusOK, gin.H{"problems": list})
+			respondProblemDetail(c, user, id)
 		})
 
-		api.GET("/problems/:id", func(c *gin.Context) {
-			if _, ok := requireLogin(c); !ok {
+		// slug はインスタンス間でエクスポート・インポートしても変わらないため、外部からのリンクに使える
+		api.GET("/problems/slug/:slug", func(c *gin.Context) {
+			user, ok := requireUser(c, userRepo)
+			if !ok {
+				return
+			}
+			id, err := problemRepo.IDBySlug(c.Request.Context(), c.Param("slug"))
+			if err != nil {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "problem not found")
 				return
 			}
+			respondProblemDetail(c, user, id)
+		})
 
-			id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		// 問題文から参照される同梱画像（問題本体と同じ公開範囲）
+		api.GET("/problems/:id/assets/*name", func(c *gin.Context) {
+			user, ok := requireUser(c, userRepo)
+			if !ok {
+				return
+			}
+			id, ok := parseIDParam(c, "id")
+			if !ok {
+				return
+			}
+			ctx := c.Request.Context()
+			access, err := resolveProblemAccess(ctx, problemRepo, contestRepo, user, id)
+			if err != nil || !access.Visible {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "problem not found")
+				return
+			}
+			asset, err := problemRepo.GetAsset(ctx, id, strings.TrimPrefix(c.Param("name"), "/"))
 			if err != nil {
-				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid id")
+				if errors.Is(err, pgx.ErrNoRows) {
+					respondError(c, http.StatusNotFound, "NOT_FOUND", "asset not found")
+					return
+				}
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to load asset")
+				return
+			}
+			c.Header("X-Content-Type-Options", "nosniff")
+			c.Header("Cache-Control", "private, max-age=300")
+			c.Data(http.StatusOK, asset.ContentType, asset.Data)
+		})
+
+		// サーバー側で描画した問題文（数式は KaTeX 用に残す）。フロントエンドごとの描画差をなくすためのもの
+		api.GET("/problems/:id/statement.html", func(c *gin.Context) {
+			user, ok := requireUser(c, userRepo)
+			if !ok {
+				return
+			}
+			id, ok := parseIDParam(c, "id")
+			if !ok {
 				return
 			}
 			ctx := c.Request.Context()
-			detail, err := problemRepo.FindDetail(ctx, id)
+			access, err := resolveProblemAccess(ctx, problemRepo, contestRepo, user, id)
+			if err != nil || !access.Visible {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "problem not found")
+				return
+			}
+			detail, err := problemRepo.FindDetailAdmin(ctx, id)
 			if err != nil {
-				respondError(c, http.StatusNotFound, "NOT_FOUND", err.Error())
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "problem not found")
 				return
 			}
-			statement := detail.StatementMD
-			c.JSON(http.StatusOK, gin.H{
-				"id":              detail.ID,
-				"slug":            detail.Slug,
-				"title":           detail.Title,
-				"statement":       statement,
-				"samples":         detail.Samples,
-				"time_limit_ms":   detail.TimeLimitMS,
-				"memory_limit_kb": detail.MemoryLimitKB,
-			})
+			body, err := renderStatementHTML(resolveStatementAssets(detail.StatementMD, detail.ID))
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to render statement")
+				return
+			}
+			etag := statementETag(body)
+			c.Header("ETag", etag)
+			c.Header("Cache-Control", "private, no-cache")
+			c.Header("X-Content-Type-Options", "nosniff")
+			// 断片を直接開かれてもスクリプトは動かさない
+			c.Header("Content-Security-Policy", "default-src 'none'; img-src 'self' https: data:; style-src 'unsafe-inline'; sandbox")
+			if c.GetHeader("If-None-Match") == etag {
+				c.Status(http.StatusNotModified)
+				return
+			}
+			c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(body))
 		})
 
 		api.GET("/submissions", func(c *gin.Context) {
@@ -1002,14 +1951,10 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 				return
 			}
 
-			var problemFilter *int64
-			if pidStr := strings.TrimSpace(c.Query("problem_id")); pidStr != "" {
-				pid, err := strconv.ParseInt(pidStr, 10, 64)
-				if err != nil || pid <= 0 {
-					respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "problem_id は正の整数で指定してください")
-					return
-				}
-				problemFilter = &pid
+			filter, err := parseSubmissionListFilter(c.Request.URL.Query())
+			if err != nil {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+				return
 			}
 
 			ctx := c.Request.Context()
@@ -1019,7 +1964,12 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 				return
 			}
 
-			items, total, err := subRepo.ListByUser(ctx, user.ID, problemFilter, page, perPage)
+			if serveSubmissionCursorPage(c, perPage, func(after *SubmissionCursor) ([]SubmissionListItem, *SubmissionCursor, error) {
+				return subRepo.ListByUserAfter(ctx, user.ID, filter, after, perPage)
+			}) {
+				return
+			}
+			items, total, err := subRepo.ListByUser(ctx, user.ID, filter, page, perPage)
 			if err != nil {
 				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch submissions")
 				return
@@ -1034,8 +1984,72 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 			})
 		})
 
+		// 自分の提出一覧を CSV で出力（日時はユーザーのタイムゾーン・ロケールで表示）
+		api.GET("/submissions/export", func(c *gin.Context) {
+			user, ok := requireUser(c, userRepo)
+			if !ok {
+				return
+			}
+			ctx := c.Request.Context()
+			var items []SubmissionListItem
+			var after *SubmissionCursor
+			for page := 1; page <= maxExportPages; page++ {
+				chunk, next, err := subRepo.ListByUserAfter(ctx, user.ID, SubmissionListFilter{}, after, maxPerPage)
+				if err != nil {
+					respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch submissions")
+					return
+				}
+				items = append(items, chunk...)
+				if next == nil {
+					break
+				}
+				after = next
+			}
+			buf := &bytes.Buffer{}
+			if err := writeSubmissionsCSV(buf, items, *user); err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to build csv")
+				return
+			}
+			c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=submissions-%s.csv", user.Username))
+			c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+		})
+
+		// 解説: 練習問題は即時、コンテスト問題は終了後に公開（管理者が public/hidden で上書き可）
+		api.GET("/problems/:id/editorial", func(c *gin.Context) {
+			user, ok := requireUser(c, userRepo)
+			if !ok {
+				return
+			}
+			id, ok := parseIDParam(c, "id")
+			if !ok {
+				return
+			}
+			ctx := c.Request.Context()
+			v, access, err := resolveProblemVisibility(ctx, problemRepo, contestRepo, user, id)
+			if err != nil {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "problem not found")
+				return
+			}
+			if !access.Visible {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", access.Reason)
+				return
+			}
+			editorial, err := problemRepo.GetEditorial(ctx, id)
+			if err != nil || editorial.EditorialMD == "" {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "editorial not found")
+				return
+			}
+			editorial.Released = v.EditorialReleased(editorial.Visibility, time.Now())
+			if !editorial.Released && !isStaffRole(user.Role) {
+				respondError(c, http.StatusForbidden, "FORBIDDEN", "解説はまだ公開されていません")
+				return
+			}
+			c.JSON(http.StatusOK, editorial)
+		})
+
 		api.GET("/problems/:id/submissions", func(c *gin.Context) {
-			if _, ok := requireLogin(c); !ok {
+			user, ok := requireUser(c, userRepo)
+			if !ok {
 				return
 			}
 
@@ -1050,19 +2064,29 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
 				return
 			}
+			filter, err := parseSubmissionListFilter(c.Request.URL.Query())
+			if err != nil {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+				return
+			}
 
 			ctx := c.Request.Context()
-			isPublic, err := problemRepo.ExistsAndPublic(ctx, id)
+			access, err := resolveProblemAccess(ctx, problemRepo, contestRepo, user, id)
 			if err != nil {
 				respondError(c, http.StatusNotFound, "NOT_FOUND", "problem not found")
 				return
 			}
-			if !isPublic {
-				respondError(c, http.StatusForbidden, "FORBIDDEN", "非公開の問題です")
+			if !access.Visible {
+				respondError(c, http.StatusForbidden, "FORBIDDEN", access.Reason)
 				return
 			}
 
-			items, total, err := subRepo.ListByProblem(ctx, id, page, perPage)
+			if serveSubmissionCursorPage(c, perPage, func(after *SubmissionCursor) ([]SubmissionListItem, *SubmissionCursor, error) {
+				return subRepo.ListByProblemAfter(ctx, id, filter, after, perPage)
+			}) {
+				return
+			}
+			items, total, err := subRepo.ListByProblem(ctx, id, filter, page, perPage)
 			if err != nil {
 				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch submissions")
 				return
@@ -1099,6 +2123,10 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 				return
 			}
 
+			if HasPermission(sessionRole(c), PermSubmissionsRead) {
+				attachArtifactLinks(res.ID, res.Details)
+			}
+
 			sourceCode := ""
 			if strings.TrimSpace(res.SourcePath) != "" {
 				if b, err := os.ReadFile(res.SourcePath); err == nil {
@@ -1109,7 +2137,7 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 				}
 			}
 
-			c.JSON(http.StatusOK, gin.H{
+			resp := gin.H{
 				"id":            res.ID,
 				"userid":        res.Username,
 				"problem_id":    res.ProblemID,
@@ -1123,11 +2151,65 @@ func NewRouter(cfg Config, store *sessions.CookieStore, authService AuthService,
 				"updated_at":    res.UpdatedAt,
 				"exit_code":     res.ExitCode,
 				"error_message": res.ErrorMsg,
+				"passed_count":  res.PassedCount,
+				"total_count":   res.TotalCount,
+				"score":         res.Score,
+				"max_score":     res.MaxScore,
+				"subtasks":      res.Subtasks,
 				"source_code":   sourceCode,
 				"judge_details": res.Details,
-			})
+			}
+			if HasPermission(sessionRole(c), PermSubmissionsRead) {
+				// 保持期間を過ぎたものや記録前の提出は null
+				var client *SubmissionClientInfo
+				if ci, err := subRepo.ClientInfo(ctx, res.ID); err == nil {
+					client = ci
+				}
+				resp["client"] = client
+			}
+			c.JSON(http.StatusOK, resp)
 		})
 
+		registerContestRoutes(api, contestsAdmin, contestRepo, userRepo, problemRepo, testcaseGen)
+		registerContestAccessCodeRoutes(api, contestsAdmin, accessCodeRepo, contestRepo, userRepo, NewAccessCodeLimiter(redisClient))
+		registerClarificationRoutes(api, contestsAdmin, clarRepo, contestRepo, userRepo)
+		registerIncidentRoutes(systemAdmin, incidentRepo)
+		registerAnnouncementRoutes(api, contestsAdmin, annRepo, contestRepo, userRepo, eventBus)
+		registerWebSocketRoutes(api, wsHub, userRepo)
+		registerEventStreamRoutes(api, eventBus, contestRepo, userRepo)
+		registerJudgeMetaRoutes(api, userRepo)
+		registerUserProgressRoutes(api, subRepo, contestRepo, userRepo)
+		registerAPITokenRoutes(api, apiTokenRepo, userRepo)
+		registerOAuthRoutes(api, cfg, store, redisClient, NewPgUserIdentityRepository(db), userRepo, totpRepo)
+		registerPasswordResetRoutes(api, cfg, mailer, NewPgPasswordResetRepository(db), store)
+		registerEmailRoutes(api, cfg, mailer, emailRepo, userRepo)
+		registerTOTPRoutes(api, cfg, store, redisClient, totpRepo, userRepo)
+		registerSessionRoutes(api, usersAdmin, store, userRepo)
+		registerLoginLockoutRoutes(usersAdmin, loginLockout, userRepo)
+		registerUserSuspensionRoutes(usersAdmin, suspensionRepo, store, userRepo)
+		registerUserAdminRoutes(usersAdmin, NewPgUserAdminRepository(db), store, userRepo)
+		registerSubmissionQuotaRoutes(usersAdmin, cfg, quotaRepo, userRepo)
+		registerUserImportRoutes(usersAdmin, NewPgUserImportJobRepository(db), userRepo)
+		registerAuditLogRoutes(admin.Group("", RequirePermission(PermAuditRead)), auditRepo)
+		registerInvitationRoutes(api, usersAdmin, cfg, store, NewPgInvitationCodeRepository(db), userRepo, NewAccessCodeLimiter(redisClient))
+		registerContestTranscriptRoutes(api, cfg, contestRepo, userRepo)
+		registerContestGradeRoutes(api, contestsAdmin, contestRepo, userRepo)
+		registerContestPenaltyRoutes(contestsAdmin, contestRepo)
+		registerTrashRoutes(admin, trashRepo, userRepo, userErasureHandler(cfg, NewPgUserErasureRepository(db), store, userRepo))
+		registerNoticeTemplateRoutes(noticesAdmin, NewPgNoticeTemplateRepository(db), noticeRepo, eventBus, userRepo)
+		registerProblemUploadRoutes(problemsAdmin, cfg, NewUploadStore(cfg.UploadDir), problemRepo, userRepo, testcaseGen)
+		registerRejudgeRoutes(problemsAdmin, rejudgeRepo, problemRepo, userRepo, queue)
+		registerLimitCalibrationRoutes(problemsAdmin, cfg, judgeClient, problemRepo, userRepo)
+		registerProblemSandboxRoutes(problemsAdmin, cfg, sandboxPolicies, problemRepo, userRepo)
+		registerProblemPublishRoutes(problemsAdmin, problemRepo, userRepo)
+		registerProblemRevisionRoutes(problemsAdmin, problemRepo, userRepo)
+		registerProblemTestcaseRoutes(problemsAdmin, problemRepo, userRepo)
+		registerLoadTestRoutes(problemsAdmin, cfg, loadTestRepo)
+		registerGraderWebhookRoutes(problemsAdmin, graderWebhookRepo, userRepo)
+		registerGymRoutes(api, contestsAdmin, gymRepo, userRepo)
+		registerAnnotationRoutes(api, admin, annotationRepo, subRepo, userRepo)
+		registerDiscussionRoutes(api, admin, discussionRepo, problemRepo, contestRepo, userRepo)
+
 		api.GET("/queue", func(c *gin.Context) {
 			if _, ok := requireLogin(c); !ok {
 				return
@@ -1157,6 +2239,37 @@ func requireLogin(c *gin.Context) (string, bool) {
 	return userid, true
 }
 
+// requireUser resolves the logged-in user record; responds 401 when missing.
+func requireUser(c *gin.Context, userRepo UserRepository) (*UserRecord, bool) {
+	userid, ok := requireLogin(c)
+	if !ok {
+		return nil, false
+	}
+	u, err := userRepo.FindByUsername(c.Request.Context(), userid)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, "UNAUTHORIZED", "ユーザーが存在しません")
+		return nil, false
+	}
+	return u, true
+}
+
+// attachArtifactLinks fills admin download links for stored per-testcase outputs.
+func attachArtifactLinks(submissionID int64, details []SubmissionJudgeDetail) {
+	for i := range details {
+		links := map[string]string{}
+		base := fmt.Sprintf("/api/v1/admin/submissions/%d/artifacts/%s", submissionID, url.PathEscape(details[i].Testcase))
+		if details[i].StdoutPath != nil {
+			links["stdout"] = base + "/stdout"
+		}
+		if details[i].StderrPath != nil {
+			links["stderr"] = base + "/stderr"
+		}
+		if len(links) > 0 {
+			details[i].Artifacts = links
+		}
+	}
+}
+
 // ensureDir creates directory if not exists
 func ensureDir(path string) error {
 	return os.MkdirAll(path, 0755)
@@ -1194,9 +2307,10 @@ func defaultSourceFor(lang string) string {
 }
 
 // createSubmissionWithSource inserts submission, writes source file, updates path, and enqueues.
-func createSubmissionWithSource(ctx context.Context, cfg Config, subRepo SubmissionRepository, db *pgxpool.Pool, queue RedisClient, userID, problemID int64, lang, source string) (int64, error) {
+// runAll asks the worker to run every testcase regardless of the problem setting.
+func createSubmissionWithSource(ctx context.Context, cfg Config, subRepo SubmissionRepository, db *pgxpool.Pool, queue RedisClient, userID, problemID int64, lang, source string, runAll bool) (int64, error) {
 	// Reserve ID
-	subID, _, err := subRepo.Create(ctx, userID, problemID, lang, "")
+	subID, _, err := subRepo.Create(ctx, userID, problemID, nil, lang, "")
 	if err != nil {
 		return 0, err
 	}
@@ -1212,7 +2326,7 @@ func createSubmissionWithSource(ctx context.Context, cfg Config, subRepo Submiss
 		_ = os.RemoveAll(dir)
 		return 0, err
 	}
-	if _, err := db.Exec(ctx, `UPDATE submissions SET source_path=$1 WHERE id=$2`, srcPath, subID); err != nil {
+	if _, err := db.Exec(ctx, `UPDATE submissions SET source_path=$1, run_all_testcases=$2 WHERE id=$3`, srcPath, runAll, subID); err != nil {
 		_ = subRepo.Delete(ctx, subID)
 		_ = os.RemoveAll(dir)
 		return 0, err
@@ -1226,9 +2340,9 @@ func createSubmissionWithSource(ctx context.Context, cfg Config, subRepo Submiss
 }
 
 const (
-	defaultPerPage       = 20
-	maxPerPage           = 100
-	maxProblemImportSize = 8 * 1024 * 1024 // 8MB (upload payload limit)
+	defaultPerPage = 20
+	maxPerPage     = 100
+	maxExportPages = 100 // CSV 出力の上限 (maxPerPage * maxExportPages 件)
 )
 
 func parsePagination(pageStr, perPageStr string) (int, int, error) {
@@ -1254,6 +2368,55 @@ func parsePagination(pageStr, perPageStr string) (int, int, error) {
 	return page, perPage, nil
 }
 
+// serveSubmissionCursorPage answers submission list requests that carry ?cursor= (empty for the first
+// page) with keyset pagination and reports whether it did. Such responses have next_cursor instead of
+// page / total counts; requests without the parameter keep using page numbers.
+func serveSubmissionCursorPage(c *gin.Context, perPage int, fetch func(after *SubmissionCursor) ([]SubmissionListItem, *SubmissionCursor, error)) bool {
+	raw, ok := c.GetQuery("cursor")
+	if !ok {
+		return false
+	}
+	after, err := ParseSubmissionCursor(raw)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "cursor が不正です")
+		return true
+	}
+	items, next, err := fetch(after)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch submissions")
+		return true
+	}
+	var nextCursor *string
+	if next != nil {
+		s := next.Encode()
+		nextCursor = &s
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"items":       items,
+		"per_page":    perPage,
+		"next_cursor": nextCursor,
+	})
+	return true
+}
+
+// pathInsideDir reports whether path lies under dir. Both are made absolute first, so a relative
+// dir (the default SUBMISSION_DIR) works as well.
+func pathInsideDir(dir, path string) bool {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	return !filepath.IsAbs(rel)
+}
+
 func calcTotalPages(total, perPage int) int {
 	if perPage <= 0 {
 		return 0
@@ -1308,23 +2471,79 @@ checker:
 	return buf.Bytes(), nil
 }
 
-// buildProblemZipFromDB builds a problem archive from DB contents for admin download.
-func buildProblemZipFromDB(detail ProblemDetail, cases []ProblemTestcase) ([]byte, error) {
+// buildProblemZipFromDB builds a problem archive from DB contents in memory (used for nesting in contest archives).
+func buildProblemZipFromDB(detail ProblemDetail, cases []ProblemTestcase, subtasks []ProblemSubtask, assets []ProblemAsset) ([]byte, error) {
 	buf := &bytes.Buffer{}
-	zw := zip.NewWriter(buf)
+	each := func(fn func(ProblemTestcase) error) error {
+		for _, tc := range cases {
+			if err := fn(tc); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := writeProblemZip(buf, detail, subtasks, assets, each); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeProblemZip writes a problem archive to w. Testcases are pulled one at a time from each and
+// written first, so only their metadata is kept for problem.yaml. When w is an http.Flusher the
+// archive is flushed after every entry, letting large downloads stream with chunked transfer.
+func writeProblemZip(w io.Writer, detail ProblemDetail, subtasks []ProblemSubtask, assets []ProblemAsset, each func(func(ProblemTestcase) error) error) error {
+	zw := zip.NewWriter(w)
+	flusher, _ := w.(http.Flusher)
 
 	write := func(name, content string) error {
-		w, err := zw.Create(name)
+		fw, err := zw.Create(name)
 		if err != nil {
 			return err
 		}
-		_, err = w.Write([]byte(content))
+		if _, err := io.WriteString(fw, content); err != nil {
+			return err
+		}
+		if flusher != nil {
+			if err := zw.Flush(); err != nil {
+				return err
+			}
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	// テストケースは連番で書き出すので、小課題・グループは書き出し後の名前で参照する
+	var cases []ProblemTestcase
+	exportNames := map[int64]string{}
+	sampleIdx, secretIdx := 1, 1
+	if err := each(func(tc ProblemTestcase) error {
+		prefix := "secret"
+		idx := secretIdx
+		if tc.IsSample {
+			prefix = "sample"
+			idx = sampleIdx
+			sampleIdx++
+		} else {
+			secretIdx++
+		}
+		name := fmt.Sprintf("%s/%02d", prefix, idx)
+		exportNames[tc.ID] = name
+		if err := write(fmt.Sprintf("%s/data/%s.in", detail.Slug, name), tc.InputText); err != nil {
+			return err
+		}
+		if err := write(fmt.Sprintf("%s/data/%s.out", detail.Slug, name), tc.OutputText); err != nil {
+			return err
+		}
+		tc.InputText, tc.OutputText = "", ""
+		cases = append(cases, tc)
+		return nil
+	}); err != nil {
 		return err
 	}
 
 	problemYAML := fmt.Sprintf(`slug: %s
 title: "%s"
-
+%s
 limits:
   time_ms: %d
   memory_mb: %d
@@ -1332,47 +2551,100 @@ limits:
 checker:
   type: %s
   eps: %g
-`, detail.Slug, detail.Title, detail.TimeLimitMS, (detail.MemoryLimitKB+1023)/1024, defaultChecker(detail.CheckerType), detail.CheckerEps)
-
-	if err := write(fmt.Sprintf("%s/problem.yaml", detail.Slug), problemYAML); err != nil {
-		return nil, err
+`, detail.Slug, detail.Title, localizedTitlesYAML(detail.LocalizedTitles), detail.TimeLimitMS, (detail.MemoryLimitKB+1023)/1024, defaultChecker(detail.CheckerType), detail.CheckerEps)
+	if detail.RunAllTestcases {
+		problemYAML += "\nrun_all_testcases: true\n"
 	}
-	if err := write(fmt.Sprintf("%s/statement.md", detail.Slug), detail.StatementMD); err != nil {
-		return nil, err
+	if detail.Difficulty != nil {
+		problemYAML += fmt.Sprintf("\ndifficulty: %d\n", *detail.Difficulty)
+	}
+	switch detail.EditorialVisibility {
+	case EditorialVisibilityPublic:
+		problemYAML += "\neditorial_public: true\n"
+	case EditorialVisibilityHidden:
+		problemYAML += "\neditorial_public: false\n"
+	}
+	if len(detail.Tags) > 0 {
+		problemYAML += "\ntags: [" + strings.Join(detail.Tags, ", ") + "]\n"
 	}
 
-	// write testcases
-	sampleIdx, secretIdx := 1, 1
+	if len(subtasks) > 0 {
+		problemYAML += "\nsubtasks:\n"
+		for _, st := range subtasks {
+			names := make([]string, 0, len(st.TestcaseIDs))
+			for _, id := range st.TestcaseIDs {
+				if name, ok := exportNames[id]; ok {
+					names = append(names, name)
+				}
+			}
+			problemYAML += fmt.Sprintf("  - name: %q\n    score: %d\n    testcases: [%s]\n", st.Name, st.Score, strings.Join(names, ", "))
+		}
+	}
+
+	// グループはテストケースに付いたグループ名から復元する
+	var groupOrder []string
+	groupCases := map[string][]ProblemTestcase{}
 	for _, tc := range cases {
-		prefix := "secret"
-		idx := secretIdx
-		if tc.IsSample {
-			prefix = "sample"
-			idx = sampleIdx
-			sampleIdx++
-		} else {
-			secretIdx++
+		if tc.GroupName == "" {
+			continue
 		}
-		name := fmt.Sprintf("%02d", idx)
-		if err := write(fmt.Sprintf("%s/data/%s/%s.in", detail.Slug, prefix, name), tc.InputText); err != nil {
-			return nil, err
+		if _, ok := groupCases[tc.GroupName]; !ok {
+			groupOrder = append(groupOrder, tc.GroupName)
 		}
-		if err := write(fmt.Sprintf("%s/data/%s/%s.out", detail.Slug, prefix, name), tc.OutputText); err != nil {
-			return nil, err
+		groupCases[tc.GroupName] = append(groupCases[tc.GroupName], tc)
+	}
+	if len(groupOrder) > 0 {
+		problemYAML += "\ngroups:\n"
+		for _, name := range groupOrder {
+			members := groupCases[name]
+			names := make([]string, 0, len(members))
+			for _, tc := range members {
+				names = append(names, exportNames[tc.ID])
+			}
+			problemYAML += fmt.Sprintf("  - name: %q\n    testcases: [%s]\n", name, strings.Join(names, ", "))
+			if first := members[0]; first.TimeLimitMS != nil || first.MemoryLimitKB != nil {
+				problemYAML += "    limits:\n"
+				if first.TimeLimitMS != nil {
+					problemYAML += fmt.Sprintf("      time_ms: %d\n", *first.TimeLimitMS)
+				}
+				if first.MemoryLimitKB != nil {
+					problemYAML += fmt.Sprintf("      memory_mb: %d\n", (*first.MemoryLimitKB+1023)/1024)
+				}
+			}
 		}
 	}
 
-	if err := zw.Close(); err != nil {
-		return nil, err
+	if err := write(fmt.Sprintf("%s/problem.yaml", detail.Slug), problemYAML); err != nil {
+		return err
 	}
-	return buf.Bytes(), nil
+	if err := write(fmt.Sprintf("%s/statement.md", detail.Slug), detail.StatementMD); err != nil {
+		return err
+	}
+	if detail.EditorialMD != "" {
+		if err := write(fmt.Sprintf("%s/%s", detail.Slug, editorialSourceName), detail.EditorialMD); err != nil {
+			return err
+		}
+	}
+	if defaultChecker(detail.CheckerType) == CheckerTypeCustom {
+		if err := write(fmt.Sprintf("%s/%s", detail.Slug, checkerSourceName), detail.CheckerSource); err != nil {
+			return err
+		}
+	}
+	for _, a := range assets {
+		if err := write(detail.Slug+"/"+assetDir+a.Name, string(a.Data)); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
 }
 
 func defaultChecker(t string) string {
 	switch strings.ToLower(strings.TrimSpace(t)) {
-	case "eps":
-		return "eps"
+	case CheckerTypeEps:
+		return CheckerTypeEps
+	case CheckerTypeCustom:
+		return CheckerTypeCustom
 	default:
-		return "exact"
+		return CheckerTypeExact
 	}
 }
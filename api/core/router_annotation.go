@@ -0,0 +1,231 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	maxCommentLength   = 4000
+	maxGradeLength     = 32
+	maxGradeNoteLength = 10000
+)
+
+// registerAnnotationRoutes wires review comments and grades on submissions.
+// Annotations are visible to the submitter and to staff who can read submissions.
+func registerAnnotationRoutes(api, admin *gin.RouterGroup, annoRepo SubmissionAnnotationRepository, subRepo SubmissionRepository, userRepo UserRepository) {
+	api.GET("/submissions/:id/annotations", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		sub, err := subRepo.FindByID(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", "submission not found")
+			return
+		}
+		if sub.UserID != user.ID && !HasPermission(user.Role, PermSubmissionsRead) {
+			respondError(c, http.StatusForbidden, "FORBIDDEN", "この提出の講評は閲覧できません")
+			return
+		}
+		comments, err := annoRepo.ListComments(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch comments")
+			return
+		}
+		grade, err := annoRepo.Grade(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch grade")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"comments": comments, "grade": grade})
+	})
+
+	admin = admin.Group("", RequirePermission(PermSubmissionsGrade))
+
+	admin.POST("/submissions/:id/comments", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		var req struct {
+			Line int    `json:"line"`
+			Body string `json:"body"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		if !validCommentBody(c, req.Body) {
+			return
+		}
+		ctx := c.Request.Context()
+		sub, err := subRepo.FindByID(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", "submission not found")
+			return
+		}
+		lines, err := sourceLineCount(sub.SourcePath)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to read source code")
+			return
+		}
+		if req.Line < 1 || req.Line > lines {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "line はソースコードの行番号（1 以上）で指定してください")
+			return
+		}
+		cm, err := annoRepo.AddComment(ctx, id, user.ID, req.Line, req.Body)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to add comment")
+			return
+		}
+		c.JSON(http.StatusCreated, cm)
+	})
+
+	admin.PATCH("/submissions/:id/comments/:comment_id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		commentID, ok := parseIDParam(c, "comment_id")
+		if !ok {
+			return
+		}
+		var req struct {
+			Body string `json:"body"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		if !validCommentBody(c, req.Body) {
+			return
+		}
+		cm, err := annoRepo.UpdateComment(c.Request.Context(), id, commentID, req.Body)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "comment not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to update comment")
+			return
+		}
+		c.JSON(http.StatusOK, cm)
+	})
+
+	admin.DELETE("/submissions/:id/comments/:comment_id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		commentID, ok := parseIDParam(c, "comment_id")
+		if !ok {
+			return
+		}
+		if err := annoRepo.DeleteComment(c.Request.Context(), id, commentID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "comment not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to delete comment")
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	// 全体の評価（grade: 「A」「80点」など任意の短い文字列、note: 提出者向けの講評）
+	admin.PUT("/submissions/:id/grade", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		var req struct {
+			Grade string `json:"grade"`
+			Note  string `json:"note"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		req.Grade = strings.TrimSpace(req.Grade)
+		req.Note = strings.TrimSpace(req.Note)
+		if req.Grade == "" && req.Note == "" {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "grade または note を指定してください")
+			return
+		}
+		if len([]rune(req.Grade)) > maxGradeLength || len([]rune(req.Note)) > maxGradeNoteLength {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "grade または note が長すぎます")
+			return
+		}
+		ctx := c.Request.Context()
+		if _, err := subRepo.FindByID(ctx, id); err != nil {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", "submission not found")
+			return
+		}
+		grade, err := annoRepo.SetGrade(ctx, id, user.ID, req.Grade, req.Note)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to save grade")
+			return
+		}
+		c.JSON(http.StatusOK, grade)
+	})
+
+	admin.DELETE("/submissions/:id/grade", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		if err := annoRepo.DeleteGrade(c.Request.Context(), id); err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to delete grade")
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
+
+func validCommentBody(c *gin.Context, body string) bool {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "body は必須です")
+		return false
+	}
+	if len([]rune(body)) > maxCommentLength {
+		respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "body が長すぎます")
+		return false
+	}
+	return true
+}
+
+// sourceLineCount returns the number of lines of the stored source (a trailing newline does not start a new line).
+func sourceLineCount(path string) (int, error) {
+	if strings.TrimSpace(path) == "" {
+		return 0, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	s := strings.TrimSuffix(string(b), "\n")
+	if s == "" {
+		return 0, nil
+	}
+	return strings.Count(s, "\n") + 1, nil
+}
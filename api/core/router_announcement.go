@@ -0,0 +1,277 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	eventContestAnnouncement        = "announcement"
+	eventContestAnnouncementDeleted = "announcement_deleted"
+	sseKeepAliveInterval            = 25 * time.Second
+)
+
+// registerAnnouncementRoutes wires contest announcement endpoints: polling, SSE stream,
+// acknowledgements and admin management.
+func registerAnnouncementRoutes(api, admin *gin.RouterGroup, annRepo ContestAnnouncementRepository, contestRepo ContestRepository, userRepo UserRepository, bus *EventBus) {
+	// ポーリング: since_id より新しいアナウンス
+	api.GET("/contests/:id/announcements", func(c *gin.Context) {
+		user, contest, ok := loadAnnouncementContest(c, contestRepo, userRepo)
+		if !ok {
+			return
+		}
+		sinceID, ok := parseSinceID(c, c.Query("since_id"))
+		if !ok {
+			return
+		}
+		items, err := annRepo.List(c.Request.Context(), contest.ID, user.ID, sinceID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch announcements")
+			return
+		}
+		lastID := sinceID
+		if len(items) > 0 {
+			lastID = items[len(items)-1].ID
+		}
+		c.JSON(http.StatusOK, gin.H{"items": items, "last_id": lastID})
+	})
+
+	// SSE: 接続時に Last-Event-ID（または since_id）以降を再送し、その後は Redis 経由で配信する
+	api.GET("/contests/:id/announcements/stream", func(c *gin.Context) {
+		user, contest, ok := loadAnnouncementContest(c, contestRepo, userRepo)
+		if !ok {
+			return
+		}
+		sinceID, ok := parseSinceID(c, firstNonEmpty(c.GetHeader("Last-Event-ID"), c.Query("since_id")))
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		events, err := bus.Subscribe(ctx, ContestEventChannel(contest.ID))
+		if err != nil {
+			respondError(c, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "live delivery is unavailable")
+			return
+		}
+		backlog, err := annRepo.List(ctx, contest.ID, user.ID, sinceID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch announcements")
+			return
+		}
+
+		startSSE(c)
+
+		lastID := sinceID
+		for _, a := range backlog {
+			data, _ := json.Marshal(a)
+			writeSSE(c.Writer, eventContestAnnouncement, a.ID, data)
+			lastID = a.ID
+		}
+		c.Writer.Flush()
+
+		keepAlive := time.NewTicker(sseKeepAliveInterval)
+		defer keepAlive.Stop()
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case ev, ok := <-events:
+				if !ok {
+					return false
+				}
+				// 再送済みのものは送らない
+				if ev.Type == eventContestAnnouncement && ev.ID <= lastID {
+					return true
+				}
+				writeSSE(w, ev.Type, ev.ID, ev.Data)
+				if ev.Type == eventContestAnnouncement {
+					lastID = ev.ID
+				}
+				return true
+			case <-keepAlive.C:
+				fmt.Fprint(w, ": ping\n\n")
+				return true
+			}
+		})
+	})
+
+	api.POST("/contests/:id/announcements/:aid/ack", func(c *gin.Context) {
+		user, contest, ok := loadAnnouncementContest(c, contestRepo, userRepo)
+		if !ok {
+			return
+		}
+		aid, ok := parseIDParam(c, "aid")
+		if !ok {
+			return
+		}
+		if err := annRepo.Ack(c.Request.Context(), contest.ID, aid, user.ID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "announcement not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to acknowledge announcement")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"acked": true})
+	})
+
+	// 管理者向け
+	admin.GET("/contests/:id/announcements", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		items, err := annRepo.List(c.Request.Context(), id, 0, 0)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch announcements")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"items": items})
+	})
+
+	admin.POST("/contests/:id/announcements", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		var req struct {
+			Title    string `json:"title"`
+			Body     string `json:"body"`
+			Critical bool   `json:"critical"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		if strings.TrimSpace(req.Title) == "" || strings.TrimSpace(req.Body) == "" {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "title と body は必須です")
+			return
+		}
+		ctx := c.Request.Context()
+		contest, err := contestRepo.Get(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", "contest not found")
+			return
+		}
+		if contest.Phase(time.Now()) != ContestPhaseRunning {
+			respondError(c, http.StatusConflict, "CONTEST_NOT_RUNNING", "アナウンスはコンテスト開催中のみ投稿できます")
+			return
+		}
+		a, err := annRepo.Create(ctx, contest.ID, user.ID, req.Title, req.Body, req.Critical)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to create announcement")
+			return
+		}
+		// 配信に失敗してもポーリングで取得できるので作成自体は成功とする
+		if err := bus.Publish(ctx, ContestEventChannel(contest.ID), eventContestAnnouncement, a.ID, a); err != nil {
+			log.Printf("[announcement] publish %d failed: %v", a.ID, err)
+		}
+		c.JSON(http.StatusCreated, a)
+	})
+
+	admin.GET("/contests/:id/announcements/:aid/acks", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		aid, ok := parseIDParam(c, "aid")
+		if !ok {
+			return
+		}
+		items, err := annRepo.AckStatus(c.Request.Context(), id, aid)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "announcement not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch acknowledgements")
+			return
+		}
+		acked := 0
+		for _, item := range items {
+			if item.AckedAt != nil {
+				acked++
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{"items": items, "acked_count": acked, "participant_count": len(items)})
+	})
+
+	admin.DELETE("/contests/:id/announcements/:aid", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		aid, ok := parseIDParam(c, "aid")
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		if err := annRepo.Delete(ctx, id, aid); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "announcement not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to delete announcement")
+			return
+		}
+		if err := bus.Publish(ctx, ContestEventChannel(id), eventContestAnnouncementDeleted, 0, gin.H{"id": aid}); err != nil {
+			log.Printf("[announcement] publish delete %d failed: %v", aid, err)
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
+
+// loadAnnouncementContest resolves a contest whose announcements the user may read:
+// admins always, others only when registered.
+func loadAnnouncementContest(c *gin.Context, contestRepo ContestRepository, userRepo UserRepository) (*UserRecord, *Contest, bool) {
+	user, contest, ok := loadClarificationContest(c, contestRepo, userRepo)
+	if !ok {
+		return nil, nil, false
+	}
+	if isStaffRole(user.Role) {
+		return user, contest, true
+	}
+	registered, err := contestRepo.IsRegistered(c.Request.Context(), contest.ID, user.ID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to check registration")
+		return nil, nil, false
+	}
+	if !registered {
+		respondError(c, http.StatusForbidden, "FORBIDDEN", "コンテストに参加登録していません")
+		return nil, nil, false
+	}
+	return user, contest, true
+}
+
+func parseSinceID(c *gin.Context, raw string) (int64, bool) {
+	if strings.TrimSpace(raw) == "" {
+		return 0, true
+	}
+	id, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+	if err != nil || id < 0 {
+		respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "since_id は 0 以上の整数で指定してください")
+		return 0, false
+	}
+	return id, true
+}
+
+// writeSSE writes one Server-Sent Events frame.
+func writeSSE(w io.Writer, event string, id int64, data []byte) {
+	if id > 0 {
+		fmt.Fprintf(w, "id: %d\n", id)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
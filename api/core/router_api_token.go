@@ -0,0 +1,102 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// registerAPITokenRoutes wires personal API token management for the logged-in user.
+func registerAPITokenRoutes(api *gin.RouterGroup, tokenRepo APITokenRepository, userRepo UserRepository) {
+	api.GET("/users/me/tokens", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		items, err := tokenRepo.List(c.Request.Context(), user.ID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch api tokens")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"items": items})
+	})
+
+	api.POST("/users/me/tokens", func(c *gin.Context) {
+		// トークンからトークンを発行させない（漏洩したトークンで延命できないように）
+		if isTokenAuth(c) {
+			respondError(c, http.StatusForbidden, "FORBIDDEN", "API トークンの発行にはログインセッションが必要です")
+			return
+		}
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		var req struct {
+			Name          string `json:"name"`
+			ExpiresInDays *int   `json:"expires_in_days"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		name := strings.TrimSpace(req.Name)
+		if name == "" || utf8.RuneCountInString(name) > apiTokenMaxNameLen {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "name must be 1-100 characters")
+			return
+		}
+		var expiresAt *time.Time
+		if req.ExpiresInDays != nil {
+			lifetime := time.Duration(*req.ExpiresInDays) * 24 * time.Hour
+			if *req.ExpiresInDays <= 0 || lifetime > apiTokenMaxLifetime {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "expires_in_days must be between 1 and 365")
+				return
+			}
+			t := time.Now().Add(lifetime)
+			expiresAt = &t
+		}
+		token, raw, err := tokenRepo.Create(c.Request.Context(), user.ID, name, expiresAt)
+		if err != nil {
+			if errors.Is(err, ErrAPITokenLimit) {
+				respondError(c, http.StatusConflict, "CONFLICT", "API トークンは 20 個までです")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to create api token")
+			return
+		}
+		// 平文のトークンはこのレスポンスでのみ返す
+		c.JSON(http.StatusCreated, gin.H{
+			"id":           token.ID,
+			"name":         token.Name,
+			"prefix":       token.Prefix,
+			"expires_at":   token.ExpiresAt,
+			"last_used_at": token.LastUsedAt,
+			"created_at":   token.CreatedAt,
+			"token":        raw,
+		})
+	})
+
+	api.DELETE("/users/me/tokens/:id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		if err := tokenRepo.Delete(c.Request.Context(), user.ID, id); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "api token not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to delete api token")
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,47 @@
+package core
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerAuditLogRoutes wires the audit log of admin operations. Filters: actor, action (prefix,
+// e.g. "PATCH /api/v1/admin/problems"), target_type, target_id and from / to (RFC 3339 or
+// YYYY-MM-DD).
+func registerAuditLogRoutes(admin *gin.RouterGroup, repo AuditLogRepository) {
+	admin.GET("/audit_logs", func(c *gin.Context) {
+		page, perPage, err := parsePagination(c.Query("page"), c.Query("per_page"))
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			return
+		}
+		f := AuditLogFilter{
+			Actor:      strings.TrimSpace(c.Query("actor")),
+			Action:     strings.TrimSpace(c.Query("action")),
+			TargetType: strings.TrimSpace(c.Query("target_type")),
+			TargetID:   strings.TrimSpace(c.Query("target_id")),
+		}
+		if f.From, err = parseFilterTime(c.Query("from"), "from", false); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			return
+		}
+		if f.To, err = parseFilterTime(c.Query("to"), "to", true); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			return
+		}
+		items, total, err := repo.List(c.Request.Context(), f, page, perPage)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch audit logs")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"items":       items,
+			"page":        page,
+			"per_page":    perPage,
+			"total_items": total,
+			"total_pages": calcTotalPages(total, perPage),
+		})
+	})
+}
@@ -0,0 +1,192 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// registerClarificationRoutes wires contest clarification endpoints (participant + admin).
+func registerClarificationRoutes(api, admin *gin.RouterGroup, clarRepo ClarificationRepository, contestRepo ContestRepository, userRepo UserRepository) {
+	// 参加者向け: 自分の質問 + 全体公開された回答
+	api.GET("/contests/:id/clarifications", func(c *gin.Context) {
+		user, contest, ok := loadClarificationContest(c, contestRepo, userRepo)
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		filter := ClarificationFilter{ViewerID: &user.ID}
+		if isStaffRole(user.Role) {
+			filter.ViewerID = nil
+		}
+		items, err := clarRepo.List(ctx, contest.ID, filter)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch clarifications")
+			return
+		}
+		var unread int
+		if isStaffRole(user.Role) {
+			// 運営側にとっての未読 = 未回答の質問
+			for _, item := range items {
+				if item.AnsweredAt == nil {
+					unread++
+				}
+			}
+		} else if unread, err = clarRepo.UnreadCount(ctx, contest.ID, user.ID); err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to count unread clarifications")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"items": items, "unread_count": unread})
+	})
+
+	api.POST("/contests/:id/clarifications", func(c *gin.Context) {
+		user, contest, ok := loadClarificationContest(c, contestRepo, userRepo)
+		if !ok {
+			return
+		}
+		var req struct {
+			ProblemID *int64 `json:"problem_id"`
+			Question  string `json:"question"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		if strings.TrimSpace(req.Question) == "" {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "question は必須です")
+			return
+		}
+		if contest.Phase(time.Now()) != ContestPhaseRunning {
+			respondError(c, http.StatusConflict, "CONTEST_NOT_RUNNING", "質問はコンテスト開催中のみ受け付けます")
+			return
+		}
+		ctx := c.Request.Context()
+		if !isStaffRole(user.Role) {
+			registered, err := contestRepo.IsRegistered(ctx, contest.ID, user.ID)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to check registration")
+				return
+			}
+			if !registered {
+				respondError(c, http.StatusForbidden, "FORBIDDEN", "コンテストに参加登録していません")
+				return
+			}
+		}
+		cl, err := clarRepo.Create(ctx, contest.ID, user.ID, req.ProblemID, req.Question)
+		if err != nil {
+			if strings.Contains(err.Error(), "required") || strings.Contains(err.Error(), "not part of") {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to create clarification")
+			return
+		}
+		c.JSON(http.StatusCreated, cl)
+	})
+
+	// 既読にする（現時点までの回答をすべて既読扱い）
+	api.POST("/contests/:id/clarifications/read", func(c *gin.Context) {
+		user, contest, ok := loadClarificationContest(c, contestRepo, userRepo)
+		if !ok {
+			return
+		}
+		if err := clarRepo.MarkRead(c.Request.Context(), contest.ID, user.ID); err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to mark clarifications read")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"unread_count": 0})
+	})
+
+	// 管理者向け
+	admin.GET("/contests/:id/clarifications", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		filter := ClarificationFilter{UnansweredOnly: c.Query("status") == "unanswered"}
+		items, err := clarRepo.List(c.Request.Context(), id, filter)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch clarifications")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"items": items})
+	})
+
+	admin.PUT("/contests/:id/clarifications/:cid/answer", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		cid, ok := parseIDParam(c, "cid")
+		if !ok {
+			return
+		}
+		var req struct {
+			Answer    string `json:"answer"`
+			Broadcast bool   `json:"broadcast"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		if strings.TrimSpace(req.Answer) == "" {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "answer は必須です")
+			return
+		}
+		cl, err := clarRepo.Answer(c.Request.Context(), id, cid, user.ID, req.Answer, req.Broadcast)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "clarification not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to answer clarification")
+			return
+		}
+		c.JSON(http.StatusOK, cl)
+	})
+
+	admin.DELETE("/contests/:id/clarifications/:cid", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		cid, ok := parseIDParam(c, "cid")
+		if !ok {
+			return
+		}
+		if err := clarRepo.Delete(c.Request.Context(), id, cid); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "clarification not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to delete clarification")
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
+
+// loadClarificationContest resolves the logged-in user and a contest visible to them.
+func loadClarificationContest(c *gin.Context, contestRepo ContestRepository, userRepo UserRepository) (*UserRecord, *Contest, bool) {
+	user, ok := requireUser(c, userRepo)
+	if !ok {
+		return nil, nil, false
+	}
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return nil, nil, false
+	}
+	contest, ok := loadVisibleContest(c, contestRepo, user, id)
+	if !ok {
+		return nil, nil, false
+	}
+	return user, contest, true
+}
@@ -0,0 +1,794 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// contestView is the public representation of a contest with its current phase.
+type contestView struct {
+	Contest
+	Phase string `json:"phase"`
+}
+
+func newContestView(c Contest, now time.Time) contestView {
+	return contestView{Contest: c, Phase: c.Phase(now)}
+}
+
+// registerContestRoutes wires contest endpoints (participant + admin).
+func registerContestRoutes(api, admin *gin.RouterGroup, contestRepo ContestRepository, userRepo UserRepository, problemRepo ProblemRepository, testcaseGen *TestcaseGenerator) {
+	// checkContestProblems responds VALIDATION_ERROR unless every problem_id exists, so that an
+	// unknown ID never reaches the foreign key of contest_problems.
+	checkContestProblems := func(c *gin.Context, problems []ContestProblemInput) bool {
+		for _, p := range problems {
+			exists, err := problemRepo.Exists(c.Request.Context(), p.ProblemID)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "問題の存在確認に失敗しました")
+				return false
+			}
+			if !exists {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "problem_id が不正です")
+				return false
+			}
+		}
+		return true
+	}
+
+	// 参加者向け
+	api.GET("/contests", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		page, perPage, err := parsePagination(c.Query("page"), c.Query("per_page"))
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			return
+		}
+		ctx := c.Request.Context()
+		items, total, err := contestRepo.List(ctx, false, user.ID, page, perPage)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch contests")
+			return
+		}
+		now := time.Now()
+		views := make([]contestView, 0, len(items))
+		for _, item := range items {
+			views = append(views, newContestView(item, now))
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"items":       views,
+			"page":        page,
+			"per_page":    perPage,
+			"total_items": total,
+			"total_pages": calcTotalPages(total, perPage),
+		})
+	})
+
+	api.GET("/contests/:id", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		contest, ok := loadVisibleContest(c, contestRepo, user, id)
+		if !ok {
+			return
+		}
+		registered, err := contestRepo.IsRegistered(ctx, id, user.ID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to check registration")
+			return
+		}
+		count, err := contestRepo.CountRegistrations(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to count registrations")
+			return
+		}
+		view := newContestView(*contest, time.Now())
+		// 開始前は問題一覧を伏せる（管理者は除く）
+		problems := []ContestProblem{}
+		if view.Phase != ContestPhaseUpcoming || isStaffRole(user.Role) {
+			problems, err = contestRepo.ListProblems(ctx, id)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch contest problems")
+				return
+			}
+			// 問題プールがあれば割り当てられた問題だけを見せる
+			if !isStaffRole(user.Role) {
+				problems = assignContestProblems(id, user.ID, problems)
+			}
+			localizeContestProblems(problems, requestLocale(c, user))
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"contest":            view,
+			"problems":           problems,
+			"registered":         registered,
+			"registration_count": count,
+		})
+	})
+
+	api.POST("/contests/:id/registration", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		contest, err := contestRepo.Get(ctx, id)
+		if err != nil || !contest.IsPublic {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", "contest not found")
+			return
+		}
+		if err := contestRepo.Register(ctx, id, user.ID); err != nil {
+			if errors.Is(err, ErrContestNotOpen) {
+				respondError(c, http.StatusConflict, "CONTEST_ENDED", "コンテストは終了しています")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to register")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"contest_id": id, "registered": true})
+	})
+
+	api.DELETE("/contests/:id/registration", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		if err := contestRepo.Unregister(ctx, id, user.ID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "contest not found")
+				return
+			}
+			if errors.Is(err, ErrContestAlreadyStarted) {
+				respondError(c, http.StatusConflict, "CONTEST_STARTED", "開始後は登録を取り消せません")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to unregister")
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	// ICPC 形式の順位表（判定確定ごとに更新される集計セルから組み立てる）
+	api.GET("/contests/:id/standings", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		contest, ok := loadVisibleContest(c, contestRepo, user, id)
+		if !ok {
+			return
+		}
+		view := newContestView(*contest, time.Now())
+		if view.Phase == ContestPhaseUpcoming && !isStaffRole(user.Role) {
+			respondError(c, http.StatusForbidden, "FORBIDDEN", "コンテスト開始前です")
+			return
+		}
+		problems, err := contestRepo.ListProblems(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch contest problems")
+			return
+		}
+		rows, err := contestRepo.Standings(ctx, *contest, problems)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to build standings")
+			return
+		}
+		localizeContestProblems(problems, requestLocale(c, user))
+		c.JSON(http.StatusOK, gin.H{
+			"contest":         view,
+			"problems":        problems,
+			"penalty_minutes": contest.Penalty.Minutes,
+			"rows":            rows,
+		})
+	})
+
+	// 解説（コンテスト終了後に自動公開、または管理者が手動で公開）
+	api.GET("/contests/:id/problems/:problem_id/editorial", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		problemID, ok := parseIDParam(c, "problem_id")
+		if !ok {
+			return
+		}
+		if _, ok := loadVisibleContest(c, contestRepo, user, id); !ok {
+			return
+		}
+		editorial, err := contestRepo.GetEditorial(c.Request.Context(), id, problemID)
+		if err != nil || editorial.EditorialMD == "" {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", "editorial not found")
+			return
+		}
+		if !editorial.Public && !isStaffRole(user.Role) {
+			respondError(c, http.StatusForbidden, "FORBIDDEN", "解説はまだ公開されていません")
+			return
+		}
+		c.JSON(http.StatusOK, editorial)
+	})
+
+	// 管理者向け CRUD
+	admin.GET("/contests", func(c *gin.Context) {
+		page, perPage, err := parsePagination(c.Query("page"), c.Query("per_page"))
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			return
+		}
+		ctx := c.Request.Context()
+		items, total, err := contestRepo.List(ctx, true, 0, page, perPage)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch contests")
+			return
+		}
+		now := time.Now()
+		views := make([]contestView, 0, len(items))
+		for _, item := range items {
+			views = append(views, newContestView(item, now))
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"items":       views,
+			"page":        page,
+			"per_page":    perPage,
+			"total_items": total,
+			"total_pages": calcTotalPages(total, perPage),
+		})
+	})
+
+	admin.POST("/contests", func(c *gin.Context) {
+		var req struct {
+			Slug        string                `json:"slug"`
+			Title       string                `json:"title"`
+			Description string                `json:"description"`
+			StartAt     *time.Time            `json:"start_at"`
+			EndAt       *time.Time            `json:"end_at"`
+			IsPublic    *bool                 `json:"is_public"`
+			ScoringMode string                `json:"scoring_mode"`
+			Problems    []ContestProblemInput `json:"problems"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		if strings.TrimSpace(req.Slug) == "" || strings.TrimSpace(req.Title) == "" || req.StartAt == nil || req.EndAt == nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "slug, title, start_at, end_at は必須です")
+			return
+		}
+		if !req.EndAt.After(*req.StartAt) {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "end_at は start_at より後にしてください")
+			return
+		}
+		// コンテストを作る前に出題問題を確かめる
+		if !checkContestProblems(c, req.Problems) {
+			return
+		}
+		isPublic := true
+		if req.IsPublic != nil {
+			isPublic = *req.IsPublic
+		}
+		ctx := c.Request.Context()
+		contest, err := contestRepo.Create(ctx, ContestCreateInput{
+			Slug:          req.Slug,
+			Title:         req.Title,
+			DescriptionMD: req.Description,
+			StartAt:       *req.StartAt,
+			EndAt:         *req.EndAt,
+			IsPublic:      isPublic,
+			ScoringMode:   req.ScoringMode,
+		})
+		if err != nil {
+			if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "unique") {
+				respondError(c, http.StatusConflict, "CONFLICT", "同じ slug のコンテストが既に存在します")
+				return
+			}
+			if strings.Contains(err.Error(), "required") || strings.Contains(err.Error(), "scoring_mode") {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to create contest")
+			return
+		}
+		if len(req.Problems) > 0 {
+			if err := contestRepo.SetProblems(ctx, contest.ID, req.Problems); err != nil {
+				_ = contestRepo.Delete(ctx, contest.ID)
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+				return
+			}
+		}
+		problems, err := contestRepo.ListProblems(ctx, contest.ID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch contest problems")
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{
+			"contest":  newContestView(*contest, time.Now()),
+			"problems": problems,
+		})
+	})
+
+	admin.GET("/contests/:id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		contest, err := contestRepo.Get(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", "contest not found")
+			return
+		}
+		problems, err := contestRepo.ListProblems(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch contest problems")
+			return
+		}
+		count, err := contestRepo.CountRegistrations(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to count registrations")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"contest":            newContestView(*contest, time.Now()),
+			"problems":           problems,
+			"registration_count": count,
+		})
+	})
+
+	admin.PATCH("/contests/:id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		var req struct {
+			Title       *string    `json:"title"`
+			Description *string    `json:"description"`
+			StartAt     *time.Time `json:"start_at"`
+			EndAt       *time.Time `json:"end_at"`
+			IsPublic    *bool      `json:"is_public"`
+			ScoringMode *string    `json:"scoring_mode"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		ctx := c.Request.Context()
+		contest, err := contestRepo.Update(ctx, id, ContestUpdateInput{
+			Title:         req.Title,
+			DescriptionMD: req.Description,
+			StartAt:       req.StartAt,
+			EndAt:         req.EndAt,
+			IsPublic:      req.IsPublic,
+			ScoringMode:   req.ScoringMode,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "contest not found")
+				return
+			}
+			if strings.Contains(err.Error(), "must") {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to update contest")
+			return
+		}
+		if req.ScoringMode != nil {
+			// 採点モードが変わったら集計セルを作り直す
+			if err := contestRepo.RebuildStandings(ctx, id); err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to rebuild standings")
+				return
+			}
+		}
+		c.JSON(http.StatusOK, newContestView(*contest, time.Now()))
+	})
+
+	admin.DELETE("/contests/:id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		if err := contestRepo.Delete(ctx, id); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "contest not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to delete contest")
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	// 出題問題の差し替え（順序はリクエスト順）
+	admin.PUT("/contests/:id/problems", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		var req struct {
+			Problems []ContestProblemInput `json:"problems"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		ctx := c.Request.Context()
+		if _, err := contestRepo.Get(ctx, id); err != nil {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", "contest not found")
+			return
+		}
+		if !checkContestProblems(c, req.Problems) {
+			return
+		}
+		if err := contestRepo.SetProblems(ctx, id, req.Problems); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			return
+		}
+		problems, err := contestRepo.ListProblems(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch contest problems")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"problems": problems})
+	})
+
+	admin.GET("/contests/:id/registrations", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		page, perPage, err := parsePagination(c.Query("page"), c.Query("per_page"))
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			return
+		}
+		ctx := c.Request.Context()
+		items, total, err := contestRepo.ListRegistrations(ctx, id, page, perPage)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch registrations")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"items":       items,
+			"page":        page,
+			"per_page":    perPage,
+			"total_items": total,
+			"total_pages": calcTotalPages(total, perPage),
+		})
+	})
+
+	// 問題プールの割り当て（参加者ごとにプールから選ばれた問題）
+	admin.GET("/contests/:id/pool_assignments", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		page, perPage, err := parsePagination(c.Query("page"), c.Query("per_page"))
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			return
+		}
+		ctx := c.Request.Context()
+		problems, err := contestRepo.ListProblems(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch contest problems")
+			return
+		}
+		regs, total, err := contestRepo.ListRegistrations(ctx, id, page, perPage)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch registrations")
+			return
+		}
+		type assignment struct {
+			Pool      string `json:"pool"`
+			ProblemID int64  `json:"problem_id"`
+			Label     string `json:"label"`
+		}
+		type row struct {
+			ContestRegistration
+			Assignments []assignment `json:"assignments"`
+		}
+		items := make([]row, 0, len(regs))
+		for _, reg := range regs {
+			r := row{ContestRegistration: reg, Assignments: []assignment{}}
+			for _, p := range assignContestProblems(id, reg.UserID, problems) {
+				if p.Pool != "" {
+					r.Assignments = append(r.Assignments, assignment{Pool: p.Pool, ProblemID: p.ProblemID, Label: p.Label})
+				}
+			}
+			items = append(items, r)
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"items":       items,
+			"page":        page,
+			"per_page":    perPage,
+			"total_items": total,
+			"total_pages": calcTotalPages(total, perPage),
+		})
+	})
+
+	admin.GET("/contests/:id/problems/:problem_id/editorial", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		problemID, ok := parseIDParam(c, "problem_id")
+		if !ok {
+			return
+		}
+		editorial, err := contestRepo.GetEditorial(c.Request.Context(), id, problemID)
+		if err != nil {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", "contest problem not found")
+			return
+		}
+		c.JSON(http.StatusOK, editorial)
+	})
+
+	// 解説は問題に 1 つで、同じ問題を使う他のコンテストや練習問題の解説も変わる
+	admin.PUT("/contests/:id/problems/:problem_id/editorial", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		problemID, ok := parseIDParam(c, "problem_id")
+		if !ok {
+			return
+		}
+		var req struct {
+			Editorial   *string `json:"editorial"`
+			Public      *bool   `json:"public"`
+			AutoRelease *bool   `json:"auto_release"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		editorial, err := contestRepo.SetEditorial(c.Request.Context(), id, problemID, ContestEditorialInput{
+			EditorialMD: req.Editorial,
+			Public:      req.Public,
+			AutoRelease: req.AutoRelease,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "contest problem not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to update editorial")
+			return
+		}
+		c.JSON(http.StatusOK, editorial)
+	})
+
+	// 集計セルを提出から作り直す（リジャッジ後など）
+	admin.POST("/contests/:id/standings/rebuild", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		if err := contestRepo.RebuildStandings(ctx, id); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "contest not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to rebuild standings")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"contest_id": id, "rebuilt": true})
+	})
+
+	// コンテストパッケージ (contest.yaml + problems/*.zip) の入出力
+	admin.GET("/contests/:id/export", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		contest, err := contestRepo.Get(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", "contest not found")
+			return
+		}
+		problems, err := contestRepo.ListProblems(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch contest problems")
+			return
+		}
+		archives := make(map[int64][]byte, len(problems))
+		for _, p := range problems {
+			detail, err := problemRepo.FindDetailAdmin(ctx, p.ProblemID)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to load problem "+p.Slug)
+				return
+			}
+			cases, err := problemRepo.ListTestcases(ctx, p.ProblemID)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to load testcases of "+p.Slug)
+				return
+			}
+			subtasks, err := problemRepo.ListSubtasks(ctx, p.ProblemID)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to load subtasks of "+p.Slug)
+				return
+			}
+			assets, err := problemRepo.ListAssets(ctx, p.ProblemID)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to load assets of "+p.Slug)
+				return
+			}
+			archive, err := buildProblemZipFromDB(*detail, cases, subtasks, assets)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to build archive of "+p.Slug)
+				return
+			}
+			archives[p.ProblemID] = archive
+		}
+		data, err := buildContestArchive(*contest, problems, archives)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to build contest archive")
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.zip", contest.Slug))
+		c.Data(http.StatusOK, "application/zip", data)
+	})
+
+	admin.POST("/contests/import", func(c *gin.Context) {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "file フィールドに zip を指定してください")
+			return
+		}
+		if fileHeader.Size > maxContestImportSize {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "ファイルが大きすぎます")
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "INVALID_CONTEST_PACKAGE", "ファイルを開けません")
+			return
+		}
+		defer file.Close()
+		data, err := io.ReadAll(io.LimitReader(file, maxContestImportSize+1))
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "アップロードの読み取りに失敗しました")
+			return
+		}
+		if int64(len(data)) > maxContestImportSize {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "ファイルが大きすぎます")
+			return
+		}
+		ctx := c.Request.Context()
+		pkg, err := ParseContestArchive(ctx, data, testcaseGen)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "INVALID_CONTEST_PACKAGE", err.Error())
+			return
+		}
+		reuse, _ := strconv.ParseBool(c.PostForm("reuse_existing_problems"))
+		// 審査権限のない作問者が取り込んだ問題は未公開の下書きになる
+		for i := range pkg.Problems {
+			pkg.Problems[i].Problem.ReviewRequired = reviewRequired(c)
+		}
+
+		result, err := contestRepo.ImportPackage(ctx, pkg, reuse)
+		if err != nil {
+			if errors.Is(err, ErrProblemSlugExists) {
+				respondError(c, http.StatusConflict, "CONFLICT", "同じ slug の問題が既に存在します (reuse_existing_problems=true で既存問題を利用できます): "+err.Error())
+				return
+			}
+			if errors.Is(err, ErrProblemSlugTrashed) {
+				respondError(c, http.StatusConflict, "CONFLICT", "同じ slug の問題がゴミ箱にあります。復元するか完全に削除してください: "+err.Error())
+				return
+			}
+			if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "unique") {
+				respondError(c, http.StatusConflict, "CONFLICT", "同じ slug のコンテストが既に存在します")
+				return
+			}
+			if strings.Contains(err.Error(), "required") || strings.Contains(err.Error(), "checker") {
+				respondError(c, http.StatusBadRequest, "INVALID_CONTEST_PACKAGE", err.Error())
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "コンテストの保存に失敗しました")
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{
+			"contest":          newContestView(result.Contest, time.Now()),
+			"created_problems": result.CreatedProblems,
+			"reused_problems":  result.ReusedProblems,
+		})
+	})
+}
+
+// loadVisibleContest loads a contest the user may see: public contests, and private ones for staff
+// and participants (who joined with an access code). Otherwise it responds 404.
+func loadVisibleContest(c *gin.Context, contestRepo ContestRepository, user *UserRecord, id int64) (*Contest, bool) {
+	ctx := c.Request.Context()
+	contest, err := contestRepo.Get(ctx, id)
+	if err == nil && !contest.IsPublic && !isStaffRole(user.Role) {
+		var registered bool
+		if registered, err = contestRepo.IsRegistered(ctx, id, user.ID); err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to check registration")
+			return nil, false
+		}
+		if !registered {
+			err = pgx.ErrNoRows
+		}
+	}
+	if err != nil {
+		respondError(c, http.StatusNotFound, "NOT_FOUND", "contest not found")
+		return nil, false
+	}
+	return contest, true
+}
+
+// checkContestSubmission validates a submission made inside a contest: the contest must be
+// running (or in its late window), contain the problem, and the user must be registered (admins may always submit).
+func checkContestSubmission(c *gin.Context, contestRepo ContestRepository, user *UserRecord, contestID, problemID int64) bool {
+	ctx := c.Request.Context()
+	contest, ok := loadVisibleContest(c, contestRepo, user, contestID)
+	if !ok {
+		return false
+	}
+	now := time.Now()
+	// 遅延提出を受け付ける期間は終了後も提出できる（成績で減点される）
+	if contest.Phase(now) != ContestPhaseRunning && !contest.AcceptsLateSubmissions(now) && !isStaffRole(user.Role) {
+		respondError(c, http.StatusConflict, "CONTEST_NOT_RUNNING", "コンテスト開催中ではありません")
+		return false
+	}
+	problems, err := contestRepo.ListProblems(ctx, contestID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch contest problems")
+		return false
+	}
+	if !containsContestProblem(problems, problemID) {
+		respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "コンテストに含まれない問題です")
+		return false
+	}
+	if !isStaffRole(user.Role) && !containsContestProblem(assignContestProblems(contestID, user.ID, problems), problemID) {
+		respondError(c, http.StatusForbidden, "FORBIDDEN", "この問題は割り当てられていません")
+		return false
+	}
+	if isStaffRole(user.Role) {
+		return true
+	}
+	registered, err := contestRepo.IsRegistered(ctx, contestID, user.ID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to check registration")
+		return false
+	}
+	if !registered {
+		respondError(c, http.StatusForbidden, "FORBIDDEN", "コンテストに参加登録していません")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,182 @@
+package core
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// registerContestAccessCodeRoutes wires joining a contest by access code and the admin management of codes.
+func registerContestAccessCodeRoutes(api, admin *gin.RouterGroup, codeRepo ContestAccessCodeRepository, contestRepo ContestRepository, userRepo UserRepository, limiter *AccessCodeLimiter) {
+	// 参加コードでの参加登録（承認不要）。コンテスト ID を知らなくても参加できる
+	api.POST("/contests/join", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		var req struct {
+			Code string `json:"code"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		ctx := c.Request.Context()
+		ip := c.ClientIP()
+		wait, err := limiter.Blocked(ctx, user.ID, ip)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to check attempts")
+			return
+		}
+		if wait > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(wait.Round(time.Second)/time.Second)))
+			respondError(c, http.StatusTooManyRequests, "TOO_MANY_ATTEMPTS", "参加コードの入力に続けて失敗したため、しばらく時間をおいてください")
+			return
+		}
+		contest, err := codeRepo.Redeem(ctx, req.Code, user.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrAccessCodeInvalid):
+				if err := limiter.Fail(ctx, user.ID, ip); err != nil {
+					log.Printf("[access_code] record failure for user %d: %v", user.ID, err)
+				}
+				respondError(c, http.StatusBadRequest, "INVALID_ACCESS_CODE", "参加コードが正しくないか、有効期限が切れています")
+			case errors.Is(err, ErrContestNotOpen):
+				respondError(c, http.StatusConflict, "CONTEST_ENDED", "コンテストは終了しています")
+			default:
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to register")
+			}
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"contest":    newContestView(*contest, time.Now()),
+			"registered": true,
+		})
+	})
+
+	// 管理者向け
+	admin.GET("/contests/:id/access_codes", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		items, err := codeRepo.List(c.Request.Context(), id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch access codes")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"items": items})
+	})
+
+	admin.POST("/contests/:id/access_codes", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		var req struct {
+			Code      string     `json:"code"`
+			MaxUses   *int       `json:"max_uses"`
+			ExpiresAt *time.Time `json:"expires_at"`
+		}
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+				return
+			}
+		}
+		ctx := c.Request.Context()
+		if _, err := contestRepo.Get(ctx, id); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "contest not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch contest")
+			return
+		}
+		code, err := codeRepo.Create(ctx, ContestAccessCodeInput{
+			ContestID: id,
+			Code:      req.Code,
+			MaxUses:   req.MaxUses,
+			ExpiresAt: req.ExpiresAt,
+			CreatedBy: user.ID,
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrAccessCodeInput):
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			case errors.Is(err, ErrAccessCodeExists):
+				respondError(c, http.StatusConflict, "CONFLICT", "同じ参加コードが既に存在します")
+			default:
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to create access code")
+			}
+			return
+		}
+		c.JSON(http.StatusCreated, code)
+	})
+
+	admin.PATCH("/contests/:id/access_codes/:code_id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		codeID, ok := parseIDParam(c, "code_id")
+		if !ok {
+			return
+		}
+		var req struct {
+			MaxUses   *int       `json:"max_uses"`
+			ExpiresAt *time.Time `json:"expires_at"`
+			IsActive  *bool      `json:"is_active"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		code, err := codeRepo.Update(c.Request.Context(), id, codeID, ContestAccessCodeUpdate{
+			MaxUses:   req.MaxUses,
+			ExpiresAt: req.ExpiresAt,
+			IsActive:  req.IsActive,
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrAccessCodeInput):
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			case errors.Is(err, pgx.ErrNoRows):
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "access code not found")
+			default:
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to update access code")
+			}
+			return
+		}
+		c.JSON(http.StatusOK, code)
+	})
+
+	admin.DELETE("/contests/:id/access_codes/:code_id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		codeID, ok := parseIDParam(c, "code_id")
+		if !ok {
+			return
+		}
+		if err := codeRepo.Delete(c.Request.Context(), id, codeID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "access code not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to delete access code")
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// registerContestGradeRoutes wires late policies and grades of contests used as assignments.
+func registerContestGradeRoutes(api, admin *gin.RouterGroup, contestRepo ContestRepository, userRepo UserRepository) {
+	// 自分の成績（遅延提出の減点を反映）
+	api.GET("/contests/:id/grades/me", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		contest, ok := loadVisibleContest(c, contestRepo, user, id)
+		if !ok {
+			return
+		}
+		problems, err := contestRepo.ListProblems(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch contest problems")
+			return
+		}
+		rows, err := contestRepo.Grades(ctx, *contest, problems)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to compute grades")
+			return
+		}
+		for _, row := range rows {
+			if row.UserID == user.ID {
+				c.JSON(http.StatusOK, gin.H{
+					"contest":     newContestView(*contest, time.Now()),
+					"late_policy": contest.latePolicy(),
+					"grade":       row,
+				})
+				return
+			}
+		}
+		respondError(c, http.StatusNotFound, "NOT_FOUND", "このコンテストに参加登録していません")
+	})
+
+	// cutoff_at を null にすると遅延提出を受け付けない
+	admin.PUT("/contests/:id/late_policy", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		var req LatePolicy
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		contest, err := contestRepo.SetLatePolicy(c.Request.Context(), id, req)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrLatePolicyInput):
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			case errors.Is(err, pgx.ErrNoRows):
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "contest not found")
+			default:
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to update late policy")
+			}
+			return
+		}
+		c.JSON(http.StatusOK, newContestView(*contest, time.Now()))
+	})
+
+	// 成績表の CSV（参加者 × 問題の減点後の得点）
+	admin.GET("/contests/:id/gradebook", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		contest, err := contestRepo.Get(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", "contest not found")
+			return
+		}
+		problems, err := contestRepo.ListProblems(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch contest problems")
+			return
+		}
+		rows, err := contestRepo.Grades(ctx, *contest, problems)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to compute grades")
+			return
+		}
+		buf := &bytes.Buffer{}
+		if err := writeContestGradebookCSV(buf, problems, rows); err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to build csv")
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=gradebook-%s.csv", contest.Slug))
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+	})
+}
@@ -0,0 +1,39 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// registerContestPenaltyRoutes wires the per-contest penalty rules of the ICPC standings.
+func registerContestPenaltyRoutes(admin *gin.RouterGroup, contestRepo ContestRepository) {
+	// 変更すると順位表を作り直す（tie_break 省略時は last_solve）
+	admin.PUT("/contests/:id/penalty_policy", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		req := defaultPenaltyPolicy()
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		contest, err := contestRepo.SetPenaltyPolicy(c.Request.Context(), id, req)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrPenaltyPolicyInput):
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			case errors.Is(err, pgx.ErrNoRows):
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "contest not found")
+			default:
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to update penalty policy")
+			}
+			return
+		}
+		c.JSON(http.StatusOK, newContestView(*contest, time.Now()))
+	})
+}
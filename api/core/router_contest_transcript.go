@@ -0,0 +1,90 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerContestTranscriptRoutes wires signed result transcripts and their public verification.
+func registerContestTranscriptRoutes(api *gin.RouterGroup, cfg Config, contestRepo ContestRepository, userRepo UserRepository) {
+	key := []byte(cfg.TranscriptSigningKey)
+
+	// 終了したコンテストの成績証明（署名付き JSON）。スタッフは ?user_id= で他の参加者の分も発行できる
+	api.GET("/contests/:id/transcript", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		if len(key) == 0 {
+			respondError(c, http.StatusServiceUnavailable, "SIGNING_DISABLED", "TRANSCRIPT_SIGNING_KEY が設定されていません")
+			return
+		}
+		targetID := user.ID
+		if v := c.Query("user_id"); v != "" {
+			if !isStaffRole(user.Role) {
+				respondError(c, http.StatusForbidden, "FORBIDDEN", "他のユーザーの成績証明は発行できません")
+				return
+			}
+			parsed, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || parsed <= 0 {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid user_id")
+				return
+			}
+			targetID = parsed
+		}
+		ctx := c.Request.Context()
+		contest, ok := loadVisibleContest(c, contestRepo, user, id)
+		if !ok {
+			return
+		}
+		now := time.Now()
+		if contest.Phase(now) != ContestPhaseEnded {
+			respondError(c, http.StatusConflict, "CONTEST_NOT_ENDED", "成績証明はコンテスト終了後に発行できます")
+			return
+		}
+		problems, err := contestRepo.ListProblems(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch contest problems")
+			return
+		}
+		rows, err := contestRepo.Standings(ctx, *contest, problems)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to build standings")
+			return
+		}
+		transcript, ok := buildContestTranscript(*contest, problems, rows, targetID, now)
+		if !ok {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", "このコンテストの参加記録がありません")
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="transcript-%s-%s.json"`, contest.Slug, transcript.Username))
+		c.JSON(http.StatusOK, SignedTranscript{Transcript: *transcript, Signature: SignTranscript(key, *transcript)})
+	})
+
+	// ログイン不要の検証。ダウンロードした JSON をそのまま送る
+	api.POST("/transcripts/verify", func(c *gin.Context) {
+		var req SignedTranscript
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		if len(key) == 0 {
+			respondError(c, http.StatusServiceUnavailable, "SIGNING_DISABLED", "TRANSCRIPT_SIGNING_KEY が設定されていません")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"valid":      VerifyTranscript(key, req),
+			"contest_id": req.Transcript.ContestID,
+			"userid":     req.Transcript.Username,
+			"issued_at":  req.Transcript.IssuedAt,
+		})
+	})
+}
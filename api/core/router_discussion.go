@@ -0,0 +1,304 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// registerDiscussionRoutes wires per-problem discussion threads and their moderation.
+func registerDiscussionRoutes(api, admin *gin.RouterGroup, discRepo DiscussionRepository, problemRepo ProblemRepository, contestRepo ContestRepository, userRepo UserRepository) {
+	api.GET("/problems/:id/discussions", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		page, perPage, err := parsePagination(c.Query("page"), c.Query("per_page"))
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			return
+		}
+		user, revealed, ok := loadDiscussionProblem(c, discRepo, problemRepo, contestRepo, userRepo, id)
+		if !ok {
+			return
+		}
+		moderator := HasPermission(user.Role, PermDiscussionsModerate)
+		items, total, err := discRepo.ListThreads(c.Request.Context(), id, moderator, page, perPage)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch threads")
+			return
+		}
+		for i := range items {
+			items[i].maskFor(user.ID, revealed)
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"items":       items,
+			"solved":      revealed,
+			"page":        page,
+			"per_page":    perPage,
+			"total_items": total,
+			"total_pages": calcTotalPages(total, perPage),
+		})
+	})
+
+	api.POST("/problems/:id/discussions", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		var req struct {
+			Title   string `json:"title"`
+			Body    string `json:"body"`
+			Spoiler bool   `json:"spoiler"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		title := strings.TrimSpace(req.Title)
+		if title == "" || len([]rune(title)) > maxThreadTitleLength {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "title は 1〜200 文字で指定してください")
+			return
+		}
+		if !validDiscussionBody(c, req.Body) {
+			return
+		}
+		user, _, ok := loadDiscussionProblem(c, discRepo, problemRepo, contestRepo, userRepo, id)
+		if !ok {
+			return
+		}
+		thread, err := discRepo.CreateThread(c.Request.Context(), id, user.ID, title, req.Body, req.Spoiler)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to create thread")
+			return
+		}
+		c.JSON(http.StatusCreated, thread)
+	})
+
+	api.GET("/discussions/:id", func(c *gin.Context) {
+		thread, user, revealed, ok := loadDiscussionThread(c, discRepo, problemRepo, contestRepo, userRepo)
+		if !ok {
+			return
+		}
+		posts, err := discRepo.ListPosts(c.Request.Context(), thread.ID, HasPermission(user.Role, PermDiscussionsModerate))
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch posts")
+			return
+		}
+		thread.maskFor(user.ID, revealed)
+		for i := range posts {
+			posts[i].maskFor(user.ID, revealed)
+		}
+		c.JSON(http.StatusOK, gin.H{"thread": thread, "posts": posts, "solved": revealed})
+	})
+
+	api.POST("/discussions/:id/posts", func(c *gin.Context) {
+		var req struct {
+			Body    string `json:"body"`
+			Spoiler bool   `json:"spoiler"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		if !validDiscussionBody(c, req.Body) {
+			return
+		}
+		thread, user, _, ok := loadDiscussionThread(c, discRepo, problemRepo, contestRepo, userRepo)
+		if !ok {
+			return
+		}
+		post, err := discRepo.CreatePost(c.Request.Context(), thread.ID, user.ID, req.Body, req.Spoiler)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrThreadLocked):
+				respondError(c, http.StatusConflict, "THREAD_LOCKED", "このスレッドはロックされています")
+			case errors.Is(err, pgx.ErrNoRows):
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "thread not found")
+			default:
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to create post")
+			}
+			return
+		}
+		c.JSON(http.StatusCreated, post)
+	})
+
+	// モデレーション
+	admin = admin.Group("", RequirePermission(PermDiscussionsModerate))
+
+	admin.PATCH("/discussions/:id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		var req struct {
+			Pinned *bool `json:"pinned"`
+			Locked *bool `json:"locked"`
+			Hidden *bool `json:"hidden"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		thread, err := discRepo.ModerateThread(c.Request.Context(), id, user.ID, ThreadModeration{
+			Pinned: req.Pinned,
+			Locked: req.Locked,
+			Hidden: req.Hidden,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "thread not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to update thread")
+			return
+		}
+		c.JSON(http.StatusOK, thread)
+	})
+
+	admin.DELETE("/discussions/:id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		if err := discRepo.DeleteThread(c.Request.Context(), id); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "thread not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to delete thread")
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	admin.PATCH("/discussion_posts/:id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		var req struct {
+			Hidden *bool `json:"hidden"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		if req.Hidden == nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "hidden は必須です")
+			return
+		}
+		post, err := discRepo.SetPostHidden(c.Request.Context(), id, user.ID, *req.Hidden)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "post not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to update post")
+			return
+		}
+		c.JSON(http.StatusOK, post)
+	})
+
+	admin.DELETE("/discussion_posts/:id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		if err := discRepo.DeletePost(c.Request.Context(), id); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "post not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to delete post")
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
+
+// loadDiscussionProblem resolves the viewer and checks that the problem's discussion is open to them.
+// Discussion is closed to participants while the owning contest is running. revealed reports whether
+// spoilers are shown (the viewer solved the problem or is a moderator).
+func loadDiscussionProblem(c *gin.Context, discRepo DiscussionRepository, problemRepo ProblemRepository, contestRepo ContestRepository, userRepo UserRepository, problemID int64) (*UserRecord, bool, bool) {
+	user, ok := requireUser(c, userRepo)
+	if !ok {
+		return nil, false, false
+	}
+	ctx := c.Request.Context()
+	v, access, err := resolveProblemVisibility(ctx, problemRepo, contestRepo, user, problemID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "NOT_FOUND", "problem not found")
+		return nil, false, false
+	}
+	if !access.Visible {
+		respondError(c, http.StatusNotFound, "NOT_FOUND", access.Reason)
+		return nil, false, false
+	}
+	moderator := HasPermission(user.Role, PermDiscussionsModerate)
+	if !moderator && v.ContestPhase(time.Now()) == ContestPhaseRunning {
+		respondError(c, http.StatusForbidden, "FORBIDDEN", "コンテスト開催中はディスカッションを利用できません")
+		return nil, false, false
+	}
+	if moderator {
+		return user, true, true
+	}
+	solved, err := discRepo.HasSolved(ctx, user.ID, problemID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to check solved state")
+		return nil, false, false
+	}
+	return user, solved, true
+}
+
+// loadDiscussionThread loads the :id thread and applies loadDiscussionProblem to its problem.
+// Hidden threads are visible to moderators only.
+func loadDiscussionThread(c *gin.Context, discRepo DiscussionRepository, problemRepo ProblemRepository, contestRepo ContestRepository, userRepo UserRepository) (*ProblemThread, *UserRecord, bool, bool) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return nil, nil, false, false
+	}
+	thread, err := discRepo.GetThread(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", "thread not found")
+			return nil, nil, false, false
+		}
+		respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch thread")
+		return nil, nil, false, false
+	}
+	user, revealed, ok := loadDiscussionProblem(c, discRepo, problemRepo, contestRepo, userRepo, thread.ProblemID)
+	if !ok {
+		return nil, nil, false, false
+	}
+	if thread.Hidden && !HasPermission(user.Role, PermDiscussionsModerate) {
+		respondError(c, http.StatusNotFound, "NOT_FOUND", "thread not found")
+		return nil, nil, false, false
+	}
+	return thread, user, revealed, true
+}
+
+func validDiscussionBody(c *gin.Context, body string) bool {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "body は必須です")
+		return false
+	}
+	if len([]rune(body)) > maxDiscussionBody {
+		respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "body が長すぎます")
+		return false
+	}
+	return true
+}
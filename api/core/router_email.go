@@ -0,0 +1,164 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// registerEmailRoutes wires the user's own email address and its verification.
+func registerEmailRoutes(api *gin.RouterGroup, cfg Config, mailer Mailer, emailRepo UserEmailRepository, userRepo UserRepository) {
+	enabled := mailer != nil && emailVerificationEnabled(cfg)
+
+	sendVerification := func(ctx context.Context, user *UserRecord, email string) (bool, error) {
+		token := randomHex(32)
+		expiresAt := time.Now().Add(emailVerificationTTL)
+		created, err := emailRepo.CreateToken(ctx, user.ID, email, hashResetToken(token), expiresAt)
+		if err != nil || !created {
+			return created, err
+		}
+		subject, body := emailVerificationMail(cfg.OAuthPublicURL, *user, token, expiresAt)
+		return true, mailer.Send(ctx, email, subject, body)
+	}
+
+	api.GET("/users/me/email", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		e, err := emailRepo.Get(c.Request.Context(), user.ID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch email")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"email":                 e.Email,
+			"verified":              e.Email != nil && e.VerifiedAt != nil,
+			"verified_at":           e.VerifiedAt,
+			"verification_required": cfg.EmailVerificationRequired && !isStaffRole(user.Role),
+		})
+	})
+
+	// 再設定リンクの送り先になるので、変更には現在のパスワードを求める。空文字で解除
+	api.PUT("/users/me/email", func(c *gin.Context) {
+		if isTokenAuth(c) {
+			respondError(c, http.StatusForbidden, "FORBIDDEN", "API トークンではメールアドレスを変更できません")
+			return
+		}
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		var req struct {
+			Email           string `json:"email"`
+			CurrentPassword string `json:"current_password"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)) != nil {
+			respondError(c, http.StatusUnauthorized, "INVALID_CREDENTIALS", "現在のパスワードが違います")
+			return
+		}
+		var email *string
+		if strings.TrimSpace(req.Email) != "" {
+			normalized, err := normalizeEmail(req.Email)
+			if err != nil {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+				return
+			}
+			email = &normalized
+		}
+		e, err := emailRepo.Set(c.Request.Context(), user.ID, email)
+		if err != nil {
+			if errors.Is(err, ErrEmailTaken) {
+				respondError(c, http.StatusConflict, "CONFLICT", "このメールアドレスは他のユーザーが使用しています")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to update email")
+			return
+		}
+		// 未確認の新しいアドレスには確認メールを送る
+		if enabled && e.Email != nil && e.VerifiedAt == nil {
+			addr := *e.Email
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), passwordResetSendTimeout)
+				defer cancel()
+				if _, err := sendVerification(ctx, user, addr); err != nil {
+					log.Printf("[email_verification] send failed for user %d: %v", user.ID, err)
+				}
+			}()
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"email":       e.Email,
+			"verified":    e.Email != nil && e.VerifiedAt != nil,
+			"verified_at": e.VerifiedAt,
+		})
+	})
+
+	// 確認メールの再送
+	api.POST("/users/me/email/verification", func(c *gin.Context) {
+		if !enabled {
+			respondError(c, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "email verification is not configured")
+			return
+		}
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		e, err := emailRepo.Get(ctx, user.ID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch email")
+			return
+		}
+		if e.Email == nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "メールアドレスが登録されていません")
+			return
+		}
+		if e.VerifiedAt != nil {
+			respondError(c, http.StatusConflict, "CONFLICT", "メールアドレスは確認済みです")
+			return
+		}
+		sent, err := sendVerification(ctx, user, *e.Email)
+		if err != nil {
+			log.Printf("[email_verification] send failed for user %d: %v", user.ID, err)
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to send verification mail")
+			return
+		}
+		if !sent {
+			c.Header("Retry-After", strconv.Itoa(int(emailVerifyMailInterval/time.Second)))
+			respondError(c, http.StatusTooManyRequests, "TOO_MANY_REQUESTS", "確認メールは少し時間をおいてから再送してください")
+			return
+		}
+		c.Status(http.StatusAccepted)
+	})
+
+	// メール内のリンクから開かれるため、ログインしていなくても確認できる
+	api.POST("/auth/verify_email", func(c *gin.Context) {
+		var req struct {
+			Token string `json:"token"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		if _, err := emailRepo.Verify(c.Request.Context(), hashResetToken(strings.TrimSpace(req.Token))); err != nil {
+			if errors.Is(err, ErrEmailVerificationToken) {
+				respondError(c, http.StatusBadRequest, "INVALID_TOKEN", "確認リンクが無効か期限切れです")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to verify email")
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,161 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// registerGraderWebhookRoutes wires grader webhook targets and their delivery log.
+func registerGraderWebhookRoutes(admin *gin.RouterGroup, webhookRepo GraderWebhookRepository, userRepo UserRepository) {
+	admin.GET("/grader_webhooks", func(c *gin.Context) {
+		var filter GraderWebhookFilter
+		switch filter.Scope = c.Query("scope"); filter.Scope {
+		case "", GraderWebhookScopeGlobal, GraderWebhookScopeContest, GraderWebhookScopeProblem:
+		default:
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "scope must be global, contest or problem")
+			return
+		}
+		for key, dst := range map[string]**int64{"contest_id": &filter.ContestID, "problem_id": &filter.ProblemID} {
+			if v := c.Query(key); v != "" {
+				id, err := strconv.ParseInt(v, 10, 64)
+				if err != nil || id <= 0 {
+					respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid "+key)
+					return
+				}
+				*dst = &id
+			}
+		}
+		items, err := webhookRepo.List(c.Request.Context(), filter)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch grader webhooks")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"items": items})
+	})
+
+	admin.POST("/grader_webhooks", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		var req struct {
+			ContestID *int64 `json:"contest_id"`
+			ProblemID *int64 `json:"problem_id"`
+			URL       string `json:"url"`
+			Secret    string `json:"secret"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		// contest_id も problem_id も無ければ全提出が対象（グローバル）。secret はこのレスポンスでのみ返す
+		w, err := webhookRepo.Create(c.Request.Context(), GraderWebhookInput{
+			ContestID: req.ContestID,
+			ProblemID: req.ProblemID,
+			URL:       req.URL,
+			Secret:    req.Secret,
+			CreatedBy: user.ID,
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrGraderWebhookTarget), errors.Is(err, ErrGraderWebhookURL):
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			case errors.Is(err, pgx.ErrNoRows):
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "contest or problem not found")
+			default:
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to create grader webhook")
+			}
+			return
+		}
+		c.JSON(http.StatusCreated, w)
+	})
+
+	admin.PATCH("/grader_webhooks/:id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		var req struct {
+			URL      *string `json:"url"`
+			IsActive *bool   `json:"is_active"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		w, err := webhookRepo.Update(c.Request.Context(), id, req.URL, req.IsActive)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrGraderWebhookURL):
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			case errors.Is(err, pgx.ErrNoRows):
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "grader webhook not found")
+			default:
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to update grader webhook")
+			}
+			return
+		}
+		c.JSON(http.StatusOK, w)
+	})
+
+	admin.DELETE("/grader_webhooks/:id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		if err := webhookRepo.Delete(c.Request.Context(), id); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "grader webhook not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to delete grader webhook")
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	admin.GET("/grader_webhooks/:id/deliveries", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		page, perPage, err := parsePagination(c.Query("page"), c.Query("per_page"))
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			return
+		}
+		items, total, err := webhookRepo.ListDeliveries(c.Request.Context(), id, page, perPage)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch deliveries")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"items":       items,
+			"page":        page,
+			"per_page":    perPage,
+			"total_items": total,
+			"total_pages": calcTotalPages(total, perPage),
+		})
+	})
+
+	admin.POST("/grader_webhook_deliveries/:id/retry", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		d, err := webhookRepo.RetryDelivery(c.Request.Context(), id)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "delivery not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to retry delivery")
+			return
+		}
+		c.JSON(http.StatusAccepted, d)
+	})
+}
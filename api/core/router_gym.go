@@ -0,0 +1,220 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// registerGymRoutes wires practice mirrors of finished contests (participant + admin).
+func registerGymRoutes(api, admin *gin.RouterGroup, gymRepo GymRepository, userRepo UserRepository) {
+	api.GET("/gyms", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		page, perPage, err := parsePagination(c.Query("page"), c.Query("per_page"))
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			return
+		}
+		items, total, err := gymRepo.List(c.Request.Context(), isStaffRole(user.Role), page, perPage)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch gyms")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"items":       items,
+			"page":        page,
+			"per_page":    perPage,
+			"total_items": total,
+			"total_pages": calcTotalPages(total, perPage),
+		})
+	})
+
+	api.GET("/gyms/:id", func(c *gin.Context) {
+		user, gym, ok := loadVisibleGym(c, gymRepo, userRepo)
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		problems, err := gymRepo.ListProblems(ctx, gym.ID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch gym problems")
+			return
+		}
+		sessions, err := gymRepo.ListUserSessions(ctx, gym.ID, user.ID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch gym sessions")
+			return
+		}
+		localizeContestProblems(problems, requestLocale(c, user))
+		c.JSON(http.StatusOK, gin.H{
+			"gym":      gym,
+			"problems": problems,
+			"sessions": sessions,
+		})
+	})
+
+	// 参加登録は不要。開始した時点から本番と同じ制限時間で計測する
+	api.POST("/gyms/:id/sessions", func(c *gin.Context) {
+		user, gym, ok := loadVisibleGym(c, gymRepo, userRepo)
+		if !ok {
+			return
+		}
+		session, err := gymRepo.StartSession(c.Request.Context(), gym.ID, user.ID)
+		if err != nil {
+			if errors.Is(err, ErrGymSessionRunning) {
+				respondError(c, http.StatusConflict, "GYM_SESSION_RUNNING", "進行中のセッションがあります")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to start session")
+			return
+		}
+		c.JSON(http.StatusCreated, session)
+	})
+
+	api.GET("/gyms/:id/standings", func(c *gin.Context) {
+		user, gym, ok := loadVisibleGym(c, gymRepo, userRepo)
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		problems, err := gymRepo.ListProblems(ctx, gym.ID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch gym problems")
+			return
+		}
+		rows, err := gymRepo.Standings(ctx, *gym, problems)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to build standings")
+			return
+		}
+		localizeContestProblems(problems, requestLocale(c, user))
+		c.JSON(http.StatusOK, gin.H{
+			"gym":             gym,
+			"problems":        problems,
+			"penalty_minutes": icpcPenaltyMinutes,
+			"rows":            rows,
+		})
+	})
+
+	// 管理者向け
+	admin.POST("/contests/:id/gym", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		var req struct {
+			Title    *string `json:"title"`
+			IsPublic *bool   `json:"is_public"`
+		}
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+				return
+			}
+		}
+		gym, err := gymRepo.CreateFromContest(c.Request.Context(), id, user.ID, GymCreateInput{Title: req.Title, IsPublic: req.IsPublic})
+		if err != nil {
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "contest not found")
+			case errors.Is(err, ErrContestNotEnded):
+				respondError(c, http.StatusConflict, "CONTEST_NOT_ENDED", "終了したコンテストのみ練習用に複製できます")
+			default:
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			}
+			return
+		}
+		c.JSON(http.StatusCreated, gym)
+	})
+
+	admin.DELETE("/gyms/:id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		if err := gymRepo.Delete(c.Request.Context(), id); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "gym not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to delete gym")
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
+
+// loadVisibleGym loads the :id gym; hidden gyms are only visible to staff.
+func loadVisibleGym(c *gin.Context, gymRepo GymRepository, userRepo UserRepository) (*UserRecord, *Gym, bool) {
+	user, ok := requireUser(c, userRepo)
+	if !ok {
+		return nil, nil, false
+	}
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return nil, nil, false
+	}
+	gym, err := gymRepo.Get(c.Request.Context(), id)
+	if err != nil || (!gym.IsPublic && !isStaffRole(user.Role)) {
+		respondError(c, http.StatusNotFound, "NOT_FOUND", "gym not found")
+		return nil, nil, false
+	}
+	return user, gym, true
+}
+
+// checkGymSubmission validates a submission made in a gym session: the session must belong to the
+// user and still be running, and the problem must be part of the gym and open outside contests.
+func checkGymSubmission(c *gin.Context, gymRepo GymRepository, problemRepo ProblemRepository, user *UserRecord, sessionID, problemID int64) bool {
+	ctx := c.Request.Context()
+	session, err := gymRepo.GetSession(ctx, sessionID)
+	if err != nil || session.UserID != user.ID {
+		respondError(c, http.StatusNotFound, "NOT_FOUND", "gym session not found")
+		return false
+	}
+	if !session.Running {
+		respondError(c, http.StatusConflict, "GYM_SESSION_ENDED", "練習セッションは終了しています")
+		return false
+	}
+	problems, err := gymRepo.ListProblems(ctx, session.GymID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch gym problems")
+		return false
+	}
+	found := false
+	for _, p := range problems {
+		if p.ProblemID == problemID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "練習セットに含まれない問題です")
+		return false
+	}
+	v, err := problemRepo.Visibility(ctx, problemID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "NOT_FOUND", "問題が見つかりません")
+		return false
+	}
+	// 練習セッションはコンテスト参加扱いにしないため、未登録として公開範囲を判定する
+	access := v.AccessFor(isStaffRole(user.Role), false, time.Now())
+	if !access.Submittable || access.ContestID != nil {
+		reason := access.Reason
+		if reason == "" || v.ContestPhase(time.Now()) == ContestPhaseRunning {
+			reason = "コンテストで使用中の問題です"
+		}
+		respondError(c, http.StatusForbidden, "FORBIDDEN", reason)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// registerIncidentRoutes wires admin endpoints for incidents recorded by the anomaly monitor.
+func registerIncidentRoutes(admin *gin.RouterGroup, incidentRepo IncidentRepository) {
+	// status=open|resolved（省略時はすべて）、kind=worker_panic などで種類を絞り込む
+	admin.GET("/incidents", func(c *gin.Context) {
+		status := c.Query("status")
+		if status != "" && status != "open" && status != "resolved" {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "status は open または resolved を指定してください")
+			return
+		}
+		page, perPage, err := parsePagination(c.Query("page"), c.Query("per_page"))
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			return
+		}
+		items, total, err := incidentRepo.List(c.Request.Context(), status, c.Query("kind"), page, perPage)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch incidents")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"items":       items,
+			"page":        page,
+			"per_page":    perPage,
+			"total_items": total,
+			"total_pages": calcTotalPages(total, perPage),
+		})
+	})
+
+	admin.PUT("/incidents/:id/resolve", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		inc, err := incidentRepo.Resolve(c.Request.Context(), id)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "incident not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to resolve incident")
+			return
+		}
+		c.JSON(http.StatusOK, inc)
+	})
+}
@@ -0,0 +1,181 @@
+package core
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// registerInvitationRoutes wires self-registration with an invitation code and the admin management of codes.
+func registerInvitationRoutes(api, admin *gin.RouterGroup, cfg Config, store *RedisSessionStore, inviteRepo InvitationCodeRepository, userRepo UserRepository, limiter *AccessCodeLimiter) {
+	// 招待コードでのアカウント作成。作成したアカウントでそのままログインする
+	api.POST("/auth/register", func(c *gin.Context) {
+		if cfg.PasswordLoginDisabled {
+			respondError(c, http.StatusForbidden, "PASSWORD_LOGIN_DISABLED", "パスワードによるログインは無効です")
+			return
+		}
+		var req struct {
+			Code     string `json:"code"`
+			UserID   string `json:"userid"`
+			Password string `json:"password"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		username := strings.TrimSpace(req.UserID)
+		if err := validateRegistration(username, req.Password); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			return
+		}
+		ctx := c.Request.Context()
+		ip := c.ClientIP()
+		wait, err := limiter.Blocked(ctx, 0, ip)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to check attempts")
+			return
+		}
+		if wait > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(wait.Round(time.Second)/time.Second)))
+			respondError(c, http.StatusTooManyRequests, "TOO_MANY_ATTEMPTS", "招待コードの入力に続けて失敗したため、しばらく時間をおいてください")
+			return
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to hash password")
+			return
+		}
+		user, err := inviteRepo.Register(ctx, req.Code, username, string(hash))
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrInvitationCodeInvalid):
+				if err := limiter.Fail(ctx, 0, ip); err != nil {
+					log.Printf("[invitation] record failure for %s: %v", ip, err)
+				}
+				respondError(c, http.StatusBadRequest, "INVALID_INVITATION_CODE", "招待コードが正しくないか、有効期限が切れています")
+			case errors.Is(err, ErrUsernameTaken):
+				respondError(c, http.StatusConflict, "CONFLICT", "userid already exists")
+			default:
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to register")
+			}
+			return
+		}
+		if err := startUserSession(c, cfg, store, user.Username, user.Role); err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to set session")
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{
+			"id":         user.ID,
+			"userid":     user.Username,
+			"role":       user.Role,
+			"created_at": user.CreatedAt,
+		})
+	})
+
+	// 管理者向け
+	admin.GET("/invitation_codes", func(c *gin.Context) {
+		items, err := inviteRepo.List(c.Request.Context())
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch invitation codes")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"items": items})
+	})
+
+	admin.POST("/invitation_codes", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		var req struct {
+			Code      string     `json:"code"`
+			Note      string     `json:"note"`
+			MaxUses   *int       `json:"max_uses"`
+			ExpiresAt *time.Time `json:"expires_at"`
+		}
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+				return
+			}
+		}
+		code, err := inviteRepo.Create(c.Request.Context(), InvitationCodeInput{
+			Code:      req.Code,
+			Note:      strings.TrimSpace(req.Note),
+			MaxUses:   req.MaxUses,
+			ExpiresAt: req.ExpiresAt,
+			CreatedBy: user.ID,
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrInvitationCodeInput):
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			case errors.Is(err, ErrInvitationCodeExists):
+				respondError(c, http.StatusConflict, "CONFLICT", "同じ招待コードが既に存在します")
+			default:
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to create invitation code")
+			}
+			return
+		}
+		c.JSON(http.StatusCreated, code)
+	})
+
+	admin.PATCH("/invitation_codes/:id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		var req struct {
+			Note      *string    `json:"note"`
+			MaxUses   *int       `json:"max_uses"`
+			ExpiresAt *time.Time `json:"expires_at"`
+			IsActive  *bool      `json:"is_active"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		code, err := inviteRepo.Update(c.Request.Context(), id, InvitationCodeUpdate{
+			Note:      req.Note,
+			MaxUses:   req.MaxUses,
+			ExpiresAt: req.ExpiresAt,
+			IsActive:  req.IsActive,
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrInvitationCodeInput):
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			case errors.Is(err, pgx.ErrNoRows):
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "invitation code not found")
+			default:
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to update invitation code")
+			}
+			return
+		}
+		c.JSON(http.StatusOK, code)
+	})
+
+	// 登録済みのユーザーは残る（どのコードで登録したかの記録だけ外れる）
+	admin.DELETE("/invitation_codes/:id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		if err := inviteRepo.Delete(c.Request.Context(), id); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "invitation code not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to delete invitation code")
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,93 @@
+package core
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// registerLimitCalibrationRoutes wires the time limit calibration of a problem from reference
+// solutions (see calibrateTimeLimit).
+func registerLimitCalibrationRoutes(admin *gin.RouterGroup, cfg Config, judge JudgeClient, problemRepo ProblemRepository, userRepo UserRepository) {
+	// 書式は起動時に検証済み（cmd/api）
+	policy, _ := ParseTimeLimitPolicy(cfg.TimeLimitPolicy)
+
+	// 参照解を runs 回ずつ全テストケースで実行し、実行時間の分布と推奨の実行時間制限を返す。
+	// confirm=true を付けると推奨値を問題の time_limit_ms に書き込む
+	admin.POST("/problems/:id/calibrate_limits", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		var req struct {
+			Solutions []LimitCalibrationSolution `json:"solutions"`
+			Runs      int                        `json:"runs"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		runs, err := validateCalibration(req.Solutions, req.Runs)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			return
+		}
+		ctx := c.Request.Context()
+		detail, err := problemRepo.FindDetailAdmin(ctx, id)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "problem not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch problem")
+			return
+		}
+		cases, err := problemRepo.ListTestcases(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch testcases")
+			return
+		}
+		if len(cases) == 0 {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "テストケースがありません")
+			return
+		}
+		// 参照解も提出と同じサンドボックス設定で実行する
+		sandbox, err := problemRepo.SandboxPolicy(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch sandbox policy")
+			return
+		}
+		report, err := calibrateTimeLimit(WithSandboxPolicy(ctx, sandbox), judge, detail, cases, req.Solutions, runs, policy)
+		if err != nil {
+			log.Printf("[calibrate] problem %d: %v", id, err)
+			respondError(c, http.StatusBadGateway, "JUDGE_UNAVAILABLE", "ジャッジサーバーでの実行に失敗しました")
+			return
+		}
+
+		if c.Query("confirm") == "true" {
+			if report.RecommendedTimeLimitMS == 0 {
+				respondError(c, http.StatusUnprocessableEntity, "NO_PASSING_SOLUTION", "正しく動作した参照解がないため、実行時間制限を更新できません")
+				return
+			}
+			limit := report.RecommendedTimeLimitMS
+			if err := problemRepo.UpdateProblem(ctx, id, ProblemUpdateInput{TimeLimitMS: &limit, ReviewRequired: reviewRequired(c)}); err != nil {
+				if errors.Is(err, ErrProblemInReview) {
+					respondProblemReviewError(c, err, "")
+					return
+				}
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to update time limit")
+				return
+			}
+			report.Applied = true
+			log.Printf("[calibrate] problem %d time limit %dms -> %dms by %s", id, report.CurrentTimeLimitMS, limit, user.Username)
+		}
+		c.JSON(http.StatusOK, report)
+	})
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// startLoadTest records a load test and runs its generator in the background. The generator is
+// detached from the request so it outlives it; submit creates and enqueues one submission.
+func startLoadTest(ctx context.Context, repo LoadTestRepository, t LoadTest, total int, submit func(ctx context.Context) (int64, error)) (*LoadTest, error) {
+	created, err := repo.Create(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	go runLoadTest(context.Background(), repo, *created, total, submit)
+	return created, nil
+}
+
+// registerLoadTestRoutes wires the reports and cleanup of load tests started via /submissions/bulk_test.
+func registerLoadTestRoutes(admin *gin.RouterGroup, cfg Config, loadTestRepo LoadTestRepository) {
+	admin.GET("/load_tests", func(c *gin.Context) {
+		items, err := loadTestRepo.List(c.Request.Context(), maxLoadTestsListed)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch load tests")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"items": items})
+	})
+
+	admin.GET("/load_tests/:id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		t, err := loadTestRepo.Get(c.Request.Context(), id)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "load test not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch load test")
+			return
+		}
+		c.JSON(http.StatusOK, t)
+	})
+
+	// 生成を止める（投入済みの提出はそのまま判定される）。API 再起動で取り残された running もこれで終わらせる
+	admin.POST("/load_tests/:id/cancel", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		if err := loadTestRepo.Finish(ctx, id, LoadTestCancelled, nil); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusConflict, "CONFLICT", "実行中の負荷試験ではありません")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to cancel load test")
+			return
+		}
+		t, err := loadTestRepo.Get(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch load test")
+			return
+		}
+		c.JSON(http.StatusOK, t)
+	})
+
+	// 負荷試験と、それが生成した提出（ファイルを含む）を削除する
+	admin.DELETE("/load_tests/:id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		ids, err := loadTestRepo.Delete(c.Request.Context(), id)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrLoadTestRunning):
+				respondError(c, http.StatusConflict, "CONFLICT", "実行中の負荷試験は削除できません。先に取り消してください")
+			case errors.Is(err, pgx.ErrNoRows):
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "load test not found")
+			default:
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to delete load test")
+			}
+			return
+		}
+		for _, sid := range ids {
+			if err := os.RemoveAll(filepath.Join(cfg.SubmissionDir, strconv.FormatInt(sid, 10))); err != nil {
+				log.Printf("[loadtest] %d: remove files of submission %d failed: %v", id, sid, err)
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{"deleted_submissions": len(ids)})
+	})
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondLoginLocked answers a password login to a locked account (or from an IP that keeps failing).
+func respondLoginLocked(c *gin.Context, wait time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(wait.Round(time.Second)/time.Second)))
+	respondError(c, http.StatusLocked, "LOCKED", "ログインに続けて失敗したため、一時的にロックしています。しばらく時間をおくか、管理者に解除を依頼してください")
+}
+
+// registerLoginLockoutRoutes wires the admin view and release of accounts locked by failed logins.
+func registerLoginLockoutRoutes(admin *gin.RouterGroup, lockout *LoginLockout, userRepo UserRepository) {
+	admin.GET("/users/:userid/lock", func(c *gin.Context) {
+		target, err := userRepo.FindByUsername(c.Request.Context(), c.Param("userid"))
+		if err != nil {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", "ユーザーが見つかりません")
+			return
+		}
+		wait, err := lockout.LockRemaining(c.Request.Context(), target.Username)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to load lock")
+			return
+		}
+		resp := gin.H{"locked": wait > 0, "locked_until": nil}
+		if wait > 0 {
+			resp["locked_until"] = time.Now().Add(wait).UTC().Truncate(time.Second)
+		}
+		c.JSON(http.StatusOK, resp)
+	})
+
+	admin.POST("/users/:userid/unlock", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		target, err := userRepo.FindByUsername(c.Request.Context(), c.Param("userid"))
+		if err != nil {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", "ユーザーが見つかりません")
+			return
+		}
+		unlocked, err := lockout.Unlock(c.Request.Context(), target.Username)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to unlock account")
+			return
+		}
+		if unlocked {
+			log.Printf("[login] account %q unlocked by %s", target.Username, user.Username)
+		}
+		c.JSON(http.StatusOK, gin.H{"unlocked": unlocked})
+	})
+}
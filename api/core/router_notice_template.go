@@ -0,0 +1,149 @@
+package core
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// registerNoticeTemplateRoutes wires the markdown preview of notices and the notice templates.
+func registerNoticeTemplateRoutes(admin *gin.RouterGroup, templateRepo NoticeTemplateRepository, noticeRepo NoticeRepository, eventBus *EventBus, userRepo UserRepository) {
+	// 公開前の確認用。問題文と同じ方法で描画・サニタイズした HTML を返す
+	admin.POST("/notices/preview", func(c *gin.Context) {
+		var req struct {
+			Title string `json:"title"`
+			Body  string `json:"body"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		respondNoticePreview(c, strings.TrimSpace(req.Title), strings.TrimSpace(req.Body))
+	})
+
+	admin.GET("/notice_templates", func(c *gin.Context) {
+		items, err := templateRepo.List(c.Request.Context())
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch notice templates")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"items": items})
+	})
+
+	admin.POST("/notice_templates", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		var req NoticeTemplateInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		t, err := templateRepo.Create(c.Request.Context(), req, user.ID)
+		if err != nil {
+			respondNoticeTemplateError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, t)
+	})
+
+	admin.PATCH("/notice_templates/:id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		var req NoticeTemplateInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		t, err := templateRepo.Update(c.Request.Context(), id, req)
+		if err != nil {
+			respondNoticeTemplateError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, t)
+	})
+
+	admin.DELETE("/notice_templates/:id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		if err := templateRepo.Delete(c.Request.Context(), id); err != nil {
+			respondNoticeTemplateError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	// 変数を埋めたプレビューを返す。publish=true を付けるとそのままお知らせとして公開する
+	admin.POST("/notice_templates/:id/render", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		var req struct {
+			Variables map[string]string `json:"variables"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		ctx := c.Request.Context()
+		t, err := templateRepo.Get(ctx, id)
+		if err != nil {
+			respondNoticeTemplateError(c, err)
+			return
+		}
+		title, body, err := t.Render(req.Variables)
+		if err != nil {
+			respondNoticeTemplateError(c, err)
+			return
+		}
+		if c.Query("publish") != "true" {
+			respondNoticePreview(c, title, body)
+			return
+		}
+		n, err := noticeRepo.Create(ctx, title, body)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to create notice")
+			return
+		}
+		if err := eventBus.Publish(ctx, NoticeEventChannel, eventNotice, n.ID, n); err != nil {
+			log.Printf("[notice] publish %d failed: %v", n.ID, err)
+		}
+		c.JSON(http.StatusCreated, n)
+	})
+}
+
+func respondNoticePreview(c *gin.Context, title, body string) {
+	if title == "" || body == "" {
+		respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "title と body は必須です")
+		return
+	}
+	html, err := renderStatementHTML(body)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to render notice")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"title": title, "body": body, "html": html})
+}
+
+func respondNoticeTemplateError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrNoticeTemplateInput):
+		respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+	case errors.Is(err, ErrNoticeTemplateExists):
+		respondError(c, http.StatusConflict, "CONFLICT", "同じ名前のテンプレートが既にあります")
+	case errors.Is(err, pgx.ErrNoRows):
+		respondError(c, http.StatusNotFound, "NOT_FOUND", "notice template not found")
+	default:
+		respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to process notice template")
+	}
+}
@@ -0,0 +1,241 @@
+package core
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// startUserSession starts a login as username under a new session ID.
+func startUserSession(c *gin.Context, cfg Config, store *RedisSessionStore, username, role string) error {
+	session, err := store.Get(c.Request, sessionName)
+	if err != nil {
+		return err
+	}
+	// ログイン前の ID を引き継がないよう、新しいセッションを発行する
+	if err := store.renew(c.Request.Context(), session); err != nil {
+		return err
+	}
+	session.Values["userid"] = username
+	session.Values["role"] = role
+	applySessionOptions(cfg, session)
+	return session.Save(c.Request, c.Writer)
+}
+
+// registerOAuthRoutes wires GitHub / Google / OpenID Connect login, linking identities to existing accounts and the
+// login options shown on the login page. Callbacks answer with redirects to the frontend:
+// "/" on success, "/login?totp_token=<token>" when the account needs its two-factor code (see
+// /auth/login/totp) and "/login?oauth_error=<code>" on failure.
+func registerOAuthRoutes(api *gin.RouterGroup, cfg Config, store *RedisSessionStore, redisClient *redis.Client, identityRepo UserIdentityRepository, userRepo UserRepository, totpRepo TOTPRepository) {
+	providers := OAuthProviders(cfg)
+	httpClient := &http.Client{Timeout: oauthHTTPTimeout}
+
+	redirectError := func(c *gin.Context, code string) {
+		c.Redirect(http.StatusFound, cfg.OAuthPublicURL+"/login?oauth_error="+url.QueryEscape(code))
+	}
+
+	api.GET("/auth/providers", func(c *gin.Context) {
+		names := make([]string, 0, len(providers))
+		labels := map[string]string{}
+		signup := false
+		for name, p := range providers {
+			names = append(names, name)
+			labels[name] = p.Label
+			signup = signup || cfg.OAuthSignupEnabled || p.SignUp
+		}
+		sort.Strings(names)
+		c.JSON(http.StatusOK, gin.H{
+			"password_login": !cfg.PasswordLoginDisabled,
+			"providers":      names,
+			"labels":         labels,
+			"signup":         signup,
+			"password_reset": passwordResetEnabled(cfg),
+		})
+	})
+
+	// ?link=1 でログイン中のユーザーに紐付ける
+	api.GET("/auth/oauth/:provider/start", func(c *gin.Context) {
+		p, ok := providers[c.Param("provider")]
+		if !ok {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", "provider not found")
+			return
+		}
+		st := oauthState{Provider: p.Name}
+		if c.Query("link") == "1" {
+			user, ok := requireUser(c, userRepo)
+			if !ok {
+				return
+			}
+			st.LinkUserID = user.ID
+		}
+		// OIDC はここで初めて発見文書を取得する
+		if err := p.discover(c.Request.Context(), httpClient); err != nil {
+			log.Printf("[oauth] %s discovery failed: %v", p.Name, err)
+			respondError(c, http.StatusBadGateway, "PROVIDER_UNAVAILABLE", "認証プロバイダに接続できません")
+			return
+		}
+		state, nonce, err := saveOAuthState(c.Request.Context(), redisClient, st)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to start oauth login")
+			return
+		}
+		setOAuthNonceCookie(c.Writer, cfg, nonce)
+		c.Redirect(http.StatusFound, p.AuthCodeURL(state))
+	})
+
+	api.GET("/auth/oauth/:provider/callback", func(c *gin.Context) {
+		p, ok := providers[c.Param("provider")]
+		if !ok {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", "provider not found")
+			return
+		}
+		ctx := c.Request.Context()
+		// 開始したブラウザ以外からのコールバック（他人が開始した URL の完了）は受け付けない
+		nonce, _ := c.Cookie(oauthNonceCookie)
+		setOAuthNonceCookie(c.Writer, cfg, "")
+		st, err := takeOAuthState(ctx, redisClient, c.Query("state"), nonce)
+		if err != nil || st.Provider != p.Name {
+			redirectError(c, "invalid_state")
+			return
+		}
+		// 利用者が同意画面で拒否した場合など
+		if c.Query("error") != "" || c.Query("code") == "" {
+			redirectError(c, "denied")
+			return
+		}
+		identity, err := p.Exchange(ctx, httpClient, c.Query("code"))
+		if err != nil {
+			log.Printf("[oauth] %s exchange failed: %v", p.Name, err)
+			redirectError(c, "provider_error")
+			return
+		}
+
+		if st.LinkUserID != 0 {
+			if err := identityRepo.Link(ctx, st.LinkUserID, p.Name, *identity); err != nil {
+				if errors.Is(err, ErrOAuthIdentityTaken) {
+					redirectError(c, "already_linked")
+					return
+				}
+				log.Printf("[oauth] link %s for user %d failed: %v", p.Name, st.LinkUserID, err)
+				redirectError(c, "server_error")
+				return
+			}
+			c.Redirect(http.StatusFound, cfg.OAuthPublicURL+"/?oauth_linked="+url.QueryEscape(p.Name))
+			return
+		}
+
+		user, err := identityRepo.FindUser(ctx, p.Name, identity.Subject)
+		if errors.Is(err, pgx.ErrNoRows) && (cfg.OAuthSignupEnabled || p.SignUp) {
+			// パスワードログインには使えないランダムなハッシュを入れておく
+			hash, hashErr := bcrypt.GenerateFromPassword([]byte(randomHex(32)), bcrypt.DefaultCost)
+			if hashErr != nil {
+				redirectError(c, "server_error")
+				return
+			}
+			user, err = identityRepo.SignUp(ctx, p.Name, *identity, string(hash))
+			if errors.Is(err, ErrOAuthIdentityTaken) {
+				// 同じアカウントでの同時ログインに負けた
+				user, err = identityRepo.FindUser(ctx, p.Name, identity.Subject)
+			}
+		}
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				redirectError(c, "not_linked")
+				return
+			}
+			if errors.Is(err, ErrAccountDisabled) {
+				redirectError(c, "disabled")
+				return
+			}
+			if errors.Is(err, ErrAccountSuspended) {
+				redirectError(c, "suspended")
+				return
+			}
+			log.Printf("[oauth] %s login failed: %v", p.Name, err)
+			redirectError(c, "server_error")
+			return
+		}
+		// 規則に合わなくなった利用者は一般ユーザーに戻す
+		if p.RoleSync {
+			role := firstNonEmpty(identity.Role, RoleUser)
+			if role != user.Role {
+				if err := identityRepo.SetRole(ctx, user.ID, role); err != nil {
+					log.Printf("[oauth] sync role of user %d failed: %v", user.ID, err)
+					redirectError(c, "server_error")
+					return
+				}
+				log.Printf("[oauth] role of %s changed from %s to %s by %s", user.Username, user.Role, role, p.Name)
+				user.Role = role
+			}
+		}
+		// 二要素認証を有効にしたアカウントは外部アカウントでも認証コードを求める
+		totpEnabled, err := totpRepo.IsEnabled(ctx, user.ID)
+		if err != nil {
+			redirectError(c, "server_error")
+			return
+		}
+		if totpEnabled {
+			token, err := startTOTPLogin(ctx, redisClient, user.ID, user.Username)
+			if err != nil {
+				redirectError(c, "server_error")
+				return
+			}
+			c.Redirect(http.StatusFound, cfg.OAuthPublicURL+"/login?totp_token="+url.QueryEscape(token))
+			return
+		}
+		if err := startUserSession(c, cfg, store, user.Username, user.Role); err != nil {
+			redirectError(c, "server_error")
+			return
+		}
+		c.Redirect(http.StatusFound, cfg.OAuthPublicURL+"/")
+	})
+
+	api.GET("/users/me/identities", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		items, err := identityRepo.List(c.Request.Context(), user.ID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch identities")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"items": items})
+	})
+
+	api.DELETE("/users/me/identities/:provider", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		if cfg.PasswordLoginDisabled {
+			// パスワードでログインできないので、最後の 1 つは外させない
+			items, err := identityRepo.List(ctx, user.ID)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch identities")
+				return
+			}
+			if len(items) <= 1 {
+				respondError(c, http.StatusConflict, "CONFLICT", "ログイン手段がなくなるため解除できません")
+				return
+			}
+		}
+		if err := identityRepo.Unlink(ctx, user.ID, c.Param("provider")); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "identity not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to unlink identity")
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
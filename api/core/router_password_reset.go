@@ -0,0 +1,119 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const passwordResetSendTimeout = 30 * time.Second
+
+// registerPasswordResetRoutes wires the forgot-password flow: a reset link is mailed to the user's
+// address (see registerEmailRoutes) and the token in it sets a new password.
+func registerPasswordResetRoutes(api *gin.RouterGroup, cfg Config, mailer Mailer, resetRepo PasswordResetRepository, store *RedisSessionStore) {
+	enabled := mailer != nil && passwordResetEnabled(cfg)
+
+	guard := func(c *gin.Context) bool {
+		if cfg.PasswordLoginDisabled {
+			respondError(c, http.StatusForbidden, "PASSWORD_LOGIN_DISABLED", "パスワードによるログインは無効です")
+			return false
+		}
+		if !enabled {
+			respondError(c, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "password reset is not configured")
+			return false
+		}
+		return true
+	}
+
+	// 登録の有無が応答から分からないよう、検索と送信は応答後に行い常に 202 を返す
+	api.POST("/auth/password_reset", func(c *gin.Context) {
+		if !guard(c) {
+			return
+		}
+		var req struct {
+			Email string `json:"email"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		email, err := normalizeEmail(req.Email)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			return
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), passwordResetSendTimeout)
+			defer cancel()
+			if err := sendPasswordReset(ctx, cfg, mailer, resetRepo, email); err != nil {
+				log.Printf("[password_reset] send failed: %v", err)
+			}
+		}()
+		c.Status(http.StatusAccepted)
+	})
+
+	api.POST("/auth/password_reset/confirm", func(c *gin.Context) {
+		if !guard(c) {
+			return
+		}
+		var req struct {
+			Token       string `json:"token"`
+			NewPassword string `json:"new_password"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		if err := validateNewPassword("", req.NewPassword); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			return
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to hash password")
+			return
+		}
+		ctx := c.Request.Context()
+		username, err := resetRepo.Consume(ctx, hashResetToken(strings.TrimSpace(req.Token)), string(hash))
+		if err != nil {
+			if errors.Is(err, ErrPasswordResetToken) {
+				respondError(c, http.StatusBadRequest, "INVALID_TOKEN", "再設定リンクが無効か期限切れです")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to reset password")
+			return
+		}
+		// 残っているログインは古いパスワード（または奪った端末）で得たものなので、すべて失効させる
+		if _, err := store.RevokeUserSessions(ctx, username, ""); err != nil {
+			log.Printf("[password_reset] revoke sessions of %s: %v", username, err)
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
+
+// sendPasswordReset issues a token for the user with the address and mails the link. Unknown
+// addresses and requests within passwordResetInterval of the previous one are ignored.
+func sendPasswordReset(ctx context.Context, cfg Config, mailer Mailer, resetRepo PasswordResetRepository, email string) error {
+	user, err := resetRepo.UserByEmail(ctx, email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	token := randomHex(32)
+	expiresAt := time.Now().Add(passwordResetTTL)
+	created, err := resetRepo.Create(ctx, user.ID, hashResetToken(token), expiresAt)
+	if err != nil || !created {
+		return err
+	}
+	subject, body := passwordResetMail(cfg.OAuthPublicURL, *user, token, expiresAt)
+	return mailer.Send(ctx, email, subject, body)
+}
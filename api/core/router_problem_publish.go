@@ -0,0 +1,174 @@
+package core
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+const maxReviewNoteLength = 2000
+
+// reviewRequired reports whether the session's changes to a published problem must be reviewed.
+func reviewRequired(c *gin.Context) bool {
+	return !HasPermission(sessionRole(c), PermProblemsPublish)
+}
+
+// respondProblemReviewError maps the errors of the publish workflow to responses.
+func respondProblemReviewError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		respondError(c, http.StatusNotFound, "NOT_FOUND", "problem not found")
+	case errors.Is(err, ErrProblemInReview):
+		respondError(c, http.StatusConflict, "PROBLEM_IN_REVIEW", "審査中の問題は変更できません。審査を取り下げてから編集してください")
+	case errors.Is(err, ErrProblemReviewState):
+		respondError(c, http.StatusConflict, "INVALID_STATE", err.Error())
+	default:
+		respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", fallback)
+	}
+}
+
+// registerProblemPublishRoutes adds the review workflow of problems: setters submit their staged
+// changes, admins (PermProblemsPublish) approve or reject them.
+func registerProblemPublishRoutes(admin *gin.RouterGroup, problemRepo ProblemRepository, userRepo UserRepository) {
+	// 審査状態と保留中の変更。パッケージの取り込みは公開中の問題との差分を添える
+	admin.GET("/problems/:id/review", func(c *gin.Context) {
+		if _, ok := requireUser(c, userRepo); !ok {
+			return
+		}
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		status, err := problemRepo.PublishStatus(ctx, id)
+		if err != nil {
+			respondProblemReviewError(c, err, "failed to fetch review status")
+			return
+		}
+		var packageChanges *ProblemImportDiff
+		if status.PendingPackage != nil {
+			if packageChanges, err = loadProblemImportDiff(ctx, problemRepo, id, *status.PendingPackage); err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to compare the pending package")
+				return
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{"review": status, "package_changes": packageChanges})
+	})
+
+	admin.POST("/problems/:id/review/submit", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		if err := problemRepo.SubmitForReview(c.Request.Context(), id, user.ID); err != nil {
+			respondProblemReviewError(c, err, "failed to submit for review")
+			return
+		}
+		log.Printf("[review] problem %d submitted by %s", id, user.Username)
+		respondProblemReviewStatus(c, problemRepo, id)
+	})
+
+	admin.POST("/problems/:id/review/withdraw", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		if err := problemRepo.WithdrawReview(c.Request.Context(), id); err != nil {
+			respondProblemReviewError(c, err, "failed to withdraw review")
+			return
+		}
+		log.Printf("[review] problem %d withdrawn by %s", id, user.Username)
+		respondProblemReviewStatus(c, problemRepo, id)
+	})
+
+	// 保留中の変更を破棄する（公開中の問題はそのまま）
+	admin.DELETE("/problems/:id/review", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		if err := problemRepo.DiscardPendingChanges(c.Request.Context(), id); err != nil {
+			respondProblemReviewError(c, err, "failed to discard pending changes")
+			return
+		}
+		log.Printf("[review] problem %d pending changes discarded by %s", id, user.Username)
+		respondProblemReviewStatus(c, problemRepo, id)
+	})
+
+	publish := admin.Group("", RequirePermission(PermProblemsPublish))
+
+	publish.POST("/problems/:id/review/approve", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		if err := problemRepo.ApproveReview(c.Request.Context(), id, user.ID); err != nil {
+			if strings.Contains(err.Error(), "checker") || strings.Contains(err.Error(), "limit") || strings.Contains(err.Error(), "required") {
+				respondError(c, http.StatusUnprocessableEntity, "VALIDATION_ERROR", "保留中の変更を適用できません: "+err.Error())
+				return
+			}
+			respondProblemReviewError(c, err, "failed to approve review")
+			return
+		}
+		log.Printf("[review] problem %d approved by %s", id, user.Username)
+		respondProblemReviewStatus(c, problemRepo, id)
+	})
+
+	publish.POST("/problems/:id/review/reject", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		var req struct {
+			Note string `json:"note"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		note := strings.TrimSpace(req.Note)
+		if note == "" || len([]rune(note)) > maxReviewNoteLength {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "note は 1〜2000 文字で指定してください")
+			return
+		}
+		if err := problemRepo.RejectReview(c.Request.Context(), id, user.ID, note); err != nil {
+			respondProblemReviewError(c, err, "failed to reject review")
+			return
+		}
+		log.Printf("[review] problem %d rejected by %s", id, user.Username)
+		respondProblemReviewStatus(c, problemRepo, id)
+	})
+}
+
+func respondProblemReviewStatus(c *gin.Context, problemRepo ProblemRepository, id int64) {
+	status, err := problemRepo.PublishStatus(c.Request.Context(), id)
+	if err != nil {
+		respondProblemReviewError(c, err, "failed to fetch review status")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"review": status})
+}
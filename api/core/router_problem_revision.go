@@ -0,0 +1,156 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// registerProblemRevisionRoutes exposes the revision history of problems: every edit of the
+// statement, limits, checker or testcases is a revision that can be compared with another one and
+// rolled back to. A rollback is itself recorded as a new revision.
+func registerProblemRevisionRoutes(admin *gin.RouterGroup, problemRepo ProblemRepository, userRepo UserRepository) {
+	findRevision := func(c *gin.Context, id int64, revision int) (*ProblemRevision, bool) {
+		rev, err := problemRepo.ProblemRevision(c.Request.Context(), id, revision)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "revision not found")
+				return nil, false
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch revision")
+			return nil, false
+		}
+		return rev, true
+	}
+	parseRevision := func(c *gin.Context) (int64, int, bool) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return 0, 0, false
+		}
+		rev, err := strconv.Atoi(c.Param("rev"))
+		if err != nil || rev <= 0 {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid rev")
+			return 0, 0, false
+		}
+		return id, rev, true
+	}
+
+	admin.GET("/problems/:id/revisions", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		exists, err := problemRepo.Exists(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch problem")
+			return
+		}
+		if !exists {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", "problem not found")
+			return
+		}
+		items, err := problemRepo.ProblemRevisions(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch revisions")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"items": items})
+	})
+
+	// against=N で比較する版を指定する（既定は直前の版。最初の版は差分なし）
+	admin.GET("/problems/:id/revisions/:rev", func(c *gin.Context) {
+		id, revision, ok := parseRevision(c)
+		if !ok {
+			return
+		}
+		against := revision - 1
+		if raw := c.Query("against"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n <= 0 {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid against")
+				return
+			}
+			against = n
+		}
+		rev, ok := findRevision(c, id, revision)
+		if !ok {
+			return
+		}
+		var diff *ProblemRevisionDiff
+		if against > 0 {
+			base, ok := findRevision(c, id, against)
+			if !ok {
+				return
+			}
+			d := diffProblemRevisions(base, rev)
+			diff = &d
+		}
+		c.JSON(http.StatusOK, gin.H{"revision": rev, "diff": diff})
+	})
+
+	// 版の内容（テストケースを含む）に戻す。開催中のコンテストの問題は confirm=true が必要
+	admin.POST("/problems/:id/revisions/:rev/rollback", func(c *gin.Context) {
+		id, revision, ok := parseRevision(c)
+		if !ok {
+			return
+		}
+		editor, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		rev, ok := findRevision(c, id, revision)
+		if !ok {
+			return
+		}
+		current, err := problemRepo.FindDetailAdmin(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch problem")
+			return
+		}
+		running, err := problemRepo.InRunningContest(ctx, id, time.Now())
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to check contest state")
+			return
+		}
+		if running && c.Query("confirm") != "true" {
+			respondError(c, http.StatusConflict, "CONFIRMATION_REQUIRED", "開催中のコンテストの問題です。差分を確認し confirm=true を付けて再送してください")
+			return
+		}
+		input := revisionPackageInput(current.Slug, rev)
+		input.ReviewRequired = reviewRequired(c)
+		if err := problemRepo.ReplaceWithPackage(ctx, id, input, &editor.ID); err != nil {
+			if errors.Is(err, ErrProblemTestcaseLimit) {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+				return
+			}
+			respondProblemReviewError(c, err, "failed to roll back problem")
+			return
+		}
+		setAuditTarget(c, "problems", id)
+		setAuditSummary(c, "rollback to revision %d", revision)
+		// 公開中の問題への作問者の巻き戻しは保留され、承認されるまで反映されない
+		if input.ReviewRequired {
+			status, err := problemRepo.PublishStatus(ctx, id)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch review status")
+				return
+			}
+			if status.PublishedAt != nil && status.PendingPackage != nil {
+				c.JSON(http.StatusAccepted, gin.H{"review": status})
+				return
+			}
+		}
+		items, err := problemRepo.ProblemRevisions(ctx, id)
+		if err != nil || len(items) == 0 {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch revisions")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"revision": items[0], "in_running_contest": running})
+	})
+}
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// registerProblemSandboxRoutes wires the per-problem go-judge sandbox policy (see SandboxPolicy).
+func registerProblemSandboxRoutes(admin *gin.RouterGroup, cfg Config, policies map[string]SandboxPolicy, problemRepo ProblemRepository, userRepo UserRepository) {
+	// 問題の設定と、言語ごとの設定（JUDGE_SANDBOX_POLICY）を重ねた実際に使われる設定を返す
+	admin.GET("/problems/:id/sandbox_policy", func(c *gin.Context) {
+		if _, ok := requireUser(c, userRepo); !ok {
+			return
+		}
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		policy, err := problemRepo.SandboxPolicy(c.Request.Context(), id)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "problem not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch sandbox policy")
+			return
+		}
+		ctx := WithSandboxPolicy(c.Request.Context(), policy)
+		effective := map[string]SandboxPolicy{}
+		for _, lang := range supportedLanguages {
+			effective[lang["key"]] = effectiveSandboxPolicy(ctx, policies, lang["key"])
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"problem_id":        id,
+			"policy":            policy,
+			"effective":         effective,
+			"network_available": cfg.GoJudgeNetworkURL != "",
+		})
+	})
+
+	// 問題の設定を置き換える。空のオブジェクトで解除する
+	admin.PUT("/problems/:id/sandbox_policy", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		var req SandboxPolicy
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		if err := req.Validate(); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			return
+		}
+		if req.Network != nil && *req.Network && cfg.GoJudgeNetworkURL == "" {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "ネットワークを許可するには GOJUDGE_NETWORK_URL の設定が必要です")
+			return
+		}
+		if err := problemRepo.SetSandboxPolicy(c.Request.Context(), id, &req); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "problem not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to update sandbox policy")
+			return
+		}
+		log.Printf("[sandbox] problem %d policy updated by %s", id, user.Username)
+		var policy *SandboxPolicy
+		if !req.IsZero() {
+			policy = &req
+		}
+		c.JSON(http.StatusOK, gin.H{"problem_id": id, "policy": policy})
+	})
+}
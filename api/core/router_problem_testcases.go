@@ -0,0 +1,186 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// registerProblemTestcaseRoutes lets setters manage single testcases (add, replace, delete,
+// reorder) without re-uploading the whole package. Every change is applied like a package
+// overwrite: it is versioned, checked against the testcase limits, and staged for review when the
+// editor needs one.
+func registerProblemTestcaseRoutes(admin *gin.RouterGroup, problemRepo ProblemRepository, userRepo UserRepository) {
+	loadTestcases := func(c *gin.Context, id int64) (*ProblemCreateInput, bool, bool) {
+		pkg, pending, err := problemRepo.TestcasePackage(c.Request.Context(), id, reviewRequired(c))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "problem not found")
+				return nil, false, false
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch testcases")
+			return nil, false, false
+		}
+		return pkg, pending, true
+	}
+	parsePosition := func(c *gin.Context) (int64, int, bool) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return 0, 0, false
+		}
+		no, err := strconv.Atoi(c.Param("no"))
+		if err != nil || no <= 0 {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid testcase number")
+			return 0, 0, false
+		}
+		return id, no, true
+	}
+	// 変更後の一覧を返す。公開中の問題への作問者の変更は保留され、承認されるまで反映されない
+	edit := func(c *gin.Context, id int64, e TestcaseEdit, summary string) {
+		editor, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		running, err := problemRepo.InRunningContest(ctx, id, time.Now())
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to check contest state")
+			return
+		}
+		if running && c.Query("confirm") != "true" {
+			respondError(c, http.StatusConflict, "CONFIRMATION_REQUIRED", "開催中のコンテストの問題です。confirm=true を付けて再送してください")
+			return
+		}
+		staged, err := problemRepo.EditTestcases(ctx, id, e, &editor.ID, reviewRequired(c))
+		if err != nil {
+			if errors.Is(err, ErrTestcaseEditInput) || errors.Is(err, ErrProblemTestcaseLimit) {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+				return
+			}
+			respondProblemReviewError(c, err, "failed to update testcases")
+			return
+		}
+		setAuditTarget(c, "problems", id)
+		setAuditSummary(c, "testcases %s", summary)
+		if staged {
+			status, err := problemRepo.PublishStatus(ctx, id)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch review status")
+				return
+			}
+			c.JSON(http.StatusAccepted, gin.H{"review": status, "items": summarizeTestcases(*status.PendingPackage)})
+			return
+		}
+		pkg, _, ok := loadTestcases(c, id)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"items": summarizeTestcases(*pkg), "pending": false, "in_running_contest": running})
+	}
+
+	// 作問者には保留中のパッケージがあればその内容を返す（pending=true）
+	admin.GET("/problems/:id/testcases", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		pkg, pending, ok := loadTestcases(c, id)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"items": summarizeTestcases(*pkg), "pending": pending})
+	})
+
+	admin.GET("/problems/:id/testcases/:no", func(c *gin.Context) {
+		id, no, ok := parsePosition(c)
+		if !ok {
+			return
+		}
+		pkg, pending, ok := loadTestcases(c, id)
+		if !ok {
+			return
+		}
+		if no > len(pkg.Testcases) {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", "testcase not found")
+			return
+		}
+		tc := pkg.Testcases[no-1]
+		c.JSON(http.StatusOK, gin.H{
+			"testcase": summarizeTestcases(*pkg)[no-1],
+			"input":    tc.InputText,
+			"output":   tc.OutputText,
+			"pending":  pending,
+		})
+	})
+
+	// position を省略すると末尾に追加する。name を省略すると sample/NN・secret/NN を割り当てる
+	admin.POST("/problems/:id/testcases", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		var req struct {
+			Name     *string `json:"name"`
+			Input    *string `json:"input"`
+			Output   *string `json:"output"`
+			IsSample *bool   `json:"is_sample"`
+			Position int     `json:"position"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+			return
+		}
+		edit(c, id, TestcaseEdit{Op: TestcaseEditAdd, Position: req.Position, Name: req.Name, Input: req.Input, Output: req.Output, IsSample: req.IsSample}, "add")
+	})
+
+	// 並べ替え。order には現在の番号を新しい順に全件並べる
+	admin.POST("/problems/:id/testcases/reorder", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		var req struct {
+			Order []int `json:"order"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+			return
+		}
+		edit(c, id, TestcaseEdit{Op: TestcaseEditReorder, Order: req.Order}, "reorder")
+	})
+
+	// 指定した項目だけを置き換える
+	admin.PATCH("/problems/:id/testcases/:no", func(c *gin.Context) {
+		id, no, ok := parsePosition(c)
+		if !ok {
+			return
+		}
+		var req struct {
+			Name     *string `json:"name"`
+			Input    *string `json:"input"`
+			Output   *string `json:"output"`
+			IsSample *bool   `json:"is_sample"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid request body")
+			return
+		}
+		if req.Name == nil && req.Input == nil && req.Output == nil && req.IsSample == nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "no fields to update")
+			return
+		}
+		edit(c, id, TestcaseEdit{Op: TestcaseEditReplace, Position: no, Name: req.Name, Input: req.Input, Output: req.Output, IsSample: req.IsSample}, "replace #"+strconv.Itoa(no))
+	})
+
+	admin.DELETE("/problems/:id/testcases/:no", func(c *gin.Context) {
+		id, no, ok := parsePosition(c)
+		if !ok {
+			return
+		}
+		edit(c, id, TestcaseEdit{Op: TestcaseEditDelete, Position: no}, "delete #"+strconv.Itoa(no))
+	})
+}
@@ -0,0 +1,345 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// registerProblemUploadRoutes wires resumable problem package uploads. The client creates an
+// upload with the total size, sends the zip in chunks with PATCH + Upload-Offset, asks for the
+// current offset after a dropped connection, and imports once every byte has arrived.
+func registerProblemUploadRoutes(admin *gin.RouterGroup, cfg Config, store *UploadStore, problemRepo ProblemRepository, userRepo UserRepository, testcaseGen *TestcaseGenerator) {
+	admin.POST("/problem_uploads", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		var req struct {
+			Filename string `json:"filename"`
+			Size     int64  `json:"size"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		limit := problemImportLimit(cfg)
+		if req.Size <= 0 {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "size は必須です")
+			return
+		}
+		if req.Size > limit {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", fmt.Sprintf("ファイルが大きすぎます (%dMB 以下にしてください)", limit/1024/1024))
+			return
+		}
+		upload, err := store.Create(user.ID, req.Filename, req.Size)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to create upload")
+			return
+		}
+		c.Header("Upload-Offset", "0")
+		c.JSON(http.StatusCreated, upload)
+	})
+
+	admin.GET("/problem_uploads/:id", func(c *gin.Context) {
+		upload, ok := loadOwnUpload(c, store, userRepo)
+		if !ok {
+			return
+		}
+		c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		c.JSON(http.StatusOK, upload)
+	})
+
+	admin.PATCH("/problem_uploads/:id", func(c *gin.Context) {
+		upload, ok := loadOwnUpload(c, store, userRepo)
+		if !ok {
+			return
+		}
+		offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+		if err != nil || offset < 0 {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "Upload-Offset ヘッダを指定してください")
+			return
+		}
+		upload, err = store.Append(upload.ID, offset, c.Request.Body)
+		if upload != nil {
+			c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		}
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrUploadNotFound):
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "upload not found")
+			case errors.Is(err, ErrUploadOffsetMismatch):
+				respondError(c, http.StatusConflict, "UPLOAD_OFFSET_MISMATCH", fmt.Sprintf("オフセットが一致しません (現在 %d bytes)", upload.Offset))
+			case errors.Is(err, ErrUploadBusy):
+				respondError(c, http.StatusConflict, "UPLOAD_BUSY", "このアップロードは別のチャンクを受信中です。完了後にオフセットを確認して再送してください")
+			case errors.Is(err, ErrUploadTooLarge):
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "宣言したサイズを超えています")
+			case upload != nil:
+				// 受信途中で切断された。受信済みのオフセットから再開できる
+				respondError(c, http.StatusBadRequest, "UPLOAD_INTERRUPTED", fmt.Sprintf("チャンクの受信が中断されました (現在 %d bytes)", upload.Offset))
+			default:
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to write chunk")
+			}
+			return
+		}
+		c.JSON(http.StatusOK, upload)
+	})
+
+	admin.POST("/problem_uploads/:id/import", func(c *gin.Context) {
+		upload, ok := loadOwnUpload(c, store, userRepo)
+		if !ok {
+			return
+		}
+		file, size, err := store.OpenComplete(upload.ID)
+		if err != nil {
+			if errors.Is(err, ErrUploadIncomplete) {
+				respondError(c, http.StatusConflict, "UPLOAD_INCOMPLETE", fmt.Sprintf("アップロードが完了していません (%d / %d bytes)", upload.Offset, upload.Size))
+				return
+			}
+			if errors.Is(err, ErrUploadNotFound) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "upload not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "アップロードの読み取りに失敗しました")
+			return
+		}
+		defer file.Close()
+		if importProblemPackage(c, problemRepo, userRepo, testcaseGen, file, size, importOverwriteRequested(c)) {
+			_ = store.Delete(upload.ID)
+		}
+	})
+
+	admin.DELETE("/problem_uploads/:id", func(c *gin.Context) {
+		upload, ok := loadOwnUpload(c, store, userRepo)
+		if !ok {
+			return
+		}
+		if err := store.Delete(upload.ID); err != nil && !errors.Is(err, ErrUploadNotFound) {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to delete upload")
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
+
+// loadOwnUpload loads the :id upload; uploads are only visible to the user who started them.
+func loadOwnUpload(c *gin.Context, store *UploadStore, userRepo UserRepository) (*Upload, bool) {
+	user, ok := requireUser(c, userRepo)
+	if !ok {
+		return nil, false
+	}
+	upload, err := store.Get(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, ErrUploadNotFound) {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", "upload not found")
+			return nil, false
+		}
+		respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to load upload")
+		return nil, false
+	}
+	if upload.OwnerID != user.ID {
+		respondError(c, http.StatusNotFound, "NOT_FOUND", "upload not found")
+		return nil, false
+	}
+	return upload, true
+}
+
+// importOverwriteRequested reports whether the import confirms overwriting the problem with the same
+// slug (overwrite=true as a form field or query parameter).
+func importOverwriteRequested(c *gin.Context) bool {
+	overwrite, _ := strconv.ParseBool(firstNonEmpty(c.PostForm("overwrite"), c.Query("overwrite")))
+	return overwrite
+}
+
+// importProblemPackage parses a problem zip (size bytes read from archive) and stores it, writing
+// the response. It reports whether the problem was created or, with overwrite, replaced. When a
+// problem with the same slug exists and overwrite is not set, it responds 409 with a comparison of
+// the two and stores nothing.
+func importProblemPackage(c *gin.Context, problemRepo ProblemRepository, userRepo UserRepository, testcaseGen *TestcaseGenerator, archive io.ReaderAt, size int64, overwrite bool) bool {
+	ctx := c.Request.Context()
+	pkg, err := ParseProblemArchiveAt(ctx, archive, size, testcaseGen)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_PROBLEM_PACKAGE", err.Error())
+		return false
+	}
+	pkg.ReviewRequired = reviewRequired(c)
+
+	existingID, err := problemRepo.IDBySlug(ctx, pkg.Slug)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to look up problem")
+		return false
+	}
+	if err == nil {
+		return overwriteProblemPackage(c, problemRepo, userRepo, existingID, pkg, overwrite)
+	}
+
+	problemID, err := problemRepo.CreateWithTestcases(ctx, pkg)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "unique") {
+			respondError(c, http.StatusConflict, "CONFLICT", "同じ slug の問題が既に存在します")
+			return false
+		}
+		respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "問題の保存に失敗しました")
+		return false
+	}
+
+	setAuditTarget(c, "problems", problemID)
+	setAuditSummary(c, "import slug=%q", pkg.Slug)
+	c.JSON(http.StatusCreated, gin.H{
+		"id":                  problemID,
+		"title":               pkg.Title,
+		"slug":                pkg.Slug,
+		"time_limit_ms":       pkg.TimeLimitMS,
+		"memory_limit_kb":     pkg.MemoryLimitKB,
+		"is_public":           pkg.IsPublic,
+		"published":           !pkg.ReviewRequired,
+		"normalized_outputs":  pkg.NormalizedOutputs,
+		"statement_sanitized": pkg.StatementSanitized,
+		"editorial":           pkg.EditorialMD != nil,
+		"judge_cost_warning":  judgeCostWarning(pkg.TimeLimitMS, len(pkg.Testcases)),
+	})
+	return true
+}
+
+// reimportProblemPackage replaces problem id with a problem zip, writing the response. The package
+// must carry the slug of the problem; naming the problem in the path confirms the overwrite.
+func reimportProblemPackage(c *gin.Context, problemRepo ProblemRepository, userRepo UserRepository, testcaseGen *TestcaseGenerator, id int64, data []byte) bool {
+	ctx := c.Request.Context()
+	exists, err := problemRepo.Exists(ctx, id)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch problem")
+		return false
+	}
+	if !exists {
+		respondError(c, http.StatusNotFound, "NOT_FOUND", "problem not found")
+		return false
+	}
+	pkg, err := ParseProblemArchive(ctx, data, testcaseGen)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_PROBLEM_PACKAGE", err.Error())
+		return false
+	}
+	pkg.ReviewRequired = reviewRequired(c)
+
+	matchedID, err := problemRepo.IDBySlug(ctx, pkg.Slug)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to look up problem")
+		return false
+	}
+	if err != nil || matchedID != id {
+		// slug は問題の識別子なので、別の問題の（または未登録の）slug のパッケージでは上書きしない
+		respondError(c, http.StatusConflict, "SLUG_MISMATCH", fmt.Sprintf("パッケージの slug %q はこの問題の slug と一致しません", pkg.Slug))
+		return false
+	}
+	return overwriteProblemPackage(c, problemRepo, userRepo, id, pkg, true)
+}
+
+func overwriteProblemPackage(c *gin.Context, problemRepo ProblemRepository, userRepo UserRepository, id int64, pkg ProblemCreateInput, overwrite bool) bool {
+	ctx := c.Request.Context()
+	diff, err := loadProblemImportDiff(ctx, problemRepo, id, pkg)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to compare with the existing problem")
+		return false
+	}
+	if !overwrite {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": gin.H{
+				"code":    "PROBLEM_EXISTS",
+				"message": "同じ slug の問題が既に存在します。内容を確認し、上書きする場合は overwrite=true を指定してください",
+			},
+			"conflict": diff,
+		})
+		return false
+	}
+	editor, ok := requireUser(c, userRepo)
+	if !ok {
+		return false
+	}
+	if err := problemRepo.ReplaceWithPackage(ctx, id, pkg, &editor.ID); err != nil {
+		if errors.Is(err, ErrProblemInReview) {
+			respondProblemReviewError(c, err, "")
+			return false
+		}
+		if errors.Is(err, ErrProblemTestcaseLimit) {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			return false
+		}
+		respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "問題の上書きに失敗しました")
+		return false
+	}
+	status := http.StatusOK
+	var review *ProblemPublishStatus
+	if pkg.ReviewRequired {
+		// 公開中の問題への取り込みは保留され、承認されるまで反映されない
+		if review, err = problemRepo.PublishStatus(ctx, id); err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch review status")
+			return false
+		}
+		if review.PublishedAt != nil {
+			status = http.StatusAccepted
+		}
+	}
+	setAuditTarget(c, "problems", id)
+	setAuditSummary(c, "overwrite slug=%q overwritten=%t", pkg.Slug, status == http.StatusOK)
+	c.JSON(status, gin.H{
+		"id":                  id,
+		"title":               pkg.Title,
+		"slug":                pkg.Slug,
+		"time_limit_ms":       pkg.TimeLimitMS,
+		"memory_limit_kb":     pkg.MemoryLimitKB,
+		"overwritten":         status == http.StatusOK,
+		"review":              review,
+		"changes":             diff,
+		"normalized_outputs":  pkg.NormalizedOutputs,
+		"statement_sanitized": pkg.StatementSanitized,
+		"editorial":           pkg.EditorialMD != nil,
+		"judge_cost_warning":  judgeCostWarning(pkg.TimeLimitMS, len(pkg.Testcases)),
+	})
+	return true
+}
+
+// problemImportLimit returns the PROBLEM_IMPORT_MAX_MB cap in bytes (8MB when unset).
+func problemImportLimit(cfg Config) int64 {
+	mb := cfg.ProblemImportMaxMB
+	if mb <= 0 {
+		mb = 8
+	}
+	return int64(mb) * 1024 * 1024
+}
+
+// readProblemImportFile reads the problem zip of the "file" form field, writing an error response
+// when it is missing or larger than the import limit.
+func readProblemImportFile(c *gin.Context, cfg Config) ([]byte, bool) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "file フィールドに zip を指定してください")
+		return nil, false
+	}
+	limit := problemImportLimit(cfg)
+	if fileHeader.Size > limit {
+		respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", fmt.Sprintf("ファイルが大きすぎます (%dMB 以下にしてください)", limit/1024/1024))
+		return nil, false
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "INVALID_PROBLEM_PACKAGE", "ファイルを開けません")
+		return nil, false
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, limit+1024))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "アップロードの読み取りに失敗しました")
+		return nil, false
+	}
+	if int64(len(data)) > limit {
+		respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", fmt.Sprintf("ファイルが大きすぎます (%dMB 以下にしてください)", limit/1024/1024))
+		return nil, false
+	}
+	return data, true
+}
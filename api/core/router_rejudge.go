@@ -0,0 +1,117 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+const maxRejudgeJobsListed = 50
+
+// startRejudge creates a rejudge job for the problem and enqueues its submissions. Submissions that
+// cannot be enqueued are restored and counted as failed in the returned job.
+func startRejudge(ctx context.Context, rejudgeRepo RejudgeRepository, queue RedisClient, problemID, userID int64, runAll bool, mode string) (*RejudgeJob, error) {
+	job, targets, err := rejudgeRepo.CreateForProblem(ctx, problemID, userID, runAll, mode)
+	if err != nil {
+		return nil, err
+	}
+	var enqueueFailed int
+	for _, t := range targets {
+		if err := queue.Enqueue(ctx, PendingQueueKey, strconv.FormatInt(t.SubmissionID, 10)); err != nil {
+			log.Printf("[rejudge] job %d: enqueue submission %d failed: %v", job.ID, t.SubmissionID, err)
+			if markErr := rejudgeRepo.MarkEnqueueFailed(ctx, job.ID, t); markErr != nil {
+				log.Printf("[rejudge] job %d: restore submission %d failed: %v", job.ID, t.SubmissionID, markErr)
+			}
+			enqueueFailed++
+		}
+	}
+	if enqueueFailed > 0 {
+		if refreshed, err := rejudgeRepo.Get(ctx, job.ID); err == nil {
+			job = refreshed
+		}
+	}
+	return job, nil
+}
+
+// registerRejudgeRoutes wires bulk rejudges of a problem and their progress.
+func registerRejudgeRoutes(admin *gin.RouterGroup, rejudgeRepo RejudgeRepository, problemRepo ProblemRepository, userRepo UserRepository, queue RedisClient) {
+	admin.POST("/problems/:id/rejudge", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		var req struct {
+			// RunAllTestcases は最初の不正解で打ち切らず全テストケースを実行する
+			RunAllTestcases bool `json:"run_all_testcases"`
+			// Mode は rerun（既定）か recheck（保存済みの出力を現在のチェッカーで判定し直す）
+			Mode string `json:"mode"`
+		}
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+				return
+			}
+		}
+		mode := firstNonEmpty(req.Mode, RejudgeModeRerun)
+		if mode != RejudgeModeRerun && mode != RejudgeModeRecheck {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "mode は rerun か recheck を指定してください")
+			return
+		}
+		ctx := c.Request.Context()
+		exists, err := problemRepo.Exists(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "問題の存在確認に失敗しました")
+			return
+		}
+		if !exists {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", "problem not found")
+			return
+		}
+		job, err := startRejudge(ctx, rejudgeRepo, queue, id, user.ID, req.RunAllTestcases, mode)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to create rejudge job")
+			return
+		}
+		setAuditSummary(c, "rejudge job=%d mode=%s run_all_testcases=%t", job.ID, mode, req.RunAllTestcases)
+		c.JSON(http.StatusAccepted, job)
+	})
+
+	admin.GET("/problems/:id/rejudges", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		items, err := rejudgeRepo.ListByProblem(c.Request.Context(), id, maxRejudgeJobsListed)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch rejudge jobs")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"items": items})
+	})
+
+	admin.GET("/rejudges/:id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		job, err := rejudgeRepo.Get(c.Request.Context(), id)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "rejudge job not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch rejudge job")
+			return
+		}
+		c.JSON(http.StatusOK, job)
+	})
+}
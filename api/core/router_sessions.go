@@ -0,0 +1,88 @@
+package core
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/sessions"
+)
+
+// registerSessionRoutes wires the login sessions of the current user (listing and logging out other
+// devices) and the admin force-logout of a user.
+func registerSessionRoutes(api, admin *gin.RouterGroup, store *RedisSessionStore, userRepo UserRepository) {
+	currentSessionID := func(c *gin.Context) string {
+		sessionAny, _ := c.Get("session")
+		if sess, ok := sessionAny.(*sessions.Session); ok {
+			return sess.ID
+		}
+		return ""
+	}
+	// セッションの失効はセッションからのみ受け付ける（API トークンでは不可）
+	requireSessionUser := func(c *gin.Context) (*UserRecord, bool) {
+		if isTokenAuth(c) {
+			respondError(c, http.StatusForbidden, "FORBIDDEN", "API トークンではセッションを操作できません")
+			return nil, false
+		}
+		return requireUser(c, userRepo)
+	}
+
+	api.GET("/users/me/sessions", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		list, err := store.ListUserSessions(c.Request.Context(), user.Username, currentSessionID(c))
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to list sessions")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"sessions": list})
+	})
+
+	// 現在のセッション以外をすべてログアウトさせる
+	api.DELETE("/users/me/sessions", func(c *gin.Context) {
+		user, ok := requireSessionUser(c)
+		if !ok {
+			return
+		}
+		revoked, err := store.RevokeUserSessions(c.Request.Context(), user.Username, currentSessionID(c))
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to revoke sessions")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"revoked": revoked})
+	})
+
+	api.DELETE("/users/me/sessions/:id", func(c *gin.Context) {
+		user, ok := requireSessionUser(c)
+		if !ok {
+			return
+		}
+		found, err := store.RevokeUserSession(c.Request.Context(), user.Username, c.Param("id"))
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to revoke session")
+			return
+		}
+		if !found {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", "セッションが見つかりません")
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	// 強制ログアウト。アカウントの乗っ取りや退職者の対応に使う（API トークンは別途失効させる）
+	admin.POST("/users/:userid/logout", func(c *gin.Context) {
+		ctx := c.Request.Context()
+		target, err := userRepo.FindByUsername(ctx, c.Param("userid"))
+		if err != nil {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", "ユーザーが見つかりません")
+			return
+		}
+		revoked, err := store.RevokeUserSessions(ctx, target.Username, "")
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to revoke sessions")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"revoked": revoked})
+	})
+}
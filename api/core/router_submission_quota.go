@@ -0,0 +1,75 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerSubmissionQuotaRoutes wires the per-user override of the submission quotas. The response
+// shows the configured defaults, the override and the limits in effect; PUT with both limits null
+// removes the override.
+func registerSubmissionQuotaRoutes(admin *gin.RouterGroup, cfg Config, repo SubmissionQuotaRepository, userRepo UserRepository) {
+	respond := func(c *gin.Context, target *UserRecord) {
+		override, err := repo.Override(c.Request.Context(), target.ID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch submission quota")
+			return
+		}
+		defaults := defaultSubmissionQuota(cfg)
+		c.JSON(http.StatusOK, gin.H{
+			"userid":    target.Username,
+			"default":   defaults,
+			"override":  override,
+			"effective": override.Apply(defaults),
+			"exempt":    isStaffRole(target.Role),
+		})
+	}
+	findTarget := func(c *gin.Context) (*UserRecord, bool) {
+		target, err := userRepo.FindByUsername(c.Request.Context(), c.Param("userid"))
+		if err != nil {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", "ユーザーが見つかりません")
+			return nil, false
+		}
+		return target, true
+	}
+
+	admin.GET("/users/:userid/submission_quota", func(c *gin.Context) {
+		if target, ok := findTarget(c); ok {
+			respond(c, target)
+		}
+	})
+
+	admin.PUT("/users/:userid/submission_quota", func(c *gin.Context) {
+		actor, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		var req SubmissionQuotaOverride
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		target, ok := findTarget(c)
+		if !ok {
+			return
+		}
+		var err error
+		// 両方 null なら上書きを削除して既定値に戻す
+		if req.Daily == nil && req.Contest == nil {
+			err = repo.DeleteOverride(c.Request.Context(), target.ID)
+		} else {
+			err = repo.SetOverride(c.Request.Context(), target.ID, req, actor.ID)
+		}
+		if err != nil {
+			if errors.Is(err, ErrSubmissionQuotaInput) {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to update submission quota")
+			return
+		}
+		respond(c, target)
+	})
+}
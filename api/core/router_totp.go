@@ -0,0 +1,245 @@
+package core
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// registerTOTPRoutes wires two-factor authentication for accounts that use the admin pages: setup
+// with an authenticator app, recovery codes, and the second step of the password login.
+func registerTOTPRoutes(api *gin.RouterGroup, cfg Config, store *RedisSessionStore, redisClient *redis.Client, totpRepo TOTPRepository, userRepo UserRepository) {
+	// 2FA の変更はセッションからのみ受け付ける（API トークンでは不可）
+	requireSessionStaff := func(c *gin.Context) (*UserRecord, bool) {
+		if isTokenAuth(c) {
+			respondError(c, http.StatusForbidden, "FORBIDDEN", "API トークンでは二要素認証を変更できません")
+			return nil, false
+		}
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return nil, false
+		}
+		if !isStaffRole(user.Role) {
+			respondError(c, http.StatusForbidden, "FORBIDDEN", "二要素認証は管理画面を使うアカウントで利用できます")
+			return nil, false
+		}
+		return user, true
+	}
+
+	api.GET("/users/me/totp", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		t, err := totpRepo.Get(c.Request.Context(), user.ID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to load two-factor settings")
+			return
+		}
+		resp := gin.H{"available": isStaffRole(user.Role), "enabled": false, "enabled_at": nil, "recovery_codes_left": 0}
+		if t != nil && t.EnabledAt != nil {
+			resp["enabled"], resp["enabled_at"], resp["recovery_codes_left"] = true, t.EnabledAt, t.RecoveryCodesLeft
+		}
+		c.JSON(http.StatusOK, resp)
+	})
+
+	// 秘密鍵を発行する。enable で最初のコードを確認するまで有効にはならない
+	api.POST("/users/me/totp/setup", func(c *gin.Context) {
+		user, ok := requireSessionStaff(c)
+		if !ok {
+			return
+		}
+		var req struct {
+			CurrentPassword string `json:"current_password"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)) != nil {
+			respondError(c, http.StatusUnauthorized, "INVALID_CREDENTIALS", "現在のパスワードが違います")
+			return
+		}
+		secret, err := newTOTPSecret()
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to generate secret")
+			return
+		}
+		if err := totpRepo.Begin(c.Request.Context(), user.ID, secret); err != nil {
+			if errors.Is(err, ErrTOTPEnabled) {
+				respondError(c, http.StatusConflict, "CONFLICT", "二要素認証は既に有効です")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to start two-factor setup")
+			return
+		}
+		// otpauth_uri を QR コードにして認証アプリで読み取る
+		c.JSON(http.StatusOK, gin.H{
+			"secret":      secret,
+			"otpauth_uri": totpURI(cfg.TOTPIssuer, user.Username, secret),
+		})
+	})
+
+	api.POST("/users/me/totp/enable", func(c *gin.Context) {
+		user, ok := requireSessionStaff(c)
+		if !ok {
+			return
+		}
+		var req struct {
+			Code string `json:"code"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		ctx := c.Request.Context()
+		t, err := totpRepo.Get(ctx, user.ID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to load two-factor settings")
+			return
+		}
+		if t == nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "先に setup で秘密鍵を発行してください")
+			return
+		}
+		if t.EnabledAt != nil {
+			respondError(c, http.StatusConflict, "CONFLICT", "二要素認証は既に有効です")
+			return
+		}
+		step, valid := verifyTOTP(t.Secret, req.Code, time.Now(), t.LastUsedStep)
+		if !valid {
+			respondError(c, http.StatusBadRequest, "INVALID_TOTP_CODE", "認証コードが違います")
+			return
+		}
+		codes, hashes := newRecoveryCodes()
+		if err := totpRepo.Enable(ctx, user.ID, step, hashes); err != nil {
+			if errors.Is(err, ErrTOTPEnabled) {
+				respondError(c, http.StatusConflict, "CONFLICT", "二要素認証は既に有効です")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to enable two-factor authentication")
+			return
+		}
+		// リカバリーコードはこの応答でしか表示しない
+		c.JSON(http.StatusOK, gin.H{"enabled": true, "recovery_codes": codes})
+	})
+
+	api.POST("/users/me/totp/disable", func(c *gin.Context) {
+		user, ok := requireSessionStaff(c)
+		if !ok {
+			return
+		}
+		var req struct {
+			CurrentPassword string `json:"current_password"`
+			Code            string `json:"code"`
+			RecoveryCode    string `json:"recovery_code"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)) != nil {
+			respondError(c, http.StatusUnauthorized, "INVALID_CREDENTIALS", "現在のパスワードが違います")
+			return
+		}
+		ctx := c.Request.Context()
+		valid, err := checkSecondFactor(ctx, totpRepo, user.ID, req.Code, req.RecoveryCode)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to verify code")
+			return
+		}
+		if !valid {
+			respondError(c, http.StatusBadRequest, "INVALID_TOTP_CODE", "認証コードが違います")
+			return
+		}
+		if err := totpRepo.Disable(ctx, user.ID); err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to disable two-factor authentication")
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	// リカバリーコードを作り直す（古いコードはすべて無効になる）
+	api.POST("/users/me/totp/recovery_codes", func(c *gin.Context) {
+		user, ok := requireSessionStaff(c)
+		if !ok {
+			return
+		}
+		var req struct {
+			Code string `json:"code"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		ctx := c.Request.Context()
+		valid, err := checkSecondFactor(ctx, totpRepo, user.ID, req.Code, "")
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to verify code")
+			return
+		}
+		if !valid {
+			respondError(c, http.StatusBadRequest, "INVALID_TOTP_CODE", "認証コードが違います")
+			return
+		}
+		codes, hashes := newRecoveryCodes()
+		if err := totpRepo.ReplaceRecoveryCodes(ctx, user.ID, hashes); err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to regenerate recovery codes")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+	})
+
+	// パスワードログインの 2 段階目。/auth/login が返した login_token と認証コード（またはリカバリーコード）を送る
+	api.POST("/auth/login/totp", func(c *gin.Context) {
+		var req struct {
+			LoginToken   string `json:"login_token"`
+			Code         string `json:"code"`
+			RecoveryCode string `json:"recovery_code"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		ctx := c.Request.Context()
+		token := strings.TrimSpace(req.LoginToken)
+		userID, username, err := pendingTOTPLogin(ctx, redisClient, token)
+		if err != nil {
+			if errors.Is(err, ErrTOTPLogin) {
+				respondError(c, http.StatusUnauthorized, "TOTP_LOGIN_EXPIRED", "ログインの有効期限が切れました。もう一度パスワードを入力してください")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to load login")
+			return
+		}
+		valid, err := checkSecondFactor(ctx, totpRepo, userID, req.Code, req.RecoveryCode)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to verify code")
+			return
+		}
+		if !valid {
+			respondError(c, http.StatusUnauthorized, "INVALID_TOTP_CODE", "認証コードが違います")
+			return
+		}
+		finishTOTPLogin(ctx, redisClient, token)
+		user, err := userRepo.FindByUsername(ctx, username)
+		if err != nil || user.ID != userID {
+			respondError(c, http.StatusUnauthorized, "TOTP_LOGIN_EXPIRED", "ログインの有効期限が切れました。もう一度パスワードを入力してください")
+			return
+		}
+		if strings.TrimSpace(req.RecoveryCode) != "" {
+			log.Printf("[totp] user %d logged in with a recovery code", user.ID)
+		}
+		if err := startUserSession(c, cfg, store, user.Username, user.Role); err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to set session")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"user": gin.H{"userid": user.Username, "role": user.Role, "permissions": RolePermissions(user.Role)}})
+	})
+}
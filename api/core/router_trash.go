@@ -0,0 +1,103 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// registerTrashRoutes wires admin deletion of problems / users and the recycle bin.
+// Notices are trashed by the existing DELETE /notices/:id. DELETE /users/:id with a mode is
+// handed to eraseUser (see userErasureHandler).
+func registerTrashRoutes(admin *gin.RouterGroup, trashRepo TrashRepository, userRepo UserRepository, eraseUser gin.HandlerFunc) {
+	admin.DELETE("/problems/:id", RequirePermission(PermProblemsWrite), func(c *gin.Context) {
+		trashHandler(c, trashRepo, userRepo, TrashKindProblem, "problem")
+	})
+
+	admin.DELETE("/users/:id", RequirePermission(PermUsersManage), func(c *gin.Context) {
+		if c.Query("mode") != "" {
+			eraseUser(c)
+			return
+		}
+		trashHandler(c, trashRepo, userRepo, TrashKindUser, "user")
+	})
+
+	admin = admin.Group("", RequirePermission(PermTrashManage))
+
+	// kind=notice|problem|user（省略時はすべて）
+	admin.GET("/trash", func(c *gin.Context) {
+		items, err := trashRepo.List(c.Request.Context(), c.Query("kind"))
+		if err != nil {
+			if errors.Is(err, ErrUnknownTrashKind) {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "kind は notice・problem・user のいずれかを指定してください")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch trash")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"items": items})
+	})
+
+	admin.POST("/trash/:kind/:id/restore", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		if err := trashRepo.Restore(c.Request.Context(), c.Param("kind"), id); err != nil {
+			respondTrashError(c, err, "failed to restore")
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	// 保持期間を待たずに完全削除する
+	admin.DELETE("/trash/:kind/:id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		if err := trashRepo.Purge(c.Request.Context(), c.Param("kind"), id); err != nil {
+			respondTrashError(c, err, "failed to purge")
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
+
+// trashHandler moves the :id record of kind to the recycle bin on behalf of the current admin.
+func trashHandler(c *gin.Context, trashRepo TrashRepository, userRepo UserRepository, kind, label string) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	user, ok := requireUser(c, userRepo)
+	if !ok {
+		return
+	}
+	if kind == TrashKindUser && id == user.ID {
+		respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "自分自身は削除できません")
+		return
+	}
+	if err := trashRepo.Trash(c.Request.Context(), kind, id, &user.ID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", label+" not found")
+			return
+		}
+		respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to delete "+label)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func respondTrashError(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, ErrUnknownTrashKind):
+		respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "kind は notice・problem・user のいずれかを指定してください")
+	case errors.Is(err, pgx.ErrNoRows):
+		respondError(c, http.StatusNotFound, "NOT_FOUND", "item not found in trash")
+	default:
+		respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", msg)
+	}
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// registerUserAdminRoutes wires the admin update of an account (role, enabled state, userid).
+func registerUserAdminRoutes(admin *gin.RouterGroup, adminRepo UserAdminRepository, store *RedisSessionStore, userRepo UserRepository) {
+	admin.PATCH("/users/:userid", func(c *gin.Context) {
+		actor, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		var req UserUpdateInput
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		ctx := c.Request.Context()
+		target, err := adminRepo.Find(ctx, c.Param("userid"))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "ユーザーが見つかりません")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch user")
+			return
+		}
+		// 自分を締め出さないよう、自分のロール変更と無効化は受け付けない
+		if target.ID == actor.ID && ((req.Role != nil && *req.Role != actor.Role) || (req.Disabled != nil && *req.Disabled)) {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "自分自身のロール変更・無効化はできません")
+			return
+		}
+		// 自分より強い権限のユーザーは変更できず、自分より強いロールも付与できない
+		role := sessionRole(c)
+		if !roleCovers(role, target.Role) || (req.Role != nil && !roleCovers(role, *req.Role)) {
+			respondError(c, http.StatusForbidden, "FORBIDDEN", "このユーザーを変更する権限がありません")
+			return
+		}
+
+		res, err := adminRepo.Update(ctx, target.Username, req)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrUserUpdateInput):
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			case errors.Is(err, ErrUsernameTaken):
+				respondError(c, http.StatusConflict, "CONFLICT", "userid already exists")
+			case errors.Is(err, ErrLastAdmin):
+				respondError(c, http.StatusConflict, "LAST_ADMIN", "有効な管理者がいなくなるため変更できません")
+			case errors.Is(err, pgx.ErrNoRows):
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "ユーザーが見つかりません")
+			default:
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to update user")
+			}
+			return
+		}
+		// セッションはユーザー名とロールを持つので、変更後は古いセッションを失効させて再ログインさせる
+		u := res.User
+		if u.Disabled || u.Role != res.FormerRole || u.Username != res.FormerUserID {
+			if _, err := store.RevokeUserSessions(ctx, res.FormerUserID, ""); err != nil {
+				log.Printf("[users] revoke sessions of %s failed: %v", res.FormerUserID, err)
+			}
+		}
+		if u.Username != res.FormerUserID {
+			if err := store.MoveSuspended(ctx, res.FormerUserID, u.Username); err != nil {
+				log.Printf("[users] move suspension of %s failed: %v", res.FormerUserID, err)
+			}
+		}
+		log.Printf("[users] %s updated by %s: userid=%s role=%s disabled=%v", res.FormerUserID, actor.Username, u.Username, u.Role, u.Disabled)
+		c.JSON(http.StatusOK, u)
+	})
+}
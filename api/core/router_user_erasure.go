@@ -0,0 +1,73 @@
+package core
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// userErasureHandler serves DELETE /admin/users/:id?mode=hard|anonymize&confirm=true (see
+// UserErasureRepository); without mode the user goes to the recycle bin as before.
+func userErasureHandler(cfg Config, erasureRepo UserErasureRepository, store *RedisSessionStore, userRepo UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		admin, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		mode := c.Query("mode")
+		if mode != UserEraseHard && mode != UserEraseAnonymize {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "mode は hard・anonymize のいずれかを指定してください")
+			return
+		}
+		if id == admin.ID {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "自分自身は削除できません")
+			return
+		}
+		// 取り消せない操作なので明示的な確認を求める
+		if c.Query("confirm") != "true" {
+			respondError(c, http.StatusConflict, "CONFIRMATION_REQUIRED", "この操作は取り消せません。confirm=true を指定してください")
+			return
+		}
+		ctx := c.Request.Context()
+		e, err := erasureRepo.Erase(ctx, id, mode)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "user not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to delete user")
+			return
+		}
+		// ファイルとセッションの削除はベストエフォート（データベースからは既に消えている）
+		for _, sid := range e.Submissions {
+			if err := os.RemoveAll(filepath.Join(cfg.SubmissionDir, strconv.FormatInt(sid, 10))); err != nil {
+				log.Printf("[users] erase %d: remove files of submission %d failed: %v", id, sid, err)
+			}
+		}
+		revoked, err := store.RevokeUserSessions(ctx, e.FormerUserID, "")
+		if err != nil {
+			log.Printf("[users] erase %d: revoke sessions failed: %v", id, err)
+		}
+		if err := store.ClearSuspended(ctx, e.FormerUserID); err != nil {
+			log.Printf("[users] erase %d: clear suspension failed: %v", id, err)
+		}
+		log.Printf("[users] %s user %d (%d submissions) by %s", mode, id, len(e.Submissions), admin.Username)
+		c.JSON(http.StatusOK, gin.H{
+			"user_id":          e.UserID,
+			"mode":             e.Mode,
+			"userid":           e.Username,
+			"submissions":      len(e.Submissions),
+			"revoked_sessions": revoked,
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/sessions"
+	"github.com/redis/go-redis/v9"
+)
+
+type stubAdminRepo struct{ UserRepository }
+
+func (stubAdminRepo) FindByUsername(_ context.Context, username string) (*UserRecord, error) {
+	return &UserRecord{ID: 1, Username: username}, nil
+}
+
+type stubErasureRepo struct{ modes []string }
+
+func (s *stubErasureRepo) Erase(_ context.Context, id int64, mode string) (*UserErasure, error) {
+	s.modes = append(s.modes, mode)
+	return &UserErasure{UserID: id, Mode: mode, FormerUserID: "bob", Submissions: []int64{7}}, nil
+}
+
+func TestUserErasureHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "7"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &stubErasureRepo{}
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		sess := sessions.NewSession(sessions.NewCookieStore([]byte("k")), sessionName)
+		sess.Values["userid"] = "admin"
+		c.Set("session", sess)
+	})
+	r.DELETE("/users/:id", userErasureHandler(Config{SubmissionDir: dir}, repo, NewRedisSessionStore(client, []byte("k")), stubAdminRepo{}))
+	del := func(target string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, target, nil))
+		return w.Code
+	}
+
+	if code := del("/users/2?mode=purge&confirm=true"); code != http.StatusBadRequest {
+		t.Fatalf("unknown mode: got %d", code)
+	}
+	if code := del("/users/1?mode=hard&confirm=true"); code != http.StatusBadRequest {
+		t.Fatalf("self deletion: got %d", code)
+	}
+	if code := del("/users/2?mode=hard"); code != http.StatusConflict || len(repo.modes) != 0 {
+		t.Fatalf("missing confirm: got %d, erased %v", code, repo.modes)
+	}
+	if code := del("/users/2?mode=hard&confirm=true"); code != http.StatusOK {
+		t.Fatalf("hard delete: got %d", code)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "7")); !os.IsNotExist(err) {
+		t.Fatalf("submission files should be removed, stat err=%v", err)
+	}
+}
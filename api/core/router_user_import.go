@@ -0,0 +1,195 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// registerUserImportRoutes wires bulk user creation from a CSV (userid[,password]) as background
+// jobs: POST /users/bulk starts a job, its progress is polled, a job stopped by an error is resumed
+// from the rows still pending, and the result CSV lists the outcome of every row with the generated
+// passwords. Uploading the same CSV again returns the existing job instead of importing it twice.
+func registerUserImportRoutes(admin *gin.RouterGroup, repo UserImportJobRepository, userRepo UserRepository) {
+	findJob := func(c *gin.Context) (int64, bool) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return 0, false
+		}
+		if _, err := repo.Get(c.Request.Context(), id); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "import job not found")
+				return 0, false
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch import job")
+			return 0, false
+		}
+		return id, true
+	}
+
+	admin.POST("/users/bulk", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "file フィールドに CSV を指定してください")
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "ファイルを開けません")
+			return
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "ファイルを読み取れません")
+			return
+		}
+		rows, err := parseUserImportCSV(bytes.NewReader(data))
+		if err != nil {
+			if errors.Is(err, ErrUserImportInput) {
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to read csv")
+			return
+		}
+
+		ctx := c.Request.Context()
+		sum := sha256.Sum256(data)
+		digest := hex.EncodeToString(sum[:])
+		// 同じ CSV の再送（タイムアウト後のリトライなど）は既存のジョブを返す。force=true で新しく取り込む
+		if c.Query("force") != "true" {
+			existing, err := repo.FindByContent(ctx, digest)
+			if err == nil {
+				c.JSON(http.StatusOK, gin.H{"job": existing, "duplicate": true})
+				return
+			}
+			if !errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to look up import jobs")
+				return
+			}
+		}
+		job, err := repo.Create(ctx, user.ID, fileHeader.Filename, digest, rows)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to create import job")
+			return
+		}
+		startUserImport(repo, job.ID)
+		setAuditTarget(c, "user_import_jobs", job.ID)
+		setAuditSummary(c, "bulk job=%d rows=%d", job.ID, job.Total)
+		c.JSON(http.StatusAccepted, gin.H{"job": job, "duplicate": false})
+	})
+
+	admin.GET("/users/bulk/jobs", func(c *gin.Context) {
+		items, err := repo.List(c.Request.Context(), maxUserImportJobsListed)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch import jobs")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"items": items})
+	})
+
+	admin.GET("/users/bulk/jobs/:id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		job, err := repo.Get(c.Request.Context(), id)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "import job not found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch import job")
+			return
+		}
+		c.JSON(http.StatusOK, job)
+	})
+
+	// 失敗で止まったジョブ、または API の再起動などで進まなくなったジョブを未処理の行から再開する
+	admin.POST("/users/bulk/jobs/:id/resume", func(c *gin.Context) {
+		id, ok := findJob(c)
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		if err := repo.Claim(ctx, id); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusConflict, "CONFLICT", "完了済みか実行中のジョブです")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to resume import job")
+			return
+		}
+		startUserImport(repo, id)
+		job, err := repo.Get(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch import job")
+			return
+		}
+		c.JSON(http.StatusAccepted, job)
+	})
+
+	// 全行の結果（生成したパスワードを含む）。format=credentials は配布用の userid,password のみ
+	admin.GET("/users/bulk/jobs/:id/result.csv", func(c *gin.Context) {
+		id, ok := findJob(c)
+		if !ok {
+			return
+		}
+		rows, err := repo.Rows(c.Request.Context(), id)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch import rows")
+			return
+		}
+		buf := &bytes.Buffer{}
+		if c.Query("format") == "credentials" {
+			credentials := []BulkUserCredential{}
+			for _, row := range rows {
+				if row.Generated && row.Status == UserImportRowCreated {
+					credentials = append(credentials, BulkUserCredential{RowNumber: row.RowNumber, UserID: row.UserID, Password: row.Password})
+				}
+			}
+			err = writeBulkCredentialsCSV(buf, credentials)
+		} else {
+			err = writeUserImportResultCSV(buf, rows)
+		}
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to build csv")
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=users-%d-%s.csv", id, time.Now().Format("20060102-150405")))
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+	})
+
+	// ジョブと、結果 CSV のために残している生成パスワードを削除する（作成したユーザーはそのまま）
+	admin.DELETE("/users/bulk/jobs/:id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		if err := repo.Delete(c.Request.Context(), id); err != nil {
+			switch {
+			case errors.Is(err, ErrUserImportRunning):
+				respondError(c, http.StatusConflict, "CONFLICT", "実行中のジョブは削除できません")
+			case errors.Is(err, pgx.ErrNoRows):
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "import job not found")
+			default:
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to delete import job")
+			}
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// registerUserSuspensionRoutes wires the admin suspension (ban) of accounts. The reason is required
+// to suspend and kept with the actor in the history.
+func registerUserSuspensionRoutes(admin *gin.RouterGroup, repo UserSuspensionRepository, store *RedisSessionStore, userRepo UserRepository) {
+	admin.GET("/users/:userid/suspension", func(c *gin.Context) {
+		s, err := repo.Get(c.Request.Context(), c.Param("userid"))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "ユーザーが見つかりません")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch suspension")
+			return
+		}
+		c.JSON(http.StatusOK, s)
+	})
+
+	admin.POST("/users/:userid/suspend", suspensionHandler(repo, store, userRepo, true))
+	admin.POST("/users/:userid/unsuspend", suspensionHandler(repo, store, userRepo, false))
+}
+
+// suspensionHandler suspends (suspend=true) or reinstates the account named by :userid.
+func suspensionHandler(repo UserSuspensionRepository, store *RedisSessionStore, userRepo UserRepository, suspend bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		var req struct {
+			Reason string `json:"reason"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid json")
+			return
+		}
+		ctx := c.Request.Context()
+		target, err := userRepo.FindByUsername(ctx, c.Param("userid"))
+		if err != nil {
+			respondError(c, http.StatusNotFound, "NOT_FOUND", "ユーザーが見つかりません")
+			return
+		}
+		if suspend && target.ID == actor.ID {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "自分自身は利用停止できません")
+			return
+		}
+		// 自分より強い権限のユーザーは停止・解除できない
+		if !roleCovers(sessionRole(c), target.Role) {
+			respondError(c, http.StatusForbidden, "FORBIDDEN", "このユーザーを変更する権限がありません")
+			return
+		}
+
+		var s *UserSuspension
+		if suspend {
+			s, err = repo.Suspend(ctx, target.Username, actor.ID, req.Reason)
+		} else {
+			s, err = repo.Unsuspend(ctx, target.Username, actor.ID, req.Reason)
+		}
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrUserSuspensionInput):
+				// 停止されていないアカウントに残ったマーク（前回の解除で消せなかったもの）は消しておく
+				if !suspend && !target.Suspended {
+					if err := store.ClearSuspended(ctx, target.Username); err != nil {
+						log.Printf("[suspension] clear stale mark for %q: %v", target.Username, err)
+					}
+				}
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			case errors.Is(err, ErrLastAdmin):
+				respondError(c, http.StatusConflict, "LAST_ADMIN", "有効な管理者がいなくなるため停止できません")
+			case errors.Is(err, pgx.ErrNoRows):
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "ユーザーが見つかりません")
+			default:
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to update suspension")
+			}
+			return
+		}
+		// 既存のセッションは SessionMiddleware が ACCOUNT_SUSPENDED で拒否する
+		if suspend {
+			err = store.MarkSuspended(ctx, s.Username, s.Reason)
+			log.Printf("[suspension] account %q suspended by %s: %s", s.Username, actor.Username, s.Reason)
+		} else {
+			err = store.ClearSuspended(ctx, s.Username)
+			log.Printf("[suspension] account %q reinstated by %s", s.Username, actor.Username)
+		}
+		if err != nil {
+			// データベースには記録済み。マークを直せるよう同じ操作の再送を促す
+			log.Printf("[suspension] update mark for %q: %v", s.Username, err)
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "利用停止の状態は保存されましたが、セッションへの反映に失敗しました。再度実行してください")
+			return
+		}
+		c.JSON(http.StatusOK, s)
+	}
+}
@@ -0,0 +1,22 @@
+package core
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// registerWebSocketRoutes wires /ws, which pushes the user's verdicts and new notices.
+// The connection is authenticated with the session cookie like any other request.
+func registerWebSocketRoutes(api *gin.RouterGroup, hub *WSHub, userRepo UserRepository) {
+	api.GET("/ws", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		// 失敗時は Upgrade がエラー応答を書く
+		conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			return
+		}
+		hub.serve(conn, user.ID)
+	})
+}
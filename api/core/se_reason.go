@@ -0,0 +1,71 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net"
+	"net/url"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SE (System Error) reason codes stored in submission_results.se_reason.
+const (
+	SEReasonJudgeUnreachable = "judge_unreachable" // go-judge に接続できない
+	SEReasonJudgeError       = "judge_error"       // go-judge がエラーを返した
+	SEReasonCompileInfra     = "compile_infra"     // コンパイル（チェッカー含む）の基盤側の失敗
+	SEReasonDatabase         = "database"
+	SEReasonTimeout          = "timeout"
+	SEReasonStorage          = "storage"        // 提出ファイルの読み書き
+	SEReasonProblemConfig    = "problem_config" // テストケース未設定など問題側の不備
+	SEReasonUnknown          = "unknown"
+)
+
+// seError tags an error with the stage that failed so ClassifySystemError can report it.
+type seError struct {
+	reason string
+	err    error
+}
+
+func (e *seError) Error() string { return e.err.Error() }
+func (e *seError) Unwrap() error { return e.err }
+
+// withSEReason tags err with reason; nil stays nil.
+func withSEReason(reason string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &seError{reason: reason, err: err}
+}
+
+// ClassifySystemError maps a judging failure to an SE reason code. Timeouts and unreachable
+// judges are reported as such whatever stage they happened in; otherwise the stage tag wins.
+func ClassifySystemError(err error) string {
+	if err == nil {
+		return ""
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return SEReasonTimeout
+	}
+	var pgErr *pgconn.PgError
+	var connErr *pgconn.ConnectError
+	if errors.As(err, &pgErr) || errors.As(err, &connErr) {
+		return SEReasonDatabase
+	}
+	// 判定ワーカーの HTTP 通信先は go-judge だけ
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return SEReasonJudgeUnreachable
+	}
+	var tagged *seError
+	if errors.As(err, &tagged) {
+		return tagged.reason
+	}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return SEReasonStorage
+	}
+	return SEReasonUnknown
+}
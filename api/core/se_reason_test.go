@@ -0,0 +1,38 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestClassifySystemError(t *testing.T) {
+	_, readErr := os.ReadFile("/nonexistent/source.cpp")
+	unreachable := &url.Error{Op: "Post", URL: "http://go-judge:5050/run", Err: errors.New("connection refused")}
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{withSEReason(SEReasonCompileInfra, errors.New("judge returned status 500")), SEReasonCompileInfra},
+		{withSEReason(SEReasonCompileInfra, unreachable), SEReasonJudgeUnreachable},
+		{withSEReason(SEReasonJudgeError, fmt.Errorf("run: %w", context.DeadlineExceeded)), SEReasonTimeout},
+		{withSEReason(SEReasonDatabase, errors.New("conn closed")), SEReasonDatabase},
+		{&pgconn.PgError{Code: "57P01"}, SEReasonDatabase},
+		{readErr, SEReasonStorage},
+		{errors.New("boom"), SEReasonUnknown},
+	}
+	for _, tc := range cases {
+		if got := ClassifySystemError(tc.err); got != tc.want {
+			t.Errorf("ClassifySystemError(%v) = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+	if !errors.Is(withSEReason(SEReasonDatabase, ErrSubmissionNotPending), ErrSubmissionNotPending) {
+		t.Fatal("tagged errors must still match their cause")
+	}
+}
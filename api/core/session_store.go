@@ -0,0 +1,275 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	sessionKeyPrefix     = "session:"      // session:<id> → セッションの値と接続情報（ハッシュ）
+	sessionUserKeyPrefix = "session:user:" // session:user:<userid> → そのユーザーのセッション ID（集合）
+)
+
+type sessionClientIPKey struct{}
+
+// RedisSessionStore keeps session values in Redis; the cookie only carries the signed session ID.
+// Unlike cookie-only sessions they can be listed per user and revoked before they expire.
+type RedisSessionStore struct {
+	client *redis.Client
+	codecs []securecookie.Codec
+	// suspensions reads the suspension state from the database when Redis has no mark (see UseSuspensionSource).
+	suspensions SuspensionSource
+}
+
+// SessionInfo describes one login of a user, as shown in GET /users/me/sessions.
+// ID is derived from the session ID so the list never exposes the value in the cookie.
+type SessionInfo struct {
+	ID         string    `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	Current    bool      `json:"current"`
+
+	sessionID string
+}
+
+func NewRedisSessionStore(client *redis.Client, keyPairs ...[]byte) *RedisSessionStore {
+	return &RedisSessionStore{client: client, codecs: securecookie.CodecsFromPairs(keyPairs...)}
+}
+
+// withSessionClientIP records the client address (as resolved by gin) for Save.
+func withSessionClientIP(r *http.Request, ip string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), sessionClientIPKey{}, ip))
+}
+
+func sessionClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(sessionClientIPKey{}).(string); ok && ip != "" {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// publicSessionID is the identifier of a session in listings and revoke requests.
+func publicSessionID(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Get returns the session cached for the request (see sessions.Registry).
+func (s *RedisSessionStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New loads the session named by the cookie. An unknown, expired or revoked ID yields a new empty
+// session with no ID; it is never reused, so a fresh ID is issued on the next Save.
+func (s *RedisSessionStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	session.Options = &sessions.Options{Path: "/", MaxAge: sessionMaxAge}
+	session.IsNew = true
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+	var id string
+	if err := securecookie.DecodeMulti(name, cookie.Value, &id, s.codecs...); err != nil {
+		// 署名の合わない（鍵の変更前の）cookie は新しいセッションとして扱う
+		return session, nil
+	}
+	data, err := s.client.HGet(r.Context(), sessionKeyPrefix+id, "values").Bytes()
+	if errors.Is(err, redis.Nil) {
+		return session, nil
+	}
+	if err != nil {
+		return session, err
+	}
+	if err := (securecookie.GobEncoder{}).Deserialize(data, &session.Values); err != nil {
+		return session, nil
+	}
+	session.ID = id
+	session.IsNew = false
+	return session, nil
+}
+
+// Save writes the session to Redis and sets the cookie. MaxAge < 0 deletes the session.
+// An anonymous session with no values is not stored.
+func (s *RedisSessionStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	ctx := r.Context()
+	if session.Options != nil && session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := s.destroy(ctx, session.ID); err != nil {
+				return err
+			}
+			session.ID = ""
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+	if session.ID != "" && !session.IsNew {
+		// 読み込んだ後に失効させられたセッションを書き戻して復活させない
+		n, err := s.client.Exists(ctx, sessionKeyPrefix+session.ID).Result()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			session.ID = ""
+			session.Values = map[interface{}]interface{}{}
+		}
+	}
+	if session.ID == "" {
+		if len(session.Values) == 0 {
+			return nil
+		}
+		session.ID = randomHex(32)
+		session.IsNew = true
+	}
+
+	data, err := securecookie.GobEncoder{}.Serialize(session.Values)
+	if err != nil {
+		return err
+	}
+	ttl := time.Duration(sessionMaxAge) * time.Second
+	if session.Options != nil && session.Options.MaxAge > 0 {
+		ttl = time.Duration(session.Options.MaxAge) * time.Second
+	}
+	userid, _ := session.Values["userid"].(string)
+	userAgent := r.UserAgent()
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+	now := time.Now().Unix()
+	key := sessionKeyPrefix + session.ID
+	pipe := s.client.TxPipeline()
+	pipe.HSetNX(ctx, key, "created_at", now)
+	pipe.HSet(ctx, key, "values", data, "userid", userid, "last_seen_at", now, "ip", sessionClientIP(r), "user_agent", userAgent)
+	pipe.Expire(ctx, key, ttl)
+	if userid != "" {
+		// 索引は最後に使われたセッションと同じだけ残す（古いセッションほど先に切れる）
+		pipe.SAdd(ctx, sessionUserKeyPrefix+userid, session.ID)
+		pipe.Expire(ctx, sessionUserKeyPrefix+userid, ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// renew discards the stored session so the next Save issues a new ID (done on login, so an ID
+// handed out before authentication never becomes a logged-in session).
+func (s *RedisSessionStore) renew(ctx context.Context, session *sessions.Session) error {
+	if session.ID != "" {
+		if err := s.destroy(ctx, session.ID); err != nil {
+			return err
+		}
+	}
+	session.ID = ""
+	session.IsNew = true
+	session.Values = map[interface{}]interface{}{}
+	return nil
+}
+
+func (s *RedisSessionStore) destroy(ctx context.Context, id string) error {
+	key := sessionKeyPrefix + id
+	userid, err := s.client.HGet(ctx, key, "userid").Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+	pipe := s.client.TxPipeline()
+	pipe.Del(ctx, key)
+	if userid != "" {
+		pipe.SRem(ctx, sessionUserKeyPrefix+userid, id)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// ListUserSessions returns the live sessions of a user, most recently used first.
+// currentID (the raw ID of the requesting session, may be empty) is marked Current.
+func (s *RedisSessionStore) ListUserSessions(ctx context.Context, userid, currentID string) ([]SessionInfo, error) {
+	indexKey := sessionUserKeyPrefix + userid
+	ids, err := s.client.SMembers(ctx, indexKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]SessionInfo, 0, len(ids))
+	for _, id := range ids {
+		fields, err := s.client.HGetAll(ctx, sessionKeyPrefix+id).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) == 0 || fields["userid"] != userid {
+			// 期限切れ、または別のユーザーとしてログインし直したセッション
+			s.client.SRem(ctx, indexKey, id)
+			continue
+		}
+		created, _ := strconv.ParseInt(fields["created_at"], 10, 64)
+		lastSeen, _ := strconv.ParseInt(fields["last_seen_at"], 10, 64)
+		list = append(list, SessionInfo{
+			ID:         publicSessionID(id),
+			CreatedAt:  time.Unix(created, 0).UTC(),
+			LastSeenAt: time.Unix(lastSeen, 0).UTC(),
+			IP:         fields["ip"],
+			UserAgent:  fields["user_agent"],
+			Current:    id == currentID,
+			sessionID:  id,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].LastSeenAt.After(list[j].LastSeenAt) })
+	return list, nil
+}
+
+// RevokeUserSession deletes one session of a user by its public ID. It reports false when the
+// user has no such session.
+func (s *RedisSessionStore) RevokeUserSession(ctx context.Context, userid, publicID string) (bool, error) {
+	list, err := s.ListUserSessions(ctx, userid, "")
+	if err != nil {
+		return false, err
+	}
+	for _, info := range list {
+		if info.ID == publicID {
+			return true, s.destroy(ctx, info.sessionID)
+		}
+	}
+	return false, nil
+}
+
+// RevokeUserSessions deletes every session of a user except exceptID (a raw session ID, may be
+// empty) and returns how many were deleted.
+func (s *RedisSessionStore) RevokeUserSessions(ctx context.Context, userid, exceptID string) (int, error) {
+	list, err := s.ListUserSessions(ctx, userid, "")
+	if err != nil {
+		return 0, err
+	}
+	revoked := 0
+	for _, info := range list {
+		if info.sessionID == exceptID {
+			continue
+		}
+		if err := s.destroy(ctx, info.sessionID); err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisSessionStoreRevoke(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	store := NewRedisSessionStore(client, []byte("test-secret-test-secret-test-sec"))
+	ctx := context.Background()
+
+	login := func() *http.Cookie {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		sess, err := store.Get(req, sessionName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sess.Values["userid"] = "alice"
+		w := httptest.NewRecorder()
+		if err := sess.Save(req, w); err != nil {
+			t.Fatal(err)
+		}
+		return w.Result().Cookies()[0]
+	}
+	load := func(cookie *http.Cookie) string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(cookie)
+		sess, err := store.Get(req, sessionName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		userid, _ := sess.Values["userid"].(string)
+		return userid
+	}
+
+	first, second := login(), login()
+	if load(first) != "alice" || load(second) != "alice" {
+		t.Fatal("expected both sessions to be restored from redis")
+	}
+	list, err := store.ListUserSessions(ctx, "alice", "")
+	if err != nil || len(list) != 2 {
+		t.Fatalf("expected 2 sessions, got %v %v", list, err)
+	}
+
+	found, err := store.RevokeUserSession(ctx, "alice", list[0].ID)
+	if err != nil || !found {
+		t.Fatalf("revoke: %v %v", found, err)
+	}
+	if n, _ := store.RevokeUserSessions(ctx, "alice", ""); n != 1 {
+		t.Fatalf("expected the remaining session to be revoked, got %d", n)
+	}
+	if load(first) != "" || load(second) != "" {
+		t.Fatal("revoked sessions must not be restored")
+	}
+}
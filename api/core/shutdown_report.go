@@ -0,0 +1,120 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// ShutdownReportsKey は停止レポートを新しい順に保持する Redis リスト。
+	ShutdownReportsKey = "shutdown_reports"
+	maxShutdownReports = 50
+	// ShutdownDrainTimeout は停止時に処理中リクエストの完了・ジョブの差し戻しを待つ上限。
+	ShutdownDrainTimeout = 10 * time.Second
+)
+
+// ShutdownReport はプロセス停止時のキューの状態と、停止で中断したジョブの扱いを記録する。
+type ShutdownReport struct {
+	Process    string    `json:"process"` // api|worker
+	InstanceID string    `json:"instance_id"`
+	Hostname   string    `json:"hostname"`
+	StartedAt  time.Time `json:"started_at"`
+	StoppedAt  time.Time `json:"stopped_at"`
+	Pending    int64     `json:"pending"`    // 停止時点で pending に残っているジョブ数
+	Processing int64     `json:"processing"` // 停止時点で processing にあるジョブ数（他ワーカーの実行中を含む）
+	Requeued   []string  `json:"requeued"`   // 中断して pending に戻したジョブ
+	Abandoned  []string  `json:"abandoned"`  // 戻せなかったジョブ（可視タイムアウト後に他ワーカーが回収する）
+	Error      string    `json:"error,omitempty"`
+}
+
+// Requeue は processing にある job を pending の取り出し側へ戻す。
+// 既に ack 済み・回収済みで processing に無ければ false を返す。
+func (q *RedisQueue) Requeue(ctx context.Context, processingKey, pendingKey, job string) (bool, error) {
+	script := redis.NewScript(`
+local removed = redis.call('ZREM', KEYS[1], ARGV[1])
+if removed == 1 then
+  redis.call('RPUSH', KEYS[2], ARGV[1])
+end
+return removed
+`)
+	n, err := script.Run(ctx, q.client, []string{processingKey, pendingKey}, job).Int()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// DrainInterruptedJobs は停止で中断したジョブをリトライ回数を増やさずに pending へ戻す。
+func DrainInterruptedJobs(ctx context.Context, queue *RedisQueue, repo SubmissionRepository, jobs []string) (requeued, abandoned []string) {
+	requeued, abandoned = []string{}, []string{}
+	for _, job := range jobs {
+		held, err := queue.Requeue(ctx, ProcessingQueueKey, PendingQueueKey, job)
+		if err != nil {
+			log.Printf("[shutdown] requeue job %s failed: %v", job, err)
+			abandoned = append(abandoned, job)
+			continue
+		}
+		if !held {
+			log.Printf("[shutdown] job %s was already reclaimed", job)
+			continue
+		}
+		if id, err := strconv.ParseInt(job, 10, 64); err == nil {
+			if err := repo.MarkStatus(ctx, id, "pending"); err != nil {
+				log.Printf("[shutdown] mark job %s pending failed: %v", job, err)
+			}
+		}
+		requeued = append(requeued, job)
+	}
+	return requeued, abandoned
+}
+
+// SaveShutdownReport はキューの残数を埋めてログに出し、Redis に保存する。
+func SaveShutdownReport(ctx context.Context, client *redis.Client, r ShutdownReport) error {
+	r.StoppedAt = time.Now()
+	if r.Requeued == nil {
+		r.Requeued = []string{}
+	}
+	if r.Abandoned == nil {
+		r.Abandoned = []string{}
+	}
+	queue, err := NewMetricsService(client).Queue(ctx)
+	if err != nil {
+		r.Error = "queue metrics unavailable: " + err.Error()
+	} else {
+		r.Pending, r.Processing = queue.Pending, queue.Processing
+	}
+	log.Printf("[shutdown] %s %s: pending=%d processing=%d requeued=%d abandoned=%d %s",
+		r.Process, r.InstanceID, r.Pending, r.Processing, len(r.Requeued), len(r.Abandoned), r.Error)
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	pipe := client.TxPipeline()
+	pipe.LPush(ctx, ShutdownReportsKey, data)
+	pipe.LTrim(ctx, ShutdownReportsKey, 0, maxShutdownReports-1)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// ListShutdownReports は新しい順に最大 limit 件の停止レポートを返す。
+func ListShutdownReports(ctx context.Context, client *redis.Client, limit int) ([]ShutdownReport, error) {
+	vals, err := client.LRange(ctx, ShutdownReportsKey, 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ShutdownReport, 0, len(vals))
+	for _, v := range vals {
+		var r ShutdownReport
+		if err := json.Unmarshal([]byte(v), &r); err != nil {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
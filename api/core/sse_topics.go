@@ -0,0 +1,326 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	eventContestPhase     = "phase"
+	eventContestBalloon   = "balloon"
+	eventStandingsUpdated = "standings_updated"
+	eventSystemError      = "system_error"
+	maxSSETopics          = 10
+)
+
+var (
+	ErrSSETopicInput     = errors.New("invalid topic")
+	ErrSSETopicForbidden = errors.New("topic not allowed")
+)
+
+// sseTopic is a topic a client subscribed to, resolved to its pub/sub channel.
+//
+//	contest:{id}  contest events (announcements, standings, balloons, phase changes); staff or registered users
+//	user:{id}     personal events such as verdicts; "user:me" for oneself, other users for staff only
+//	admin         staff events (system errors); staff only
+//	notices       newly published notices; any logged-in user
+type sseTopic struct {
+	name    string
+	channel string
+	userID  int64    // user topics: only events addressed to this user
+	contest *Contest // contest topics: also receive phase changes
+}
+
+// resolveSSETopics parses a comma-separated topic list and checks that user may read each topic.
+// Unknown contests yield pgx.ErrNoRows.
+func resolveSSETopics(ctx context.Context, raw string, user *UserRecord, contestRepo ContestRepository) ([]sseTopic, error) {
+	seen := map[string]bool{}
+	var topics []sseTopic
+	for _, part := range strings.Split(raw, ",") {
+		name := strings.TrimSpace(part)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if len(topics) == maxSSETopics {
+			return nil, fmt.Errorf("%w: topics は %d 個まで指定できます", ErrSSETopicInput, maxSSETopics)
+		}
+		kind, arg, _ := strings.Cut(name, ":")
+		switch kind {
+		case "contest":
+			id, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil || id <= 0 {
+				return nil, fmt.Errorf("%w: %s", ErrSSETopicInput, name)
+			}
+			contest, err := contestRepo.Get(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			if !isStaffRole(user.Role) {
+				registered, err := contestRepo.IsRegistered(ctx, id, user.ID)
+				if err != nil {
+					return nil, err
+				}
+				if !registered {
+					return nil, fmt.Errorf("%w: %s", ErrSSETopicForbidden, name)
+				}
+			}
+			topics = append(topics, sseTopic{name: name, channel: ContestEventChannel(id), contest: contest})
+		case "user":
+			id := user.ID
+			if arg != "me" {
+				parsed, err := strconv.ParseInt(arg, 10, 64)
+				if err != nil || parsed <= 0 {
+					return nil, fmt.Errorf("%w: %s", ErrSSETopicInput, name)
+				}
+				id = parsed
+			}
+			if id != user.ID && !isStaffRole(user.Role) {
+				return nil, fmt.Errorf("%w: %s", ErrSSETopicForbidden, name)
+			}
+			topics = append(topics, sseTopic{name: name, channel: UserEventChannel, userID: id})
+		case "admin":
+			if arg != "" {
+				return nil, fmt.Errorf("%w: %s", ErrSSETopicInput, name)
+			}
+			if !isStaffRole(user.Role) {
+				return nil, fmt.Errorf("%w: %s", ErrSSETopicForbidden, name)
+			}
+			topics = append(topics, sseTopic{name: name, channel: AdminEventChannel})
+		case "notices":
+			if arg != "" {
+				return nil, fmt.Errorf("%w: %s", ErrSSETopicInput, name)
+			}
+			topics = append(topics, sseTopic{name: name, channel: NoticeEventChannel})
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrSSETopicInput, name)
+		}
+	}
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("%w: topics を指定してください", ErrSSETopicInput)
+	}
+	return topics, nil
+}
+
+// topicFrame is the data of a frame on the topic stream.
+type topicFrame struct {
+	Topic string          `json:"topic"`
+	ID    int64           `json:"id,omitempty"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// routeTopicEvent returns the names of the topics an event from the bus belongs to.
+func routeTopicEvent(topics []sseTopic, ev Event) []string {
+	var out []string
+	for _, t := range topics {
+		if t.channel != ev.Channel {
+			continue
+		}
+		if t.userID != 0 && ev.UserID != t.userID {
+			continue
+		}
+		out = append(out, t.name)
+	}
+	return out
+}
+
+// ContestPhaseEvent is sent on contest topics when the stream opens and whenever the phase changes.
+type ContestPhaseEvent struct {
+	ContestID int64     `json:"contest_id"`
+	Phase     string    `json:"phase"`
+	StartAt   time.Time `json:"start_at"`
+	EndAt     time.Time `json:"end_at"`
+}
+
+// nextPhaseChange returns the earliest start / end after now among the contest topics.
+func nextPhaseChange(topics []sseTopic, now time.Time) (time.Time, bool) {
+	var next time.Time
+	for _, t := range topics {
+		if t.contest == nil {
+			continue
+		}
+		for _, at := range []time.Time{t.contest.StartAt, t.contest.EndAt} {
+			if at.After(now) && (next.IsZero() || at.Before(next)) {
+				next = at
+			}
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// startSSE writes the headers of a Server-Sent Events response.
+func startSSE(c *gin.Context) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+}
+
+// registerEventStreamRoutes wires the topic stream that carries contest, personal, staff and notice
+// events over one connection. Frames are "event: <type>" with a topicFrame as data.
+func registerEventStreamRoutes(api *gin.RouterGroup, bus *EventBus, contestRepo ContestRepository, userRepo UserRepository) {
+	api.GET("/events/stream", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		topics, err := resolveSSETopics(ctx, c.Query("topics"), user, contestRepo)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrSSETopicInput):
+				respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+			case errors.Is(err, ErrSSETopicForbidden):
+				respondError(c, http.StatusForbidden, "FORBIDDEN", err.Error())
+			case errors.Is(err, pgx.ErrNoRows):
+				respondError(c, http.StatusNotFound, "NOT_FOUND", "contest not found")
+			default:
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to resolve topics")
+			}
+			return
+		}
+		channels := []string{}
+		subscribed := map[string]bool{}
+		for _, t := range topics {
+			if !subscribed[t.channel] {
+				subscribed[t.channel] = true
+				channels = append(channels, t.channel)
+			}
+		}
+		events, err := bus.Subscribe(ctx, channels...)
+		if err != nil {
+			respondError(c, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "live delivery is unavailable")
+			return
+		}
+
+		startSSE(c)
+		writeFrame := func(w io.Writer, eventType, topic string, id int64, data []byte) {
+			frame, _ := json.Marshal(topicFrame{Topic: topic, ID: id, Data: data})
+			writeSSE(w, eventType, 0, frame)
+		}
+		// 開催状況は接続ごとに開始・終了時刻から求める（インスタンス間の調整が要らない）
+		phases := map[string]string{}
+		sendPhases := func(w io.Writer, now time.Time) {
+			for _, t := range topics {
+				if t.contest == nil {
+					continue
+				}
+				phase := t.contest.Phase(now)
+				if phases[t.name] == phase {
+					continue
+				}
+				phases[t.name] = phase
+				data, _ := json.Marshal(ContestPhaseEvent{ContestID: t.contest.ID, Phase: phase, StartAt: t.contest.StartAt, EndAt: t.contest.EndAt})
+				writeFrame(w, eventContestPhase, t.name, 0, data)
+			}
+		}
+		sendPhases(c.Writer, time.Now())
+		c.Writer.Flush()
+
+		// 次の開始・終了時刻に発火する。先の予定がなければ nil のまま（受信しない）
+		var phaseTimer *time.Timer
+		var phaseC <-chan time.Time
+		armPhaseTimer := func(now time.Time) {
+			phaseC = nil
+			if next, ok := nextPhaseChange(topics, now); ok {
+				phaseTimer = time.NewTimer(next.Sub(now))
+				phaseC = phaseTimer.C
+			}
+		}
+		armPhaseTimer(time.Now())
+		defer func() {
+			if phaseTimer != nil {
+				phaseTimer.Stop()
+			}
+		}()
+
+		keepAlive := time.NewTicker(sseKeepAliveInterval)
+		defer keepAlive.Stop()
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case ev, ok := <-events:
+				if !ok {
+					return false
+				}
+				for _, topic := range routeTopicEvent(topics, ev) {
+					writeFrame(w, ev.Type, topic, ev.ID, ev.Data)
+				}
+				return true
+			case <-phaseC:
+				// タイマーは境界ちょうどに発火するので、少し先の時刻で判定する
+				now := time.Now().Add(time.Millisecond)
+				sendPhases(w, now)
+				armPhaseTimer(now)
+				return true
+			case <-keepAlive.C:
+				fmt.Fprint(w, ": ping\n\n")
+				return true
+			}
+		})
+	})
+}
+
+// ContestBalloonEvent announces the first accepted submission of a user for a contest problem.
+type ContestBalloonEvent struct {
+	SubmissionID int64  `json:"submission_id"`
+	UserID       int64  `json:"user_id"`
+	Username     string `json:"userid"`
+	ProblemID    int64  `json:"problem_id"`
+	ProblemTitle string `json:"problem_title"`
+}
+
+// publishJudgedEvents publishes what follows from a judged submission besides the owner's verdict:
+// standings changes and balloons on the contest channel, and system errors for staff.
+func publishJudgedEvents(ctx context.Context, bus *EventBus, subRepo SubmissionRepository, v *SubmissionResultView) error {
+	if v.Verdict != nil && *v.Verdict == VerdictSE {
+		if err := bus.Publish(ctx, AdminEventChannel, eventSystemError, v.ID, gin.H{
+			"submission_id": v.ID,
+			"user_id":       v.UserID,
+			"problem_id":    v.ProblemID,
+			"error_message": v.ErrorMsg,
+		}); err != nil {
+			return err
+		}
+	}
+	// リジャッジが完了していれば、再計算した順位表（first_solve を含む）の更新を一度だけ知らせる
+	updates, err := subRepo.ClaimRejudgeStandings(ctx, v.ID)
+	if err != nil {
+		return err
+	}
+	for _, u := range updates {
+		if err := bus.Publish(ctx, ContestEventChannel(u.ContestID), eventStandingsUpdated, v.ID, gin.H{"rejudge_job_id": u.JobID, "problem_id": u.ProblemID}); err != nil {
+			return err
+		}
+	}
+	if v.ContestID == nil {
+		return nil
+	}
+	channel := ContestEventChannel(*v.ContestID)
+	if err := bus.Publish(ctx, channel, eventStandingsUpdated, v.ID, gin.H{"user_id": v.UserID, "problem_id": v.ProblemID}); err != nil {
+		return err
+	}
+	first, err := subRepo.IsFirstContestAC(ctx, v.ID)
+	if err != nil || !first {
+		return err
+	}
+	return bus.Publish(ctx, channel, eventContestBalloon, v.ID, ContestBalloonEvent{
+		SubmissionID: v.ID,
+		UserID:       v.UserID,
+		Username:     v.Username,
+		ProblemID:    v.ProblemID,
+		ProblemTitle: v.ProblemTitle,
+	})
+}
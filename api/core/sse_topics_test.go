@@ -0,0 +1,66 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestResolveSSETopicsAuthorization(t *testing.T) {
+	ctx := context.Background()
+	alice := &UserRecord{ID: 1, Role: "user"}
+	staff := &UserRecord{ID: 9, Role: "admin"}
+
+	// contest を含まないので ContestRepository は使われない
+	topics, err := resolveSSETopics(ctx, "user:me, notices, user:me", alice, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topics) != 2 || topics[0].userID != 1 || topics[0].channel != UserEventChannel || topics[1].channel != NoticeEventChannel {
+		t.Fatalf("got %+v", topics)
+	}
+	for _, raw := range []string{"user:2", "admin"} {
+		if _, err := resolveSSETopics(ctx, raw, alice, nil); !errors.Is(err, ErrSSETopicForbidden) {
+			t.Fatalf("%s: expected ErrSSETopicForbidden, got %v", raw, err)
+		}
+		if _, err := resolveSSETopics(ctx, raw, staff, nil); err != nil {
+			t.Fatalf("%s: staff should be allowed, got %v", raw, err)
+		}
+	}
+	for _, raw := range []string{"", "room:1", "contest:x", "admin:1"} {
+		if _, err := resolveSSETopics(ctx, raw, staff, nil); !errors.Is(err, ErrSSETopicInput) {
+			t.Fatalf("%q: expected ErrSSETopicInput, got %v", raw, err)
+		}
+	}
+}
+
+func TestRouteTopicEventAndPhaseChange(t *testing.T) {
+	now := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	contest := &Contest{ID: 3, StartAt: now.Add(time.Hour), EndAt: now.Add(3 * time.Hour)}
+	topics := []sseTopic{
+		{name: "user:1", channel: UserEventChannel, userID: 1},
+		{name: "user:2", channel: UserEventChannel, userID: 2},
+		{name: "contest:3", channel: ContestEventChannel(3), contest: contest},
+	}
+	if got := routeTopicEvent(topics, Event{Channel: UserEventChannel, UserID: 2}); !reflect.DeepEqual(got, []string{"user:2"}) {
+		t.Fatalf("got %v", got)
+	}
+	if got := routeTopicEvent(topics, Event{Channel: ContestEventChannel(3)}); !reflect.DeepEqual(got, []string{"contest:3"}) {
+		t.Fatalf("got %v", got)
+	}
+	if got := routeTopicEvent(topics, Event{Channel: NoticeEventChannel}); got != nil {
+		t.Fatalf("got %v", got)
+	}
+
+	if next, ok := nextPhaseChange(topics, now); !ok || !next.Equal(contest.StartAt) {
+		t.Fatalf("next = %v %v", next, ok)
+	}
+	if next, ok := nextPhaseChange(topics, contest.StartAt); !ok || !next.Equal(contest.EndAt) {
+		t.Fatalf("next = %v %v", next, ok)
+	}
+	if _, ok := nextPhaseChange(topics, contest.EndAt); ok {
+		t.Fatal("no change expected after the contest")
+	}
+}
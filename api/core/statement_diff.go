@@ -0,0 +1,148 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDiffCells bounds the LCS table; larger inputs are shown as a full replacement.
+const maxDiffCells = 4_000_000
+
+type lineEdit struct {
+	op   byte // ' ' | '-' | '+'
+	text string
+}
+
+// diffLines computes a line-based edit script from a to b (LCS).
+func diffLines(a, b []string) []lineEdit {
+	if len(a)*len(b) > maxDiffCells {
+		edits := make([]lineEdit, 0, len(a)+len(b))
+		for _, l := range a {
+			edits = append(edits, lineEdit{'-', l})
+		}
+		for _, l := range b {
+			edits = append(edits, lineEdit{'+', l})
+		}
+		return edits
+	}
+	// lcs[i][j] = LCS length of a[i:] and b[j:]
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var edits []lineEdit
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			edits = append(edits, lineEdit{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, lineEdit{'-', a[i]})
+			i++
+		default:
+			edits = append(edits, lineEdit{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		edits = append(edits, lineEdit{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		edits = append(edits, lineEdit{'+', b[j]})
+	}
+	return edits
+}
+
+// unifiedDiff renders a unified diff of two texts with the given context lines,
+// returning the diff and the number of added / removed lines. Identical texts yield "".
+func unifiedDiff(oldText, newText string, context int) (string, int, int) {
+	edits := diffLines(splitLines(oldText), splitLines(newText))
+
+	added, removed := 0, 0
+	// oldPos/newPos[k] = 編集 k より前に消費した行数
+	oldPos := make([]int, len(edits)+1)
+	newPos := make([]int, len(edits)+1)
+	for k, e := range edits {
+		oldPos[k+1], newPos[k+1] = oldPos[k], newPos[k]
+		switch e.op {
+		case ' ':
+			oldPos[k+1]++
+			newPos[k+1]++
+		case '-':
+			oldPos[k+1]++
+			removed++
+		case '+':
+			newPos[k+1]++
+			added++
+		}
+	}
+	if added == 0 && removed == 0 {
+		return "", 0, 0
+	}
+
+	var sb strings.Builder
+	sb.WriteString("--- current\n+++ proposed\n")
+	for k := 0; k < len(edits); {
+		if edits[k].op == ' ' {
+			k++
+			continue
+		}
+		// 変更の塊を前後 context 行込みで 1 ハンクにまとめる（近い塊は結合）
+		start := max(k-context, 0)
+		end := k
+		for end < len(edits) {
+			if edits[end].op != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(edits) && edits[next].op == ' ' {
+				next++
+			}
+			if next < len(edits) && next-end <= 2*context {
+				end = next
+				continue
+			}
+			end = min(end+context, len(edits))
+			break
+		}
+		oldCount := oldPos[end] - oldPos[start]
+		newCount := newPos[end] - newPos[start]
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(oldPos[start], oldCount), hunkRange(newPos[start], newCount))
+		for _, e := range edits[start:end] {
+			sb.WriteByte(e.op)
+			sb.WriteString(e.text)
+			sb.WriteByte('\n')
+		}
+		k = end
+	}
+	return sb.String(), added, removed
+}
+
+func hunkRange(pos, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", pos)
+	}
+	return fmt.Sprintf("%d,%d", pos+1, count)
+}
+
+func splitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
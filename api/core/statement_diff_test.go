@@ -0,0 +1,23 @@
+package core
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	oldText := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	newText := "a\nb\nC\nd\ne\nf\ng\nh\ni\nj\nk\n"
+
+	diff, added, removed := unifiedDiff(oldText, newText, 1)
+	if added != 2 || removed != 1 {
+		t.Fatalf("added=%d removed=%d", added, removed)
+	}
+	want := "--- current\n+++ proposed\n" +
+		"@@ -2,3 +2,3 @@\n b\n-c\n+C\n d\n" +
+		"@@ -10,1 +10,2 @@\n j\n+k\n"
+	if diff != want {
+		t.Fatalf("unexpected diff:\n%s", diff)
+	}
+
+	if diff, _, _ := unifiedDiff("same\n", "same", 3); diff != "" {
+		t.Fatalf("expected no diff, got %q", diff)
+	}
+}
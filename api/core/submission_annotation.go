@@ -0,0 +1,155 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SubmissionComment is a reviewer comment anchored to a source line.
+type SubmissionComment struct {
+	ID           int64     `json:"id"`
+	SubmissionID int64     `json:"submission_id"`
+	Line         int       `json:"line"`
+	Body         string    `json:"body"`
+	AuthorID     *int64    `json:"author_id"`
+	AuthorName   *string   `json:"author_userid"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// SubmissionGrade is the overall grade note of a submission.
+type SubmissionGrade struct {
+	SubmissionID int64     `json:"submission_id"`
+	Grade        string    `json:"grade"`
+	Note         string    `json:"note"`
+	GradedBy     *int64    `json:"graded_by"`
+	GraderName   *string   `json:"graded_by_userid"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// SubmissionAnnotationRepository stores review comments and grades on submissions.
+type SubmissionAnnotationRepository interface {
+	ListComments(ctx context.Context, submissionID int64) ([]SubmissionComment, error)
+	AddComment(ctx context.Context, submissionID, authorID int64, line int, body string) (*SubmissionComment, error)
+	UpdateComment(ctx context.Context, submissionID, id int64, body string) (*SubmissionComment, error)
+	DeleteComment(ctx context.Context, submissionID, id int64) error
+	// Grade returns nil when the submission has not been graded.
+	Grade(ctx context.Context, submissionID int64) (*SubmissionGrade, error)
+	SetGrade(ctx context.Context, submissionID, gradedBy int64, grade, note string) (*SubmissionGrade, error)
+	DeleteGrade(ctx context.Context, submissionID int64) error
+}
+
+type PgSubmissionAnnotationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPgSubmissionAnnotationRepository(db *pgxpool.Pool) *PgSubmissionAnnotationRepository {
+	return &PgSubmissionAnnotationRepository{db: db}
+}
+
+const submissionCommentSelect = `
+SELECT c.id, c.submission_id, c.line, c.body, c.author_id, u.username, c.created_at, c.updated_at
+FROM submission_comments c
+LEFT JOIN users u ON u.id = c.author_id
+`
+
+func (r *PgSubmissionAnnotationRepository) ListComments(ctx context.Context, submissionID int64) ([]SubmissionComment, error) {
+	rows, err := r.db.Query(ctx, submissionCommentSelect+`WHERE c.submission_id=$1 ORDER BY c.line, c.id`, submissionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SubmissionComment{}
+	for rows.Next() {
+		var cm SubmissionComment
+		if err := rows.Scan(&cm.ID, &cm.SubmissionID, &cm.Line, &cm.Body, &cm.AuthorID, &cm.AuthorName, &cm.CreatedAt, &cm.UpdatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, cm)
+	}
+	return items, rows.Err()
+}
+
+func (r *PgSubmissionAnnotationRepository) getComment(ctx context.Context, submissionID, id int64) (*SubmissionComment, error) {
+	var cm SubmissionComment
+	if err := r.db.QueryRow(ctx, submissionCommentSelect+`WHERE c.submission_id=$1 AND c.id=$2`, submissionID, id).Scan(
+		&cm.ID, &cm.SubmissionID, &cm.Line, &cm.Body, &cm.AuthorID, &cm.AuthorName, &cm.CreatedAt, &cm.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &cm, nil
+}
+
+func (r *PgSubmissionAnnotationRepository) AddComment(ctx context.Context, submissionID, authorID int64, line int, body string) (*SubmissionComment, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, errors.New("body is required")
+	}
+	var id int64
+	if err := r.db.QueryRow(ctx, `INSERT INTO submission_comments (submission_id, author_id, line, body) VALUES ($1,$2,$3,$4) RETURNING id`,
+		submissionID, authorID, line, body).Scan(&id); err != nil {
+		return nil, err
+	}
+	return r.getComment(ctx, submissionID, id)
+}
+
+func (r *PgSubmissionAnnotationRepository) UpdateComment(ctx context.Context, submissionID, id int64, body string) (*SubmissionComment, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, errors.New("body is required")
+	}
+	ct, err := r.db.Exec(ctx, `UPDATE submission_comments SET body=$3, updated_at=NOW() WHERE submission_id=$1 AND id=$2`, submissionID, id, body)
+	if err != nil {
+		return nil, err
+	}
+	if ct.RowsAffected() == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return r.getComment(ctx, submissionID, id)
+}
+
+func (r *PgSubmissionAnnotationRepository) DeleteComment(ctx context.Context, submissionID, id int64) error {
+	ct, err := r.db.Exec(ctx, `DELETE FROM submission_comments WHERE submission_id=$1 AND id=$2`, submissionID, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PgSubmissionAnnotationRepository) Grade(ctx context.Context, submissionID int64) (*SubmissionGrade, error) {
+	const q = `
+SELECT g.submission_id, g.grade, g.note, g.graded_by, u.username, g.updated_at
+FROM submission_grades g
+LEFT JOIN users u ON u.id = g.graded_by
+WHERE g.submission_id=$1`
+	var g SubmissionGrade
+	if err := r.db.QueryRow(ctx, q, submissionID).Scan(&g.SubmissionID, &g.Grade, &g.Note, &g.GradedBy, &g.GraderName, &g.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &g, nil
+}
+
+func (r *PgSubmissionAnnotationRepository) SetGrade(ctx context.Context, submissionID, gradedBy int64, grade, note string) (*SubmissionGrade, error) {
+	const q = `
+INSERT INTO submission_grades (submission_id, grade, note, graded_by, updated_at) VALUES ($1,$2,$3,$4,NOW())
+ON CONFLICT (submission_id) DO UPDATE SET grade=EXCLUDED.grade, note=EXCLUDED.note, graded_by=EXCLUDED.graded_by, updated_at=NOW()`
+	if _, err := r.db.Exec(ctx, q, submissionID, strings.TrimSpace(grade), strings.TrimSpace(note), gradedBy); err != nil {
+		return nil, err
+	}
+	return r.Grade(ctx, submissionID)
+}
+
+func (r *PgSubmissionAnnotationRepository) DeleteGrade(ctx context.Context, submissionID int64) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM submission_grades WHERE submission_id=$1`, submissionID)
+	return err
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"context"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxUserAgentLength bounds the stored user agent.
+const maxUserAgentLength = 512
+
+// SubmissionClientInfo is the client that made a submission (admin only).
+type SubmissionClientInfo struct {
+	SubmissionID int64     `json:"submission_id"`
+	UserID       int64     `json:"user_id"`
+	Username     string    `json:"userid"`
+	ProblemID    int64     `json:"problem_id"`
+	IP           *string   `json:"ip"`
+	UserAgent    string    `json:"user_agent"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// SubmissionClientFilter narrows a client info search. At least one field should be set.
+type SubmissionClientFilter struct {
+	IP     string
+	UserID *int64
+}
+
+const submissionClientSelect = `
+SELECT ci.submission_id, s.user_id, u.username, s.problem_id, host(ci.ip), ci.user_agent, ci.created_at
+FROM submission_client_info ci
+JOIN submissions s ON s.id = ci.submission_id
+JOIN users u ON u.id = s.user_id
+`
+
+// SaveClientInfo records the submitting IP and user agent. Unparseable IPs are stored as NULL.
+func (r *PgSubmissionRepository) SaveClientInfo(ctx context.Context, submissionID int64, ip, userAgent string) error {
+	var ipArg *string
+	if net.ParseIP(ip) != nil {
+		ipArg = &ip
+	}
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	_, err := r.db.Exec(ctx, `INSERT INTO submission_client_info (submission_id, ip, user_agent) VALUES ($1,$2,$3)
+ON CONFLICT (submission_id) DO NOTHING`, submissionID, ipArg, userAgent)
+	return err
+}
+
+func (r *PgSubmissionRepository) ClientInfo(ctx context.Context, submissionID int64) (*SubmissionClientInfo, error) {
+	var ci SubmissionClientInfo
+	if err := r.db.QueryRow(ctx, submissionClientSelect+`WHERE ci.submission_id=$1`, submissionID).Scan(
+		&ci.SubmissionID, &ci.UserID, &ci.Username, &ci.ProblemID, &ci.IP, &ci.UserAgent, &ci.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &ci, nil
+}
+
+// SearchClientInfo lists recorded client info newest first (e.g. every account seen from one IP).
+func (r *PgSubmissionRepository) SearchClientInfo(ctx context.Context, filter SubmissionClientFilter, limit int) ([]SubmissionClientInfo, error) {
+	var conds []string
+	var args []any
+	if filter.IP != "" {
+		args = append(args, filter.IP)
+		conds = append(conds, "ci.ip = $"+strconv.Itoa(len(args))+"::inet")
+	}
+	if filter.UserID != nil {
+		args = append(args, *filter.UserID)
+		conds = append(conds, "s.user_id = $"+strconv.Itoa(len(args)))
+	}
+	q := submissionClientSelect
+	if len(conds) > 0 {
+		q += "WHERE " + strings.Join(conds, " AND ")
+	}
+	args = append(args, limit)
+	q += "\nORDER BY ci.submission_id DESC\nLIMIT $" + strconv.Itoa(len(args))
+	rows, err := r.db.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SubmissionClientInfo{}
+	for rows.Next() {
+		var ci SubmissionClientInfo
+		if err := rows.Scan(&ci.SubmissionID, &ci.UserID, &ci.Username, &ci.ProblemID, &ci.IP, &ci.UserAgent, &ci.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, ci)
+	}
+	return items, rows.Err()
+}
+
+// PurgeClientInfo deletes client info recorded before the given time.
+func (r *PgSubmissionRepository) PurgeClientInfo(ctx context.Context, before time.Time) (int64, error) {
+	ct, err := r.db.Exec(ctx, `DELETE FROM submission_client_info WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
+
+// RunClientInfoPurger deletes client info older than retention every interval until ctx is cancelled.
+func RunClientInfoPurger(ctx context.Context, subRepo SubmissionRepository, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := subRepo.PurgeClientInfo(ctx, time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("[client-info] purge failed: %v", err)
+		} else if n > 0 {
+			log.Printf("[client-info] purged %d records", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
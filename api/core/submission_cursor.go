@@ -0,0 +1,113 @@
+package core
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// SubmissionCursor marks a position in a submission list ordered by (created_at, id) descending.
+// Clients see it only as an opaque string.
+type SubmissionCursor struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+// Encode returns the opaque form of the cursor. created_at is kept at microsecond precision,
+// which is what PostgreSQL stores.
+func (c SubmissionCursor) Encode() string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixMicro(), 10) + ":" + strconv.FormatInt(c.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseSubmissionCursor decodes a cursor produced by Encode. An empty string means the first page
+// and yields nil.
+func ParseSubmissionCursor(s string) (*SubmissionCursor, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	microStr, idStr, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	micro, err := strconv.ParseInt(microStr, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &SubmissionCursor{CreatedAt: time.UnixMicro(micro).UTC(), ID: id}, nil
+}
+
+// ListByUserAfter is the keyset variant of ListByUser: it returns up to limit submissions older than
+// after (or the newest ones when after is nil) and the cursor of the next page, nil on the last page.
+// It skips COUNT(*) and OFFSET so the cost does not grow with the page number.
+func (r *PgSubmissionRepository) ListByUserAfter(ctx context.Context, userID int64, filter SubmissionListFilter, after *SubmissionCursor, limit int) ([]SubmissionListItem, *SubmissionCursor, error) {
+	filters, args := filter.apply([]string{"s.user_id=$1"}, []interface{}{userID})
+	return r.listSubmissionsAfter(ctx, filters, args, after, limit)
+}
+
+// ListByProblemAfter is the keyset variant of ListByProblem.
+func (r *PgSubmissionRepository) ListByProblemAfter(ctx context.Context, problemID int64, filter SubmissionListFilter, after *SubmissionCursor, limit int) ([]SubmissionListItem, *SubmissionCursor, error) {
+	filters, args := filter.apply([]string{"s.problem_id=$1"}, []interface{}{problemID})
+	return r.listSubmissionsAfter(ctx, filters, args, after, limit)
+}
+
+func (r *PgSubmissionRepository) listSubmissionsAfter(ctx context.Context, filters []string, args []interface{}, after *SubmissionCursor, limit int) ([]SubmissionListItem, *SubmissionCursor, error) {
+	if limit <= 0 {
+		return nil, nil, errors.New("invalid pagination")
+	}
+	if after != nil {
+		// 行値比較にすると (user_id|problem_id, created_at DESC, id DESC) の索引をそのまま辿れる
+		filters = append(filters, fmt.Sprintf("(s.created_at, s.id) < ($%d, $%d)", len(args)+1, len(args)+2))
+		args = append(args, after.CreatedAt, after.ID)
+	}
+	query := fmt.Sprintf(`
+SELECT s.id, s.user_id, u.username, s.problem_id, p.title, s.language, s.status,
+       sr.verdict, sr.time_ms, sr.memory_kb, s.created_at
+FROM submissions s
+JOIN users u ON u.id = s.user_id
+JOIN problems p ON p.id = s.problem_id
+LEFT JOIN submission_results sr ON sr.submission_id = s.id
+WHERE %s
+ORDER BY s.created_at DESC, s.id DESC
+LIMIT $%d`, strings.Join(filters, " AND "), len(args)+1)
+	// 1 件多く読んで次ページの有無を判定する
+	rows, err := r.db.Query(ctx, query, append(args, limit+1)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	items := make([]SubmissionListItem, 0, limit+1)
+	for rows.Next() {
+		var v SubmissionListItem
+		if err := rows.Scan(&v.ID, &v.UserID, &v.Username, &v.ProblemID, &v.ProblemTitle, &v.Language, &v.Status, &v.Verdict, &v.TimeMS, &v.MemoryKB, &v.CreatedAt); err != nil {
+			return nil, nil, err
+		}
+		items = append(items, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	var next *SubmissionCursor
+	if len(items) > limit {
+		items = items[:limit]
+		last := items[limit-1]
+		next = &SubmissionCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+	return items, next, nil
+}
@@ -0,0 +1,26 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubmissionCursorRoundTrip(t *testing.T) {
+	want := SubmissionCursor{CreatedAt: time.Date(2026, 4, 1, 9, 30, 15, 123456000, time.UTC), ID: 98765}
+	got, err := ParseSubmissionCursor(want.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || !got.CreatedAt.Equal(want.CreatedAt) || got.ID != want.ID {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	if c, err := ParseSubmissionCursor(""); err != nil || c != nil {
+		t.Fatalf("empty cursor should mean the first page, got %+v %v", c, err)
+	}
+	for _, bad := range []string{"!!", "MTIz", "YWJjOjE", "MTIzOjA"} {
+		if _, err := ParseSubmissionCursor(bad); err != ErrInvalidCursor {
+			t.Fatalf("%q: expected ErrInvalidCursor, got %v", bad, err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxSubmissionFilterValues = 20
+
+var submissionStatuses = []string{"pending", "running", "succeeded", "failed"}
+
+// SubmissionListFilter narrows submission lists in SQL. Zero fields do not filter; list fields
+// match any of their values.
+type SubmissionListFilter struct {
+	ProblemID *int64
+	Verdicts  []string
+	Statuses  []string
+	Languages []string
+	From      *time.Time // created_at >= From
+	To        *time.Time // created_at < To
+}
+
+// apply appends the filter's conditions (on submissions s / submission_results sr) to filters and
+// their values to args, numbering placeholders after the existing args.
+func (f SubmissionListFilter) apply(filters []string, args []interface{}) ([]string, []interface{}) {
+	add := func(cond string, v interface{}) {
+		args = append(args, v)
+		filters = append(filters, fmt.Sprintf(cond, len(args)))
+	}
+	if f.ProblemID != nil && *f.ProblemID > 0 {
+		add("s.problem_id=$%d", *f.ProblemID)
+	}
+	if len(f.Verdicts) > 0 {
+		add("sr.verdict = ANY($%d)", f.Verdicts)
+	}
+	if len(f.Statuses) > 0 {
+		add("s.status = ANY($%d)", f.Statuses)
+	}
+	if len(f.Languages) > 0 {
+		add("s.language = ANY($%d)", f.Languages)
+	}
+	if f.From != nil {
+		add("s.created_at >= $%d", *f.From)
+	}
+	if f.To != nil {
+		add("s.created_at < $%d", *f.To)
+	}
+	return filters, args
+}
+
+// needsResults reports whether the conditions refer to submission_results.
+func (f SubmissionListFilter) needsResults() bool {
+	return len(f.Verdicts) > 0
+}
+
+// parseSubmissionListFilter reads problem_id, verdict, status, language (comma-separated lists) and
+// from / to from a query string. from / to take RFC 3339 or YYYY-MM-DD (UTC); a date-only "to"
+// includes that whole day.
+func parseSubmissionListFilter(q url.Values) (SubmissionListFilter, error) {
+	var f SubmissionListFilter
+	if v := strings.TrimSpace(q.Get("problem_id")); v != "" {
+		pid, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || pid <= 0 {
+			return f, errors.New("problem_id は正の整数で指定してください")
+		}
+		f.ProblemID = &pid
+	}
+
+	var err error
+	if f.Verdicts, err = parseFilterList(q.Get("verdict"), "verdict", strings.ToUpper, func(v string) bool {
+		return verdictCodePattern.MatchString(v)
+	}); err != nil {
+		return f, err
+	}
+	if f.Statuses, err = parseFilterList(q.Get("status"), "status", strings.ToLower, func(v string) bool {
+		for _, s := range submissionStatuses {
+			if s == v {
+				return true
+			}
+		}
+		return false
+	}); err != nil {
+		return f, err
+	}
+	if f.Languages, err = parseFilterList(q.Get("language"), "language", strings.ToLower, isSupportedLanguage); err != nil {
+		return f, err
+	}
+
+	if f.From, err = parseFilterTime(q.Get("from"), "from", false); err != nil {
+		return f, err
+	}
+	if f.To, err = parseFilterTime(q.Get("to"), "to", true); err != nil {
+		return f, err
+	}
+	if f.From != nil && f.To != nil && !f.From.Before(*f.To) {
+		return f, errors.New("from は to より前の日時を指定してください")
+	}
+	return f, nil
+}
+
+func parseFilterList(raw, name string, normalize func(string) string, valid func(string) bool) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var out []string
+	for _, part := range strings.Split(raw, ",") {
+		v := normalize(strings.TrimSpace(part))
+		if v == "" {
+			continue
+		}
+		if !valid(v) {
+			return nil, fmt.Errorf("%s の値 %q は指定できません", name, v)
+		}
+		out = append(out, v)
+	}
+	if len(out) > maxSubmissionFilterValues {
+		return nil, fmt.Errorf("%s は %d 個まで指定できます", name, maxSubmissionFilterValues)
+	}
+	return out, nil
+}
+
+func parseFilterTime(raw, name string, endOfDay bool) (*time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return &t, nil
+	}
+	d, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s は RFC 3339 形式または YYYY-MM-DD で指定してください", name)
+	}
+	if endOfDay {
+		d = d.AddDate(0, 0, 1)
+	}
+	return &d, nil
+}
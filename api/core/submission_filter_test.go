@@ -0,0 +1,51 @@
+package core
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSubmissionListFilter(t *testing.T) {
+	q := url.Values{
+		"problem_id": {"7"},
+		"verdict":    {"wa, tle"},
+		"status":     {"succeeded"},
+		"language":   {"C"},
+		"from":       {"2026-04-01"},
+		"to":         {"2026-04-30"},
+	}
+	f, err := parseSubmissionListFilter(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *f.ProblemID != 7 || strings.Join(f.Verdicts, ",") != "WA,TLE" || f.Statuses[0] != "succeeded" || f.Languages[0] != "c" {
+		t.Fatalf("got %+v", f)
+	}
+	// 日付だけの to はその日の終わりまで含む
+	if !f.From.Equal(time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)) || !f.To.Equal(time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("range %v - %v", f.From, f.To)
+	}
+
+	filters, args := f.apply([]string{"s.user_id=$1"}, []interface{}{int64(3)})
+	want := "s.user_id=$1 AND s.problem_id=$2 AND sr.verdict = ANY($3) AND s.status = ANY($4) AND s.language = ANY($5) AND s.created_at >= $6 AND s.created_at < $7"
+	if got := strings.Join(filters, " AND "); got != want || len(args) != 7 {
+		t.Fatalf("got %s (%d args)", got, len(args))
+	}
+	if !f.needsResults() {
+		t.Fatal("verdict filter needs submission_results")
+	}
+
+	for _, bad := range []url.Values{
+		{"status": {"done"}},
+		{"language": {"cobol"}},
+		{"verdict": {"not a verdict"}},
+		{"from": {"yesterday"}},
+		{"from": {"2026-05-01"}, "to": {"2026-04-01"}},
+	} {
+		if _, err := parseSubmissionListFilter(bad); err == nil {
+			t.Fatalf("%v: expected an error", bad)
+		}
+	}
+}
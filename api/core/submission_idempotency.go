@@ -0,0 +1,144 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	idempotencyKeyPrefix = "idempotency:submissions:"
+	idempotencyTTL       = 24 * time.Hour
+	// idempotencyLockTTL は処理中の印の寿命。ハンドラが落ちても鍵が永久に塞がらないようにする
+	idempotencyLockTTL   = time.Minute
+	idempotencyMaxKeyLen = 255
+	idempotencyPending   = "pending"
+)
+
+// idempotentResponse is the stored outcome of the first request made with a key.
+type idempotentResponse struct {
+	Fingerprint string          `json:"fingerprint"`
+	Status      int             `json:"status"`
+	Body        json.RawMessage `json:"body"`
+}
+
+// teeResponseWriter copies the response body while writing it through.
+type teeResponseWriter struct {
+	gin.ResponseWriter
+	buf bytes.Buffer
+}
+
+func (w *teeResponseWriter) Write(b []byte) (int, error) {
+	w.buf.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *teeResponseWriter) WriteString(s string) (int, error) {
+	w.buf.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// SubmissionIdempotencyMiddleware deduplicates retried submissions carrying an Idempotency-Key
+// header. The first successful response is kept per user and key for 24h and replayed for
+// retries with the same body; failed attempts release the key so the client can retry.
+func SubmissionIdempotencyMiddleware(client *redis.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > idempotencyMaxKeyLen {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "Idempotency-Key は 255 文字以内で指定してください")
+			c.Abort()
+			return
+		}
+		username := sessionUsername(c)
+		if username == "" {
+			c.Next()
+			return
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "invalid body")
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(body)
+		fingerprint := hex.EncodeToString(sum[:])
+		keySum := sha256.Sum256([]byte(key))
+		redisKey := idempotencyKeyPrefix + username + ":" + hex.EncodeToString(keySum[:])
+
+		ctx := c.Request.Context()
+		acquired, err := client.SetNX(ctx, redisKey, idempotencyPending, idempotencyLockTTL).Result()
+		if err != nil {
+			// Redis が使えなければ重複排除なしで受け付ける
+			log.Printf("idempotency lock failed: %v", err)
+			c.Next()
+			return
+		}
+		if !acquired {
+			replayIdempotentResponse(c, client, redisKey, fingerprint)
+			return
+		}
+
+		w := &teeResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		ctx = c.Request.Context()
+		status := w.Status()
+		if status < 200 || status >= 300 {
+			_ = client.Del(ctx, redisKey).Err()
+			return
+		}
+		stored, err := json.Marshal(idempotentResponse{Fingerprint: fingerprint, Status: status, Body: w.buf.Bytes()})
+		if err == nil {
+			err = client.Set(ctx, redisKey, stored, idempotencyTTL).Err()
+		}
+		if err != nil {
+			log.Printf("idempotency store failed: %v", err)
+		}
+	}
+}
+
+func replayIdempotentResponse(c *gin.Context, client *redis.Client, redisKey, fingerprint string) {
+	raw, err := client.Get(c.Request.Context(), redisKey).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to check idempotency key")
+		c.Abort()
+		return
+	}
+	if err != nil || raw == idempotencyPending {
+		// 先行リクエストが処理中（または直前に失敗して鍵が解放された）
+		c.Header("Retry-After", "1")
+		respondError(c, http.StatusConflict, "IDEMPOTENCY_IN_PROGRESS", "同じ Idempotency-Key の提出を処理中です")
+		c.Abort()
+		return
+	}
+	var prev idempotentResponse
+	if err := json.Unmarshal([]byte(raw), &prev); err != nil {
+		respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to read idempotency key")
+		c.Abort()
+		return
+	}
+	if prev.Fingerprint != fingerprint {
+		respondError(c, http.StatusUnprocessableEntity, "IDEMPOTENCY_KEY_REUSED", "Idempotency-Key が別の内容の提出に使われています")
+		c.Abort()
+		return
+	}
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(prev.Status, "application/json; charset=utf-8", prev.Body)
+	c.Abort()
+}
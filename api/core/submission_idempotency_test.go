@@ -0,0 +1,70 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/sessions"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestSubmissionIdempotencyMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	calls, fail := 0, false
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		sess := sessions.NewSession(sessions.NewCookieStore([]byte("k")), sessionName)
+		sess.Values["userid"] = "alice"
+		c.Set("session", sess)
+	})
+	r.POST("/submissions", SubmissionIdempotencyMiddleware(client), func(c *gin.Context) {
+		calls++
+		if fail {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed")
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"id": calls})
+	})
+	post := func(key, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/submissions", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := post("k1", `{"source_code":"a"}`)
+	again := post("k1", `{"source_code":"a"}`)
+	if first.Code != http.StatusCreated || again.Code != http.StatusCreated || again.Body.String() != first.Body.String() || calls != 1 {
+		t.Fatalf("replay: first=%d %s again=%d %s calls=%d", first.Code, first.Body, again.Code, again.Body, calls)
+	}
+	if again.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatal("replayed response should be marked")
+	}
+	if w := post("k1", `{"source_code":"b"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("reused key with another body: got %d", w.Code)
+	}
+	if post("", `{}`); calls != 2 {
+		t.Fatalf("requests without a key are not deduplicated, calls=%d", calls)
+	}
+
+	// 失敗した応答は保存せず、同じ鍵で再試行できる
+	fail = true
+	if w := post("k2", `{}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("got %d", w.Code)
+	}
+	fail = false
+	if w := post("k2", `{}`); w.Code != http.StatusCreated || calls != 4 {
+		t.Fatalf("retry after failure: got %d, calls=%d", w.Code, calls)
+	}
+}
@@ -0,0 +1,182 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxSubmissionQuota bounds the limits that can be set per user.
+const maxSubmissionQuota = 100000
+
+// SubmissionQuotaLimits are the submission caps applied to a user (0 = unlimited).
+type SubmissionQuotaLimits struct {
+	Daily   int `json:"daily_limit"`
+	Contest int `json:"contest_limit"`
+}
+
+// SubmissionQuotaOverride is the per-user override of the defaults; nil fields use the default.
+type SubmissionQuotaOverride struct {
+	Daily     *int       `json:"daily_limit"`
+	Contest   *int       `json:"contest_limit"`
+	UpdatedBy *int64     `json:"updated_by,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// Apply returns the limits of defaults with the override on top.
+func (o *SubmissionQuotaOverride) Apply(defaults SubmissionQuotaLimits) SubmissionQuotaLimits {
+	if o == nil {
+		return defaults
+	}
+	if o.Daily != nil {
+		defaults.Daily = *o.Daily
+	}
+	if o.Contest != nil {
+		defaults.Contest = *o.Contest
+	}
+	return defaults
+}
+
+// Validate checks the override limits.
+func (o SubmissionQuotaOverride) Validate() error {
+	for _, f := range []struct {
+		name  string
+		value *int
+	}{{"daily_limit", o.Daily}, {"contest_limit", o.Contest}} {
+		if f.value != nil && (*f.value < 0 || *f.value > maxSubmissionQuota) {
+			return fmt.Errorf("%w: %s は 0〜%d で指定してください（0 は無制限）", ErrSubmissionQuotaInput, f.name, maxSubmissionQuota)
+		}
+	}
+	return nil
+}
+
+// defaultSubmissionQuota returns the configured global limits.
+func defaultSubmissionQuota(cfg Config) SubmissionQuotaLimits {
+	return SubmissionQuotaLimits{Daily: max(cfg.SubmissionDailyQuota, 0), Contest: max(cfg.SubmissionContestQuota, 0)}
+}
+
+type SubmissionQuotaRepository interface {
+	// Override returns the override of the user, nil when none is set.
+	Override(ctx context.Context, userID int64) (*SubmissionQuotaOverride, error)
+	SetOverride(ctx context.Context, userID int64, o SubmissionQuotaOverride, actorID int64) error
+	DeleteOverride(ctx context.Context, userID int64) error
+	// CountSince counts the submissions of the user created at or after since.
+	CountSince(ctx context.Context, userID int64, since time.Time) (int, error)
+	CountInContest(ctx context.Context, userID, contestID int64) (int, error)
+}
+
+type PgSubmissionQuotaRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPgSubmissionQuotaRepository(db *pgxpool.Pool) *PgSubmissionQuotaRepository {
+	return &PgSubmissionQuotaRepository{db: db}
+}
+
+func (r *PgSubmissionQuotaRepository) Override(ctx context.Context, userID int64) (*SubmissionQuotaOverride, error) {
+	var o SubmissionQuotaOverride
+	err := r.db.QueryRow(ctx, `SELECT daily_limit, contest_limit, updated_by, updated_at FROM user_submission_quotas WHERE user_id=$1`, userID).
+		Scan(&o.Daily, &o.Contest, &o.UpdatedBy, &o.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (r *PgSubmissionQuotaRepository) SetOverride(ctx context.Context, userID int64, o SubmissionQuotaOverride, actorID int64) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	_, err := r.db.Exec(ctx, `INSERT INTO user_submission_quotas (user_id, daily_limit, contest_limit, updated_by, updated_at)
+VALUES ($1,$2,$3,$4,NOW())
+ON CONFLICT (user_id) DO UPDATE SET daily_limit=EXCLUDED.daily_limit, contest_limit=EXCLUDED.contest_limit,
+    updated_by=EXCLUDED.updated_by, updated_at=NOW()`, userID, o.Daily, o.Contest, actorID)
+	return err
+}
+
+func (r *PgSubmissionQuotaRepository) DeleteOverride(ctx context.Context, userID int64) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM user_submission_quotas WHERE user_id=$1`, userID)
+	return err
+}
+
+// 負荷試験の提出は上限に数えない
+func (r *PgSubmissionQuotaRepository) CountSince(ctx context.Context, userID int64, since time.Time) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM submissions WHERE user_id=$1 AND created_at >= $2 AND load_test_id IS NULL`, userID, since).Scan(&n)
+	return n, err
+}
+
+func (r *PgSubmissionQuotaRepository) CountInContest(ctx context.Context, userID, contestID int64) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM submissions WHERE user_id=$1 AND contest_id=$2 AND load_test_id IS NULL`, userID, contestID).Scan(&n)
+	return n, err
+}
+
+// startOfDay returns the beginning of the day of now in loc, and the beginning of the next day.
+func startOfDay(now time.Time, loc *time.Location) (time.Time, time.Time) {
+	local := now.In(loc)
+	start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// setQuotaHeaders writes the limit and what remains after this submission.
+func setQuotaHeaders(c *gin.Context, scope string, limit, used int) {
+	c.Header("X-Quota-"+scope+"-Limit", strconv.Itoa(limit))
+	c.Header("X-Quota-"+scope+"-Remaining", strconv.Itoa(max(limit-used-1, 0)))
+}
+
+// checkSubmissionQuota enforces the daily and contest quotas of user before a submission is
+// created, and reports the remaining quota in X-Quota-* headers. It responds 429
+// SUBMISSION_QUOTA_EXCEEDED and returns false when a quota is used up. Staff are not limited;
+// a failed lookup lets the submission through.
+func checkSubmissionQuota(c *gin.Context, cfg Config, repo SubmissionQuotaRepository, user *UserRecord, contestID *int64) bool {
+	if isStaffRole(user.Role) {
+		return true
+	}
+	ctx := c.Request.Context()
+	override, err := repo.Override(ctx, user.ID)
+	if err != nil {
+		log.Printf("[quota] load override of user %d: %v", user.ID, err)
+		return true
+	}
+	limits := override.Apply(defaultSubmissionQuota(cfg))
+
+	if limits.Daily > 0 {
+		start, next := startOfDay(time.Now(), user.Location())
+		used, err := repo.CountSince(ctx, user.ID, start)
+		if err != nil {
+			log.Printf("[quota] count daily submissions of user %d: %v", user.ID, err)
+			return true
+		}
+		setQuotaHeaders(c, "Daily", limits.Daily, used)
+		if used >= limits.Daily {
+			c.Header("Retry-After", strconv.Itoa(int(time.Until(next).Seconds())+1))
+			respondError(c, http.StatusTooManyRequests, "SUBMISSION_QUOTA_EXCEEDED", fmt.Sprintf("本日の提出数の上限（%d 件）に達しました", limits.Daily))
+			return false
+		}
+	}
+	if limits.Contest > 0 && contestID != nil {
+		used, err := repo.CountInContest(ctx, user.ID, *contestID)
+		if err != nil {
+			log.Printf("[quota] count contest submissions of user %d: %v", user.ID, err)
+			return true
+		}
+		setQuotaHeaders(c, "Contest", limits.Contest, used)
+		if used >= limits.Contest {
+			respondError(c, http.StatusTooManyRequests, "SUBMISSION_QUOTA_EXCEEDED", fmt.Sprintf("このコンテストの提出数の上限（%d 件）に達しました", limits.Contest))
+			return false
+		}
+	}
+	return true
+}
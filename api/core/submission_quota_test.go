@@ -0,0 +1,56 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type stubSubmissionQuotaRepo struct {
+	SubmissionQuotaRepository
+	override     *SubmissionQuotaOverride
+	daily, inCtx int
+}
+
+func (s *stubSubmissionQuotaRepo) Override(context.Context, int64) (*SubmissionQuotaOverride, error) {
+	return s.override, nil
+}
+
+func (s *stubSubmissionQuotaRepo) CountSince(context.Context, int64, time.Time) (int, error) {
+	return s.daily, nil
+}
+
+func (s *stubSubmissionQuotaRepo) CountInContest(context.Context, int64, int64) (int, error) {
+	return s.inCtx, nil
+}
+
+func TestSubmissionQuotaOverrideAndHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := Config{SubmissionDailyQuota: 10, SubmissionContestQuota: 50}
+	five := 5
+	repo := &stubSubmissionQuotaRepo{override: &SubmissionQuotaOverride{Contest: &five}, daily: 3, inCtx: 4}
+	contestID := int64(2)
+	check := func(user *UserRecord) (*httptest.ResponseRecorder, bool) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/submissions", nil)
+		return w, checkSubmissionQuota(c, cfg, repo, user, &contestID)
+	}
+
+	w, ok := check(&UserRecord{ID: 1, Role: "user"})
+	if !ok || w.Header().Get("X-Quota-Daily-Remaining") != "6" || w.Header().Get("X-Quota-Contest-Limit") != "5" || w.Header().Get("X-Quota-Contest-Remaining") != "0" {
+		t.Fatalf("ok=%v headers=%v", ok, w.Header())
+	}
+
+	repo.inCtx = 5
+	if w, ok := check(&UserRecord{ID: 1, Role: "user"}); ok || w.Code != http.StatusTooManyRequests {
+		t.Fatalf("contest quota not enforced: ok=%v status=%d", ok, w.Code)
+	}
+	if w, ok := check(&UserRecord{ID: 2, Role: "admin"}); !ok || w.Header().Get("X-Quota-Daily-Limit") != "" {
+		t.Fatal("staff must not be limited")
+	}
+}
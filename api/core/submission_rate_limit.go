@@ -0,0 +1,155 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+const submissionRateKey = "ratelimit:submissions:"
+
+// SubmissionRateRule allows Limit submissions per Window.
+type SubmissionRateRule struct {
+	Limit  int
+	Window time.Duration
+}
+
+// ParseSubmissionRateLimits parses SUBMISSION_RATE_LIMITS, e.g. "1/10s,60/1h". Empty disables limiting.
+func ParseSubmissionRateLimits(s string) ([]SubmissionRateRule, error) {
+	var rules []SubmissionRateRule
+	for _, part := range parseCSV(s) {
+		count, window, ok := strings.Cut(part, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit %q: want COUNT/DURATION", part)
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(count))
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("invalid rate limit %q: count must be a positive integer", part)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(window))
+		if err != nil || d < time.Second {
+			return nil, fmt.Errorf("invalid rate limit %q: window must be a duration of at least 1s", part)
+		}
+		rules = append(rules, SubmissionRateRule{Limit: limit, Window: d})
+	}
+	return rules, nil
+}
+
+// submissionRateScript checks every window before counting, so a rejected attempt does not
+// consume the longer windows. It returns the milliseconds to wait, or 0 when counted.
+var submissionRateScript = redis.NewScript(`
+local n = #KEYS
+local wait = 0
+for i = 1, n do
+  local count = tonumber(redis.call('GET', KEYS[i]) or '0')
+  if count >= tonumber(ARGV[i]) then
+    local ttl = redis.call('PTTL', KEYS[i])
+    if ttl < 0 then ttl = tonumber(ARGV[n + i]) end
+    if ttl > wait then wait = ttl end
+  end
+end
+if wait > 0 then return wait end
+for i = 1, n do
+  if redis.call('INCR', KEYS[i]) == 1 then
+    redis.call('PEXPIRE', KEYS[i], ARGV[n + i])
+  end
+end
+return 0
+`)
+
+// SubmissionRateLimiter counts submissions per user in fixed Redis windows.
+type SubmissionRateLimiter struct {
+	client *redis.Client
+	rules  []SubmissionRateRule
+}
+
+func NewSubmissionRateLimiter(client *redis.Client, rules []SubmissionRateRule) *SubmissionRateLimiter {
+	return &SubmissionRateLimiter{client: client, rules: rules}
+}
+
+// Allow counts one submission for username, or returns how long to wait when a window is full.
+func (l *SubmissionRateLimiter) Allow(ctx context.Context, username string) (time.Duration, error) {
+	return l.allow(ctx, username, l.rules, nil)
+}
+
+// AllowRole applies the per-minute quota of role together with SUBMISSION_RATE_LIMITS (which staff
+// are exempt from), counting the submission only when every window has room.
+func (l *SubmissionRateLimiter) AllowRole(ctx context.Context, username, role string) (time.Duration, error) {
+	quotas, err := GetSubmissionRoleQuotas(ctx, l.client)
+	if err != nil {
+		return 0, err
+	}
+	var roleRules []SubmissionRateRule
+	limit, ok := quotas[role]
+	if !ok {
+		limit = quotas[RoleUser]
+	}
+	if limit > 0 {
+		roleRules = []SubmissionRateRule{{Limit: limit, Window: time.Minute}}
+	}
+	var rules []SubmissionRateRule
+	if !isStaffRole(role) {
+		rules = l.rules
+	}
+	return l.allow(ctx, username, rules, roleRules)
+}
+
+// allow runs the script over rules and roleRules; the latter are counted under their own keys so that
+// a role quota and an env rule of the same window do not share a counter.
+func (l *SubmissionRateLimiter) allow(ctx context.Context, username string, rules, roleRules []SubmissionRateRule) (time.Duration, error) {
+	n := len(rules) + len(roleRules)
+	if n == 0 {
+		return 0, nil
+	}
+	keys := make([]string, 0, n)
+	args := make([]any, 2*n)
+	for _, r := range rules {
+		keys = append(keys, submissionRateKey+r.Window.String()+":"+username)
+	}
+	for _, r := range roleRules {
+		keys = append(keys, submissionRateKey+"role:"+r.Window.String()+":"+username)
+	}
+	for i, r := range append(append([]SubmissionRateRule{}, rules...), roleRules...) {
+		args[i] = r.Limit
+		args[n+i] = r.Window.Milliseconds()
+	}
+	ms, err := submissionRateScript.Run(ctx, l.client, keys, args...).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// SubmissionRateLimitMiddleware rejects submissions over the limit with 429 and Retry-After.
+// Every role is held to its per-minute quota, and users additionally to SUBMISSION_RATE_LIMITS.
+// Requests without a login are left for the handler to reject. Redis errors let the submission through.
+func SubmissionRateLimitMiddleware(limiter *SubmissionRateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		username := sessionUsername(c)
+		if username == "" {
+			c.Next()
+			return
+		}
+		wait, err := limiter.AllowRole(c.Request.Context(), username, sessionRole(c))
+		if err != nil {
+			log.Printf("submission rate limit check failed: %v", err)
+			c.Next()
+			return
+		}
+		if wait > 0 {
+			seconds := int((wait + time.Second - 1) / time.Second)
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			respondError(c, http.StatusTooManyRequests, "RATE_LIMITED", fmt.Sprintf("提出が多すぎます。%d 秒後に再度提出してください", seconds))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
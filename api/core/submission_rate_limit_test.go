@@ -0,0 +1,112 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestParseSubmissionRateLimits(t *testing.T) {
+	rules, err := ParseSubmissionRateLimits("1/10s, 60/1h")
+	if err != nil || len(rules) != 2 || rules[0] != (SubmissionRateRule{1, 10 * time.Second}) || rules[1] != (SubmissionRateRule{60, time.Hour}) {
+		t.Fatalf("got %+v, %v", rules, err)
+	}
+	if rules, err := ParseSubmissionRateLimits(""); err != nil || rules != nil {
+		t.Fatalf("empty: got %+v, %v", rules, err)
+	}
+	for _, bad := range []string{"10", "0/10s", "x/10s", "1/10", "1/500ms"} {
+		if _, err := ParseSubmissionRateLimits(bad); err == nil {
+			t.Fatalf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestSubmissionRateLimiter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	limiter := NewSubmissionRateLimiter(client, []SubmissionRateRule{{1, 10 * time.Second}, {3, time.Hour}})
+	ctx := context.Background()
+
+	allow := func(user string) time.Duration {
+		wait, err := limiter.Allow(ctx, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return wait
+	}
+	if wait := allow("alice"); wait != 0 {
+		t.Fatalf("first submission blocked for %v", wait)
+	}
+	if wait := allow("alice"); wait <= 0 || wait > 10*time.Second {
+		t.Fatalf("second submission within 10s: wait %v", wait)
+	}
+	if wait := allow("bob"); wait != 0 {
+		t.Fatalf("other users are limited separately, got %v", wait)
+	}
+	// 拒否された試行は 1 時間枠を消費しない
+	for i := 0; i < 2; i++ {
+		mr.FastForward(10 * time.Second)
+		if wait := allow("alice"); wait != 0 {
+			t.Fatalf("submission %d blocked for %v", i+2, wait)
+		}
+	}
+	mr.FastForward(10 * time.Second)
+	if wait := allow("alice"); wait <= 10*time.Second {
+		t.Fatalf("hourly limit should apply, got %v", wait)
+	}
+}
+
+func TestSubmissionRoleQuotas(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	limiter := NewSubmissionRateLimiter(client, nil)
+	ctx := context.Background()
+
+	allow := func(user, role string) time.Duration {
+		wait, err := limiter.AllowRole(ctx, user, role)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return wait
+	}
+	for i := 0; i < 6; i++ {
+		if wait := allow("alice", RoleUser); wait != 0 {
+			t.Fatalf("submission %d blocked for %v", i+1, wait)
+		}
+	}
+	if wait := allow("alice", RoleUser); wait <= 0 || wait > time.Minute {
+		t.Fatalf("7th submission within a minute: wait %v", wait)
+	}
+	for i := 0; i < 50; i++ {
+		if wait := allow("root", RoleAdmin); wait != 0 {
+			t.Fatal("admin is unlimited by default")
+		}
+	}
+
+	if err := SetSubmissionRoleQuotas(ctx, client, map[string]int{RoleUser: 10, RoleAdmin: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if wait := allow("alice", RoleUser); wait != 0 {
+		t.Fatalf("raised quota should apply at once, got %v", wait)
+	}
+	allow("root2", RoleAdmin)
+	allow("root2", RoleAdmin)
+	if wait := allow("root2", RoleAdmin); wait <= 0 {
+		t.Fatal("admin quota override should apply")
+	}
+	quotas, err := GetSubmissionRoleQuotas(ctx, client)
+	if err != nil || quotas[RoleSetter] != 30 || quotas[RoleUser] != 10 {
+		t.Fatalf("got %v, %v", quotas, err)
+	}
+	for _, bad := range []map[string]int{{"root": 1}, {RoleUser: -1}, {}} {
+		if err := SetSubmissionRoleQuotas(ctx, client, bad); !errors.Is(err, ErrSubmissionQuotaInput) {
+			t.Errorf("%v: expected ErrSubmissionQuotaInput, got %v", bad, err)
+		}
+	}
+}
@@ -21,12 +21,18 @@ type Submission struct {
 	SourcePath string
 	Status     string
 	CreatedAt  time.Time
+	// ScoringMode is the scoring mode of the contest the submission belongs to ("" outside contests).
+	ScoringMode string
+	// RunAllTestcases overrides fail-fast judging for this submission only.
+	RunAllTestcases bool
 }
 
 // SubmissionResult holds judge outcome.
 type SubmissionResult struct {
 	SubmissionID int64
 	Verdict      string
+	// JudgedBy is the ID of the worker that produced the result.
+	JudgedBy     string
 	TimeMS       *int32
 	MemoryKB     *int32
 	StdoutPath   *string
@@ -35,30 +41,72 @@ type SubmissionResult struct {
 	ErrorMessage *string
 	UpdatedAt    time.Time
 	Details      []SubmissionJudgeDetail
+	// AC で制限の closeCallRatio 以上を使用した場合に立つ
+	CloseCallTime   bool
+	CloseCallMemory bool
+	PassedCount     int32
+	TotalCount      int32
+	// Score / MaxScore are set for scored (partial) judging only.
+	Score    *int32
+	MaxScore *int32
+	// Subtasks is the per-subtask breakdown for problems that define subtasks.
+	Subtasks []SubtaskResult
+	// Signature is the worker's HMAC over the result (see SignResult); empty when signing is disabled.
+	Signature string
+	// SEReason classifies System Errors (see ClassifySystemError); empty for other verdicts.
+	SEReason string
+	// Rechecked is set when the verdict was recomputed from stored outputs (see WorkerProcessor.recheck).
+	Rechecked bool
 }
 
 // SubmissionJudgeDetail represents per-testcase execution detail.
 type SubmissionJudgeDetail struct {
-	Testcase string `json:"testcase"`
-	Status   string `json:"status"`
-	TimeMS   *int32 `json:"time_ms,omitempty"`
-	MemoryKB *int32 `json:"memory_kb,omitempty"`
+	Testcase    string `json:"testcase"`
+	Status      string `json:"status"`
+	TimeMS      *int32 `json:"time_ms,omitempty"`
+	MemoryKB    *int32 `json:"memory_kb,omitempty"`
+	InputBytes  *int32 `json:"input_bytes,omitempty"`
+	OutputBytes *int32 `json:"output_bytes,omitempty"`
+	// Group is the testcase group whose limits applied ("" when ungrouped).
+	Group string `json:"group,omitempty"`
+	// Artifacts holds download links for stored stdout/stderr (admin only).
+	Artifacts  map[string]string `json:"artifacts,omitempty"`
+	StdoutPath *string           `json:"-"`
+	StderrPath *string           `json:"-"`
 }
 
 // SubmissionRepository defines persistence operations needed by worker/API.
 type SubmissionRepository interface {
 	FindByID(ctx context.Context, id int64) (*Submission, error)
 	MarkStatus(ctx context.Context, id int64, status string) error
+	// Statuses returns the status of each existing submission among ids (missing ones are absent).
+	Statuses(ctx context.Context, ids []int64) (map[int64]string, error)
+	// QueueStates returns status and timestamps of each existing submission among ids, for queue inspection.
+	QueueStates(ctx context.Context, ids []int64) (map[int64]SubmissionQueueState, error)
 	SaveResult(ctx context.Context, result SubmissionResult, finalStatus string) error
-	Create(ctx context.Context, userID, problemID int64, language, sourcePath string) (int64, time.Time, error)
+	Create(ctx context.Context, userID, problemID int64, contestID *int64, language, sourcePath string) (int64, time.Time, error)
 	Delete(ctx context.Context, id int64) error
+	SaveClientInfo(ctx context.Context, submissionID int64, ip, userAgent string) error
+	ClientInfo(ctx context.Context, submissionID int64) (*SubmissionClientInfo, error)
+	SearchClientInfo(ctx context.Context, filter SubmissionClientFilter, limit int) ([]SubmissionClientInfo, error)
+	PurgeClientInfo(ctx context.Context, before time.Time) (int64, error)
+	FindStoredResult(ctx context.Context, submissionID int64) (*SubmissionResult, error)
+	RecheckRequested(ctx context.Context, submissionID int64) (bool, error)
 	FindWithResult(ctx context.Context, id int64) (*SubmissionResultView, error)
+	// IsFirstContestAC reports whether the submission is its user's first AC for the problem in its contest.
+	IsFirstContestAC(ctx context.Context, id int64) (bool, error)
+	// ClaimRejudgeStandings returns, once, the contests recomputed by rejudge jobs the submission completed.
+	ClaimRejudgeStandings(ctx context.Context, submissionID int64) ([]RejudgeStandingsUpdate, error)
 	AcquirePending(ctx context.Context, id int64) (*Submission, error)
 	IncrementRetry(ctx context.Context, id int64) (int, error)
 	CountByUser(ctx context.Context, userID int64) (int, error)
+	// Progress aggregates the user's submissions per problem; nil problemIDs covers every problem tried.
+	Progress(ctx context.Context, userID int64, problemIDs []int64) ([]ProblemProgress, error)
 	CountSolvedProblemsByUser(ctx context.Context, userID int64) (int, error)
-	ListByUser(ctx context.Context, userID int64, problemID *int64, page, perPage int) ([]SubmissionListItem, int, error)
-	ListByProblem(ctx context.Context, problemID int64, page, perPage int) ([]SubmissionListItem, int, error)
+	ListByUser(ctx context.Context, userID int64, filter SubmissionListFilter, page, perPage int) ([]SubmissionListItem, int, error)
+	ListByProblem(ctx context.Context, problemID int64, filter SubmissionListFilter, page, perPage int) ([]SubmissionListItem, int, error)
+	ListByUserAfter(ctx context.Context, userID int64, filter SubmissionListFilter, after *SubmissionCursor, limit int) ([]SubmissionListItem, *SubmissionCursor, error)
+	ListByProblemAfter(ctx context.Context, problemID int64, filter SubmissionListFilter, after *SubmissionCursor, limit int) ([]SubmissionListItem, *SubmissionCursor, error)
 }
 
 // PgSubmissionRepository is a pgx implementation.
@@ -82,6 +130,48 @@ func (r *PgSubmissionRepository) FindByID(ctx context.Context, id int64) (*Submi
 	return &s, nil
 }
 
+func (r *PgSubmissionRepository) Statuses(ctx context.Context, ids []int64) (map[int64]string, error) {
+	statuses := make(map[int64]string, len(ids))
+	if len(ids) == 0 {
+		return statuses, nil
+	}
+	rows, err := r.db.Query(ctx, `SELECT id, status FROM submissions WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var status string
+		if err := rows.Scan(&id, &status); err != nil {
+			return nil, err
+		}
+		statuses[id] = status
+	}
+	return statuses, rows.Err()
+}
+
+func (r *PgSubmissionRepository) QueueStates(ctx context.Context, ids []int64) (map[int64]SubmissionQueueState, error) {
+	states := make(map[int64]SubmissionQueueState, len(ids))
+	if len(ids) == 0 {
+		return states, nil
+	}
+	rows, err := r.db.Query(ctx, `SELECT id, status, problem_id, created_at, updated_at FROM submissions WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var st SubmissionQueueState
+		if err := rows.Scan(&id, &st.Status, &st.ProblemID, &st.CreatedAt, &st.UpdatedAt); err != nil {
+			return nil, err
+		}
+		states[id] = st
+	}
+	return states, rows.Err()
+}
+
 func (r *PgSubmissionRepository) MarkStatus(ctx context.Context, id int64, status string) error {
 	if status == "" {
 		return errors.New("status is empty")
@@ -111,8 +201,8 @@ func (r *PgSubmissionRepository) SaveResult(ctx context.Context, result Submissi
 		return errors.New("submission not found")
 	}
 
-	const q = `INSERT INTO submission_results (submission_id, verdict, time_ms, memory_kb, stdout_path, stderr_path, exit_code, error_message, updated_at)
-               VALUES ($1,$2,$3,$4,$5,$6,$7,$8,NOW())
+	const q = `INSERT INTO submission_results (submission_id, verdict, time_ms, memory_kb, stdout_path, stderr_path, exit_code, error_message, close_call_time, close_call_memory, passed_count, total_count, score, max_score, judged_by, signature, subtask_results, se_reason, updated_at)
+               VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,NOW())
                ON CONFLICT (submission_id) DO UPDATE SET
                  verdict=EXCLUDED.verdict,
                  time_ms=EXCLUDED.time_ms,
@@ -121,9 +211,20 @@ func (r *PgSubmissionRepository) SaveResult(ctx context.Context, result Submissi
                  stderr_path=EXCLUDED.stderr_path,
                  exit_code=EXCLUDED.exit_code,
                  error_message=EXCLUDED.error_message,
+                 close_call_time=EXCLUDED.close_call_time,
+                 close_call_memory=EXCLUDED.close_call_memory,
+                 passed_count=EXCLUDED.passed_count,
+                 total_count=EXCLUDED.total_count,
+                 score=EXCLUDED.score,
+                 max_score=EXCLUDED.max_score,
+                 judged_by=EXCLUDED.judged_by,
+                 signature=EXCLUDED.signature,
+                 subtask_results=EXCLUDED.subtask_results,
+                 se_reason=EXCLUDED.se_reason,
                  updated_at=NOW()`
 
-	if _, err := tx.Exec(ctx, q, result.SubmissionID, result.Verdict, result.TimeMS, result.MemoryKB, result.StdoutPath, result.StderrPath, result.ExitCode, result.ErrorMessage); err != nil {
+	if _, err := tx.Exec(ctx, q, result.SubmissionID, result.Verdict, result.TimeMS, result.MemoryKB, result.StdoutPath, result.StderrPath, result.ExitCode, result.ErrorMessage, result.CloseCallTime, result.CloseCallMemory,
+		result.PassedCount, result.TotalCount, result.Score, result.MaxScore, stringPtrIfNotEmpty(result.JudgedBy), stringPtrIfNotEmpty(result.Signature), subtaskResultsArg(result.Subtasks), stringPtrIfNotEmpty(result.SEReason)); err != nil {
 		return err
 	}
 
@@ -132,29 +233,59 @@ func (r *PgSubmissionRepository) SaveResult(ctx context.Context, result Submissi
 		return err
 	}
 	for _, d := range result.Details {
-		if _, err := tx.Exec(ctx, `INSERT INTO submission_result_details (submission_id, testcase, status, time_ms, memory_kb)
-VALUES ($1,$2,$3,$4,$5)`, result.SubmissionID, d.Testcase, d.Status, d.TimeMS, d.MemoryKB); err != nil {
+		if _, err := tx.Exec(ctx, `INSERT INTO submission_result_details (submission_id, testcase, status, time_ms, memory_kb, input_bytes, output_bytes, stdout_path, stderr_path, group_name)
+VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)`, result.SubmissionID, d.Testcase, d.Status, d.TimeMS, d.MemoryKB, d.InputBytes, d.OutputBytes, d.StdoutPath, d.StderrPath, stringPtrIfNotEmpty(d.Group)); err != nil {
 			return err
 		}
 	}
 
+	// コンテスト提出なら順位表の該当セルを更新
+	if err := refreshContestStandingCell(ctx, tx, result.SubmissionID); err != nil {
+		return err
+	}
+	// リジャッジ中なら進捗を進める
+	if err := advanceRejudgeItems(ctx, tx, result.SubmissionID, result.Verdict, result.Rechecked); err != nil {
+		return err
+	}
+	// リジャッジの最後の提出なら、ジョブが触れた順位表をまとめて再計算する
+	if err := finishRejudgeJobs(ctx, tx, result.SubmissionID); err != nil {
+		return err
+	}
+	// 採点系 Webhook の配信をキューに積む
+	if err := enqueueGraderWebhooks(ctx, tx, result); err != nil {
+		return err
+	}
+
 	return tx.Commit(ctx)
 }
 
-func (r *PgSubmissionRepository) Create(ctx context.Context, userID, problemID int64, language, sourcePath string) (int64, time.Time, error) {
-	const q = `INSERT INTO submissions (user_id, problem_id, language, source_path, status)
-			VALUES ($1,$2,$3,$4,'pending') RETURNING id, created_at`
+// Create inserts a pending submission. contestID is set for submissions made inside a contest.
+func (r *PgSubmissionRepository) Create(ctx context.Context, userID, problemID int64, contestID *int64, language, sourcePath string) (int64, time.Time, error) {
+	const q = `INSERT INTO submissions (user_id, problem_id, contest_id, language, source_path, status)
+			VALUES ($1,$2,$3,$4,$5,'pending') RETURNING id, created_at`
 	var id int64
 	var created time.Time
-	if err := r.db.QueryRow(ctx, q, userID, problemID, language, sourcePath).Scan(&id, &created); err != nil {
+	if err := r.db.QueryRow(ctx, q, userID, problemID, contestID, language, sourcePath).Scan(&id, &created); err != nil {
 		return 0, time.Time{}, err
 	}
+	if contestID != nil {
+		// 判定待ちを順位表に反映 (失敗しても判定確定時に再計算される)
+		_ = refreshContestStandingCell(ctx, r.db, id)
+	}
 	return id, created, nil
 }
 
 func (r *PgSubmissionRepository) Delete(ctx context.Context, id int64) error {
-	_, err := r.db.Exec(ctx, `DELETE FROM submissions WHERE id=$1`, id)
-	return err
+	var contestID *int64
+	var userID, problemID int64
+	err := r.db.QueryRow(ctx, `DELETE FROM submissions WHERE id=$1 RETURNING contest_id, user_id, problem_id`, id).Scan(&contestID, &userID, &problemID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil || contestID == nil {
+		return err
+	}
+	return recomputeStandingCell(ctx, r.db, *contestID, userID, problemID)
 }
 
 // AcquirePending locks a pending submission and transitions it to running atomically.
@@ -167,9 +298,13 @@ func (r *PgSubmissionRepository) AcquirePending(ctx context.Context, id int64) (
 		_ = tx.Rollback(ctx)
 	}()
 
-	const sel = `SELECT id, user_id, problem_id, language, source_path, status, created_at FROM submissions WHERE id=$1 FOR UPDATE`
+	const sel = `SELECT s.id, s.user_id, s.problem_id, s.language, s.source_path, s.status, s.created_at, COALESCE(c.scoring_mode, ''), s.run_all_testcases
+FROM submissions s
+LEFT JOIN contests c ON c.id = s.contest_id
+WHERE s.id=$1
+FOR UPDATE OF s`
 	var s Submission
-	if err := tx.QueryRow(ctx, sel, id).Scan(&s.ID, &s.UserID, &s.ProblemID, &s.Language, &s.SourcePath, &s.Status, &s.CreatedAt); err != nil {
+	if err := tx.QueryRow(ctx, sel, id).Scan(&s.ID, &s.UserID, &s.ProblemID, &s.Language, &s.SourcePath, &s.Status, &s.CreatedAt, &s.ScoringMode, &s.RunAllTestcases); err != nil {
 		return nil, err
 	}
 	if s.Status != "pending" {
@@ -212,7 +347,7 @@ func (r *PgSubmissionRepository) CountByUser(ctx context.Context, userID int64)
 func (r *PgSubmissionRepository) CountSolvedProblemsByUser(ctx context.Context, userID int64) (int, error) {
 	const q = `SELECT COUNT(DISTINCT s.problem_id) FROM submissions s
 LEFT JOIN submission_results r ON r.submission_id = s.id
-WHERE s.user_id=$1 AND r.verdict='AC'`
+WHERE s.user_id=$1 AND r.verdict='` + VerdictAC + `'`
 	var c int
 	if err := r.db.QueryRow(ctx, q, userID).Scan(&c); err != nil {
 		return 0, err
@@ -227,6 +362,7 @@ type SubmissionResultView struct {
 	Username     string                  `json:"userid"`
 	ProblemID    int64                   `json:"problem_id"`
 	ProblemTitle string                  `json:"problem_title"`
+	ContestID    *int64                  `json:"contest_id"`
 	Language     string                  `json:"language"`
 	Status       string                  `json:"status"`
 	CreatedAt    time.Time               `json:"created_at"`
@@ -238,6 +374,11 @@ type SubmissionResultView struct {
 	StderrPath   *string                 `json:"stderr_path"`
 	ExitCode     *int32                  `json:"exit_code"`
 	ErrorMsg     *string                 `json:"error_message"`
+	PassedCount  *int32                  `json:"passed_count"`
+	TotalCount   *int32                  `json:"total_count"`
+	Score        *int32                  `json:"score"`
+	MaxScore     *int32                  `json:"max_score"`
+	Subtasks     []SubtaskResult         `json:"subtasks"`
 	SourcePath   string                  `json:"-"`
 	Details      []SubmissionJudgeDetail `json:"judge_details"`
 }
@@ -259,9 +400,10 @@ type SubmissionListItem struct {
 
 func (r *PgSubmissionRepository) FindWithResult(ctx context.Context, id int64) (*SubmissionResultView, error) {
 	const q = `
-SELECT s.id, s.user_id, u.username, s.problem_id, p.title, s.language, s.status, s.source_path,
+SELECT s.id, s.user_id, u.username, s.problem_id, p.title, s.contest_id, s.language, s.status, s.source_path,
        s.created_at, s.updated_at,
-       sr.verdict, sr.time_ms, sr.memory_kb, sr.stdout_path, sr.stderr_path, sr.exit_code, sr.error_message
+       sr.verdict, sr.time_ms, sr.memory_kb, sr.stdout_path, sr.stderr_path, sr.exit_code, sr.error_message,
+       sr.passed_count, sr.total_count, sr.score, sr.max_score, sr.subtask_results
 FROM submissions s
 JOIN users u ON u.id = s.user_id
 JOIN problems p ON p.id = s.problem_id
@@ -272,9 +414,10 @@ WHERE s.id=$1`
 	var timeMS, memoryKB sql.NullInt32
 	var exitCode sql.NullInt32
 	if err := r.db.QueryRow(ctx, q, id).Scan(
-		&v.ID, &v.UserID, &v.Username, &v.ProblemID, &v.ProblemTitle, &v.Language, &v.Status, &v.SourcePath,
+		&v.ID, &v.UserID, &v.Username, &v.ProblemID, &v.ProblemTitle, &v.ContestID, &v.Language, &v.Status, &v.SourcePath,
 		&v.CreatedAt, &v.UpdatedAt,
 		&verdict, &timeMS, &memoryKB, &stdoutPath, &stderrPath, &exitCode, &errMsg,
+		&v.PassedCount, &v.TotalCount, &v.Score, &v.MaxScore, &v.Subtasks,
 	); err != nil {
 		return nil, err
 	}
@@ -301,24 +444,30 @@ WHERE s.id=$1`
 	}
 
 	// load judge details (if any)
-	const detailQ = `SELECT testcase, status, time_ms, memory_kb FROM submission_result_details WHERE submission_id=$1 ORDER BY id`
+	const detailQ = `SELECT testcase, status, time_ms, memory_kb, input_bytes, output_bytes, stdout_path, stderr_path, COALESCE(group_name, '')
+FROM submission_result_details WHERE submission_id=$1 ORDER BY id`
 	rows, err := r.db.Query(ctx, detailQ, id)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	for rows.Next() {
-		var tc, status string
-		var t sql.NullInt32
-		var m sql.NullInt32
-		if err := rows.Scan(&tc, &status, &t, &m); err != nil {
+		var tc, status, group string
+		var t, m, inBytes, outBytes sql.NullInt32
+		var stdoutP, stderrP sql.NullString
+		if err := rows.Scan(&tc, &status, &t, &m, &inBytes, &outBytes, &stdoutP, &stderrP, &group); err != nil {
 			return nil, err
 		}
 		v.Details = append(v.Details, SubmissionJudgeDetail{
-			Testcase: tc,
-			Status:   status,
-			TimeMS:   ptrFromNullInt32(t),
-			MemoryKB: ptrFromNullInt32(m),
+			Testcase:    tc,
+			Status:      status,
+			TimeMS:      ptrFromNullInt32(t),
+			MemoryKB:    ptrFromNullInt32(m),
+			InputBytes:  ptrFromNullInt32(inBytes),
+			OutputBytes: ptrFromNullInt32(outBytes),
+			StdoutPath:  ptrFromNullString(stdoutP),
+			StderrPath:  ptrFromNullString(stderrP),
+			Group:       group,
 		})
 	}
 	if err := rows.Err(); err != nil {
@@ -327,79 +476,62 @@ WHERE s.id=$1`
 	return &v, nil
 }
 
-func (r *PgSubmissionRepository) ListByUser(ctx context.Context, userID int64, problemID *int64, page, perPage int) ([]SubmissionListItem, int, error) {
-	if page <= 0 || perPage <= 0 {
-		return nil, 0, errors.New("invalid pagination")
-	}
-
-	filters := []string{"s.user_id=$1"}
-	args := []interface{}{userID}
-	if problemID != nil && *problemID > 0 {
-		filters = append(filters, fmt.Sprintf("s.problem_id=$%d", len(args)+1))
-		args = append(args, *problemID)
-	}
-	where := strings.Join(filters, " AND ")
-
-	countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM submissions s WHERE %s`, where)
-	var total int
-	if err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
-		return nil, 0, err
-	}
-
-	limitPlaceholder := len(args) + 1
-	offsetPlaceholder := len(args) + 2
-	query := fmt.Sprintf(`
-SELECT s.id, s.user_id, u.username, s.problem_id, p.title, s.language, s.status,
-       sr.verdict, sr.time_ms, sr.memory_kb, s.created_at
+func (r *PgSubmissionRepository) IsFirstContestAC(ctx context.Context, id int64) (bool, error) {
+	var first bool
+	err := r.db.QueryRow(ctx, `
+SELECT NOT EXISTS (
+    SELECT 1 FROM submissions o
+    JOIN submission_results osr ON osr.submission_id = o.id
+    WHERE o.contest_id = s.contest_id AND o.user_id = s.user_id AND o.problem_id = s.problem_id
+      AND osr.verdict = $2 AND o.id < s.id)
 FROM submissions s
-JOIN users u ON u.id = s.user_id
-JOIN problems p ON p.id = s.problem_id
-LEFT JOIN submission_results sr ON sr.submission_id = s.id
-WHERE %s
-ORDER BY s.created_at DESC
-LIMIT $%d OFFSET $%d`, where, limitPlaceholder, offsetPlaceholder)
-
-	argsWithPage := append(append([]interface{}{}, args...), perPage, (page-1)*perPage)
-	rows, err := r.db.Query(ctx, query, argsWithPage...)
-	if err != nil {
-		return nil, 0, err
+JOIN submission_results sr ON sr.submission_id = s.id
+WHERE s.id=$1 AND s.contest_id IS NOT NULL AND sr.verdict = $2`, id, VerdictAC).Scan(&first)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
 	}
-	defer rows.Close()
+	return first, err
+}
 
-	items := make([]SubmissionListItem, 0, perPage)
-	for rows.Next() {
-		var v SubmissionListItem
-		if err := rows.Scan(&v.ID, &v.UserID, &v.Username, &v.ProblemID, &v.ProblemTitle, &v.Language, &v.Status, &v.Verdict, &v.TimeMS, &v.MemoryKB, &v.CreatedAt); err != nil {
-			return nil, 0, err
-		}
-		items = append(items, v)
-	}
-	return items, total, rows.Err()
+func (r *PgSubmissionRepository) ListByUser(ctx context.Context, userID int64, filter SubmissionListFilter, page, perPage int) ([]SubmissionListItem, int, error) {
+	filters, args := filter.apply([]string{"s.user_id=$1"}, []interface{}{userID})
+	return r.listSubmissionsPage(ctx, filter, filters, args, page, perPage)
 }
 
-func (r *PgSubmissionRepository) ListByProblem(ctx context.Context, problemID int64, page, perPage int) ([]SubmissionListItem, int, error) {
+func (r *PgSubmissionRepository) ListByProblem(ctx context.Context, problemID int64, filter SubmissionListFilter, page, perPage int) ([]SubmissionListItem, int, error) {
+	filters, args := filter.apply([]string{"s.problem_id=$1"}, []interface{}{problemID})
+	return r.listSubmissionsPage(ctx, filter, filters, args, page, perPage)
+}
+
+func (r *PgSubmissionRepository) listSubmissionsPage(ctx context.Context, filter SubmissionListFilter, filters []string, args []interface{}, page, perPage int) ([]SubmissionListItem, int, error) {
 	if page <= 0 || perPage <= 0 {
 		return nil, 0, errors.New("invalid pagination")
 	}
+	where := strings.Join(filters, " AND ")
 
-	const countQuery = `SELECT COUNT(*) FROM submissions WHERE problem_id=$1`
+	// 判定結果で絞り込むときだけ submission_results を結合して数える
+	countFrom := "submissions s"
+	if filter.needsResults() {
+		countFrom += " LEFT JOIN submission_results sr ON sr.submission_id = s.id"
+	}
 	var total int
-	if err := r.db.QueryRow(ctx, countQuery, problemID).Scan(&total); err != nil {
+	if err := r.db.QueryRow(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE %s`, countFrom, where), args...).Scan(&total); err != nil {
 		return nil, 0, err
 	}
 
-	query := `
+	query := fmt.Sprintf(`
 SELECT s.id, s.user_id, u.username, s.problem_id, p.title, s.language, s.status,
        sr.verdict, sr.time_ms, sr.memory_kb, s.created_at
 FROM submissions s
 JOIN users u ON u.id = s.user_id
 JOIN problems p ON p.id = s.problem_id
 LEFT JOIN submission_results sr ON sr.submission_id = s.id
-WHERE s.problem_id=$1
-ORDER BY s.created_at DESC
-LIMIT $2 OFFSET $3`
+WHERE %s
+ORDER BY s.created_at DESC, s.id DESC
+LIMIT $%d OFFSET $%d`, where, len(args)+1, len(args)+2)
 
-	rows, err := r.db.Query(ctx, query, problemID, perPage, (page-1)*perPage)
+	argsWithPage := append(append([]interface{}{}, args...), perPage, (page-1)*perPage)
+	rows, err := r.db.Query(ctx, query, argsWithPage...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -426,3 +558,10 @@ func ptrFromNullInt32(n sql.NullInt32) *int32 {
 	}
 	return ptrInt32(n.Int32)
 }
+
+func ptrFromNullString(n sql.NullString) *string {
+	if !n.Valid {
+		return nil
+	}
+	return &n.String
+}
@@ -0,0 +1,67 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// submissionRoleQuotaKey is a Redis hash of role -> submissions per minute set from the admin API.
+// Roles missing from it use defaultSubmissionRoleQuotas.
+const submissionRoleQuotaKey = "ratelimit:role_quotas"
+
+const maxSubmissionRoleQuota = 10000
+
+var ErrSubmissionQuotaInput = errors.New("invalid submission quota")
+
+// defaultSubmissionRoleQuotas is submissions per minute by role; 0 is unlimited.
+var defaultSubmissionRoleQuotas = map[string]int{
+	RoleUser:   6,
+	RoleTA:     30,
+	RoleSetter: 30,
+	RoleAdmin:  0,
+}
+
+// GetSubmissionRoleQuotas returns the per-minute quota of every role (0 is unlimited).
+func GetSubmissionRoleQuotas(ctx context.Context, client *redis.Client) (map[string]int, error) {
+	stored, err := client.HGetAll(ctx, submissionRoleQuotaKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]int, len(defaultSubmissionRoleQuotas))
+	for role, limit := range defaultSubmissionRoleQuotas {
+		out[role] = limit
+	}
+	for role, v := range stored {
+		if n, err := strconv.Atoi(v); err == nil && ValidRole(role) {
+			out[role] = n
+		}
+	}
+	return out, nil
+}
+
+// SetSubmissionRoleQuotas overrides the quotas of the given roles; the others are unchanged.
+func SetSubmissionRoleQuotas(ctx context.Context, client *redis.Client, quotas map[string]int) error {
+	if len(quotas) == 0 {
+		return fmt.Errorf("%w: ロールを 1 つ以上指定してください", ErrSubmissionQuotaInput)
+	}
+	values := make([]any, 0, 2*len(quotas))
+	for role, limit := range quotas {
+		if !ValidRole(role) {
+			return fmt.Errorf("%w: unknown role %q", ErrSubmissionQuotaInput, role)
+		}
+		if limit < 0 || limit > maxSubmissionRoleQuota {
+			return fmt.Errorf("%w: %s の上限は 0（無制限）〜%d で指定してください", ErrSubmissionQuotaInput, role, maxSubmissionRoleQuota)
+		}
+		values = append(values, role, limit)
+	}
+	return client.HSet(ctx, submissionRoleQuotaKey, values...).Err()
+}
+
+// ResetSubmissionRoleQuotas drops the overrides so that every role uses its default.
+func ResetSubmissionRoleQuotas(ctx context.Context, client *redis.Client) error {
+	return client.Del(ctx, submissionRoleQuotaKey).Err()
+}
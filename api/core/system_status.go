@@ -23,7 +23,14 @@ type SystemStatus struct {
 		UsedBytes  uint64 `json:"used_bytes"`
 		TotalBytes uint64 `json:"total_bytes"`
 	} `json:"memory"`
-	UptimeSeconds int64 `json:"uptime_seconds"`
+	// Redis は疎通の結果。down の間、Queue / Workers は MetricsAt 時点の値（MetricsStale=true）
+	Redis struct {
+		Status string `json:"status"` // ok | down
+		Error  string `json:"error,omitempty"`
+	} `json:"redis"`
+	MetricsStale  bool       `json:"metrics_stale"`
+	MetricsAt     *time.Time `json:"metrics_collected_at"`
+	UptimeSeconds int64      `json:"uptime_seconds"`
 }
 
 // CollectSystemStatus で現在のステータスを集約する。
@@ -32,14 +39,21 @@ func CollectSystemStatus(ctx context.Context, metrics *MetricsService, startedAt
 
 	// Queue
 	if metrics != nil {
-		if qm, err := metrics.Queue(ctx); err == nil {
-			st.Queue.Pending = qm.Pending
-			st.Queue.Processing = qm.Processing
+		st.Redis.Status = "ok"
+		if err := metrics.Ping(ctx); err != nil {
+			st.Redis.Status, st.Redis.Error = "down", err.Error()
 		}
-		workers, _ := metrics.Workers(ctx) // ignore error to keep best-effort
-		st.Workers.Total = len(workers)
+		m := metrics.Overview(ctx)
+		if m.Stale && st.Redis.Status == "ok" {
+			// 疎通はあるが取得に失敗した
+			st.Redis.Error = m.RedisError
+		}
+		st.MetricsStale, st.MetricsAt = m.Stale, m.CollectedAt
+		st.Queue.Pending = m.Queue.Pending
+		st.Queue.Processing = m.Queue.Processing
+		st.Workers.Total = len(m.Workers)
 		active := 0
-		for _, w := range workers {
+		for _, w := range m.Workers {
 			if w.Status != "starting" {
 				active++
 			}
@@ -0,0 +1,313 @@
+package core
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// TOTP（RFC 6238）: HMAC-SHA1・6 桁・30 秒。Google Authenticator などの認証アプリと互換
+const (
+	totpPeriod            = 30
+	totpDigits            = 6
+	totpSkew              = 1 // 前後 1 ステップまでの時計のずれを許す
+	totpRecoveryCodeCount = 10
+
+	totpLoginTTL         = 5 * time.Minute
+	totpLoginKeyPrefix   = "totp:login:"
+	totpLoginMaxAttempts = 5
+)
+
+var (
+	ErrTOTPEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTOTPLogin   = errors.New("invalid or expired two-factor login")
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func newTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// totpCode returns the code of a time step (HOTP of RFC 4226 with the step as the counter).
+func totpCode(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	v := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, v%1000000)
+}
+
+// verifyTOTP checks code against the steps around now and returns the matching step. Steps up to
+// lastStep were already used and are rejected so that an observed code cannot be replayed.
+func verifyTOTP(secret, code string, now time.Time, lastStep int64) (int64, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if hmac.Equal([]byte(totpCode(key, step)), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpURI is the otpauth:// URI that authenticator apps read from a QR code.
+func totpURI(issuer, account, secret string) string {
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(totpDigits))
+	q.Set("period", fmt.Sprint(totpPeriod))
+	return "otpauth://totp/" + url.PathEscape(issuer+":"+account) + "?" + q.Encode()
+}
+
+// newRecoveryCodes returns codes to show once (xxxxx-xxxxx) and the hashes to store.
+func newRecoveryCodes() ([]string, []string) {
+	codes := make([]string, totpRecoveryCodeCount)
+	hashes := make([]string, totpRecoveryCodeCount)
+	for i := range codes {
+		raw := randomHex(5)
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, hashes
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return hashResetToken(code)
+}
+
+// UserTOTP is the two-factor setting of a user. EnabledAt is nil while the setup is unconfirmed.
+type UserTOTP struct {
+	Secret            string
+	EnabledAt         *time.Time
+	LastUsedStep      int64
+	RecoveryCodesLeft int
+}
+
+// TOTPRepository stores TOTP secrets and recovery codes.
+type TOTPRepository interface {
+	// Get returns nil when the user has not started a setup.
+	Get(ctx context.Context, userID int64) (*UserTOTP, error)
+	IsEnabled(ctx context.Context, userID int64) (bool, error)
+	// Begin stores a new unconfirmed secret; it returns ErrTOTPEnabled when 2FA is already on.
+	Begin(ctx context.Context, userID int64, secret string) error
+	// Enable confirms the setup with the step of the first valid code and stores the recovery codes.
+	Enable(ctx context.Context, userID int64, step int64, codeHashes []string) error
+	// UseStep records step as used; it reports false when that step (or a later one) was used already.
+	UseStep(ctx context.Context, userID int64, step int64) (bool, error)
+	// UseRecoveryCode consumes an unused recovery code and reports whether there was one.
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error
+	Disable(ctx context.Context, userID int64) error
+}
+
+type PgTOTPRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPgTOTPRepository(db *pgxpool.Pool) *PgTOTPRepository {
+	return &PgTOTPRepository{db: db}
+}
+
+func (r *PgTOTPRepository) Get(ctx context.Context, userID int64) (*UserTOTP, error) {
+	var t UserTOTP
+	err := r.db.QueryRow(ctx, `
+SELECT t.secret, t.enabled_at, t.last_used_step,
+       (SELECT COUNT(*) FROM user_totp_recovery_codes c WHERE c.user_id = t.user_id AND c.used_at IS NULL)
+FROM user_totp t WHERE t.user_id=$1`, userID).Scan(&t.Secret, &t.EnabledAt, &t.LastUsedStep, &t.RecoveryCodesLeft)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *PgTOTPRepository) IsEnabled(ctx context.Context, userID int64) (bool, error) {
+	var enabled bool
+	err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM user_totp WHERE user_id=$1 AND enabled_at IS NOT NULL)`, userID).Scan(&enabled)
+	return enabled, err
+}
+
+func (r *PgTOTPRepository) Begin(ctx context.Context, userID int64, secret string) error {
+	ct, err := r.db.Exec(ctx, `
+INSERT INTO user_totp (user_id, secret) VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW()
+WHERE user_totp.enabled_at IS NULL`, userID, secret)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrTOTPEnabled
+	}
+	return nil
+}
+
+func (r *PgTOTPRepository) Enable(ctx context.Context, userID int64, step int64, codeHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	ct, err := tx.Exec(ctx, `UPDATE user_totp SET enabled_at=NOW(), last_used_step=$2 WHERE user_id=$1 AND enabled_at IS NULL`, userID, step)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrTOTPEnabled
+	}
+	if err := replaceRecoveryCodesTx(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *PgTOTPRepository) UseStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	ct, err := r.db.Exec(ctx, `UPDATE user_totp SET last_used_step=$2 WHERE user_id=$1 AND last_used_step < $2`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() == 1, nil
+}
+
+func (r *PgTOTPRepository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	ct, err := r.db.Exec(ctx, `UPDATE user_totp_recovery_codes SET used_at=NOW() WHERE user_id=$1 AND code_hash=$2 AND used_at IS NULL`, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() == 1, nil
+}
+
+func (r *PgTOTPRepository) ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if err := replaceRecoveryCodesTx(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func replaceRecoveryCodesTx(ctx context.Context, tx pgx.Tx, userID int64, codeHashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM user_totp_recovery_codes WHERE user_id=$1`, userID); err != nil {
+		return err
+	}
+	for _, h := range codeHashes {
+		if _, err := tx.Exec(ctx, `INSERT INTO user_totp_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *PgTOTPRepository) Disable(ctx context.Context, userID int64) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+	if _, err := tx.Exec(ctx, `DELETE FROM user_totp_recovery_codes WHERE user_id=$1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM user_totp WHERE user_id=$1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// checkSecondFactor accepts either a current authenticator code or an unused recovery code.
+func checkSecondFactor(ctx context.Context, totpRepo TOTPRepository, userID int64, code, recoveryCode string) (bool, error) {
+	if strings.TrimSpace(recoveryCode) != "" {
+		return totpRepo.UseRecoveryCode(ctx, userID, hashRecoveryCode(recoveryCode))
+	}
+	t, err := totpRepo.Get(ctx, userID)
+	if err != nil || t == nil || t.EnabledAt == nil {
+		return false, err
+	}
+	step, ok := verifyTOTP(t.Secret, code, time.Now(), t.LastUsedStep)
+	if !ok {
+		return false, nil
+	}
+	return totpRepo.UseStep(ctx, userID, step)
+}
+
+// startTOTPLogin remembers a password login that still needs the second factor and returns the
+// token that the client sends back with the code.
+func startTOTPLogin(ctx context.Context, rdb *redis.Client, userID int64, username string) (string, error) {
+	token := randomHex(24)
+	key := totpLoginKeyPrefix + hashResetToken(token)
+	pipe := rdb.TxPipeline()
+	pipe.HSet(ctx, key, "user_id", userID, "username", username, "attempts", 0)
+	pipe.Expire(ctx, key, totpLoginTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// pendingTOTPLogin returns the user of a pending login and counts the attempt. After
+// totpLoginMaxAttempts attempts the login is dropped and the password has to be entered again.
+func pendingTOTPLogin(ctx context.Context, rdb *redis.Client, token string) (int64, string, error) {
+	if token == "" {
+		return 0, "", ErrTOTPLogin
+	}
+	key := totpLoginKeyPrefix + hashResetToken(token)
+	vals, err := rdb.HGetAll(ctx, key).Result()
+	if err != nil {
+		return 0, "", err
+	}
+	var userID int64
+	if _, err := fmt.Sscan(vals["user_id"], &userID); err != nil || userID == 0 {
+		return 0, "", ErrTOTPLogin
+	}
+	attempts, err := rdb.HIncrBy(ctx, key, "attempts", 1).Result()
+	if err != nil {
+		return 0, "", err
+	}
+	if attempts > totpLoginMaxAttempts {
+		rdb.Del(ctx, key)
+		return 0, "", ErrTOTPLogin
+	}
+	return userID, vals["username"], nil
+}
+
+func finishTOTPLogin(ctx context.Context, rdb *redis.Client, token string) {
+	rdb.Del(ctx, totpLoginKeyPrefix+hashResetToken(token))
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"context"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestVerifyTOTP(t *testing.T) {
+	// RFC 6238 付録 B の SHA-1 の例（8 桁の下 6 桁）
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	cases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+	}
+	for _, tc := range cases {
+		step, ok := verifyTOTP(secret, tc.code, time.Unix(tc.unix, 0), 0)
+		if !ok || step != tc.unix/totpPeriod {
+			t.Errorf("unix %d: code %s rejected (step %d)", tc.unix, tc.code, step)
+		}
+		// 一度使ったステップのコードは受け付けない
+		if _, ok := verifyTOTP(secret, tc.code, time.Unix(tc.unix, 0), tc.unix/totpPeriod); ok {
+			t.Errorf("unix %d: replayed code accepted", tc.unix)
+		}
+	}
+	if _, ok := verifyTOTP(secret, "287082", time.Unix(59+5*totpPeriod, 0), 0); ok {
+		t.Error("code outside the allowed skew accepted")
+	}
+	if hashRecoveryCode("AB12C-34DEF") != hashRecoveryCode(" ab12c34def ") {
+		t.Error("recovery codes should match regardless of case and hyphen")
+	}
+	if uri := totpURI("TUIS OJ", "alice", "ABC"); !strings.HasPrefix(uri, "otpauth://totp/TUIS%20OJ:alice?") || !strings.Contains(uri, "secret=ABC") {
+		t.Errorf("unexpected uri %s", uri)
+	}
+}
+
+func TestPendingTOTPLogin(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+
+	token, err := startTOTPLogin(ctx, client, 42, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < totpLoginMaxAttempts; i++ {
+		id, name, err := pendingTOTPLogin(ctx, client, token)
+		if err != nil || id != 42 || name != "alice" {
+			t.Fatalf("attempt %d: %d %q %v", i+1, id, name, err)
+		}
+	}
+	if _, _, err := pendingTOTPLogin(ctx, client, token); !errors.Is(err, ErrTOTPLogin) {
+		t.Fatalf("expected the login to be dropped after too many attempts, got %v", err)
+	}
+	if _, _, err := pendingTOTPLogin(ctx, client, "unknown"); !errors.Is(err, ErrTOTPLogin) {
+		t.Fatalf("expected unknown token to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Kinds of records that go to the recycle bin instead of being deleted immediately.
+const (
+	TrashKindNotice  = "notice"
+	TrashKindProblem = "problem"
+	TrashKindUser    = "user"
+)
+
+// trashTables maps a trash kind to its table and the column shown as the item label.
+var trashTables = map[string]struct{ table, label string }{
+	TrashKindNotice:  {"notices", "title"},
+	TrashKindProblem: {"problems", "title"},
+	TrashKindUser:    {"users", "username"},
+}
+
+// ErrUnknownTrashKind is returned for kinds other than notice / problem / user.
+var ErrUnknownTrashKind = errors.New("unknown trash kind")
+
+// TrashItem is a record waiting in the recycle bin.
+type TrashItem struct {
+	Kind          string     `json:"kind"`
+	ID            int64      `json:"id"`
+	Label         string     `json:"label"`
+	DeletedAt     time.Time  `json:"deleted_at"`
+	DeletedBy     *int64     `json:"deleted_by"`
+	DeletedByName *string    `json:"deleted_by_userid"`
+	PurgeAt       *time.Time `json:"purge_at"` // nil when the recycle bin is never emptied
+}
+
+// TrashRepository moves records in and out of the recycle bin.
+type TrashRepository interface {
+	List(ctx context.Context, kind string) ([]TrashItem, error)
+	Trash(ctx context.Context, kind string, id int64, deletedBy *int64) error
+	Restore(ctx context.Context, kind string, id int64) error
+	Purge(ctx context.Context, kind string, id int64) error
+	PurgeExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type PgTrashRepository struct {
+	db *pgxpool.Pool
+	// retention is only used to report purge_at on listed items (<= 0 keeps forever).
+	retention time.Duration
+}
+
+func NewPgTrashRepository(db *pgxpool.Pool, retention time.Duration) *PgTrashRepository {
+	return &PgTrashRepository{db: db, retention: retention}
+}
+
+// List returns trashed records of the kind newest first; an empty kind lists every kind.
+func (r *PgTrashRepository) List(ctx context.Context, kind string) ([]TrashItem, error) {
+	kinds := []string{TrashKindNotice, TrashKindProblem, TrashKindUser}
+	if kind != "" {
+		if _, ok := trashTables[kind]; !ok {
+			return nil, ErrUnknownTrashKind
+		}
+		kinds = []string{kind}
+	}
+	items := []TrashItem{}
+	for _, k := range kinds {
+		t := trashTables[k]
+		rows, err := r.db.Query(ctx, `
+SELECT x.id, x.`+t.label+`, x.deleted_at, x.deleted_by, u.username
+FROM `+t.table+` x
+LEFT JOIN users u ON u.id = x.deleted_by
+WHERE x.deleted_at IS NOT NULL`)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			it := TrashItem{Kind: k}
+			if err := rows.Scan(&it.ID, &it.Label, &it.DeletedAt, &it.DeletedBy, &it.DeletedByName); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if r.retention > 0 {
+				purgeAt := it.DeletedAt.Add(r.retention)
+				it.PurgeAt = &purgeAt
+			}
+			items = append(items, it)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	// 種別をまたいで新しい順に並べる
+	sort.SliceStable(items, func(i, j int) bool { return items[i].DeletedAt.After(items[j].DeletedAt) })
+	return items, nil
+}
+
+// Trash moves a live record to the recycle bin. Returns pgx.ErrNoRows when it does not exist or is already trashed.
+func (r *PgTrashRepository) Trash(ctx context.Context, kind string, id int64, deletedBy *int64) error {
+	t, ok := trashTables[kind]
+	if !ok {
+		return ErrUnknownTrashKind
+	}
+	ct, err := r.db.Exec(ctx, `UPDATE `+t.table+` SET deleted_at=NOW(), deleted_by=$2 WHERE id=$1 AND deleted_at IS NULL`, id, deletedBy)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// Restore brings a trashed record back. Returns pgx.ErrNoRows when it is not in the recycle bin.
+func (r *PgTrashRepository) Restore(ctx context.Context, kind string, id int64) error {
+	t, ok := trashTables[kind]
+	if !ok {
+		return ErrUnknownTrashKind
+	}
+	ct, err := r.db.Exec(ctx, `UPDATE `+t.table+` SET deleted_at=NULL, deleted_by=NULL WHERE id=$1 AND deleted_at IS NOT NULL`, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// Purge permanently deletes a trashed record (and whatever cascades from it).
+func (r *PgTrashRepository) Purge(ctx context.Context, kind string, id int64) error {
+	t, ok := trashTables[kind]
+	if !ok {
+		return ErrUnknownTrashKind
+	}
+	ct, err := r.db.Exec(ctx, `DELETE FROM `+t.table+` WHERE id=$1 AND deleted_at IS NOT NULL`, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// PurgeExpired permanently deletes every record trashed before the given time.
+func (r *PgTrashRepository) PurgeExpired(ctx context.Context, before time.Time) (int64, error) {
+	var total int64
+	for _, k := range []string{TrashKindNotice, TrashKindProblem, TrashKindUser} {
+		ct, err := r.db.Exec(ctx, `DELETE FROM `+trashTables[k].table+` WHERE deleted_at < $1`, before)
+		if err != nil {
+			return total, err
+		}
+		total += ct.RowsAffected()
+	}
+	return total, nil
+}
+
+// RunTrashPurger empties recycle bin entries older than retention every interval until ctx is cancelled.
+func RunTrashPurger(ctx context.Context, repo TrashRepository, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := repo.PurgeExpired(ctx, time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("[trash] purge failed: %v", err)
+		} else if n > 0 {
+			log.Printf("[trash] purged %d records", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
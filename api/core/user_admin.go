@@ -0,0 +1,144 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrAccountDisabled = errors.New("account is disabled")
+	ErrUserUpdateInput = errors.New("invalid user update")
+	// ErrLastAdmin is returned when an update or a suspension would leave no usable admin.
+	ErrLastAdmin = errors.New("last enabled admin")
+)
+
+// UserUpdateInput is an admin change of an account; nil fields are left unchanged.
+type UserUpdateInput struct {
+	Role     *string `json:"role"`
+	Disabled *bool   `json:"disabled"`
+	Username *string `json:"userid"`
+}
+
+// UserUpdateResult is the account after the update, with the name it had before.
+type UserUpdateResult struct {
+	User         AdminUserListItem
+	FormerUserID string
+	FormerRole   string
+}
+
+type UserAdminRepository interface {
+	// Find returns an account by name, including disabled ones.
+	Find(ctx context.Context, username string) (*AdminUserListItem, error)
+	Update(ctx context.Context, username string, in UserUpdateInput) (*UserUpdateResult, error)
+}
+
+type PgUserAdminRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPgUserAdminRepository(db *pgxpool.Pool) *PgUserAdminRepository {
+	return &PgUserAdminRepository{db: db}
+}
+
+// validateUserUpdate normalises in and checks the values.
+func validateUserUpdate(in *UserUpdateInput) error {
+	if in.Role == nil && in.Disabled == nil && in.Username == nil {
+		return fmt.Errorf("%w: role・disabled・userid のいずれかを指定してください", ErrUserUpdateInput)
+	}
+	if in.Role != nil {
+		role := strings.TrimSpace(*in.Role)
+		if !ValidRole(role) {
+			return fmt.Errorf("%w: invalid role", ErrUserUpdateInput)
+		}
+		in.Role = &role
+	}
+	if in.Username != nil {
+		name := strings.TrimSpace(*in.Username)
+		if !registerUsernamePattern.MatchString(name) {
+			return fmt.Errorf("%w: ユーザー名は 3〜32 文字の英数字と _ . - で指定してください", ErrUserUpdateInput)
+		}
+		in.Username = &name
+	}
+	return nil
+}
+
+func (r *PgUserAdminRepository) Find(ctx context.Context, username string) (*AdminUserListItem, error) {
+	var u AdminUserListItem
+	err := r.db.QueryRow(ctx, `SELECT id, username, role, disabled_at IS NOT NULL, created_at FROM users WHERE username=$1 AND deleted_at IS NULL`, username).
+		Scan(&u.ID, &u.Username, &u.Role, &u.Disabled, &u.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// Update applies in to the account. Returns pgx.ErrNoRows when it does not exist,
+// ErrUsernameTaken on a rename to an existing name, and ErrLastAdmin when it would demote or
+// disable the last usable admin (see requireOtherAdmin).
+func (r *PgUserAdminRepository) Update(ctx context.Context, username string, in UserUpdateInput) (*UserUpdateResult, error) {
+	if err := validateUserUpdate(&in); err != nil {
+		return nil, err
+	}
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	res := &UserUpdateResult{FormerUserID: username}
+	u := &res.User
+	err = tx.QueryRow(ctx, `SELECT id, username, role, disabled_at IS NOT NULL, created_at FROM users WHERE username=$1 AND deleted_at IS NULL FOR UPDATE`, username).
+		Scan(&u.ID, &u.Username, &u.Role, &u.Disabled, &u.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	res.FormerRole = u.Role
+	if in.Role != nil {
+		u.Role = *in.Role
+	}
+	if in.Disabled != nil {
+		u.Disabled = *in.Disabled
+	}
+	if in.Username != nil {
+		u.Username = *in.Username
+	}
+	if res.FormerRole == RoleAdmin && (u.Role != RoleAdmin || u.Disabled) {
+		if err := requireOtherAdmin(ctx, tx, u.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+UPDATE users SET username=$2, role=$3,
+       disabled_at=CASE WHEN $4::boolean THEN COALESCE(disabled_at, NOW()) ELSE NULL END
+WHERE id=$1`, u.ID, u.Username, u.Role, u.Disabled)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrUsernameTaken
+		}
+		return nil, err
+	}
+	return res, tx.Commit(ctx)
+}
+
+// requireOtherAdmin returns ErrLastAdmin unless an admin other than userID can still sign in:
+// neither disabled (disabled_at) nor suspended (is_active). The rows are locked so concurrent
+// updates cannot remove the last one between them.
+func requireOtherAdmin(ctx context.Context, tx pgx.Tx, userID int64) error {
+	var others int
+	if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM (SELECT 1 FROM users
+WHERE role=$1 AND id<>$2 AND deleted_at IS NULL AND disabled_at IS NULL AND is_active FOR UPDATE) a`, RoleAdmin, userID).Scan(&others); err != nil {
+		return err
+	}
+	if others == 0 {
+		return ErrLastAdmin
+	}
+	return nil
+}
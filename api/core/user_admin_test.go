@@ -0,0 +1,24 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateUserUpdate(t *testing.T) {
+	str := func(s string) *string { return &s }
+	for _, in := range []UserUpdateInput{
+		{},
+		{Role: str("owner")},
+		{Username: str("a b")},
+		{Username: str("x")},
+	} {
+		if err := validateUserUpdate(&in); !errors.Is(err, ErrUserUpdateInput) {
+			t.Errorf("%+v: expected ErrUserUpdateInput, got %v", in, err)
+		}
+	}
+	in := UserUpdateInput{Role: str(" ta "), Username: str(" alice_2 ")}
+	if err := validateUserUpdate(&in); err != nil || *in.Role != RoleTA || *in.Username != "alice_2" {
+		t.Fatalf("unexpected result: %v role=%q userid=%q", err, *in.Role, *in.Username)
+	}
+}
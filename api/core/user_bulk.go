@@ -0,0 +1,52 @@
+package core
+
+import (
+	"crypto/rand"
+	"encoding/csv"
+	"io"
+	"math/big"
+)
+
+// bulkPasswordLength is the length of the passwords generated for bulk-created accounts.
+const bulkPasswordLength = 12
+
+// bulkPasswordAlphabet leaves out look-alike characters (0/O, 1/l/I) since the passwords are
+// handed out on paper, and symbols so spreadsheets never read them as formulas.
+const bulkPasswordAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789"
+
+// generateBulkPassword returns a random password for a bulk-created account.
+func generateBulkPassword() (string, error) {
+	size := big.NewInt(int64(len(bulkPasswordAlphabet)))
+	b := make([]byte, bulkPasswordLength)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return "", err
+		}
+		b[i] = bulkPasswordAlphabet[n.Int64()]
+	}
+	return string(b), nil
+}
+
+// BulkUserCredential is an account created by POST /admin/users/bulk with a generated password,
+// returned once so it can be handed to the user.
+type BulkUserCredential struct {
+	RowNumber int    `json:"row_number"`
+	UserID    string `json:"userid"`
+	Password  string `json:"password"`
+}
+
+// writeBulkCredentialsCSV writes the generated credentials as userid,password rows.
+func writeBulkCredentialsCSV(w io.Writer, credentials []BulkUserCredential) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"userid", "password"}); err != nil {
+		return err
+	}
+	for _, cred := range credentials {
+		if err := cw.Write([]string{cred.UserID, cred.Password}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
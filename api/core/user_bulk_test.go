@@ -0,0 +1,31 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBulkPasswordsAndCredentialsCSV(t *testing.T) {
+	a, err := generateBulkPassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := generateBulkPassword()
+	if len(a) != bulkPasswordLength || a == b {
+		t.Fatalf("unexpected passwords %q %q", a, b)
+	}
+	for _, r := range a {
+		if !strings.ContainsRune(bulkPasswordAlphabet, r) {
+			t.Fatalf("password %q has %q outside the alphabet", a, r)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := writeBulkCredentialsCSV(buf, []BulkUserCredential{{RowNumber: 2, UserID: "s001", Password: "abcDEF234567"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "userid,password\ns001,abcDEF234567\n" {
+		t.Fatalf("csv = %q", got)
+	}
+}
@@ -0,0 +1,108 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Modes of UserErasureRepository.Erase, for removal requests that the recycle bin (which can be
+// restored) does not satisfy.
+const (
+	// UserEraseHard deletes the account and everything that cascades from it, submissions included.
+	UserEraseHard = "hard"
+	// UserEraseAnonymize keeps the account's submissions for statistics and standings but renames it
+	// to deleted_<id>, removes its credentials and contact details and scrubs the submitted sources.
+	UserEraseAnonymize = "anonymize"
+)
+
+var ErrUnknownEraseMode = errors.New("unknown erase mode")
+
+// UserErasure is the outcome of an erasure. Username is the new name of an anonymized account;
+// Submissions lists the submissions whose files (SUBMISSION_DIR/<id>) are to be removed.
+type UserErasure struct {
+	UserID       int64
+	Mode         string
+	FormerUserID string
+	Username     string
+	Submissions  []int64
+}
+
+type UserErasureRepository interface {
+	Erase(ctx context.Context, id int64, mode string) (*UserErasure, error)
+}
+
+type PgUserErasureRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPgUserErasureRepository(db *pgxpool.Pool) *PgUserErasureRepository {
+	return &PgUserErasureRepository{db: db}
+}
+
+func anonymizedUsername(id int64) string {
+	return "deleted_" + strconv.FormatInt(id, 10)
+}
+
+// Erase applies mode to the user, whether live or in the recycle bin. Returns pgx.ErrNoRows when
+// the user does not exist.
+func (r *PgUserErasureRepository) Erase(ctx context.Context, id int64, mode string) (*UserErasure, error) {
+	if mode != UserEraseHard && mode != UserEraseAnonymize {
+		return nil, ErrUnknownEraseMode
+	}
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	e := &UserErasure{UserID: id, Mode: mode}
+	if err := tx.QueryRow(ctx, `SELECT username FROM users WHERE id=$1 FOR UPDATE`, id).Scan(&e.FormerUserID); err != nil {
+		return nil, err
+	}
+	// 完全削除では提出ごと、匿名化ではソースのパスだけを消す（source_path が空の提出はリジャッジされない）
+	q := `DELETE FROM submissions WHERE user_id=$1 RETURNING id`
+	if mode == UserEraseAnonymize {
+		q = `UPDATE submissions SET source_path='' WHERE user_id=$1 RETURNING id`
+	}
+	rows, err := tx.Query(ctx, q, id)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var sid int64
+		if err := rows.Scan(&sid); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		e.Submissions = append(e.Submissions, sid)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if mode == UserEraseHard {
+		if _, err := tx.Exec(ctx, `DELETE FROM users WHERE id=$1`, id); err != nil {
+			return nil, err
+		}
+		return e, tx.Commit(ctx)
+	}
+	for _, table := range []string{"user_identities", "api_tokens", "password_reset_tokens", "email_verification_tokens", "user_totp", "user_totp_recovery_codes"} {
+		if _, err := tx.Exec(ctx, `DELETE FROM `+table+` WHERE user_id=$1`, id); err != nil {
+			return nil, err
+		}
+	}
+	// password_hash を空にするとどのパスワードとも一致しない。ごみ箱からは戻し、保持期間後の完全削除の対象から外す
+	e.Username = anonymizedUsername(id)
+	if _, err := tx.Exec(ctx, `
+UPDATE users SET username=$2, password_hash='', role='user', email=NULL, email_verified_at=NULL,
+       deleted_at=NULL, deleted_by=NULL
+WHERE id=$1`, id, e.Username); err != nil {
+		return nil, err
+	}
+	return e, tx.Commit(ctx)
+}
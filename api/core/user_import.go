@@ -0,0 +1,409 @@
+package core
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// User import job states.
+const (
+	UserImportRunning   = "running"
+	UserImportCompleted = "completed"
+	UserImportFailed    = "failed" // 途中で止まった。未処理の行から再開できる
+)
+
+// User import row states.
+const (
+	UserImportRowPending = "pending"
+	UserImportRowCreated = "created"
+	UserImportRowFailed  = "failed"
+)
+
+const (
+	maxUserImportRows       = 5000
+	maxUserImportJobsListed = 50
+	// userImportStallAfter is how long a running job may go without progress before it is treated as
+	// abandoned (e.g. the API instance running it was restarted) and can be resumed.
+	userImportStallAfter = 2 * time.Minute
+)
+
+var (
+	ErrUserImportInput   = errors.New("invalid user import")
+	ErrUserImportRunning = errors.New("user import is running")
+)
+
+// UserImportJob is a bulk creation of users from a CSV and its progress.
+type UserImportJob struct {
+	ID            int64      `json:"id"`
+	RequestedBy   *int64     `json:"requested_by"`
+	RequesterName *string    `json:"requested_by_userid"`
+	Filename      string     `json:"filename"`
+	Status        string     `json:"status"`
+	ErrorMessage  *string    `json:"error_message"`
+	Total         int        `json:"total"`
+	Created       int        `json:"created"`
+	Failed        int        `json:"failed"`
+	Pending       int        `json:"pending"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	FinishedAt    *time.Time `json:"finished_at"`
+	// FailedRows is filled by Get only.
+	FailedRows []UserImportRow `json:"failed_rows,omitempty"`
+}
+
+// UserImportRow is one data row of the CSV (row 1 is the header).
+type UserImportRow struct {
+	RowNumber int    `json:"row_number"`
+	UserID    string `json:"userid"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason,omitempty"`
+	// Password is the password to set; after creation only generated ones are kept (for the result CSV).
+	Password  string `json:"-"`
+	Generated bool   `json:"-"`
+}
+
+// parseUserImportCSV reads a userid[,password] CSV into rows. Rows that cannot be imported are
+// returned already failed; an empty password is replaced with a generated one.
+func parseUserImportCSV(r io.Reader) ([]UserImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil || len(records) == 0 {
+		return nil, fmt.Errorf("%w: CSV を読み取れません", ErrUserImportInput)
+	}
+	// password 列は省略でき、空欄の行には生成したパスワードを設定する
+	header := records[0]
+	if len(header) < 1 || strings.ToLower(strings.TrimSpace(header[0])) != "userid" || (len(header) >= 2 && strings.ToLower(strings.TrimSpace(header[1])) != "password") {
+		return nil, fmt.Errorf("%w: ヘッダーは userid,password 形式にしてください", ErrUserImportInput)
+	}
+	if len(records)-1 > maxUserImportRows {
+		return nil, fmt.Errorf("%w: 1 回に取り込めるのは %d 行までです", ErrUserImportInput, maxUserImportRows)
+	}
+
+	rows := make([]UserImportRow, 0, len(records)-1)
+	for i, rec := range records[1:] {
+		row := UserImportRow{RowNumber: i + 2, Status: UserImportRowPending} // header is row 1
+		if len(rec) > 0 {
+			row.UserID = strings.TrimSpace(rec[0])
+		}
+		switch {
+		case len(rec) < 1:
+			row.Status, row.Reason = UserImportRowFailed, "INVALID_ROW"
+		case row.UserID == "" || len(row.UserID) > 64:
+			row.Status, row.Reason = UserImportRowFailed, "VALIDATION_ERROR"
+		default:
+			if len(rec) >= 2 {
+				row.Password = rec[1]
+			}
+			if row.Password == "" {
+				if row.Password, err = generateBulkPassword(); err != nil {
+					return nil, err
+				}
+				row.Generated = true
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// writeUserImportResultCSV writes every row with its outcome; generated passwords are included so
+// the file can be handed out.
+func writeUserImportResultCSV(w io.Writer, rows []UserImportRow) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"row_number", "userid", "status", "reason", "password"}); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		password := ""
+		if row.Generated && row.Status == UserImportRowCreated {
+			password = row.Password
+		}
+		if err := cw.Write([]string{strconv.Itoa(row.RowNumber), row.UserID, row.Status, row.Reason, password}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// UserImportJobRepository stores user import jobs and their rows.
+type UserImportJobRepository interface {
+	// FindByContent returns the latest job created from a CSV with the given SHA-256.
+	FindByContent(ctx context.Context, sha256 string) (*UserImportJob, error)
+	Create(ctx context.Context, requestedBy int64, filename, sha256 string, rows []UserImportRow) (*UserImportJob, error)
+	Get(ctx context.Context, id int64) (*UserImportJob, error)
+	List(ctx context.Context, limit int) ([]UserImportJob, error)
+	// Claim marks a failed or stalled job as running again; it returns pgx.ErrNoRows when the job is
+	// completed or still making progress.
+	Claim(ctx context.Context, id int64) error
+	PendingRows(ctx context.Context, id int64) ([]UserImportRow, error)
+	// CreateUser creates the account of a pending row and records the outcome in one transaction, so
+	// a resumed job never creates a row twice. It reports false when the userid is already taken.
+	CreateUser(ctx context.Context, id int64, row UserImportRow, passwordHash string) (bool, error)
+	FailRow(ctx context.Context, id int64, rowNumber int, reason string) error
+	Finish(ctx context.Context, id int64, status string, errMsg *string) error
+	Rows(ctx context.Context, id int64) ([]UserImportRow, error)
+	// Delete removes the job with its rows (and the generated passwords kept for the result CSV). It
+	// returns ErrUserImportRunning while the job is making progress.
+	Delete(ctx context.Context, id int64) error
+}
+
+type PgUserImportJobRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPgUserImportJobRepository(db *pgxpool.Pool) *PgUserImportJobRepository {
+	return &PgUserImportJobRepository{db: db}
+}
+
+const userImportJobSelect = `
+SELECT j.id, j.requested_by, u.username, j.filename, j.status, j.error_message, j.created_at, j.updated_at, j.finished_at,
+       COUNT(r.row_number),
+       COUNT(*) FILTER (WHERE r.status='created'),
+       COUNT(*) FILTER (WHERE r.status='failed'),
+       COUNT(*) FILTER (WHERE r.status='pending')
+FROM user_import_jobs j
+LEFT JOIN users u ON u.id = j.requested_by
+LEFT JOIN user_import_job_rows r ON r.job_id = j.id
+`
+
+func scanUserImportJob(row pgx.Row) (*UserImportJob, error) {
+	var j UserImportJob
+	if err := row.Scan(&j.ID, &j.RequestedBy, &j.RequesterName, &j.Filename, &j.Status, &j.ErrorMessage, &j.CreatedAt, &j.UpdatedAt, &j.FinishedAt,
+		&j.Total, &j.Created, &j.Failed, &j.Pending); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+func (r *PgUserImportJobRepository) FindByContent(ctx context.Context, sha256 string) (*UserImportJob, error) {
+	return scanUserImportJob(r.db.QueryRow(ctx, userImportJobSelect+`WHERE j.content_sha256=$1 GROUP BY j.id, u.username ORDER BY j.id DESC LIMIT 1`, sha256))
+}
+
+func (r *PgUserImportJobRepository) Create(ctx context.Context, requestedBy int64, filename, sha256 string, rows []UserImportRow) (*UserImportJob, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var id int64
+	if err := tx.QueryRow(ctx, `INSERT INTO user_import_jobs (requested_by, filename, content_sha256) VALUES ($1,$2,$3) RETURNING id`,
+		requestedBy, filename, sha256).Scan(&id); err != nil {
+		return nil, err
+	}
+	numbers := make([]int32, len(rows))
+	userids := make([]string, len(rows))
+	passwords := make([]*string, len(rows))
+	generated := make([]bool, len(rows))
+	statuses := make([]string, len(rows))
+	reasons := make([]*string, len(rows))
+	for i, row := range rows {
+		numbers[i], userids[i], generated[i], statuses[i] = int32(row.RowNumber), row.UserID, row.Generated, row.Status
+		if row.Status == UserImportRowPending {
+			passwords[i] = &rows[i].Password
+		}
+		reasons[i] = stringPtrIfNotEmpty(row.Reason)
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO user_import_job_rows (job_id, row_number, userid, password, generated, status, reason)
+SELECT $1, * FROM unnest($2::INT[], $3::TEXT[], $4::TEXT[], $5::BOOLEAN[], $6::TEXT[], $7::TEXT[])`,
+		id, numbers, userids, passwords, generated, statuses, reasons); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return r.Get(ctx, id)
+}
+
+func (r *PgUserImportJobRepository) Get(ctx context.Context, id int64) (*UserImportJob, error) {
+	j, err := scanUserImportJob(r.db.QueryRow(ctx, userImportJobSelect+`WHERE j.id=$1 GROUP BY j.id, u.username`, id))
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.Query(ctx, `SELECT row_number, userid, status, COALESCE(reason, '') FROM user_import_job_rows WHERE job_id=$1 AND status='failed' ORDER BY row_number`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	j.FailedRows = []UserImportRow{}
+	for rows.Next() {
+		var row UserImportRow
+		if err := rows.Scan(&row.RowNumber, &row.UserID, &row.Status, &row.Reason); err != nil {
+			return nil, err
+		}
+		j.FailedRows = append(j.FailedRows, row)
+	}
+	return j, rows.Err()
+}
+
+func (r *PgUserImportJobRepository) List(ctx context.Context, limit int) ([]UserImportJob, error) {
+	rows, err := r.db.Query(ctx, userImportJobSelect+`GROUP BY j.id, u.username ORDER BY j.id DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []UserImportJob{}
+	for rows.Next() {
+		j, err := scanUserImportJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *j)
+	}
+	return items, rows.Err()
+}
+
+func (r *PgUserImportJobRepository) Claim(ctx context.Context, id int64) error {
+	ct, err := r.db.Exec(ctx, `UPDATE user_import_jobs SET status=$2, error_message=NULL, finished_at=NULL, updated_at=NOW()
+WHERE id=$1 AND (status=$3 OR (status=$2 AND updated_at < NOW() - make_interval(secs => $4)))`,
+		id, UserImportRunning, UserImportFailed, userImportStallAfter.Seconds())
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PgUserImportJobRepository) PendingRows(ctx context.Context, id int64) ([]UserImportRow, error) {
+	rows, err := r.db.Query(ctx, `SELECT row_number, userid, status, COALESCE(password, ''), generated FROM user_import_job_rows
+WHERE job_id=$1 AND status='pending' ORDER BY row_number`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []UserImportRow
+	for rows.Next() {
+		var row UserImportRow
+		if err := rows.Scan(&row.RowNumber, &row.UserID, &row.Status, &row.Password, &row.Generated); err != nil {
+			return nil, err
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+func (r *PgUserImportJobRepository) CreateUser(ctx context.Context, id int64, row UserImportRow, passwordHash string) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var userID int64
+	err = tx.QueryRow(ctx, `INSERT INTO users (username, password_hash, role) VALUES ($1,$2,'user') ON CONFLICT (username) DO NOTHING RETURNING id`,
+		row.UserID, passwordHash).Scan(&userID)
+	created := err == nil
+	switch {
+	case created:
+		// 入力されたパスワードは作成後に消し、生成したものだけ結果 CSV のために残す
+		_, err = tx.Exec(ctx, `UPDATE user_import_job_rows SET status='created', user_id=$3, password=CASE WHEN generated THEN password END
+WHERE job_id=$1 AND row_number=$2`, id, row.RowNumber, userID)
+	case errors.Is(err, pgx.ErrNoRows):
+		_, err = tx.Exec(ctx, `UPDATE user_import_job_rows SET status='failed', reason='USERID_ALREADY_EXISTS', password=NULL
+WHERE job_id=$1 AND row_number=$2`, id, row.RowNumber)
+	}
+	if err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE user_import_jobs SET updated_at=NOW() WHERE id=$1`, id); err != nil {
+		return false, err
+	}
+	return created, tx.Commit(ctx)
+}
+
+func (r *PgUserImportJobRepository) FailRow(ctx context.Context, id int64, rowNumber int, reason string) error {
+	_, err := r.db.Exec(ctx, `UPDATE user_import_job_rows SET status='failed', reason=$3, password=NULL WHERE job_id=$1 AND row_number=$2`, id, rowNumber, reason)
+	return err
+}
+
+func (r *PgUserImportJobRepository) Finish(ctx context.Context, id int64, status string, errMsg *string) error {
+	_, err := r.db.Exec(ctx, `UPDATE user_import_jobs SET status=$2, error_message=$3, finished_at=NOW(), updated_at=NOW() WHERE id=$1`, id, status, errMsg)
+	return err
+}
+
+func (r *PgUserImportJobRepository) Rows(ctx context.Context, id int64) ([]UserImportRow, error) {
+	rows, err := r.db.Query(ctx, `SELECT row_number, userid, status, COALESCE(reason, ''), COALESCE(password, ''), generated FROM user_import_job_rows
+WHERE job_id=$1 ORDER BY row_number`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []UserImportRow{}
+	for rows.Next() {
+		var row UserImportRow
+		if err := rows.Scan(&row.RowNumber, &row.UserID, &row.Status, &row.Reason, &row.Password, &row.Generated); err != nil {
+			return nil, err
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+func (r *PgUserImportJobRepository) Delete(ctx context.Context, id int64) error {
+	var running bool
+	err := r.db.QueryRow(ctx, `SELECT status=$2 AND updated_at >= NOW() - make_interval(secs => $3) FROM user_import_jobs WHERE id=$1`,
+		id, UserImportRunning, userImportStallAfter.Seconds()).Scan(&running)
+	if err != nil {
+		return err
+	}
+	if running {
+		return ErrUserImportRunning
+	}
+	_, err = r.db.Exec(ctx, `DELETE FROM user_import_jobs WHERE id=$1`, id)
+	return err
+}
+
+// startUserImport processes the pending rows of a claimed (or new) job in the background, detached
+// from the request so it outlives it.
+func startUserImport(repo UserImportJobRepository, id int64) {
+	go runUserImport(context.Background(), repo, id)
+}
+
+// runUserImport creates the accounts of the pending rows in order. A database error stops the job as
+// failed; resuming it continues with the rows still pending.
+func runUserImport(ctx context.Context, repo UserImportJobRepository, id int64) {
+	fail := func(msg string) {
+		log.Printf("[user_import] %d %s", id, msg)
+		if err := repo.Finish(ctx, id, UserImportFailed, &msg); err != nil {
+			log.Printf("[user_import] %d finish failed: %v", id, err)
+		}
+	}
+	rows, err := repo.PendingRows(ctx, id)
+	if err != nil {
+		fail(fmt.Sprintf("load rows: %v", err))
+		return
+	}
+	for _, row := range rows {
+		hash, err := bcrypt.GenerateFromPassword([]byte(row.Password), bcrypt.DefaultCost)
+		if err != nil {
+			// 72 バイトを超えるパスワードなど、その行だけの問題
+			if err := repo.FailRow(ctx, id, row.RowNumber, "INVALID_PASSWORD"); err != nil {
+				fail(fmt.Sprintf("row %d: %v", row.RowNumber, err))
+				return
+			}
+			continue
+		}
+		if _, err := repo.CreateUser(ctx, id, row, string(hash)); err != nil {
+			fail(fmt.Sprintf("row %d: %v", row.RowNumber, err))
+			return
+		}
+	}
+	if err := repo.Finish(ctx, id, UserImportCompleted, nil); err != nil {
+		log.Printf("[user_import] %d finish failed: %v", id, err)
+	}
+}
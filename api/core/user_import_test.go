@@ -0,0 +1,41 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseUserImportCSV(t *testing.T) {
+	rows, err := parseUserImportCSV(strings.NewReader("userid,password\ns001,secret\ns002\n ,x\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("rows = %+v", rows)
+	}
+	if rows[0].Password != "secret" || rows[0].Generated || rows[0].Status != UserImportRowPending {
+		t.Errorf("given password: %+v", rows[0])
+	}
+	if rows[1].RowNumber != 3 || !rows[1].Generated || len(rows[1].Password) != bulkPasswordLength {
+		t.Errorf("generated password: %+v", rows[1])
+	}
+	if rows[2].Status != UserImportRowFailed || rows[2].Reason != "VALIDATION_ERROR" {
+		t.Errorf("blank userid: %+v", rows[2])
+	}
+	if _, err := parseUserImportCSV(strings.NewReader("name\ns001\n")); !errors.Is(err, ErrUserImportInput) {
+		t.Errorf("bad header: %v", err)
+	}
+
+	rows[1].Status = UserImportRowCreated
+	rows[0].Status = UserImportRowCreated
+	buf := &bytes.Buffer{}
+	if err := writeUserImportResultCSV(buf, rows); err != nil {
+		t.Fatal(err)
+	}
+	want := "row_number,userid,status,reason,password\n2,s001,created,,\n3,s002,created,," + rows[1].Password + "\n4,,failed,VALIDATION_ERROR,\n"
+	if buf.String() != want {
+		t.Errorf("result csv:\n%s", buf.String())
+	}
+}
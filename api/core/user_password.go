@@ -0,0 +1,22 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	minPasswordLen = 8
+	maxPasswordLen = 72 // bcrypt はこれを超えるバイトを無視する
+)
+
+// validateNewPassword checks a password chosen by the user on a password change.
+func validateNewPassword(current, next string) error {
+	if len(next) < minPasswordLen || len(next) > maxPasswordLen {
+		return fmt.Errorf("新しいパスワードは %d〜%d バイトで指定してください", minPasswordLen, maxPasswordLen)
+	}
+	if next == current {
+		return errors.New("新しいパスワードが現在のものと同じです")
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateNewPassword(t *testing.T) {
+	if err := validateNewPassword("initial-pass", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	for _, next := range []string{"short", strings.Repeat("a", 73), "initial-pass"} {
+		if err := validateNewPassword("initial-pass", next); err == nil {
+			t.Fatalf("%q: expected an error", next)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // コンテナに zoneinfo が無くてもタイムゾーンを解決できるようにする
+)
+
+const (
+	defaultTimezone = "Asia/Tokyo"
+	defaultLocale   = "ja"
+)
+
+// supportedLocales lists locales accepted for user preferences.
+var supportedLocales = map[string]struct{}{
+	"ja": {},
+	"en": {},
+}
+
+// normalizeTimezone validates an IANA timezone name.
+func normalizeTimezone(tz string) (string, error) {
+	tz = strings.TrimSpace(tz)
+	if tz == "" {
+		return "", errors.New("timezone は必須です")
+	}
+	if _, err := time.LoadLocation(tz); err != nil {
+		return "", errors.New("timezone は IANA 形式で指定してください (例: Asia/Tokyo)")
+	}
+	return tz, nil
+}
+
+// normalizeLocale validates a locale against supportedLocales.
+func normalizeLocale(locale string) (string, error) {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if _, ok := supportedLocales[locale]; !ok {
+		return "", errors.New("locale は ja または en を指定してください")
+	}
+	return locale, nil
+}
+
+// Location returns the user's time zone, falling back to the default.
+func (u UserRecord) Location() *time.Location {
+	for _, tz := range []string{u.Timezone, defaultTimezone} {
+		if loc, err := time.LoadLocation(tz); err == nil && tz != "" {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// PreferredLocale returns the user's locale, falling back to the default.
+func (u UserRecord) PreferredLocale() string {
+	if locale, err := normalizeLocale(u.Locale); err == nil {
+		return locale
+	}
+	return defaultLocale
+}
+
+// formatLocalTime renders t for human-facing output (CSV, mail). API JSON stays RFC3339 UTC.
+func formatLocalTime(t time.Time, loc *time.Location, locale string) string {
+	if t.IsZero() {
+		return ""
+	}
+	t = t.In(loc)
+	if locale == "ja" {
+		return t.Format("2006/01/02 15:04:05 (MST)")
+	}
+	return t.Format("Jan 2, 2006 15:04:05 MST")
+}
+
+// writeSubmissionsCSV writes submissions with timestamps localized to the user's preferences.
+func writeSubmissionsCSV(w io.Writer, items []SubmissionListItem, user UserRecord) error {
+	locale := user.PreferredLocale()
+	loc := user.Location()
+	header := []string{"id", "problem_id", "problem_title", "language", "status", "verdict", "time_ms", "memory_kb", "submitted_at"}
+	if locale == "ja" {
+		header = []string{"提出ID", "問題ID", "問題名", "言語", "状態", "結果", "実行時間(ms)", "メモリ(KB)", "提出日時"}
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, item := range items {
+		record := []string{
+			strconv.FormatInt(item.ID, 10),
+			strconv.FormatInt(item.ProblemID, 10),
+			item.ProblemTitle,
+			item.Language,
+			item.Status,
+			derefString(item.Verdict),
+			formatOptionalInt32(item.TimeMS),
+			formatOptionalInt32(item.MemoryKB),
+			formatLocalTime(item.CreatedAt, loc, locale),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func formatOptionalInt32(v *int32) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatInt(int64(*v), 10)
+}
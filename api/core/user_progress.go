@@ -0,0 +1,135 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProblemProgress is the caller's result on one problem: the best judged submission (AC first, then
+// the highest score, then the latest) and when the problem was first solved.
+type ProblemProgress struct {
+	ProblemID        int64      `json:"problem_id"`
+	Label            string     `json:"label,omitempty"`
+	Slug             string     `json:"slug"`
+	Title            string     `json:"title"`
+	SubmissionCount  int        `json:"submission_count"`
+	Solved           bool       `json:"solved"`
+	BestVerdict      *string    `json:"best_verdict"`
+	BestSubmissionID *int64     `json:"best_submission_id"`
+	BestScore        *int32     `json:"best_score"`
+	MaxScore         *int32     `json:"max_score"`
+	BestTimeMS       *int32     `json:"best_time_ms"` // 正解した提出のうち最短の実行時間
+	FirstAcceptedAt  *time.Time `json:"first_accepted_at"`
+	LastSubmittedAt  *time.Time `json:"last_submitted_at"`
+}
+
+// 負荷試験の提出は数えない
+func (r *PgSubmissionRepository) Progress(ctx context.Context, userID int64, problemIDs []int64) ([]ProblemProgress, error) {
+	const best = `ORDER BY (sr.verdict = 'AC') DESC, sr.score DESC NULLS LAST, s.id DESC) FILTER (WHERE sr.verdict IS NOT NULL))[1]`
+	rows, err := r.db.Query(ctx, `SELECT p.id, p.slug, p.title, COUNT(s.id), COALESCE(bool_or(sr.verdict = 'AC'), FALSE),
+       (array_agg(sr.verdict `+best+`,
+       (array_agg(s.id `+best+`,
+       MAX(sr.score), MAX(sr.max_score),
+       MIN(sr.time_ms) FILTER (WHERE sr.verdict = 'AC'),
+       MIN(s.created_at) FILTER (WHERE sr.verdict = 'AC'),
+       MAX(s.created_at)
+FROM problems p
+LEFT JOIN submissions s ON s.problem_id = p.id AND s.user_id = $1 AND s.load_test_id IS NULL
+LEFT JOIN submission_results sr ON sr.submission_id = s.id
+WHERE p.deleted_at IS NULL AND ($2::BIGINT[] IS NULL OR p.id = ANY($2))
+GROUP BY p.id
+HAVING $2::BIGINT[] IS NOT NULL OR COUNT(s.id) > 0
+ORDER BY p.id`, userID, problemIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ProblemProgress{}
+	for rows.Next() {
+		var p ProblemProgress
+		if err := rows.Scan(&p.ProblemID, &p.Slug, &p.Title, &p.SubmissionCount, &p.Solved, &p.BestVerdict, &p.BestSubmissionID,
+			&p.BestScore, &p.MaxScore, &p.BestTimeMS, &p.FirstAcceptedAt, &p.LastSubmittedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, p)
+	}
+	return items, rows.Err()
+}
+
+// orderProgressBySet arranges progress in the order of the set's problems, with their labels and
+// (localized) titles. Problems outside the set are dropped.
+func orderProgressBySet(items []ProblemProgress, problems []ContestProblem) []ProblemProgress {
+	byID := make(map[int64]ProblemProgress, len(items))
+	for _, p := range items {
+		byID[p.ProblemID] = p
+	}
+	out := make([]ProblemProgress, 0, len(problems))
+	for _, cp := range problems {
+		p, ok := byID[cp.ProblemID]
+		if !ok {
+			p = ProblemProgress{ProblemID: cp.ProblemID, Slug: cp.Slug}
+		}
+		p.Label, p.Title = cp.Label, cp.Title
+		out = append(out, p)
+	}
+	return out
+}
+
+// registerUserProgressRoutes wires the caller's per-problem progress. problem_set (a contest id)
+// limits it to the problems of that set; without it every problem the caller submitted to is listed.
+func registerUserProgressRoutes(api *gin.RouterGroup, subRepo SubmissionRepository, contestRepo ContestRepository, userRepo UserRepository) {
+	api.GET("/users/me/progress", func(c *gin.Context) {
+		user, ok := requireUser(c, userRepo)
+		if !ok {
+			return
+		}
+		ctx := c.Request.Context()
+		raw := strings.TrimSpace(c.Query("problem_set"))
+		if raw == "" {
+			items, err := subRepo.Progress(ctx, user.ID, nil)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to aggregate progress")
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"items": items})
+			return
+		}
+
+		setID, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || setID <= 0 {
+			respondError(c, http.StatusBadRequest, "VALIDATION_ERROR", "problem_set は正の整数で指定してください")
+			return
+		}
+		contest, ok := loadVisibleContest(c, contestRepo, user, setID)
+		if !ok {
+			return
+		}
+		// 開始前は問題一覧を伏せる（管理者は除く）
+		problems := []ContestProblem{}
+		if newContestView(*contest, time.Now()).Phase != ContestPhaseUpcoming || isStaffRole(user.Role) {
+			if problems, err = contestRepo.ListProblems(ctx, setID); err != nil {
+				respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to fetch contest problems")
+				return
+			}
+			if !isStaffRole(user.Role) {
+				problems = assignContestProblems(setID, user.ID, problems)
+			}
+			localizeContestProblems(problems, requestLocale(c, user))
+		}
+		ids := make([]int64, 0, len(problems))
+		for _, p := range problems {
+			ids = append(ids, p.ProblemID)
+		}
+		items, err := subRepo.Progress(ctx, user.ID, ids)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to aggregate progress")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"problem_set": setID, "items": orderProgressBySet(items, problems)})
+	})
+}
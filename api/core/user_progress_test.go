@@ -0,0 +1,22 @@
+package core
+
+import "testing"
+
+func TestProgressFollowsProblemSetOrder(t *testing.T) {
+	ac := VerdictAC
+	items := []ProblemProgress{
+		{ProblemID: 3, Slug: "c", Title: "C", SubmissionCount: 2, Solved: true, BestVerdict: &ac},
+		{ProblemID: 9, Slug: "other", Title: "Other", SubmissionCount: 1},
+	}
+	set := []ContestProblem{
+		{ProblemID: 3, Label: "B", Slug: "c", Title: "問題 C"},
+		{ProblemID: 5, Label: "A", Slug: "a", Title: "問題 A"},
+	}
+	got := orderProgressBySet(items, set)
+	if len(got) != 2 || got[0].Label != "B" || got[0].Title != "問題 C" || !got[0].Solved {
+		t.Fatalf("progress = %+v", got)
+	}
+	if got[1].ProblemID != 5 || got[1].SubmissionCount != 0 || got[1].BestVerdict != nil {
+		t.Fatalf("untried problem = %+v", got[1])
+	}
+}
@@ -0,0 +1,293 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// suspendedKeyPrefix marks a suspended account in Redis (user:suspended:<userid> → reason), so the
+// session middleware can reject it without a database lookup. The database (users.is_active) stays
+// the source of truth: login and submissions check it directly, and the middleware falls back to it
+// when the mark is missing (e.g. after Redis was flushed or restored).
+const suspendedKeyPrefix = "user:suspended:"
+
+// activeKeyPrefix caches that the database has an account as active, so the fallback runs at most
+// once per activeCacheTTL per user.
+const (
+	activeKeyPrefix = "user:active:"
+	activeCacheTTL  = time.Minute
+)
+
+// SuspensionSource reads whether userid is suspended, with the reason, from the source of truth.
+type SuspensionSource func(ctx context.Context, userid string) (string, bool, error)
+
+const maxSuspensionReasonLength = 1000
+
+// Actions recorded in user_suspension_events.
+const (
+	SuspensionActionSuspend   = "suspend"
+	SuspensionActionUnsuspend = "unsuspend"
+)
+
+var (
+	ErrAccountSuspended    = errors.New("account is suspended")
+	ErrUserSuspensionInput = errors.New("invalid suspension")
+)
+
+// UserSuspensionEvent is one suspend / unsuspend in the audit history.
+type UserSuspensionEvent struct {
+	Action    string    `json:"action"`
+	Reason    string    `json:"reason"`
+	ActorID   *int64    `json:"actor_id"`
+	Actor     string    `json:"actor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserSuspension is the suspension state of an account with its history (newest first).
+type UserSuspension struct {
+	UserID      int64                 `json:"user_id"`
+	Username    string                `json:"userid"`
+	Role        string                `json:"role"`
+	Active      bool                  `json:"is_active"`
+	Reason      string                `json:"reason"`
+	SuspendedAt *time.Time            `json:"suspended_at"`
+	History     []UserSuspensionEvent `json:"history"`
+}
+
+type UserSuspensionRepository interface {
+	Get(ctx context.Context, username string) (*UserSuspension, error)
+	// Suspend suspends the account, or replaces the reason of a suspended one. It returns
+	// ErrLastAdmin when no other usable admin would remain.
+	Suspend(ctx context.Context, username string, actorID int64, reason string) (*UserSuspension, error)
+	Unsuspend(ctx context.Context, username string, actorID int64, reason string) (*UserSuspension, error)
+}
+
+type PgUserSuspensionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPgUserSuspensionRepository(db *pgxpool.Pool) *PgUserSuspensionRepository {
+	return &PgUserSuspensionRepository{db: db}
+}
+
+// normalizeSuspensionReason trims reason and checks its length; required for suspensions.
+func normalizeSuspensionReason(reason string, required bool) (string, error) {
+	reason = strings.TrimSpace(reason)
+	if required && reason == "" {
+		return "", fmt.Errorf("%w: reason を指定してください", ErrUserSuspensionInput)
+	}
+	if len([]rune(reason)) > maxSuspensionReasonLength {
+		return "", fmt.Errorf("%w: reason は %d 文字以内で指定してください", ErrUserSuspensionInput, maxSuspensionReasonLength)
+	}
+	return reason, nil
+}
+
+func (r *PgUserSuspensionRepository) Get(ctx context.Context, username string) (*UserSuspension, error) {
+	var s UserSuspension
+	if err := r.db.QueryRow(ctx, `SELECT id, username, role, is_active, suspension_reason, suspended_at FROM users WHERE username=$1 AND deleted_at IS NULL`, username).
+		Scan(&s.UserID, &s.Username, &s.Role, &s.Active, &s.Reason, &s.SuspendedAt); err != nil {
+		return nil, err
+	}
+	rows, err := r.db.Query(ctx, `SELECT e.action, e.reason, e.actor_id, COALESCE(a.username, ''), e.created_at
+FROM user_suspension_events e
+LEFT JOIN users a ON a.id = e.actor_id
+WHERE e.user_id=$1
+ORDER BY e.created_at DESC, e.id DESC`, s.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	s.History = []UserSuspensionEvent{}
+	for rows.Next() {
+		var e UserSuspensionEvent
+		if err := rows.Scan(&e.Action, &e.Reason, &e.ActorID, &e.Actor, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		s.History = append(s.History, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// State reports whether username is suspended, with the reason. A missing (or deleted) account is
+// not suspended. It is the SuspensionSource of the session middleware.
+func (r *PgUserSuspensionRepository) State(ctx context.Context, username string) (string, bool, error) {
+	var active bool
+	var reason string
+	err := r.db.QueryRow(ctx, `SELECT is_active, suspension_reason FROM users WHERE username=$1 AND deleted_at IS NULL`, username).Scan(&active, &reason)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return reason, !active, nil
+}
+
+func (r *PgUserSuspensionRepository) Suspend(ctx context.Context, username string, actorID int64, reason string) (*UserSuspension, error) {
+	reason, err := normalizeSuspensionReason(reason, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.record(ctx, username, actorID, SuspensionActionSuspend, reason,
+		`UPDATE users SET is_active=FALSE, suspension_reason=$2, suspended_at=COALESCE(suspended_at, NOW()) WHERE id=$1`); err != nil {
+		return nil, err
+	}
+	return r.Get(ctx, username)
+}
+
+func (r *PgUserSuspensionRepository) Unsuspend(ctx context.Context, username string, actorID int64, reason string) (*UserSuspension, error) {
+	reason, err := normalizeSuspensionReason(reason, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.record(ctx, username, actorID, SuspensionActionUnsuspend, reason,
+		`UPDATE users SET is_active=TRUE, suspension_reason='', suspended_at=NULL WHERE id=$1`); err != nil {
+		return nil, err
+	}
+	return r.Get(ctx, username)
+}
+
+// record applies update ($1 = user id, $2 = the reason of a suspension) and appends the event,
+// in one transaction.
+func (r *PgUserSuspensionRepository) record(ctx context.Context, username string, actorID int64, action, reason, update string) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var userID int64
+	var role string
+	var active, disabled bool
+	if err := tx.QueryRow(ctx, `SELECT id, role, is_active, disabled_at IS NOT NULL FROM users WHERE username=$1 AND deleted_at IS NULL FOR UPDATE`, username).
+		Scan(&userID, &role, &active, &disabled); err != nil {
+		return err
+	}
+	if action == SuspensionActionUnsuspend && active {
+		return fmt.Errorf("%w: このアカウントは停止されていません", ErrUserSuspensionInput)
+	}
+	// 無効化（disabled_at）と同じく、使える管理者がいなくなる停止は断る
+	if action == SuspensionActionSuspend && role == RoleAdmin && active && !disabled {
+		if err := requireOtherAdmin(ctx, tx, userID); err != nil {
+			return err
+		}
+	}
+	args := []any{userID}
+	if action == SuspensionActionSuspend {
+		args = append(args, reason)
+	}
+	if _, err := tx.Exec(ctx, update, args...); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO user_suspension_events (user_id, action, reason, actor_id) VALUES ($1,$2,$3,$4)`, userID, action, reason, actorID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// UseSuspensionSource makes SuspensionReason consult source when userid has no suspension mark.
+func (s *RedisSessionStore) UseSuspensionSource(source SuspensionSource) {
+	s.suspensions = source
+}
+
+// MarkSuspended records the suspension of userid for the session middleware.
+func (s *RedisSessionStore) MarkSuspended(ctx context.Context, userid, reason string) error {
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, suspendedKeyPrefix+userid, reason, 0)
+		pipe.Del(ctx, activeKeyPrefix+userid)
+		return nil
+	})
+	return err
+}
+
+// ClearSuspended removes the suspension mark of userid.
+func (s *RedisSessionStore) ClearSuspended(ctx context.Context, userid string) error {
+	return s.client.Del(ctx, suspendedKeyPrefix+userid).Err()
+}
+
+// MoveSuspended carries the suspension mark over a rename of the account.
+func (s *RedisSessionStore) MoveSuspended(ctx context.Context, from, to string) error {
+	err := s.client.Rename(ctx, suspendedKeyPrefix+from, suspendedKeyPrefix+to).Err()
+	if err != nil && strings.Contains(err.Error(), "no such key") {
+		return nil
+	}
+	return err
+}
+
+// SuspensionReason reports whether userid is suspended, with the reason. Without a mark it asks the
+// suspension source (when set) and caches the answer: a suspension as the mark, an active account
+// for activeCacheTTL.
+func (s *RedisSessionStore) SuspensionReason(ctx context.Context, userid string) (string, bool, error) {
+	reason, err := s.client.Get(ctx, suspendedKeyPrefix+userid).Result()
+	if err == nil {
+		return reason, true, nil
+	}
+	if !errors.Is(err, redis.Nil) {
+		return "", false, err
+	}
+	if s.suspensions == nil {
+		return "", false, nil
+	}
+	if n, err := s.client.Exists(ctx, activeKeyPrefix+userid).Result(); err != nil {
+		return "", false, err
+	} else if n > 0 {
+		return "", false, nil
+	}
+	reason, suspended, err := s.suspensions(ctx, userid)
+	if err != nil {
+		return "", false, err
+	}
+	if suspended {
+		err = s.MarkSuspended(ctx, userid, reason)
+	} else {
+		err = s.client.Set(ctx, activeKeyPrefix+userid, "1", activeCacheTTL).Err()
+	}
+	if err != nil {
+		log.Printf("[suspension] cache state of %q: %v", userid, err)
+	}
+	return reason, suspended, nil
+}
+
+// respondAccountSuspended writes the dedicated error of a suspended account.
+func respondAccountSuspended(c *gin.Context, reason string) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"error": gin.H{
+			"code":    "ACCOUNT_SUSPENDED",
+			"message": "このアカウントは利用停止されています",
+		},
+		"reason": reason,
+	})
+}
+
+// rejectSuspendedSession responds ACCOUNT_SUSPENDED and aborts when the logged-in user of the
+// request is suspended. Logging out stays possible.
+func rejectSuspendedSession(c *gin.Context, store *RedisSessionStore, userid string) bool {
+	if strings.TrimSpace(userid) == "" || c.Request.URL.Path == "/api/v1/auth/logout" {
+		return false
+	}
+	reason, suspended, err := store.SuspensionReason(c.Request.Context(), userid)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "failed to check account state")
+		c.Abort()
+		return true
+	}
+	if !suspended {
+		return false
+	}
+	respondAccountSuspended(c, reason)
+	c.Abort()
+	return true
+}
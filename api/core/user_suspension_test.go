@@ -0,0 +1,102 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestSessionMiddlewareRejectsSuspendedAccount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	store := NewRedisSessionStore(client, []byte("test-secret-test-secret-test-sec"))
+
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	sess, err := store.Get(req, sessionName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess.Values["userid"] = "mallory"
+	w := httptest.NewRecorder()
+	if err := sess.Save(req, w); err != nil {
+		t.Fatal(err)
+	}
+	cookie := w.Result().Cookies()[0]
+
+	r := gin.New()
+	r.Use(SessionMiddleware(Config{}, store))
+	r.GET("/api/v1/problems", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.POST("/api/v1/auth/logout", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodGet, "/api/v1/problems"); w.Code != http.StatusOK {
+		t.Fatalf("active account: status %d", w.Code)
+	}
+	if err := store.MarkSuspended(context.Background(), "mallory", "スパム投稿"); err != nil {
+		t.Fatal(err)
+	}
+	w = do(http.MethodGet, "/api/v1/problems")
+	var body struct {
+		Error  struct{ Code string } `json:"error"`
+		Reason string                `json:"reason"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &body)
+	if w.Code != http.StatusForbidden || body.Error.Code != "ACCOUNT_SUSPENDED" || body.Reason != "スパム投稿" {
+		t.Fatalf("suspended account: status %d body %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodPost, "/api/v1/auth/logout"); w.Code != http.StatusNoContent {
+		t.Fatalf("logout must stay possible, got %d", w.Code)
+	}
+	if err := store.ClearSuspended(context.Background(), "mallory"); err != nil {
+		t.Fatal(err)
+	}
+	if w := do(http.MethodGet, "/api/v1/problems"); w.Code != http.StatusOK {
+		t.Fatalf("reinstated account: status %d", w.Code)
+	}
+}
+
+func TestSuspensionReasonFallsBackToSource(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	store := NewRedisSessionStore(client, []byte("test-secret-test-secret-test-sec"))
+	ctx := context.Background()
+
+	suspended := map[string]string{"mallory": "スパム投稿"}
+	lookups := 0
+	store.UseSuspensionSource(func(ctx context.Context, userid string) (string, bool, error) {
+		lookups++
+		reason, ok := suspended[userid]
+		return reason, ok, nil
+	})
+
+	// Redis のマークが消えてもデータベースの停止状態で拒否し、マークを戻す
+	if reason, ok, err := store.SuspensionReason(ctx, "mallory"); err != nil || !ok || reason != "スパム投稿" {
+		t.Fatalf("mallory: %q %t %v", reason, ok, err)
+	}
+	if !mr.Exists(suspendedKeyPrefix + "mallory") {
+		t.Fatal("suspension mark was not restored")
+	}
+	for i := 0; i < 2; i++ {
+		if _, ok, err := store.SuspensionReason(ctx, "alice"); err != nil || ok {
+			t.Fatalf("alice: %t %v", ok, err)
+		}
+	}
+	if lookups != 2 {
+		t.Fatalf("lookups = %d, want 2 (the active state is cached)", lookups)
+	}
+}
@@ -0,0 +1,151 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Built-in verdict codes.
+const (
+	VerdictAC  = "AC"
+	VerdictWA  = "WA"
+	VerdictTLE = "TLE"
+	VerdictMLE = "MLE"
+	VerdictOLE = "OLE"
+	VerdictRE  = "RE"
+	VerdictCE  = "CE"
+	VerdictSE  = "SE"
+)
+
+// Verdict kinds decide how a verdict is treated by scoring and statistics.
+const (
+	VerdictKindAccepted = "accepted"
+	VerdictKindRejected = "rejected" // 不正解扱い（ペナルティ対象）
+	VerdictKindExcluded = "excluded" // 解答の判定ではない（CE / SE）: ペナルティ・統計の対象外
+)
+
+// VerdictInfo describes a verdict code.
+type VerdictInfo struct {
+	Code  string `json:"code"`
+	Label string `json:"label"`
+	Kind  string `json:"kind"`
+}
+
+var builtinVerdicts = []VerdictInfo{
+	{VerdictAC, "Accepted", VerdictKindAccepted},
+	{VerdictWA, "Wrong Answer", VerdictKindRejected},
+	{VerdictTLE, "Time Limit Exceeded", VerdictKindRejected},
+	{VerdictMLE, "Memory Limit Exceeded", VerdictKindRejected},
+	{VerdictOLE, "Output Limit Exceeded", VerdictKindRejected},
+	{VerdictRE, "Runtime Error", VerdictKindRejected},
+	{VerdictCE, "Compilation Error", VerdictKindExcluded},
+	{VerdictSE, "System Error", VerdictKindExcluded},
+}
+
+// verdict codes are stored in submission_results.verdict (VARCHAR(8))
+var verdictCodePattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]{0,7}$`)
+
+type verdictRegistry struct {
+	mu     sync.RWMutex
+	order  []string
+	byCode map[string]VerdictInfo
+}
+
+var verdicts = newVerdictRegistry()
+
+func newVerdictRegistry() *verdictRegistry {
+	r := &verdictRegistry{byCode: map[string]VerdictInfo{}}
+	for _, v := range builtinVerdicts {
+		r.order = append(r.order, v.Code)
+		r.byCode[v.Code] = v
+	}
+	return r
+}
+
+// RegisterVerdict adds a deployment-specific verdict. Only one accepted verdict (AC) exists,
+// so custom verdicts must be rejected or excluded. Built-in codes cannot be redefined.
+func RegisterVerdict(v VerdictInfo) error {
+	v.Code = strings.ToUpper(strings.TrimSpace(v.Code))
+	v.Label = strings.TrimSpace(v.Label)
+	if v.Kind == "" {
+		v.Kind = VerdictKindRejected
+	}
+	if !verdictCodePattern.MatchString(v.Code) {
+		return fmt.Errorf("invalid verdict code %q (A-Z, 0-9, _ / up to 8 chars)", v.Code)
+	}
+	if v.Kind != VerdictKindRejected && v.Kind != VerdictKindExcluded {
+		return fmt.Errorf("verdict %s: kind must be rejected or excluded", v.Code)
+	}
+	if v.Label == "" {
+		v.Label = v.Code
+	}
+	verdicts.mu.Lock()
+	defer verdicts.mu.Unlock()
+	if existing, ok := verdicts.byCode[v.Code]; ok {
+		if existing == v {
+			return nil
+		}
+		return fmt.Errorf("verdict %s is already registered", v.Code)
+	}
+	verdicts.order = append(verdicts.order, v.Code)
+	verdicts.byCode[v.Code] = v
+	return nil
+}
+
+// RegisterExtraVerdicts registers verdicts from the EXTRA_VERDICTS setting:
+// comma-separated CODE[:Label[:kind]] entries, e.g. "QLE:Query Limit Exceeded,PARTIAL:Partially Correct".
+func RegisterExtraVerdicts(spec string) error {
+	for _, entry := range parseCSV(spec) {
+		parts := strings.SplitN(entry, ":", 3)
+		v := VerdictInfo{Code: parts[0]}
+		if len(parts) > 1 {
+			v.Label = parts[1]
+		}
+		if len(parts) > 2 {
+			v.Kind = strings.ToLower(strings.TrimSpace(parts[2]))
+		}
+		if err := RegisterVerdict(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Verdicts returns all registered verdicts, built-ins first.
+func Verdicts() []VerdictInfo {
+	verdicts.mu.RLock()
+	defer verdicts.mu.RUnlock()
+	out := make([]VerdictInfo, 0, len(verdicts.order))
+	for _, code := range verdicts.order {
+		out = append(out, verdicts.byCode[code])
+	}
+	return out
+}
+
+// LookupVerdict returns the registered verdict for code.
+func LookupVerdict(code string) (VerdictInfo, bool) {
+	verdicts.mu.RLock()
+	defer verdicts.mu.RUnlock()
+	v, ok := verdicts.byCode[code]
+	return v, ok
+}
+
+// verdictPenalized reports whether the verdict counts as a wrong attempt.
+// Unknown codes (e.g. a custom verdict removed from the settings) are treated as rejected.
+func verdictPenalized(code string) bool {
+	v, ok := LookupVerdict(code)
+	return !ok || v.Kind == VerdictKindRejected
+}
+
+// verdictCodes returns the registered codes of the given kind.
+func verdictCodes(kind string) []string {
+	var out []string
+	for _, v := range Verdicts() {
+		if v.Kind == kind {
+			out = append(out, v.Code)
+		}
+	}
+	return out
+}
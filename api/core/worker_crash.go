@@ -0,0 +1,208 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	workerCrashFilePrefix = "worker-crash-"
+	// maxCrashStackBytes bounds the stack kept in an incident; the end of a crash output (the
+	// goroutine that crashed comes first, the rest are the other goroutines) is dropped.
+	maxCrashStackBytes = 16 << 10
+)
+
+// JobPanicError is returned by RunJobRecovered when processing a job panicked.
+type JobPanicError struct {
+	Job   string
+	Value string
+	Stack string
+}
+
+func (e *JobPanicError) Error() string {
+	return fmt.Sprintf("panic while processing job %s: %s", e.Job, e.Value)
+}
+
+// RunJobRecovered runs fn for job and turns a panic into a *JobPanicError, so that one bad job
+// fails like any other error instead of killing the worker goroutine (and the process).
+func RunJobRecovered(job string, fn func() (string, error)) (verdict string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &JobPanicError{Job: job, Value: fmt.Sprint(r), Stack: truncateCrashOutput(string(debug.Stack()))}
+		}
+	}()
+	return fn()
+}
+
+// WorkerCrashRecorder keeps forensic records of worker crashes as incidents: panics recovered
+// while processing a job, and earlier runs of a worker on this host that ended without shutting
+// down (a fatal error, an unrecovered panic in another goroutine, or a kill such as the OOM killer).
+// The Go runtime writes the output of a fatal crash to a file per worker in dir; the next worker
+// started on the host reports the files left behind.
+type WorkerCrashRecorder struct {
+	monitor  *AnomalyMonitor
+	client   *redis.Client
+	workerID string
+	hostname string
+	dir      string
+}
+
+func NewWorkerCrashRecorder(monitor *AnomalyMonitor, client *redis.Client, workerID, hostname, dir string) *WorkerCrashRecorder {
+	return &WorkerCrashRecorder{monitor: monitor, client: client, workerID: workerID, hostname: hostname, dir: dir}
+}
+
+// RecordPanic raises an incident for a job that panicked, with the stack and the jobs the worker
+// had in flight. The same job panicking again on retry refreshes the open incident.
+func (r *WorkerCrashRecorder) RecordPanic(ctx context.Context, slot int, perr *JobPanicError) {
+	inFlight := r.inFlight(ctx, r.workerID)
+	_, err := r.monitor.Raise(ctx, Incident{
+		Kind:    IncidentKindWorkerPanic,
+		Subject: "job:" + perr.Job,
+		Summary: fmt.Sprintf("ワーカー %s（%s）で提出 %s の処理中に panic が発生しました: %s", r.workerID, r.hostname, perr.Job, perr.Value),
+		Details: map[string]any{
+			"worker_id": r.workerID,
+			"hostname":  r.hostname,
+			"slot":      slot,
+			"job":       perr.Job,
+			"panic":     perr.Value,
+			"stack":     perr.Stack,
+			"in_flight": inFlight,
+		},
+	})
+	if err != nil {
+		log.Printf("[crash] record panic of job %s failed: %v", perr.Job, err)
+	}
+}
+
+// CaptureCrashOutput makes the runtime write the output of a fatal crash of this process to the
+// worker's crash file. The returned function removes the file on a clean shutdown.
+func (r *WorkerCrashRecorder) CaptureCrashOutput() (func(), error) {
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(r.dir, workerCrashFilePrefix+r.workerID+".log")
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := debug.SetCrashOutput(f, debug.CrashOptions{}); err != nil {
+		f.Close()
+		_ = os.Remove(path)
+		return nil, err
+	}
+	// SetCrashOutput は複製した fd を使うので、ここで閉じてよい
+	f.Close()
+	return func() {
+		_ = debug.SetCrashOutput(nil, debug.CrashOptions{})
+		_ = os.Remove(path)
+	}, nil
+}
+
+// ReportPreviousCrashes raises an incident for every crash file left by a worker that is no longer
+// alive, then removes the file. An empty file means the process ended without a Go crash output,
+// i.e. it was killed.
+func (r *WorkerCrashRecorder) ReportPreviousCrashes(ctx context.Context) {
+	paths, err := filepath.Glob(filepath.Join(r.dir, workerCrashFilePrefix+"*.log"))
+	if err != nil {
+		log.Printf("[crash] list crash files failed: %v", err)
+		return
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		workerID := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), workerCrashFilePrefix), ".log")
+		if workerID == r.workerID {
+			continue
+		}
+		// 同じホストで動いている別のワーカーのファイルは残す
+		alive, err := r.client.Exists(ctx, WorkerHeartbeatKey(workerID)).Result()
+		if err != nil {
+			log.Printf("[crash] check heartbeat of %s failed: %v", workerID, err)
+			return
+		}
+		if alive > 0 {
+			continue
+		}
+		if err := r.reportCrashFile(ctx, workerID, path); err != nil {
+			log.Printf("[crash] report crash of %s failed: %v", workerID, err)
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			log.Printf("[crash] remove %s failed: %v", path, err)
+		}
+	}
+}
+
+func (r *WorkerCrashRecorder) reportCrashFile(ctx context.Context, workerID, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(io.LimitReader(f, maxCrashStackBytes))
+	if err != nil {
+		return err
+	}
+	output := string(data)
+	if info.Size() > maxCrashStackBytes {
+		output += "\n... (truncated)"
+	}
+
+	summary := fmt.Sprintf("ワーカー %s（%s）が異常終了していました", workerID, r.hostname)
+	if strings.TrimSpace(output) == "" {
+		summary += "（クラッシュ出力がないため、OOM killer などによる強制終了の可能性があります）"
+	}
+	inFlight := r.inFlight(ctx, workerID)
+	if len(inFlight) > 0 {
+		summary += fmt.Sprintf("。実行中だった提出: %s", strings.Join(inFlight, ", "))
+	}
+	_, err = r.monitor.Raise(ctx, Incident{
+		Kind:    IncidentKindWorkerCrash,
+		Subject: "worker:" + workerID,
+		Summary: summary,
+		Details: map[string]any{
+			"worker_id":     workerID,
+			"hostname":      r.hostname,
+			"crash_output":  output,
+			"file_modified": info.ModTime().UTC().Format(time.RFC3339), // 出力があればクラッシュした時刻
+			"in_flight":     inFlight,
+			"reported_by":   r.workerID,
+		},
+	})
+	return err
+}
+
+// inFlight returns the jobs the worker had reserved. After a crash they stay listed until the
+// reaper of the API requeues them.
+func (r *WorkerCrashRecorder) inFlight(ctx context.Context, workerID string) []string {
+	jobs, err := r.client.SMembers(ctx, WorkerInFlightKey(workerID)).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.Printf("[crash] read in-flight jobs of %s failed: %v", workerID, err)
+	}
+	sort.Strings(jobs)
+	if jobs == nil {
+		jobs = []string{}
+	}
+	return jobs
+}
+
+func truncateCrashOutput(s string) string {
+	if len(s) <= maxCrashStackBytes {
+		return s
+	}
+	return s[:maxCrashStackBytes] + "\n... (truncated)"
+}
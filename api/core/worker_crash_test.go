@@ -0,0 +1,75 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+type recordingIncidentRepo struct {
+	IncidentRepository
+	opened []Incident
+}
+
+func (r *recordingIncidentRepo) Open(_ context.Context, in Incident) (*Incident, bool, error) {
+	r.opened = append(r.opened, in)
+	return &in, true, nil
+}
+
+func TestRunJobRecovered(t *testing.T) {
+	_, err := RunJobRecovered("42", func() (string, error) {
+		var m map[string]int
+		m["x"]++ // nil map への書き込みで panic させる
+		return VerdictAC, nil
+	})
+	var perr *JobPanicError
+	if !errors.As(err, &perr) || perr.Job != "42" || !strings.Contains(perr.Stack, "TestRunJobRecovered") {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+}
+
+func TestReportPreviousCrashes(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	write := func(workerID, content string) string {
+		path := filepath.Join(dir, workerCrashFilePrefix+workerID+".log")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	dead := write("dead", "fatal error: concurrent map writes\n")
+	alive := write("alive", "")
+	mr.Set(WorkerHeartbeatKey("alive"), "{}")
+	if _, err := client.SAdd(ctx, WorkerInFlightKey("dead"), "7", "3").Result(); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &recordingIncidentRepo{}
+	NewWorkerCrashRecorder(NewAnomalyMonitor(repo, ""), client, "new", "host1", dir).ReportPreviousCrashes(ctx)
+
+	if len(repo.opened) != 1 {
+		t.Fatalf("opened %d incidents, want 1", len(repo.opened))
+	}
+	inc := repo.opened[0]
+	if inc.Kind != IncidentKindWorkerCrash || inc.Subject != "worker:dead" || !reflect.DeepEqual(inc.Details["in_flight"], []string{"3", "7"}) {
+		t.Fatalf("unexpected incident: %+v", inc)
+	}
+	if _, err := os.Stat(dead); !os.IsNotExist(err) {
+		t.Fatalf("crash file of the dead worker should be removed: %v", err)
+	}
+	if _, err := os.Stat(alive); err != nil {
+		t.Fatalf("crash file of the live worker should be kept: %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// judgeArtifactMaxAge is how old a go-judge cached file must be before cleanup treats it as
+// stale. Jobs remove their artifacts when they finish, so anything this old was left behind.
+const judgeArtifactMaxAge = time.Hour
+
+// runOutputNames are the files the worker writes next to a submission's source. Sources are never removed.
+var runOutputNames = map[string]bool{
+	"compile_stdout.txt": true,
+	"compile_stderr.txt": true,
+	"run_stdout.txt":     true,
+	"run_stderr.txt":     true,
+	"cases":              true,
+}
+
+// WorkerDiskUsage is the disk usage a worker reports in its heartbeat.
+type WorkerDiskUsage struct {
+	SubmissionDirBytes int64      `json:"submission_dir_bytes"`
+	JudgeCacheBytes    int64      `json:"judge_cache_bytes"`
+	LimitBytes         int64      `json:"limit_bytes,omitempty"`
+	LastCleanupAt      *time.Time `json:"last_cleanup_at,omitempty"`
+	LastCleanupFreed   int64      `json:"last_cleanup_freed_bytes,omitempty"`
+	CheckedAt          time.Time  `json:"checked_at"`
+}
+
+// dirUsage sums the sizes of regular files under root. A missing root counts as empty.
+func dirUsage(root string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// 走査中に他のワーカーが消したファイルは無視する
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total, err
+}
+
+// cleanupRunOutputs deletes compile / run outputs of submissions under root last modified before
+// cutoff, and returns the bytes freed.
+func cleanupRunOutputs(root string, cutoff time.Time) (int64, error) {
+	subs, err := os.ReadDir(root)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	var freed int64
+	for _, sub := range subs {
+		if !sub.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, sub.Name())
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if !runOutputNames[e.Name()] {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil || !info.ModTime().Before(cutoff) {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			size, _ := dirUsage(path)
+			if err := os.RemoveAll(path); err == nil {
+				freed += size
+			}
+		}
+	}
+	return freed, nil
+}
+
+// DiskJanitor measures the worker's disk usage for the heartbeat and, when usage exceeds the
+// limit, removes stale go-judge artifacts and run outputs older than the retention period.
+type DiskJanitor struct {
+	state           *HeartbeatState
+	judge           JudgeClient
+	submissionDir   string
+	judgeCacheDir   string // go-judge の -dir をマウントした場所（空なら計測しない）
+	limitBytes      int64  // <= 0 ならクリーンアップしない
+	outputRetention time.Duration
+
+	lastCleanupAt    *time.Time
+	lastCleanupFreed int64
+}
+
+func NewDiskJanitor(state *HeartbeatState, judge JudgeClient, cfg Config) *DiskJanitor {
+	return &DiskJanitor{
+		state:           state,
+		judge:           judge,
+		submissionDir:   cfg.SubmissionDir,
+		judgeCacheDir:   cfg.JudgeFileStoreDir,
+		limitBytes:      int64(cfg.WorkerDiskLimitMB) << 20,
+		outputRetention: time.Duration(cfg.RunOutputRetentionDays) * 24 * time.Hour,
+	}
+}
+
+// Run checks usage every interval until ctx is cancelled.
+func (j *DiskJanitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		j.tick(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *DiskJanitor) tick(ctx context.Context) {
+	usage := j.measure()
+	if j.limitBytes > 0 && usage.SubmissionDirBytes+usage.JudgeCacheBytes > j.limitBytes {
+		freed := j.cleanup(ctx)
+		now := time.Now()
+		j.lastCleanupAt, j.lastCleanupFreed = &now, freed
+		log.Printf("[disk] usage %d bytes exceeds limit %d, freed %d bytes", usage.SubmissionDirBytes+usage.JudgeCacheBytes, j.limitBytes, freed)
+		usage = j.measure()
+	}
+	j.state.SetDiskUsage(usage)
+}
+
+func (j *DiskJanitor) measure() WorkerDiskUsage {
+	u := WorkerDiskUsage{LimitBytes: j.limitBytes, LastCleanupAt: j.lastCleanupAt, LastCleanupFreed: j.lastCleanupFreed, CheckedAt: time.Now()}
+	var err error
+	if u.SubmissionDirBytes, err = dirUsage(j.submissionDir); err != nil {
+		log.Printf("[disk] measure %s: %v", j.submissionDir, err)
+	}
+	if j.judgeCacheDir != "" {
+		if u.JudgeCacheBytes, err = dirUsage(j.judgeCacheDir); err != nil {
+			log.Printf("[disk] measure %s: %v", j.judgeCacheDir, err)
+		}
+	}
+	return u
+}
+
+// cleanup removes stale go-judge artifacts first (through the go-judge API, so its index stays
+// consistent) and then old run outputs. It returns the bytes freed.
+func (j *DiskJanitor) cleanup(ctx context.Context) int64 {
+	var freed int64
+	if j.judgeCacheDir != "" {
+		cutoff := time.Now().Add(-judgeArtifactMaxAge)
+		entries, err := os.ReadDir(j.judgeCacheDir)
+		if err != nil {
+			log.Printf("[disk] list %s: %v", j.judgeCacheDir, err)
+		}
+		for _, e := range entries {
+			info, err := e.Info()
+			if err != nil || !info.Mode().IsRegular() || !info.ModTime().Before(cutoff) {
+				continue
+			}
+			// go-judge のファイルストアはファイル ID をそのままファイル名にしている
+			if err := j.judge.RemoveFiles(ctx, e.Name()); err == nil {
+				freed += info.Size()
+			}
+		}
+	}
+	if j.outputRetention > 0 {
+		n, err := cleanupRunOutputs(j.submissionDir, time.Now().Add(-j.outputRetention))
+		if err != nil {
+			log.Printf("[disk] cleanup run outputs: %v", err)
+		}
+		freed += n
+	}
+	return freed
+}
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCleanupRunOutputsKeepsSourcesAndRecentOutputs(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel string, age time.Duration) string {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("0123456789"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		at := time.Now().Add(-age)
+		if err := os.Chtimes(path, at, at); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	oldSource := write("1/main.cpp", 48*time.Hour)
+	oldOutput := write("1/run_stdout.txt", 48*time.Hour)
+	oldCase := write("1/cases/01.stdout", 48*time.Hour)
+	_ = os.Chtimes(filepath.Join(root, "1", "cases"), time.Now().Add(-48*time.Hour), time.Now().Add(-48*time.Hour))
+	newOutput := write("2/run_stdout.txt", time.Minute)
+
+	freed, err := cleanupRunOutputs(root, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if freed != 20 {
+		t.Fatalf("freed %d bytes, want 20", freed)
+	}
+	for _, p := range []string{oldOutput, oldCase} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Fatalf("%s should be removed", p)
+		}
+	}
+	for _, p := range []string{oldSource, newOutput} {
+		if _, err := os.Stat(p); err != nil {
+			t.Fatalf("%s should be kept: %v", p, err)
+		}
+	}
+	if usage, _ := dirUsage(root); usage != 20 {
+		t.Fatalf("usage %d, want 20", usage)
+	}
+}
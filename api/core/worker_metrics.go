@@ -30,23 +30,24 @@ func SaveHeartbeat(ctx context.Context, client RedisClientRaw, hb WorkerHeartbea
 // WorkerHeartbeat はワーカーが Redis に定期送信する稼働情報。
 // JSON で保存し API から参照する。
 type WorkerHeartbeat struct {
-	WorkerID       string    `json:"worker_id"`
-	Hostname       string    `json:"hostname"`
-	PID            int       `json:"pid"`
-	Version        string    `json:"version"` // 予備: ビルドバージョンやGit SHA
-	Concurrency    int       `json:"concurrency"`
-	UptimeSeconds  int64     `json:"uptime_seconds"`
-	Status         string    `json:"status"` // idle|busy|starting
-	RunningCount   int       `json:"running_count"`
-	CurrentJob     string    `json:"current_job,omitempty"`
-	RunningJobs    []string  `json:"running_jobs,omitempty"`
-	ProcessedTotal int64     `json:"processed_total"`
-	FailedTotal    int64     `json:"failed_total"`
-	LastError      string    `json:"last_error,omitempty"`
-	MemoryRSSBytes uint64    `json:"memory_rss_bytes"`
-	NumGoroutine   int       `json:"num_goroutine"`
-	StartedAt      time.Time `json:"started_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	WorkerID       string           `json:"worker_id"`
+	Hostname       string           `json:"hostname"`
+	PID            int              `json:"pid"`
+	Version        string           `json:"version"` // 予備: ビルドバージョンやGit SHA
+	Concurrency    int              `json:"concurrency"`
+	UptimeSeconds  int64            `json:"uptime_seconds"`
+	Status         string           `json:"status"` // idle|busy|starting
+	RunningCount   int              `json:"running_count"`
+	CurrentJob     string           `json:"current_job,omitempty"`
+	RunningJobs    []string         `json:"running_jobs,omitempty"`
+	ProcessedTotal int64            `json:"processed_total"`
+	FailedTotal    int64            `json:"failed_total"`
+	LastError      string           `json:"last_error,omitempty"`
+	MemoryRSSBytes uint64           `json:"memory_rss_bytes"`
+	NumGoroutine   int              `json:"num_goroutine"`
+	Disk           *WorkerDiskUsage `json:"disk,omitempty"` // 提出ディレクトリと go-judge キャッシュの使用量（DiskJanitor が更新）
+	StartedAt      time.Time        `json:"started_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
 }
 
 // UpdateRuntimeStats はメモリ/Goroutine を現在値で上書きするヘルパー。
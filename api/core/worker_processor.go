@@ -4,11 +4,14 @@ import (
 	"context"
 	"errors"
 	"log"
-	"math"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+
+	"tuis-oj-prototype/core/checker"
+
+	"github.com/jackc/pgx/v5"
 )
 
 // WorkerProcessor consumes submission IDs and runs judge.
@@ -17,11 +20,17 @@ type WorkerProcessor struct {
 	problemRepo        ProblemRepository
 	judge              JudgeClient
 	compileTimeLimitMs int
+	// workerID is recorded on each result so failure rates can be compared per worker.
+	workerID string
+	// signingKey signs each persisted result (empty disables signing).
+	signingKey []byte
+	// checkers caches compiled custom checkers across submissions.
+	checkers checkerCache
 }
 
 const defaultCompileTimeLimitMs = 5000
 
-func NewWorkerProcessor(subRepo SubmissionRepository, problemRepo ProblemRepository, judge JudgeClient, compileTimeLimitMs int) *WorkerProcessor {
+func NewWorkerProcessor(subRepo SubmissionRepository, problemRepo ProblemRepository, judge JudgeClient, compileTimeLimitMs int, workerID string, signingKey []byte) *WorkerProcessor {
 	if compileTimeLimitMs <= 0 {
 		compileTimeLimitMs = defaultCompileTimeLimitMs
 	}
@@ -30,6 +39,8 @@ func NewWorkerProcessor(subRepo SubmissionRepository, problemRepo ProblemReposit
 		problemRepo:        problemRepo,
 		judge:              judge,
 		compileTimeLimitMs: compileTimeLimitMs,
+		workerID:           workerID,
+		signingKey:         signingKey,
 	}
 }
 
@@ -43,7 +54,7 @@ func (p *WorkerProcessor) Process(ctx context.Context, jobID string) (string, er
 
 	sub, err := p.subRepo.AcquirePending(ctx, id)
 	if err != nil {
-		return "", err
+		return "", withSEReason(SEReasonDatabase, err)
 	}
 
 	// Read source
@@ -55,9 +66,12 @@ func (p *WorkerProcessor) Process(ctx context.Context, jobID string) (string, er
 	// Problem limits / checker (fallback to defaults if missing)
 	timeLimitMs := 2000
 	memoryLimitMb := 256
-	checkerType := "exact"
+	checkerType := CheckerTypeExact
 	checkerEps := 0.0
-	if detail, err := p.problemRepo.FindDetail(ctx, sub.ProblemID); err == nil {
+	checkerSource := ""
+	runAll := sub.RunAllTestcases
+	// 非公開・コンテスト中の問題も含めて制限値を取得する
+	if detail, err := p.problemRepo.FindDetailAdmin(ctx, sub.ProblemID); err == nil {
 		if detail.TimeLimitMS > 0 {
 			timeLimitMs = int(detail.TimeLimitMS)
 		}
@@ -71,6 +85,31 @@ func (p *WorkerProcessor) Process(ctx context.Context, jobID string) (string, er
 		if strings.TrimSpace(detail.CheckerType) != "" {
 			checkerType = strings.ToLower(strings.TrimSpace(detail.CheckerType))
 			checkerEps = detail.CheckerEps
+			checkerSource = detail.CheckerSource
+		}
+		runAll = runAll || detail.RunAllTestcases
+	}
+	// 問題ごとのサンドボックス設定はコンパイル・実行（チェッカーを含む）すべてに効かせる
+	sandbox, err := p.problemRepo.SandboxPolicy(ctx, sub.ProblemID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return "", withSEReason(SEReasonDatabase, err)
+	}
+	ctx = WithSandboxPolicy(ctx, sandbox)
+
+	// チェッカー変更に伴うリジャッジは、保存済みの出力で判定し直せればプログラムを実行しない
+	if recheck, err := p.subRepo.RecheckRequested(ctx, id); err != nil {
+		return "", withSEReason(SEReasonDatabase, err)
+	} else if recheck {
+		verdict, done, err := p.recheck(ctx, sub, judgeSettings{
+			timeLimitMs:   timeLimitMs,
+			memoryLimitMb: memoryLimitMb,
+			checkerType:   checkerType,
+			checkerEps:    checkerEps,
+			checkerSource: checkerSource,
+			runAll:        runAll,
+		})
+		if err != nil || done {
+			return verdict, err
 		}
 	}
 
@@ -89,12 +128,13 @@ func (p *WorkerProcessor) Process(ctx context.Context, jobID string) (string, er
 
 	// If compile failed or errored
 	if err != nil {
-		return "", err
+		return "", withSEReason(SEReasonCompileInfra, err)
 	}
 	if compileRes.Status != "Accepted" || compileRes.ExitStatus != 0 {
 		result := SubmissionResult{
 			SubmissionID: sub.ID,
-			Verdict:      "CE",
+			Verdict:      VerdictCE,
+			JudgedBy:     p.workerID,
 			StdoutPath:   stringPtrIfNotEmpty(compileStdoutPath),
 			StderrPath:   stringPtrIfNotEmpty(compileStderrPath),
 		}
@@ -111,10 +151,11 @@ func (p *WorkerProcessor) Process(ctx context.Context, jobID string) (string, er
 				result.ErrorMessage = ptr(compileRes.Error)
 			}
 		}
+		result.Signature = SignResult(p.signingKey, result)
 		if saveErr := p.subRepo.SaveResult(ctx, result, "failed"); saveErr != nil {
 			log.Printf("failed to save compile result for %d: %v", id, saveErr)
 		}
-		return "CE", nil
+		return VerdictCE, nil
 	}
 
 	// Run with artifact
@@ -123,34 +164,83 @@ func (p *WorkerProcessor) Process(ctx context.Context, jobID string) (string, er
 		return "", err
 	}
 
+	subtasks, err := p.problemRepo.ListSubtasks(ctx, sub.ProblemID)
+	if err != nil {
+		return "", withSEReason(SEReasonDatabase, err)
+	}
+
+	checkerID := ""
+	if checkerType == CheckerTypeCustom {
+		if checkerID, err = p.checkerFor(ctx, sub.ProblemID, checkerSource); err != nil {
+			_ = p.judge.RemoveFiles(ctx, artifactID)
+			return "", withSEReason(SEReasonCompileInfra, err)
+		}
+	}
+
 	dir := filepath.Dir(sub.SourcePath)
-	finalVerdict := "AC"
+	// 部分点採点・小課題のある問題、全テストケース実行モードでは全ケースを実行する（通常は最初の不正解で打ち切り）
+	scored := sub.ScoringMode == ScoringModeIOI || len(subtasks) > 0
+	runAll = runAll || scored
+	var passed int32
+	passedIDs := make(map[int64]bool, len(testCases))
+	finalVerdict := VerdictAC
 	finalStatus := "succeeded"
 	runStdoutPath, runStderrPath := "", ""
 	var finalTimeMS, finalMemKB *int32
 	var finalExit *int32
 	var finalErrMsg *string
 	var details []SubmissionJudgeDetail
+	var closeCallTime, closeCallMemory bool
 
 	for _, tc := range testCases {
-		runRes, runErr := p.judge.RunWithArtifact(ctx, sub.Language, artifactID, tc.stdin, timeLimitMs, memoryLimitMb)
+		// グループの制限があればそちらを使う
+		caseTimeMs, caseMemMb := timeLimitMs, memoryLimitMb
+		if tc.timeLimitMs > 0 {
+			caseTimeMs = tc.timeLimitMs
+		}
+		if tc.memoryLimitMb > 0 {
+			caseMemMb = tc.memoryLimitMb
+		}
+		runRes, runErr := p.judge.RunWithArtifact(ctx, sub.Language, artifactID, tc.stdin, caseTimeMs, caseMemMb)
 
 		verdict := mapVerdict(runRes)
-		if verdict == "AC" {
+		if verdict == VerdictAC {
 			actualOut := ""
 			if runRes != nil {
 				actualOut = runRes.Files["stdout"]
 			}
-			if !outputsEqualWithChecker(actualOut, tc.expected, checkerType, checkerEps) {
-				verdict = "WA"
+			if checkerType == CheckerTypeCustom {
+				checked, checkErr := p.runChecker(ctx, sub.ProblemID, checkerID, tc, actualOut)
+				if checkErr != nil {
+					_ = p.judge.RemoveFiles(ctx, artifactID)
+					return "", withSEReason(SEReasonJudgeError, checkErr)
+				}
+				verdict = checked
+			} else if !checker.Equal(actualOut, tc.expected, checkerType, checkerEps) {
+				verdict = VerdictWA
 			}
 		}
 		if runErr != nil {
-			return "", runErr
+			return "", withSEReason(SEReasonJudgeError, runErr)
 		}
 
 		// Track per-testcase detail and aggregate max time/memory
-		detail := SubmissionJudgeDetail{Testcase: tc.name, Status: verdict}
+		detail := SubmissionJudgeDetail{Testcase: tc.name, Status: verdict, Group: tc.group}
+		detail.InputBytes = ptr(int32(len(tc.stdin)))
+		if runRes != nil {
+			caseDir := filepath.Join(dir, "cases")
+			if out, ok := runRes.Files["stdout"]; ok {
+				detail.OutputBytes = ptr(int32(len(out)))
+				if p, err := writeFileContent(caseDir, tc.name+".stdout", out); err == nil {
+					detail.StdoutPath = &p
+				}
+			}
+			if errOut, ok := runRes.Files["stderr"]; ok && errOut != "" {
+				if p, err := writeFileContent(caseDir, tc.name+".stderr", errOut); err == nil {
+					detail.StderrPath = &p
+				}
+			}
+		}
 		if runRes != nil {
 			if runRes.Time > 0 {
 				t := int32(runRes.Time / 1_000_000)
@@ -172,7 +262,7 @@ func (p *WorkerProcessor) Process(ctx context.Context, jobID string) (string, er
 		details = append(details, detail)
 
 		// Capture first failing stdout/stderr for inspection
-		if verdict != "AC" && finalVerdict == "AC" {
+		if verdict != VerdictAC && finalVerdict == VerdictAC {
 			if runRes != nil {
 				if out, ok := runRes.Files["stdout"]; ok {
 					runStdoutPath, _ = writeFileContent(dir, "run_stdout.txt", out)
@@ -193,16 +283,26 @@ func (p *WorkerProcessor) Process(ctx context.Context, jobID string) (string, er
 			}
 		}
 
-		if verdict != "AC" {
-			finalVerdict = verdict
-			finalStatus = "failed"
-			break
+		if verdict != VerdictAC {
+			if finalVerdict == VerdictAC {
+				finalVerdict = verdict
+				finalStatus = "failed"
+			}
+			if !runAll {
+				break
+			}
+			continue
 		}
+		passed++
+		passedIDs[tc.id] = true
+		closeCallTime = closeCallTime || (detail.TimeMS != nil && isCloseCall(int64(*detail.TimeMS), int64(caseTimeMs)))
+		closeCallMemory = closeCallMemory || (detail.MemoryKB != nil && isCloseCall(int64(*detail.MemoryKB), int64(caseMemMb)*1024))
 	}
 
 	result := SubmissionResult{
 		SubmissionID: sub.ID,
 		Verdict:      finalVerdict,
+		JudgedBy:     p.workerID,
 		StdoutPath:   stringPtrIfNotEmpty(runStdoutPath),
 		StderrPath:   stringPtrIfNotEmpty(runStderrPath),
 		TimeMS:       finalTimeMS,
@@ -210,8 +310,25 @@ func (p *WorkerProcessor) Process(ctx context.Context, jobID string) (string, er
 		ExitCode:     finalExit,
 		ErrorMessage: finalErrMsg,
 		Details:      details,
+		PassedCount:  passed,
+		TotalCount:   int32(len(testCases)),
+	}
+	if len(subtasks) > 0 {
+		breakdown, score, maxScore := scoreSubtasks(subtasks, passedIDs)
+		result.Subtasks = breakdown
+		result.Score, result.MaxScore = &score, &maxScore
+	} else if scored {
+		score, maxScore := partialScore(passed, int32(len(testCases)))
+		result.Score, result.MaxScore = &score, &maxScore
+	}
+
+	if finalVerdict == VerdictAC {
+		// テストケースごとに適用された制限と比べる
+		result.CloseCallTime = closeCallTime
+		result.CloseCallMemory = closeCallMemory
 	}
 
+	result.Signature = SignResult(p.signingKey, result)
 	if saveErr := p.subRepo.SaveResult(ctx, result, finalStatus); saveErr != nil {
 		log.Printf("failed to save run result for %d: %v", id, saveErr)
 	}
@@ -222,26 +339,45 @@ func (p *WorkerProcessor) Process(ctx context.Context, jobID string) (string, er
 	return finalVerdict, nil
 }
 
+// problemMaxScore is the full score of a problem in partial-scoring contests.
+const problemMaxScore int32 = 100
+
+// partialScore awards points proportionally to the passed testcases
+// (used when the problem defines no subtasks).
+func partialScore(passed, total int32) (int32, int32) {
+	if total <= 0 {
+		return 0, problemMaxScore
+	}
+	return problemMaxScore * passed / total, problemMaxScore
+}
+
+// closeCallRatio: AC でも制限のこの割合以上を使っていれば "close call" とみなす
+const closeCallRatio = 0.95
+
+func isCloseCall(used, limit int64) bool {
+	return limit > 0 && float64(used) >= float64(limit)*closeCallRatio
+}
+
 func mapVerdict(res *judgeResponse) string {
 	if res == nil {
-		return "RE"
+		return VerdictRE
 	}
 	switch res.Status {
 	case "Accepted":
 		if res.ExitStatus == 0 {
-			return "AC"
+			return VerdictAC
 		}
-		return "RE"
+		return VerdictRE
 	case "Time Limit Exceeded":
-		return "TLE"
+		return VerdictTLE
 	case "Memory Limit Exceeded":
-		return "MLE"
+		return VerdictMLE
 	case "Output Limit Exceeded":
-		return "OLE"
+		return VerdictOLE
 	case "Runtime Error":
-		return "RE"
+		return VerdictRE
 	default:
-		return "RE"
+		return VerdictRE
 	}
 }
 
@@ -254,51 +390,41 @@ func stringPtrIfNotEmpty(s string) *string {
 
 // testCase represents single input/output pair.
 type testCase struct {
+	id       int64
 	name     string
 	stdin    string
 	expected string
+	// group and its limit overrides (0 = use the problem's limits)
+	group         string
+	timeLimitMs   int
+	memoryLimitMb int
 }
 
 // loadTestCases uses inline DB contents only (file path fallback is disabled).
 func (p *WorkerProcessor) loadTestCases(ctx context.Context, problemID int64) ([]testCase, error) {
 	dbCases, err := p.problemRepo.ListTestcases(ctx, problemID)
 	if err != nil {
-		return nil, err
+		return nil, withSEReason(SEReasonDatabase, err)
 	}
 	if len(dbCases) == 0 {
-		return nil, errors.New("no testcases defined for problem")
+		return nil, withSEReason(SEReasonProblemConfig, errors.New("no testcases defined for problem"))
 	}
 	out := make([]testCase, 0, len(dbCases))
 	for i, tc := range dbCases {
-		out = append(out, testCase{
+		c := testCase{
+			id:       tc.ID,
 			name:     strconv.Itoa(i + 1),
 			stdin:    tc.InputText,
 			expected: tc.OutputText,
-		})
-	}
-	return out, nil
-}
-
-func outputsEqualWithChecker(actual, expected, checkerType string, eps float64) bool {
-	switch strings.ToLower(strings.TrimSpace(checkerType)) {
-	case "eps":
-		aa := strings.Fields(actual)
-		bb := strings.Fields(expected)
-		if len(aa) != len(bb) {
-			return false
+			group:    tc.GroupName,
 		}
-		for i := range aa {
-			x, err1 := strconv.ParseFloat(aa[i], 64)
-			y, err2 := strconv.ParseFloat(bb[i], 64)
-			if err1 != nil || err2 != nil {
-				return false
-			}
-			if math.Abs(x-y) > eps {
-				return false
-			}
+		if tc.TimeLimitMS != nil {
+			c.timeLimitMs = int(*tc.TimeLimitMS)
 		}
-		return true
-	default:
-		return strings.TrimRight(actual, "\r\n ") == strings.TrimRight(expected, "\r\n ")
+		if tc.MemoryLimitKB != nil {
+			c.memoryLimitMb = int((*tc.MemoryLimitKB + 1023) / 1024)
+		}
+		out = append(out, c)
 	}
+	return out, nil
 }
@@ -0,0 +1,328 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// Worker registry states.
+const (
+	WorkerActive  = "active"
+	WorkerStopped = "stopped"
+	WorkerLost    = "lost"
+)
+
+const (
+	WorkerInFlightPrefix     = "worker:inflight:"
+	workerRegistryRetention  = 30 * 24 * time.Hour
+	maxWorkerRegistryEntries = 200
+	// WorkerRegistryTouchInterval is how often a worker writes its last-seen time and counts.
+	WorkerRegistryTouchInterval = 30 * time.Second
+)
+
+// WorkerVersion returns the build version of the running binary: the VCS revision when it was
+// built from a checkout, otherwise the module version.
+func WorkerVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	return buildInfoVersion(info)
+}
+
+func buildInfoVersion(info *debug.BuildInfo) string {
+	var revision string
+	dirty := false
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			dirty = s.Value == "true"
+		}
+	}
+	if revision == "" {
+		if info.Main.Version == "" {
+			return "unknown"
+		}
+		return info.Main.Version
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if dirty {
+		revision += "-dirty"
+	}
+	return revision
+}
+
+// WorkerInFlightKey is the Redis set of jobs the worker has reserved and not yet acked.
+func WorkerInFlightKey(id string) string {
+	return WorkerInFlightPrefix + id
+}
+
+// TrackWorkerJob records job as in flight on the worker.
+func TrackWorkerJob(ctx context.Context, client *redis.Client, workerID, job string) error {
+	return client.SAdd(ctx, WorkerInFlightKey(workerID), job).Err()
+}
+
+// UntrackWorkerJob removes job from the worker's in-flight set.
+func UntrackWorkerJob(ctx context.Context, client *redis.Client, workerID, job string) error {
+	return client.SRem(ctx, WorkerInFlightKey(workerID), job).Err()
+}
+
+// ReclaimWorkerJobs moves the worker's in-flight jobs that are still reserved back to pending and
+// drops its in-flight set. Jobs already acked or reclaimed by the visibility timeout are skipped.
+func ReclaimWorkerJobs(ctx context.Context, client *redis.Client, workerID string) ([]string, error) {
+	script := redis.NewScript(`
+local jobs = redis.call('SMEMBERS', KEYS[1])
+local moved = {}
+for _, job in ipairs(jobs) do
+  if redis.call('ZREM', KEYS[2], job) == 1 then
+    redis.call('RPUSH', KEYS[3], job)
+    table.insert(moved, job)
+  end
+end
+redis.call('DEL', KEYS[1])
+return moved
+`)
+	res, err := script.Run(ctx, client, []string{WorkerInFlightKey(workerID), ProcessingQueueKey, PendingQueueKey}).StringSlice()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// WorkerRegistration is a worker process as recorded in the registry.
+type WorkerRegistration struct {
+	WorkerID       string     `json:"worker_id"`
+	Hostname       string     `json:"hostname"`
+	PID            int        `json:"pid"`
+	Version        string     `json:"version"`
+	Concurrency    int        `json:"concurrency"`
+	Status         string     `json:"status"`
+	StartedAt      time.Time  `json:"started_at"`
+	LastSeenAt     time.Time  `json:"last_seen_at"`
+	StoppedAt      *time.Time `json:"stopped_at"`
+	RequeuedJobs   int        `json:"requeued_jobs"`
+	ProcessedTotal int64      `json:"processed_total"`
+	FailedTotal    int64      `json:"failed_total"`
+}
+
+// WorkerHost summarises the registered workers of one host.
+type WorkerHost struct {
+	Hostname       string    `json:"hostname"`
+	FirstSeenAt    time.Time `json:"first_seen_at"`
+	LastSeenAt     time.Time `json:"last_seen_at"`
+	Versions       []string  `json:"versions"` // 新しく起動した順
+	Workers        int       `json:"workers"`
+	ActiveWorkers  int       `json:"active_workers"`
+	ProcessedTotal int64     `json:"processed_total"`
+	FailedTotal    int64     `json:"failed_total"`
+}
+
+// WorkerRegistry persists worker processes beyond the lifetime of their heartbeat.
+type WorkerRegistry interface {
+	Register(ctx context.Context, w WorkerRegistration) error
+	// Touch records that the worker is alive with its lifetime counts.
+	Touch(ctx context.Context, workerID string, processed, failed int64) error
+	MarkStopped(ctx context.Context, workerID string) error
+	// MarkLost moves an active worker to lost; it reports false when another caller got there first.
+	MarkLost(ctx context.Context, workerID string, requeued int) (bool, error)
+	ListActive(ctx context.Context) ([]WorkerRegistration, error)
+	List(ctx context.Context, limit int) ([]WorkerRegistration, error)
+	// Hosts aggregates the registry by hostname, most recently seen first.
+	Hosts(ctx context.Context) ([]WorkerHost, error)
+	// Prune deletes workers that stopped or were lost before the given time.
+	Prune(ctx context.Context, before time.Time) error
+}
+
+type PgWorkerRegistry struct {
+	db *pgxpool.Pool
+}
+
+func NewPgWorkerRegistry(db *pgxpool.Pool) *PgWorkerRegistry {
+	return &PgWorkerRegistry{db: db}
+}
+
+const workerRegistrationColumns = `worker_id, hostname, pid, version, concurrency, status, started_at, last_seen_at, stopped_at, requeued_jobs, processed_total, failed_total`
+
+func (r *PgWorkerRegistry) Register(ctx context.Context, w WorkerRegistration) error {
+	_, err := r.db.Exec(ctx, `INSERT INTO workers (worker_id, hostname, pid, version, concurrency) VALUES ($1,$2,$3,$4,$5)
+ON CONFLICT (worker_id) DO UPDATE SET status='active', stopped_at=NULL, last_seen_at=NOW()`,
+		w.WorkerID, w.Hostname, w.PID, w.Version, w.Concurrency)
+	return err
+}
+
+func (r *PgWorkerRegistry) Touch(ctx context.Context, workerID string, processed, failed int64) error {
+	_, err := r.db.Exec(ctx, `UPDATE workers SET last_seen_at=NOW(), processed_total=$2, failed_total=$3 WHERE worker_id=$1`,
+		workerID, processed, failed)
+	return err
+}
+
+func (r *PgWorkerRegistry) MarkStopped(ctx context.Context, workerID string) error {
+	_, err := r.db.Exec(ctx, `UPDATE workers SET status=$2, stopped_at=NOW() WHERE worker_id=$1 AND status=$3`,
+		workerID, WorkerStopped, WorkerActive)
+	return err
+}
+
+func (r *PgWorkerRegistry) MarkLost(ctx context.Context, workerID string, requeued int) (bool, error) {
+	ct, err := r.db.Exec(ctx, `UPDATE workers SET status=$2, stopped_at=NOW(), requeued_jobs=$3 WHERE worker_id=$1 AND status=$4`,
+		workerID, WorkerLost, requeued, WorkerActive)
+	if err != nil {
+		return false, err
+	}
+	return ct.RowsAffected() == 1, nil
+}
+
+func (r *PgWorkerRegistry) ListActive(ctx context.Context) ([]WorkerRegistration, error) {
+	return r.list(ctx, `SELECT `+workerRegistrationColumns+` FROM workers WHERE status=$1 ORDER BY started_at`, WorkerActive)
+}
+
+func (r *PgWorkerRegistry) List(ctx context.Context, limit int) ([]WorkerRegistration, error) {
+	return r.list(ctx, `SELECT `+workerRegistrationColumns+` FROM workers ORDER BY started_at DESC LIMIT $1`, limit)
+}
+
+func (r *PgWorkerRegistry) list(ctx context.Context, q string, args ...interface{}) ([]WorkerRegistration, error) {
+	rows, err := r.db.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []WorkerRegistration{}
+	for rows.Next() {
+		var w WorkerRegistration
+		if err := rows.Scan(&w.WorkerID, &w.Hostname, &w.PID, &w.Version, &w.Concurrency, &w.Status, &w.StartedAt,
+			&w.LastSeenAt, &w.StoppedAt, &w.RequeuedJobs, &w.ProcessedTotal, &w.FailedTotal); err != nil {
+			return nil, err
+		}
+		items = append(items, w)
+	}
+	return items, rows.Err()
+}
+
+func (r *PgWorkerRegistry) Hosts(ctx context.Context) ([]WorkerHost, error) {
+	rows, err := r.db.Query(ctx, `
+SELECT hostname, MIN(started_at), MAX(last_seen_at),
+       ARRAY(SELECT v.version FROM workers v WHERE v.hostname = w.hostname AND v.version <> ''
+             GROUP BY v.version ORDER BY MAX(v.started_at) DESC),
+       COUNT(*), COUNT(*) FILTER (WHERE status=$1), COALESCE(SUM(processed_total), 0), COALESCE(SUM(failed_total), 0)
+FROM workers w
+GROUP BY hostname
+ORDER BY MAX(last_seen_at) DESC`, WorkerActive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []WorkerHost{}
+	for rows.Next() {
+		var h WorkerHost
+		if err := rows.Scan(&h.Hostname, &h.FirstSeenAt, &h.LastSeenAt, &h.Versions, &h.Workers, &h.ActiveWorkers, &h.ProcessedTotal, &h.FailedTotal); err != nil {
+			return nil, err
+		}
+		items = append(items, h)
+	}
+	return items, rows.Err()
+}
+
+func (r *PgWorkerRegistry) Prune(ctx context.Context, before time.Time) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM workers WHERE status<>$1 AND stopped_at < $2`, WorkerActive, before)
+	return err
+}
+
+// WorkerReaper watches registered workers and handles those whose heartbeat expired without a
+// shutdown: their in-flight jobs are requeued at once instead of waiting for the visibility timeout,
+// the worker is marked lost, and an incident is raised when jobs were interrupted.
+type WorkerReaper struct {
+	registry WorkerRegistry
+	client   *redis.Client
+	subRepo  SubmissionRepository
+	monitor  *AnomalyMonitor
+}
+
+func NewWorkerReaper(registry WorkerRegistry, client *redis.Client, subRepo SubmissionRepository, monitor *AnomalyMonitor) *WorkerReaper {
+	return &WorkerReaper{registry: registry, client: client, subRepo: subRepo, monitor: monitor}
+}
+
+// Run sweeps every interval until ctx is cancelled.
+func (r *WorkerReaper) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := r.Sweep(ctx, time.Now()); err != nil {
+			log.Printf("[reaper] sweep failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep checks every active worker once. Workers started less than a heartbeat TTL ago are skipped
+// so that one that has not sent its first heartbeat yet is not taken for lost.
+func (r *WorkerReaper) Sweep(ctx context.Context, now time.Time) error {
+	workers, err := r.registry.ListActive(ctx)
+	if err != nil {
+		return err
+	}
+	for _, w := range workers {
+		if now.Sub(w.StartedAt) < WorkerHeartbeatTTL {
+			continue
+		}
+		n, err := r.client.Exists(ctx, WorkerHeartbeatKey(w.WorkerID)).Result()
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+		if err := r.reap(ctx, w); err != nil {
+			log.Printf("[reaper] worker %s: %v", w.WorkerID, err)
+		}
+	}
+	return r.registry.Prune(ctx, now.Add(-workerRegistryRetention))
+}
+
+func (r *WorkerReaper) reap(ctx context.Context, w WorkerRegistration) error {
+	jobs, err := ReclaimWorkerJobs(ctx, r.client, w.WorkerID)
+	if err != nil {
+		return err
+	}
+	// 期限切れの再投入と同じく、リトライとして数える（ワーカーを落とす提出が繰り返し流れないように）
+	for _, job := range jobs {
+		if id, err := strconv.ParseInt(job, 10, 64); err == nil {
+			_ = r.subRepo.MarkStatus(ctx, id, "pending")
+			_, _ = r.subRepo.IncrementRetry(ctx, id)
+		}
+	}
+	marked, err := r.registry.MarkLost(ctx, w.WorkerID, len(jobs))
+	if err != nil || !marked {
+		return err
+	}
+	log.Printf("[reaper] worker %s (%s pid=%d) lost, requeued %d jobs", w.WorkerID, w.Hostname, w.PID, len(jobs))
+	if len(jobs) == 0 {
+		return nil
+	}
+	_, err = r.monitor.Raise(ctx, Incident{
+		Kind:    IncidentKindWorkerLost,
+		Subject: "worker:" + w.WorkerID,
+		Summary: fmt.Sprintf("ワーカー %s（%s）のハートビートが途絶えたため、実行中だった %d 件を再投入しました", w.WorkerID, w.Hostname, len(jobs)),
+		Details: map[string]any{
+			"worker_id":  w.WorkerID,
+			"hostname":   w.Hostname,
+			"pid":        w.PID,
+			"started_at": w.StartedAt,
+			"requeued":   jobs,
+		},
+	})
+	return err
+}
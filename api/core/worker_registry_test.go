@@ -0,0 +1,67 @@
+package core
+
+import (
+	"context"
+	"reflect"
+	"runtime/debug"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestReclaimWorkerJobsRequeuesOnlyReservedJobs(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ctx := context.Background()
+
+	// 1 は実行中、2 は他のワーカーのもの、3 は ack 済みで実行中の印だけ残っている
+	if err := client.ZAdd(ctx, ProcessingQueueKey, redis.Z{Score: 1, Member: "1"}, redis.Z{Score: 1, Member: "2"}).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.LPush(ctx, PendingQueueKey, "9").Err(); err != nil {
+		t.Fatal(err)
+	}
+	for _, job := range []string{"1", "3"} {
+		if err := TrackWorkerJob(ctx, client, "w1", job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	jobs, err := ReclaimWorkerJobs(ctx, client, "w1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(jobs, []string{"1"}) {
+		t.Fatalf("reclaimed %v, want [1]", jobs)
+	}
+	// 再投入したジョブは待ち行列の先頭（次に取り出される側）に入る
+	if next, _ := client.RPop(ctx, PendingQueueKey).Result(); next != "1" {
+		t.Fatalf("next pending job is %q, want 1", next)
+	}
+	if n, _ := client.ZCard(ctx, ProcessingQueueKey).Result(); n != 1 {
+		t.Fatal("job of another worker was touched")
+	}
+	if mr.Exists(WorkerInFlightKey("w1")) {
+		t.Fatal("in-flight set was not removed")
+	}
+
+	jobs, err = ReclaimWorkerJobs(ctx, client, "w1")
+	if err != nil || len(jobs) != 0 {
+		t.Fatalf("second reclaim = %v, %v", jobs, err)
+	}
+}
+
+func TestBuildInfoVersion(t *testing.T) {
+	info := &debug.BuildInfo{Main: debug.Module{Version: "(devel)"}}
+	if got := buildInfoVersion(info); got != "(devel)" {
+		t.Fatalf("got %q", got)
+	}
+	info.Settings = []debug.BuildSetting{{Key: "vcs.revision", Value: "0123456789abcdef0123"}, {Key: "vcs.modified", Value: "true"}}
+	if got := buildInfoVersion(info); got != "0123456789ab-dirty" {
+		t.Fatalf("got %q", got)
+	}
+	if got := buildInfoVersion(&debug.BuildInfo{}); got != "unknown" {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -0,0 +1,206 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	eventSubmissionVerdict = "submission_verdict"
+	eventNotice            = "notice"
+)
+
+const (
+	wsWriteTimeout   = 10 * time.Second
+	wsPongTimeout    = 60 * time.Second
+	wsPingInterval   = 25 * time.Second
+	wsSendBuffer     = 32
+	wsMaxInboundSize = 512
+	// wsResubscribeDelay は Redis の購読が切れたときに張り直すまでの待ち時間
+	wsResubscribeDelay = 3 * time.Second
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+	// Origin は OriginRefererMiddleware で検証済み
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// SubmissionVerdictEvent is the payload pushed when a submission finishes judging.
+type SubmissionVerdictEvent struct {
+	SubmissionID int64     `json:"submission_id"`
+	ProblemID    int64     `json:"problem_id"`
+	ProblemTitle string    `json:"problem_title"`
+	Status       string    `json:"status"`
+	Verdict      *string   `json:"verdict"`
+	TimeMS       *int32    `json:"time_ms"`
+	MemoryKB     *int32    `json:"memory_kb"`
+	Score        *int32    `json:"score"`
+	MaxScore     *int32    `json:"max_score"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// PublishSubmissionVerdict pushes the stored result of a submission to its owner's live connections,
+// and the derived contest (standings / balloon) and staff (system error) events.
+func PublishSubmissionVerdict(ctx context.Context, bus *EventBus, subRepo SubmissionRepository, submissionID int64) error {
+	v, err := subRepo.FindWithResult(ctx, submissionID)
+	if err != nil {
+		return err
+	}
+	if err := bus.PublishToUser(ctx, v.UserID, eventSubmissionVerdict, v.ID, SubmissionVerdictEvent{
+		SubmissionID: v.ID,
+		ProblemID:    v.ProblemID,
+		ProblemTitle: v.ProblemTitle,
+		Status:       v.Status,
+		Verdict:      v.Verdict,
+		TimeMS:       v.TimeMS,
+		MemoryKB:     v.MemoryKB,
+		Score:        v.Score,
+		MaxScore:     v.MaxScore,
+		UpdatedAt:    v.UpdatedAt,
+	}); err != nil {
+		return err
+	}
+	return publishJudgedEvents(ctx, bus, subRepo, v)
+}
+
+// WSHub fans events from the EventBus out to the WebSocket connections of this API instance.
+// One Redis subscription serves all connections; it is opened with the first connection.
+type WSHub struct {
+	bus     *EventBus
+	mu      sync.Mutex
+	clients map[int64]map[*wsClient]struct{}
+	running bool
+}
+
+type wsClient struct {
+	userID int64
+	send   chan []byte
+}
+
+func NewWSHub(bus *EventBus) *WSHub {
+	return &WSHub{bus: bus, clients: map[int64]map[*wsClient]struct{}{}}
+}
+
+func (h *WSHub) register(cl *wsClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.clients[cl.userID] == nil {
+		h.clients[cl.userID] = map[*wsClient]struct{}{}
+	}
+	h.clients[cl.userID][cl] = struct{}{}
+	if !h.running {
+		h.running = true
+		go h.run()
+	}
+}
+
+func (h *WSHub) unregister(cl *wsClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if set, ok := h.clients[cl.userID]; ok {
+		if _, ok := set[cl]; ok {
+			delete(set, cl)
+			close(cl.send)
+		}
+		if len(set) == 0 {
+			delete(h.clients, cl.userID)
+		}
+	}
+}
+
+// run keeps the subscription open for the life of the process, re-subscribing after Redis errors.
+func (h *WSHub) run() {
+	ctx := context.Background()
+	for {
+		events, err := h.bus.Subscribe(ctx, UserEventChannel, NoticeEventChannel)
+		if err != nil {
+			log.Printf("[ws] subscribe failed: %v", err)
+			time.Sleep(wsResubscribeDelay)
+			continue
+		}
+		for ev := range events {
+			h.dispatch(ev)
+		}
+		log.Printf("[ws] subscription closed, re-subscribing")
+		time.Sleep(wsResubscribeDelay)
+	}
+}
+
+// dispatch sends user events to that user's connections and everything else to all connections.
+// A connection whose buffer is full misses the event rather than stalling the hub.
+func (h *WSHub) dispatch(ev Event) {
+	msg, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	deliver := func(set map[*wsClient]struct{}) {
+		for cl := range set {
+			select {
+			case cl.send <- msg:
+			default:
+				log.Printf("[ws] dropping %s for slow client of user %d", ev.Type, cl.userID)
+			}
+		}
+	}
+	if ev.UserID != 0 {
+		deliver(h.clients[ev.UserID])
+		return
+	}
+	for _, set := range h.clients {
+		deliver(set)
+	}
+}
+
+// serve pumps events to conn until either side closes. Inbound messages are read only to
+// process pings / close frames.
+func (h *WSHub) serve(conn *websocket.Conn, userID int64) {
+	cl := &wsClient{userID: userID, send: make(chan []byte, wsSendBuffer)}
+	h.register(cl)
+	defer conn.Close()
+	defer h.unregister(cl)
+
+	go func() {
+		ping := time.NewTicker(wsPingInterval)
+		defer ping.Stop()
+		for {
+			select {
+			case msg, ok := <-cl.send:
+				_ = conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+				if !ok {
+					_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+					return
+				}
+				if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+					conn.Close()
+					return
+				}
+			case <-ping.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+					conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	conn.SetReadLimit(wsMaxInboundSize)
+	_ = conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	})
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
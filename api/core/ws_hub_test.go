@@ -0,0 +1,90 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestWSHubRoutesEventsByUser(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	bus := NewEventBus(client)
+	hub := NewWSHub(bus)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := wsUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		userID := int64(1)
+		if r.URL.Query().Get("user") == "2" {
+			userID = 2
+		}
+		hub.serve(conn, userID)
+	}))
+	defer srv.Close()
+
+	dial := func(user string) *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"?user="+user, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+	alice, bob := dial("1"), dial("2")
+
+	ctx := context.Background()
+	// ハブの購読が確立するまで待つ
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		n, err := client.PubSubNumSub(ctx, UserEventChannel).Result()
+		if err == nil && n[UserEventChannel] > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("hub did not subscribe")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := bus.PublishToUser(ctx, 2, eventSubmissionVerdict, 42, map[string]string{"verdict": "AC"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Publish(ctx, NoticeEventChannel, eventNotice, 7, map[string]string{"title": "maintenance"}); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(conn *websocket.Conn) Event {
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ev Event
+		if err := json.Unmarshal(msg, &ev); err != nil {
+			t.Fatal(err)
+		}
+		return ev
+	}
+	if ev := read(bob); ev.Type != eventSubmissionVerdict || ev.ID != 42 || ev.UserID != 2 {
+		t.Fatalf("bob got %+v", ev)
+	}
+	if ev := read(bob); ev.Type != eventNotice || ev.ID != 7 {
+		t.Fatalf("bob got %+v", ev)
+	}
+	// alice には他人の判定は届かず、お知らせだけが届く
+	if ev := read(alice); ev.Type != eventNotice {
+		t.Fatalf("alice got %+v", ev)
+	}
+}
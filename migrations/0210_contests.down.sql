@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS contest_registrations;
+DROP TABLE IF EXISTS contest_problems;
+DROP TRIGGER IF EXISTS trg_contests_updated ON contests;
+DROP TABLE IF EXISTS contests;
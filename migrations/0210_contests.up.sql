@@ -0,0 +1,38 @@
+-- コンテスト（開始・終了時刻、出題問題、参加登録）
+
+CREATE TABLE IF NOT EXISTS contests (
+    id              BIGSERIAL PRIMARY KEY,
+    slug            VARCHAR(128) NOT NULL UNIQUE,
+    title           TEXT NOT NULL,
+    description_md  TEXT NOT NULL DEFAULT '',
+    start_at        TIMESTAMPTZ NOT NULL,
+    end_at          TIMESTAMPTZ NOT NULL,
+    is_public       BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (end_at > start_at)
+);
+CREATE INDEX IF NOT EXISTS idx_contests_start_at ON contests(start_at);
+CREATE TRIGGER trg_contests_updated
+    BEFORE UPDATE ON contests
+    FOR EACH ROW EXECUTE PROCEDURE set_updated_at();
+
+-- コンテストに出題する問題（A, B, C ... のラベル付き）
+CREATE TABLE IF NOT EXISTS contest_problems (
+    contest_id  BIGINT NOT NULL REFERENCES contests(id) ON DELETE CASCADE,
+    problem_id  BIGINT NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    label       VARCHAR(16) NOT NULL,
+    position    INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (contest_id, problem_id),
+    UNIQUE (contest_id, label)
+);
+CREATE INDEX IF NOT EXISTS idx_contest_problems_problem ON contest_problems(problem_id);
+
+-- 参加登録
+CREATE TABLE IF NOT EXISTS contest_registrations (
+    contest_id     BIGINT NOT NULL REFERENCES contests(id) ON DELETE CASCADE,
+    user_id        BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    registered_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (contest_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_contest_registrations_user ON contest_registrations(user_id);
@@ -0,0 +1,8 @@
+CREATE INDEX IF NOT EXISTS idx_submission_result_details_submission ON submission_result_details(submission_id);
+DROP INDEX IF EXISTS idx_submission_result_details_submission_id_id;
+
+ALTER TABLE submission_result_details
+    DROP COLUMN IF EXISTS stderr_path,
+    DROP COLUMN IF EXISTS stdout_path,
+    DROP COLUMN IF EXISTS output_bytes,
+    DROP COLUMN IF EXISTS input_bytes;
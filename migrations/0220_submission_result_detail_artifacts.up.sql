@@ -0,0 +1,11 @@
+-- テストケースごとの入出力サイズと保存済み成果物（stdout/stderr）のパス
+ALTER TABLE submission_result_details
+    ADD COLUMN IF NOT EXISTS input_bytes  INTEGER,
+    ADD COLUMN IF NOT EXISTS output_bytes INTEGER,
+    ADD COLUMN IF NOT EXISTS stdout_path  TEXT,
+    ADD COLUMN IF NOT EXISTS stderr_path  TEXT;
+
+-- 提出単位の取得 (WHERE submission_id=$1 ORDER BY id) をインデックスのみで完結させる
+CREATE INDEX IF NOT EXISTS idx_submission_result_details_submission_id_id
+    ON submission_result_details(submission_id, id);
+DROP INDEX IF EXISTS idx_submission_result_details_submission;
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS trg_contest_standing_cells_updated ON contest_standing_cells;
+DROP TABLE IF EXISTS contest_standing_cells;
+
+DROP INDEX IF EXISTS idx_submissions_contest_user_problem;
+ALTER TABLE submissions DROP COLUMN IF EXISTS contest_id;
@@ -0,0 +1,22 @@
+-- コンテスト中の提出と ICPC 形式順位表の集計セル
+
+ALTER TABLE submissions
+    ADD COLUMN IF NOT EXISTS contest_id BIGINT REFERENCES contests(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_submissions_contest_user_problem
+    ON submissions(contest_id, user_id, problem_id) WHERE contest_id IS NOT NULL;
+
+-- (contest, user, problem) ごとの集計結果。判定確定のたびに該当セルだけ再計算する
+CREATE TABLE IF NOT EXISTS contest_standing_cells (
+    contest_id        BIGINT NOT NULL REFERENCES contests(id) ON DELETE CASCADE,
+    user_id           BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    problem_id        BIGINT NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    wrong_attempts    INTEGER NOT NULL DEFAULT 0,
+    pending_attempts  INTEGER NOT NULL DEFAULT 0,
+    solved_at         TIMESTAMPTZ,
+    solved_minutes    INTEGER,
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (contest_id, user_id, problem_id)
+);
+CREATE TRIGGER trg_contest_standing_cells_updated
+    BEFORE UPDATE ON contest_standing_cells
+    FOR EACH ROW EXECUTE PROCEDURE set_updated_at();
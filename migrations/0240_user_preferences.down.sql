@@ -0,0 +1,3 @@
+ALTER TABLE users
+    DROP COLUMN IF EXISTS locale,
+    DROP COLUMN IF EXISTS timezone;
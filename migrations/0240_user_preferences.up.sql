@@ -0,0 +1,4 @@
+-- ユーザーごとのタイムゾーン・ロケール設定（API の時刻は常に UTC、CSV 出力などの表示用）
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'Asia/Tokyo',
+    ADD COLUMN IF NOT EXISTS locale   VARCHAR(16) NOT NULL DEFAULT 'ja';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS contest_clarification_reads;
+DROP TRIGGER IF EXISTS trg_contest_clarifications_updated ON contest_clarifications;
+DROP TABLE IF EXISTS contest_clarifications;
@@ -0,0 +1,27 @@
+-- コンテスト中の質問（Clarification）と既読管理
+
+CREATE TABLE IF NOT EXISTS contest_clarifications (
+    id            BIGSERIAL PRIMARY KEY,
+    contest_id    BIGINT NOT NULL REFERENCES contests(id) ON DELETE CASCADE,
+    problem_id    BIGINT REFERENCES problems(id) ON DELETE SET NULL,
+    user_id       BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    question      TEXT NOT NULL,
+    answer        TEXT,
+    answered_by   BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    answered_at   TIMESTAMPTZ,
+    is_public     BOOLEAN NOT NULL DEFAULT FALSE, -- 回答を参加者全員に公開する
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_contest_clarifications_contest ON contest_clarifications(contest_id, id);
+CREATE TRIGGER trg_contest_clarifications_updated
+    BEFORE UPDATE ON contest_clarifications
+    FOR EACH ROW EXECUTE PROCEDURE set_updated_at();
+
+-- 参加者ごとの既読位置（これより後に回答されたものが未読）
+CREATE TABLE IF NOT EXISTS contest_clarification_reads (
+    contest_id    BIGINT NOT NULL REFERENCES contests(id) ON DELETE CASCADE,
+    user_id       BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    last_read_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (contest_id, user_id)
+);
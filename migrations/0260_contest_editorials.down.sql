@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_contests_editorials_pending;
+ALTER TABLE contests DROP COLUMN IF EXISTS editorials_released_at;
+
+ALTER TABLE contest_problems
+    DROP COLUMN IF EXISTS editorial_auto_release,
+    DROP COLUMN IF EXISTS editorial_public,
+    DROP COLUMN IF EXISTS editorial_md;
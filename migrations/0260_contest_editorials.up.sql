@@ -0,0 +1,12 @@
+-- コンテスト問題ごとの解説と、コンテスト終了時の自動公開
+
+ALTER TABLE contest_problems
+    ADD COLUMN IF NOT EXISTS editorial_md            TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS editorial_public        BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS editorial_auto_release  BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- 終了後の一括公開を済ませた時刻（NULL の間は releaser の対象）
+ALTER TABLE contests
+    ADD COLUMN IF NOT EXISTS editorials_released_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_contests_editorials_pending
+    ON contests(end_at) WHERE editorials_released_at IS NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_problems_contest;
+ALTER TABLE problems DROP COLUMN IF EXISTS contest_id;
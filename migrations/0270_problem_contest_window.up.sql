@@ -0,0 +1,4 @@
+-- 問題の公開期間をコンテストに紐付ける（開催中は参加者のみ、終了後は練習問題として公開）
+ALTER TABLE problems
+    ADD COLUMN IF NOT EXISTS contest_id BIGINT REFERENCES contests(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_problems_contest ON problems(contest_id) WHERE contest_id IS NOT NULL;
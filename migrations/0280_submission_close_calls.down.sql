@@ -0,0 +1,3 @@
+ALTER TABLE submission_results
+    DROP COLUMN IF EXISTS close_call_memory,
+    DROP COLUMN IF EXISTS close_call_time;
@@ -0,0 +1,4 @@
+-- AC だが制限の 95% 以上を使った提出（制限が厳しすぎないかの検知用）
+ALTER TABLE submission_results
+    ADD COLUMN IF NOT EXISTS close_call_time   BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS close_call_memory BOOLEAN NOT NULL DEFAULT FALSE;
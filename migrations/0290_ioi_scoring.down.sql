@@ -0,0 +1,9 @@
+ALTER TABLE contest_standing_cells
+    DROP COLUMN IF EXISTS best_score_minutes,
+    DROP COLUMN IF EXISTS best_score;
+
+ALTER TABLE submission_results
+    DROP COLUMN IF EXISTS max_score,
+    DROP COLUMN IF EXISTS score;
+
+ALTER TABLE contests DROP COLUMN IF EXISTS scoring_mode;
@@ -0,0 +1,14 @@
+-- IOI 形式（部分点）コンテスト: 得点フィールドと採点モード
+
+ALTER TABLE contests
+    ADD COLUMN IF NOT EXISTS scoring_mode VARCHAR(16) NOT NULL DEFAULT 'icpc'
+        CHECK (scoring_mode IN ('icpc', 'ioi'));
+
+-- passed_count / total_count は既存列を利用する
+ALTER TABLE submission_results
+    ADD COLUMN IF NOT EXISTS score     INTEGER,
+    ADD COLUMN IF NOT EXISTS max_score INTEGER;
+
+ALTER TABLE contest_standing_cells
+    ADD COLUMN IF NOT EXISTS best_score         INTEGER,
+    ADD COLUMN IF NOT EXISTS best_score_minutes INTEGER;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS incidents;
+DROP INDEX IF EXISTS idx_submission_results_judged_by;
+ALTER TABLE submission_results
+    DROP COLUMN IF EXISTS judged_by;
@@ -0,0 +1,20 @@
+-- 判定異常の自動検知: ワーカー識別子とインシデント記録
+
+-- どのワーカーが判定したか（ワーカー間の失敗率比較用）
+ALTER TABLE submission_results
+    ADD COLUMN IF NOT EXISTS judged_by VARCHAR(64);
+CREATE INDEX IF NOT EXISTS idx_submission_results_judged_by ON submission_results(judged_by, updated_at);
+
+CREATE TABLE IF NOT EXISTS incidents (
+    id            BIGSERIAL PRIMARY KEY,
+    kind          VARCHAR(32) NOT NULL, -- se_spike | problem_zero_ac | worker_failure
+    subject       VARCHAR(128) NOT NULL, -- 対象（global / problem:<id> / worker:<id>）
+    summary       TEXT NOT NULL,
+    details       JSONB NOT NULL DEFAULT '{}'::jsonb,
+    detected_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    resolved_at   TIMESTAMPTZ
+);
+-- 同じ対象の未解決インシデントは 1 件にまとめる
+CREATE UNIQUE INDEX IF NOT EXISTS uq_incidents_open ON incidents(kind, subject) WHERE resolved_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_incidents_detected ON incidents(detected_at DESC);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS contest_announcement_acks;
+DROP TABLE IF EXISTS contest_announcements;
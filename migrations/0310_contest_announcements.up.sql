@@ -0,0 +1,19 @@
+-- コンテスト内アナウンス（全体お知らせとは別）と既読確認
+
+CREATE TABLE IF NOT EXISTS contest_announcements (
+    id            BIGSERIAL PRIMARY KEY,
+    contest_id    BIGINT NOT NULL REFERENCES contests(id) ON DELETE CASCADE,
+    title         VARCHAR(255) NOT NULL,
+    body          TEXT NOT NULL,
+    is_critical   BOOLEAN NOT NULL DEFAULT FALSE, -- 重要: 参加者の確認状況を追跡する
+    created_by    BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_contest_announcements_contest ON contest_announcements(contest_id, id);
+
+CREATE TABLE IF NOT EXISTS contest_announcement_acks (
+    announcement_id BIGINT NOT NULL REFERENCES contest_announcements(id) ON DELETE CASCADE,
+    user_id         BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    acked_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (announcement_id, user_id)
+);
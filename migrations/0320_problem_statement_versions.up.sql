@@ -0,0 +1,10 @@
+-- 問題文の変更履歴（更新前の statement_md を保存する）
+
+CREATE TABLE IF NOT EXISTS problem_statement_versions (
+    id            BIGSERIAL PRIMARY KEY,
+    problem_id    BIGINT NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    statement_md  TEXT NOT NULL,
+    edited_by     BIGINT REFERENCES users(id) ON DELETE SET NULL, -- この版を置き換えた管理者
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_problem_statement_versions_problem ON problem_statement_versions(problem_id, id DESC);
@@ -0,0 +1,3 @@
+ALTER TABLE problems
+    DROP COLUMN IF EXISTS editorial_visibility,
+    DROP COLUMN IF EXISTS editorial_md;
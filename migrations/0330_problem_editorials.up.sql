@@ -0,0 +1,6 @@
+-- 問題ごとの解説。auto は練習問題なら即時、コンテスト問題なら終了後に公開する
+
+ALTER TABLE problems
+    ADD COLUMN IF NOT EXISTS editorial_md         TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS editorial_visibility VARCHAR(16) NOT NULL DEFAULT 'auto'
+        CHECK (editorial_visibility IN ('auto', 'public', 'hidden'));
@@ -0,0 +1,10 @@
+-- 提出時のクライアント情報（不正調査用、保持期間を過ぎたら削除）
+
+CREATE TABLE IF NOT EXISTS submission_client_info (
+    submission_id BIGINT PRIMARY KEY REFERENCES submissions(id) ON DELETE CASCADE,
+    ip            INET,
+    user_agent    TEXT NOT NULL DEFAULT '',
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_submission_client_info_ip ON submission_client_info(ip);
+CREATE INDEX IF NOT EXISTS idx_submission_client_info_created ON submission_client_info(created_at);
@@ -0,0 +1,2 @@
+ALTER TABLE submission_results
+    DROP COLUMN IF EXISTS signature;
@@ -0,0 +1,3 @@
+-- ワーカーが保存した判定結果の HMAC 署名（DB 上の改ざん検知用）
+ALTER TABLE submission_results
+    ADD COLUMN IF NOT EXISTS signature VARCHAR(128);
@@ -0,0 +1,4 @@
+ALTER TABLE submission_results
+    DROP COLUMN IF EXISTS subtask_results;
+DROP TABLE IF EXISTS problem_subtask_testcases;
+DROP TABLE IF EXISTS problem_subtasks;
@@ -0,0 +1,20 @@
+-- 小課題（サブタスク）: テストケースのグループごとの配点と、提出ごとの内訳
+
+CREATE TABLE IF NOT EXISTS problem_subtasks (
+    id          BIGSERIAL PRIMARY KEY,
+    problem_id  BIGINT NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    position    INTEGER NOT NULL,
+    name        VARCHAR(64) NOT NULL,
+    score       INTEGER NOT NULL CHECK (score >= 0),
+    UNIQUE (problem_id, position)
+);
+
+-- 1 つのテストケースが複数の小課題に属してよい
+CREATE TABLE IF NOT EXISTS problem_subtask_testcases (
+    subtask_id   BIGINT NOT NULL REFERENCES problem_subtasks(id) ON DELETE CASCADE,
+    testcase_id  BIGINT NOT NULL REFERENCES testcases(id) ON DELETE CASCADE,
+    PRIMARY KEY (subtask_id, testcase_id)
+);
+
+ALTER TABLE submission_results
+    ADD COLUMN IF NOT EXISTS subtask_results JSONB;
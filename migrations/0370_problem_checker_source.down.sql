@@ -0,0 +1,4 @@
+UPDATE problems SET checker_type = 'exact' WHERE checker_type = 'custom';
+
+ALTER TABLE problems
+    DROP COLUMN IF EXISTS checker_source;
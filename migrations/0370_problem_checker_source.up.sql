@@ -0,0 +1,4 @@
+-- カスタムチェッカー（checker_type = 'custom'）のソース。ワーカーが go-judge でコンパイルし問題ごとにキャッシュする
+
+ALTER TABLE problems
+    ADD COLUMN IF NOT EXISTS checker_source TEXT;
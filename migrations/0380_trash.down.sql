@@ -0,0 +1,15 @@
+DROP INDEX IF EXISTS idx_users_deleted_at;
+DROP INDEX IF EXISTS idx_problems_deleted_at;
+DROP INDEX IF EXISTS idx_notices_deleted_at;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS deleted_by,
+    DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE problems
+    DROP COLUMN IF EXISTS deleted_by,
+    DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE notices
+    DROP COLUMN IF EXISTS deleted_by,
+    DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,15 @@
+-- 稼働履歴: API プロセスが定期的に DB / Redis / go-judge の疎通と応答時間を記録する
+-- スナップショットが欠けている時間帯は API 停止とみなす
+
+CREATE TABLE IF NOT EXISTS health_snapshots (
+    id                BIGSERIAL PRIMARY KEY,
+    taken_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    db_ok             BOOLEAN NOT NULL,
+    db_latency_ms     INTEGER,
+    redis_ok          BOOLEAN NOT NULL,
+    redis_latency_ms  INTEGER,
+    judge_ok          BOOLEAN NOT NULL,
+    judge_latency_ms  INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS idx_health_snapshots_taken_at ON health_snapshots (taken_at);
@@ -0,0 +1,7 @@
+ALTER TABLE submission_result_details
+    DROP COLUMN IF EXISTS group_name;
+
+ALTER TABLE testcases
+    DROP COLUMN IF EXISTS group_id;
+
+DROP TABLE IF EXISTS problem_testcase_groups;
@@ -0,0 +1,17 @@
+-- テストケースグループ: グループごとに実行時間・メモリ制限を上書きする（例: small 1s / large 3s）
+
+CREATE TABLE IF NOT EXISTS problem_testcase_groups (
+    id               BIGSERIAL PRIMARY KEY,
+    problem_id       BIGINT NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    name             VARCHAR(64) NOT NULL,
+    time_limit_ms    INTEGER CHECK (time_limit_ms > 0),
+    memory_limit_kb  INTEGER CHECK (memory_limit_kb > 0),
+    UNIQUE (problem_id, name)
+);
+
+-- 1 つのテストケースは高々 1 つのグループに属する
+ALTER TABLE testcases
+    ADD COLUMN IF NOT EXISTS group_id BIGINT REFERENCES problem_testcase_groups(id) ON DELETE SET NULL;
+
+ALTER TABLE submission_result_details
+    ADD COLUMN IF NOT EXISTS group_name VARCHAR(64);
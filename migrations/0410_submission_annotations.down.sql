@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS submission_grades;
+DROP TABLE IF EXISTS submission_comments;
@@ -0,0 +1,21 @@
+-- 提出への講評: 行番号付きコメントと全体の評価メモ（提出者本人と運営のみ閲覧）
+
+CREATE TABLE IF NOT EXISTS submission_comments (
+    id             BIGSERIAL PRIMARY KEY,
+    submission_id  BIGINT NOT NULL REFERENCES submissions(id) ON DELETE CASCADE,
+    author_id      BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    line           INTEGER NOT NULL CHECK (line >= 1),
+    body           TEXT NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_submission_comments_submission ON submission_comments (submission_id, line, id);
+
+CREATE TABLE IF NOT EXISTS submission_grades (
+    submission_id  BIGINT PRIMARY KEY REFERENCES submissions(id) ON DELETE CASCADE,
+    grade          VARCHAR(32) NOT NULL DEFAULT '',
+    note           TEXT NOT NULL DEFAULT '',
+    graded_by      BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
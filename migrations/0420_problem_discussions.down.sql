@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS problem_thread_posts;
+DROP TABLE IF EXISTS problem_threads;
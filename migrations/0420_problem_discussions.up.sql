@@ -0,0 +1,33 @@
+-- 問題ごとのディスカッション: スレッドと返信（ネタバレ指定は未 AC の閲覧者に本文を伏せる）
+
+CREATE TABLE IF NOT EXISTS problem_threads (
+    id              BIGSERIAL PRIMARY KEY,
+    problem_id      BIGINT NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    author_id       BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    title           VARCHAR(200) NOT NULL,
+    body            TEXT NOT NULL,
+    spoiler         BOOLEAN NOT NULL DEFAULT FALSE,
+    pinned          BOOLEAN NOT NULL DEFAULT FALSE,
+    locked          BOOLEAN NOT NULL DEFAULT FALSE,
+    hidden_at       TIMESTAMPTZ,
+    hidden_by       BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_posted_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_problem_threads_problem ON problem_threads (problem_id, pinned DESC, last_posted_at DESC);
+
+CREATE TABLE IF NOT EXISTS problem_thread_posts (
+    id          BIGSERIAL PRIMARY KEY,
+    thread_id   BIGINT NOT NULL REFERENCES problem_threads(id) ON DELETE CASCADE,
+    author_id   BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    body        TEXT NOT NULL,
+    spoiler     BOOLEAN NOT NULL DEFAULT FALSE,
+    hidden_at   TIMESTAMPTZ,
+    hidden_by   BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_problem_thread_posts_thread ON problem_thread_posts (thread_id, id);
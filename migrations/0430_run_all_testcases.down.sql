@@ -0,0 +1,4 @@
+ALTER TABLE submissions
+    DROP COLUMN IF EXISTS run_all_testcases;
+ALTER TABLE problems
+    DROP COLUMN IF EXISTS run_all_testcases;
@@ -0,0 +1,7 @@
+-- 全テストケース実行モード。最初の不正解で打ち切らず、全ケースの詳細を記録する
+-- problems は問題ごとの既定、submissions は管理者のテスト提出・リジャッジ単位の指定
+
+ALTER TABLE problems
+    ADD COLUMN IF NOT EXISTS run_all_testcases BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE submissions
+    ADD COLUMN IF NOT EXISTS run_all_testcases BOOLEAN NOT NULL DEFAULT FALSE;
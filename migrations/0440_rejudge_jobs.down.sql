@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS rejudge_job_items;
+DROP TABLE IF EXISTS rejudge_jobs;
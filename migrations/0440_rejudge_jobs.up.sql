@@ -0,0 +1,21 @@
+-- 問題単位の一括リジャッジ。ジョブごとに対象提出と進捗（queued / done / failed）を記録する
+
+CREATE TABLE IF NOT EXISTS rejudge_jobs (
+    id                 BIGSERIAL PRIMARY KEY,
+    problem_id         BIGINT NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    requested_by       BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    run_all_testcases  BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_rejudge_jobs_problem ON rejudge_jobs (problem_id, id DESC);
+
+CREATE TABLE IF NOT EXISTS rejudge_job_items (
+    job_id         BIGINT NOT NULL REFERENCES rejudge_jobs(id) ON DELETE CASCADE,
+    submission_id  BIGINT NOT NULL REFERENCES submissions(id) ON DELETE CASCADE,
+    status         VARCHAR(16) NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'done', 'failed')),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (job_id, submission_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_rejudge_job_items_submission ON rejudge_job_items (submission_id) WHERE status = 'queued';
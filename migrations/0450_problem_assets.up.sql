@@ -0,0 +1,10 @@
+-- 問題パッケージの assets/ に同梱された問題文用の画像
+
+CREATE TABLE IF NOT EXISTS problem_assets (
+    problem_id    BIGINT NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    name          VARCHAR(255) NOT NULL,
+    content_type  VARCHAR(100) NOT NULL,
+    data          BYTEA NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (problem_id, name)
+);
@@ -0,0 +1,5 @@
+ALTER TABLE submissions
+    DROP COLUMN IF EXISTS gym_session_id;
+DROP TABLE IF EXISTS contest_gym_sessions;
+DROP TABLE IF EXISTS contest_gym_problems;
+DROP TABLE IF EXISTS contest_gyms;
@@ -0,0 +1,38 @@
+-- 終了したコンテストを複製した練習用ミラー（gym）。参加登録は不要で、誰でもセッションを開始して
+-- 本番と同じ問題セット・制限時間で挑戦できる。順位表はセッション単位で集計し、レーティングには影響しない
+
+CREATE TABLE IF NOT EXISTS contest_gyms (
+    id                 BIGSERIAL PRIMARY KEY,
+    source_contest_id  BIGINT REFERENCES contests(id) ON DELETE SET NULL,
+    title              VARCHAR(255) NOT NULL,
+    description_md     TEXT NOT NULL DEFAULT '',
+    scoring_mode       VARCHAR(16) NOT NULL DEFAULT 'icpc' CHECK (scoring_mode IN ('icpc', 'ioi')),
+    duration_minutes   INTEGER NOT NULL CHECK (duration_minutes > 0),
+    is_public          BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by         BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS contest_gym_problems (
+    gym_id      BIGINT NOT NULL REFERENCES contest_gyms(id) ON DELETE CASCADE,
+    problem_id  BIGINT NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    label       VARCHAR(16) NOT NULL,
+    position    INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (gym_id, problem_id),
+    UNIQUE (gym_id, label)
+);
+
+CREATE TABLE IF NOT EXISTS contest_gym_sessions (
+    id          BIGSERIAL PRIMARY KEY,
+    gym_id      BIGINT NOT NULL REFERENCES contest_gyms(id) ON DELETE CASCADE,
+    user_id     BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    started_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ends_at     TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_contest_gym_sessions_gym ON contest_gym_sessions (gym_id, user_id);
+
+ALTER TABLE submissions
+    ADD COLUMN IF NOT EXISTS gym_session_id BIGINT REFERENCES contest_gym_sessions(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_submissions_gym_session ON submissions (gym_session_id) WHERE gym_session_id IS NOT NULL;
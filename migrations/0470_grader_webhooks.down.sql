@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS grader_webhook_deliveries;
+DROP TABLE IF EXISTS grader_webhooks;
@@ -0,0 +1,34 @@
+-- 独自の採点系を持つ学科向けの Webhook。コンテスト（課題）または問題単位で登録し、
+-- 判定結果を HMAC 署名付きで送る。送信はキューに積み、失敗時はバックオフして再送する
+
+CREATE TABLE IF NOT EXISTS grader_webhooks (
+    id          BIGSERIAL PRIMARY KEY,
+    contest_id  BIGINT REFERENCES contests(id) ON DELETE CASCADE,
+    problem_id  BIGINT REFERENCES problems(id) ON DELETE CASCADE,
+    url         TEXT NOT NULL,
+    secret      TEXT NOT NULL,
+    is_active   BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by  BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK ((contest_id IS NULL) <> (problem_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_grader_webhooks_contest ON grader_webhooks (contest_id) WHERE contest_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_grader_webhooks_problem ON grader_webhooks (problem_id) WHERE problem_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS grader_webhook_deliveries (
+    id                BIGSERIAL PRIMARY KEY,
+    webhook_id        BIGINT NOT NULL REFERENCES grader_webhooks(id) ON DELETE CASCADE,
+    submission_id     BIGINT NOT NULL REFERENCES submissions(id) ON DELETE CASCADE,
+    payload           JSONB NOT NULL,
+    status            VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts          INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_status_code  INTEGER,
+    last_error        TEXT,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_grader_webhook_deliveries_due ON grader_webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_grader_webhook_deliveries_webhook ON grader_webhook_deliveries (webhook_id, id DESC);
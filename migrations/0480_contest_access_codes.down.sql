@@ -0,0 +1,2 @@
+ALTER TABLE contest_registrations DROP COLUMN IF EXISTS access_code_id;
+DROP TABLE IF EXISTS contest_access_codes;
@@ -0,0 +1,19 @@
+-- 非公開コンテストの参加コード。コードを入力したユーザーは管理者の承認なしで参加登録され、
+-- 以後そのコンテストを閲覧・提出できる。コードは大文字に正規化して全体で一意
+
+CREATE TABLE IF NOT EXISTS contest_access_codes (
+    id          BIGSERIAL PRIMARY KEY,
+    contest_id  BIGINT NOT NULL REFERENCES contests(id) ON DELETE CASCADE,
+    code        VARCHAR(64) NOT NULL UNIQUE,
+    max_uses    INTEGER CHECK (max_uses IS NULL OR max_uses > 0),
+    use_count   INTEGER NOT NULL DEFAULT 0,
+    expires_at  TIMESTAMPTZ,
+    is_active   BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by  BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_contest_access_codes_contest ON contest_access_codes (contest_id);
+
+ALTER TABLE contest_registrations
+    ADD COLUMN IF NOT EXISTS access_code_id BIGINT REFERENCES contest_access_codes(id) ON DELETE SET NULL;
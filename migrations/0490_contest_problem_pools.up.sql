@@ -0,0 +1,6 @@
+-- 試験向けの問題プール。pool が同じ問題のうち 1 問だけを参加者ごとに割り当てる
+-- （コンテスト ID・プール名・ユーザー ID から決定的に選ぶので、再読み込みしても変わらない）。
+-- pool が NULL の問題は従来どおり全員に出題する
+
+ALTER TABLE contest_problems
+    ADD COLUMN IF NOT EXISTS pool VARCHAR(32);
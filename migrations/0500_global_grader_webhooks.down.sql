@@ -0,0 +1,4 @@
+DELETE FROM grader_webhooks WHERE contest_id IS NULL AND problem_id IS NULL;
+ALTER TABLE grader_webhooks DROP CONSTRAINT IF EXISTS grader_webhooks_scope_check;
+ALTER TABLE grader_webhooks
+    ADD CONSTRAINT grader_webhooks_check CHECK ((contest_id IS NULL) <> (problem_id IS NULL));
@@ -0,0 +1,5 @@
+-- 採点系 Webhook にグローバル（全提出）の登録を許す。contest_id / problem_id とも NULL ならグローバル
+
+ALTER TABLE grader_webhooks DROP CONSTRAINT IF EXISTS grader_webhooks_check;
+ALTER TABLE grader_webhooks
+    ADD CONSTRAINT grader_webhooks_scope_check CHECK (contest_id IS NULL OR problem_id IS NULL);
@@ -0,0 +1,15 @@
+-- スクリプト / CLI 向けの個人 API トークン。Authorization: Bearer で送られたトークンを
+-- SHA-256 で照合する（平文は発行時に一度だけ返し、保存しない）
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id            BIGSERIAL PRIMARY KEY,
+    user_id       BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name          VARCHAR(100) NOT NULL,
+    token_hash    CHAR(64) NOT NULL UNIQUE,
+    token_prefix  VARCHAR(16) NOT NULL,
+    expires_at    TIMESTAMPTZ,
+    last_used_at  TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_tokens_user ON api_tokens (user_id, id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_submission_results_se_reason;
+ALTER TABLE submission_results DROP COLUMN IF EXISTS se_reason;
@@ -0,0 +1,6 @@
+-- SE（System Error）の原因を機械可読なコードで保存する（judge_unreachable / compile_infra / database / timeout / storage / unknown）
+
+ALTER TABLE submission_results ADD COLUMN IF NOT EXISTS se_reason TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_submission_results_se_reason
+    ON submission_results (updated_at, se_reason) WHERE verdict = 'SE';
@@ -0,0 +1,3 @@
+ALTER TABLE contests
+    DROP COLUMN IF EXISTS late_cutoff_at,
+    DROP COLUMN IF EXISTS late_penalty_percent;
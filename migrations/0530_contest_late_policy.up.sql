@@ -0,0 +1,7 @@
+-- 課題（コンテスト）の遅延提出ポリシー。終了後も late_cutoff_at まで提出を受け付け、
+-- 成績では 1 日（24 時間）遅れるごとに late_penalty_percent ずつ減点する
+
+ALTER TABLE contests
+    ADD COLUMN IF NOT EXISTS late_penalty_percent INT NOT NULL DEFAULT 0
+        CHECK (late_penalty_percent BETWEEN 0 AND 100),
+    ADD COLUMN IF NOT EXISTS late_cutoff_at TIMESTAMPTZ;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_submissions_problem_created;
+DROP INDEX IF EXISTS idx_submissions_user_created;
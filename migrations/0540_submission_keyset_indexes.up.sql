@@ -0,0 +1,6 @@
+-- 提出一覧のカーソル（keyset）ページング用。(created_at, id) の降順で索引を辿れるようにする
+
+CREATE INDEX IF NOT EXISTS idx_submissions_user_created
+    ON submissions (user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_submissions_problem_created
+    ON submissions (problem_id, created_at DESC, id DESC);
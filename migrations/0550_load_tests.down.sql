@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_submissions_load_test;
+ALTER TABLE submissions DROP COLUMN IF EXISTS load_test_id;
+DROP TABLE IF EXISTS load_tests;
@@ -0,0 +1,20 @@
+-- 判定キューの負荷試験。目標レートで合成提出を投入し、提出に load_test_id を付けて集計・後片付けできるようにする
+
+CREATE TABLE IF NOT EXISTS load_tests (
+    id               BIGSERIAL PRIMARY KEY,
+    problem_id       BIGINT NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    language         VARCHAR(32) NOT NULL,
+    rate_per_minute  INTEGER NOT NULL CHECK (rate_per_minute > 0),
+    duration_sec     INTEGER NOT NULL CHECK (duration_sec > 0),
+    requested_by     BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    status           VARCHAR(16) NOT NULL DEFAULT 'running'
+        CHECK (status IN ('running', 'completed', 'cancelled', 'failed')),
+    error_message    TEXT,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at      TIMESTAMPTZ
+);
+
+ALTER TABLE submissions
+    ADD COLUMN IF NOT EXISTS load_test_id BIGINT REFERENCES load_tests(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_submissions_load_test ON submissions (load_test_id) WHERE load_test_id IS NOT NULL;
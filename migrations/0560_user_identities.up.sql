@@ -0,0 +1,12 @@
+-- OAuth ログイン（GitHub / Google）で使う外部アカウントとローカルユーザーの紐付け。
+-- 1 ユーザーにつきプロバイダごとに 1 つまで、外部アカウントは 1 ユーザーにだけ紐付く
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    user_id     BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider    VARCHAR(16) NOT NULL,
+    subject     TEXT NOT NULL,
+    email       TEXT,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (provider, subject),
+    UNIQUE (user_id, provider)
+);
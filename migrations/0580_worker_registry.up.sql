@@ -0,0 +1,14 @@
+-- ワーカーの登録簿。ハートビート（Redis、TTL 付き）が消えても経歴が残るよう DB に記録する。
+-- status: active（稼働中）| stopped（正常停止）| lost（ハートビートが途絶えた）
+
+CREATE TABLE IF NOT EXISTS workers (
+    worker_id      VARCHAR(64) PRIMARY KEY,
+    hostname       TEXT NOT NULL,
+    pid            INT NOT NULL,
+    concurrency    INT NOT NULL,
+    status         VARCHAR(16) NOT NULL DEFAULT 'active',
+    started_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    stopped_at     TIMESTAMPTZ,
+    requeued_jobs  INT NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_workers_status ON workers(status, started_at DESC);
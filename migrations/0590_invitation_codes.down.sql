@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS invitation_code_id;
+DROP TABLE IF EXISTS invitation_codes;
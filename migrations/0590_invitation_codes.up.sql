@@ -0,0 +1,16 @@
+-- 招待コードによる自己登録。授業などでまとめて配布し、回数上限・有効期限で制限する。
+-- 登録したユーザーにはどのコードで登録したかを残す
+
+CREATE TABLE IF NOT EXISTS invitation_codes (
+    id          BIGSERIAL PRIMARY KEY,
+    code        VARCHAR(64) NOT NULL UNIQUE,
+    note        TEXT NOT NULL DEFAULT '',
+    max_uses    INT,
+    use_count   INT NOT NULL DEFAULT 0,
+    expires_at  TIMESTAMPTZ,
+    is_active   BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by  BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS invitation_code_id BIGINT REFERENCES invitation_codes(id) ON DELETE SET NULL;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_workers_hostname;
+ALTER TABLE workers DROP COLUMN IF EXISTS failed_total;
+ALTER TABLE workers DROP COLUMN IF EXISTS processed_total;
+ALTER TABLE workers DROP COLUMN IF EXISTS last_seen_at;
+ALTER TABLE workers DROP COLUMN IF EXISTS version;
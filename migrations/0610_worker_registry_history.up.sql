@@ -0,0 +1,8 @@
+-- ワーカー登録簿に最終確認時刻・ビルドバージョン・処理件数を持たせ、再起動後もフリートの経歴を追えるようにする。
+-- 件数はプロセスの生涯の累計（ワーカーが定期的に書き込む）
+
+ALTER TABLE workers ADD COLUMN IF NOT EXISTS version VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE workers ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE workers ADD COLUMN IF NOT EXISTS processed_total BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE workers ADD COLUMN IF NOT EXISTS failed_total BIGINT NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_workers_hostname ON workers(hostname, started_at DESC);
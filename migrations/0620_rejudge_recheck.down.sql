@@ -0,0 +1,2 @@
+ALTER TABLE rejudge_job_items DROP COLUMN IF EXISTS method;
+ALTER TABLE rejudge_jobs DROP COLUMN IF EXISTS mode;
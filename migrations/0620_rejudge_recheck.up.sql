@@ -0,0 +1,7 @@
+-- リジャッジの方式。recheck はプログラムを実行し直さず、保存済みの出力を現在のチェッカーで判定し直す
+-- （出力が残っていない提出は通常どおり実行し直す）。method は各提出が実際にどちらで処理されたか
+
+ALTER TABLE rejudge_jobs ADD COLUMN IF NOT EXISTS mode VARCHAR(16) NOT NULL DEFAULT 'rerun'
+    CHECK (mode IN ('rerun', 'recheck'));
+ALTER TABLE rejudge_job_items ADD COLUMN IF NOT EXISTS method VARCHAR(16)
+    CHECK (method IN ('rerun', 'recheck'));
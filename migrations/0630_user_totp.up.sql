@@ -0,0 +1,18 @@
+-- 管理画面を使うアカウントの二要素認証（TOTP）。enabled_at が入るまでは設定途中の秘密鍵
+-- last_used_step は同じコードの再利用を防ぐため、最後に受け付けた時間ステップを持つ
+
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id         BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret          TEXT NOT NULL,
+    enabled_at      TIMESTAMPTZ,
+    last_used_step  BIGINT NOT NULL DEFAULT 0,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- 認証アプリを失ったときのリカバリーコード（各 1 回限り、SHA-256 で保存）
+CREATE TABLE IF NOT EXISTS user_totp_recovery_codes (
+    user_id    BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash  CHAR(64) NOT NULL,
+    used_at    TIMESTAMPTZ,
+    PRIMARY KEY (user_id, code_hash)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE problems
+    DROP COLUMN IF EXISTS title_en,
+    DROP COLUMN IF EXISTS title_ja;
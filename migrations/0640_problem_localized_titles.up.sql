@@ -0,0 +1,5 @@
+-- 問題名の日本語・英語表記（任意）。未設定の言語では title を使う
+
+ALTER TABLE problems
+    ADD COLUMN IF NOT EXISTS title_ja TEXT,
+    ADD COLUMN IF NOT EXISTS title_en TEXT;
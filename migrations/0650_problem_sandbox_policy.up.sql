@@ -0,0 +1,5 @@
+-- 問題ごとのジャッジサンドボックス設定（ネットワーク、アドレス空間制限、スタックサイズ、プロセス数）。
+-- NULL なら JUDGE_SANDBOX_POLICY の言語ごとの設定のみを使う
+
+ALTER TABLE problems
+    ADD COLUMN IF NOT EXISTS sandbox_policy JSONB;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS notice_templates;
@@ -0,0 +1,22 @@
+-- お知らせのテンプレート。本文と題名の {{変数名}} を作成時に置き換える。
+-- よく使うコンテスト開始・メンテナンスの 2 件を初期データとして入れる
+
+CREATE TABLE IF NOT EXISTS notice_templates (
+    id          BIGSERIAL PRIMARY KEY,
+    name        VARCHAR(64) NOT NULL UNIQUE,
+    title       TEXT NOT NULL,
+    body        TEXT NOT NULL,
+    created_by  BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE TRIGGER trg_notice_templates_updated
+    BEFORE UPDATE ON notice_templates
+    FOR EACH ROW EXECUTE PROCEDURE set_updated_at();
+
+INSERT INTO notice_templates (name, title, body) VALUES
+    ('contest_start', '{{contest}} を開始しました',
+     E'{{contest}} を開始しました。終了は {{end_at}} です。\n\n- 問題はコンテストページから確認できます\n- 質問はクラリフィケーションから送ってください'),
+    ('maintenance', 'メンテナンスのお知らせ（{{start_at}}〜{{end_at}}）',
+     E'{{start_at}} から {{end_at}} まで、メンテナンスのためサービスを停止します。\n\n{{details}}\n\nこの間は提出・閲覧ができません。ご不便をおかけします。')
+ON CONFLICT (name) DO NOTHING;
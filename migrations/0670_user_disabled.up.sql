@@ -0,0 +1,4 @@
+-- 管理者によるアカウントの無効化。無効なアカウントはログイン・API トークン・既存セッションのいずれでも使えない
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMPTZ;
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_problems_publish_review;
+ALTER TABLE problems
+    DROP COLUMN IF EXISTS review_note,
+    DROP COLUMN IF EXISTS reviewed_at,
+    DROP COLUMN IF EXISTS reviewed_by,
+    DROP COLUMN IF EXISTS review_submitted_at,
+    DROP COLUMN IF EXISTS review_submitted_by,
+    DROP COLUMN IF EXISTS pending_package,
+    DROP COLUMN IF EXISTS pending_update,
+    DROP COLUMN IF EXISTS published_at,
+    DROP COLUMN IF EXISTS publish_state;
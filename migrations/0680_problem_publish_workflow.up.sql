@@ -0,0 +1,21 @@
+-- 問題の公開ワークフロー（draft → review → published）。
+-- published_at が NULL の問題は一度も承認されておらず、管理者・作問者以外には見えない。
+-- 公開済みの問題への作問者の変更は pending_update（設定・問題文）と pending_package（パッケージの取り込み）に
+-- 保留し、管理者が承認するまで公開中の問題には反映しない。既存の問題は公開済みとして扱う
+
+ALTER TABLE problems
+    ADD COLUMN IF NOT EXISTS publish_state VARCHAR(16) NOT NULL DEFAULT 'published'
+        CHECK (publish_state IN ('draft', 'review', 'published')),
+    ADD COLUMN IF NOT EXISTS published_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS pending_update JSONB,
+    ADD COLUMN IF NOT EXISTS pending_package JSONB,
+    ADD COLUMN IF NOT EXISTS review_submitted_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS review_submitted_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS reviewed_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS review_note TEXT NOT NULL DEFAULT '';
+
+UPDATE problems SET published_at = created_at WHERE published_at IS NULL AND publish_state = 'published';
+ALTER TABLE problems ALTER COLUMN published_at SET DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS idx_problems_publish_review ON problems(publish_state) WHERE publish_state = 'review';
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS user_suspension_events;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS suspended_at,
+    DROP COLUMN IF EXISTS suspension_reason,
+    DROP COLUMN IF EXISTS is_active;
@@ -0,0 +1,18 @@
+-- 利用停止（BAN）。停止中のアカウントは ACCOUNT_SUSPENDED で拒否され、提出もできない。
+-- 停止・解除の理由と実施者は user_suspension_events に履歴として残す
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD COLUMN IF NOT EXISTS suspension_reason TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS user_suspension_events (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    action     VARCHAR(16) NOT NULL CHECK (action IN ('suspend', 'unsuspend')),
+    reason     TEXT NOT NULL DEFAULT '',
+    actor_id   BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_suspension_events_user ON user_suspension_events(user_id, created_at DESC);
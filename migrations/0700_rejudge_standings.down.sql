@@ -0,0 +1,3 @@
+ALTER TABLE rejudge_jobs
+    DROP COLUMN IF EXISTS standings_published_at,
+    DROP COLUMN IF EXISTS standings_recomputed_at;
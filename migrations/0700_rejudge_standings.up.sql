@@ -0,0 +1,7 @@
+-- リジャッジ完了時の順位表の再計算。最後の対象提出が確定した時点で、ジョブが触れたコンテストの
+-- 集計セルをまとめて計算し直し（standings_recomputed_at）、standings_updated イベントを一度だけ配信する
+-- （standings_published_at）
+
+ALTER TABLE rejudge_jobs
+    ADD COLUMN IF NOT EXISTS standings_recomputed_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS standings_published_at TIMESTAMPTZ;
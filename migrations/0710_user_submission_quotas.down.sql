@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_submissions_user_created;
+DROP TABLE IF EXISTS user_submission_quotas;
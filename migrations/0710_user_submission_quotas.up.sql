@@ -0,0 +1,13 @@
+-- 提出数の上限（1 日あたり・コンテストあたり）。既定値は SUBMISSION_DAILY_QUOTA /
+-- SUBMISSION_CONTEST_QUOTA で設定し、ユーザーごとの上書きをここに保存する（NULL は既定値、0 は無制限）
+
+CREATE TABLE IF NOT EXISTS user_submission_quotas (
+    user_id       BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    daily_limit   INT CHECK (daily_limit >= 0),
+    contest_limit INT CHECK (contest_limit >= 0),
+    updated_by    BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- 当日の提出数の集計用
+CREATE INDEX IF NOT EXISTS idx_submissions_user_created ON submissions(user_id, created_at);
@@ -0,0 +1,21 @@
+-- 管理操作の監査ログ。/admin 以下で成功した変更（POST / PUT / PATCH / DELETE）を、実施者・対象・
+-- 変更内容の要約・接続元 IP とともに記録する。実施者のアカウントが消えても名前（actor）は残す
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id          BIGSERIAL PRIMARY KEY,
+    actor_id    BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    actor       VARCHAR(64) NOT NULL DEFAULT '',
+    action      VARCHAR(255) NOT NULL,
+    method      VARCHAR(8) NOT NULL,
+    path        TEXT NOT NULL,
+    target_type VARCHAR(64) NOT NULL DEFAULT '',
+    target_id   VARCHAR(128) NOT NULL DEFAULT '',
+    summary     TEXT NOT NULL DEFAULT '',
+    ip          VARCHAR(64) NOT NULL DEFAULT '',
+    status      INT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created ON audit_logs(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor ON audit_logs(actor, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_target ON audit_logs(target_type, target_id, created_at DESC);
@@ -0,0 +1,4 @@
+ALTER TABLE contests
+    DROP COLUMN IF EXISTS tie_break,
+    DROP COLUMN IF EXISTS penalty_count_compile_errors,
+    DROP COLUMN IF EXISTS penalty_minutes;
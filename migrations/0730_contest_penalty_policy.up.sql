@@ -0,0 +1,7 @@
+-- コンテストごとのペナルティ規則（ICPC 形式）。不正解 1 回あたりの加算分数、コンパイルエラーを
+-- 不正解に数えるか、同点時の順位の付け方（last_solve: 最後の正解が早い方が上位 / none: 同順位）
+
+ALTER TABLE contests
+    ADD COLUMN IF NOT EXISTS penalty_minutes INTEGER NOT NULL DEFAULT 20 CHECK (penalty_minutes >= 0),
+    ADD COLUMN IF NOT EXISTS penalty_count_compile_errors BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS tie_break VARCHAR(16) NOT NULL DEFAULT 'last_solve' CHECK (tie_break IN ('last_solve', 'none'));
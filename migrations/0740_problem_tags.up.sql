@@ -0,0 +1,16 @@
+-- 問題のタグ（例: dp, graph）。problem.yaml の tags と PATCH /admin/problems/:id で設定し、
+-- 問題一覧は ?tag= で絞り込める。どの問題にも付いていないタグは残しておく（名前の再利用のため）
+
+CREATE TABLE IF NOT EXISTS tags (
+    id         BIGSERIAL PRIMARY KEY,
+    name       VARCHAR(32) NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS problem_tags (
+    problem_id BIGINT NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    tag_id     BIGINT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (problem_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_problem_tags_tag ON problem_tags(tag_id);
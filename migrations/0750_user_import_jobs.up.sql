@@ -0,0 +1,31 @@
+-- CSV によるユーザー一括作成のジョブ。行ごとに状態（pending / created / failed）を持ち、途中で失敗しても
+-- 未処理の行から再開できる。同じ内容の CSV（content_sha256）の再投入は既存のジョブを返す。
+-- password は未処理の行と、生成したパスワード（配布用の結果 CSV に出す）だけに残す
+
+CREATE TABLE IF NOT EXISTS user_import_jobs (
+    id              BIGSERIAL PRIMARY KEY,
+    requested_by    BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    filename        VARCHAR(255) NOT NULL DEFAULT '',
+    content_sha256  CHAR(64) NOT NULL,
+    status          VARCHAR(16) NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'completed', 'failed')),
+    error_message   TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at     TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_import_jobs_sha ON user_import_jobs (content_sha256, id DESC);
+
+CREATE TABLE IF NOT EXISTS user_import_job_rows (
+    job_id      BIGINT NOT NULL REFERENCES user_import_jobs(id) ON DELETE CASCADE,
+    row_number  INT NOT NULL,
+    userid      VARCHAR(64) NOT NULL DEFAULT '',
+    password    TEXT,
+    generated   BOOLEAN NOT NULL DEFAULT FALSE,
+    status      VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'created', 'failed')),
+    reason      VARCHAR(64),
+    user_id     BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    PRIMARY KEY (job_id, row_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_import_job_rows_pending ON user_import_job_rows (job_id, row_number) WHERE status = 'pending';
@@ -0,0 +1,4 @@
+-- 問題の難易度（1〜10、未設定は NULL）。problem.yaml の difficulty と PATCH /admin/problems/:id で設定し、
+-- 問題一覧は ?sort=difficulty で易しい順に並べられる
+
+ALTER TABLE problems ADD COLUMN IF NOT EXISTS difficulty SMALLINT CHECK (difficulty BETWEEN 1 AND 10);
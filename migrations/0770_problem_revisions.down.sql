@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS problem_revisions;
+DROP TABLE IF EXISTS problem_revision_contents;
@@ -0,0 +1,27 @@
+-- 問題の版履歴。問題文・制限・チェッカー・テストケースなどを変更するたびに変更後の内容を 1 版として残し、
+-- 版ごとの差分の確認と過去の版への巻き戻しに使う。解説・公開設定・サンドボックス設定は対象外。
+-- テストケース・小課題・グループ・添付ファイルは内容のハッシュごとに 1 度だけ保存し、同じ内容の版で共有する。
+-- 導入前からある問題は、最初の変更の直前の内容を baseline の版として記録する
+
+CREATE TABLE IF NOT EXISTS problem_revision_contents (
+    problem_id  BIGINT NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    sha256      CHAR(64) NOT NULL,
+    content     JSONB NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (problem_id, sha256)
+);
+
+CREATE TABLE IF NOT EXISTS problem_revisions (
+    id              BIGSERIAL PRIMARY KEY,
+    problem_id      BIGINT NOT NULL REFERENCES problems(id) ON DELETE CASCADE,
+    revision        INT NOT NULL,
+    reason          VARCHAR(16) NOT NULL CHECK (reason IN ('baseline', 'create', 'update', 'package', 'rollback')),
+    rolled_back_to  INT, -- reason=rollback のとき、内容を戻した先の版
+    settings        JSONB NOT NULL,
+    content_sha256  CHAR(64) NOT NULL,
+    changes         TEXT[] NOT NULL DEFAULT '{}', -- 直前の版から変わった項目
+    edited_by       BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (problem_id, revision),
+    FOREIGN KEY (problem_id, content_sha256) REFERENCES problem_revision_contents(problem_id, sha256)
+);
@@ -0,0 +1,11 @@
+ALTER TABLE contest_problems
+    ADD COLUMN IF NOT EXISTS editorial_md            TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS editorial_public        BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS editorial_auto_release  BOOLEAN NOT NULL DEFAULT TRUE;
+
+UPDATE contest_problems cp
+SET editorial_md = p.editorial_md,
+    editorial_public = p.editorial_visibility = 'public',
+    editorial_auto_release = p.editorial_visibility <> 'hidden'
+FROM problems p
+WHERE p.id = cp.problem_id;
@@ -0,0 +1,23 @@
+-- コンテスト問題ごとの解説（0260）を問題の解説（0330）にまとめる。
+-- 問題側が空なら、最後に終わったコンテストの解説と公開設定を移す
+
+UPDATE problems p
+SET editorial_md = src.editorial_md,
+    editorial_visibility = CASE
+        WHEN src.editorial_public THEN 'public'
+        WHEN src.editorial_auto_release THEN 'auto'
+        ELSE 'hidden'
+    END
+FROM (
+    SELECT DISTINCT ON (cp.problem_id) cp.problem_id, cp.editorial_md, cp.editorial_public, cp.editorial_auto_release
+    FROM contest_problems cp
+    JOIN contests c ON c.id = cp.contest_id
+    WHERE cp.editorial_md <> ''
+    ORDER BY cp.problem_id, c.end_at DESC
+) src
+WHERE p.id = src.problem_id AND p.editorial_md = '';
+
+ALTER TABLE contest_problems
+    DROP COLUMN IF EXISTS editorial_md,
+    DROP COLUMN IF EXISTS editorial_public,
+    DROP COLUMN IF EXISTS editorial_auto_release;